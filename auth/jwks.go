@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// keySetLifetime is how long fetched keys are used before the key set is fetched again
+	keySetLifetime = time.Hour
+	// keySetMinRefresh is the minimum time between two fetches for tokens signed with an unknown key or
+	// after a failed fetch
+	keySetMinRefresh = time.Minute
+	keySetTimeout    = 10 * time.Second
+)
+
+var (
+	// ErrInvalidToken occurs if a token is no RS256 signed JWT with a valid signature, uid and exp claim
+	ErrInvalidToken = errors.New("auth token is not a valid RS256 signed JWT with uid and exp claims")
+	// ErrTokenExpired occurs if the exp claim of a token is in the past
+	ErrTokenExpired = errors.New("auth token is expired")
+	// ErrUnknownSigningKey occurs if a token is signed with a key that is not part of the key set
+	ErrUnknownSigningKey = errors.New("auth token is signed with an unknown key")
+)
+
+// Claims are the verified claims of a DC/OS auth token
+type Claims struct {
+	UID       string
+	ExpiresAt time.Time
+}
+
+// KeySet verifies DC/OS auth tokens with the public keys of the IAM JWKS endpoint. The keys are cached
+// and fetched again after an hour, or earlier for a token signed with an unknown key. A failed fetch is
+// repeated after a minute at the earliest.
+type KeySet struct {
+	url       string
+	client    *http.Client
+	clock     clock.Clock
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+	// attemptedAt is the time of the last fetch, fetchErr its error if it failed
+	attemptedAt time.Time
+	fetchErr    error
+	sync.Mutex
+}
+
+// NewKeySet creates a KeySet fetching the keys from the JWKS endpoint at url
+func NewKeySet(url string) *KeySet {
+	return newKeySet(url, clock.New())
+}
+
+func newKeySet(url string, clk clock.Clock) *KeySet {
+	// The JWKS endpoint is served by the local IAM, requests to it must never be sent through a proxy
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	return &KeySet{
+		url:    url,
+		client: &http.Client{Transport: transport, Timeout: keySetTimeout},
+		clock:  clk,
+	}
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type tokenClaims struct {
+	UID string `json:"uid"`
+	Exp int64  `json:"exp"`
+}
+
+// Verify checks the signature and expiry of token and returns its claims, a token without expiry is invalid
+func (s *KeySet) Verify(ctx context.Context, token string) (*Claims, error) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return nil, ErrInvalidToken
+	}
+	var header tokenHeader
+	if err := decodeSegment(segments[0], &header); err != nil || header.Algorithm != "RS256" {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(segments[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	key, err := s.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(segments[0] + "." + segments[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(segments[1], &claims); err != nil || claims.UID == "" || claims.Exp == 0 {
+		return nil, ErrInvalidToken
+	}
+	verified := &Claims{UID: claims.UID, ExpiresAt: time.Unix(claims.Exp, 0)}
+	if !s.clock.Now().Before(verified.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+	return verified, nil
+}
+
+// key returns the key with id, the key set is fetched if it is outdated or does not contain it
+func (s *KeySet) key(ctx context.Context, id string) (*rsa.PublicKey, error) {
+	s.Lock()
+	defer s.Unlock()
+	now := s.clock.Now()
+	key, ok := s.keys[id]
+	switch {
+	case ok && now.Sub(s.fetchedAt) < keySetLifetime:
+		return key, nil
+	case ok && now.Sub(s.attemptedAt) < keySetMinRefresh:
+		// the last refresh failed, keep verifying with the known key
+		return key, nil
+	case !ok && now.Sub(s.attemptedAt) < keySetMinRefresh:
+		if s.fetchErr != nil {
+			return nil, s.fetchErr
+		}
+		return nil, ErrUnknownSigningKey
+	}
+
+	s.attemptedAt = now
+	keys, err := s.fetch(ctx)
+	s.fetchErr = err
+	if err != nil {
+		if ok {
+			// keep verifying with the known key while the IAM is unavailable
+			logrus.WithError(err).Warn("Failed to refresh the IAM key set, using the cached keys")
+			return key, nil
+		}
+		return nil, err
+	}
+	s.keys = keys
+	s.fetchedAt = now
+	if key, ok = keys[id]; !ok {
+		return nil, ErrUnknownSigningKey
+	}
+	return key, nil
+}
+
+func (s *KeySet) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create key set request")
+	}
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "key set request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("key set request failed with status %v", resp.StatusCode)
+	}
+	var keySet jsonWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&keySet); err != nil {
+		return nil, errors.Wrap(err, "failed to decode key set")
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range keySet.Keys {
+		if jwk.KeyType != "RSA" {
+			continue
+		}
+		n, nErr := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, eErr := base64.RawURLEncoding.DecodeString(jwk.E)
+		if nErr != nil || eErr != nil || len(e) == 0 || len(e) > 4 {
+			logrus.WithField("kid", jwk.KeyID).Warn("Ignoring invalid key of the IAM key set")
+			continue
+		}
+		keys[jwk.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+// fakeJWKS serves the public keys of the IAM
+type fakeJWKS struct {
+	server  *httptest.Server
+	keys    map[string]*rsa.PrivateKey
+	fetches int
+	// failing answers the fetches with 503
+	failing bool
+	sync.Mutex
+}
+
+func newFakeJWKS(t *testing.T) *fakeJWKS {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	tests.H(t).IsNil(err)
+	jwks := &fakeJWKS{keys: map[string]*rsa.PrivateKey{"secret": key}}
+	jwks.server = httptest.NewServer(http.HandlerFunc(jwks.serve))
+	return jwks
+}
+
+func (jwks *fakeJWKS) serve(w http.ResponseWriter, r *http.Request) {
+	jwks.Lock()
+	defer jwks.Unlock()
+	jwks.fetches++
+	if jwks.failing {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var keySet jsonWebKeySet
+	for id, key := range jwks.keys {
+		keySet.Keys = append(keySet.Keys, jsonWebKey{
+			KeyType: "RSA",
+			KeyID:   id,
+			N:       encodeSegment(key.N.Bytes()),
+			E:       encodeSegment(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(keySet)
+}
+
+func (jwks *fakeJWKS) Fetches() int {
+	jwks.Lock()
+	defer jwks.Unlock()
+	return jwks.fetches
+}
+
+func (jwks *fakeJWKS) addKey(t *testing.T, id string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	tests.H(t).IsNil(err)
+	jwks.Lock()
+	jwks.keys[id] = key
+	jwks.Unlock()
+}
+
+func (jwks *fakeJWKS) setFailing(failing bool) {
+	jwks.Lock()
+	defer jwks.Unlock()
+	jwks.failing = failing
+}
+
+// token creates a token for uid signed with the key id
+func (jwks *fakeJWKS) token(t *testing.T, id string, uid string, expiresAt time.Time) string {
+	return jwks.signedToken(t, id, map[string]interface{}{"uid": uid, "exp": expiresAt.Unix()})
+}
+
+// signedToken creates a token with the claims signed with the key id
+func (jwks *fakeJWKS) signedToken(t *testing.T, id string, tokenClaims map[string]interface{}) string {
+	jwks.Lock()
+	key := jwks.keys[id]
+	jwks.Unlock()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": id})
+	claims, _ := json.Marshal(tokenClaims)
+	unsigned := encodeSegment(header) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	tests.H(t).IsNil(err)
+	return unsigned + "." + encodeSegment(signature)
+}
+
+func TestKeySet(t *testing.T) {
+	t.Run("verifies a token signed with a key of the key set", func(t *testing.T) {
+		jwks := newFakeJWKS(t)
+		defer jwks.server.Close()
+		keySet := NewKeySet(jwks.server.URL)
+
+		claims, err := keySet.Verify(context.Background(), jwks.token(t, "secret", "bootstrapuser", time.Now().Add(time.Hour)))
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(claims.UID, "bootstrapuser")
+	})
+
+	t.Run("returns ErrInvalidToken for a token with a wrong signature", func(t *testing.T) {
+		jwks := newFakeJWKS(t)
+		defer jwks.server.Close()
+		keySet := NewKeySet(jwks.server.URL)
+		token := jwks.token(t, "secret", "bootstrapuser", time.Now().Add(time.Hour))
+		jwks.keys["secret"], _ = rsa.GenerateKey(rand.Reader, 2048)
+
+		_, err := keySet.Verify(context.Background(), token)
+
+		tests.H(t).ErrEql(err, ErrInvalidToken)
+	})
+
+	t.Run("returns ErrInvalidToken for a malformed token", func(t *testing.T) {
+		jwks := newFakeJWKS(t)
+		defer jwks.server.Close()
+		keySet := NewKeySet(jwks.server.URL)
+
+		_, err := keySet.Verify(context.Background(), "not-a-token")
+
+		tests.H(t).ErrEql(err, ErrInvalidToken)
+	})
+
+	t.Run("returns ErrTokenExpired for an expired token", func(t *testing.T) {
+		jwks := newFakeJWKS(t)
+		defer jwks.server.Close()
+		keySet := NewKeySet(jwks.server.URL)
+
+		_, err := keySet.Verify(context.Background(), jwks.token(t, "secret", "bootstrapuser", time.Now().Add(-time.Minute)))
+
+		tests.H(t).ErrEql(err, ErrTokenExpired)
+	})
+
+	t.Run("returns ErrInvalidToken for a token without expiry", func(t *testing.T) {
+		jwks := newFakeJWKS(t)
+		defer jwks.server.Close()
+		keySet := NewKeySet(jwks.server.URL)
+
+		_, err := keySet.Verify(context.Background(), jwks.signedToken(t, "secret", map[string]interface{}{"uid": "bootstrapuser"}))
+
+		tests.H(t).ErrEql(err, ErrInvalidToken)
+	})
+
+	t.Run("caches the key set", func(t *testing.T) {
+		jwks := newFakeJWKS(t)
+		defer jwks.server.Close()
+		fakeClock := clock.NewFake(time.Now())
+		keySet := newKeySet(jwks.server.URL, fakeClock)
+		token := jwks.token(t, "secret", "bootstrapuser", time.Now().Add(3*time.Hour))
+
+		keySet.Verify(context.Background(), token)
+		fakeClock.Advance(30 * time.Minute)
+		keySet.Verify(context.Background(), token)
+		tests.H(t).IntEql(jwks.Fetches(), 1)
+
+		fakeClock.Advance(time.Hour)
+		keySet.Verify(context.Background(), token)
+		tests.H(t).IntEql(jwks.Fetches(), 2)
+	})
+
+	t.Run("fetches the key set again for an unknown key at most once a minute", func(t *testing.T) {
+		jwks := newFakeJWKS(t)
+		defer jwks.server.Close()
+		fakeClock := clock.NewFake(time.Now())
+		keySet := newKeySet(jwks.server.URL, fakeClock)
+		keySet.Verify(context.Background(), jwks.token(t, "secret", "bootstrapuser", time.Now().Add(time.Hour)))
+		jwks.addKey(t, "rotated")
+		token := jwks.token(t, "rotated", "bootstrapuser", time.Now().Add(time.Hour))
+
+		_, err := keySet.Verify(context.Background(), token)
+		tests.H(t).ErrEql(err, ErrUnknownSigningKey)
+
+		fakeClock.Advance(time.Minute)
+		_, err = keySet.Verify(context.Background(), token)
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(jwks.Fetches(), 2)
+	})
+	t.Run("fetches the key set again at most once a minute after a failed fetch", func(t *testing.T) {
+		jwks := newFakeJWKS(t)
+		defer jwks.server.Close()
+		jwks.setFailing(true)
+		fakeClock := clock.NewFake(time.Now())
+		keySet := newKeySet(jwks.server.URL, fakeClock)
+		token := jwks.token(t, "secret", "bootstrapuser", time.Now().Add(time.Hour))
+
+		_, err := keySet.Verify(context.Background(), token)
+		tests.H(t).NotNil(err)
+		_, err = keySet.Verify(context.Background(), token)
+		tests.H(t).NotNil(err)
+		tests.H(t).IntEql(jwks.Fetches(), 1)
+
+		jwks.setFailing(false)
+		fakeClock.Advance(time.Minute)
+		_, err = keySet.Verify(context.Background(), token)
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(jwks.Fetches(), 2)
+	})
+
+	t.Run("keeps the cached keys without refetching while the refresh fails", func(t *testing.T) {
+		jwks := newFakeJWKS(t)
+		defer jwks.server.Close()
+		fakeClock := clock.NewFake(time.Now())
+		keySet := newKeySet(jwks.server.URL, fakeClock)
+		token := jwks.token(t, "secret", "bootstrapuser", time.Now().Add(3*time.Hour))
+		keySet.Verify(context.Background(), token)
+		jwks.setFailing(true)
+
+		fakeClock.Advance(time.Hour)
+		_, err := keySet.Verify(context.Background(), token)
+		tests.H(t).IsNil(err)
+		_, err = keySet.Verify(context.Background(), token)
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(jwks.Fetches(), 2)
+	})
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// loginTokenLifetime is the validity of the JWT signed with the service account key for a login
+	loginTokenLifetime = 5 * time.Minute
+	// defaultTokenLifetime is assumed for auth tokens without exp claim
+	defaultTokenLifetime = time.Hour
+	// refreshBefore is how long before its expiry an auth token is replaced
+	refreshBefore = 10 * time.Minute
+	loginTimeout  = 30 * time.Second
+)
+
+// CredentialProvider obtains DC/OS auth tokens for a service account and refreshes them before they expire
+type CredentialProvider struct {
+	account   *ServiceAccount
+	client    *http.Client
+	clock     clock.Clock
+	token     string
+	expiresAt time.Time
+	sync.Mutex
+}
+
+// NewCredentialProvider creates a CredentialProvider logging in with the service account secret at path
+func NewCredentialProvider(path string) (*CredentialProvider, error) {
+	account, err := LoadServiceAccount(path)
+	if err != nil {
+		return nil, err
+	}
+	return newCredentialProvider(account, clock.New()), nil
+}
+
+func newCredentialProvider(account *ServiceAccount, clk clock.Clock) *CredentialProvider {
+	// The login endpoint is served by the local IAM, requests to it must never be sent through a proxy
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	return &CredentialProvider{
+		account: account,
+		client:  &http.Client{Transport: transport, Timeout: loginTimeout},
+		clock:   clk,
+	}
+}
+
+// Token returns the current auth token, logging in again if there is none or it is about to expire
+func (p *CredentialProvider) Token(ctx context.Context) (string, error) {
+	p.Lock()
+	defer p.Unlock()
+	if p.token != "" && p.clock.Now().Before(p.expiresAt.Add(-refreshBefore)) {
+		return p.token, nil
+	}
+	token, err := p.login(ctx)
+	if err != nil {
+		return "", err
+	}
+	p.token = token
+	p.expiresAt = p.clock.Now().Add(defaultTokenLifetime)
+	if expiresAt, ok := tokenExpiry(token); ok {
+		p.expiresAt = expiresAt
+	}
+	logrus.WithFields(logrus.Fields{
+		"uid":       p.account.UID,
+		"expiresAt": p.expiresAt,
+	}).Info("Obtained DC/OS auth token")
+	return token, nil
+}
+
+// Invalidate drops the current auth token, e.g. after it was rejected, so the next Token call logs in again
+func (p *CredentialProvider) Invalidate() {
+	p.Lock()
+	defer p.Unlock()
+	p.token = ""
+}
+
+type loginRequest struct {
+	UID   string `json:"uid"`
+	Token string `json:"token"`
+}
+
+type loginResponse struct {
+	Token string `json:"token"`
+}
+
+func (p *CredentialProvider) login(ctx context.Context) (string, error) {
+	loginToken, err := p.account.loginToken(p.clock.Now().Add(loginTokenLifetime))
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(loginRequest{UID: p.account.UID, Token: loginToken})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.account.LoginEndpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create login request")
+	}
+	req.Header.Set("content-type", "application/json")
+
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrap(err, "login request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("login of service account %s failed with status %v", p.account.UID, resp.StatusCode)
+	}
+	var response loginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", errors.Wrap(err, "failed to decode login response")
+	}
+	if response.Token == "" {
+		return "", errors.New("login response does not contain a token")
+	}
+	return response.Token, nil
+}
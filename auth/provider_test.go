@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+// fakeIAM is a login endpoint verifying the login tokens of one service account
+type fakeIAM struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	logins    int
+	expiresIn time.Duration
+	now       func() time.Time
+	sync.Mutex
+}
+
+func newFakeIAM(t *testing.T) *fakeIAM {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	tests.H(t).IsNil(err)
+	iam := &fakeIAM{key: key, expiresIn: 24 * time.Hour, now: time.Now}
+	iam.server = httptest.NewServer(http.HandlerFunc(iam.login))
+	return iam
+}
+
+func (iam *fakeIAM) login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UID != "dcos_ui_update_service" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	segments := strings.Split(req.Token, ".")
+	signature, _ := base64.RawURLEncoding.DecodeString(segments[2])
+	digest := sha256.Sum256([]byte(segments[0] + "." + segments[1]))
+	if err := rsa.VerifyPKCS1v15(&iam.key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	iam.Lock()
+	iam.logins++
+	logins := iam.logins
+	iam.Unlock()
+	claims, _ := json.Marshal(map[string]interface{}{"uid": req.UID, "exp": iam.now().Add(iam.expiresIn).Unix()})
+	token := fmt.Sprintf("header.%s.token-%d", encodeSegment(claims), logins)
+	json.NewEncoder(w).Encode(loginResponse{Token: token})
+}
+
+func (iam *fakeIAM) Logins() int {
+	iam.Lock()
+	defer iam.Unlock()
+	return iam.logins
+}
+
+// writeServiceAccount writes the service account secret for the fake IAM to dir
+func (iam *fakeIAM) writeServiceAccount(t *testing.T, dir string) string {
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(iam.key)})
+	secret, err := json.Marshal(ServiceAccount{
+		UID:           "dcos_ui_update_service",
+		PrivateKey:    string(keyPEM),
+		LoginEndpoint: iam.server.URL + "/acs/api/v1/auth/login",
+		Scheme:        "RS256",
+	})
+	tests.H(t).IsNil(err)
+	secretPath := path.Join(dir, "service_account.json")
+	tests.H(t).IsNil(ioutil.WriteFile(secretPath, secret, 0600))
+	return secretPath
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "ui-update-auth")
+	tests.H(t).IsNil(err)
+	return dir
+}
+
+func TestLoadServiceAccount(t *testing.T) {
+	t.Run("returns ErrInvalidServiceAccount without login endpoint", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		secretPath := path.Join(dir, "service_account.json")
+		ioutil.WriteFile(secretPath, []byte(`{"uid":"dcos_ui_update_service","private_key":"key"}`), 0600)
+
+		_, err := LoadServiceAccount(secretPath)
+		tests.H(t).ErrEql(err, ErrInvalidServiceAccount)
+	})
+
+	t.Run("returns ErrInvalidPrivateKey for a key that is not PEM encoded", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		secretPath := path.Join(dir, "service_account.json")
+		ioutil.WriteFile(secretPath, []byte(`{"uid":"dcos_ui_update_service","private_key":"key","login_endpoint":"http://127.0.0.1:8101"}`), 0600)
+
+		_, err := LoadServiceAccount(secretPath)
+		tests.H(t).ErrEql(err, ErrInvalidPrivateKey)
+	})
+
+	t.Run("returns ErrUnsupportedScheme for other schemes than RS256", func(t *testing.T) {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		secretPath := path.Join(dir, "service_account.json")
+		ioutil.WriteFile(secretPath, []byte(`{"uid":"dcos_ui_update_service","private_key":"key","login_endpoint":"http://127.0.0.1:8101","scheme":"HS256"}`), 0600)
+
+		_, err := LoadServiceAccount(secretPath)
+		tests.H(t).ErrEql(err, ErrUnsupportedScheme)
+	})
+}
+
+func TestCredentialProvider(t *testing.T) {
+	t.Run("logs in with a token signed by the service account key", func(t *testing.T) {
+		iam := newFakeIAM(t)
+		defer iam.server.Close()
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		provider, err := NewCredentialProvider(iam.writeServiceAccount(t, dir))
+		tests.H(t).IsNil(err)
+		token, err := provider.Token(context.Background())
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringContains(token, "token-1")
+	})
+
+	t.Run("reuses the token until it is about to expire", func(t *testing.T) {
+		iam := newFakeIAM(t)
+		defer iam.server.Close()
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		account, err := LoadServiceAccount(iam.writeServiceAccount(t, dir))
+		tests.H(t).IsNil(err)
+		fakeClock := clock.NewFake(time.Now())
+		iam.now = fakeClock.Now
+		iam.expiresIn = time.Hour
+		provider := newCredentialProvider(account, fakeClock)
+
+		provider.Token(context.Background())
+		fakeClock.Advance(45 * time.Minute)
+		token, _ := provider.Token(context.Background())
+		tests.H(t).StringContains(token, "token-1")
+
+		fakeClock.Advance(10 * time.Minute)
+		token, _ = provider.Token(context.Background())
+		tests.H(t).StringContains(token, "token-2")
+	})
+
+	t.Run("logs in again after the token was invalidated", func(t *testing.T) {
+		iam := newFakeIAM(t)
+		defer iam.server.Close()
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		provider, err := NewCredentialProvider(iam.writeServiceAccount(t, dir))
+		tests.H(t).IsNil(err)
+
+		provider.Token(context.Background())
+		provider.Invalidate()
+		provider.Token(context.Background())
+
+		tests.H(t).IntEql(iam.Logins(), 2)
+	})
+
+	t.Run("returns an error if the login is rejected", func(t *testing.T) {
+		iam := newFakeIAM(t)
+		defer iam.server.Close()
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		secretPath := iam.writeServiceAccount(t, dir)
+		iam.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+		provider, err := NewCredentialProvider(secretPath)
+		tests.H(t).IsNil(err)
+
+		_, err = provider.Token(context.Background())
+		tests.H(t).NotNil(err)
+	})
+}
@@ -0,0 +1,120 @@
+// Package auth authenticates requests against DC/OS components that require an auth token in strict mode.
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidServiceAccount occurs if the service account secret misses its uid, private key or login endpoint
+	ErrInvalidServiceAccount = errors.New("service account secret requires uid, private_key and login_endpoint")
+	// ErrUnsupportedScheme occurs if the service account secret uses another signing scheme than RS256
+	ErrUnsupportedScheme = errors.New("service account secret scheme must be RS256")
+	// ErrInvalidPrivateKey occurs if the private key of the service account secret is not a PEM encoded RSA key
+	ErrInvalidPrivateKey = errors.New("service account private key must be a PEM encoded RSA key")
+)
+
+// ServiceAccount is the DC/OS service account secret, as stored by `dcos security secrets create-sa-secret`
+type ServiceAccount struct {
+	UID           string `json:"uid"`
+	PrivateKey    string `json:"private_key"`
+	LoginEndpoint string `json:"login_endpoint"`
+	Scheme        string `json:"scheme"`
+
+	key *rsa.PrivateKey
+}
+
+// LoadServiceAccount reads and validates the service account secret at path
+func LoadServiceAccount(path string) (*ServiceAccount, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read service account secret")
+	}
+	var account ServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, errors.Wrap(err, "failed to parse service account secret")
+	}
+	if account.UID == "" || account.PrivateKey == "" || account.LoginEndpoint == "" {
+		return nil, ErrInvalidServiceAccount
+	}
+	if account.Scheme != "" && account.Scheme != "RS256" {
+		return nil, ErrUnsupportedScheme
+	}
+	if account.key, err = parsePrivateKey(account.PrivateKey); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func parsePrivateKey(encoded string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidPrivateKey
+	}
+	return rsaKey, nil
+}
+
+// loginToken creates the RS256 signed JWT sent to the login endpoint to prove the possession of the private key
+func (a *ServiceAccount) loginToken(expiresAt time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{"uid": a.UID, "exp": expiresAt.Unix()})
+	if err != nil {
+		return "", err
+	}
+	unsigned := encodeSegment(header) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", errors.Wrap(err, "failed to sign login token")
+	}
+	return unsigned + "." + encodeSegment(signature), nil
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// tokenExpiry reads the exp claim of token without verifying it, ok is false if it has none
+func tokenExpiry(token string) (time.Time, bool) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(segments[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Transport adds the auth token of Credentials to requests sent to Hosts, or to all requests if Hosts
+// is empty. A request rejected with 401 is sent once more with a new token if its body can be replayed.
+type Transport struct {
+	Base        http.RoundTripper
+	Credentials *CredentialProvider
+	Hosts       []string
+}
+
+// NewTransport wraps base so requests to hosts are authenticated with credentials
+func NewTransport(base http.RoundTripper, credentials *CredentialProvider, hosts ...string) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		Base:        base,
+		Credentials: credentials,
+		Hosts:       hosts,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.authenticates(req) {
+		return t.Base.RoundTrip(req)
+	}
+	resp, err := t.roundTripWithToken(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	t.Credentials.Invalidate()
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	return t.roundTripWithToken(retry)
+}
+
+func (t *Transport) roundTripWithToken(req *http.Request) (*http.Response, error) {
+	token, err := t.Credentials.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	// RoundTrippers must not modify the request
+	authenticated := req.Clone(req.Context())
+	authenticated.Header.Set("Authorization", "token="+token)
+	return t.Base.RoundTrip(authenticated)
+}
+
+func (t *Transport) authenticates(req *http.Request) bool {
+	if len(t.Hosts) == 0 {
+		return true
+	}
+	host := strings.ToLower(req.URL.Host)
+	for _, allowed := range t.Hosts {
+		if host == strings.ToLower(allowed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestTransport(t *testing.T) {
+	newProvider := func(t *testing.T, iam *fakeIAM) *CredentialProvider {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		provider, err := NewCredentialProvider(iam.writeServiceAccount(t, dir))
+		tests.H(t).IsNil(err)
+		return provider
+	}
+
+	t.Run("adds the auth token to requests", func(t *testing.T) {
+		iam := newFakeIAM(t)
+		defer iam.server.Close()
+		var authorization string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+		}))
+		defer server.Close()
+		client := &http.Client{Transport: NewTransport(nil, newProvider(t, iam))}
+
+		resp, err := client.Get(server.URL)
+		tests.H(t).IsNil(err)
+		resp.Body.Close()
+
+		tests.H(t).StringContains(authorization, "token=")
+		tests.H(t).StringContains(authorization, "token-1")
+	})
+
+	t.Run("does not add the auth token to requests for other hosts", func(t *testing.T) {
+		iam := newFakeIAM(t)
+		defer iam.server.Close()
+		authorization := "unset"
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+		}))
+		defer server.Close()
+		client := &http.Client{Transport: NewTransport(nil, newProvider(t, iam), "master.mesos")}
+
+		resp, err := client.Get(server.URL)
+		tests.H(t).IsNil(err)
+		resp.Body.Close()
+
+		tests.H(t).StringEql(authorization, "")
+		tests.H(t).IntEql(iam.Logins(), 0)
+	})
+
+	t.Run("retries a rejected request once with a new token", func(t *testing.T) {
+		iam := newFakeIAM(t)
+		defer iam.server.Close()
+		var bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+			if len(bodies) == 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}))
+		defer server.Close()
+		serverURL, _ := url.Parse(server.URL)
+		client := &http.Client{Transport: NewTransport(nil, newProvider(t, iam), serverURL.Host)}
+
+		resp, err := client.Post(server.URL, "application/json", bytes.NewBufferString(`{"packageName":"dcos-ui"}`))
+		tests.H(t).IsNil(err)
+		resp.Body.Close()
+
+		tests.H(t).IntEql(resp.StatusCode, http.StatusOK)
+		tests.H(t).IntEql(iam.Logins(), 2)
+		tests.H(t).IntEql(len(bodies), 2)
+		tests.H(t).StringEql(bodies[1], `{"packageName":"dcos-ui"}`)
+	})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/pkg/errors"
+)
+
+// checkCommand checks the local service, `check [--json] [flags]`
+const checkCommand = "check"
+
+// Exit codes of the check command
+const (
+	exitCheckUnreachable  = 6
+	exitCheckUnhealthy    = 7
+	exitCheckNotConverged = 8
+)
+
+// clusterStateComplete is the state of GET /api/v1/status/ once all masters serve the stored version
+const clusterStateComplete = "Complete"
+
+// checkVerdict is the normalized result of the check command, printed as JSON with --json
+type checkVerdict struct {
+	OK      bool `json:"ok"`
+	Healthy bool `json:"healthy"`
+	// Converged is true if all masters serve the stored version, or if the service cannot tell
+	Converged    bool     `json:"converged"`
+	State        string   `json:"state,omitempty"`
+	Version      string   `json:"version,omitempty"`
+	FailedChecks []string `json:"failedChecks"`
+	Lagging      []string `json:"lagging"`
+	Error        string   `json:"error,omitempty"`
+	ExitCode     int      `json:"exitCode"`
+}
+
+type checkHealthResponse struct {
+	Healthy bool `json:"healthy"`
+	Checks  map[string]struct {
+		Healthy bool   `json:"healthy"`
+		Error   string `json:"error"`
+	} `json:"checks"`
+}
+
+type checkStatusResponse struct {
+	State   string   `json:"state"`
+	Version string   `json:"version"`
+	Lagging []string `json:"lagging"`
+}
+
+// runCheck queries the health and cluster status of the service listening on the configured address and
+// prints the verdict to out, the exit code tells configuration management whether the master converged
+func runCheck(cliArgs []string, out io.Writer) int {
+	asJSON := false
+	var serviceArgs []string
+	for _, arg := range cliArgs {
+		if arg == "--json" {
+			asJSON = true
+			continue
+		}
+		serviceArgs = append(serviceArgs, arg)
+	}
+	cfg, err := config.Parse(serviceArgs)
+	if err != nil {
+		printVerdict(out, checkVerdict{Error: err.Error(), ExitCode: exitConfigError}, asJSON)
+		return exitConfigError
+	}
+
+	verdict := check(newCheckClient(cfg), checkBaseURL(cfg))
+	printVerdict(out, verdict, asJSON)
+	return verdict.ExitCode
+}
+
+// newCheckClient connects to the listen address of the service, also if it is a unix socket
+func newCheckClient(cfg *config.Config) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.HTTPClientTimeout()}
+	return &http.Client{
+		Timeout: cfg.HTTPClientTimeout(),
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, cfg.ListenNetProtocol(), cfg.ListenNetAddress())
+			},
+			// the check only talks to the local service, its certificate is issued for the master's name
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+}
+
+func checkBaseURL(cfg *config.Config) string {
+	if cfg.TLSEnabled() {
+		return "https://localhost"
+	}
+	return "http://localhost"
+}
+
+// check builds the verdict from GET /api/v1/health/ and GET /api/v1/status/
+func check(client *http.Client, baseURL string) checkVerdict {
+	verdict := checkVerdict{FailedChecks: []string{}, Lagging: []string{}}
+	var health checkHealthResponse
+	if _, err := getCheckJSON(client, baseURL+"/api/v1/health/", &health); err != nil {
+		verdict.Error = err.Error()
+		verdict.ExitCode = exitCheckUnreachable
+		return verdict
+	}
+	verdict.Healthy = health.Healthy
+	for name, c := range health.Checks {
+		if !c.Healthy {
+			verdict.FailedChecks = append(verdict.FailedChecks, name)
+		}
+	}
+	sort.Strings(verdict.FailedChecks)
+
+	var status checkStatusResponse
+	code, err := getCheckJSON(client, baseURL+"/api/v1/status/", &status)
+	switch {
+	case code == http.StatusNotImplemented:
+		// the version store does not share node states, there is nothing to converge
+		verdict.Converged = true
+	case err != nil:
+		verdict.Error = err.Error()
+	default:
+		verdict.State = status.State
+		verdict.Version = status.Version
+		if status.Lagging != nil {
+			verdict.Lagging = status.Lagging
+		}
+		verdict.Converged = status.State == clusterStateComplete
+	}
+
+	switch {
+	case !verdict.Healthy:
+		verdict.ExitCode = exitCheckUnhealthy
+	case !verdict.Converged:
+		verdict.ExitCode = exitCheckNotConverged
+	default:
+		verdict.OK = true
+		verdict.ExitCode = exitOK
+	}
+	return verdict
+}
+
+// getCheckJSON decodes the JSON body of url into v, the body of 503 health responses is decoded as well
+func getCheckJSON(client *http.Client, url string, v interface{}) (int, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not reach the service")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return resp.StatusCode, errors.Errorf("%s returned %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return resp.StatusCode, errors.Wrapf(err, "could not parse the response of %s", url)
+	}
+	return resp.StatusCode, nil
+}
+
+func printVerdict(out io.Writer, verdict checkVerdict, asJSON bool) {
+	if asJSON {
+		json.NewEncoder(out).Encode(verdict)
+		return
+	}
+	switch {
+	case verdict.ExitCode == exitCheckUnreachable || verdict.ExitCode == exitConfigError:
+		fmt.Fprintf(out, "error: %s\n", verdict.Error)
+	case !verdict.Healthy:
+		fmt.Fprintf(out, "unhealthy: failed checks %s\n", strings.Join(verdict.FailedChecks, ", "))
+	case !verdict.Converged:
+		fmt.Fprintf(out, "not converged: cluster state %s, lagging %s\n", verdict.State, strings.Join(verdict.Lagging, ", "))
+	default:
+		fmt.Fprintln(out, "ok")
+	}
+}
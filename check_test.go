@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func checkServer(healthStatus int, health string, statusStatus int, status string) *httptest.Server {
+	return httptest.NewServer(checkHandler(healthStatus, health, statusStatus, status))
+}
+
+// checkHandler responds to the endpoints queried by the check command
+func checkHandler(healthStatus int, health string, statusStatus int, status string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/health/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(healthStatus)
+		w.Write([]byte(health))
+	})
+	mux.HandleFunc("/api/v1/status/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusStatus)
+		w.Write([]byte(status))
+	})
+	return mux
+}
+
+func TestCheck(t *testing.T) {
+	healthy := `{"healthy":true,"checks":{"filesystem":{"healthy":true}}}`
+
+	t.Run("is ok if the service is healthy and the cluster converged", func(t *testing.T) {
+		server := checkServer(http.StatusOK, healthy, http.StatusOK, `{"state":"Complete","version":"2.25.0","lagging":[]}`)
+		defer server.Close()
+
+		verdict := check(server.Client(), server.URL)
+
+		tests.H(t).BoolEql(verdict.OK, true)
+		tests.H(t).IntEql(verdict.ExitCode, exitOK)
+		tests.H(t).StringEql(verdict.Version, "2.25.0")
+	})
+
+	t.Run("lists the failed health checks", func(t *testing.T) {
+		server := checkServer(http.StatusServiceUnavailable, `{"healthy":false,"checks":{"filesystem":{"healthy":false,"error":"read-only"}}}`, http.StatusOK, `{"state":"Complete"}`)
+		defer server.Close()
+
+		verdict := check(server.Client(), server.URL)
+
+		tests.H(t).IntEql(verdict.ExitCode, exitCheckUnhealthy)
+		tests.H(t).InterfaceEql(verdict.FailedChecks, []string{"filesystem"})
+	})
+
+	t.Run("is not converged while masters lag behind", func(t *testing.T) {
+		server := checkServer(http.StatusOK, healthy, http.StatusOK, `{"state":"InProgress","version":"2.25.0","lagging":["10.0.0.2"]}`)
+		defer server.Close()
+
+		verdict := check(server.Client(), server.URL)
+
+		tests.H(t).IntEql(verdict.ExitCode, exitCheckNotConverged)
+		tests.H(t).InterfaceEql(verdict.Lagging, []string{"10.0.0.2"})
+	})
+
+	t.Run("is converged if the version store does not share node states", func(t *testing.T) {
+		server := checkServer(http.StatusOK, healthy, http.StatusNotImplemented, "not implemented")
+		defer server.Close()
+
+		verdict := check(server.Client(), server.URL)
+
+		tests.H(t).IntEql(verdict.ExitCode, exitOK)
+		tests.H(t).BoolEql(verdict.Converged, true)
+	})
+}
+
+func TestRunCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ui-update-check")
+	tests.H(t).IsNil(err)
+	defer os.RemoveAll(dir)
+	socket := path.Join(dir, "service.sock")
+
+	t.Run("prints the verdict of the service on the unix socket as JSON", func(t *testing.T) {
+		l, err := net.Listen("unix", socket)
+		tests.H(t).IsNil(err)
+		go http.Serve(l, checkHandler(http.StatusOK, `{"healthy":true,"checks":{}}`, http.StatusOK, `{"state":"Complete","lagging":[]}`))
+		defer l.Close()
+
+		var out bytes.Buffer
+		code := runCheck([]string{"--json", "--listen-addr", socket}, &out)
+
+		tests.H(t).IntEql(code, exitOK)
+		var verdict checkVerdict
+		tests.H(t).IsNil(json.Unmarshal(out.Bytes(), &verdict))
+		tests.H(t).BoolEql(verdict.OK, true)
+	})
+
+	t.Run("exits with exitCheckUnreachable if the service does not listen", func(t *testing.T) {
+		var out bytes.Buffer
+		code := runCheck([]string{"--listen-addr", path.Join(dir, "missing.sock")}, &out)
+
+		tests.H(t).IntEql(code, exitCheckUnreachable)
+		tests.H(t).StringContains(out.String(), "error: could not reach the service")
+	})
+}
@@ -0,0 +1,116 @@
+// Package clock abstracts the time functions used for timeouts, polling and backoffs
+// so the code relying on them can be tested without sleeping.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time and timer channels
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+// New returns a Clock backed by the time package
+func New() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type fakeTimer struct {
+	deadline time.Time
+	channel  chan time.Time
+}
+
+// Fake is a Clock that only moves forward when Advance is called
+type Fake struct {
+	now     time.Time
+	timers  []*fakeTimer
+	waiters chan struct{}
+	sync.Mutex
+}
+
+// NewFake creates a Fake clock starting at the provided time
+func NewFake(start time.Time) *Fake {
+	return &Fake{
+		now:     start,
+		waiters: make(chan struct{}, 1),
+	}
+}
+
+// Now returns the current fake time
+func (f *Fake) Now() time.Time {
+	f.Lock()
+	defer f.Unlock()
+	return f.now
+}
+
+// After returns a channel receiving the fake time once the clock was advanced by at least d
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.Lock()
+	defer f.Unlock()
+
+	timer := &fakeTimer{
+		deadline: f.now.Add(d),
+		channel:  make(chan time.Time, 1),
+	}
+	if d <= 0 {
+		timer.channel <- f.now
+		return timer.channel
+	}
+	f.timers = append(f.timers, timer)
+	select {
+	case f.waiters <- struct{}{}:
+	default:
+	}
+	return timer.channel
+}
+
+// Advance moves the clock forward by d and fires all timers that expired
+func (f *Fake) Advance(d time.Duration) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.now = f.now.Add(d)
+	sort.Slice(f.timers, func(i, j int) bool {
+		return f.timers[i].deadline.Before(f.timers[j].deadline)
+	})
+	pending := f.timers[:0]
+	for _, timer := range f.timers {
+		if timer.deadline.After(f.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.channel <- f.now
+	}
+	f.timers = pending
+}
+
+// Timers returns the number of timers waiting to fire
+func (f *Fake) Timers() int {
+	f.Lock()
+	defer f.Unlock()
+	return len(f.timers)
+}
+
+// BlockUntil waits until at least n timers are waiting to fire
+func (f *Fake) BlockUntil(n int) {
+	for {
+		if f.Timers() >= n {
+			return
+		}
+		<-f.waiters
+	}
+}
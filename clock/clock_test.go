@@ -0,0 +1,64 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Now returns the start time until advanced", func(t *testing.T) {
+		clk := NewFake(start)
+		tests.H(t).BoolEql(clk.Now().Equal(start), true)
+
+		clk.Advance(time.Minute)
+		tests.H(t).BoolEql(clk.Now().Equal(start.Add(time.Minute)), true)
+	})
+
+	t.Run("After fires once the deadline is reached", func(t *testing.T) {
+		clk := NewFake(start)
+		timer := clk.After(10 * time.Second)
+
+		clk.Advance(5 * time.Second)
+		select {
+		case <-timer:
+			t.Fatal("timer fired before its deadline")
+		default:
+		}
+		tests.H(t).IntEql(clk.Timers(), 1)
+
+		clk.Advance(5 * time.Second)
+		select {
+		case fired := <-timer:
+			tests.H(t).BoolEql(fired.Equal(start.Add(10*time.Second)), true)
+		default:
+			t.Fatal("timer did not fire at its deadline")
+		}
+		tests.H(t).IntEql(clk.Timers(), 0)
+	})
+
+	t.Run("After fires immediately for non-positive durations", func(t *testing.T) {
+		clk := NewFake(start)
+		select {
+		case <-clk.After(0):
+		default:
+			t.Fatal("timer did not fire immediately")
+		}
+	})
+
+	t.Run("BlockUntil waits for timers to be created", func(t *testing.T) {
+		clk := NewFake(start)
+		done := make(chan struct{})
+		go func() {
+			<-clk.After(time.Second)
+			close(done)
+		}()
+
+		clk.BlockUntil(1)
+		clk.Advance(time.Second)
+		<-done
+	})
+}
@@ -2,12 +2,18 @@ package cosmos
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 	"path"
+	"time"
 
+	"github.com/dcos/dcos-ui-update-service/auth"
+	"github.com/dcos/dcos-ui-update-service/health"
+	"github.com/dcos/dcos-ui-update-service/proxy"
+	"github.com/dcos/dcos-ui-update-service/retry"
 	"github.com/pkg/errors"
 )
 
@@ -15,8 +21,23 @@ import (
 type Client struct {
 	httpClient  *http.Client
 	UniverseURL *url.URL
+	// RequestTimeout is the deadline applied to each request, no deadline is applied if zero
+	RequestTimeout time.Duration
+	// Retry is applied to failed requests, they are not retried by default
+	Retry retry.Policy
+	// BundleAssetName is the package asset holding the ui bundle, `<packageName>-bundle` if empty
+	BundleAssetName string
 }
 
+var (
+	// ErrBundleAssetNotFound occurs if the package details don't contain the ui bundle asset
+	ErrBundleAssetNotFound = errors.New("Could not find bundle asset in package details")
+	// ErrBundleAssetBadURI occurs if the ui bundle asset URI cannot be parsed
+	ErrBundleAssetBadURI = errors.New("Failed to parse bundle asset URI")
+	// ErrSignatureAssetNotFound occurs if the package details don't contain the signature asset of the ui bundle
+	ErrSignatureAssetNotFound = errors.New("Could not find bundle signature asset in package details")
+)
+
 type VersionNumberString string
 type PackageNumberRevision string
 
@@ -80,74 +101,188 @@ func (c *Client) ListPackageVersions(packageName string) (*ListVersionResponse,
 		return nil, errors.Wrap(err, "could not create json body from ListVersionRequest")
 	}
 
-	reqURL := *c.UniverseURL
-	reqURL.Path = path.Join(reqURL.Path, "/package/list-versions")
-	req, err := http.NewRequest("POST", reqURL.String(), bytes.NewBuffer(body))
+	var response ListVersionResponse
+	err = c.Retry.Do(context.Background(), "cosmos /package/list-versions", func() error {
+		return c.post(
+			"/package/list-versions",
+			body,
+			"application/vnd.dcos.package.list-versions-response+json;charset=utf-8;version=v1",
+			"application/vnd.dcos.package.list-versions-request+json;charset=utf-8;version=v1",
+			&response,
+		)
+	})
 	if err != nil {
-		return nil, errors.Wrap(err, "request to cosmos /package/list-versions failed")
+		return nil, err
 	}
-	req.Header.Set("accept", "application/vnd.dcos.package.list-versions-response+json;charset=utf-8;version=v1")
-	req.Header.Set("content-type", "application/vnd.dcos.package.list-versions-request+json;charset=utf-8;version=v1")
+	return &response, nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// GetPackageAssets retrieves the package assets from Cosmos matching the packageName and packageVersion provided
+func (c *Client) GetPackageAssets(packageName string, packageVersion string) (map[PackageAssetNameString]PackageAssetURIString, error) {
+	response, err := c.describePackage(packageName, packageVersion)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request to cosmos /package/list-versions failed with status %v", resp.StatusCode)
+	assets := response.Package.Resource.Assets.Uris
+
+	if len(assets) == 0 {
+		return nil, fmt.Errorf("Could not get asset uris from JSON")
 	}
-	var response ListVersionResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+
+	return assets, nil
+}
+
+// MinDcosReleaseVersion returns the minimum DC/OS release required by the package version, empty if it has none
+func (c *Client) MinDcosReleaseVersion(packageName string, packageVersion string) (string, error) {
+	response, err := c.describePackage(packageName, packageVersion)
+	health.DefaultTracker.Record(health.Cosmos, err)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to decode cosmos /package/list-versions response.")
+		return "", err
 	}
+	return response.Package.MinDcosReleaseVersion, nil
+}
 
-	return &response, nil
+// PackageOrigin returns the source repository and the package release number of the package version
+func (c *Client) PackageOrigin(packageName string, packageVersion string) (string, int, error) {
+	response, err := c.describePackage(packageName, packageVersion)
+	health.DefaultTracker.Record(health.Cosmos, err)
+	if err != nil {
+		return "", 0, err
+	}
+	return response.Package.Scm, response.Package.ReleaseVersion, nil
 }
 
-// GetPackageAssets retrieves the package assets from Cosmos matching the packageName and packageVersion provided
-func (c *Client) GetPackageAssets(packageName string, packageVersion string) (map[PackageAssetNameString]PackageAssetURIString, error) {
+func (c *Client) describePackage(packageName string, packageVersion string) (*PackageDetailResponse, error) {
 	packageDetailReq := PackageDetailRequest{PackageName: packageName, PackageVersion: packageVersion}
 	body, err := json.Marshal(packageDetailReq)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create json body from PackageDetailRequest")
+	}
+
+	var response PackageDetailResponse
+	err = c.Retry.Do(context.Background(), "cosmos /package/describe", func() error {
+		return c.post(
+			"/package/describe",
+			body,
+			"application/vnd.dcos.package.describe-response+json;charset=utf-8;version=v3",
+			"application/vnd.dcos.package.describe-request+json;charset=UTF-8;version=v1",
+			&response,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return &response, nil
+}
 
+// post sends body to the Cosmos endpoint and decodes the response into response. Failed requests,
+// 5xx and 429 responses are transient errors, other non-OK responses fail right away.
+func (c *Client) post(endpoint string, body []byte, accept, contentType string, response interface{}) error {
 	reqURL := *c.UniverseURL
-	reqURL.Path = path.Join(reqURL.Path, "/package/describe")
+	reqURL.Path = path.Join(reqURL.Path, endpoint)
 	req, err := http.NewRequest("POST", reqURL.String(), bytes.NewBuffer(body))
 	if err != nil {
-		return nil, errors.Wrap(err, "could not create json body from PackageDetailRequest")
+		return errors.Wrapf(err, "request to cosmos %s failed", endpoint)
 	}
-	req.Header.Set("accept", "application/vnd.dcos.package.describe-response+json;charset=utf-8;version=v3")
-	req.Header.Set("content-type", "application/vnd.dcos.package.describe-request+json;charset=UTF-8;version=v1")
+	req.Header.Set("accept", accept)
+	req.Header.Set("content-type", contentType)
 
-	resp, err := c.httpClient.Do(req)
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
 	if err != nil {
-		return nil, err
+		return retry.Transient(err)
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to query cosmos")
+		err := fmt.Errorf("request to cosmos %s failed with status %v", endpoint, resp.StatusCode)
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			return retry.Transient(err)
+		}
+		return err
 	}
-	defer resp.Body.Close()
-	var response PackageDetailResponse
-	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return errors.Wrapf(err, "failed to decode cosmos %s response", endpoint)
+	}
+	return nil
+}
+
+// ListVersions returns the versions of packageName available in Cosmos
+func (c *Client) ListVersions(packageName string) ([]string, error) {
+	resp, err := c.ListPackageVersions(packageName)
+	health.DefaultTracker.Record(health.Cosmos, err)
 	if err != nil {
-		return nil, errors.Wrap(err, "could not decode package detail response")
+		return nil, err
 	}
-	assets := response.Package.Resource.Assets.Uris
+	versions := make([]string, 0, len(resp.Results))
+	for version := range resp.Results {
+		versions = append(versions, string(version))
+	}
+	return versions, nil
+}
 
-	if len(assets) == 0 {
-		return nil, fmt.Errorf("Could not get asset uris from JSON")
+// ResolveBundle returns the URL of the bundle asset of the given package version
+func (c *Client) ResolveBundle(packageName string, packageVersion string) (*url.URL, error) {
+	return c.resolveAsset(packageName, packageVersion, c.bundleAssetName(packageName), ErrBundleAssetNotFound)
+}
+
+// ResolveSignature returns the URL of the detached signature of the bundle of the given package version,
+// published as the `<bundle asset>-signature` asset
+func (c *Client) ResolveSignature(packageName string, packageVersion string) (*url.URL, error) {
+	return c.resolveAsset(packageName, packageVersion, c.bundleAssetName(packageName)+"-signature", ErrSignatureAssetNotFound)
+}
+
+func (c *Client) bundleAssetName(packageName string) string {
+	if c.BundleAssetName != "" {
+		return c.BundleAssetName
 	}
+	return packageName + "-bundle"
+}
 
-	return assets, nil
+func (c *Client) resolveAsset(packageName, packageVersion, assetName string, errNotFound error) (*url.URL, error) {
+	assets, err := c.GetPackageAssets(packageName, packageVersion)
+	health.DefaultTracker.Record(health.Cosmos, err)
+	if err != nil {
+		return nil, err
+	}
+	assetURI, found := assets[PackageAssetNameString(assetName)]
+	if !found {
+		return nil, errNotFound
+	}
+	assetURL, err := url.Parse(string(assetURI))
+	if err != nil {
+		return nil, ErrBundleAssetBadURI
+	}
+	return assetURL, nil
+}
+
+func (c *Client) requestContext() (context.Context, context.CancelFunc) {
+	if c.RequestTimeout > 0 {
+		return context.WithTimeout(context.Background(), c.RequestTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// UseCredentials authenticates the requests to Cosmos with the auth token of credentials, as required in strict mode
+func (c *Client) UseCredentials(credentials *auth.CredentialProvider) {
+	c.httpClient.Transport = auth.NewTransport(c.httpClient.Transport, credentials)
+}
+
+// UseProxy sends the requests to Cosmos through the proxies of settings, requests to a Cosmos on the
+// master (loopback) stay direct. It must be called before UseCredentials.
+func (c *Client) UseProxy(settings proxy.Settings) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = settings.Func(false)
+	c.httpClient.Transport = transport
 }
 
+// NewClient creates the Cosmos client, requests are sent directly, ignoring the proxy environment
+// variables, unless UseProxy is called
 func NewClient(universeURL *url.URL) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
 	return &Client{
-		httpClient:  &http.Client{},
+		httpClient:  &http.Client{Transport: transport},
 		UniverseURL: universeURL,
 	}
 }
@@ -8,6 +8,10 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/proxy"
+	"github.com/dcos/dcos-ui-update-service/retry"
 )
 
 var (
@@ -249,3 +253,261 @@ func TestCosmosDetail(t *testing.T) {
 		}
 	})
 }
+
+func TestCosmosPackageSource(t *testing.T) {
+	t.Run("ListVersions returns the versions from list-versions", func(t *testing.T) {
+		server := serveSuccessfulListVersionResponseTestServer(t)
+		// Close the server when test finishes
+		defer server.Close()
+
+		cosmos := makeTestClient(server)
+
+		versions, err := cosmos.ListVersions("dcos-ui")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %q", err.Error())
+		}
+		if len(versions) != 30 {
+			t.Fatalf("Expected 30 versions, got %d", len(versions))
+		}
+	})
+
+	t.Run("ResolveBundle returns the bundle asset url", func(t *testing.T) {
+		server := serveSuccessfulDescribeResponseServer(t)
+		// Close the server when test finishes
+		defer server.Close()
+
+		cosmos := makeTestClient(server)
+
+		bundleURL, err := cosmos.ResolveBundle("dcos-ui", "2.25.0")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %q", err.Error())
+		}
+		if bundleURL.Host != "frontend-elasticl-11uu7xp48vh9c-805473783.eu-central-1.elb.amazonaws.com" {
+			t.Fatalf("Unexpected bundle url %q", bundleURL.String())
+		}
+	})
+
+	t.Run("ResolveBundle returns ErrBundleAssetNotFound if the bundle asset is missing", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			io.WriteString(rw, `{"package":{"resource":{"assets":{"uris":{"other":"https://example.com"}}}}}`)
+		}))
+		// Close the server when test finishes
+		defer server.Close()
+
+		cosmos := makeTestClient(server)
+
+		_, err := cosmos.ResolveBundle("dcos-ui", "2.25.0")
+
+		if err != ErrBundleAssetNotFound {
+			t.Fatalf("Expected ErrBundleAssetNotFound, got %v", err)
+		}
+	})
+
+	t.Run("ResolveBundle returns the configured bundle asset url", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			io.WriteString(rw, `{"package":{"resource":{"assets":{"uris":{"acme-ui-bundle":"https://example.com/wrong.tar.gz","ui-archive":"https://example.com/acme-ui.tar.gz"}}}}}`)
+		}))
+		// Close the server when test finishes
+		defer server.Close()
+
+		cosmos := makeTestClient(server)
+		cosmos.BundleAssetName = "ui-archive"
+
+		bundleURL, err := cosmos.ResolveBundle("acme-ui", "2.25.0")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %q", err.Error())
+		}
+		if bundleURL.String() != "https://example.com/acme-ui.tar.gz" {
+			t.Fatalf("Unexpected bundle url %q", bundleURL.String())
+		}
+	})
+
+	t.Run("ResolveSignature returns the signature asset url of the bundle", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			io.WriteString(rw, `{"package":{"resource":{"assets":{"uris":{"dcos-ui-bundle":"https://example.com/dcos-ui.tar.gz","dcos-ui-bundle-signature":"https://example.com/dcos-ui.tar.gz.sig"}}}}}`)
+		}))
+		// Close the server when test finishes
+		defer server.Close()
+
+		cosmos := makeTestClient(server)
+
+		signatureURL, err := cosmos.ResolveSignature("dcos-ui", "2.25.0")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %q", err.Error())
+		}
+		if signatureURL.String() != "https://example.com/dcos-ui.tar.gz.sig" {
+			t.Fatalf("Unexpected signature url %q", signatureURL.String())
+		}
+	})
+
+	t.Run("ResolveSignature returns ErrSignatureAssetNotFound if the signature asset is missing", func(t *testing.T) {
+		server := serveSuccessfulDescribeResponseServer(t)
+		// Close the server when test finishes
+		defer server.Close()
+
+		cosmos := makeTestClient(server)
+
+		_, err := cosmos.ResolveSignature("dcos-ui", "2.25.0")
+
+		if err != ErrSignatureAssetNotFound {
+			t.Fatalf("Expected ErrSignatureAssetNotFound, got %v", err)
+		}
+	})
+
+	t.Run("MinDcosReleaseVersion returns the minimum DC/OS release of the version", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			io.WriteString(rw, `{"package":{"version":"2.25.0","minDcosReleaseVersion":"1.13"}}`)
+		}))
+		// Close the server when test finishes
+		defer server.Close()
+
+		cosmos := makeTestClient(server)
+
+		minVersion, err := cosmos.MinDcosReleaseVersion("dcos-ui", "2.25.0")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %q", err.Error())
+		}
+		if minVersion != "1.13" {
+			t.Fatalf("Expected minimum DC/OS release 1.13, got %q", minVersion)
+		}
+	})
+
+	t.Run("PackageOrigin returns the source repository and release of the version", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			io.WriteString(rw, `{"package":{"version":"2.25.0","releaseVersion":7,"scm":"https://github.com/dcos/dcos-ui.git"}}`)
+		}))
+		// Close the server when test finishes
+		defer server.Close()
+
+		cosmos := makeTestClient(server)
+
+		scm, release, err := cosmos.PackageOrigin("dcos-ui", "2.25.0")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %q", err.Error())
+		}
+		if scm != "https://github.com/dcos/dcos-ui.git" || release != 7 {
+			t.Fatalf("Unexpected package origin %q release %d", scm, release)
+		}
+	})
+}
+
+func TestRequestTimeout(t *testing.T) {
+	t.Run("ListPackageVersions fails when cosmos exceeds the request timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			<-time.After(100 * time.Millisecond)
+			io.WriteString(rw, sucessListResponse)
+		}))
+		defer server.Close()
+
+		client := makeTestClient(server)
+		client.RequestTimeout = 10 * time.Millisecond
+
+		_, err := client.ListPackageVersions("dcos-ui")
+		if err == nil {
+			t.Fatalf("Expected a timeout error, got none")
+		}
+	})
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("ListPackageVersions retries a 502 response", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			requests++
+			if requests == 1 {
+				rw.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			io.WriteString(rw, sucessListResponse)
+		}))
+		defer server.Close()
+
+		client := makeTestClient(server)
+		client.Retry = retry.Policy{Attempts: 3, Min: time.Millisecond, Max: time.Millisecond}
+
+		resp, err := client.ListPackageVersions("dcos-ui")
+		if err != nil {
+			t.Fatalf("Expected no error, got %q", err.Error())
+		}
+		if requests != 2 || len(resp.Results) != 30 {
+			t.Fatalf("Expected 30 versions after 2 requests, got %d after %d", len(resp.Results), requests)
+		}
+	})
+
+	t.Run("GetPackageAssets reports the attempts", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client := makeTestClient(server)
+		client.Retry = retry.Policy{Attempts: 2, Min: time.Millisecond, Max: time.Millisecond}
+
+		_, err := client.GetPackageAssets("dcos-ui", "2.25.0")
+		expected := "cosmos /package/describe failed after 2 attempts: request to cosmos /package/describe failed with status 503"
+		if err == nil || err.Error() != expected {
+			t.Fatalf("Expected %q, got %v", expected, err)
+		}
+	})
+
+	t.Run("does not retry a 404 response", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			requests++
+			rw.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		client := makeTestClient(server)
+		client.Retry = retry.Policy{Attempts: 3, Min: time.Millisecond, Max: time.Millisecond}
+
+		if _, err := client.ListPackageVersions("dcos-ui"); err == nil {
+			t.Fatalf("Expected error, got nil")
+		}
+		if requests != 1 {
+			t.Fatalf("Expected 1 request, got %d", requests)
+		}
+	})
+}
+
+func TestUseProxy(t *testing.T) {
+	t.Run("sends requests to a remote Cosmos through the proxy", func(t *testing.T) {
+		var proxiedHost string
+		proxyServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			proxiedHost = req.URL.Host
+			io.WriteString(rw, sucessListResponse)
+		}))
+		defer proxyServer.Close()
+		proxyURL, _ := url.Parse(proxyServer.URL)
+
+		cosmosURL, _ := url.Parse("http://cosmos.external")
+		client := NewClient(cosmosURL)
+		client.UseProxy(proxy.Settings{HTTP: proxyURL})
+
+		if _, err := client.ListPackageVersions("dcos-ui"); err != nil {
+			t.Fatalf("Expected no error, got %q", err.Error())
+		}
+		if proxiedHost != "cosmos.external" {
+			t.Fatalf("Expected the request to cosmos.external to be proxied, got %q", proxiedHost)
+		}
+	})
+
+	t.Run("sends requests to a Cosmos on the master directly", func(t *testing.T) {
+		server := serveSuccessfulListVersionResponseTestServer(t)
+		defer server.Close()
+		unreachable, _ := url.Parse("http://127.0.0.1:1")
+
+		client := makeTestClient(server)
+		client.UseProxy(proxy.Settings{HTTP: unreachable, HTTPS: unreachable})
+
+		if _, err := client.ListPackageVersions("dcos-ui"); err != nil {
+			t.Fatalf("Expected no error, got %q", err.Error())
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package dcos
+
+import (
+	"context"
+	"net"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrNoIPDetected occurs if the detect ip script does not print a valid IP address
+	ErrNoIPDetected = errors.New("detect ip script did not return a valid IP address")
+)
+
+// DetectIP runs the DC/OS detect_ip script and returns the IP address it prints
+func DetectIP(script string, timeout time.Duration) (net.IP, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, script).Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run detect ip script %q", script)
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(output)))
+	if ip == nil {
+		return nil, ErrNoIPDetected
+	}
+	return ip, nil
+}
+
+// IPDetector returns the current IP address of this node
+type IPDetector func() (net.IP, error)
+
+// IPChangeListener is called with the previous and the new IP when the detected IP changes,
+// previous is nil for the first detection
+type IPChangeListener func(previous, current net.IP)
+
+// IPCache keeps the detected IP of this node, so operations don't have to run the detection every time.
+// Call Refresh periodically to pick up IP changes.
+type IPCache struct {
+	detect    IPDetector
+	ip        net.IP
+	lastIP    net.IP
+	listeners []IPChangeListener
+	sync.Mutex
+}
+
+// NewIPCache creates an IPCache using detect
+func NewIPCache(detect IPDetector) *IPCache {
+	return &IPCache{
+		detect: detect,
+	}
+}
+
+// IP returns the cached IP, detecting it if it is not known yet
+func (c *IPCache) IP() (net.IP, error) {
+	c.Lock()
+	ip := c.ip
+	c.Unlock()
+	if ip != nil {
+		return ip, nil
+	}
+	return c.Refresh()
+}
+
+// Invalidate drops the cached IP, the next call to IP detects it again
+func (c *IPCache) Invalidate() {
+	c.Lock()
+	defer c.Unlock()
+	c.ip = nil
+}
+
+// OnChange registers a listener called whenever a detection returns a different IP than the previous one
+func (c *IPCache) OnChange(listener IPChangeListener) {
+	c.Lock()
+	defer c.Unlock()
+	c.listeners = append(c.listeners, listener)
+}
+
+// Refresh detects the IP and updates the cache, a failed detection keeps the cached IP
+func (c *IPCache) Refresh() (net.IP, error) {
+	ip, err := c.detect()
+	if err != nil {
+		return nil, err
+	}
+
+	c.Lock()
+	previous := c.lastIP
+	c.ip = ip
+	c.lastIP = ip
+	listeners := append([]IPChangeListener(nil), c.listeners...)
+	c.Unlock()
+
+	if !previous.Equal(ip) {
+		logrus.WithFields(logrus.Fields{
+			"previousIP": previous.String(),
+			"ip":         ip.String(),
+		}).Info("Detected node IP changed")
+		for _, listener := range listeners {
+			listener(previous, ip)
+		}
+	}
+	return ip, nil
+}
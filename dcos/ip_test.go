@@ -0,0 +1,124 @@
+package dcos
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func writeScript(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "detect_ip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "detect_ip")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\n"+content+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+func TestDetectIP(t *testing.T) {
+	t.Run("returns the IP printed by the script", func(t *testing.T) {
+		script := writeScript(t, "echo 10.0.0.1")
+		defer os.RemoveAll(filepath.Dir(script))
+
+		ip, err := DetectIP(script, time.Second)
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(ip.String(), "10.0.0.1")
+	})
+
+	t.Run("returns ErrNoIPDetected if the output is not an IP", func(t *testing.T) {
+		script := writeScript(t, "echo unknown")
+		defer os.RemoveAll(filepath.Dir(script))
+
+		_, err := DetectIP(script, time.Second)
+
+		tests.H(t).ErrEql(err, ErrNoIPDetected)
+	})
+
+	t.Run("returns an error if the script fails", func(t *testing.T) {
+		script := writeScript(t, "exit 1")
+		defer os.RemoveAll(filepath.Dir(script))
+
+		_, err := DetectIP(script, time.Second)
+
+		tests.H(t).NotNil(err)
+	})
+}
+
+func TestIPCache(t *testing.T) {
+	detector := func(ips ...string) (IPDetector, *int) {
+		calls := 0
+		return func() (net.IP, error) {
+			ip := ips[calls]
+			if calls < len(ips)-1 {
+				calls++
+			}
+			if ip == "" {
+				return nil, errors.New("detection failed")
+			}
+			return net.ParseIP(ip), nil
+		}, &calls
+	}
+
+	t.Run("detects the IP once", func(t *testing.T) {
+		detect, calls := detector("10.0.0.1", "10.0.0.2")
+		cache := NewIPCache(detect)
+
+		first, _ := cache.IP()
+		second, _ := cache.IP()
+
+		tests.H(t).StringEql(first.String(), "10.0.0.1")
+		tests.H(t).StringEql(second.String(), "10.0.0.1")
+		tests.H(t).IntEql(*calls, 1)
+	})
+
+	t.Run("detects the IP again after Invalidate", func(t *testing.T) {
+		detect, _ := detector("10.0.0.1", "10.0.0.2")
+		cache := NewIPCache(detect)
+
+		cache.IP()
+		cache.Invalidate()
+		ip, _ := cache.IP()
+
+		tests.H(t).StringEql(ip.String(), "10.0.0.2")
+	})
+
+	t.Run("keeps the cached IP if a refresh fails", func(t *testing.T) {
+		detect, _ := detector("10.0.0.1", "")
+		cache := NewIPCache(detect)
+
+		cache.IP()
+		_, err := cache.Refresh()
+		ip, _ := cache.IP()
+
+		tests.H(t).NotNil(err)
+		tests.H(t).StringEql(ip.String(), "10.0.0.1")
+	})
+
+	t.Run("calls listeners only when the IP changes", func(t *testing.T) {
+		detect, _ := detector("10.0.0.1", "10.0.0.1", "10.0.0.2")
+		cache := NewIPCache(detect)
+		var changes [][2]string
+		cache.OnChange(func(previous, current net.IP) {
+			changes = append(changes, [2]string{previous.String(), current.String()})
+		})
+
+		cache.Refresh()
+		cache.Refresh()
+		cache.Refresh()
+
+		tests.H(t).IntEql(len(changes), 2)
+		tests.H(t).StringEql(changes[0][1], "10.0.0.1")
+		tests.H(t).StringEql(changes[1][0], "10.0.0.1")
+		tests.H(t).StringEql(changes[1][1], "10.0.0.2")
+	})
+}
@@ -0,0 +1,25 @@
+package dcos
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+type versionFile struct {
+	Version string `json:"version"`
+}
+
+// ReleaseVersion returns the DC/OS release version of the cluster from the dcos-version.json file at path
+func ReleaseVersion(path string) (string, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Could not find %q on file system", path)
+	}
+
+	var content versionFile
+	if err := json.Unmarshal(file, &content); err != nil || content.Version == "" {
+		return "", fmt.Errorf("The file could not be parsed: %q", path)
+	}
+	return content.Version, nil
+}
@@ -0,0 +1,32 @@
+package dcos
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReleaseVersion(t *testing.T) {
+	t.Run("returns the version from dcos-version.json", func(t *testing.T) {
+		version, err := ReleaseVersion("../fixtures/dcos-version.json")
+		if err != nil {
+			t.Fatalf("Expected no error, got %q", err.Error())
+		}
+		if version != "2.1.0" {
+			t.Fatalf("Expected version 2.1.0, got %q", version)
+		}
+	})
+
+	t.Run("throws if the file is not found", func(t *testing.T) {
+		_, err := ReleaseVersion("../fixtures/non-existant")
+		if err == nil || !strings.Contains(err.Error(), "Could not find") {
+			t.Fatalf("Error message should hint that it was not found. Instead got %v", err)
+		}
+	})
+
+	t.Run("throws if the file has no version", func(t *testing.T) {
+		_, err := ReleaseVersion("../fixtures/empty")
+		if err == nil || !strings.Contains(err.Error(), "could not be parsed") {
+			t.Fatalf("Error message should show that it can not parse the file. Instead got %v", err)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+// Package devserver runs the updater API for local ui development, versions are listed from a YAML
+// file instead of Cosmos and their bundles are copied from local directories.
+package devserver
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultListen  = "127.0.0.1:5000"
+	defaultWorkDir = "dcos-ui-update-devserver"
+)
+
+var (
+	// ErrNoVersions occurs if the devserver config does not list any version
+	ErrNoVersions = errors.New("the devserver config must list at least one version")
+	// ErrInvalidVersion occurs if a version of the devserver config has no name or no directory
+	ErrInvalidVersion = errors.New("every version of the devserver config needs a version and a path")
+)
+
+// Version is a ui version offered by the devserver, Path is the directory with the built ui,
+// the content of the dist directory of a bundle
+type Version struct {
+	Version string `mapstructure:"version"`
+	Path    string `mapstructure:"path"`
+}
+
+// Config is the devserver configuration read from a YAML file, e.g.
+//
+//	listen: 127.0.0.1:5000
+//	defaultUI: ./dist
+//	versions:
+//	  - version: 2.25.1
+//	    path: ./builds/2.25.1
+type Config struct {
+	// Listen is the TCP address the API is served on
+	Listen string `mapstructure:"listen"`
+	// WorkDir holds versions-root and the ui dist symlinks, a directory below the temp dir if empty
+	WorkDir string `mapstructure:"workDir"`
+	// DefaultUI is the directory with the pre-bundled ui, a placeholder page is served if empty
+	DefaultUI string `mapstructure:"defaultUI"`
+	// Versions are the versions offered instead of the versions of Cosmos
+	Versions []Version `mapstructure:"versions"`
+}
+
+// LoadConfig reads the devserver config from the YAML file at path, relative paths are resolved
+// against the directory of the file
+func LoadConfig(path string) (*Config, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, errors.Wrap(err, "failed to read the devserver config")
+	}
+	cfg := &Config{}
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the devserver config")
+	}
+	if len(cfg.Versions) == 0 {
+		return nil, ErrNoVersions
+	}
+
+	base := filepath.Dir(path)
+	for i, version := range cfg.Versions {
+		if version.Version == "" || version.Path == "" {
+			return nil, ErrInvalidVersion
+		}
+		cfg.Versions[i].Path = resolvePath(base, version.Path)
+	}
+	if cfg.Listen == "" {
+		cfg.Listen = defaultListen
+	}
+	if cfg.WorkDir == "" {
+		cfg.WorkDir = filepath.Join(os.TempDir(), defaultWorkDir)
+	} else {
+		cfg.WorkDir = resolvePath(base, cfg.WorkDir)
+	}
+	if cfg.DefaultUI != "" {
+		cfg.DefaultUI = resolvePath(base, cfg.DefaultUI)
+	}
+	return cfg, nil
+}
+
+func resolvePath(base, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
+
+func (c *Config) versionsRoot() string {
+	return filepath.Join(c.WorkDir, "ui-versions")
+}
+
+func (c *Config) defaultDocRoot() string {
+	if c.DefaultUI != "" {
+		return c.DefaultUI
+	}
+	return filepath.Join(c.WorkDir, "dcos-ui")
+}
+
+// Args are the service flags pointing the updater to the work dir and listen address, flags
+// given on the command line after them take precedence
+func (c *Config) Args() []string {
+	return []string{
+		"--listen-net", "tcp",
+		"--listen-addr", c.Listen,
+		"--versions-root", c.versionsRoot(),
+		"--default-ui-path", c.defaultDocRoot(),
+		"--ui-dist-symlink", filepath.Join(c.WorkDir, "dcos-ui-dist"),
+		"--ui-dist-stage-symlink", filepath.Join(c.WorkDir, "new-dcos-ui-dist"),
+		"--init-ui-dist-symlink",
+	}
+}
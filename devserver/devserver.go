@@ -0,0 +1,46 @@
+package devserver
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/uiservice"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const placeholderIndex = `<!DOCTYPE html>
+<html><head><title>Pre-bundled UI</title></head>
+<body><p>Pre-bundled UI served by the dcos-ui-update-service devserver.</p></body></html>
+`
+
+// Setup creates the work dir and the service offering the versions of devCfg, the service
+// config cfg has to be parsed with devCfg.Args()
+func Setup(devCfg *Config, cfg *config.Config) (*uiservice.UIService, error) {
+	if err := os.MkdirAll(cfg.VersionsRoot(), 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create the devserver work dir")
+	}
+	if devCfg.DefaultUI == "" {
+		if err := writePlaceholderUI(cfg.DefaultDocRoot()); err != nil {
+			return nil, errors.Wrap(err, "failed to create the placeholder pre-bundled ui")
+		}
+	}
+
+	source := &updatemanager.DirectURLSource{Bundles: make(map[string]*url.URL)}
+	for _, version := range devCfg.Versions {
+		source.Bundles[version.Version] = &url.URL{Scheme: "file", Path: version.Path}
+		logrus.WithFields(logrus.Fields{"version": version.Version, "path": version.Path}).Info("Offering version")
+	}
+	return uiservice.SetupDevService(cfg, source, dirFetcher{}, &memVersionStore{})
+}
+
+func writePlaceholderUI(docRoot string) error {
+	if err := os.MkdirAll(docRoot, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(docRoot, "index.html"), []byte(placeholderIndex), 0644)
+}
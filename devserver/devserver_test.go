@@ -0,0 +1,110 @@
+package devserver
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+// buildIndex is the index.html of the built ui of 2.25.1
+const buildIndex = `<html><script>window.DCOS_UI_VERSION = "2.25.1";</script></html>`
+
+// writeDevConfig creates a devserver config offering 2.25.1 in a new temp dir
+func writeDevConfig(t *testing.T, content string) (string, string) {
+	dir, err := ioutil.TempDir("", "devserver_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	build := filepath.Join(dir, "builds", "2.25.1")
+	os.MkdirAll(build, 0755)
+	ioutil.WriteFile(filepath.Join(build, "index.html"), []byte(buildIndex), 0644)
+	path := filepath.Join(dir, "devserver.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, path
+}
+
+// removeWorkDir makes the read-only versions writable again before removing dir
+func removeWorkDir(dir string) {
+	filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			os.Chmod(p, 0755)
+		}
+		return nil
+	})
+	os.RemoveAll(dir)
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("resolves the paths relative to the config file", func(t *testing.T) {
+		dir, path := writeDevConfig(t, "workDir: work\nversions:\n  - version: 2.25.1\n    path: builds/2.25.1\n")
+		defer removeWorkDir(dir)
+
+		cfg, err := LoadConfig(path)
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(cfg.Listen, defaultListen)
+		tests.H(t).StringEql(cfg.WorkDir, filepath.Join(dir, "work"))
+		tests.H(t).IntEql(len(cfg.Versions), 1)
+		tests.H(t).StringEql(cfg.Versions[0].Version, "2.25.1")
+		tests.H(t).StringEql(cfg.Versions[0].Path, filepath.Join(dir, "builds", "2.25.1"))
+	})
+
+	t.Run("returns ErrNoVersions if no version is listed", func(t *testing.T) {
+		dir, path := writeDevConfig(t, "listen: 127.0.0.1:5001\n")
+		defer removeWorkDir(dir)
+
+		_, err := LoadConfig(path)
+
+		tests.H(t).ErrEql(err, ErrNoVersions)
+	})
+
+	t.Run("returns ErrInvalidVersion for a version without a path", func(t *testing.T) {
+		dir, path := writeDevConfig(t, "versions:\n  - version: 2.25.1\n")
+		defer removeWorkDir(dir)
+
+		_, err := LoadConfig(path)
+
+		tests.H(t).ErrEql(err, ErrInvalidVersion)
+	})
+}
+
+func TestDevServer(t *testing.T) {
+	dir, path := writeDevConfig(t, "workDir: work\nversions:\n  - version: 2.25.1\n    path: builds/2.25.1\n")
+	defer removeWorkDir(dir)
+	devCfg, err := LoadConfig(path)
+	tests.H(t).IsNil(err)
+	cfg, err := config.Parse(devCfg.Args())
+	tests.H(t).IsNil(err)
+	service, err := Setup(devCfg, cfg)
+	tests.H(t).IsNil(err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	tests.H(t).IsNil(err)
+	go service.Run(l)
+	defer service.Shutdown(context.Background())
+	baseURL := "http://" + l.Addr().String()
+
+	resp, err := http.Post(baseURL+"/api/v1/update/2.25.1/", "text/plain", nil)
+	tests.H(t).IsNil(err)
+	resp.Body.Close()
+	tests.H(t).IntEql(resp.StatusCode, http.StatusOK)
+
+	served, err := ioutil.ReadFile(filepath.Join(cfg.UIDistSymlink(), "index.html"))
+	tests.H(t).IsNil(err)
+	tests.H(t).StringEql(string(served), buildIndex)
+
+	// the previous version is the pre-bundled ui, which is restored with a reset
+	resp, err = http.Post(baseURL+"/api/v1/rollback/", "text/plain", nil)
+	tests.H(t).IsNil(err)
+	resp.Body.Close()
+	tests.H(t).IntEql(resp.StatusCode, http.StatusConflict)
+}
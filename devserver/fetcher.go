@@ -0,0 +1,76 @@
+package devserver
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// dirFetcher is a BundleFetcher copying the built ui from the directory of a file URL into the
+// dist directory of the version
+type dirFetcher struct{}
+
+func (dirFetcher) DownloadAndUnpack(ctx context.Context, bundleURL *url.URL, targetDirectory string) error {
+	if bundleURL.Scheme != "file" {
+		return errors.Errorf("the devserver only fetches file URLs, got %s", bundleURL)
+	}
+	return copyTree(bundleURL.Path, filepath.Join(targetDirectory, "dist"))
+}
+
+func (dirFetcher) Head(ctx context.Context, bundleURL *url.URL) (int, int64, error) {
+	info, err := os.Stat(bundleURL.Path)
+	if os.IsNotExist(err) {
+		return http.StatusNotFound, -1, nil
+	}
+	if err != nil {
+		return 0, -1, err
+	}
+	return http.StatusOK, info.Size(), nil
+}
+
+func (dirFetcher) Fetch(ctx context.Context, fileURL *url.URL) ([]byte, error) {
+	if fileURL.Scheme != "file" {
+		return nil, errors.Errorf("the devserver only fetches file URLs, got %s", fileURL)
+	}
+	return ioutil.ReadFile(fileURL.Path)
+}
+
+func copyTree(src, dest string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
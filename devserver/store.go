@@ -0,0 +1,44 @@
+package devserver
+
+import (
+	"sync"
+
+	"github.com/dcos/dcos-ui-update-service/uiservice"
+)
+
+// memVersionStore keeps the served version in memory, there are no other masters to notify
+type memVersionStore struct {
+	current     uiservice.UIVersion
+	previous    uiservice.UIVersion
+	hasPrevious bool
+	sync.Mutex
+}
+
+func (s *memVersionStore) CurrentVersion() (uiservice.UIVersion, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.current, nil
+}
+
+func (s *memVersionStore) UpdateCurrentVersion(version uiservice.UIVersion) error {
+	s.Lock()
+	defer s.Unlock()
+	if version != s.current {
+		s.previous = s.current
+		s.hasPrevious = true
+	}
+	s.current = version
+	return nil
+}
+
+// WatchForVersionChange does not register the listener, the version only changes by requests to this process
+func (s *memVersionStore) WatchForVersionChange(listener uiservice.VersionChangeListener) error {
+	return nil
+}
+
+// PreviousVersion makes the rollback API available on the devserver
+func (s *memVersionStore) PreviousVersion() (uiservice.UIVersion, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.previous, s.hasPrevious, nil
+}
@@ -2,15 +2,20 @@ package downloader
 
 import (
 	"archive/tar"
-	"bytes"
 	"compress/gzip"
-	"fmt"
+	"context"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/dcos/dcos-ui-update-service/health"
+	"github.com/dcos/dcos-ui-update-service/memory"
+	"github.com/dcos/dcos-ui-update-service/retry"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
@@ -27,25 +32,108 @@ var (
 	ErrCreatingDirectoryWhileUnpacking = errors.New("Could not create directory while unzipping package")
 	// ErrCreatingFileWhileUnpacking occurs if we cannot open or copy an archive file while unzipping the package
 	ErrCreatingFileWhileUnpacking = errors.New("Failed to create file while unzipping package")
+	// ErrPackageTooManyFiles occurs if a package contains more than the maximum number of files
+	ErrPackageTooManyFiles = errors.New("Package contains too many files")
+	// ErrPackageTooDeep occurs if a package contains directories nested deeper than the maximum depth
+	ErrPackageTooDeep = errors.New("Package directories are nested too deep")
+	// ErrReadingPackageFailed occurs if a local package file cannot be read
+	ErrReadingPackageFailed = errors.New("Failed to read package file")
+	// ErrPackageTooLarge occurs if a package is larger than the maximum package size
+	ErrPackageTooLarge = errors.New("Package exceeds the maximum package size")
 )
 
 // Client is used to download a package from a URL and extract it to the filesystem
 type Client struct {
 	client *http.Client
 	Fs     afero.Fs
+	// Timeout is the deadline for downloading a whole package, no deadline is applied if zero
+	Timeout time.Duration
+	// MaxFiles is the maximum number of files of a package, not limited if zero
+	MaxFiles int
+	// MaxDepth is the maximum directory depth of a package, not limited if zero
+	MaxDepth int
+	// MaxSize is the maximum compressed size of a package in bytes, not limited if zero
+	MaxSize int64
+	// Retry is applied to failed download requests, they are not retried by default
+	Retry retry.Policy
+	// ProgressInterval is the minimum time between two progress reports, each read is reported if zero
+	ProgressInterval time.Duration
+	// Memory accounts the buffers of each extraction, extractions wait while its budget is exhausted
+	Memory *memory.Accountant
 }
 
-// ExtractTarGzToDir extracts payload as a tar file, unzips each entry.
+// sizeLimitedReader fails with ErrPackageTooLarge once more than limit bytes were read
+type sizeLimitedReader struct {
+	r        io.Reader
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.limit > 0 && l.read > l.limit {
+		l.exceeded = true
+		return n, ErrPackageTooLarge
+	}
+	return n, err
+}
+
+// maxTrailingBytes is the maximum number of bytes read after the end of a downloaded archive
+const maxTrailingBytes = 64 * 1024
+
+const (
+	// extractBufferSize is the size of the buffer copying the files of a package
+	extractBufferSize = 256 * 1024
+	// unpackMemory is reserved for an extraction: the copy buffer, the gzip window and the buffers of the tar
+	// reader and the HTTP response
+	unpackMemory = extractBufferSize + 256*1024
+)
+
+// extractProgressInterval is the number of extracted files between progress log records
+const extractProgressInterval = 1000
+
+// extractReport summarizes an extraction for the final log record
+type extractReport struct {
+	files int
+	dirs  int
+	bytes int64
+	start time.Time
+}
+
+func (r *extractReport) fields() logrus.Fields {
+	return logrus.Fields{
+		"files":    r.files,
+		"dirs":     r.dirs,
+		"bytes":    r.bytes,
+		"duration": time.Since(r.start).String(),
+	}
+}
+
+// ExtractTarGzToDir extracts the package read from payload as a tar file, unzips each entry.
 // It assumes that the tar file represents a directory and writes any
-// file/directory within into dest.
-func (d *Client) extractTarGzToDir(dest string, payload []byte) error {
-	gzr, err := gzip.NewReader(bytes.NewReader(payload))
+// file/directory within into dest. The package is streamed, it is never held in memory as a whole.
+func (d *Client) extractTarGzToDir(dest string, payload io.Reader) error {
+	limited := &sizeLimitedReader{r: payload, limit: d.MaxSize}
+	err := d.extractTarGz(dest, limited)
+	if limited.exceeded {
+		logrus.WithFields(logrus.Fields{"maxSize": d.MaxSize}).Error("Extract tar.gz to directory: Package too large")
+		return ErrPackageTooLarge
+	}
+	return err
+}
+
+func (d *Client) extractTarGz(dest string, payload io.Reader) error {
+	gzr, err := gzip.NewReader(payload)
 	if err != nil {
 		logrus.WithError(err).Error(ErrUnzippingPackageFailed.Error())
 		return ErrUnzippingPackageFailed
 	}
 	defer gzr.Close()
 	tr := tar.NewReader(gzr)
+	report := &extractReport{start: time.Now()}
+	buffer := make([]byte, extractBufferSize)
 
 	for {
 		header, err := tr.Next()
@@ -54,7 +142,7 @@ func (d *Client) extractTarGzToDir(dest string, payload []byte) error {
 
 		// if no more files are found return
 		case err == io.EOF:
-			logrus.Info("Extract tar.gz to directory: No more files found")
+			logrus.WithFields(report.fields()).Info("Extract tar.gz to directory: Completed")
 			return nil
 
 		// return any other error
@@ -64,78 +152,273 @@ func (d *Client) extractTarGzToDir(dest string, payload []byte) error {
 		// if the header is nil, just skip it (not sure how this
 		// happens)
 		case header == nil:
-			logrus.Info("Extract tar.gz to directory: Header is nil, skip")
 			continue
 		}
 
 		target := filepath.Join(dest, header.Name)
+		depth := pathDepth(header.Name)
 
 		// check the file type
 		switch header.Typeflag {
 
 		// if its a dir and it doesn't exist create it
 		case tar.TypeDir:
-			logrus.Infof("Extract tar.gz to directory: Creating directory - %s", target)
+			if d.MaxDepth > 0 && depth > d.MaxDepth {
+				logrus.WithFields(report.fields()).WithField("path", header.Name).Error("Extract tar.gz to directory: Directory too deep")
+				return ErrPackageTooDeep
+			}
 			if _, err := d.Fs.Stat(target); err != nil {
 				if err := d.Fs.MkdirAll(target, 0755); err != nil {
 					logrus.WithError(err).Errorf("Failed to make directory while unzipping new version package. Target: %s", target)
 					return ErrCreatingDirectoryWhileUnpacking
 				}
 			}
+			report.dirs++
 
 		// if it's a file create it
 		case tar.TypeReg:
-			logrus.Infof("Extract tar.gz to directory: Creating file - %s", target)
-			f, err := d.Fs.OpenFile(target, os.O_CREATE|os.O_RDWR, 0755)
+			if d.MaxDepth > 0 && depth-1 > d.MaxDepth {
+				logrus.WithFields(report.fields()).WithField("path", header.Name).Error("Extract tar.gz to directory: File too deep")
+				return ErrPackageTooDeep
+			}
+			if d.MaxFiles > 0 && report.files >= d.MaxFiles {
+				logrus.WithFields(report.fields()).Error("Extract tar.gz to directory: Too many files")
+				return ErrPackageTooManyFiles
+			}
+			written, err := d.extractFile(target, tr, buffer)
 			if err != nil {
-				logrus.WithError(err).Errorf("Error opening file while unzipping new version package. Target: %s", target)
-				return ErrCreatingFileWhileUnpacking
+				return err
 			}
-			defer f.Close()
-
-			// copy over contents
-			if _, err := io.Copy(f, tr); err != nil {
-				logrus.WithError(err).Errorf("Failed to copy file contents from archive. Target: %s", target)
-				return ErrCreatingFileWhileUnpacking
+			report.files++
+			report.bytes += written
+			if report.files%extractProgressInterval == 0 {
+				logrus.WithFields(report.fields()).Info("Extract tar.gz to directory: In progress")
 			}
 		}
 	}
 }
 
-func (d *Client) DownloadAndUnpack(fileURL fmt.Stringer, targetDirectory string) error {
+func (d *Client) extractFile(target string, r io.Reader, buffer []byte) (int64, error) {
+	f, err := d.Fs.OpenFile(target, os.O_CREATE|os.O_RDWR, 0755)
+	if err != nil {
+		logrus.WithError(err).Errorf("Error opening file while unzipping new version package. Target: %s", target)
+		return 0, ErrCreatingFileWhileUnpacking
+	}
+	defer f.Close()
+
+	// copy over contents
+	written, err := io.CopyBuffer(f, r, buffer)
+	if err != nil {
+		logrus.WithError(err).Errorf("Failed to copy file contents from archive. Target: %s", target)
+		return written, ErrCreatingFileWhileUnpacking
+	}
+	return written, nil
+}
+
+// pathDepth is the number of elements of an archive path
+func pathDepth(name string) int {
+	cleaned := strings.Trim(filepath.ToSlash(filepath.Clean(name)), "/")
+	if cleaned == "" || cleaned == "." {
+		return 0
+	}
+	return strings.Count(cleaned, "/") + 1
+}
+
+// DownloadAndUnpack downloads the tar.gz package from fileURL and extracts it to targetDirectory,
+// file URLs are read from the filesystem of the client
+func (d *Client) DownloadAndUnpack(ctx context.Context, fileURL *url.URL, targetDirectory string) error {
+	reservation, err := d.reserveMemory(ctx, "download")
+	if err != nil {
+		return err
+	}
+	defer reservation.Release()
+	if fileURL.Scheme == "file" {
+		NotifyUnpacking(ctx)
+		return d.unpackFile(ctx, fileURL.Path, targetDirectory)
+	}
+	err = d.Retry.Do(ctx, "bundle download", func() error {
+		return d.downloadAndUnpack(ctx, fileURL, targetDirectory)
+	})
+	health.DefaultTracker.Record(health.Downloader, err)
+	return err
+}
+
+func (d *Client) downloadAndUnpack(ctx context.Context, fileURL *url.URL, targetDirectory string) error {
 	req, err := http.NewRequest("GET", fileURL.String(), nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("content-type", "application/octet-stream")
-	resp, err := d.client.Do(req)
+	requestCtx, cancel := d.requestContext(ctx)
+	defer cancel()
+	resp, err := d.client.Do(req.WithContext(requestCtx))
 	if err != nil {
 		logrus.WithError(err).Error("Package download request failed")
-		return ErrDowloadPackageFailed
+		if ctx.Err() != nil {
+			// the download was canceled, it is not retried
+			return ErrDowloadPackageFailed
+		}
+		return retry.Transient(ErrDowloadPackageFailed)
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		logrus.WithField("statusCode", resp.StatusCode).Error("Download and unpack: non-OK response received")
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			return retry.Transient(ErrDowloadPackageFailed)
+		}
 		return ErrDowloadPackageFailed
 	}
+	if d.MaxSize > 0 && resp.ContentLength > d.MaxSize {
+		logrus.WithFields(logrus.Fields{"contentLength": resp.ContentLength, "maxSize": d.MaxSize}).Error("Download and unpack: package too large")
+		return ErrPackageTooLarge
+	}
 	logrus.WithField("statusCode", resp.StatusCode).Info("Download and unpack: response received")
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		logrus.WithError(err).Error("Failed to read package download response body")
-		return ErrBadPackageDownloadResponse
+	body := newProgressReader(ctx, resp.Body, resp.ContentLength, d.ProgressInterval)
+	var source io.Reader = body
+	if hasVerifier(ctx) {
+		// a package that has to be verified is only unpacked once it was downloaded and verified
+		staged, err := d.stageVerified(ctx, body)
+		if err != nil {
+			return err
+		}
+		defer d.removeStaged(staged)
+		source = staged
 	}
-	err = d.extractTarGzToDir(targetDirectory, body)
+	// without verification the body is extracted while it is downloaded
+	NotifyUnpacking(ctx)
+	payload := newDigestReader(ctx, source)
+	err = d.extractTarGzToDir(targetDirectory, payload)
 	if err != nil {
 		return err
 	}
+	// padding after the end of the archive is read so the reported size and checksum match the package
+	payload.record(ctx)
+	body.finish()
 	logrus.Info("Download and unpack successful")
 
 	return nil
 }
 
+// UnpackFile extracts the tar.gz package at archivePath to targetDirectory
+func (d *Client) UnpackFile(archivePath string, targetDirectory string) error {
+	reservation, err := d.reserveMemory(context.Background(), "unpack")
+	if err != nil {
+		return err
+	}
+	defer reservation.Release()
+	return d.unpackFile(context.Background(), archivePath, targetDirectory)
+}
+
+// reserveMemory waits until the memory of an extraction fits into the budget, nil if memory is not accounted
+func (d *Client) reserveMemory(ctx context.Context, purpose string) (*memory.Reservation, error) {
+	if d.Memory == nil {
+		return nil, nil
+	}
+	return d.Memory.Reserve(ctx, purpose, unpackMemory)
+}
+
+func (d *Client) unpackFile(ctx context.Context, archivePath string, targetDirectory string) error {
+	file, err := d.Fs.Open(archivePath)
+	if err != nil {
+		logrus.WithError(err).WithField("path", archivePath).Error("Failed to read package file")
+		return ErrReadingPackageFailed
+	}
+	defer file.Close()
+	if err := d.verifyFile(ctx, file); err != nil {
+		return err
+	}
+	payload := newDigestReader(ctx, file)
+	if err := d.extractTarGzToDir(targetDirectory, payload); err != nil {
+		return err
+	}
+	payload.record(ctx)
+	logrus.WithField("path", archivePath).Info("Unpack of package file successful")
+	return nil
+}
+
+// Head requests only the headers of fileURL, returns the response status and content length, -1 if unknown
+func (d *Client) Head(ctx context.Context, fileURL *url.URL) (int, int64, error) {
+	req, err := http.NewRequest("HEAD", fileURL.String(), nil)
+	if err != nil {
+		return 0, -1, err
+	}
+	ctx, cancel := d.requestContext(ctx)
+	defer cancel()
+	resp, err := d.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, -1, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, resp.ContentLength, nil
+}
+
+// maxFetchSize is the maximum size of a file downloaded by Fetch
+const maxFetchSize = 1024 * 1024
+
+// Fetch downloads the small file at fileURL, e.g. the detached signature of a package. File URLs are read
+// from the filesystem.
+func (d *Client) Fetch(ctx context.Context, fileURL *url.URL) ([]byte, error) {
+	if fileURL.Scheme == "file" {
+		file, err := d.Fs.Open(fileURL.Path)
+		if err != nil {
+			return nil, ErrReadingPackageFailed
+		}
+		defer file.Close()
+		return readLimited(file)
+	}
+	var data []byte
+	err := d.Retry.Do(ctx, "file download", func() error {
+		req, err := http.NewRequest("GET", fileURL.String(), nil)
+		if err != nil {
+			return err
+		}
+		requestCtx, cancel := d.requestContext(ctx)
+		defer cancel()
+		resp, err := d.client.Do(req.WithContext(requestCtx))
+		if err != nil {
+			if ctx.Err() != nil {
+				return ErrDowloadPackageFailed
+			}
+			return retry.Transient(ErrDowloadPackageFailed)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			logrus.WithFields(logrus.Fields{"url": fileURL.String(), "statusCode": resp.StatusCode}).Error("Fetch: non-OK response received")
+			if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+				return retry.Transient(ErrDowloadPackageFailed)
+			}
+			return ErrDowloadPackageFailed
+		}
+		data, err = readLimited(resp.Body)
+		return err
+	})
+	return data, err
+}
+
+// readLimited reads r completely, it fails with ErrPackageTooLarge for more than maxFetchSize bytes
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxFetchSize+1))
+	if err != nil {
+		return nil, ErrBadPackageDownloadResponse
+	}
+	if len(data) > maxFetchSize {
+		return nil, ErrPackageTooLarge
+	}
+	return data, nil
+}
+
+func (d *Client) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.Timeout > 0 {
+		return context.WithTimeout(ctx, d.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
 func New(fs afero.Fs) *Client {
 	return &Client{
-		client: &http.Client{},
-		Fs:     fs,
+		client:           &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+		Fs:               fs,
+		ProgressInterval: DefaultProgressInterval,
+		Memory:           memory.DefaultAccountant,
 	}
 }
@@ -1,12 +1,24 @@
 package downloader
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/dcos/dcos-ui-update-service/memory"
+	"github.com/dcos/dcos-ui-update-service/retry"
+	"github.com/pkg/errors"
 	"github.com/spf13/afero"
 )
 
@@ -27,7 +39,7 @@ func TestDownloader(t *testing.T) {
 				t.Fatalf("Could not create a tmp dir")
 			}
 			serverURL, _ := url.Parse(server.URL)
-			err = loader.DownloadAndUnpack(serverURL, dest)
+			err = loader.DownloadAndUnpack(context.Background(), serverURL, dest)
 
 			if err != nil {
 				t.Fatalf("Should not have thrown an error, got %#v", err)
@@ -56,7 +68,7 @@ func TestDownloader(t *testing.T) {
 			}
 
 			downloadURL, _ := url.Parse(server.URL)
-			err = loader.DownloadAndUnpack(downloadURL, dest)
+			err = loader.DownloadAndUnpack(context.Background(), downloadURL, dest)
 
 			if err == nil {
 				t.Fatalf("Should have thrown an error, got none")
@@ -74,7 +86,7 @@ func TestDownloader(t *testing.T) {
 			}
 
 			downloadURL, _ := url.Parse("http://unknown")
-			err = loader.DownloadAndUnpack(downloadURL, dest)
+			err = loader.DownloadAndUnpack(context.Background(), downloadURL, dest)
 
 			if err == nil {
 				t.Fatalf("Should have thrown an error, got none")
@@ -82,3 +94,491 @@ func TestDownloader(t *testing.T) {
 		})
 	})
 }
+
+func TestDownloaderTimeout(t *testing.T) {
+	t.Run("should throw if the download exceeds the timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			<-time.After(100 * time.Millisecond)
+			http.ServeFile(rw, req, "../fixtures/release.tar.gz")
+		}))
+		defer server.Close()
+		appFS := afero.NewMemMapFs()
+
+		loader := New(appFS)
+		loader.Timeout = 10 * time.Millisecond
+
+		serverURL, _ := url.Parse(server.URL)
+		err := loader.DownloadAndUnpack(context.Background(), serverURL, "/tmp/downloader_test")
+
+		if err != ErrDowloadPackageFailed {
+			t.Fatalf("Expected ErrDowloadPackageFailed, got %#v", err)
+		}
+	})
+}
+
+func TestDownloaderRetry(t *testing.T) {
+	t.Run("should retry a 503 response", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			requests++
+			if requests == 1 {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			http.ServeFile(rw, req, "../fixtures/release.tar.gz")
+		}))
+		defer server.Close()
+		appFS := afero.NewMemMapFs()
+
+		loader := New(appFS)
+		loader.Retry = retry.Policy{Attempts: 3, Min: time.Millisecond, Max: time.Millisecond}
+
+		serverURL, _ := url.Parse(server.URL)
+		err := loader.DownloadAndUnpack(context.Background(), serverURL, "/tmp/downloader_test")
+
+		if err != nil {
+			t.Fatalf("Should not have thrown an error, got %#v", err)
+		}
+		if requests != 2 {
+			t.Fatalf("Expected 2 requests, got %d", requests)
+		}
+	})
+
+	t.Run("should not retry a 404 response", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			requests++
+			rw.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		loader := New(afero.NewMemMapFs())
+		loader.Retry = retry.Policy{Attempts: 3, Min: time.Millisecond, Max: time.Millisecond}
+
+		serverURL, _ := url.Parse(server.URL)
+		err := loader.DownloadAndUnpack(context.Background(), serverURL, "/tmp/downloader_test")
+
+		if err != ErrDowloadPackageFailed {
+			t.Fatalf("Expected ErrDowloadPackageFailed, got %#v", err)
+		}
+		if requests != 1 {
+			t.Fatalf("Expected 1 request, got %d", requests)
+		}
+	})
+
+	t.Run("should report the attempts if all requests fail", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		loader := New(afero.NewMemMapFs())
+		loader.Retry = retry.Policy{Attempts: 2, Min: time.Millisecond, Max: time.Millisecond}
+
+		serverURL, _ := url.Parse(server.URL)
+		err := loader.DownloadAndUnpack(context.Background(), serverURL, "/tmp/downloader_test")
+
+		if errors.Cause(err) != ErrDowloadPackageFailed || !strings.Contains(err.Error(), "after 2 attempts") {
+			t.Fatalf("Expected ErrDowloadPackageFailed after 2 attempts, got %v", err)
+		}
+	})
+}
+
+func TestDownloaderUnpackFile(t *testing.T) {
+	t.Run("should unpack a local package file", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		payload, _ := ioutil.ReadFile("../fixtures/release.tar.gz")
+		afero.WriteFile(appFS, "/artifacts/release.tar.gz", payload, 0644)
+
+		loader := New(appFS)
+		err := loader.UnpackFile("/artifacts/release.tar.gz", "/ui-versions/2.25.2")
+
+		if err != nil {
+			t.Fatalf("Should not have thrown an error, got %#v", err)
+		}
+		if exists, _ := afero.Exists(appFS, "/ui-versions/2.25.2/README.md"); !exists {
+			t.Fatalf("Expected README.md to be unpacked")
+		}
+	})
+
+	t.Run("should throw if the package file is missing", func(t *testing.T) {
+		loader := New(afero.NewMemMapFs())
+		err := loader.UnpackFile("/artifacts/release.tar.gz", "/ui-versions/2.25.2")
+
+		if err != ErrReadingPackageFailed {
+			t.Fatalf("Expected ErrReadingPackageFailed, got %#v", err)
+		}
+	})
+}
+
+func TestDownloaderMemory(t *testing.T) {
+	t.Run("waits for the memory budget before extracting", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		payload, _ := ioutil.ReadFile("../fixtures/release.tar.gz")
+		afero.WriteFile(appFS, "/artifacts/release.tar.gz", payload, 0644)
+		loader := New(appFS)
+		loader.Memory = memory.NewAccountant(unpackMemory)
+		running, _ := loader.Memory.Reserve(context.Background(), "download", unpackMemory)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := loader.DownloadAndUnpack(ctx, &url.URL{Scheme: "file", Path: "/artifacts/release.tar.gz"}, "/ui-versions/2.25.2")
+
+		if err != context.DeadlineExceeded {
+			t.Fatalf("Expected the extraction to wait for the memory budget, got %#v", err)
+		}
+		running.Release()
+		if err := loader.DownloadAndUnpack(context.Background(), &url.URL{Scheme: "file", Path: "/artifacts/release.tar.gz"}, "/ui-versions/2.25.2"); err != nil {
+			t.Fatalf("Should not have thrown an error, got %#v", err)
+		}
+		if reserved := loader.Memory.Stats().Reserved; reserved != 0 {
+			t.Fatalf("Expected the memory to be released, %d bytes are reserved", reserved)
+		}
+	})
+}
+
+func TestDownloaderFileURL(t *testing.T) {
+	t.Run("should unpack a package referenced by a file URL", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		payload, _ := ioutil.ReadFile("../fixtures/release.tar.gz")
+		afero.WriteFile(appFS, "/artifacts/release.tar.gz", payload, 0644)
+
+		loader := New(appFS)
+		fileURL := &url.URL{Scheme: "file", Path: "/artifacts/release.tar.gz"}
+		err := loader.DownloadAndUnpack(context.Background(), fileURL, "/ui-versions/2.25.2")
+
+		if err != nil {
+			t.Fatalf("Should not have thrown an error, got %#v", err)
+		}
+		if exists, _ := afero.Exists(appFS, "/ui-versions/2.25.2/README.md"); !exists {
+			t.Fatalf("Expected README.md to be unpacked")
+		}
+	})
+}
+
+// makeTarGz creates a package with a directory entry for names ending in a slash and files otherwise
+func makeTarGz(t *testing.T, names ...string) []byte {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for _, name := range names {
+		header := &tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(name))}
+		if strings.HasSuffix(name, "/") {
+			header = &tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			tw.Write([]byte(name))
+		}
+	}
+	tw.Close()
+	gzw.Close()
+	return buf.Bytes()
+}
+
+func TestDownloaderExtractLimits(t *testing.T) {
+	t.Run("extracts a package within the limits", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		loader := New(appFS)
+		loader.MaxFiles = 2
+		loader.MaxDepth = 2
+
+		err := loader.extractTarGzToDir("/dest", bytes.NewReader(makeTarGz(t, "dist/", "dist/js/", "dist/index.html", "dist/js/app.js")))
+
+		if err != nil {
+			t.Fatalf("Should not have thrown an error, got %#v", err)
+		}
+		if exists, _ := afero.Exists(appFS, "/dest/dist/js/app.js"); !exists {
+			t.Fatalf("Expected app.js to be unpacked")
+		}
+	})
+
+	t.Run("should throw if the package has too many files", func(t *testing.T) {
+		loader := New(afero.NewMemMapFs())
+		loader.MaxFiles = 2
+
+		err := loader.extractTarGzToDir("/dest", bytes.NewReader(makeTarGz(t, "a", "b", "c")))
+
+		if err != ErrPackageTooManyFiles {
+			t.Fatalf("Expected ErrPackageTooManyFiles, got %#v", err)
+		}
+	})
+
+	t.Run("should throw if directories are nested too deep", func(t *testing.T) {
+		loader := New(afero.NewMemMapFs())
+		loader.MaxDepth = 2
+
+		err := loader.extractTarGzToDir("/dest", bytes.NewReader(makeTarGz(t, "a/", "a/b/", "a/b/c/")))
+
+		if err != ErrPackageTooDeep {
+			t.Fatalf("Expected ErrPackageTooDeep, got %#v", err)
+		}
+	})
+
+	t.Run("should throw if a file is nested too deep without directory entries", func(t *testing.T) {
+		loader := New(afero.NewMemMapFs())
+		loader.MaxDepth = 2
+
+		err := loader.extractTarGzToDir("/dest", bytes.NewReader(makeTarGz(t, "a/b/c/file")))
+
+		if err != ErrPackageTooDeep {
+			t.Fatalf("Expected ErrPackageTooDeep, got %#v", err)
+		}
+	})
+
+	t.Run("should throw if the package exceeds the maximum size", func(t *testing.T) {
+		loader := New(afero.NewMemMapFs())
+		loader.MaxSize = 10
+
+		err := loader.extractTarGzToDir("/dest", bytes.NewReader(makeTarGz(t, "dist/", "dist/index.html")))
+
+		if err != ErrPackageTooLarge {
+			t.Fatalf("Expected ErrPackageTooLarge, got %#v", err)
+		}
+	})
+
+	t.Run("should reject a download with a content length above the maximum size", func(t *testing.T) {
+		// the request is recorded before the response is written, the client cannot return before
+		requested := make(chan struct{}, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			select {
+			case requested <- struct{}{}:
+			default:
+			}
+			http.ServeFile(rw, req, "../fixtures/release.tar.gz")
+		}))
+		defer server.Close()
+		appFS := afero.NewMemMapFs()
+		loader := New(appFS)
+		loader.MaxSize = 10
+
+		serverURL, _ := url.Parse(server.URL)
+		err := loader.DownloadAndUnpack(context.Background(), serverURL, "/dest")
+
+		if err != ErrPackageTooLarge {
+			t.Fatalf("Expected ErrPackageTooLarge, got %#v", err)
+		}
+		select {
+		case <-requested:
+		default:
+			t.Fatalf("Expected the package to be requested")
+		}
+		if exists, _ := afero.Exists(appFS, "/dest/README.md"); exists {
+			t.Fatalf("Expected nothing to be unpacked")
+		}
+	})
+}
+
+func TestDownloaderProgress(t *testing.T) {
+	t.Run("reports the download progress", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			http.ServeFile(rw, req, "../fixtures/release.tar.gz")
+		}))
+		defer server.Close()
+		loader := New(afero.NewMemMapFs())
+		loader.ProgressInterval = 0
+		var reports []Progress
+		ctx := WithProgressCallback(context.Background(), func(progress Progress) {
+			reports = append(reports, progress)
+		})
+
+		serverURL, _ := url.Parse(server.URL)
+		if err := loader.DownloadAndUnpack(ctx, serverURL, "/dest"); err != nil {
+			t.Fatalf("Should not have thrown an error, got %#v", err)
+		}
+
+		if len(reports) < 2 {
+			t.Fatalf("Expected at least a first and a final report, got %#v", reports)
+		}
+		first, last := reports[0], reports[len(reports)-1]
+		if first.Downloaded != 0 || first.Total <= 0 {
+			t.Fatalf("Expected the first report before the body is read, got %#v", first)
+		}
+		if last.Downloaded != last.Total || last.Percent() != 100 {
+			t.Fatalf("Expected the final report to complete the download, got %#v", last)
+		}
+	})
+
+	t.Run("reports an unknown total as -1 percent", func(t *testing.T) {
+		progress := Progress{Downloaded: 10, Total: -1}
+
+		if progress.Percent() != -1 {
+			t.Fatalf("Expected -1, got %d", progress.Percent())
+		}
+		if (Progress{Downloaded: 25, Total: 100}).Percent() != 25 {
+			t.Fatalf("Expected 25 percent")
+		}
+	})
+}
+
+func TestDownloaderDigest(t *testing.T) {
+	fixture, _ := ioutil.ReadFile("../fixtures/release.tar.gz")
+	want := sha256.Sum256(fixture)
+
+	t.Run("records the checksum of a downloaded package", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			http.ServeFile(rw, req, "../fixtures/release.tar.gz")
+		}))
+		defer server.Close()
+		var digest string
+		ctx := WithDigestRecorder(context.Background(), func(d string) { digest = d })
+
+		serverURL, _ := url.Parse(server.URL)
+		if err := New(afero.NewMemMapFs()).DownloadAndUnpack(ctx, serverURL, "/dest"); err != nil {
+			t.Fatalf("Should not have thrown an error, got %#v", err)
+		}
+
+		if digest != "sha256:"+hex.EncodeToString(want[:]) {
+			t.Fatalf("Expected the checksum of the package, got %s", digest)
+		}
+	})
+
+	t.Run("records the checksum of a package file", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		afero.WriteFile(appFS, "/bundles/release.tar.gz", fixture, 0644)
+		var digest string
+		ctx := WithDigestRecorder(context.Background(), func(d string) { digest = d })
+
+		fileURL, _ := url.Parse("file:///bundles/release.tar.gz")
+		if err := New(appFS).DownloadAndUnpack(ctx, fileURL, "/dest"); err != nil {
+			t.Fatalf("Should not have thrown an error, got %#v", err)
+		}
+
+		if digest != "sha256:"+hex.EncodeToString(want[:]) {
+			t.Fatalf("Expected the checksum of the package, got %s", digest)
+		}
+	})
+
+	t.Run("copies the downloaded package", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			http.ServeFile(rw, req, "../fixtures/release.tar.gz")
+		}))
+		defer server.Close()
+		var archive bytes.Buffer
+		ctx := WithArchiveCopy(context.Background(), func() io.Writer {
+			archive.Reset()
+			return &archive
+		})
+
+		serverURL, _ := url.Parse(server.URL)
+		if err := New(afero.NewMemMapFs()).DownloadAndUnpack(ctx, serverURL, "/dest"); err != nil {
+			t.Fatalf("Should not have thrown an error, got %#v", err)
+		}
+
+		if !bytes.Equal(archive.Bytes(), fixture) {
+			t.Fatalf("Expected a copy of the package, got %d bytes", archive.Len())
+		}
+	})
+}
+
+func TestDownloaderVerifier(t *testing.T) {
+	fixture, _ := ioutil.ReadFile("../fixtures/release.tar.gz")
+	errUntrusted := errors.New("untrusted")
+
+	t.Run("unpacks a downloaded package once it was verified", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			http.ServeFile(rw, req, "../fixtures/release.tar.gz")
+		}))
+		defer server.Close()
+		appFS := afero.NewMemMapFs()
+		var verified []byte
+		ctx := WithVerifier(context.Background(), func(r io.Reader) error {
+			verified, _ = ioutil.ReadAll(r)
+			if exists, _ := afero.Exists(appFS, "/dest/README.md"); exists {
+				t.Fatalf("Expected the package to be verified before it is unpacked")
+			}
+			return nil
+		})
+
+		serverURL, _ := url.Parse(server.URL)
+		if err := New(appFS).DownloadAndUnpack(ctx, serverURL, "/dest"); err != nil {
+			t.Fatalf("Should not have thrown an error, got %#v", err)
+		}
+
+		if !bytes.Equal(verified, fixture) {
+			t.Fatalf("Expected the package to be verified, got %d bytes", len(verified))
+		}
+		if exists, _ := afero.Exists(appFS, "/dest/README.md"); !exists {
+			t.Fatalf("Expected the verified package to be unpacked")
+		}
+	})
+
+	t.Run("does not unpack a downloaded package failing verification", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			http.ServeFile(rw, req, "../fixtures/release.tar.gz")
+		}))
+		defer server.Close()
+		appFS := afero.NewMemMapFs()
+		ctx := WithVerifier(context.Background(), func(io.Reader) error { return errUntrusted })
+
+		serverURL, _ := url.Parse(server.URL)
+		err := New(appFS).DownloadAndUnpack(ctx, serverURL, "/dest")
+
+		if err != errUntrusted {
+			t.Fatalf("Expected the verification error, got %#v", err)
+		}
+		if exists, _ := afero.Exists(appFS, "/dest/README.md"); exists {
+			t.Fatalf("Expected the package not to be unpacked")
+		}
+	})
+
+	t.Run("does not unpack a package file failing verification", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		afero.WriteFile(appFS, "/bundles/release.tar.gz", fixture, 0644)
+		ctx := WithVerifier(context.Background(), func(io.Reader) error { return errUntrusted })
+
+		fileURL, _ := url.Parse("file:///bundles/release.tar.gz")
+		err := New(appFS).DownloadAndUnpack(ctx, fileURL, "/dest")
+
+		if err != errUntrusted {
+			t.Fatalf("Expected the verification error, got %#v", err)
+		}
+		if exists, _ := afero.Exists(appFS, "/dest/README.md"); exists {
+			t.Fatalf("Expected the package not to be unpacked")
+		}
+	})
+}
+
+func TestDownloaderFetch(t *testing.T) {
+	t.Run("downloads a small file", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			io.WriteString(rw, "signature")
+		}))
+		defer server.Close()
+
+		serverURL, _ := url.Parse(server.URL)
+		data, err := New(afero.NewMemMapFs()).Fetch(context.Background(), serverURL)
+
+		if err != nil || string(data) != "signature" {
+			t.Fatalf("Expected the file content, got %q, %#v", data, err)
+		}
+	})
+
+	t.Run("fails for a missing file", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		serverURL, _ := url.Parse(server.URL)
+		_, err := New(afero.NewMemMapFs()).Fetch(context.Background(), serverURL)
+
+		if err != ErrDowloadPackageFailed {
+			t.Fatalf("Expected ErrDowloadPackageFailed, got %#v", err)
+		}
+	})
+
+	t.Run("fails for a file larger than the limit", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Write(make([]byte, maxFetchSize+1))
+		}))
+		defer server.Close()
+
+		serverURL, _ := url.Parse(server.URL)
+		_, err := New(afero.NewMemMapFs()).Fetch(context.Background(), serverURL)
+
+		if err != ErrPackageTooLarge {
+			t.Fatalf("Expected ErrPackageTooLarge, got %#v", err)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package downloader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+)
+
+type digestRecorderKey struct{}
+
+type archiveCopyKey struct{}
+
+// WithDigestRecorder returns a context calling record with the SHA-256 checksum of a package, formatted as
+// "sha256:<hex>", once it was downloaded and unpacked completely
+func WithDigestRecorder(ctx context.Context, record func(digest string)) context.Context {
+	return context.WithValue(ctx, digestRecorderKey{}, record)
+}
+
+// RecordDigest calls the digest recorder of the context, if any
+func RecordDigest(ctx context.Context, digest string) {
+	if record, ok := ctx.Value(digestRecorderKey{}).(func(string)); ok {
+		record(digest)
+	}
+}
+
+// WithArchiveCopy returns a context writing a copy of the package to the writer returned by create while it
+// is downloaded. create is called before each download attempt, the copy is complete once the digest of the
+// package was recorded. Errors of the writer don't fail the download, they have to be tracked by the writer.
+func WithArchiveCopy(ctx context.Context, create func() io.Writer) context.Context {
+	return context.WithValue(ctx, archiveCopyKey{}, create)
+}
+
+// ArchiveCopy starts a copy of the package for the archive copy of the context, it returns ioutil.Discard
+// if the context has none
+func ArchiveCopy(ctx context.Context) io.Writer {
+	if create, ok := ctx.Value(archiveCopyKey{}).(func() io.Writer); ok {
+		return create()
+	}
+	return ioutil.Discard
+}
+
+// digestReader hashes the bytes read from r
+type digestReader struct {
+	r    io.Reader
+	hash hash.Hash
+}
+
+// newDigestReader hashes the bytes read from r and copies them to the archive copy of the context, if any
+func newDigestReader(ctx context.Context, r io.Reader) *digestReader {
+	hash := sha256.New()
+	return &digestReader{r: io.TeeReader(r, io.MultiWriter(hash, ArchiveCopy(ctx))), hash: hash}
+}
+
+func (d *digestReader) Read(p []byte) (int, error) {
+	return d.r.Read(p)
+}
+
+// record reads the padding after the end of the archive and records the checksum of the package, it is
+// not recorded if more than maxTrailingBytes follow the archive
+func (d *digestReader) record(ctx context.Context) {
+	if _, err := io.CopyN(ioutil.Discard, d.r, maxTrailingBytes); err != io.EOF {
+		return
+	}
+	RecordDigest(ctx, "sha256:"+hex.EncodeToString(d.hash.Sum(nil)))
+}
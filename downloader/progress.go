@@ -0,0 +1,96 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+type unpackNotifierKey struct{}
+
+// WithUnpackNotifier returns a context calling notify once a package was downloaded and unpacking starts
+func WithUnpackNotifier(ctx context.Context, notify func()) context.Context {
+	return context.WithValue(ctx, unpackNotifierKey{}, notify)
+}
+
+// NotifyUnpacking calls the notifier of the context, if any
+func NotifyUnpacking(ctx context.Context) {
+	if notify, ok := ctx.Value(unpackNotifierKey{}).(func()); ok {
+		notify()
+	}
+}
+
+// DefaultProgressInterval is the minimum time between two progress reports of a download
+const DefaultProgressInterval = time.Second
+
+// Progress is the state of a package download
+type Progress struct {
+	// Downloaded is the number of bytes received so far
+	Downloaded int64
+	// Total is the size of the package in bytes, -1 if the server did not send a content length
+	Total int64
+}
+
+// Percent is the downloaded share of the package, -1 if the total is unknown
+func (p Progress) Percent() int {
+	if p.Total <= 0 {
+		return -1
+	}
+	if p.Downloaded >= p.Total {
+		return 100
+	}
+	return int(p.Downloaded * 100 / p.Total)
+}
+
+type progressCallbackKey struct{}
+
+// WithProgressCallback returns a context calling report with the progress of a package download. It is
+// called when the response was received, at most once per ProgressInterval of the client while the body
+// is read and once the download completed.
+func WithProgressCallback(ctx context.Context, report func(Progress)) context.Context {
+	return context.WithValue(ctx, progressCallbackKey{}, report)
+}
+
+// ReportProgress calls the progress callback of the context, if any
+func ReportProgress(ctx context.Context, progress Progress) {
+	if report, ok := ctx.Value(progressCallbackKey{}).(func(Progress)); ok {
+		report(progress)
+	}
+}
+
+// progressReader reports the bytes read from r to the progress callback of ctx
+type progressReader struct {
+	r        io.Reader
+	ctx      context.Context
+	interval time.Duration
+	progress Progress
+	reported time.Time
+}
+
+func newProgressReader(ctx context.Context, r io.Reader, total int64, interval time.Duration) *progressReader {
+	p := &progressReader{r: r, ctx: ctx, interval: interval, progress: Progress{Total: total}}
+	p.report()
+	return p
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.progress.Downloaded += int64(n)
+	if n > 0 && time.Since(p.reported) >= p.interval {
+		p.report()
+	}
+	return n, err
+}
+
+func (p *progressReader) report() {
+	p.reported = time.Now()
+	ReportProgress(p.ctx, p.progress)
+}
+
+// finish reports the final progress, the last read may have been within the interval of the previous report
+func (p *progressReader) finish() {
+	if p.progress.Total < 0 {
+		p.progress.Total = p.progress.Downloaded
+	}
+	p.report()
+}
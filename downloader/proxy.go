@@ -0,0 +1,23 @@
+package downloader
+
+import (
+	"net/http"
+
+	"github.com/dcos/dcos-ui-update-service/auth"
+	"github.com/dcos/dcos-ui-update-service/proxy"
+)
+
+// UseProxy routes package downloads through the proxies of settings. Without a proxy for the scheme of a
+// bundle URL it is downloaded through the proxy of the HTTPS_PROXY / HTTP_PROXY environment variables if
+// fromEnvironment is set, directly otherwise. Hosts matching an entry of settings.NoProxy are downloaded directly.
+func (d *Client) UseProxy(settings proxy.Settings, fromEnvironment bool) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = settings.Func(fromEnvironment)
+	d.client.Transport = transport
+}
+
+// UseCredentials authenticates downloads from hosts with the auth token of credentials, downloads
+// from other hosts are sent without token. It must be called after UseProxy.
+func (d *Client) UseCredentials(credentials *auth.CredentialProvider, hosts ...string) {
+	d.client.Transport = auth.NewTransport(d.client.Transport, credentials, hosts...)
+}
@@ -0,0 +1,34 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/proxy"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/spf13/afero"
+)
+
+func TestDownloaderUseProxy(t *testing.T) {
+	t.Run("downloads through the configured proxy", func(t *testing.T) {
+		var proxiedHost string
+		proxyServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			proxiedHost = req.URL.Host
+			http.ServeFile(rw, req, "../fixtures/release.tar.gz")
+		}))
+		defer proxyServer.Close()
+
+		loader := New(afero.NewMemMapFs())
+		proxyURL, _ := url.Parse(proxyServer.URL)
+		loader.UseProxy(proxy.Settings{HTTP: proxyURL}, false)
+
+		bundleURL, _ := url.Parse("http://bundles.external/dcos-ui.tar.gz")
+		err := loader.DownloadAndUnpack(context.Background(), bundleURL, "/dest")
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(proxiedHost, "bundles.external")
+	})
+}
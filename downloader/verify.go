@@ -0,0 +1,74 @@
+package downloader
+
+import (
+	"context"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+type verifierKey struct{}
+
+// WithVerifier returns a context checking each package with verify before it is unpacked. A downloaded
+// package is staged in a temporary file until it was verified, it is not unpacked if verify fails.
+func WithVerifier(ctx context.Context, verify func(io.Reader) error) context.Context {
+	return context.WithValue(ctx, verifierKey{}, verify)
+}
+
+// VerifyPackage checks the package read from r with the verifier of the context, nil if it has none
+func VerifyPackage(ctx context.Context, r io.Reader) error {
+	if verify, ok := ctx.Value(verifierKey{}).(func(io.Reader) error); ok {
+		return verify(r)
+	}
+	return nil
+}
+
+func hasVerifier(ctx context.Context) bool {
+	_, ok := ctx.Value(verifierKey{}).(func(io.Reader) error)
+	return ok
+}
+
+// stageVerified copies the package read from body to a temporary file and verifies it, the returned file is
+// positioned at its start. The caller closes and removes it.
+func (d *Client) stageVerified(ctx context.Context, body io.Reader) (afero.File, error) {
+	staged, err := afero.TempFile(d.Fs, "", "dcos-ui-bundle-")
+	if err != nil {
+		logrus.WithError(err).Error("Failed to create the staging file of the package")
+		return nil, ErrCreatingFileWhileUnpacking
+	}
+	limited := &sizeLimitedReader{r: body, limit: d.MaxSize}
+	if _, err := io.Copy(staged, limited); err != nil {
+		d.removeStaged(staged)
+		if limited.exceeded {
+			return nil, ErrPackageTooLarge
+		}
+		logrus.WithError(err).Error("Failed to download the package to its staging file")
+		return nil, ErrDowloadPackageFailed
+	}
+	if err := d.verifyFile(ctx, staged); err != nil {
+		d.removeStaged(staged)
+		return nil, err
+	}
+	return staged, nil
+}
+
+// verifyFile verifies the package in file and rewinds it
+func (d *Client) verifyFile(ctx context.Context, file afero.File) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return ErrReadingPackageFailed
+	}
+	if err := VerifyPackage(ctx, file); err != nil {
+		logrus.WithError(err).Error("Package verification failed")
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return ErrReadingPackageFailed
+	}
+	return nil
+}
+
+func (d *Client) removeStaged(staged afero.File) {
+	staged.Close()
+	d.Fs.Remove(staged.Name())
+}
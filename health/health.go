@@ -0,0 +1,112 @@
+// Package health tracks the outcome of the recent operations of each subsystem,
+// so the health endpoint can report more than a single boolean.
+package health
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+)
+
+// Subsystems reported by the health endpoint
+const (
+	Cosmos       = "cosmos"
+	Downloader   = "downloader"
+	Zookeeper    = "zookeeper"
+	VersionStore = "versionstore"
+	Filesystem   = "filesystem"
+)
+
+// FailingThreshold is the number of consecutive failures after which a subsystem is failing instead of degraded
+const FailingThreshold = 3
+
+// State summarizes the recent outcomes of a subsystem
+type State string
+
+const (
+	// StateUnknown is reported until the first operation of a subsystem completed
+	StateUnknown State = "unknown"
+	// StateOK is reported if the last operation succeeded
+	StateOK State = "ok"
+	// StateDegraded is reported if less than FailingThreshold operations failed in a row
+	StateDegraded State = "degraded"
+	// StateFailing is reported if at least FailingThreshold operations failed in a row
+	StateFailing State = "failing"
+)
+
+// Status of a subsystem
+type Status struct {
+	State               State      `json:"state"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastErrorTime       *time.Time `json:"lastErrorTime,omitempty"`
+	LastSuccess         *time.Time `json:"lastSuccess,omitempty"`
+}
+
+// DefaultTracker is the tracker reported by the health endpoint
+var DefaultTracker = NewTracker()
+
+// Tracker keeps the Status of each subsystem
+type Tracker struct {
+	clock    clock.Clock
+	statuses map[string]*Status
+	sync.Mutex
+}
+
+// NewTracker creates a Tracker without any recorded outcomes
+func NewTracker() *Tracker {
+	return newTracker(clock.New())
+}
+
+func newTracker(clk clock.Clock) *Tracker {
+	return &Tracker{
+		clock:    clk,
+		statuses: make(map[string]*Status),
+	}
+}
+
+// Record stores the outcome of an operation of the subsystem, a nil err records a success
+func (t *Tracker) Record(subsystem string, err error) {
+	t.Lock()
+	defer t.Unlock()
+	status, ok := t.statuses[subsystem]
+	if !ok {
+		status = &Status{}
+		t.statuses[subsystem] = status
+	}
+
+	now := t.clock.Now()
+	if err == nil {
+		status.State = StateOK
+		status.ConsecutiveFailures = 0
+		status.LastSuccess = &now
+		return
+	}
+	status.ConsecutiveFailures++
+	status.LastError = err.Error()
+	status.LastErrorTime = &now
+	status.State = StateDegraded
+	if status.ConsecutiveFailures >= FailingThreshold {
+		status.State = StateFailing
+	}
+}
+
+// Status returns the Status of the subsystem, StateUnknown if nothing was recorded yet
+func (t *Tracker) Status(subsystem string) Status {
+	t.Lock()
+	defer t.Unlock()
+	if status, ok := t.statuses[subsystem]; ok {
+		return *status
+	}
+	return Status{State: StateUnknown}
+}
+
+// Snapshot returns the Status of each of the given subsystems
+func (t *Tracker) Snapshot(subsystems ...string) map[string]Status {
+	result := make(map[string]Status, len(subsystems))
+	for _, subsystem := range subsystems {
+		result[subsystem] = t.Status(subsystem)
+	}
+	return result
+}
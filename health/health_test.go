@@ -0,0 +1,57 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestTracker(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("reports unknown before the first outcome", func(t *testing.T) {
+		tracker := newTracker(clock.NewFake(start))
+
+		status := tracker.Status(Cosmos)
+		tests.H(t).StringEql(string(status.State), string(StateUnknown))
+		tests.H(t).BoolEql(status.LastSuccess == nil, true)
+	})
+
+	t.Run("counts consecutive failures until the next success", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		tracker := newTracker(clk)
+
+		tracker.Record(Cosmos, errors.New("connection refused"))
+		tests.H(t).StringEql(string(tracker.Status(Cosmos).State), string(StateDegraded))
+
+		tracker.Record(Cosmos, errors.New("connection refused"))
+		clk.Advance(time.Minute)
+		tracker.Record(Cosmos, errors.New("timeout"))
+		status := tracker.Status(Cosmos)
+		tests.H(t).StringEql(string(status.State), string(StateFailing))
+		tests.H(t).IntEql(status.ConsecutiveFailures, 3)
+		tests.H(t).StringEql(status.LastError, "timeout")
+		tests.H(t).BoolEql(status.LastErrorTime.Equal(start.Add(time.Minute)), true)
+
+		clk.Advance(time.Minute)
+		tracker.Record(Cosmos, nil)
+		status = tracker.Status(Cosmos)
+		tests.H(t).StringEql(string(status.State), string(StateOK))
+		tests.H(t).IntEql(status.ConsecutiveFailures, 0)
+		tests.H(t).StringEql(status.LastError, "timeout")
+		tests.H(t).BoolEql(status.LastSuccess.Equal(start.Add(2*time.Minute)), true)
+	})
+
+	t.Run("Snapshot includes all requested subsystems", func(t *testing.T) {
+		tracker := newTracker(clock.NewFake(start))
+		tracker.Record(Zookeeper, nil)
+
+		snapshot := tracker.Snapshot(Zookeeper, Downloader)
+		tests.H(t).IntEql(len(snapshot), 2)
+		tests.H(t).StringEql(string(snapshot[Zookeeper].State), string(StateOK))
+		tests.H(t).StringEql(string(snapshot[Downloader].State), string(StateUnknown))
+	})
+}
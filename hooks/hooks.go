@@ -0,0 +1,196 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Point identifies when in the version lifecycle a hook is run
+type Point string
+
+const (
+	// PreDownload hooks run before a new version is downloaded, a failure aborts the update
+	PreDownload Point = "pre-download"
+	// PreActivate hooks run before a downloaded version is served, a failure aborts the update
+	PreActivate Point = "pre-activate"
+	// PostActivate hooks run after a new version is served
+	PostActivate Point = "post-activate"
+	// PostRollback hooks run after a failed update has been rolled back
+	PostRollback Point = "post-rollback"
+	// DefaultChanged hooks run after the pre-bundled ui was replaced on disk, e.g. by a DC/OS upgrade
+	DefaultChanged Point = "default-changed"
+)
+
+// Event is the payload passed to hooks, executables receive it as JSON on stdin
+type Event struct {
+	Point           Point     `json:"point"`
+	Version         string    `json:"version"`
+	PreviousVersion string    `json:"previousVersion"`
+	Path            string    `json:"path,omitempty"`
+	Time            time.Time `json:"time"`
+}
+
+// Hook is an action attached to a hook point
+type Hook interface {
+	Name() string
+	Run(ctx context.Context, event Event) error
+}
+
+// Result is the outcome of running a single hook
+type Result struct {
+	Name     string `json:"name"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// HookFunc adapts a go function to a Hook, used to register in process plugins
+type HookFunc struct {
+	HookName string
+	Func     func(ctx context.Context, event Event) error
+}
+
+// Name returns the name of the hook
+func (h HookFunc) Name() string {
+	return h.HookName
+}
+
+// Run calls the wrapped function
+func (h HookFunc) Run(ctx context.Context, event Event) error {
+	return h.Func(ctx, event)
+}
+
+type executableHook struct {
+	path string
+}
+
+func (h executableHook) Name() string {
+	return h.path
+}
+
+func (h executableHook) Run(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "could not create json event for hook")
+	}
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, h.path)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "hook did not complete in time")
+		}
+		return errors.Wrapf(err, "hook failed with output: %s", output.String())
+	}
+	return nil
+}
+
+// Registry holds the hooks registered for each hook point
+type Registry struct {
+	timeout time.Duration
+	hooks   map[Point][]Hook
+	sync.Mutex
+}
+
+// New creates a Registry with the executables configured for each hook point, the post-activate URLs are
+// called and the configured systemd units are reloaded or restarted after the post-activate executables
+func New(cfg *config.Config) *Registry {
+	r := NewRegistry(cfg.HookTimeout())
+	for point, executables := range map[Point][]string{
+		PreDownload:    cfg.PreDownloadHooks(),
+		PreActivate:    cfg.PreActivateHooks(),
+		PostActivate:   cfg.PostActivateHooks(),
+		PostRollback:   cfg.PostRollbackHooks(),
+		DefaultChanged: cfg.DefaultChangedHooks(),
+	} {
+		for _, path := range executables {
+			r.Register(point, executableHook{path: path})
+		}
+	}
+	for _, hookURL := range cfg.PostActivateURLs() {
+		r.Register(PostActivate, urlHook{url: hookURL, client: http.DefaultClient})
+	}
+	for _, unit := range cfg.ReloadUnits() {
+		r.Register(PostActivate, unitHook{action: UnitReload, unit: unit})
+	}
+	for _, unit := range cfg.RestartUnits() {
+		r.Register(PostActivate, unitHook{action: UnitRestart, unit: unit})
+	}
+	return r
+}
+
+// NewRegistry creates an empty Registry, each hook is given at most timeout to complete
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{
+		timeout: timeout,
+		hooks:   make(map[Point][]Hook),
+	}
+}
+
+// Register adds a hook to be run at the given hook point
+func (r *Registry) Register(point Point, hook Hook) {
+	r.Lock()
+	defer r.Unlock()
+	r.hooks[point] = append(r.hooks[point], hook)
+}
+
+// Run runs all hooks registered for the event's point in registration order.
+// Every hook is run, the returned error is the first failure encountered.
+func (r *Registry) Run(event Event) ([]Result, error) {
+	return r.RunContext(context.Background(), event)
+}
+
+// RunContext runs the hooks like Run, each hook's context is also done when ctx is done
+func (r *Registry) RunContext(ctx context.Context, event Event) ([]Result, error) {
+	if r == nil {
+		return []Result{}, nil
+	}
+	r.Lock()
+	hooks := append([]Hook(nil), r.hooks[event.Point]...)
+	r.Unlock()
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	var firstErr error
+	results := make([]Result, 0, len(hooks))
+	for _, hook := range hooks {
+		result, err := r.runHook(ctx, hook, event)
+		if err != nil && firstErr == nil {
+			firstErr = errors.Wrapf(err, "%s hook %s failed", event.Point, hook.Name())
+		}
+		results = append(results, result)
+	}
+	return results, firstErr
+}
+
+func (r *Registry) runHook(parent context.Context, hook Hook, event Event) (result Result, err error) {
+	logger := logrus.WithFields(logrus.Fields{"hook": hook.Name(), "point": event.Point, "version": event.Version})
+	ctx, cancel := context.WithTimeout(parent, r.timeout)
+	defer cancel()
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			err = errors.Errorf("hook panicked: %v", p)
+		}
+		result = Result{Name: hook.Name(), Duration: time.Since(start).String()}
+		if err != nil {
+			result.Error = err.Error()
+			logger.WithError(err).Warn("Hook failed")
+		} else {
+			logger.Debug("Hook completed")
+		}
+	}()
+	err = hook.Run(ctx, event)
+	return result, err
+}
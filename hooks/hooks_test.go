@@ -0,0 +1,130 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("runs registered hooks for the event point only", func(t *testing.T) {
+		r := NewRegistry(time.Second)
+		var called []string
+		r.Register(PreActivate, HookFunc{"pre", func(ctx context.Context, e Event) error {
+			called = append(called, "pre:"+e.Version)
+			return nil
+		}})
+		r.Register(PostActivate, HookFunc{"post", func(ctx context.Context, e Event) error {
+			called = append(called, "post")
+			return nil
+		}})
+
+		results, err := r.Run(Event{Point: PreActivate, Version: "2.24.4"})
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(len(results), 1)
+		tests.H(t).InterfaceEql(called, []string{"pre:2.24.4"})
+	})
+
+	t.Run("runs all hooks and returns the first error", func(t *testing.T) {
+		r := NewRegistry(time.Second)
+		secondCalled := false
+		r.Register(PostActivate, HookFunc{"failing", func(ctx context.Context, e Event) error {
+			return errors.New("purge failed")
+		}})
+		r.Register(PostActivate, HookFunc{"second", func(ctx context.Context, e Event) error {
+			secondCalled = true
+			return nil
+		}})
+
+		results, err := r.Run(Event{Point: PostActivate})
+		tests.H(t).NotNil(err)
+		tests.H(t).StringContains(err.Error(), "purge failed")
+		tests.H(t).BoolEql(secondCalled, true)
+		tests.H(t).StringEql(results[0].Error, "purge failed")
+		tests.H(t).StringEql(results[1].Error, "")
+	})
+
+	t.Run("recovers from panicking hooks", func(t *testing.T) {
+		r := NewRegistry(time.Second)
+		r.Register(PreDownload, HookFunc{"panics", func(ctx context.Context, e Event) error {
+			panic("boom")
+		}})
+
+		_, err := r.Run(Event{Point: PreDownload})
+		tests.H(t).NotNil(err)
+		tests.H(t).StringContains(err.Error(), "boom")
+	})
+
+	t.Run("cancels the hooks when the context is done", func(t *testing.T) {
+		r := NewRegistry(time.Minute)
+		r.Register(PostActivate, HookFunc{"blocking", func(ctx context.Context, e Event) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		results, err := r.RunContext(ctx, Event{Point: PostActivate})
+		tests.H(t).NotNil(err)
+		tests.H(t).StringContains(results[0].Error, "context canceled")
+	})
+
+	t.Run("nil registry runs nothing", func(t *testing.T) {
+		var r *Registry
+		results, err := r.Run(Event{Point: PreDownload})
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(len(results), 0)
+	})
+}
+
+func TestExecutableHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hooks_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeScript := func(name, body string) string {
+		p := path.Join(dir, name)
+		if err := ioutil.WriteFile(p, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	t.Run("passes the event as json on stdin", func(t *testing.T) {
+		out := path.Join(dir, "event.json")
+		r := NewRegistry(time.Second)
+		r.Register(PostActivate, executableHook{path: writeScript("capture.sh", "cat > "+out)})
+
+		_, err := r.Run(Event{Point: PostActivate, Version: "2.24.4"})
+		tests.H(t).IsNil(err)
+
+		data, _ := ioutil.ReadFile(out)
+		tests.H(t).StringContains(string(data), `"version":"2.24.4"`)
+	})
+
+	t.Run("fails if the executable exits non-zero", func(t *testing.T) {
+		r := NewRegistry(time.Second)
+		r.Register(PostActivate, executableHook{path: writeScript("fail.sh", "echo nope; exit 3")})
+
+		_, err := r.Run(Event{Point: PostActivate})
+		tests.H(t).NotNil(err)
+		tests.H(t).StringContains(err.Error(), "nope")
+	})
+
+	t.Run("fails if the executable exceeds the timeout", func(t *testing.T) {
+		r := NewRegistry(50 * time.Millisecond)
+		r.Register(PostActivate, executableHook{path: writeScript("slow.sh", "exec sleep 2")})
+
+		_, err := r.Run(Event{Point: PostActivate})
+		tests.H(t).NotNil(err)
+		tests.H(t).StringContains(err.Error(), "did not complete in time")
+	})
+}
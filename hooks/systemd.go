@@ -0,0 +1,44 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// Actions of systemd unit hooks
+const (
+	UnitReload  = "reload"
+	UnitRestart = "restart"
+)
+
+// systemctlPath is the systemctl executable asking systemd to reload or restart units
+var systemctlPath = "systemctl"
+
+// unitHook reloads or restarts a systemd unit, e.g. a proxy that keeps file handles of the
+// previously served dist directory open. Only units listed in the config are registered.
+type unitHook struct {
+	action string
+	unit   string
+}
+
+func (h unitHook) Name() string {
+	return "systemctl " + h.action + " " + h.unit
+}
+
+func (h unitHook) Run(ctx context.Context, event Event) error {
+	var output bytes.Buffer
+	// "--" ends the options, so a unit name is never interpreted as one
+	cmd := exec.CommandContext(ctx, systemctlPath, h.action, "--", h.unit)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return errors.Wrapf(ctx.Err(), "%s of unit %s did not complete in time", h.action, h.unit)
+		}
+		return errors.Wrapf(err, "%s of unit %s failed with output: %s", h.action, h.unit, output.String())
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package hooks
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestUnitHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hooks_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// fakeSystemctl replaces systemctl with a script appending its arguments to calls until restore is called
+	fakeSystemctl := func(t *testing.T, body string) (calls string, restore func()) {
+		calls = path.Join(dir, t.Name()+".calls")
+		os.MkdirAll(path.Dir(calls), 0755)
+		script := path.Join(dir, t.Name()+".sh")
+		err := ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" >> "+calls+"\n"+body), 0755)
+		tests.H(t).IsNil(err)
+		previous := systemctlPath
+		systemctlPath = script
+		return calls, func() { systemctlPath = previous }
+	}
+
+	t.Run("reloads and restarts the configured units after activation", func(t *testing.T) {
+		calls, restore := fakeSystemctl(t, "")
+		defer restore()
+		cfg, err := config.Parse([]string{
+			"--reload-units", "dcos-adminrouter.service",
+			"--restart-units", "dcos-ui-cache.service",
+		})
+		tests.H(t).IsNil(err)
+
+		results, err := New(cfg).Run(Event{Point: PostActivate, Version: "2.24.4"})
+		tests.H(t).IsNil(err)
+
+		tests.H(t).IntEql(len(results), 2)
+		tests.H(t).StringEql(results[0].Name, "systemctl reload dcos-adminrouter.service")
+		data, _ := ioutil.ReadFile(calls)
+		tests.H(t).StringEql(string(data), "reload -- dcos-adminrouter.service\nrestart -- dcos-ui-cache.service\n")
+	})
+
+	t.Run("does not touch units before activation", func(t *testing.T) {
+		calls, restore := fakeSystemctl(t, "")
+		defer restore()
+		cfg, _ := config.Parse([]string{"--reload-units", "dcos-adminrouter.service"})
+
+		_, err := New(cfg).Run(Event{Point: PreActivate})
+		tests.H(t).IsNil(err)
+
+		_, statErr := os.Stat(calls)
+		tests.H(t).BoolEql(os.IsNotExist(statErr), true)
+	})
+
+	t.Run("fails with the systemctl output", func(t *testing.T) {
+		_, restore := fakeSystemctl(t, "echo 'Unit dcos-adminrouter.service not loaded.'; exit 5")
+		defer restore()
+		r := NewRegistry(time.Second)
+		r.Register(PostActivate, unitHook{action: UnitReload, unit: "dcos-adminrouter.service"})
+
+		_, err := r.Run(Event{Point: PostActivate})
+		tests.H(t).NotNil(err)
+		tests.H(t).StringContains(err.Error(), "reload of unit dcos-adminrouter.service failed")
+		tests.H(t).StringContains(err.Error(), "not loaded")
+	})
+}
@@ -0,0 +1,58 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// maxURLHookResponse is the part of an error response included in the hook error
+const maxURLHookResponse = 512
+
+// urlHook POSTs the event as JSON to a URL, e.g. an endpoint making Admin Router or another reverse proxy
+// purge the pages it cached for the previously served version. Any status other than 2xx fails the hook.
+type urlHook struct {
+	url    string
+	client *http.Client
+}
+
+// Name returns the URL without credentials, so they are not logged or returned in results
+func (h urlHook) Name() string {
+	parsed, err := url.Parse(h.url)
+	if err != nil {
+		return "POST <invalid url>"
+	}
+	parsed.User = nil
+	return "POST " + parsed.String()
+}
+
+func (h urlHook) Run(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "could not create json event for hook")
+	}
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(payload))
+	if err != nil {
+		return errors.Wrap(err, "could not create the hook request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "hook did not complete in time")
+		}
+		return errors.Wrap(err, "hook request failed")
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxURLHookResponse))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("hook responded with %s: %s", resp.Status, string(body))
+	}
+	return nil
+}
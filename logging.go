@@ -1,11 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 
 	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/logring"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -14,16 +16,41 @@ type logWriterHook struct {
 	LogLevels []log.Level
 }
 
-func initLogging(config *config.Config) {
+func initLogging(config *config.Config) (*logring.Ring, error) {
 	setupSplitLogging()
 
+	// Keep recent warnings and errors for the logs endpoint
+	ring := logring.New(config.LogBufferSize())
+	if ring != nil {
+		log.AddHook(ring)
+	}
+
+	formatter, err := newFormatter(config.LogFormat())
+	if err != nil {
+		return nil, err
+	}
+	log.SetFormatter(formatter)
+
 	// Set logging level
 	lvl, err := log.ParseLevel(config.LogLevel())
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	log.SetLevel(lvl)
 	log.Infof("Logging set to: %s", config.LogLevel())
+	return ring, nil
+}
+
+// newFormatter returns the logrus formatter for the configured log format. The json formatter writes one
+// object per line, so fields like operation, version and zk-node can be indexed without parsing the message.
+func newFormatter(format string) (log.Formatter, error) {
+	switch format {
+	case "text":
+		return &log.TextFormatter{}, nil
+	case "json":
+		return &log.JSONFormatter{}, nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
 }
 
 func setupSplitLogging() {
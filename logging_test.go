@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestNewFormatter(t *testing.T) {
+	t.Run("json writes the fields as keys", func(t *testing.T) {
+		formatter, err := newFormatter("json")
+		tests.H(t).IsNil(err)
+		logger := log.New()
+		entry := logger.WithFields(log.Fields{"operation": "update", "version": "2.25.0"})
+		entry.Message = "Cluster operation finished"
+
+		line, err := formatter.Format(entry)
+
+		tests.H(t).IsNil(err)
+		var record map[string]interface{}
+		tests.H(t).IsNil(json.Unmarshal(line, &record))
+		tests.H(t).InterfaceEql(record["operation"], "update")
+		tests.H(t).InterfaceEql(record["version"], "2.25.0")
+		tests.H(t).InterfaceEql(record["msg"], "Cluster operation finished")
+	})
+
+	t.Run("returns an error for an unknown format", func(t *testing.T) {
+		_, err := newFormatter("xml")
+
+		tests.H(t).NotNil(err)
+	})
+}
@@ -0,0 +1,99 @@
+// Package logring keeps the most recent warning and error log records in memory,
+// so they can be retrieved through the API when the journal of a master is not accessible.
+package logring
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Record is a log entry kept in the ring
+type Record struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+	level   logrus.Level
+}
+
+// Ring is a logrus hook keeping the last records with level warning or higher severity
+type Ring struct {
+	records []Record
+	next    int
+	full    bool
+	sync.Mutex
+}
+
+// New creates a Ring keeping size records, returns nil if size is not positive
+func New(size int) *Ring {
+	if size <= 0 {
+		return nil
+	}
+	return &Ring{
+		records: make([]Record, size),
+	}
+}
+
+// Levels returns the levels the Ring keeps records of
+func (r *Ring) Levels() []logrus.Level {
+	return []logrus.Level{
+		logrus.PanicLevel,
+		logrus.FatalLevel,
+		logrus.ErrorLevel,
+		logrus.WarnLevel,
+	}
+}
+
+// Fire stores the entry, replacing the oldest record once the ring is full
+func (r *Ring) Fire(entry *logrus.Entry) error {
+	record := Record{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Message: entry.Message,
+		level:   entry.Level,
+	}
+	if len(entry.Data) > 0 {
+		record.Fields = make(map[string]interface{}, len(entry.Data))
+		for key, value := range entry.Data {
+			if err, ok := value.(error); ok {
+				// errors marshal to an empty object
+				value = err.Error()
+			}
+			record.Fields[key] = value
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.records[r.next] = record
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+	return nil
+}
+
+// Records returns the records with at least the severity of minLevel logged after since, oldest first
+func (r *Ring) Records(minLevel logrus.Level, since time.Time) []Record {
+	result := []Record{}
+	if r == nil {
+		return result
+	}
+	r.Lock()
+	defer r.Unlock()
+
+	ordered := r.records[:r.next]
+	if r.full {
+		ordered = append(append([]Record(nil), r.records[r.next:]...), r.records[:r.next]...)
+	}
+	for _, record := range ordered {
+		// lower logrus levels are more severe
+		if record.level > minLevel || record.Time.Before(since) {
+			continue
+		}
+		result = append(result, record)
+	}
+	return result
+}
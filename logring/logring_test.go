@@ -0,0 +1,66 @@
+package logring
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/sirupsen/logrus"
+)
+
+func newLogger(ring *Ring) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(ring)
+	return logger
+}
+
+func TestRing(t *testing.T) {
+	t.Run("keeps only warnings and errors", func(t *testing.T) {
+		ring := New(10)
+		logger := newLogger(ring)
+
+		logger.Info("started")
+		logger.Warn("slow response")
+		logger.WithError(errors.New("connection refused")).Error("request failed")
+
+		records := ring.Records(logrus.WarnLevel, time.Time{})
+		tests.H(t).IntEql(len(records), 2)
+		tests.H(t).StringEql(records[0].Message, "slow response")
+		tests.H(t).StringEql(records[1].Level, "error")
+		tests.H(t).InterfaceEql(records[1].Fields["error"], "connection refused")
+	})
+
+	t.Run("drops the oldest records once full", func(t *testing.T) {
+		ring := New(2)
+		logger := newLogger(ring)
+
+		logger.Warn("first")
+		logger.Warn("second")
+		logger.Warn("third")
+
+		records := ring.Records(logrus.WarnLevel, time.Time{})
+		tests.H(t).IntEql(len(records), 2)
+		tests.H(t).StringEql(records[0].Message, "second")
+		tests.H(t).StringEql(records[1].Message, "third")
+	})
+
+	t.Run("filters by level and time", func(t *testing.T) {
+		ring := New(10)
+		start := time.Now()
+		ring.Fire(&logrus.Entry{Time: start.Add(-time.Hour), Level: logrus.ErrorLevel, Message: "old error"})
+		ring.Fire(&logrus.Entry{Time: start, Level: logrus.WarnLevel, Message: "warning"})
+		ring.Fire(&logrus.Entry{Time: start, Level: logrus.ErrorLevel, Message: "error"})
+
+		records := ring.Records(logrus.ErrorLevel, start.Add(-time.Minute))
+		tests.H(t).IntEql(len(records), 1)
+		tests.H(t).StringEql(records[0].Message, "error")
+	})
+
+	t.Run("returns no records for a disabled ring", func(t *testing.T) {
+		ring := New(0)
+		tests.H(t).IntEql(len(ring.Records(logrus.WarnLevel, time.Time{})), 0)
+	})
+}
@@ -1,43 +1,188 @@
 package main
 
 import (
+	"context"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/coreos/go-systemd/activation"
 	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/devserver"
 	"github.com/dcos/dcos-ui-update-service/uiservice"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
+// Exit codes of the service, systemd unit conditions and wrapper scripts rely on them
+const (
+	exitOK           = 0
+	exitConfigError  = 2
+	exitListenError  = 3
+	exitZKInitError  = 4
+	exitServiceError = 5
+)
+
 // TODO: think about client timeouts https://blog.cloudflare.com/the-complete-guide-to-golang-net-http-timeouts/
 func main() {
-	cliArgs := os.Args[1:]
+	os.Exit(run(os.Args[1:]))
+}
+
+// devServerCommand starts the devserver sub-mode, `devserver <config.yaml> [flags]`
+const devServerCommand = "devserver"
+
+// run starts the service and returns the exit code, deferred cleanup runs before the process exits
+func run(cliArgs []string) int {
+	if len(cliArgs) > 0 && cliArgs[0] == devServerCommand {
+		return runDevServer(cliArgs[1:])
+	}
+	if len(cliArgs) > 0 && cliArgs[0] == checkCommand {
+		return runCheck(cliArgs[1:], os.Stdout)
+	}
 	config, err := config.Parse(cliArgs)
+	if err != nil {
+		logrus.WithError(err).Error("Could not load config")
+		return exitConfigError
+	}
 
+	logRing, err := initLogging(config)
 	if err != nil {
-		logrus.WithError(err).Fatalf("Could not load config")
+		logrus.WithError(err).Error("Could not set up logging")
+		return exitConfigError
 	}
 
-	initLogging(config)
+	if config.InitZK() {
+		if err := zookeeper.Bootstrap(config); err != nil {
+			logrus.WithError(err).Error("Failed to initialize ZK subtree")
+			return exitZKInitError
+		}
+		logrus.Info("ZK subtree initialized")
+		return exitOK
+	}
 
 	service, err := uiservice.SetupService(config)
 	if err != nil {
-		logrus.WithError(err).Fatal("Failed to initiate ui service")
+		logrus.WithError(err).Error("Failed to initiate ui service")
+		return exitServiceError
 	}
+	service.LogRing = logRing
+	return serve(service)
+}
 
-	listener := listener(service.Config)
+// runDevServer serves the updater API with the versions of the devserver config instead of Cosmos and ZK
+func runDevServer(cliArgs []string) int {
+	if len(cliArgs) == 0 {
+		logrus.Error("Missing devserver config, usage: devserver <config.yaml> [flags]")
+		return exitConfigError
+	}
+	devConfig, err := devserver.LoadConfig(cliArgs[0])
+	if err != nil {
+		logrus.WithError(err).Error("Could not load devserver config")
+		return exitConfigError
+	}
+	config, err := config.Parse(append(devConfig.Args(), cliArgs[1:]...))
+	if err != nil {
+		logrus.WithError(err).Error("Could not load config")
+		return exitConfigError
+	}
+	logRing, err := initLogging(config)
+	if err != nil {
+		logrus.WithError(err).Error("Could not set up logging")
+		return exitConfigError
+	}
 
-	if err := service.Run(listener); err != nil {
-		logrus.WithError(err).Fatal("Application error")
+	service, err := devserver.Setup(devConfig, config)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to initiate devserver")
+		return exitServiceError
+	}
+	service.LogRing = logRing
+	return serve(service)
+}
+
+// serve runs the service until it fails or is shut down by SIGTERM or SIGINT, SIGHUP reloads the TLS certificate
+func serve(service *uiservice.UIService) int {
+	config := service.Config
+	defer service.Scheduler.Stop()
+
+	listener, ownsSocket, err := listener(config)
+	if err != nil {
+		logrus.WithError(err).Error("Cannot listen for connections")
+		return exitListenError
+	}
+	// Closing a unix listener removes its socket file, unless it was handed to a new process
+	defer listener.Close()
+
+	shutdownResult := make(chan error, 1)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGUSR2)
+	defer signal.Stop(signals)
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGUSR2 {
+				logrus.Info("Received warm restart signal")
+				if err := service.PrepareRestart(); err != nil {
+					logrus.WithError(err).Warn("Refusing warm restart, this process keeps serving")
+					continue
+				}
+				if err := restart(listener, ownsSocket, config.ShutdownTimeout()); err != nil {
+					service.CancelRestart()
+					logrus.WithError(err).Error("Warm restart failed, this process keeps serving")
+					continue
+				}
+			} else {
+				logrus.WithField("signal", sig.String()).Info("Received shutdown signal")
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout())
+			shutdownResult <- service.Shutdown(ctx)
+			cancel()
+			return
+		}
+	}()
+
+	reloads := make(chan os.Signal, 1)
+	signal.Notify(reloads, syscall.SIGHUP)
+	defer signal.Stop(reloads)
+	go func() {
+		for range reloads {
+			if err := service.ReloadCertificate(); err != nil {
+				logrus.WithError(err).Warn("Failed to reload TLS certificate, keeping the previous certificate")
+			}
+		}
+	}()
+
+	signalReady()
+	err = service.Run(listener)
+	if err == http.ErrServerClosed {
+		if shutdownErr := <-shutdownResult; shutdownErr != nil {
+			logrus.WithError(shutdownErr).Error("Shutdown did not complete cleanly")
+			return exitServiceError
+		}
+		return exitOK
 	}
+	logrus.WithError(err).Error("Application error")
+	return exitServiceError
 }
 
-func listener(config *config.Config) net.Listener {
+// listener returns the listener passed by a warm restart, activated by systemd or listening on the configured
+// address, and if the service owns its socket file
+func listener(config *config.Config) (net.Listener, bool, error) {
+	inherited, ownsSocket, err := inheritedListener()
+	if err != nil {
+		return nil, false, err
+	}
+	if inherited != nil {
+		logrus.WithFields(logrus.Fields{"socket": inherited.Addr()}).Info("Listening on the socket of the previous process")
+		return inherited, ownsSocket, nil
+	}
+
 	// Use systemd socket activation.
 	l, err := activation.Listeners()
 	if err != nil {
-		logrus.WithError(err).Fatal("Failed to activate listeners from systemd")
+		return nil, false, errors.Wrap(err, "failed to activate listeners from systemd")
 	}
 
 	var listener net.Listener
@@ -49,15 +194,19 @@ func listener(config *config.Config) net.Listener {
 			logrus.WithFields(logrus.Fields{
 				"connections": config.ListenNetProtocol(),
 				"address":     config.ListenNetAddress(),
-				"err":         err.Error(),
-			}).Fatal("Cannot listen for connections")
+			}).WithError(err).Error("Cannot listen for connections")
+			return nil, false, err
 		}
+		ownsSocket = true
 		logrus.WithFields(logrus.Fields{"net": config.ListenNetProtocol(), "Addr": config.ListenNetAddress()}).Info("Listening")
 	case 1:
 		listener = l[0]
 		logrus.WithFields(logrus.Fields{"socket": listener.Addr()}).Info("Listening on systemd")
 	default:
-		logrus.Fatal("Found multiple systemd sockets.")
+		for _, sl := range l {
+			sl.Close()
+		}
+		return nil, false, errors.New("found multiple systemd sockets")
 	}
-	return listener
+	return listener, ownsSocket, nil
 }
@@ -69,7 +69,7 @@ func setupTestUIService() *uiservice.UIService {
 		"--master-count-file", "../fixtures/single-master",
 	})
 
-	um, _ := updatemanager.NewClient(cfg)
+	um, _ := updatemanager.NewClient(cfg, &updatemanager.FakePackageSource{}, &updatemanager.FakeBundleFetcher{})
 	um.Fs = afero.NewOsFs()
 
 	os.MkdirAll(cfg.VersionsRoot(), 0755)
@@ -116,3 +116,18 @@ func (vs *fakeVersionStore) UpdateCurrentVersion(newVersion uiservice.UIVersion)
 func (vs *fakeVersionStore) WatchForVersionChange(listener uiservice.VersionChangeListener) error {
 	return nil
 }
+
+func TestRunExitCodes(t *testing.T) {
+	t.Run("returns the config error code for an invalid config", func(t *testing.T) {
+		tests.H(t).IntEql(run([]string{"--extract-max-files", "-1"}), exitConfigError)
+	})
+
+	t.Run("returns the config error code for an invalid log level", func(t *testing.T) {
+		tests.H(t).IntEql(run([]string{"--log-level", "loud"}), exitConfigError)
+	})
+
+	t.Run("returns the config error code if the devserver config is missing", func(t *testing.T) {
+		tests.H(t).IntEql(run([]string{devServerCommand}), exitConfigError)
+		tests.H(t).IntEql(run([]string{devServerCommand, "does-not-exist.yaml"}), exitConfigError)
+	})
+}
@@ -0,0 +1,223 @@
+// Package memory accounts the memory of expensive operations against a budget, so operations coinciding on
+// a master queue instead of exhausting its memory.
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dcos/dcos-ui-update-service/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	reservedBytes = metrics.DefaultRegistry.Gauge(
+		"ui_update_memory_reserved_bytes",
+		"Bytes of memory reserved by running operations.",
+	)
+	budgetBytes = metrics.DefaultRegistry.Gauge(
+		"ui_update_memory_budget_bytes",
+		"The memory budget of the operations, 0 if it is not limited.",
+	)
+	waitingOperations = metrics.DefaultRegistry.Gauge(
+		"ui_update_memory_waiting_operations",
+		"Number of operations waiting for memory.",
+	)
+	queuedOperations = metrics.DefaultRegistry.Counter(
+		"ui_update_memory_queued_total",
+		"Number of operations that waited for memory because the budget was exhausted.",
+		"purpose",
+	)
+)
+
+// DefaultAccountant is the accountant shared by the downloads, exports and caches of the service
+var DefaultAccountant = NewAccountant(0)
+
+// Stats is a snapshot of the memory accounting
+type Stats struct {
+	Limit    int64            `json:"limit"`
+	Reserved int64            `json:"reserved"`
+	Peak     int64            `json:"peak"`
+	Waiting  int              `json:"waiting"`
+	Purposes map[string]int64 `json:"purposes"`
+}
+
+// Accountant grants memory reservations while they fit into the limit, later reservations wait in order.
+// A reservation larger than the limit is granted once no other memory is reserved, so it runs alone.
+type Accountant struct {
+	limit    int64
+	reserved int64
+	peak     int64
+	purposes map[string]int64
+	waiting  []*waiter
+	sync.Mutex
+}
+
+type waiter struct {
+	purpose string
+	bytes   int64
+	ready   chan struct{}
+}
+
+// Reservation is memory reserved for an operation, it must be released once the operation completed
+type Reservation struct {
+	accountant *Accountant
+	purpose    string
+	bytes      int64
+	once       sync.Once
+}
+
+// NewAccountant creates an accountant with a limit in bytes, 0 only accounts without limiting
+func NewAccountant(limit int64) *Accountant {
+	return &Accountant{limit: limit, purposes: map[string]int64{}}
+}
+
+// SetLimit changes the limit, waiting reservations fitting into a larger limit are granted
+func (a *Accountant) SetLimit(limit int64) {
+	a.Lock()
+	defer a.Unlock()
+	a.limit = limit
+	a.wake()
+	a.updateMetrics()
+}
+
+// Reserve reserves bytes for purpose, waiting until they fit into the limit or ctx is done
+func (a *Accountant) Reserve(ctx context.Context, purpose string, bytes int64) (*Reservation, error) {
+	a.Lock()
+	if len(a.waiting) == 0 && a.fits(bytes) {
+		a.grant(purpose, bytes)
+		a.updateMetrics()
+		a.Unlock()
+		return a.reservation(purpose, bytes), nil
+	}
+	w := &waiter{purpose: purpose, bytes: bytes, ready: make(chan struct{})}
+	a.waiting = append(a.waiting, w)
+	if a == DefaultAccountant {
+		queuedOperations.Inc(purpose)
+	}
+	logrus.WithFields(logrus.Fields{
+		"purpose":  purpose,
+		"bytes":    bytes,
+		"reserved": a.reserved,
+		"limit":    a.limit,
+	}).Info("Memory budget exhausted, waiting for running operations")
+	a.updateMetrics()
+	a.Unlock()
+
+	select {
+	case <-w.ready:
+		return a.reservation(purpose, bytes), nil
+	case <-ctx.Done():
+	}
+	a.Lock()
+	defer a.Unlock()
+	select {
+	case <-w.ready:
+		// granted while the context was canceled
+		a.release(purpose, bytes)
+	default:
+		a.remove(w)
+	}
+	a.wake()
+	a.updateMetrics()
+	return nil, ctx.Err()
+}
+
+// TryReserve reserves bytes for purpose if they fit into the limit right away, for caches which rather
+// drop data than wait
+func (a *Accountant) TryReserve(purpose string, bytes int64) (*Reservation, bool) {
+	a.Lock()
+	defer a.Unlock()
+	if len(a.waiting) > 0 || (a.limit > 0 && a.reserved+bytes > a.limit) {
+		return nil, false
+	}
+	a.grant(purpose, bytes)
+	a.updateMetrics()
+	return a.reservation(purpose, bytes), true
+}
+
+// Stats returns the current reservations
+func (a *Accountant) Stats() Stats {
+	a.Lock()
+	defer a.Unlock()
+	stats := Stats{
+		Limit:    a.limit,
+		Reserved: a.reserved,
+		Peak:     a.peak,
+		Waiting:  len(a.waiting),
+		Purposes: map[string]int64{},
+	}
+	for purpose, bytes := range a.purposes {
+		stats.Purposes[purpose] = bytes
+	}
+	return stats
+}
+
+// Release returns the memory of the reservation, it may be called more than once
+func (r *Reservation) Release() {
+	if r == nil {
+		return
+	}
+	r.once.Do(func() {
+		a := r.accountant
+		a.Lock()
+		defer a.Unlock()
+		a.release(r.purpose, r.bytes)
+		a.wake()
+		a.updateMetrics()
+	})
+}
+
+func (a *Accountant) reservation(purpose string, bytes int64) *Reservation {
+	return &Reservation{accountant: a, purpose: purpose, bytes: bytes}
+}
+
+// fits is true if bytes can be reserved now, the accountant must be locked
+func (a *Accountant) fits(bytes int64) bool {
+	return a.limit <= 0 || a.reserved == 0 || a.reserved+bytes <= a.limit
+}
+
+func (a *Accountant) grant(purpose string, bytes int64) {
+	a.reserved += bytes
+	a.purposes[purpose] += bytes
+	if a.reserved > a.peak {
+		a.peak = a.reserved
+	}
+}
+
+func (a *Accountant) release(purpose string, bytes int64) {
+	a.reserved -= bytes
+	a.purposes[purpose] -= bytes
+	if a.purposes[purpose] <= 0 {
+		delete(a.purposes, purpose)
+	}
+}
+
+// wake grants the waiting reservations in order while they fit
+func (a *Accountant) wake() {
+	for len(a.waiting) > 0 && a.fits(a.waiting[0].bytes) {
+		w := a.waiting[0]
+		a.waiting = a.waiting[1:]
+		a.grant(w.purpose, w.bytes)
+		close(w.ready)
+	}
+}
+
+func (a *Accountant) remove(w *waiter) {
+	for i, waiting := range a.waiting {
+		if waiting == w {
+			a.waiting = append(a.waiting[:i], a.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+// updateMetrics reports the DefaultAccountant, the accountant must be locked
+func (a *Accountant) updateMetrics() {
+	if a != DefaultAccountant {
+		return
+	}
+	reservedBytes.Set(float64(a.reserved))
+	budgetBytes.Set(float64(a.limit))
+	waitingOperations.Set(float64(len(a.waiting)))
+}
@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+// reserveAsync reserves in the background and sends the reservation once it was granted
+func reserveAsync(ctx context.Context, a *Accountant, purpose string, bytes int64) (<-chan *Reservation, <-chan error) {
+	granted := make(chan *Reservation, 1)
+	failed := make(chan error, 1)
+	go func() {
+		r, err := a.Reserve(ctx, purpose, bytes)
+		if err != nil {
+			failed <- err
+			return
+		}
+		granted <- r
+	}()
+	return granted, failed
+}
+
+func waitForWaiting(t *testing.T, a *Accountant, n int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for a.Stats().Waiting != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiting reservations, got %d", n, a.Stats().Waiting)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAccountant(t *testing.T) {
+	t.Run("grants reservations fitting into the limit", func(t *testing.T) {
+		a := NewAccountant(100)
+
+		first, err := a.Reserve(context.Background(), "download", 60)
+		tests.H(t).IsNil(err)
+		second, err := a.Reserve(context.Background(), "export", 40)
+		tests.H(t).IsNil(err)
+
+		stats := a.Stats()
+		tests.H(t).Int64Eql(stats.Reserved, 100)
+		tests.H(t).InterfaceEql(stats.Purposes, map[string]int64{"download": 60, "export": 40})
+		first.Release()
+		second.Release()
+		tests.H(t).Int64Eql(a.Stats().Reserved, 0)
+		tests.H(t).Int64Eql(a.Stats().Peak, 100)
+	})
+
+	t.Run("queues reservations until running operations release their memory", func(t *testing.T) {
+		a := NewAccountant(100)
+		running, _ := a.Reserve(context.Background(), "download", 80)
+
+		granted, _ := reserveAsync(context.Background(), a, "download", 50)
+		waitForWaiting(t, a, 1)
+		running.Release()
+
+		select {
+		case r := <-granted:
+			tests.H(t).Int64Eql(a.Stats().Reserved, 50)
+			r.Release()
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected the queued reservation to be granted")
+		}
+	})
+
+	t.Run("grants queued reservations in order", func(t *testing.T) {
+		a := NewAccountant(100)
+		running, _ := a.Reserve(context.Background(), "download", 80)
+		large, _ := reserveAsync(context.Background(), a, "download", 90)
+		waitForWaiting(t, a, 1)
+
+		_, ok := a.TryReserve("recorder", 10)
+		tests.H(t).BoolEql(ok, false)
+		running.Release()
+		r := <-large
+		r.Release()
+	})
+
+	t.Run("runs a reservation larger than the limit alone", func(t *testing.T) {
+		a := NewAccountant(100)
+
+		r, err := a.Reserve(context.Background(), "export", 500)
+
+		tests.H(t).IsNil(err)
+		tests.H(t).Int64Eql(a.Stats().Reserved, 500)
+		r.Release()
+	})
+
+	t.Run("stops waiting once the context is done", func(t *testing.T) {
+		a := NewAccountant(100)
+		running, _ := a.Reserve(context.Background(), "download", 100)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		_, failed := reserveAsync(ctx, a, "download", 50)
+		waitForWaiting(t, a, 1)
+		cancel()
+
+		tests.H(t).ErrEql(<-failed, context.Canceled)
+		tests.H(t).IntEql(a.Stats().Waiting, 0)
+		running.Release()
+		tests.H(t).Int64Eql(a.Stats().Reserved, 0)
+	})
+
+	t.Run("releases a reservation once", func(t *testing.T) {
+		a := NewAccountant(100)
+		first, _ := a.Reserve(context.Background(), "download", 30)
+		second, _ := a.Reserve(context.Background(), "download", 30)
+
+		first.Release()
+		first.Release()
+
+		tests.H(t).Int64Eql(a.Stats().Reserved, 30)
+		second.Release()
+	})
+
+	t.Run("does not limit without limit", func(t *testing.T) {
+		a := NewAccountant(0)
+
+		a.Reserve(context.Background(), "download", 1<<40)
+		_, ok := a.TryReserve("recorder", 1<<40)
+
+		tests.H(t).BoolEql(ok, true)
+	})
+}
@@ -0,0 +1,258 @@
+// Package metrics implements a small registry of counters and summaries exported
+// in the Prometheus text exposition format.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultRegistry is the registry served by the metrics endpoint
+var DefaultRegistry = NewRegistry()
+
+type metric interface {
+	write(w io.Writer)
+}
+
+// Registry holds the registered metrics
+type Registry struct {
+	metrics map[string]metric
+	sync.Mutex
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]metric),
+	}
+}
+
+// Counter registers a counter with the given label names, or returns the already registered one
+func (r *Registry) Counter(name, help string, labelNames ...string) *CounterVec {
+	r.Lock()
+	defer r.Unlock()
+	if existing, ok := r.metrics[name].(*CounterVec); ok {
+		return existing
+	}
+	counter := &CounterVec{vec: newVec(name, help, labelNames)}
+	r.metrics[name] = counter
+	return counter
+}
+
+// Gauge registers a gauge with the given label names, or returns the already registered one
+func (r *Registry) Gauge(name, help string, labelNames ...string) *GaugeVec {
+	r.Lock()
+	defer r.Unlock()
+	if existing, ok := r.metrics[name].(*GaugeVec); ok {
+		return existing
+	}
+	gauge := &GaugeVec{vec: newVec(name, help, labelNames)}
+	r.metrics[name] = gauge
+	return gauge
+}
+
+// Summary registers a summary with the given label names, or returns the already registered one
+func (r *Registry) Summary(name, help string, labelNames ...string) *SummaryVec {
+	r.Lock()
+	defer r.Unlock()
+	if existing, ok := r.metrics[name].(*SummaryVec); ok {
+		return existing
+	}
+	summary := &SummaryVec{vec: newVec(name, help, labelNames)}
+	r.metrics[name] = summary
+	return summary
+}
+
+// WriteTo writes all metrics sorted by name in the Prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metrics := make([]metric, 0, len(names))
+	for _, name := range names {
+		metrics = append(metrics, r.metrics[name])
+	}
+	r.Unlock()
+
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		m.write(&buf)
+	}
+	return buf.WriteTo(w)
+}
+
+// Handler returns an http.Handler serving the registry
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.WriteTo(w)
+	})
+}
+
+type series struct {
+	labelValues []string
+	count       uint64
+	sum         float64
+}
+
+type vec struct {
+	name       string
+	help       string
+	labelNames []string
+	series     map[string]*series
+	sync.Mutex
+}
+
+func newVec(name, help string, labelNames []string) vec {
+	return vec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		series:     make(map[string]*series),
+	}
+}
+
+func (v *vec) observe(value float64, labelValues []string) {
+	v.update(labelValues, func(s *series) {
+		s.count++
+		s.sum += value
+	})
+}
+
+func (v *vec) set(value float64, labelValues []string) {
+	v.update(labelValues, func(s *series) {
+		s.count = 1
+		s.sum = value
+	})
+}
+
+func (v *vec) update(labelValues []string, apply func(*series)) {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.name, len(v.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	v.Lock()
+	defer v.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		v.series[key] = s
+	}
+	apply(s)
+}
+
+func (v *vec) get(labelValues []string) (uint64, float64) {
+	v.Lock()
+	defer v.Unlock()
+	if s, ok := v.series[strings.Join(labelValues, "\xff")]; ok {
+		return s.count, s.sum
+	}
+	return 0, 0
+}
+
+func (v *vec) sortedSeries() []series {
+	v.Lock()
+	defer v.Unlock()
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]series, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *v.series[key])
+	}
+	return result
+}
+
+func (v *vec) labels(labelValues []string) string {
+	if len(labelValues) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(labelValues))
+	for i, value := range labelValues {
+		pairs[i] = fmt.Sprintf("%s=%s", v.labelNames[i], strconv.Quote(value))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	vec
+}
+
+// Inc increments the counter for the given label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.observe(1, labelValues)
+}
+
+// Value returns the current count for the given label values
+func (c *CounterVec) Value(labelValues ...string) uint64 {
+	count, _ := c.get(labelValues)
+	return count
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, s := range c.sortedSeries() {
+		fmt.Fprintf(w, "%s%s %d\n", c.name, c.labels(s.labelValues), s.count)
+	}
+}
+
+// GaugeVec is a value that can go up and down partitioned by labels
+type GaugeVec struct {
+	vec
+}
+
+// Set sets the gauge for the given label values
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.set(value, labelValues)
+}
+
+// Value returns the current value for the given label values
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	_, value := g.get(labelValues)
+	return value
+}
+
+func (g *GaugeVec) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	for _, s := range g.sortedSeries() {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, g.labels(s.labelValues), strconv.FormatFloat(s.sum, 'g', -1, 64))
+	}
+}
+
+// SummaryVec tracks the count and sum of observations partitioned by labels
+type SummaryVec struct {
+	vec
+}
+
+// Observe records a value for the given label values
+func (s *SummaryVec) Observe(value float64, labelValues ...string) {
+	s.observe(value, labelValues)
+}
+
+// Count returns the number of observations for the given label values
+func (s *SummaryVec) Count(labelValues ...string) uint64 {
+	count, _ := s.get(labelValues)
+	return count
+}
+
+func (s *SummaryVec) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s summary\n", s.name, s.help, s.name)
+	for _, series := range s.sortedSeries() {
+		labels := s.labels(series.labelValues)
+		fmt.Fprintf(w, "%s_sum%s %s\n", s.name, labels, strconv.FormatFloat(series.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count%s %d\n", s.name, labels, series.count)
+	}
+}
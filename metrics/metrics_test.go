@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("Counter returns the registered counter", func(t *testing.T) {
+		reg := NewRegistry()
+		first := reg.Counter("requests_total", "Requests", "operation")
+		second := reg.Counter("requests_total", "Requests", "operation")
+
+		first.Inc("get")
+		second.Inc("get")
+
+		tests.H(t).Int64Eql(int64(first.Value("get")), 2)
+		tests.H(t).Int64Eql(int64(first.Value("set")), 0)
+	})
+
+	t.Run("Gauge keeps the last value", func(t *testing.T) {
+		reg := NewRegistry()
+		gauge := reg.Gauge("versions", "Versions", "state")
+		gauge.Set(3, "stored")
+		gauge.Set(1, "stored")
+
+		var buf bytes.Buffer
+		reg.WriteTo(&buf)
+
+		tests.H(t).StringEql(buf.String(), `# HELP versions Versions
+# TYPE versions gauge
+versions{state="stored"} 1
+`)
+	})
+
+	t.Run("WriteTo writes the text exposition format", func(t *testing.T) {
+		reg := NewRegistry()
+		reg.Counter("requests_total", "Requests made", "operation").Inc("get")
+		summary := reg.Summary("request_duration_seconds", "Request duration", "operation")
+		summary.Observe(0.5, "get")
+		summary.Observe(0.25, "get")
+
+		var buf bytes.Buffer
+		reg.WriteTo(&buf)
+
+		tests.H(t).StringEql(buf.String(), `# HELP request_duration_seconds Request duration
+# TYPE request_duration_seconds summary
+request_duration_seconds_sum{operation="get"} 0.75
+request_duration_seconds_count{operation="get"} 2
+# HELP requests_total Requests made
+# TYPE requests_total counter
+requests_total{operation="get"} 1
+`)
+	})
+
+	t.Run("Handler serves the metrics", func(t *testing.T) {
+		reg := NewRegistry()
+		reg.Counter("requests_total", "Requests made").Inc()
+
+		rr := httptest.NewRecorder()
+		reg.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringContains(rr.Body.String(), "requests_total 1")
+	})
+}
@@ -0,0 +1,144 @@
+package mirror
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm = "AWS4-HMAC-SHA256"
+	s3DateTime  = "20060102T150405Z"
+	s3Date      = "20060102"
+)
+
+// S3 publishes bundles to a bucket of S3 or an S3 compatible service with PUT object requests signed
+// with AWS signature version 4
+type S3 struct {
+	// Endpoint is the URL of the service, objects are addressed path-style as <endpoint>/<bucket>/<key>
+	Endpoint        *url.URL
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	HTTPClient      *http.Client
+	// now returns the signing time, time.Now if nil
+	now func() time.Time
+}
+
+// Publish uploads the size bytes of bundle as object name below the prefix, digest is the "sha256:<hex>"
+// checksum of the bundle, S3 rejects the upload if the received bytes don't match it
+func (s *S3) Publish(ctx context.Context, name string, bundle io.Reader, size int64, digest string) error {
+	target := *s.Endpoint
+	target.Path = "/" + strings.Join(nonEmpty(strings.Trim(target.Path, "/"), s.Bucket, strings.Trim(s.Prefix, "/"), name), "/")
+	target.RawPath = uriEncode(target.Path, false)
+	req, err := http.NewRequest("PUT", target.String(), bundle)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	s.sign(req, strings.TrimPrefix(digest, "sha256:"), now().UTC())
+	return do(ctx, s.HTTPClient, req)
+}
+
+// sign adds the signature version 4 authorization of all headers of req to it
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	req.Header.Set("X-Amz-Date", now.Format(s3DateTime))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{now.Format(s3Date), s.Region, "s3", "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3Algorithm, now.Format(s3DateTime), scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), now.Format(s3Date))
+	for _, part := range []string{s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes all bytes except the unreserved characters, slashes are kept unless encodeSlash
+func uriEncode(s string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			encoded.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			encoded.WriteByte(b)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
+
+func nonEmpty(parts ...string) []string {
+	result := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+// Package mirror publishes verified ui bundles to an internal artifact mirror, so later downloads are served
+// from the trusted internal copy instead of the public package source.
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// WebDAV publishes bundles with PUT requests below BaseURL, e.g. to a WebDAV share or an artifact
+// repository accepting uploads
+type WebDAV struct {
+	BaseURL *url.URL
+	// Username and Password are sent with basic auth if Username is set
+	Username   string
+	Password   string
+	HTTPClient *http.Client
+}
+
+// Publish uploads the size bytes of bundle as name below the base URL
+func (w *WebDAV) Publish(ctx context.Context, name string, bundle io.Reader, size int64, digest string) error {
+	target := *w.BaseURL
+	target.Path = path.Join(target.Path, name)
+	req, err := http.NewRequest("PUT", target.String(), bundle)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	if w.Username != "" {
+		req.SetBasicAuth(w.Username, w.Password)
+	}
+	return do(ctx, w.HTTPClient, req)
+}
+
+// do sends the upload request, any response other than 200, 201 or 204 fails the upload
+func do(ctx context.Context, client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	}
+	return fmt.Errorf("upload of %s failed with status %d", req.URL.String(), resp.StatusCode)
+}
@@ -0,0 +1,37 @@
+package mirror
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestWebDAV(t *testing.T) {
+	t.Run("uploads the bundle below the base URL with basic auth", func(t *testing.T) {
+		var path, user, password, body string
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			path = req.URL.Path
+			user, password, _ = req.BasicAuth()
+			data, _ := ioutil.ReadAll(req.Body)
+			body = string(data)
+			rw.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+		baseURL, _ := url.Parse(server.URL + "/dav/ui-bundles/")
+		webdav := &WebDAV{BaseURL: baseURL, Username: "publisher", Password: "secret", HTTPClient: http.DefaultClient}
+
+		err := webdav.Publish(context.Background(), "release.tar.gz", strings.NewReader("bundle"), 6, "sha256:abc123")
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(path, "/dav/ui-bundles/release.tar.gz")
+		tests.H(t).StringEql(user, "publisher")
+		tests.H(t).StringEql(password, "secret")
+		tests.H(t).StringEql(body, "bundle")
+	})
+}
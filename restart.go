@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/coreos/go-systemd/daemon"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// listenFDEnv is the descriptor of the listener a warm restart hands to the new process
+	listenFDEnv = "DCOS_UI_UPDATE_LISTEN_FD"
+	// readyFDEnv is the descriptor the new process writes to once it serves, so the old process stops accepting
+	readyFDEnv = "DCOS_UI_UPDATE_READY_FD"
+	// ownsSocketEnv is set if the socket file of the listener was created by the service, not by systemd
+	ownsSocketEnv = "DCOS_UI_UPDATE_OWNS_SOCKET"
+)
+
+var (
+	// ErrListenerNotInheritable occurs if the listener has no file descriptor that can be passed on
+	ErrListenerNotInheritable = errors.New("listener cannot be passed to a new process")
+	// ErrRestartNotReady occurs if the new process exited or did not serve before the restart timeout
+	ErrRestartNotReady = errors.New("new process did not start serving")
+)
+
+// fileListener is implemented by the TCP and unix listeners
+type fileListener interface {
+	File() (*os.File, error)
+}
+
+// inheritedListener returns the listener passed by the process that started this one for a warm restart and
+// if the service owns its socket file, nil if this process was not started by a warm restart
+func inheritedListener() (net.Listener, bool, error) {
+	value := os.Getenv(listenFDEnv)
+	if value == "" {
+		return nil, false, nil
+	}
+	ownsSocket := os.Getenv(ownsSocketEnv) != ""
+	os.Unsetenv(listenFDEnv)
+	os.Unsetenv(ownsSocketEnv)
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "invalid %s", listenFDEnv)
+	}
+	file := os.NewFile(uintptr(fd), "inherited-listener")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to use the inherited listener")
+	}
+	// like the listener of the first process the socket file is removed once the listener is closed
+	if unixListener, ok := listener.(*net.UnixListener); ok && ownsSocket {
+		unixListener.SetUnlinkOnClose(true)
+	}
+	return listener, ownsSocket, nil
+}
+
+// signalReady tells the process that started this one that it serves, and systemd that this process is
+// the main process of the unit now
+func signalReady() {
+	value := os.Getenv(readyFDEnv)
+	if value == "" {
+		return
+	}
+	os.Unsetenv(readyFDEnv)
+	if fd, err := strconv.Atoi(value); err == nil {
+		ready := os.NewFile(uintptr(fd), "restart-ready")
+		ready.Write([]byte{1})
+		ready.Close()
+	}
+	daemon.SdNotify(false, "MAINPID="+strconv.Itoa(os.Getpid()))
+}
+
+// restart starts the binary again with the arguments of this process and passes it the listener. It returns
+// once the new process serves, the caller then drains the requests of this process and shuts it down. If the
+// new process does not serve within timeout it is killed and this process keeps serving.
+func restart(listener net.Listener, ownsSocket bool, timeout time.Duration) error {
+	inheritable, ok := listener.(fileListener)
+	if !ok {
+		return ErrListenerNotInheritable
+	}
+	listenerFile, err := inheritable.File()
+	if err != nil {
+		return errors.Wrap(err, "failed to get the listener descriptor")
+	}
+	defer listenerFile.Close()
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return errors.Wrap(err, "failed to create the ready pipe")
+	}
+	defer readyReader.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		readyWriter.Close()
+		return errors.Wrap(err, "failed to find the executable")
+	}
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	// ExtraFiles start at descriptor 3
+	cmd.ExtraFiles = []*os.File{listenerFile, readyWriter}
+	cmd.Env = append(os.Environ(), listenFDEnv+"=3", readyFDEnv+"=4")
+	if ownsSocket {
+		cmd.Env = append(cmd.Env, ownsSocketEnv+"=1")
+	}
+	err = cmd.Start()
+	readyWriter.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to start the new process")
+	}
+	logrus.WithField("pid", cmd.Process.Pid).Info("Started new process for warm restart, waiting for it to serve")
+
+	ready := make(chan bool, 1)
+	go func() {
+		// the read fails with EOF if the new process exited without signalling that it serves
+		n, _ := readyReader.Read(make([]byte, 1))
+		ready <- n == 1
+	}()
+	select {
+	case serving := <-ready:
+		if !serving {
+			cmd.Wait()
+			return ErrRestartNotReady
+		}
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		cmd.Wait()
+		return ErrRestartNotReady
+	}
+
+	// the new process serves the socket now, closing this process' listener must not remove the socket file
+	if unixListener, ok := listener.(*net.UnixListener); ok {
+		unixListener.SetUnlinkOnClose(false)
+	}
+	logrus.WithField("pid", cmd.Process.Pid).Info("New process serves, draining this process")
+	return nil
+}
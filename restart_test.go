@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+type plainListener struct {
+	net.Listener
+}
+
+func TestWarmRestart(t *testing.T) {
+	t.Run("listens on the inherited listener", func(t *testing.T) {
+		l, err := listen()
+		tests.H(t).IsNil(err)
+		defer l.Close()
+		file, err := l.(*net.TCPListener).File()
+		tests.H(t).IsNil(err)
+		// the inherited descriptor is closed by inheritedListener, hand it a copy
+		fd, err := syscall.Dup(int(file.Fd()))
+		tests.H(t).IsNil(err)
+		file.Close()
+		os.Setenv(listenFDEnv, strconv.Itoa(fd))
+
+		inherited, ownsSocket, err := inheritedListener()
+
+		tests.H(t).IsNil(err)
+		defer inherited.Close()
+		tests.H(t).StringEql(inherited.Addr().String(), l.Addr().String())
+		tests.H(t).BoolEql(ownsSocket, false)
+		tests.H(t).StringEql(os.Getenv(listenFDEnv), "")
+	})
+
+	t.Run("returns no listener if none was inherited", func(t *testing.T) {
+		inherited, _, err := inheritedListener()
+
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(inherited == nil, true)
+	})
+
+	t.Run("signals the previous process once it serves", func(t *testing.T) {
+		reader, writer, err := os.Pipe()
+		tests.H(t).IsNil(err)
+		defer reader.Close()
+		fd, err := syscall.Dup(int(writer.Fd()))
+		tests.H(t).IsNil(err)
+		writer.Close()
+		os.Setenv(readyFDEnv, strconv.Itoa(fd))
+
+		signalReady()
+
+		buf := make([]byte, 1)
+		reader.SetReadDeadline(time.Now().Add(time.Second))
+		n, _ := reader.Read(buf)
+		tests.H(t).IntEql(n, 1)
+	})
+
+	t.Run("rejects listeners without file descriptor", func(t *testing.T) {
+		l, err := listen()
+		tests.H(t).IsNil(err)
+		defer l.Close()
+
+		tests.H(t).ErrEql(restart(plainListener{l}, false, time.Second), ErrListenerNotInheritable)
+	})
+}
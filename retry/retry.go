@@ -0,0 +1,105 @@
+// Package retry repeats operations failing with transient errors, waiting with an exponential backoff
+// between the attempts, so a single failed request doesn't fail a whole update.
+package retry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/jpillora/backoff"
+	"github.com/sirupsen/logrus"
+)
+
+// Policy configures how often and how fast an operation is retried
+type Policy struct {
+	// Attempts is the maximum number of attempts, operations are not retried if it is less than 2
+	Attempts int
+	// Min is the delay before the first retry, it is doubled for each further retry up to Max
+	Min time.Duration
+	Max time.Duration
+	// Clock is used to wait between attempts, the real clock if nil
+	Clock clock.Clock
+}
+
+// Error is returned once all attempts of an operation failed, its Cause is the error of the last attempt
+type Error struct {
+	Operation string
+	Attempts  int
+	Err       error
+}
+
+func (e *Error) Error() string {
+	if e.Attempts == 1 {
+		return fmt.Sprintf("%s failed after 1 attempt: %v", e.Operation, e.Err)
+	}
+	return fmt.Sprintf("%s failed after %d attempts: %v", e.Operation, e.Attempts, e.Err)
+}
+
+// Cause returns the error of the last attempt, it is used by errors.Cause
+func (e *Error) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the error of the last attempt
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+type transientError struct {
+	error
+}
+
+// Transient marks err as worth retrying, errors not marked as transient end the operation right away
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return transientError{err}
+}
+
+// Do runs fn until it succeeds, fails with an error not marked as Transient, the attempts are used up
+// or ctx is done. The error returned by fn is returned unchanged if fn was not retried, otherwise it
+// is wrapped in an Error reporting the number of attempts.
+func (p Policy) Do(ctx context.Context, operation string, fn func() error) error {
+	clk := p.Clock
+	if clk == nil {
+		clk = clock.New()
+	}
+	b := &backoff.Backoff{
+		Min:    p.Min,
+		Max:    p.Max,
+		Factor: 2,
+		Jitter: false,
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		transient, ok := err.(transientError)
+		if !ok {
+			if err != nil && attempt > 1 {
+				return &Error{Operation: operation, Attempts: attempt, Err: err}
+			}
+			return err
+		}
+		if attempt >= p.Attempts {
+			if attempt == 1 {
+				return transient.error
+			}
+			return &Error{Operation: operation, Attempts: attempt, Err: transient.error}
+		}
+
+		delay := b.Duration()
+		logrus.WithError(transient.error).WithFields(logrus.Fields{
+			"operation":   operation,
+			"attempt":     attempt,
+			"maxAttempts": p.Attempts,
+			"retryIn":     delay,
+		}).Warn("Transient failure, retrying")
+		select {
+		case <-ctx.Done():
+			return &Error{Operation: operation, Attempts: attempt, Err: transient.error}
+		case <-clk.After(delay):
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	pkgerrors "github.com/pkg/errors"
+)
+
+var errUnavailable = errors.New("502 Bad Gateway")
+
+func TestPolicyDo(t *testing.T) {
+	t.Run("retries transient errors until the operation succeeds", func(t *testing.T) {
+		policy := Policy{Attempts: 3, Min: time.Millisecond, Max: time.Millisecond}
+		calls := 0
+
+		err := policy.Do(context.Background(), "list", func() error {
+			calls++
+			if calls < 3 {
+				return Transient(errUnavailable)
+			}
+			return nil
+		})
+
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(calls, 3)
+	})
+
+	t.Run("reports the attempts once they are used up", func(t *testing.T) {
+		policy := Policy{Attempts: 3, Min: time.Millisecond, Max: time.Millisecond}
+		calls := 0
+
+		err := policy.Do(context.Background(), "list", func() error {
+			calls++
+			return Transient(errUnavailable)
+		})
+
+		tests.H(t).IntEql(calls, 3)
+		tests.H(t).StringEql(err.Error(), "list failed after 3 attempts: 502 Bad Gateway")
+		tests.H(t).ErrEql(pkgerrors.Cause(err), errUnavailable)
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		policy := Policy{Attempts: 3, Min: time.Millisecond, Max: time.Millisecond}
+		calls := 0
+
+		err := policy.Do(context.Background(), "list", func() error {
+			calls++
+			return errUnavailable
+		})
+
+		tests.H(t).IntEql(calls, 1)
+		tests.H(t).ErrEql(err, errUnavailable)
+	})
+
+	t.Run("returns the unwrapped error without retries", func(t *testing.T) {
+		calls := 0
+
+		err := Policy{}.Do(context.Background(), "list", func() error {
+			calls++
+			return Transient(errUnavailable)
+		})
+
+		tests.H(t).IntEql(calls, 1)
+		tests.H(t).ErrEql(err, errUnavailable)
+	})
+
+	t.Run("doubles the delay up to the max backoff", func(t *testing.T) {
+		fakeClock := clock.NewFake(time.Now())
+		policy := Policy{Attempts: 4, Min: time.Second, Max: 3 * time.Second, Clock: fakeClock}
+		var attempts []time.Time
+		done := make(chan error)
+
+		go func() {
+			done <- policy.Do(context.Background(), "download", func() error {
+				attempts = append(attempts, fakeClock.Now())
+				return Transient(errUnavailable)
+			})
+		}()
+		for _, delay := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+			fakeClock.BlockUntil(1)
+			fakeClock.Advance(delay)
+		}
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Expected the retries to wait 1s, 2s and 3s")
+		}
+
+		tests.H(t).IntEql(len(attempts), 4)
+		tests.H(t).InterfaceEql(attempts[3].Sub(attempts[0]), 6*time.Second)
+	})
+
+	t.Run("stops retrying once the context is done", func(t *testing.T) {
+		fakeClock := clock.NewFake(time.Now())
+		policy := Policy{Attempts: 3, Min: time.Minute, Max: time.Minute, Clock: fakeClock}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := policy.Do(ctx, "download", func() error {
+			return Transient(errUnavailable)
+		})
+
+		tests.H(t).StringEql(err.Error(), "download failed after 1 attempt: 502 Bad Gateway")
+	})
+}
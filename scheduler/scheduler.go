@@ -0,0 +1,205 @@
+// Package scheduler runs periodic maintenance jobs in the background, isolating
+// jobs from each other's failures and keeping their status for diagnostics.
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultJitter is the maximum fraction of the interval added to each delay,
+// so jobs of the masters in a cluster don't run in lockstep
+const DefaultJitter = 0.1
+
+var (
+	jobRuns = metrics.DefaultRegistry.Counter(
+		"scheduler_job_runs_total",
+		"Number of runs of scheduled maintenance jobs.",
+		"job",
+	)
+	jobFailures = metrics.DefaultRegistry.Counter(
+		"scheduler_job_failures_total",
+		"Number of scheduled maintenance job runs that failed or panicked.",
+		"job",
+	)
+)
+
+// JobFunc is the work done by a job on every run
+type JobFunc func() error
+
+// JobStatus is the state of a job as reported by the diagnostics endpoint
+type JobStatus struct {
+	Name         string     `json:"name"`
+	Interval     string     `json:"interval"`
+	Running      bool       `json:"running"`
+	Runs         int        `json:"runs"`
+	Failures     int        `json:"failures"`
+	LastRun      *time.Time `json:"lastRun,omitempty"`
+	LastDuration string     `json:"lastDuration,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+	NextRun      *time.Time `json:"nextRun,omitempty"`
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      JobFunc
+	status   JobStatus
+}
+
+// Scheduler runs each added job every interval plus jitter until stopped
+type Scheduler struct {
+	clock   clock.Clock
+	jitter  float64
+	random  *rand.Rand
+	jobs    []*job
+	started bool
+	closed  chan struct{}
+	sync.Mutex
+}
+
+// New creates a Scheduler using the real clock and DefaultJitter
+func New() *Scheduler {
+	return newScheduler(clock.New(), DefaultJitter)
+}
+
+func newScheduler(clk clock.Clock, jitter float64) *Scheduler {
+	return &Scheduler{
+		clock:  clk,
+		jitter: jitter,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+		closed: make(chan struct{}),
+	}
+}
+
+// Add registers a job, a job with a non-positive interval is disabled.
+// Jobs added after Start are started right away.
+func (s *Scheduler) Add(name string, interval time.Duration, run JobFunc) {
+	if interval <= 0 {
+		logrus.WithField("job", name).Info("Scheduled job is disabled")
+		return
+	}
+	j := &job{
+		name:     name,
+		interval: interval,
+		run:      run,
+		status:   JobStatus{Name: name, Interval: interval.String()},
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.jobs = append(s.jobs, j)
+	if s.started {
+		go s.loop(j)
+	}
+}
+
+// Start runs the registered jobs in the background until Stop is called
+func (s *Scheduler) Start() {
+	s.Lock()
+	defer s.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, j := range s.jobs {
+		go s.loop(j)
+	}
+}
+
+// Stop ends scheduling, runs in progress are not interrupted
+func (s *Scheduler) Stop() {
+	s.Lock()
+	defer s.Unlock()
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+}
+
+// Status returns the state of all jobs sorted by name
+func (s *Scheduler) Status() []JobStatus {
+	if s == nil {
+		return []JobStatus{}
+	}
+	s.Lock()
+	defer s.Unlock()
+	result := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		result = append(result, j.status)
+	}
+	sort.Slice(result, func(i, k int) bool {
+		return result[i].Name < result[k].Name
+	})
+	return result
+}
+
+func (s *Scheduler) loop(j *job) {
+	for {
+		delay := s.delay(j.interval)
+		s.Lock()
+		next := s.clock.Now().Add(delay)
+		j.status.NextRun = &next
+		s.Unlock()
+
+		select {
+		case <-s.closed:
+			return
+		case <-s.clock.After(delay):
+			s.runJob(j)
+		}
+	}
+}
+
+func (s *Scheduler) delay(interval time.Duration) time.Duration {
+	maxJitter := int64(float64(interval) * s.jitter)
+	if maxJitter <= 0 {
+		return interval
+	}
+	s.Lock()
+	defer s.Unlock()
+	return interval + time.Duration(s.random.Int63n(maxJitter))
+}
+
+func (s *Scheduler) runJob(j *job) {
+	s.Lock()
+	j.status.Running = true
+	s.Unlock()
+
+	start := s.clock.Now()
+	err := safeRun(j.run)
+	duration := s.clock.Now().Sub(start)
+
+	jobRuns.Inc(j.name)
+	s.Lock()
+	defer s.Unlock()
+	j.status.Running = false
+	j.status.Runs++
+	j.status.LastRun = &start
+	j.status.LastDuration = duration.String()
+	j.status.LastError = ""
+	if err != nil {
+		jobFailures.Inc(j.name)
+		j.status.Failures++
+		j.status.LastError = err.Error()
+		logrus.WithError(err).WithField("job", j.name).Warn("Scheduled job failed")
+	}
+}
+
+// safeRun runs the job, turning a panic into an error so it does not take down the service
+func safeRun(run JobFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return run()
+}
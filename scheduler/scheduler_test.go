@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestScheduler(t *testing.T) {
+	t.Run("runs a job every interval", func(t *testing.T) {
+		clk := clock.NewFake(time.Now())
+		s := newScheduler(clk, 0)
+		ran := make(chan struct{}, 2)
+		s.Add("gc", time.Minute, func() error {
+			ran <- struct{}{}
+			return nil
+		})
+		s.Start()
+		defer s.Stop()
+
+		for i := 0; i < 2; i++ {
+			clk.BlockUntil(1)
+			clk.Advance(time.Minute)
+			<-ran
+		}
+		clk.BlockUntil(1)
+
+		status := s.Status()
+		tests.H(t).IntEql(len(status), 1)
+		tests.H(t).StringEql(status[0].Name, "gc")
+		tests.H(t).IntEql(status[0].Runs, 2)
+		tests.H(t).IntEql(status[0].Failures, 0)
+	})
+
+	t.Run("records failures and recovers from panics", func(t *testing.T) {
+		clk := clock.NewFake(time.Now())
+		s := newScheduler(clk, 0)
+		s.Add("failing", time.Minute, func() error {
+			return errors.New("probe failed")
+		})
+		s.Add("panicking", time.Minute, func() error {
+			panic("boom")
+		})
+		s.Start()
+		defer s.Stop()
+
+		clk.BlockUntil(2)
+		clk.Advance(time.Minute)
+		clk.BlockUntil(2)
+
+		status := s.Status()
+		tests.H(t).IntEql(len(status), 2)
+		tests.H(t).IntEql(status[0].Failures, 1)
+		tests.H(t).StringEql(status[0].LastError, "probe failed")
+		tests.H(t).IntEql(status[1].Failures, 1)
+		tests.H(t).StringEql(status[1].LastError, "job panicked: boom")
+	})
+
+	t.Run("skips jobs without interval", func(t *testing.T) {
+		s := newScheduler(clock.NewFake(time.Now()), 0)
+		s.Add("disabled", 0, func() error { return nil })
+
+		tests.H(t).IntEql(len(s.Status()), 0)
+	})
+
+	t.Run("adds jitter up to the configured fraction", func(t *testing.T) {
+		s := newScheduler(clock.NewFake(time.Now()), DefaultJitter)
+		for i := 0; i < 100; i++ {
+			delay := s.delay(time.Minute)
+			tests.H(t).BoolEqlWithMessage(delay >= time.Minute && delay < 66*time.Second, true, delay.String())
+		}
+	})
+
+	t.Run("Status is empty for a nil scheduler", func(t *testing.T) {
+		var s *Scheduler
+		tests.H(t).IntEql(len(s.Status()), 0)
+	})
+}
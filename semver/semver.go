@@ -0,0 +1,143 @@
+package semver
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidVersion occurs if a version string is not a valid semantic version
+	ErrInvalidVersion = errors.New("invalid semantic version")
+)
+
+// Version is a parsed semantic version, see https://semver.org
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	PreRelease []string
+	Build      string
+}
+
+// Parse parses a semantic version string, a leading `v` is ignored
+func Parse(version string) (Version, error) {
+	var v Version
+	s := strings.TrimPrefix(version, "v")
+	if i := strings.Index(s, "+"); i >= 0 {
+		v.Build = s[i+1:]
+		s = s[:i]
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		if i == len(s)-1 {
+			return Version{}, errors.Wrap(ErrInvalidVersion, version)
+		}
+		v.PreRelease = strings.Split(s[i+1:], ".")
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return Version{}, errors.Wrap(ErrInvalidVersion, version)
+	}
+	numbers := make([]uint64, 3)
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return Version{}, errors.Wrap(ErrInvalidVersion, version)
+		}
+		numbers[i] = n
+	}
+	v.Major, v.Minor, v.Patch = numbers[0], numbers[1], numbers[2]
+	return v, nil
+}
+
+// IsPreRelease returns true if the version has a pre-release component
+func (v Version) IsPreRelease() bool {
+	return len(v.PreRelease) > 0
+}
+
+// String implements fmt.Stringer
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.IsPreRelease() {
+		s += "-" + strings.Join(v.PreRelease, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 if v is lower, equal or higher than other, build metadata is ignored
+func (v Version) Compare(other Version) int {
+	if c := compareUint(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	// a version without pre-release has higher precedence
+	switch {
+	case !v.IsPreRelease() && !other.IsPreRelease():
+		return 0
+	case !v.IsPreRelease():
+		return 1
+	case !other.IsPreRelease():
+		return -1
+	}
+	for i := 0; i < len(v.PreRelease) && i < len(other.PreRelease); i++ {
+		if c := comparePreReleaseIdentifier(v.PreRelease[i], other.PreRelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(v.PreRelease)), uint64(len(other.PreRelease)))
+}
+
+func comparePreReleaseIdentifier(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(an, bn)
+	case aErr == nil:
+		// numeric identifiers have lower precedence
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// SortDescending sorts version strings from highest to lowest. Versions that are not valid
+// semantic versions are sorted lexically after all valid versions.
+func SortDescending(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		vi, iErr := Parse(versions[i])
+		vj, jErr := Parse(versions[j])
+		switch {
+		case iErr == nil && jErr == nil:
+			return vi.Compare(vj) > 0
+		case iErr == nil:
+			return true
+		case jErr == nil:
+			return false
+		}
+		return versions[i] < versions[j]
+	})
+}
@@ -0,0 +1,56 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("parses release versions", func(t *testing.T) {
+		v, err := Parse("2.25.1")
+		tests.H(t).IsNil(err)
+		tests.H(t).InterfaceEql(v, Version{Major: 2, Minor: 25, Patch: 1})
+	})
+
+	t.Run("parses pre-release and build metadata", func(t *testing.T) {
+		v, err := Parse("v1.0.20-3.0.10+abc")
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(v.IsPreRelease(), true)
+		tests.H(t).StringEql(v.String(), "1.0.20-3.0.10+abc")
+	})
+
+	t.Run("rejects invalid versions", func(t *testing.T) {
+		for _, version := range []string{"", "2.25", "2.x.1", "2.25.1-", "master"} {
+			_, err := Parse(version)
+			tests.H(t).NotNil(err)
+		}
+	})
+}
+
+func TestCompare(t *testing.T) {
+	var testCases = []struct {
+		a, b string
+		want int
+	}{
+		{"2.25.1", "2.25.1", 0},
+		{"2.25.1", "2.25.0", 1},
+		{"2.9.0", "2.25.0", -1},
+		{"2.25.0-rc.1", "2.25.0", -1},
+		{"2.25.0-rc.2", "2.25.0-rc.10", -1},
+		{"2.25.0-1", "2.25.0-rc", -1},
+		{"2.25.0-rc.1", "2.25.0-rc.1.1", -1},
+		{"2.25.0+a", "2.25.0+b", 0},
+	}
+	for _, tt := range testCases {
+		a, _ := Parse(tt.a)
+		b, _ := Parse(tt.b)
+		tests.H(t).IntEql(a.Compare(b), tt.want)
+	}
+}
+
+func TestSortDescending(t *testing.T) {
+	versions := []string{"2.9.0", "master", "2.25.0", "2.25.0-rc.1", "1.0.20-3.0.10", "dev"}
+	SortDescending(versions)
+	tests.H(t).InterfaceEql(versions, []string{"2.25.0", "2.25.0-rc.1", "2.9.0", "1.0.20-3.0.10", "dev", "master"})
+}
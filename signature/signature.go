@@ -0,0 +1,147 @@
+// Package signature verifies detached signatures of ui bundles with trusted public keys. Keys are either
+// PEM encoded ECDSA or RSA public keys, verifying cosign style base64 signatures of the SHA-256 digest of
+// the bundle, or armored OpenPGP public keys verifying armored or binary GPG signatures.
+package signature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+)
+
+var (
+	// ErrInvalid occurs if a signature does not match the content with any trusted key
+	ErrInvalid = errors.New("signature does not match any trusted key")
+	// ErrMalformed occurs if a signature is neither an OpenPGP nor a base64 signature
+	ErrMalformed = errors.New("signature is malformed")
+	// ErrNoKeys occurs if a key file holds no supported public key
+	ErrNoKeys = errors.New("no ECDSA, RSA or OpenPGP public key found")
+)
+
+// armoredPGPPrefix starts armored OpenPGP signatures and keys
+const armoredPGPPrefix = "-----BEGIN PGP"
+
+// Verifier checks signatures with the trusted keys
+type Verifier struct {
+	keys    []crypto.PublicKey
+	keyring openpgp.EntityList
+}
+
+// LoadKeys reads the trusted public keys from files, each file holds PEM public keys or an armored
+// OpenPGP key ring
+func LoadKeys(files []string) (*Verifier, error) {
+	v := &Verifier{}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read public key file %s", file)
+		}
+		if err := v.add(data); err != nil {
+			return nil, errors.Wrapf(err, "failed to load public key file %s", file)
+		}
+	}
+	return v, nil
+}
+
+func (v *Verifier) add(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(armoredPGPPrefix)) {
+		keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		v.keyring = append(v.keyring, keyring...)
+		return nil
+	}
+	found := false
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return err
+		}
+		switch key.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey:
+			v.keys = append(v.keys, key)
+			found = true
+		default:
+			return errors.Errorf("unsupported public key type %T", key)
+		}
+	}
+	if !found {
+		return ErrNoKeys
+	}
+	return nil
+}
+
+// Verify checks that signature signs the content read from r with one of the trusted keys
+func (v *Verifier) Verify(r io.Reader, signature []byte) error {
+	trimmed := bytes.TrimSpace(signature)
+	if len(trimmed) == 0 {
+		return ErrMalformed
+	}
+	if bytes.HasPrefix(trimmed, []byte(armoredPGPPrefix)) {
+		return v.verifyPGP(openpgp.CheckArmoredDetachedSignature, r, trimmed)
+	}
+	if trimmed[0]&0x80 != 0 {
+		// binary OpenPGP packets have the high bit of the first byte set, base64 never has
+		return v.verifyPGP(openpgp.CheckDetachedSignature, r, signature)
+	}
+	return v.verifyDigest(r, trimmed)
+}
+
+type checkFunc func(openpgp.KeyRing, io.Reader, io.Reader) (*openpgp.Entity, error)
+
+func (v *Verifier) verifyPGP(check checkFunc, r io.Reader, signature []byte) error {
+	if len(v.keyring) == 0 {
+		return ErrInvalid
+	}
+	if _, err := check(v.keyring, r, bytes.NewReader(signature)); err != nil {
+		return errors.Wrap(ErrInvalid, err.Error())
+	}
+	return nil
+}
+
+// ecdsaSignature is the ASN.1 encoding of ECDSA signatures
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+func (v *Verifier) verifyDigest(r io.Reader, encoded []byte) error {
+	raw, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return ErrMalformed
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return err
+	}
+	digest := hash.Sum(nil)
+	for _, key := range v.keys {
+		switch key := key.(type) {
+		case *ecdsa.PublicKey:
+			var sig ecdsaSignature
+			if _, err := asn1.Unmarshal(raw, &sig); err == nil && sig.R != nil && sig.S != nil && ecdsa.Verify(key, digest, sig.R, sig.S) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, raw) == nil {
+				return nil
+			}
+		}
+	}
+	return ErrInvalid
+}
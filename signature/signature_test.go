@@ -0,0 +1,136 @@
+package signature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+const bundle = "dcos-ui bundle content"
+
+func writeKeyFile(t *testing.T, dir, name string, data []byte) string {
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func pemPublicKey(t *testing.T, key crypto.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestVerifyDigestSignature(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "signature")
+	defer os.RemoveAll(dir)
+	digest := sha256.Sum256([]byte(bundle))
+
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecdsaSignature, _ := ecdsaKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaSignature, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	verifier, err := LoadKeys([]string{
+		writeKeyFile(t, dir, "cosign.pub", pemPublicKey(t, &ecdsaKey.PublicKey)),
+		writeKeyFile(t, dir, "rsa.pub", pemPublicKey(t, &rsaKey.PublicKey)),
+	})
+	tests.H(t).IsNil(err)
+
+	t.Run("accepts an ECDSA signature", func(t *testing.T) {
+		signature := base64.StdEncoding.EncodeToString(ecdsaSignature) + "\n"
+		tests.H(t).IsNil(verifier.Verify(strings.NewReader(bundle), []byte(signature)))
+	})
+
+	t.Run("accepts an RSA signature", func(t *testing.T) {
+		signature := base64.StdEncoding.EncodeToString(rsaSignature)
+		tests.H(t).IsNil(verifier.Verify(strings.NewReader(bundle), []byte(signature)))
+	})
+
+	t.Run("rejects a signature of other content", func(t *testing.T) {
+		signature := base64.StdEncoding.EncodeToString(ecdsaSignature)
+		tests.H(t).ErrEql(verifier.Verify(strings.NewReader("tampered"), []byte(signature)), ErrInvalid)
+	})
+
+	t.Run("rejects a signature of an untrusted key", func(t *testing.T) {
+		untrusted, _ := otherKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+		signature := base64.StdEncoding.EncodeToString(untrusted)
+		tests.H(t).ErrEql(verifier.Verify(strings.NewReader(bundle), []byte(signature)), ErrInvalid)
+	})
+
+	t.Run("rejects a malformed signature", func(t *testing.T) {
+		tests.H(t).ErrEql(verifier.Verify(strings.NewReader(bundle), []byte("not base64!")), ErrMalformed)
+		tests.H(t).ErrEql(verifier.Verify(strings.NewReader(bundle), nil), ErrMalformed)
+	})
+}
+
+func TestVerifyPGPSignature(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "signature")
+	defer os.RemoveAll(dir)
+	entity, err := openpgp.NewEntity("DC/OS UI", "", "ui@example.com", nil)
+	tests.H(t).IsNil(err)
+	var publicKey bytes.Buffer
+	w, _ := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	entity.Serialize(w)
+	w.Close()
+	verifier, err := LoadKeys([]string{writeKeyFile(t, dir, "ui.asc", publicKey.Bytes())})
+	tests.H(t).IsNil(err)
+
+	t.Run("accepts an armored signature", func(t *testing.T) {
+		var signature bytes.Buffer
+		tests.H(t).IsNil(openpgp.ArmoredDetachSign(&signature, entity, strings.NewReader(bundle), nil))
+
+		tests.H(t).IsNil(verifier.Verify(strings.NewReader(bundle), signature.Bytes()))
+	})
+
+	t.Run("accepts a binary signature", func(t *testing.T) {
+		var signature bytes.Buffer
+		tests.H(t).IsNil(openpgp.DetachSign(&signature, entity, strings.NewReader(bundle), nil))
+
+		tests.H(t).IsNil(verifier.Verify(strings.NewReader(bundle), signature.Bytes()))
+	})
+
+	t.Run("rejects a signature of other content", func(t *testing.T) {
+		var signature bytes.Buffer
+		openpgp.ArmoredDetachSign(&signature, entity, strings.NewReader(bundle), nil)
+
+		err := verifier.Verify(strings.NewReader("tampered"), signature.Bytes())
+		tests.H(t).ErrEql(errors.Cause(err), ErrInvalid)
+	})
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "signature")
+	defer os.RemoveAll(dir)
+
+	t.Run("returns ErrNoKeys for a file without public key", func(t *testing.T) {
+		_, err := LoadKeys([]string{writeKeyFile(t, dir, "empty.pub", []byte("no key"))})
+		tests.H(t).ErrEql(errors.Cause(err), ErrNoKeys)
+	})
+
+	t.Run("fails for a missing file", func(t *testing.T) {
+		_, err := LoadKeys([]string{filepath.Join(dir, "missing.pub")})
+		tests.H(t).BoolEql(err != nil, true)
+	})
+}
@@ -0,0 +1,72 @@
+package uiservice
+
+import (
+	"path"
+	"sync"
+
+	"github.com/dcos/dcos-ui-update-service/health"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// accessCheckPrefix is the prefix of the ephemeral scratch nodes written to verify the ZK credentials and ACLs
+const accessCheckPrefix = "access-check-"
+
+// ErrZKAccessDenied occurs if ZK rejects writes below the base path with the configured credentials
+var ErrZKAccessDenied = errors.New("ZK denied access to the base path, zk-auth-info must match zk-znode-owner and the ACLs of the existing nodes")
+
+// StoreAccess is implemented by version stores which verify that they can write with the configured credentials
+type StoreAccess interface {
+	// AccessError returns the error of the last access check, nil if it succeeded or did not run yet
+	AccessError() error
+}
+
+type zkAccess struct {
+	err error
+	sync.Mutex
+}
+
+// verifyAccess creates, sets and deletes a scratch node with the configured ACLs each time the
+// connection is established, so wrong credentials are reported right away instead of failing updates
+func (zks *zkVersionStore) verifyAccess() {
+	err := zks.writeAccessCheckNode()
+	if errors.Cause(err) == zookeeper.ErrNoAuth {
+		err = errors.Wrap(ErrZKAccessDenied, err.Error())
+	}
+	zks.access.Lock()
+	zks.access.err = err
+	zks.access.Unlock()
+
+	if err != nil {
+		health.DefaultTracker.Record(health.Zookeeper, err)
+		log.WithError(err).WithFields(logrus.Fields{
+			"basePath":   zks.zkBasePath,
+			"znodeOwner": zks.znodeOwner,
+		}).Error("ZK access check failed, version updates will fail until the ZK credentials or ACLs are fixed")
+		return
+	}
+	log.Debug("Verified write access to ZK base path")
+}
+
+func (zks *zkVersionStore) writeAccessCheckNode() error {
+	node, err := zks.client.CreateEphemeralSequential(path.Join(zks.zkBasePath, accessCheckPrefix), nil, zookeeper.PermAll)
+	if err != nil {
+		return errors.Wrap(err, "could not create ZK access check node")
+	}
+	if _, err := zks.client.Set(node, []byte("ok")); err != nil {
+		zks.client.Delete(node)
+		return errors.Wrap(err, "could not set ZK access check node")
+	}
+	if err := zks.client.Delete(node); err != nil {
+		return errors.Wrap(err, "could not delete ZK access check node")
+	}
+	return nil
+}
+
+// AccessError returns the error of the last ZK access check
+func (zks *zkVersionStore) AccessError() error {
+	zks.access.Lock()
+	defer zks.access.Unlock()
+	return zks.access.err
+}
@@ -0,0 +1,67 @@
+package uiservice
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/health"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	pkgerrors "github.com/pkg/errors"
+)
+
+func TestZKAccessCheck(t *testing.T) {
+	defer health.DefaultTracker.Record(health.Zookeeper, nil)
+
+	t.Run("removes the access check node after writing it", func(t *testing.T) {
+		store, fakeClient := makeZKStore("")
+		var deleted []string
+		fakeClient.DeleteCall = func(path string) {
+			deleted = append(deleted, path)
+		}
+
+		store.verifyAccess()
+
+		tests.H(t).IsNil(store.AccessError())
+		tests.H(t).IntEql(len(fakeClient.SequentialCreated), 1)
+		tests.H(t).BoolEql(strings.HasPrefix(fakeClient.SequentialCreated[0], "/dcos/ui-service-test/access-check-"), true)
+		tests.H(t).IntEql(len(deleted), 1)
+		tests.H(t).StringEql(deleted[0], fakeClient.SequentialCreated[0])
+	})
+
+	t.Run("reports ErrZKAccessDenied if the credentials are rejected", func(t *testing.T) {
+		store, fakeClient := makeZKStore("")
+		fakeClient.CreateError = zookeeper.ErrNoAuth
+
+		store.verifyAccess()
+
+		tests.H(t).ErrEql(pkgerrors.Cause(store.AccessError()), ErrZKAccessDenied)
+		tests.H(t).StringEql(string(health.DefaultTracker.Status(health.Zookeeper).State), string(health.StateDegraded))
+	})
+
+	t.Run("removes the access check node if it cannot be set", func(t *testing.T) {
+		store, fakeClient := makeZKStore("")
+		fakeClient.SetError = errors.New("set failed")
+		deleted := 0
+		fakeClient.DeleteCall = func(string) {
+			deleted++
+		}
+
+		store.verifyAccess()
+
+		tests.H(t).NotNil(store.AccessError())
+		tests.H(t).IntEql(deleted, 1)
+	})
+
+	t.Run("clears the error once the access check succeeds", func(t *testing.T) {
+		store, fakeClient := makeZKStore("")
+		fakeClient.CreateError = zookeeper.ErrNoAuth
+		store.verifyAccess()
+
+		fakeClient.CreateError = nil
+		store.verifyAccess()
+
+		tests.H(t).IsNil(store.AccessError())
+	})
+}
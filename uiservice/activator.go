@@ -0,0 +1,172 @@
+package uiservice
+
+import (
+	"context"
+	"os"
+	"strconv"
+
+	"github.com/dcos/dcos-ui-update-service/hooks"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// distActivator activates a version by swapping the ui dist symlink and, if storeVersion is set,
+// saving the version to the version store so the other masters follow
+type distActivator struct {
+	service      *UIService
+	version      UIVersion
+	storeVersion bool
+	// result records the decisions of a cluster operation, nil for local activations
+	result *ClusterOperationResult
+	// soak defers storing the version to promote, which soaks the committed version first
+	soak         bool
+	committed    bool
+	previousPath string
+}
+
+// newLocalActivator creates an activator only changing the version served by this master
+func newLocalActivator(service *UIService) *distActivator {
+	return &distActivator{service: service}
+}
+
+// newClusterActivator creates an activator serving the version and saving it to the version store,
+// the steps are recorded as decisions of result. With a soak duration the version is saved by promote.
+func newClusterActivator(service *UIService, version UIVersion, result *ClusterOperationResult) *distActivator {
+	return &distActivator{
+		service:      service,
+		version:      version,
+		storeVersion: true,
+		result:       result,
+		soak:         service.Config.SoakDuration() > 0,
+	}
+}
+
+func (a *distActivator) Prepare(newVersionPath string) error {
+	if err := beginActivation(a.service); err != nil {
+		a.decide(DecisionPreempted, nil, err)
+		return err
+	}
+	a.previousPath, _ = os.Readlink(a.service.Config.UIDistSymlink())
+	if _, err := os.Stat(newVersionPath); err != nil {
+		return errors.Wrap(err, "new version path is not accessible")
+	}
+	// a staging symlink left over by a crashed swap would fail the commit
+	stageSymlink := a.service.Config.UIDistStageSymlink()
+	if _, err := os.Lstat(stageSymlink); err == nil {
+		if err := os.Remove(stageSymlink); err != nil {
+			return errors.Wrap(err, "unable to remove stale staging symlink")
+		}
+	}
+	return nil
+}
+
+func (a *distActivator) Commit(newVersionPath string) error {
+	err := updateServedVersion(a.service, newVersionPath)
+	a.decide(DecisionActivate, map[string]string{"path": newVersionPath}, err)
+	if err != nil {
+		return errors.Wrap(err, "unable to update the ui dist symlink to the new version")
+	}
+	a.committed = true
+	if !a.storeVersion || a.soak {
+		return nil
+	}
+	return a.store()
+}
+
+// store saves the version to the version store so the other masters follow
+func (a *distActivator) store() error {
+	conflicts, _ := a.service.VersionStore.(VersionConflicts)
+	var before *VersionConflict
+	if conflicts != nil {
+		before = conflicts.LastVersionConflict()
+	}
+	err := a.service.VersionStore.UpdateCurrentVersion(a.version)
+	inputs := map[string]string{"version": string(a.version)}
+	if conflicts != nil {
+		// a version another master stored concurrently was overwritten by this write
+		if conflict := conflicts.LastVersionConflict(); conflict != nil && conflict != before {
+			inputs["overwritten"] = string(conflict.Overwritten)
+			inputs["conflictRule"] = conflict.Rule
+		}
+	}
+	a.decide(DecisionStoreVersion, inputs, err)
+	if err != nil {
+		return versionStoreError{errors.Wrap(err, "unable to save new version to the version store")}
+	}
+	return nil
+}
+
+func (a *distActivator) Rollback(previousVersionPath string) error {
+	if previousVersionPath == "" {
+		return nil
+	}
+	if target, err := os.Readlink(a.service.Config.UIDistSymlink()); err == nil && target == previousVersionPath {
+		// the commit failed before swapping the symlink
+		return nil
+	}
+	err := updateServedVersion(a.service, previousVersionPath)
+	a.decide(DecisionRollback, map[string]string{"path": previousVersionPath}, err)
+	return err
+}
+
+// Context is done once the update was cancelled, the download stops before the version is activated
+func (a *distActivator) Context() context.Context {
+	return operationContext(a.service)
+}
+
+// ReportHookResults records the post-activate hooks of a cluster operation, they run after the version
+// was committed, so a failure is reported without failing the operation
+func (a *distActivator) ReportHookResults(point hooks.Point, results []hooks.Result) {
+	if point != hooks.PostActivate || a.result == nil || len(results) == 0 {
+		return
+	}
+	a.result.PostActivateHooks = results
+	var err error
+	if failed := a.result.failedHook(); failed != nil {
+		err = errors.Errorf("hook %s failed: %s", failed.Name, failed.Error)
+	}
+	a.decide(DecisionPostActivate, map[string]string{"hooks": strconv.Itoa(len(results))}, err)
+}
+
+// PublishToMirror is true for cluster updates, the master leading the update publishes the bundle
+func (a *distActivator) PublishToMirror() bool {
+	return a.storeVersion
+}
+
+// promote soaks a committed version before storing it for the other masters. If the soak or storing the
+// version fails, the previously served version is restored. Without a soak the version was stored by Commit.
+func (a *distActivator) promote() error {
+	if !a.soak || !a.committed {
+		return nil
+	}
+	probes, failures, err := soakVersion(a.service, a.version)
+	a.decide(DecisionSoak, map[string]string{
+		"url":      a.service.Config.SoakProbeURL(),
+		"duration": a.service.Config.SoakDuration().String(),
+		"probes":   strconv.Itoa(probes),
+		"failures": strconv.Itoa(failures),
+	}, err)
+	if err == nil {
+		err = a.store()
+	}
+	if err == nil {
+		return nil
+	}
+	previousPath := a.previousPath
+	if _, statErr := os.Stat(previousPath); previousPath == "" || statErr != nil {
+		// the previous version was pruned after the activation
+		previousPath = a.service.Config.DefaultDocRoot()
+	}
+	restoreErr := updateServedVersion(a.service, previousPath)
+	a.decide(DecisionRollback, map[string]string{"path": previousPath}, restoreErr)
+	if restoreErr != nil {
+		logrus.WithError(restoreErr).WithField("path", previousPath).Error("Restoring the previous version failed")
+	}
+	return err
+}
+
+func (a *distActivator) decide(step string, inputs map[string]string, err error) {
+	if a.result != nil {
+		a.result.decide(step, inputs, decisionOutcome(err))
+	}
+}
@@ -0,0 +1,90 @@
+package uiservice
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/hooks"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestDistActivator(t *testing.T) {
+	t.Run("commit serves the version and saves it to the store", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		store := VersionStoreDouble()
+		service.VersionStore = store
+		newVersionPath := path.Join(service.Config.VersionsRoot(), "2.24.4", "dist")
+		os.MkdirAll(newVersionPath, 0755)
+
+		activator := newClusterActivator(service, "2.24.4", nil)
+		tests.H(t).ErrEql(activator.Prepare(newVersionPath), nil)
+		tests.H(t).ErrEql(activator.Commit(newVersionPath), nil)
+
+		target, _ := os.Readlink(service.Config.UIDistSymlink())
+		tests.H(t).StringEql(target, newVersionPath)
+		tests.H(t).InterfaceEql(store.UpdatedVersions, []UIVersion{"2.24.4"})
+	})
+
+	t.Run("prepare fails if the version path is missing", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		err := newLocalActivator(service).Prepare(path.Join(service.Config.VersionsRoot(), "2.24.4", "dist"))
+		tests.H(t).BoolEql(err != nil, true)
+	})
+
+	t.Run("prepare removes a stale staging symlink", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		newVersionPath := path.Join(service.Config.VersionsRoot(), "2.24.4", "dist")
+		os.MkdirAll(newVersionPath, 0755)
+		os.Symlink(service.Config.DefaultDocRoot(), service.Config.UIDistStageSymlink())
+
+		activator := newLocalActivator(service)
+		tests.H(t).ErrEql(activator.Prepare(newVersionPath), nil)
+		tests.H(t).ErrEql(activator.Commit(newVersionPath), nil)
+	})
+
+	t.Run("rollback serves the previous version after a failed store write", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		store := VersionStoreDouble()
+		store.UpdateError = errors.New("zk unavailable")
+		service.VersionStore = store
+		newVersionPath := path.Join(service.Config.VersionsRoot(), "2.24.4", "dist")
+		os.MkdirAll(newVersionPath, 0755)
+
+		activator := newClusterActivator(service, "2.24.4", nil)
+		tests.H(t).BoolEql(activator.Commit(newVersionPath) != nil, true)
+		tests.H(t).ErrEql(activator.Rollback(service.Config.DefaultDocRoot()), nil)
+
+		target, _ := os.Readlink(service.Config.UIDistSymlink())
+		tests.H(t).StringEql(target, service.Config.DefaultDocRoot())
+	})
+
+	t.Run("reports a failed post-activate hook in the operation result", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		result := &ClusterOperationResult{Operation: OperationUpdate, Version: "2.24.4"}
+		activator := newClusterActivator(service, "2.24.4", result)
+
+		activator.ReportHookResults(hooks.PreActivate, []hooks.Result{{Name: "check"}})
+		activator.ReportHookResults(hooks.PostActivate, []hooks.Result{
+			{Name: "POST http://127.0.0.1:61001/purge", Error: "hook responded with 502 Bad Gateway"},
+		})
+
+		tests.H(t).IntEql(len(result.PostActivateHooks), 1)
+		tests.H(t).IntEql(len(result.Decisions), 1)
+		tests.H(t).StringEql(result.Decisions[0].Step, DecisionPostActivate)
+		tests.H(t).StringContains(result.Decisions[0].Outcome, "502 Bad Gateway")
+
+		rr := httptest.NewRecorder()
+		writeOperationResult(rr, httptest.NewRequest("POST", "/api/v1/update/2.24.4/", nil), http.StatusOK, result, "Update to 2.24.4 completed")
+		tests.H(t).StringContains(rr.Body.String(), "Post-activate hook POST http://127.0.0.1:61001/purge failed")
+	})
+}
@@ -4,23 +4,128 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/health"
+	"github.com/dcos/dcos-ui-update-service/memory"
+	"github.com/dcos/dcos-ui-update-service/metrics"
+	"github.com/dcos/dcos-ui-update-service/scheduler"
 	"github.com/dcos/dcos-ui-update-service/updatemanager"
 	"github.com/gorilla/mux"
-	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
 func newRouter(service *UIService) *mux.Router {
 	r := mux.NewRouter()
+	// reading routes answer quickly, the routes changing the served version may download a bundle
+	readTimeout, updateTimeout := service.Config.APIReadTimeout(), service.Config.APIUpdateTimeout()
 	r.HandleFunc("/api/v1/", notImplementedHandler)
-	r.HandleFunc("/api/v1/version/", versionHandler(service)).Methods("GET")
-	r.HandleFunc("/api/v1/update/{version}/", updateHandler(service)).Methods("POST")
-	r.HandleFunc("/api/v1/reset/", resetToDefaultUIHandler(service)).Methods("DELETE")
+	r.HandleFunc("/api/v1/version/", routeTimeout(readTimeout, versionHandler(service))).Methods("GET")
+	r.HandleFunc("/api/v1/version/export/", exportVersionHandler(service)).Methods("GET")
+	r.HandleFunc("/api/v1/versions/", routeTimeout(readTimeout, availableVersionsHandler(service))).Methods("GET")
+	r.HandleFunc("/api/v1/compatibility/", compatibilityHandler(service)).Methods("GET")
+	r.HandleFunc("/api/v1/assets-manifest/", assetsManifestHandler(service)).Methods("GET")
+	r.HandleFunc("/api/v1/versions/{version}/", routeTimeout(updateTimeout, leaderOnly(service, removeVersionHandler(service)))).Methods("DELETE")
+	r.HandleFunc("/api/v1/versions/local/{version}/provenance/", provenanceHandler(service)).Methods("GET")
+	r.HandleFunc("/api/v1/versions/local/{version}/provenance/", routeTimeout(updateTimeout, approvalTicketHandler(service))).Methods("PUT")
+	r.HandleFunc("/api/v1/versions/local/", localVersionsHandler(service)).Methods("GET")
+	r.HandleFunc("/api/v1/versions/local/{version}/labels/{label}/", routeTimeout(updateTimeout, setLabelHandler(service))).Methods("PUT")
+	r.HandleFunc("/api/v1/versions/local/{version}/labels/{label}/", routeTimeout(updateTimeout, removeLabelHandler(service))).Methods("DELETE")
+	r.HandleFunc("/api/v1/activate/by-label/{label}/", routeTimeout(updateTimeout, activateByLabelHandler(service))).Methods("POST")
+	r.HandleFunc("/api/v1/trash/", trashHandler(service)).Methods("GET")
+	r.HandleFunc("/api/v1/trash/{version}/restore/", routeTimeout(updateTimeout, restoreVersionHandler(service))).Methods("POST")
+	r.HandleFunc("/api/v1/update/available/", routeTimeout(readTimeout, updateAvailableHandler(service))).Methods("GET")
+	r.HandleFunc("/api/v1/update/", routeTimeout(updateTimeout, leaderOnly(service, installBundleHandler(service)))).Methods("POST")
+	r.HandleFunc("/api/v1/update/{version}/", routeTimeout(updateTimeout, leaderOnly(service, updateHandler(service)))).Methods("POST")
+	r.HandleFunc("/api/v1/update/{version}/", routeTimeout(updateTimeout, leaderOnly(service, cancelUpdateHandler(service)))).Methods("DELETE")
+	r.HandleFunc("/api/v1/update/status/{jobID}/", routeTimeout(readTimeout, updateStatusHandler(service))).Methods("GET")
+	r.HandleFunc("/api/v1/reset/", routeTimeout(updateTimeout, leaderOnly(service, resetToDefaultUIHandler(service)))).Methods("DELETE")
+	r.HandleFunc("/api/v1/rollback/", routeTimeout(updateTimeout, leaderOnly(service, rollbackHandler(service)))).Methods("POST")
+	r.HandleFunc("/api/v1/hooks/rerun/", routeTimeout(updateTimeout, leaderOnly(service, rerunHooksHandler(service)))).Methods("POST")
+	r.HandleFunc("/api/v1/operations/", operationsHandler(service)).Methods("GET")
+	r.HandleFunc("/api/v1/history/", historyHandler(service)).Methods("GET")
+	r.HandleFunc("/api/v1/history/cluster/", clusterHistoryHandler(service)).Methods("GET")
+	r.HandleFunc("/api/v1/operations/{id}/", routeTimeout(updateTimeout, dequeueHandler(service))).Methods("DELETE")
+	r.HandleFunc("/api/v1/node/", routeTimeout(readTimeout, nodeHandler(service))).Methods("GET")
+	r.HandleFunc("/api/v1/status/", routeTimeout(readTimeout, clusterStateHandler(service))).Methods("GET")
+	r.HandleFunc("/api/v1/health/", healthHandler(service)).Methods("GET")
+	r.Handle("/api/v1/metrics/", metrics.DefaultRegistry.Handler()).Methods("GET")
+	r.HandleFunc(diagnosticsPath, diagnosticsHandler(service)).Methods("GET")
+	r.HandleFunc("/api/v1/logs/", logsHandler(service)).Methods("GET")
+	r.HandleFunc("/api/v1/state/export/", exportStateHandler(service)).Methods("GET")
+	r.HandleFunc("/api/v1/state/import/", routeTimeout(updateTimeout, leaderOnly(service, importStateHandler(service)))).Methods("POST")
+	r.HandleFunc("/api/v1/acknowledge-failure/", routeTimeout(updateTimeout, leaderOnly(service, acknowledgeFailureHandler(service)))).Methods("POST")
+	r.HandleFunc("/api/v1/pin/{version}/", routeTimeout(updateTimeout, leaderOnly(service, pinHandler(service)))).Methods("POST")
+	r.HandleFunc("/api/v1/pin/", routeTimeout(updateTimeout, leaderOnly(service, unpinHandler(service)))).Methods("DELETE")
+	if service.Config.DebugEndpoints() {
+		r.HandleFunc("/api/v1/debug/asset/{version}/", debugAssetHandler(service)).Methods("GET")
+		r.HandleFunc("/api/v1/debug/statemachine/", stateMachineHandler(service)).Methods("GET")
+	}
+
+	if service.Config.UIVersionSelection() {
+		r.HandleFunc("/api/v1/versions/{version}/install/", routeTimeout(updateTimeout, installVersionHandler(service))).Methods("POST")
+	}
+	if service.Config.ServeUI() {
+		r.PathPrefix(service.Config.UIAssetPrefix()).MatcherFunc(notAPIPath).Handler(service.uiFileHandler()).Methods("GET", "HEAD")
+	}
+
+	r.NotFoundHandler = trailingSlashRedirectHandler(r)
+
+	if rate := service.Config.APIRateLimit(); rate > 0 {
+		limiter := newRateLimiter(rate, service.Config.APIRateBurst(), clock.New())
+		limiter.trustedProxy = service.Config.APIRateLimitTrustedProxy()
+		r.Use(apiRateLimit(limiter))
+	}
+	if max := service.Config.APIMaxRequestBody(); max > 0 {
+		r.Use(apiRequestBodyLimit(max))
+	}
+	if service.recorder != nil {
+		r.Use(service.recorder.middleware)
+	}
+	if service.apiAuth != nil {
+		r.Use(apiAuthentication(service.apiAuth))
+	}
+	if header := service.Config.CSRFHeader(); header != "" {
+		r.Use(csrfProtection(header, service.Config.CSRFAllowedOrigins()))
+	}
+	if service.Config.Replica() {
+		r.Use(replicaReadOnly())
+	}
 
 	return r
 }
 
+// trailingSlashRedirectHandler redirects requests missing the trailing slash to the registered route,
+// POST and DELETE requests are redirected with 308 so clients keep the method and body
+func trailingSlashRedirectHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		slashed := r.WithContext(r.Context())
+		slashedURL := *r.URL
+		slashedURL.Path += "/"
+		slashed.URL = &slashedURL
+
+		var match mux.RouteMatch
+		router.Match(slashed, &match)
+		if match.MatchErr == mux.ErrNotFound {
+			http.NotFound(w, r)
+			return
+		}
+
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, slashedURL.String(), status)
+	})
+}
+
 func notImplementedHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
@@ -29,6 +134,8 @@ type versionResponse struct {
 	Default        bool   `json:"default"`
 	PackageVersion string `json:"packageVersion"`
 	BuildVersion   string `json:"buildVersion"`
+	// PackageName is the package or package name alias the served version was installed from
+	PackageName string `json:"packageName,omitempty"`
 }
 
 func versionHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
@@ -39,7 +146,12 @@ func versionHandler(service *UIService) func(http.ResponseWriter, *http.Request)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-		buildVersion, err := buildVersionFromUIIndex(service.Config.UIDistSymlink())
+		var buildVersion string
+		if len(version) > 0 {
+			buildVersion, err = buildVersionFromUIIndex(service.Config.UIDistSymlink())
+		} else {
+			buildVersion, err = defaultUIBuildVersion(service)
+		}
 		if err != nil {
 			logrus.WithError(err).Warn("Failed to read version from UI Dist")
 			buildVersion = ""
@@ -47,14 +159,58 @@ func versionHandler(service *UIService) func(http.ResponseWriter, *http.Request)
 
 		var response versionResponse
 		if len(version) > 0 {
-			response = versionResponse{false, version, buildVersion}
+			response = versionResponse{false, version, buildVersion, service.UpdateManager.PackageName(version)}
 		} else {
-			response = versionResponse{true, "Default", buildVersion}
+			response = versionResponse{true, defaultPackageVersion, buildVersion, ""}
 		}
 		js, err := json.Marshal(response)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
+		w.Header().Set("ETag", packageVersionETag(response.PackageVersion))
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
+
+func availableVersionsHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseVersionsQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		versions, err := service.UpdateManager.AvailableVersions()
+		if err != nil {
+			logrus.WithError(err).Error("Could not list available versions.")
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		var cluster clusterCompatibility
+		if query.compatibleOnly {
+			if cluster, err = loadClusterCompatibility(service); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		response := query.apply(versions, func(version string) bool {
+			return cluster.check(service, version).Installable
+		})
+		response.PackageName = service.UpdateManager.PackageName("")
+		// the installed version is empty while the pre-bundled ui is served
+		response.Installed, err = service.UpdateManager.CurrentVersion()
+		if err != nil {
+			logrus.WithError(err).Warn("Could not get the installed version.")
+		}
+
+		js, err := json.Marshal(response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write(js)
@@ -72,58 +228,205 @@ func updateHandler(service *UIService) func(http.ResponseWriter, *http.Request)
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
-		if updatingVersion, err := setServiceUpdating(service, version); err != nil {
-			if version == updatingVersion {
-				http.Error(
-					w,
-					"Service is currently processing an update request",
-					http.StatusAccepted,
-				)
-			} else {
-				http.Error(
-					w,
-					fmt.Sprintf("Service is currently processing an update request to %s", service.updatingVersion),
-					http.StatusConflict,
-				)
+		updateVersion(service, w, r, version)
+	}
+}
+
+// updateVersion changes the version served by the cluster to version, honoring the async and queue
+// parameters of r
+func updateVersion(service *UIService, w http.ResponseWriter, r *http.Request, version string) {
+	async, err := parseBoolParam(r, "async")
+	if err != nil {
+		http.Error(w, "async must be a boolean", http.StatusBadRequest)
+		return
+	}
+	queue, err := parseBoolParam(r, "queue")
+	if err != nil || (queue && !async) {
+		http.Error(w, "queue must be a boolean and requires async=true", http.StatusBadRequest)
+		return
+	}
+	// queued updates keep their order, a new one waits behind them even if the service is idle
+	if queue && service.updateQueue().Len() > 0 {
+		enqueueUpdate(service, w, version)
+		return
+	}
+	if updatingVersion, err := setServiceUpdating(service, version); err != nil {
+		if queue && err != ErrShuttingDown {
+			enqueueUpdate(service, w, version)
+			return
+		}
+		if version == updatingVersion {
+			writeLocalBusy(service, w, r, http.StatusAccepted, version, updatingVersion,
+				"Service is currently processing an update request")
+		} else {
+			writeLocalBusy(service, w, r, http.StatusConflict, version, updatingVersion,
+				fmt.Sprintf("Service is currently processing an update request to %s", updatingVersion))
+		}
+		return
+	}
+	markPreemptible(service, OperationUpdate)
+	op := newClusterOperation(service, OperationUpdate, version)
+	if rejectIfStale(service, w, r) || rejectIfFrozen(service, w) || rejectIfPinned(service, w, version) || !acquireClusterOperation(service, w, op) {
+		resetServiceFromUpdate(service)
+		return
+	}
+	result := newOperationResult(service, op)
+	finish := func() {
+		releaseClusterOperation(service)
+		resetServiceFromUpdate(service)
+	}
+
+	if async {
+		jobs := service.updateJobs()
+		job := jobs.create(version)
+		clusterActivator := newClusterActivator(service, UIVersion(version), result)
+		activator := &jobActivator{Activator: clusterActivator, jobs: jobs, id: job.ID}
+		go func() {
+			defer finish()
+			err := updateToVersion(service, version, activator)
+			if err == nil {
+				err = clusterActivator.promote()
+			}
+			if err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{"version": version, "job": job.ID}).Error("Update failed")
+			}
+			activator.finish(result.finish(service, err))
+		}()
+		writeUpdateJob(w, http.StatusAccepted, job)
+		return
+	}
+	defer finish()
+	defer cancelOnTimeout(service, r)()
+
+	activator := newClusterActivator(service, UIVersion(version), result)
+	err = updateToVersion(service, version, activator)
+	if err == nil {
+		err = activator.promote()
+	}
+	writeUpdateResult(w, r, result.finish(service, err), err, fmt.Sprintf("Update to %s completed", version))
+}
+
+// writeUpdateResult responds with the result of updating to result.Version, message is sent on success
+func writeUpdateResult(w http.ResponseWriter, r *http.Request, result *ClusterOperationResult, err error, message string) {
+	switch err {
+	case nil:
+		writeOperationResult(w, r, http.StatusOK, result, message)
+	case updatemanager.ErrRequestedVersionNotFound:
+		writeOperationResult(w, r, http.StatusBadRequest, result, err.Error())
+	case updatemanager.ErrReadOnlyFilesystem:
+		writeOperationResult(w, r, http.StatusServiceUnavailable, result, err.Error())
+	case ErrSuperseded, updatemanager.ErrUpdateCancelled:
+		writeOperationResult(w, r, http.StatusConflict, result, err.Error())
+	case updatemanager.ErrBundleSignatureNotFound, updatemanager.ErrBundleSignatureInvalid:
+		writeOperationResult(w, r, http.StatusUnprocessableEntity, result, err.Error())
+	default:
+		logrus.WithFields(logrus.Fields{
+			"version": result.Version,
+			"err":     err,
+		}).Error("Update failed")
+		writeOperationResult(w, r, http.StatusInternalServerError, result, err.Error())
+	}
+}
+
+// removeVersionHandler removes a downloaded version. The currently served version can only be removed
+// with ?force=true&switchToDefault=true, which switches to the pre-bundled UI before removing it.
+func removeVersionHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version := mux.Vars(r)["version"]
+		logrus.WithField("version", version).Debug("Received remove version request.")
+		if !directVersionPattern.MatchString(version) {
+			// the trash, the quarantine and the other dot-prefixed directories of versions-root are no versions
+			http.Error(w, updatemanager.ErrRequestedVersionNotFound.Error(), http.StatusNotFound)
+			return
+		}
+
+		force, forceErr := parseBoolParam(r, "force")
+		switchToDefault, switchErr := parseBoolParam(r, "switchToDefault")
+		if forceErr != nil || switchErr != nil {
+			http.Error(w, "force and switchToDefault must be booleans", http.StatusBadRequest)
+			return
+		}
+		if force && !switchToDefault {
+			http.Error(w, "force is only supported together with switchToDefault", http.StatusBadRequest)
+			return
+		}
+
+		if updatingVersion, lockErr := setServiceUpdating(service, version); lockErr != nil {
+			message := "Cannot remove version, an update is currently in progress."
+			if updatingVersion == version {
+				message = fmt.Sprintf("Cannot remove version %s, it is part of an operation currently in progress.", version)
 			}
+			http.Error(w, message, http.StatusConflict)
 			return
 		}
 		defer resetServiceFromUpdate(service)
 
-		err := service.UpdateManager.UpdateToVersion(version, func(newVersionPath string) error {
-			updateErr := updateServedVersion(service, newVersionPath)
-			if updateErr != nil {
-				return errors.Wrap(updateErr, "unable to update the ui dist symlink to the new version")
+		op := newClusterOperation(service, OperationRemove, version)
+		if rejectIfPinnedVersionRemoved(service, w, version) || !acquireClusterOperation(service, w, op) {
+			return
+		}
+		defer releaseClusterOperation(service)
+		result := newOperationResult(service, op)
+
+		currentVersion, err := service.UpdateManager.CurrentVersion()
+		if err != nil {
+			logrus.WithError(err).Error("Failed to check the current version")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if writableErr := service.UpdateManager.CheckWritable(); writableErr != nil {
+			http.Error(w, writableErr.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		if currentVersion == version {
+			if !force {
+				http.Error(
+					w,
+					fmt.Sprintf("Cannot remove version %s, it is currently served. Use force=true&switchToDefault=true to reset to the default UI first.", version),
+					http.StatusConflict,
+				)
+				return
+			}
+			if rejectIfPinned(service, w, string(PreBundledUIVersion)) {
+				return
 			}
 
-			newUIVersion := UIVersion(version)
-			updateErr = service.VersionStore.UpdateCurrentVersion(newUIVersion)
-			if updateErr != nil {
-				return errors.Wrap(updateErr, "unable to save new version to the version store")
+			err = updateServedVersion(service, service.Config.DefaultDocRoot())
+			if err != nil {
+				logrus.WithError(err).Error("Failed to reset to default document root")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
 			}
-			return nil
-		})
+			storeErr := service.VersionStore.UpdateCurrentVersion(PreBundledUIVersion)
+			if storeErr != nil {
+				logrus.WithError(storeErr).Error("Failed to update the version store to the PreBundledUIVersion.")
+			}
+		}
 
+		err = service.UpdateManager.RemoveVersion(version)
+		result.finish(service, err)
 		switch err {
 		case nil:
-			w.Header().Add("Content-Type", "text/plain")
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(fmt.Sprintf("Update to %s completed", version)))
-			return
+			writeOperationResult(w, r, http.StatusOK, result, fmt.Sprintf("Removed version %s", version))
 		case updatemanager.ErrRequestedVersionNotFound:
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+			writeOperationResult(w, r, http.StatusNotFound, result, err.Error())
 		default:
-			logrus.WithFields(logrus.Fields{
-				"version": version,
-				"err":     err,
-			}).Error("Update failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			logrus.WithError(err).WithField("version", version).Error("Failed to remove version")
+			writeOperationResult(w, r, http.StatusInternalServerError, result, err.Error())
 		}
 	}
 }
 
+func parseBoolParam(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
 func resetToDefaultUIHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// verify we aren't currently serving pre-bundled version
@@ -136,7 +439,13 @@ func resetToDefaultUIHandler(service *UIService) func(http.ResponseWriter, *http
 
 		logrus.WithField("CurrentVersion", currentVersion).Debug("Received reset request.")
 
-		if updatingVersion, lockErr := setServiceUpdating(service, ""); lockErr != nil {
+		if writableErr := service.UpdateManager.CheckWritable(); writableErr != nil {
+			http.Error(w, writableErr.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		// a reset cancels the queued updates and the update in progress if it has not been activated yet
+		if updatingVersion, lockErr := setServiceUpdating(service, ""); lockErr != nil && !preemptForOperation(service, OperationReset, "") {
 			var message string
 			if UIVersion(updatingVersion) == PreBundledUIVersion {
 				message = "Cannot process reset, another reset is currently in progress."
@@ -144,19 +453,23 @@ func resetToDefaultUIHandler(service *UIService) func(http.ResponseWriter, *http
 				message = "Cannot process reset, an update is currently in progress."
 			}
 			logrus.WithError(lockErr).Error(message)
-
-			w.Header().Add("Content-Type", "text/plain")
-			w.WriteHeader(http.StatusConflict)
-			w.Write([]byte(message))
+			writeLocalBusy(service, w, r, http.StatusConflict, "", updatingVersion, message)
 			return
 		}
 		defer resetServiceFromUpdate(service)
 
+		op := newClusterOperation(service, OperationReset, "")
+		if rejectIfPinned(service, w, string(PreBundledUIVersion)) || !acquireClusterOperation(service, w, op) {
+			return
+		}
+		defer releaseClusterOperation(service)
+		result := newOperationResult(service, op)
+
 		if UIVersion(currentVersion) != PreBundledUIVersion {
 			err = updateServedVersion(service, service.Config.DefaultDocRoot())
 			if err != nil {
 				logrus.WithError(err).Error("Failed to reset to default document root")
-				w.WriteHeader(http.StatusInternalServerError)
+				writeOperationResult(w, r, http.StatusInternalServerError, result.finish(service, err), "")
 				return
 			}
 
@@ -169,12 +482,122 @@ func resetToDefaultUIHandler(service *UIService) func(http.ResponseWriter, *http
 		err = service.UpdateManager.RemoveAllVersionsExcept("")
 		if err != nil {
 			logrus.WithError(err).Error("Failed to remove previous versions when resetting to default document root")
-			w.WriteHeader(http.StatusInternalServerError)
+			writeOperationResult(w, r, http.StatusInternalServerError, result.finish(service, err), "")
 			return
 		}
 
-		w.Header().Add("Content-Type", "text/plain")
+		writeOperationResult(w, r, http.StatusOK, result.finish(service, nil), "OK")
+	}
+}
+
+type diagnosticsResponse struct {
+	RecordingEnabled bool                               `json:"recordingEnabled"`
+	Requests         []recordedExchange                 `json:"requests"`
+	Jobs             []scheduler.JobStatus              `json:"jobs"`
+	Freeze           *SyncFreeze                        `json:"freeze,omitempty"`
+	Pin              *VersionPin                        `json:"pin,omitempty"`
+	VersionConflict  *VersionConflict                   `json:"lastVersionConflict,omitempty"`
+	StoreMigration   *MigrationStatus                   `json:"versionStoreMigration,omitempty"`
+	DefaultUI        *DefaultUIInfo                     `json:"defaultUI,omitempty"`
+	Quarantined      []updatemanager.QuarantinedVersion `json:"quarantinedVersions"`
+	Memory           memory.Stats                       `json:"memory"`
+	// Role is master or replica
+	Role string `json:"role"`
+}
+
+func diagnosticsHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := diagnosticsResponse{
+			RecordingEnabled: service.recorder != nil,
+			Requests:         service.recorder.Exchanges(),
+			Jobs:             service.Scheduler.Status(),
+			DefaultUI:        service.defaultUI.Info(),
+			Memory:           memory.DefaultAccountant.Stats(),
+			Role:             service.Config.Role(),
+		}
+		if freeze, err := activeFreeze(service); err == nil {
+			response.Freeze = freeze
+		}
+		if pin, err := activePin(service); err == nil {
+			response.Pin = pin
+		}
+		if conflicts, ok := service.VersionStore.(VersionConflicts); ok {
+			response.VersionConflict = conflicts.LastVersionConflict()
+		}
+		if migration, ok := service.VersionStore.(VersionStoreMigration); ok {
+			status := migration.MigrationStatus()
+			response.StoreMigration = &status
+		}
+		quarantined, err := service.UpdateManager.QuarantinedVersions()
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to list quarantined versions")
+		}
+		response.Quarantined = quarantined
+		js, err := json.Marshal(response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
+		w.Write(js)
+	}
+}
+
+type healthCheck struct {
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+type healthResponse struct {
+	Healthy    bool                     `json:"healthy"`
+	Checks     map[string]healthCheck   `json:"checks"`
+	Subsystems map[string]health.Status `json:"subsystems"`
+	Tainted    *DistTaint               `json:"tainted,omitempty"`
+}
+
+func healthHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := healthResponse{
+			Healthy: true,
+			Checks:  map[string]healthCheck{},
+		}
+		addCheck := func(name string, err error) {
+			check := healthCheck{Healthy: err == nil}
+			if err != nil {
+				check.Error = err.Error()
+				response.Healthy = false
+			}
+			response.Checks[name] = check
+		}
+		addCheck("filesystem", service.UpdateManager.CheckWritable())
+		if access, ok := service.VersionStore.(StoreAccess); ok {
+			addCheck("zookeeper-access", access.AccessError())
+		}
+		var taintErr error
+		if response.Tainted = service.distTaint(); response.Tainted != nil {
+			taintErr = ErrDistTainted
+		}
+		addCheck("dist-integrity", taintErr)
+		response.Subsystems = health.DefaultTracker.Snapshot(
+			health.Cosmos,
+			health.Downloader,
+			health.Zookeeper,
+			health.VersionStore,
+			health.Filesystem,
+		)
+
+		js, err := json.Marshal(response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		if response.Healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(js)
 	}
 }
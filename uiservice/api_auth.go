@@ -0,0 +1,174 @@
+package uiservice
+
+import (
+	"crypto/subtle"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/dcos/dcos-ui-update-service/auth"
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// healthPath is served without credentials, so health checks of the master keep working with api-auth
+const healthPath = "/api/v1/health/"
+
+// ErrMissingCredentials occurs for API requests without Authorization header
+var ErrMissingCredentials = errors.New("authorization required")
+
+// apiPermission is what the credentials of a request allow, each permission includes the lower ones
+type apiPermission int
+
+const (
+	permissionNone apiPermission = iota
+	// permissionRead allows the requests reading versions, jobs and status
+	permissionRead
+	// permissionMutate allows the updates, resets, rollbacks and all other requests changing the service
+	permissionMutate
+)
+
+// apiAuthenticator checks the credentials of an API request
+type apiAuthenticator interface {
+	// authenticate returns the identity and permission of the credentials of r, an error if they are
+	// missing or invalid
+	authenticate(r *http.Request) (string, apiPermission, error)
+}
+
+// newAPIAuthenticator creates the authenticator configured by api-auth, nil if requests are not authenticated
+func newAPIAuthenticator(cfg *config.Config) (apiAuthenticator, error) {
+	switch cfg.APIAuth() {
+	case "token":
+		mutateToken, err := readTokenFile(cfg.APIAuthTokenFile())
+		if err != nil {
+			return nil, err
+		}
+		var readToken string
+		if file := cfg.APIAuthReadTokenFile(); file != "" {
+			if readToken, err = readTokenFile(file); err != nil {
+				return nil, err
+			}
+		}
+		return &sharedTokenAuth{readToken: readToken, mutateToken: mutateToken}, nil
+	case "iam":
+		return &iamAuth{
+			keys:       auth.NewKeySet(cfg.APIAuthJWKSURL()),
+			readUIDs:   uidSet(cfg.APIAuthReadUIDs()),
+			mutateUIDs: uidSet(cfg.APIAuthMutateUIDs()),
+		}, nil
+	}
+	return nil, nil
+}
+
+func readTokenFile(file string) (string, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read API token file '%s'", file)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", errors.Errorf("API token file '%s' is empty", file)
+	}
+	return token, nil
+}
+
+func uidSet(uids []string) map[string]bool {
+	set := map[string]bool{}
+	for _, uid := range uids {
+		set[uid] = true
+	}
+	return set
+}
+
+// requestToken returns the token of the Authorization header, sent as "Bearer <token>" or as
+// "token=<token>" like DC/OS clients do
+func requestToken(r *http.Request) string {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	switch {
+	case len(header) > 7 && strings.EqualFold(header[:7], "bearer "):
+		return strings.TrimSpace(header[7:])
+	case strings.HasPrefix(header, "token="):
+		return strings.TrimPrefix(header, "token=")
+	}
+	return ""
+}
+
+// sharedTokenAuth grants the permission of the configured bearer token a request presents
+type sharedTokenAuth struct {
+	readToken   string
+	mutateToken string
+}
+
+func (a *sharedTokenAuth) authenticate(r *http.Request) (string, apiPermission, error) {
+	token := requestToken(r)
+	switch {
+	case token == "":
+		return "", permissionNone, ErrMissingCredentials
+	case subtle.ConstantTimeCompare([]byte(token), []byte(a.mutateToken)) == 1:
+		return "mutate-token", permissionMutate, nil
+	case a.readToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.readToken)) == 1:
+		return "read-token", permissionRead, nil
+	}
+	return "", permissionNone, auth.ErrInvalidToken
+}
+
+// iamAuth verifies DC/OS auth tokens and grants the permission configured for their uid
+type iamAuth struct {
+	keys       *auth.KeySet
+	readUIDs   map[string]bool
+	mutateUIDs map[string]bool
+}
+
+func (a *iamAuth) authenticate(r *http.Request) (string, apiPermission, error) {
+	token := requestToken(r)
+	if token == "" {
+		return "", permissionNone, ErrMissingCredentials
+	}
+	claims, err := a.keys.Verify(r.Context(), token)
+	if err != nil {
+		return "", permissionNone, err
+	}
+	switch {
+	case a.mutateUIDs[claims.UID]:
+		return claims.UID, permissionMutate, nil
+	case len(a.readUIDs) == 0 || a.readUIDs[claims.UID]:
+		return claims.UID, permissionRead, nil
+	}
+	return claims.UID, permissionNone, nil
+}
+
+// apiAuthentication rejects API requests without valid credentials with 401 and requests the credentials
+// don't permit with 403. Mutating methods require permissionMutate, all others permissionRead. The ui files
+// and the health endpoint are served without credentials.
+func apiAuthentication(authenticator apiAuthenticator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == healthPath {
+				next.ServeHTTP(w, r)
+				return
+			}
+			required := permissionRead
+			if mutatingMethod(r.Method) {
+				required = permissionMutate
+			}
+			logger := logrus.WithFields(logrus.Fields{"method": r.Method, "path": r.URL.Path})
+			identity, granted, err := authenticator.authenticate(r)
+			if err != nil {
+				if err != ErrMissingCredentials {
+					logger.WithError(err).Warn("Rejected request with invalid credentials")
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="dcos-ui-update-service"`)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if granted < required {
+				logger.WithField("identity", identity).Warn("Rejected request without permission")
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package uiservice
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func setupAPIAuthUIService(t *testing.T, args ...string) *UIService {
+	service := setupTestUIService()
+	cfg, err := config.Parse(append([]string{
+		"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+		"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+		"--ui-dist-stage-symlink", "../testdata/uiserv-sandbox/new-dcos-ui-dist",
+	}, args...))
+	tests.H(t).IsNil(err)
+	service.Config = cfg
+	service.apiAuth, err = newAPIAuthenticator(cfg)
+	tests.H(t).IsNil(err)
+	return service
+}
+
+func writeAPIToken(t *testing.T, dir, name, token string) string {
+	file := path.Join(dir, name)
+	tests.H(t).IsNil(ioutil.WriteFile(file, []byte(token+"\n"), 0600))
+	return file
+}
+
+func serveAuthenticated(service *UIService, method, target, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, req)
+	return rr
+}
+
+// iamSigner serves the JWKS of its key and signs DC/OS auth tokens with it
+type iamSigner struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+}
+
+func newIAMSigner(t *testing.T) *iamSigner {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	tests.H(t).IsNil(err)
+	signer := &iamSigner{key: key}
+	signer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "secret",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	return signer
+}
+
+func (s *iamSigner) token(t *testing.T, uid string) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "secret"})
+	claims, _ := json.Marshal(map[string]interface{}{"uid": uid, "exp": time.Now().Add(time.Hour).Unix()})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	tests.H(t).IsNil(err)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestSharedTokenAuthentication(t *testing.T) {
+	setup := func(t *testing.T, dir string) *UIService {
+		return setupAPIAuthUIService(t,
+			"--api-auth", "token",
+			"--api-auth-token-file", writeAPIToken(t, dir, "mutate-token", "s3cr3t"),
+			"--api-auth-read-token-file", writeAPIToken(t, dir, "read-token", "r3ad"),
+		)
+	}
+
+	t.Run("rejects requests without token with 401", func(t *testing.T) {
+		defer tearDown(t)
+		dir, _ := ioutil.TempDir("", "api-auth")
+		defer os.RemoveAll(dir)
+		service := setup(t, dir)
+
+		rr := serveAuthenticated(service, "GET", "/api/v1/operations/", "")
+
+		tests.H(t).IntEql(rr.Code, http.StatusUnauthorized)
+		tests.H(t).StringContains(rr.Header().Get("WWW-Authenticate"), "Bearer")
+	})
+
+	t.Run("rejects requests with a wrong token with 401", func(t *testing.T) {
+		defer tearDown(t)
+		dir, _ := ioutil.TempDir("", "api-auth")
+		defer os.RemoveAll(dir)
+		service := setup(t, dir)
+
+		rr := serveAuthenticated(service, "GET", "/api/v1/operations/", "Bearer wrong")
+
+		tests.H(t).IntEql(rr.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("allows reads with the read token", func(t *testing.T) {
+		defer tearDown(t)
+		dir, _ := ioutil.TempDir("", "api-auth")
+		defer os.RemoveAll(dir)
+		service := setup(t, dir)
+
+		rr := serveAuthenticated(service, "GET", "/api/v1/operations/", "Bearer r3ad")
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+	})
+
+	t.Run("rejects mutating requests with the read token with 403", func(t *testing.T) {
+		defer tearDown(t)
+		dir, _ := ioutil.TempDir("", "api-auth")
+		defer os.RemoveAll(dir)
+		service := setup(t, dir)
+
+		rr := serveAuthenticated(service, "DELETE", "/api/v1/reset/", "Bearer r3ad")
+
+		tests.H(t).IntEql(rr.Code, http.StatusForbidden)
+	})
+
+	t.Run("allows mutating requests with the mutate token", func(t *testing.T) {
+		defer tearDown(t)
+		dir, _ := ioutil.TempDir("", "api-auth")
+		defer os.RemoveAll(dir)
+		service := setup(t, dir)
+
+		rr := serveAuthenticated(service, "DELETE", "/api/v1/reset/", "Bearer s3cr3t")
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+	})
+
+	t.Run("serves the health endpoint without token", func(t *testing.T) {
+		defer tearDown(t)
+		dir, _ := ioutil.TempDir("", "api-auth")
+		defer os.RemoveAll(dir)
+		service := setup(t, dir)
+
+		rr := serveAuthenticated(service, "GET", "/api/v1/health/", "")
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+	})
+
+	t.Run("fails setup with an empty token file", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "api-auth")
+		defer os.RemoveAll(dir)
+		cfg, _ := config.Parse([]string{"--api-auth", "token", "--api-auth-token-file", writeAPIToken(t, dir, "token", "")})
+
+		_, err := newAPIAuthenticator(cfg)
+
+		tests.H(t).NotNil(err)
+	})
+}
+
+func TestIAMAuthentication(t *testing.T) {
+	setup := func(t *testing.T, signer *iamSigner, args ...string) *UIService {
+		return setupAPIAuthUIService(t, append([]string{
+			"--api-auth", "iam",
+			"--api-auth-jwks-url", signer.server.URL,
+			"--api-auth-mutate-uids", "bootstrapuser",
+		}, args...)...)
+	}
+
+	t.Run("allows mutating requests for the mutating uids", func(t *testing.T) {
+		defer tearDown(t)
+		signer := newIAMSigner(t)
+		defer signer.server.Close()
+		service := setup(t, signer)
+
+		rr := serveAuthenticated(service, "DELETE", "/api/v1/reset/", "token="+signer.token(t, "bootstrapuser"))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+	})
+
+	t.Run("allows reads but rejects mutating requests for other uids", func(t *testing.T) {
+		defer tearDown(t)
+		signer := newIAMSigner(t)
+		defer signer.server.Close()
+		service := setup(t, signer)
+		token := "token=" + signer.token(t, "viewer")
+
+		tests.H(t).IntEql(serveAuthenticated(service, "GET", "/api/v1/operations/", token).Code, http.StatusOK)
+		tests.H(t).IntEql(serveAuthenticated(service, "DELETE", "/api/v1/reset/", token).Code, http.StatusForbidden)
+	})
+
+	t.Run("rejects reads of uids not allowed to read with 403", func(t *testing.T) {
+		defer tearDown(t)
+		signer := newIAMSigner(t)
+		defer signer.server.Close()
+		service := setup(t, signer, "--api-auth-read-uids", "monitoring")
+
+		rr := serveAuthenticated(service, "GET", "/api/v1/operations/", "Bearer "+signer.token(t, "viewer"))
+
+		tests.H(t).IntEql(rr.Code, http.StatusForbidden)
+	})
+
+	t.Run("rejects tokens not signed by the IAM with 401", func(t *testing.T) {
+		defer tearDown(t)
+		signer := newIAMSigner(t)
+		defer signer.server.Close()
+		service := setup(t, signer)
+		forger := newIAMSigner(t)
+		defer forger.server.Close()
+
+		rr := serveAuthenticated(service, "DELETE", "/api/v1/reset/", "token="+forger.token(t, "bootstrapuser"))
+
+		tests.H(t).IntEql(rr.Code, http.StatusUnauthorized)
+	})
+}
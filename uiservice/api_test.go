@@ -1,6 +1,7 @@
 package uiservice
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -8,8 +9,10 @@ import (
 	"path"
 	"testing"
 
+	"github.com/dcos/dcos-ui-update-service/health"
 	"github.com/dcos/dcos-ui-update-service/tests"
 	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
 )
 
 func TestRouter(t *testing.T) {
@@ -24,6 +27,7 @@ func TestRouter(t *testing.T) {
 			{"returns with 404 on api", "/api", http.StatusNotFound},
 			{"returns with 405 on GET api/v1/reset", "/api/v1/reset/", http.StatusMethodNotAllowed},
 			{"returns with 200 on GET api/v1/version", "/api/v1/version/", http.StatusOK},
+			{"returns with 200 on GET api/v1/metrics", "/api/v1/metrics/", http.StatusOK},
 		}
 
 		for _, tt := range testCases {
@@ -185,6 +189,74 @@ func TestRouter(t *testing.T) {
 		tests.H(t).StringContains(rr.Body.String(), "Failed to update version in store")
 	})
 
+	t.Run("Version Update - If-Match matches the current version", func(t *testing.T) {
+		req, err := http.NewRequest("POST", "/api/v1/update/2.24.5/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("If-Match", `"2.24.4"`)
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		newVersionPath := path.Join(path.Join(service.Config.VersionsRoot(), "2.24.5"), "dist")
+		os.MkdirAll(newVersionPath, 0755)
+
+		um := UpdateManagerDouble()
+		um.UpdateNewVersionPath = newVersionPath
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+	})
+
+	t.Run("Version Update - If-Match does not match the current version", func(t *testing.T) {
+		req, err := http.NewRequest("POST", "/api/v1/update/2.24.5/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("If-Match", `"2.24.3", "Default"`)
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		updateCalled := false
+		um := UpdateManagerDouble()
+		um.UpdateCall = func(string) { updateCalled = true }
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusPreconditionFailed)
+		tests.H(t).StringEql(rr.Header().Get("ETag"), `"2.24.4"`)
+		tests.H(t).BoolEql(updateCalled, false)
+		tests.H(t).BoolEql(service.updating, false)
+	})
+
+	t.Run("Version Update - If-Match of the pre-bundled ui", func(t *testing.T) {
+		req, err := http.NewRequest("POST", "/api/v1/update/2.24.5/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("If-Match", `"Default"`)
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		newVersionPath := path.Join(path.Join(service.Config.VersionsRoot(), "2.24.5"), "dist")
+		os.MkdirAll(newVersionPath, 0755)
+
+		um := UpdateManagerDouble()
+		um.VersionResult = ""
+		um.UpdateNewVersionPath = newVersionPath
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+	})
+
 	t.Run("Version Update - version not available", func(t *testing.T) {
 		req, err := http.NewRequest("POST", "/api/v1/update/2.24.4/", nil)
 		if err != nil {
@@ -203,4 +275,235 @@ func TestRouter(t *testing.T) {
 		tests.H(t).IntEql(rr.Code, http.StatusBadRequest)
 		tests.H(t).StringContains(rr.Body.String(), updatemanager.ErrRequestedVersionNotFound.Error())
 	})
+
+	t.Run("Version Update - read-only filesystem", func(t *testing.T) {
+		req, err := http.NewRequest("POST", "/api/v1/update/2.24.4/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		um := UpdateManagerDouble()
+		um.UpdateError = updatemanager.ErrReadOnlyFilesystem
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusServiceUnavailable)
+		tests.H(t).StringContains(rr.Body.String(), "E_READONLY_FS")
+	})
+
+	t.Run("Version Update - untrusted bundle signature", func(t *testing.T) {
+		req, err := http.NewRequest("POST", "/api/v1/update/2.24.4/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		um := UpdateManagerDouble()
+		um.UpdateError = updatemanager.ErrBundleSignatureInvalid
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusUnprocessableEntity)
+		tests.H(t).StringContains(rr.Body.String(), ErrorCodeSignature)
+		entries, err := service.auditLog().Entries(context.Background(), 10)
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(entries[0].Outcome, OutcomeUntrusted)
+	})
+
+	t.Run("Reset - read-only filesystem", func(t *testing.T) {
+		req, err := http.NewRequest("DELETE", "/api/v1/reset/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		um := UpdateManagerDouble()
+		um.WritableError = updatemanager.ErrReadOnlyFilesystem
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusServiceUnavailable)
+		tests.H(t).StringContains(rr.Body.String(), "E_READONLY_FS")
+	})
+
+	t.Run("Health - healthy", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/health/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringContains(rr.Body.String(), `"healthy":true`)
+	})
+
+	t.Run("Health - read-only filesystem", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.WritableError = updatemanager.ErrReadOnlyFilesystem
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/health/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusServiceUnavailable)
+		tests.H(t).StringContains(rr.Body.String(), "E_READONLY_FS")
+	})
+
+	t.Run("Health - ZK access denied", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		store, fakeClient := makeZKStore("")
+		fakeClient.CreateError = zookeeper.ErrNoAuth
+		store.verifyAccess()
+		defer health.DefaultTracker.Record(health.Zookeeper, nil)
+		service.VersionStore = store
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/health/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusServiceUnavailable)
+		tests.H(t).StringContains(rr.Body.String(), `"zookeeper-access":{"healthy":false`)
+	})
+
+	t.Run("Health - tainted dist", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.markTainted("2.25.0", false)
+		defer service.clearTaint()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/health/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusServiceUnavailable)
+		tests.H(t).StringContains(rr.Body.String(), `"dist-integrity":{"healthy":false`)
+		tests.H(t).StringContains(rr.Body.String(), `"tainted":{"version":"2.25.0"`)
+	})
+
+	t.Run("Health - subsystem statuses", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		for i := 0; i < health.FailingThreshold; i++ {
+			health.DefaultTracker.Record(health.Cosmos, updatemanager.ErrCosmosRequestFailure)
+		}
+		defer health.DefaultTracker.Record(health.Cosmos, nil)
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/health/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringContains(rr.Body.String(), `"cosmos":{"state":"failing","consecutiveFailures":3,"lastError":"Retrieving data from Cosmos failed"`)
+		tests.H(t).StringContains(rr.Body.String(), `"filesystem":{"state":"ok"`)
+	})
+
+	t.Run("Trailing slash redirects", func(t *testing.T) {
+		var testCases = []struct {
+			name       string
+			method     string
+			uri        string
+			statusCode int
+			location   string
+		}{
+			{"redirects GET version", "GET", "/api/v1/version", http.StatusMovedPermanently, "/api/v1/version/"},
+			{"keeps query on GET versions", "GET", "/api/v1/versions?limit=5", http.StatusMovedPermanently, "/api/v1/versions/?limit=5"},
+			{"preserves method on POST update", "POST", "/api/v1/update/2.24.4", http.StatusPermanentRedirect, "/api/v1/update/2.24.4/"},
+			{"preserves method on DELETE reset", "DELETE", "/api/v1/reset", http.StatusPermanentRedirect, "/api/v1/reset/"},
+			{"returns 404 for unknown route", "GET", "/api/v1/unknown", http.StatusNotFound, ""},
+			{"returns 404 for unknown route with slash", "GET", "/api/v1/unknown/", http.StatusNotFound, ""},
+		}
+
+		for _, tt := range testCases {
+			t.Run(tt.name, func(t *testing.T) {
+				defer tearDown(t)
+				service := setupTestUIService()
+
+				rr := httptest.NewRecorder()
+				newRouter(service).ServeHTTP(rr, httptest.NewRequest(tt.method, tt.uri, nil))
+
+				tests.H(t).IntEql(rr.Code, tt.statusCode)
+				tests.H(t).StringEql(rr.Header().Get("Location"), tt.location)
+			})
+		}
+	})
+
+	t.Run("Remove version", func(t *testing.T) {
+		var testCases = []struct {
+			name           string
+			uri            string
+			currentVersion string
+			removeError    error
+			statusCode     int
+			removeCalled   bool
+		}{
+			{"removes a version not served", "/api/v1/versions/2.24.3/", "2.24.4", nil, http.StatusOK, true},
+			{"returns 404 for unknown version", "/api/v1/versions/2.24.3/", "2.24.4", updatemanager.ErrRequestedVersionNotFound, http.StatusNotFound, true},
+			{"refuses to remove the served version", "/api/v1/versions/2.24.4/", "2.24.4", nil, http.StatusConflict, false},
+			{"refuses force without switchToDefault", "/api/v1/versions/2.24.4/?force=true", "2.24.4", nil, http.StatusBadRequest, false},
+			{"rejects invalid force", "/api/v1/versions/2.24.4/?force=maybe", "2.24.4", nil, http.StatusBadRequest, false},
+			{"removes the served version with force and switchToDefault", "/api/v1/versions/2.24.4/?force=true&switchToDefault=true", "2.24.4", nil, http.StatusOK, true},
+			{"returns 404 for the trash", "/api/v1/versions/.trash/", "2.24.4", nil, http.StatusNotFound, false},
+			{"returns 404 for the quarantine", "/api/v1/versions/.quarantine/", "2.24.4", nil, http.StatusNotFound, false},
+		}
+
+		for _, tt := range testCases {
+			t.Run(tt.name, func(t *testing.T) {
+				defer tearDown(t)
+				service := setupUIServiceWithVersion()
+
+				removeCalled := false
+				um := UpdateManagerDouble()
+				um.VersionResult = tt.currentVersion
+				um.ResetCall = func() error {
+					removeCalled = true
+					return tt.removeError
+				}
+				service.UpdateManager = um
+
+				rr := httptest.NewRecorder()
+				newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", tt.uri, nil))
+
+				tests.H(t).IntEql(rr.Code, tt.statusCode)
+				tests.H(t).BoolEql(removeCalled, tt.removeCalled)
+			})
+		}
+	})
+
+	t.Run("Remove version - switches to default when forced", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		um := UpdateManagerDouble()
+		um.VersionResult = "2.24.4"
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/versions/2.24.4/?force=true&switchToDefault=true", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		servedPath, _ := os.Readlink(service.Config.UIDistSymlink())
+		tests.H(t).StringEql(servedPath, service.Config.DefaultDocRoot())
+	})
+
+	t.Run("Remove version - locked during operation on the version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.UpdateManager = UpdateManagerDouble()
+		service.updating = true
+		service.updatingVersion = "2.24.3"
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/versions/2.24.3/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+		tests.H(t).StringContains(rr.Body.String(), "operation currently in progress")
+	})
 }
@@ -0,0 +1,127 @@
+package uiservice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// assetsManifest caches the content hashes of the served ui files, keyed by the target of the ui dist symlink
+// so the hashes are regenerated once the symlink points to another version
+type assetsManifest struct {
+	target string
+	hashes map[string]string
+	sync.Mutex
+}
+
+// get returns the hashes of the files below the dist symlink target, hashing them again if the target changed
+func (m *assetsManifest) get(symlink string, rules uiFileRules) (map[string]string, error) {
+	target, err := os.Readlink(symlink)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the ui dist symlink")
+	}
+	m.Lock()
+	defer m.Unlock()
+	if m.hashes != nil && m.target == target {
+		return m.hashes, nil
+	}
+	hashes, err := hashAssets(target, rules)
+	if err != nil {
+		return nil, err
+	}
+	m.target, m.hashes = target, hashes
+	logrus.WithFields(logrus.Fields{"target": target, "files": len(hashes)}).Info("Generated the assets manifest")
+	return hashes, nil
+}
+
+// hashAssets returns the hex SHA-256 digests of the files below root by their slash separated path, leaving
+// out the files the ui file rules hide
+func hashAssets(root string, rules uiFileRules) (map[string]string, error) {
+	hashes := map[string]string{}
+	err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == root {
+			return nil
+		}
+		if rules.hidden(info.Name()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		digest, err := fileSHA256(name)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(rel)] = digest
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to hash the served ui files")
+	}
+	return hashes, nil
+}
+
+func fileSHA256(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// assetsManifestHandler returns the content hashes of the served ui files by file name, so proxies and the
+// ui can check they load the same asset set from every master during a rolling update
+func assetsManifestHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rules := uiFileRules{
+			hideDotfiles: service.Config.UIHideDotfiles(),
+			hiddenFiles:  service.Config.UIHiddenFiles(),
+		}
+		hashes, err := service.assets.get(service.Config.UIDistSymlink(), rules)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to generate the assets manifest")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		js, err := json.Marshal(hashes)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.Header().Set("ETag", `"`+manifestDigest(js)+`"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
+
+// manifestDigest shortens the digest of the marshalled manifest to an ETag, json.Marshal sorts the file
+// names so equal asset sets get equal ETags on all masters
+func manifestDigest(js []byte) string {
+	sum := sha256.Sum256(js)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,62 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func getAssetsManifest(t *testing.T, service *UIService) map[string]string {
+	rr := getUIFile(service, "/api/v1/assets-manifest/")
+	tests.H(t).IntEql(rr.Code, http.StatusOK)
+	var hashes map[string]string
+	tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &hashes))
+	return hashes
+}
+
+func TestAssetsManifest(t *testing.T) {
+	t.Run("returns the content hashes of the served files", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		distPath := path.Join(service.Config.VersionsRoot(), "2.24.4", "dist")
+		writeUIFile(t, distPath, "index.html", "2.24.4")
+		writeUIFile(t, path.Join(distPath, "assets"), "main.js", "")
+
+		hashes := getAssetsManifest(t, service)
+
+		tests.H(t).IntEql(len(hashes), 2)
+		tests.H(t).StringEql(hashes["index.html"], "4b93c074571f4f33140c09b06f0c4a73f445e1604dbe1feb9c4acc05364bde47")
+		tests.H(t).StringEql(hashes["assets/main.js"], "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	})
+
+	t.Run("regenerates the hashes once the dist symlink points to another version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		writeUIFile(t, path.Join(service.Config.VersionsRoot(), "2.24.4", "dist"), "main.js", "2.24.4")
+		newVersionPath := path.Join(service.Config.VersionsRoot(), "2.25.0", "dist")
+		writeUIFile(t, newVersionPath, "main.js", "2.25.0")
+		previous := getAssetsManifest(t, service)
+
+		tests.H(t).IsNil(updateServedVersion(service, newVersionPath))
+		hashes := getAssetsManifest(t, service)
+
+		tests.H(t).IntEql(len(hashes), 1)
+		tests.H(t).BoolEql(hashes["main.js"] != previous["main.js"], true)
+	})
+
+	t.Run("leaves out the hidden files", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		distPath := path.Join(service.Config.VersionsRoot(), "2.24.4", "dist")
+		writeUIFile(t, distPath, "main.js", "2.24.4")
+		writeUIFile(t, distPath, ".env", "secret")
+
+		hashes := getAssetsManifest(t, service)
+
+		_, ok := hashes[".env"]
+		tests.H(t).BoolEql(ok, false)
+	})
+}
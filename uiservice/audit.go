@@ -0,0 +1,265 @@
+package uiservice
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/memory"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// maxAuditEntries is the number of audit entries kept in memory, the oldest entry is dropped first
+const maxAuditEntries = 200
+
+// auditReadFactor is the memory reserved for reading the audit file relative to its size, the parsed entries
+// and the response are kept at the same time
+const auditReadFactor = 3
+
+// defaultHistoryLimit is the number of audit entries returned by GET /api/v1/history/ without ?limit
+const defaultHistoryLimit = 100
+
+// OperationSync is the change of the served version to the version stored by another master
+const OperationSync = "sync"
+
+// What triggered a version change
+const (
+	TriggerAPI         = "api"
+	TriggerAutoUpdate  = "auto-update"
+	TriggerVersionSync = "version-sync"
+)
+
+// Outcomes of a version change
+const (
+	OutcomeSuccess    = "success"
+	OutcomeFailure    = "failure"
+	OutcomeSuperseded = "superseded"
+	OutcomeCancelled  = "cancelled"
+	OutcomeUntrusted  = "untrusted"
+)
+
+// AuditEntry records a change of the served version on this master
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Trigger   string    `json:"trigger"`
+	Master    string    `json:"master"`
+	OpID      string    `json:"opId,omitempty"`
+	// FromVersion and ToVersion are empty for the pre-bundled ui
+	FromVersion string `json:"fromVersion"`
+	ToVersion   string `json:"toVersion"`
+	Outcome     string `json:"outcome"`
+	Error       string `json:"error,omitempty"`
+	DurationMs  int64  `json:"durationMs"`
+	// Decisions are the steps of the cluster operation, empty for version syncs
+	Decisions []OperationDecision `json:"decisions,omitempty"`
+}
+
+// auditLog appends audit entries to the configured file and keeps the last ones in memory
+type auditLog struct {
+	file    string
+	entries []AuditEntry
+	limit   int
+	sync.Mutex
+}
+
+// auditLog returns the audit log of the service, creating it on first use
+func (service *UIService) auditLog() *auditLog {
+	service.Lock()
+	defer service.Unlock()
+	if service.audit == nil {
+		service.audit = &auditLog{file: service.Config.AuditLogFile(), limit: maxAuditEntries}
+	}
+	return service.audit
+}
+
+// changesServedVersion is true for the cluster operations recorded in the audit log
+func changesServedVersion(operation string) bool {
+	switch operation {
+	case OperationUpdate, OperationAutoUpdate, OperationRollback, OperationReset:
+		return true
+	}
+	return false
+}
+
+// newAuditEntry describes a version change from fromVersion to toVersion that started at started and failed with err
+func newAuditEntry(service *UIService, operation, trigger, fromVersion, toVersion string, started time.Time, err error) AuditEntry {
+	now := time.Now().UTC()
+	entry := AuditEntry{
+		Time:        now,
+		Operation:   operation,
+		Trigger:     trigger,
+		Master:      service.nodeName(),
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+		Outcome:     OutcomeSuccess,
+		DurationMs:  int64(now.Sub(started) / time.Millisecond),
+	}
+	if err != nil {
+		entry.Outcome = operationOutcome(err)
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// operationOutcome is the outcome of a version change or cluster operation that failed with err
+func operationOutcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Cause(err) == ErrSuperseded:
+		return OutcomeSuperseded
+	case errors.Cause(err) == updatemanager.ErrUpdateCancelled:
+		return OutcomeCancelled
+	case errors.Cause(err) == updatemanager.ErrBundleSignatureNotFound, errors.Cause(err) == updatemanager.ErrBundleSignatureInvalid:
+		return OutcomeUntrusted
+	}
+	return OutcomeFailure
+}
+
+// recordSync records a version sync of this master to the version stored by another master
+func recordSync(service *UIService, fromVersion, toVersion string, started time.Time, err error) {
+	reportNodeState(service, toVersion, err)
+	service.auditLog().record(newAuditEntry(service, OperationSync, TriggerVersionSync, fromVersion, toVersion, started, err))
+}
+
+// record keeps the entry and appends it to the audit file. Failing to write the file does not fail the
+// version change, it is logged.
+func (a *auditLog) record(entry AuditEntry) {
+	a.Lock()
+	defer a.Unlock()
+	a.entries = append(a.entries, entry)
+	if len(a.entries) > a.limit {
+		a.entries = a.entries[len(a.entries)-a.limit:]
+	}
+	if a.file == "" {
+		return
+	}
+	if err := appendAuditEntry(a.file, entry); err != nil {
+		logrus.WithError(err).WithField("path", a.file).Warn("Failed to write the audit log")
+	}
+}
+
+func appendAuditEntry(file string, entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Entries returns the last limit entries, the most recent first. With an audit file the entries are read
+// from it, so they include the changes before the last restart. Reading the file waits while the memory
+// budget is exhausted.
+func (a *auditLog) Entries(ctx context.Context, limit int) ([]AuditEntry, error) {
+	if a.file != "" {
+		reservation, err := reserveAuditRead(ctx, a.file)
+		if err != nil {
+			return nil, err
+		}
+		defer reservation.Release()
+	}
+	a.Lock()
+	defer a.Unlock()
+	entries := a.entries
+	if a.file != "" {
+		var err error
+		if entries, err = readAuditEntries(a.file); err != nil {
+			return nil, err
+		}
+	}
+	result := make([]AuditEntry, 0, limit)
+	for i := len(entries) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, entries[i])
+	}
+	return result, nil
+}
+
+// reserveAuditRead reserves the memory for reading the audit file
+func reserveAuditRead(ctx context.Context, file string) (*memory.Reservation, error) {
+	info, err := os.Stat(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return memory.DefaultAccountant.Reserve(ctx, "history", info.Size()*auditReadFactor)
+}
+
+// readAuditEntries parses the audit file, lines that cannot be parsed are skipped
+func readAuditEntries(file string) ([]AuditEntry, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			logrus.WithError(err).WithField("path", file).Debug("Skipping unreadable audit log line")
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// historyHandler lists the version changes of this master, the most recent first
+func historyHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := defaultHistoryLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+		entries, err := service.auditLog().Entries(r.Context(), limit)
+		if r.Context().Err() != nil {
+			// the client went away while the history waited for memory
+			return
+		}
+		if err != nil {
+			logrus.WithError(err).Error("Failed to read the audit log")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		js, err := json.Marshal(entries)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
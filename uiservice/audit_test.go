@@ -0,0 +1,100 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func getHistory(t *testing.T, service *UIService, query string) []AuditEntry {
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/history/"+query, nil))
+	tests.H(t).IntEql(rr.Code, http.StatusOK)
+	var entries []AuditEntry
+	tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &entries))
+	return entries
+}
+
+func TestAuditLog(t *testing.T) {
+	t.Run("records updates and resets", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		um := UpdateManagerDouble()
+		um.VersionResult = "2.24.4"
+		um.UpdateNewVersionPath = service.Config.DefaultDocRoot()
+		service.UpdateManager = um
+
+		newRouter(service).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/update/2.25.0/", nil))
+		newRouter(service).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/api/v1/reset/", nil))
+
+		entries := getHistory(t, service, "")
+		tests.H(t).IntEql(len(entries), 2)
+		tests.H(t).StringEql(entries[0].Operation, OperationReset)
+		tests.H(t).StringEql(entries[0].ToVersion, "")
+		tests.H(t).StringEql(entries[1].Operation, OperationUpdate)
+		tests.H(t).StringEql(entries[1].Trigger, TriggerAPI)
+		tests.H(t).StringEql(entries[1].FromVersion, "2.24.4")
+		tests.H(t).StringEql(entries[1].ToVersion, "2.25.0")
+		tests.H(t).StringEql(entries[1].Outcome, OutcomeSuccess)
+		tests.H(t).BoolEql(entries[1].OpID != "", true)
+	})
+
+	t.Run("records failed version syncs", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.VersionResult = "2.24.4"
+		um.UpdateError = errors.New("download failed")
+		service.UpdateManager = um
+
+		syncToVersion(service, "2.25.0")
+
+		entries := getHistory(t, service, "")
+		tests.H(t).IntEql(len(entries), 1)
+		tests.H(t).StringEql(entries[0].Operation, OperationSync)
+		tests.H(t).StringEql(entries[0].Trigger, TriggerVersionSync)
+		tests.H(t).StringEql(entries[0].Outcome, OutcomeFailure)
+		tests.H(t).StringEql(entries[0].Error, "download failed")
+	})
+
+	t.Run("reads the entries back from the audit file", func(t *testing.T) {
+		defer tearDown(t)
+		dir, err := ioutil.TempDir("", "audit")
+		tests.H(t).IsNil(err)
+		defer os.RemoveAll(dir)
+		file := path.Join(dir, "log", "audit.log")
+		service := setupTestUIService()
+		service.Config, _ = config.Parse([]string{"--audit-log-file", file})
+		started := time.Now().UTC()
+		for _, version := range []string{"2.24.5", "2.25.0", "2.26.0"} {
+			service.auditLog().record(newAuditEntry(service, OperationUpdate, TriggerAPI, "", version, started, nil))
+		}
+
+		// a restarted service reads the file written before
+		service.audit = nil
+		entries := getHistory(t, service, "?limit=2")
+
+		tests.H(t).IntEql(len(entries), 2)
+		tests.H(t).StringEql(entries[0].ToVersion, "2.26.0")
+		tests.H(t).StringEql(entries[1].ToVersion, "2.25.0")
+	})
+
+	t.Run("returns 400 for an invalid limit", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/history/?limit=0", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusBadRequest)
+	})
+}
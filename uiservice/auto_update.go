@@ -0,0 +1,208 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/scheduler"
+	"github.com/dcos/dcos-ui-update-service/semver"
+	"github.com/sirupsen/logrus"
+)
+
+// UpdateCheck is the result of checking the package source for a newer version than the served one
+type UpdateCheck struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	// Installed is the served version, empty while the pre-bundled ui is served
+	Installed string `json:"installed"`
+	// Baseline is the version newer versions are compared to, the build version of the
+	// pre-bundled ui while it is served
+	Baseline        string `json:"baseline,omitempty"`
+	Latest          string `json:"latest,omitempty"`
+	UpdateAvailable bool   `json:"updateAvailable"`
+	AutoUpdate      bool   `json:"autoUpdate"`
+	Error           string `json:"error,omitempty"`
+	// AutoUpdateResult is the outcome of the automatic update to Latest, if one was started
+	AutoUpdateResult *ClusterOperationResult `json:"autoUpdateResult,omitempty"`
+}
+
+// checkForUpdate compares the newest stable version of the package source with the served version.
+// While the pre-bundled ui is served its build version is compared, no update is reported if it is
+// unknown, so the pre-bundled ui is never replaced just because its version cannot be read.
+func checkForUpdate(service *UIService) (*UpdateCheck, error) {
+	check := &UpdateCheck{
+		CheckedAt:  time.Now().UTC(),
+		AutoUpdate: service.Config.AutoUpdate(),
+	}
+	installed, err := service.UpdateManager.CurrentVersion()
+	if err != nil {
+		return check, err
+	}
+	check.Installed = installed
+	check.Baseline = installed
+	if installed == "" {
+		if check.Baseline, err = defaultUIBuildVersion(service); err != nil {
+			logrus.WithError(err).Debug("Could not get the build version of the pre-bundled ui")
+		}
+	}
+
+	versions, err := service.UpdateManager.AvailableVersions()
+	if err != nil {
+		return check, err
+	}
+	latest, ok := latestStableVersion(versions)
+	if !ok {
+		return check, nil
+	}
+	check.Latest = latest.String()
+	if baseline, err := semver.Parse(check.Baseline); err == nil {
+		check.UpdateAvailable = latest.Compare(baseline) > 0
+	}
+	return check, nil
+}
+
+// latestStableVersion returns the newest version without a pre-release component,
+// the legacy dcos-ui package versions are all pre-releases
+func latestStableVersion(versions []string) (semver.Version, bool) {
+	var latest semver.Version
+	found := false
+	for _, version := range versions {
+		v, err := semver.Parse(version)
+		if err != nil || v.IsPreRelease() {
+			continue
+		}
+		if !found || v.Compare(latest) > 0 {
+			latest = v
+			found = true
+		}
+	}
+	return latest, found
+}
+
+// runUpdateCheck checks for a newer version and keeps the result for GET /api/v1/update/available/
+func runUpdateCheck(service *UIService) (*UpdateCheck, error) {
+	check, err := checkForUpdate(service)
+	if err != nil {
+		check.Error = err.Error()
+	}
+	service.Lock()
+	service.updateCheck = check
+	service.Unlock()
+	if check.UpdateAvailable {
+		logrus.WithFields(logrus.Fields{
+			"installed": check.Installed,
+			"latest":    check.Latest,
+		}).Info("Newer ui version available")
+	}
+	return check, err
+}
+
+// lastUpdateCheck returns a copy of the last update check result, nil before the first check
+func (service *UIService) lastUpdateCheck() *UpdateCheck {
+	service.Lock()
+	defer service.Unlock()
+	if service.updateCheck == nil {
+		return nil
+	}
+	check := *service.updateCheck
+	return &check
+}
+
+// autoUpdateJob checks for a newer version and, with auto-update enabled, updates all masters to it
+func autoUpdateJob(service *UIService) scheduler.JobFunc {
+	return func() error {
+		check, err := runUpdateCheck(service)
+		if err != nil || !check.UpdateAvailable || !check.AutoUpdate {
+			return err
+		}
+		result, err := autoUpdate(service, check.Latest)
+		if result != nil {
+			service.Lock()
+			if service.updateCheck == check {
+				check.AutoUpdateResult = result
+			}
+			service.Unlock()
+		}
+		return err
+	}
+}
+
+// autoUpdate runs the coordinated update flow of POST /api/v1/update/{version}/ without a request.
+// It is skipped without an error while an update is in progress, another master holds the cluster
+// operation, syncs are frozen or the ui is pinned to another version, the next check tries again.
+func autoUpdate(service *UIService, version string) (*ClusterOperationResult, error) {
+	logger := logrus.WithField("version", version)
+	if _, err := setServiceUpdating(service, version); err != nil {
+		logger.Debug("Skipping automatic update, update in progress")
+		return nil, nil
+	}
+	defer resetServiceFromUpdate(service)
+	markPreemptible(service, OperationAutoUpdate)
+
+	if freeze, err := activeFreeze(service); err != nil || freeze != nil {
+		if freeze != nil {
+			logger.WithField("frozenVersion", freeze.Version).Warn("Skipping automatic update, version syncs are frozen")
+		}
+		return nil, err
+	}
+	if pin, err := activePin(service); err != nil || (pin != nil && pin.Version != version) {
+		if pin != nil {
+			logger.WithField("pinnedVersion", pin.Version).Info("Skipping automatic update, the ui is pinned")
+		}
+		return nil, err
+	}
+	op := newClusterOperation(service, OperationAutoUpdate, version)
+	if service.ClusterStatus != nil {
+		if active, err := service.ClusterStatus.AcquireOperation(op); err != nil {
+			if err == ErrClusterBusy {
+				logger.WithField("activeOperation", active.Operation).Info("Skipping automatic update, cluster is busy")
+				return nil, nil
+			}
+			return nil, err
+		}
+		defer releaseClusterOperation(service)
+	}
+
+	logger.Info("Updating automatically to the newer version")
+	result := newOperationResult(service, op)
+	activator := newClusterActivator(service, UIVersion(version), result)
+	err := updateToVersion(service, version, activator)
+	if err == nil {
+		err = activator.promote()
+	}
+	if err != nil {
+		logger.WithError(err).Error("Automatic update failed")
+	}
+	return result.finish(service, err), err
+}
+
+// updateAvailableHandler returns the result of the last update check, ?refresh=true checks again first
+func updateAvailableHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		refresh, err := parseBoolParam(r, "refresh")
+		if err != nil {
+			http.Error(w, "refresh must be a boolean", http.StatusBadRequest)
+			return
+		}
+		check := service.lastUpdateCheck()
+		if refresh {
+			if _, err := runUpdateCheck(service); err != nil {
+				logrus.WithError(err).Warn("Update check failed")
+			}
+			check = service.lastUpdateCheck()
+		}
+		if check == nil {
+			http.Error(w, "No update check has run yet, use ?refresh=true to check now", http.StatusNotFound)
+			return
+		}
+
+		js, err := json.Marshal(check)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
@@ -0,0 +1,232 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func setupAutoUpdateUIService(um *fakeUpdateManager) *UIService {
+	service := setupTestUIService()
+	service.Config, _ = config.Parse([]string{
+		"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+		"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+		"--ui-dist-stage-symlink", "../testdata/uiserv-sandbox/new-dcos-ui-dist",
+		"--auto-update-check-interval", "1h",
+		"--auto-update",
+	})
+	um.UpdateNewVersionPath = service.Config.DefaultDocRoot()
+	service.UpdateManager = um
+	return service
+}
+
+func TestCheckForUpdate(t *testing.T) {
+	available := []string{"2.24.4", "2.25.0", "2.26.0-rc.1", "1.0.5-2.2.5"}
+
+	t.Run("reports the newest stable version newer than the served one", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.AvailableResult = available
+		service.UpdateManager = um
+
+		check, err := checkForUpdate(service)
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(check.Installed, "2.24.4")
+		tests.H(t).StringEql(check.Latest, "2.25.0")
+		tests.H(t).BoolEql(check.UpdateAvailable, true)
+	})
+
+	t.Run("reports no update if the newest version is served", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.VersionResult = "2.25.0"
+		um.AvailableResult = available
+		service.UpdateManager = um
+
+		check, err := checkForUpdate(service)
+
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(check.UpdateAvailable, false)
+	})
+
+	t.Run("compares the build version of the pre-bundled ui while it is served", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		writeDefaultIndex(t, service.Config.DefaultDocRoot(), "2.24.0")
+		um := UpdateManagerDouble()
+		um.VersionResult = ""
+		um.AvailableResult = available
+		service.UpdateManager = um
+
+		check, err := checkForUpdate(service)
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(check.Baseline, "2.24.0")
+		tests.H(t).BoolEql(check.UpdateAvailable, true)
+	})
+
+	t.Run("reports no update if the version of the pre-bundled ui is unknown", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.VersionResult = ""
+		um.AvailableResult = available
+		service.UpdateManager = um
+
+		check, err := checkForUpdate(service)
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(check.Latest, "2.25.0")
+		tests.H(t).BoolEql(check.UpdateAvailable, false)
+	})
+}
+
+func TestAutoUpdateJob(t *testing.T) {
+	t.Run("updates all masters to the newer version", func(t *testing.T) {
+		defer tearDown(t)
+		um := UpdateManagerDouble()
+		um.AvailableResult = []string{"2.24.4", "2.25.0"}
+		var updatedTo string
+		um.UpdateCall = func(version string) {
+			updatedTo = version
+		}
+		service := setupAutoUpdateUIService(um)
+		clusterStatus := &fakeClusterStatus{}
+		service.ClusterStatus = clusterStatus
+
+		tests.H(t).IsNil(autoUpdateJob(service)())
+
+		tests.H(t).StringEql(updatedTo, "2.25.0")
+		tests.H(t).StringEql(clusterStatus.Acquired[0].Operation, OperationAutoUpdate)
+		tests.H(t).IntEql(clusterStatus.Released, 1)
+		tests.H(t).InterfaceEql(service.VersionStore.(*fakeVersionStore).UpdatedVersions, []UIVersion{"2.25.0"})
+		check := service.lastUpdateCheck()
+		tests.H(t).StringEql(check.AutoUpdateResult.Version, "2.25.0")
+		tests.H(t).StringEql(check.AutoUpdateResult.Error, "")
+	})
+
+	t.Run("only records the check without auto-update", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.AvailableResult = []string{"2.25.0"}
+		updated := false
+		um.UpdateCall = func(string) {
+			updated = true
+		}
+		service.UpdateManager = um
+
+		tests.H(t).IsNil(autoUpdateJob(service)())
+
+		tests.H(t).BoolEql(updated, false)
+		tests.H(t).BoolEql(service.lastUpdateCheck().UpdateAvailable, true)
+	})
+
+	t.Run("skips the update while another master holds the cluster operation", func(t *testing.T) {
+		defer tearDown(t)
+		um := UpdateManagerDouble()
+		um.AvailableResult = []string{"2.25.0"}
+		updated := false
+		um.UpdateCall = func(string) {
+			updated = true
+		}
+		service := setupAutoUpdateUIService(um)
+		service.ClusterStatus = &fakeClusterStatus{AcquireError: ErrClusterBusy}
+
+		tests.H(t).IsNil(autoUpdateJob(service)())
+
+		tests.H(t).BoolEql(updated, false)
+	})
+
+	t.Run("skips the update while syncs are frozen", func(t *testing.T) {
+		defer tearDown(t)
+		um := UpdateManagerDouble()
+		um.AvailableResult = []string{"2.25.0"}
+		updated := false
+		um.UpdateCall = func(string) {
+			updated = true
+		}
+		service := setupAutoUpdateUIService(um)
+		service.ClusterFreeze = &fakeClusterFreeze{Active: &SyncFreeze{Version: "2.25.0"}}
+
+		tests.H(t).IsNil(autoUpdateJob(service)())
+
+		tests.H(t).BoolEql(updated, false)
+	})
+
+	t.Run("skips the update while pinned to another version", func(t *testing.T) {
+		defer tearDown(t)
+		um := UpdateManagerDouble()
+		um.AvailableResult = []string{"2.25.0"}
+		updated := false
+		um.UpdateCall = func(string) {
+			updated = true
+		}
+		service := setupAutoUpdateUIService(um)
+		service.ClusterPin = &fakeClusterPin{Active: &VersionPin{Version: "2.24.4"}}
+
+		tests.H(t).IsNil(autoUpdateJob(service)())
+
+		tests.H(t).BoolEql(updated, false)
+	})
+
+	t.Run("records a failed check", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.AvailableError = errors.New("cosmos unavailable")
+		service.UpdateManager = um
+
+		tests.H(t).ErrEql(autoUpdateJob(service)(), um.AvailableError)
+
+		tests.H(t).StringEql(service.lastUpdateCheck().Error, "cosmos unavailable")
+	})
+}
+
+func TestUpdateAvailableHandler(t *testing.T) {
+	t.Run("returns 404 before the first check", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/update/available/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusNotFound)
+	})
+
+	t.Run("checks again with refresh", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.AvailableResult = []string{"2.24.4", "2.25.0"}
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/update/available/?refresh=true", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var check UpdateCheck
+		tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &check))
+		tests.H(t).StringEql(check.Latest, "2.25.0")
+		tests.H(t).BoolEql(check.UpdateAvailable, true)
+	})
+
+	t.Run("returns 400 for an invalid refresh parameter", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/update/available/?refresh=maybe", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusBadRequest)
+	})
+}
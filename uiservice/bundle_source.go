@@ -0,0 +1,87 @@
+package uiservice
+
+import (
+	"net/url"
+	"path"
+
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const bundleSourcesNode = "bundles"
+
+// BundleSources keeps the bundle URLs of the versions installed from a direct URL or a local file, so the
+// other masters download the same bundle when they sync to such a version
+type BundleSources interface {
+	// StoreBundleSource stores the bundle URL of version, replacing an existing one
+	StoreBundleSource(version, bundleURL string) error
+	// BundleSource returns the bundle URL of version, false if the version is installed from the package source
+	BundleSource(version string) (string, bool, error)
+}
+
+func makeBundleSourcesPath(basePath string) string {
+	return path.Join(basePath, bundleSourcesNode)
+}
+
+// StoreBundleSource creates or replaces a persistent node named after the version below the bundles node
+func (zks *zkVersionStore) StoreBundleSource(version, bundleURL string) error {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return ErrZookeeperNotConnected
+	}
+	sourcesPath := makeBundleSourcesPath(zks.zkBasePath)
+	if err := zks.client.Create(sourcesPath, nil, zookeeper.PermAll); err != nil && err != zookeeper.ErrNodeExists {
+		return errors.Wrap(err, "Failed to create the bundle sources node")
+	}
+	sourcePath := path.Join(sourcesPath, version)
+	err := zks.client.Create(sourcePath, []byte(bundleURL), zookeeper.PermAll)
+	if err == zookeeper.ErrNodeExists {
+		_, err = zks.client.Set(sourcePath, []byte(bundleURL))
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to store the bundle source")
+	}
+	return nil
+}
+
+// BundleSource reads the node stored by StoreBundleSource
+func (zks *zkVersionStore) BundleSource(version string) (string, bool, error) {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return "", false, ErrZookeeperNotConnected
+	}
+	sourcePath := path.Join(makeBundleSourcesPath(zks.zkBasePath), version)
+	exists, _, err := zks.client.Exists(sourcePath)
+	if err != nil {
+		return "", false, errors.Wrap(err, "Failed to check the bundle source")
+	}
+	if !exists {
+		return "", false, nil
+	}
+	data, _, err := zks.client.Get(sourcePath)
+	if err != nil {
+		return "", false, errors.Wrap(err, "Failed to read the bundle source")
+	}
+	return string(data), true, nil
+}
+
+// updateToVersion downloads and activates version like UpdateManager.UpdateToVersion. Versions installed
+// from a direct URL or a local file are downloaded from their stored bundle source.
+func updateToVersion(service *UIService, version string, activator updatemanager.Activator) error {
+	sources, ok := service.VersionStore.(BundleSources)
+	if !ok || UIVersion(version) == PreBundledUIVersion {
+		return service.UpdateManager.UpdateToVersion(version, activator)
+	}
+	source, found, err := sources.BundleSource(version)
+	if err != nil {
+		logrus.WithError(err).WithField("version", version).Warn("Failed to read the bundle source, using the package source")
+	}
+	if !found {
+		return service.UpdateManager.UpdateToVersion(version, activator)
+	}
+	bundleURL, err := url.Parse(source)
+	if err != nil {
+		return errors.Wrapf(err, "invalid bundle source of version %s", version)
+	}
+	return service.UpdateManager.UpdateFromBundle(version, bundleURL, activator)
+}
@@ -0,0 +1,82 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Reason codes of the responses rejecting an operation because another one is in progress
+const (
+	ReasonInProgressSameVersion  = "IN_PROGRESS_SAME_VERSION"
+	ReasonInProgressOtherVersion = "IN_PROGRESS_OTHER_VERSION"
+	ReasonClusterLocked          = "CLUSTER_LOCKED"
+	ReasonMaintenanceMode        = "MAINTENANCE_MODE"
+)
+
+// defaultRetryAfter is suggested to retry a rejected operation if no recent update tells how long it takes
+const defaultRetryAfter = 10 * time.Second
+
+// busyResponse tells automation why an operation was rejected by this master and when to retry it,
+// operations rejected by another master's cluster operation get a clusterBusyResponse with its id
+type busyResponse struct {
+	Message    string `json:"message"`
+	ReasonCode string `json:"reasonCode"`
+	// Version is the version of the blocking operation, empty for a reset
+	Version string `json:"version,omitempty"`
+	// RetryAfter is the number of seconds after which the operation is likely to succeed
+	RetryAfter int `json:"retryAfter"`
+}
+
+// localBusyReason returns the reason code for an operation to version rejected while the service updates to updatingVersion
+func localBusyReason(version, updatingVersion string) string {
+	switch updatingVersion {
+	case maintenanceVersion:
+		return ReasonMaintenanceMode
+	case version:
+		return ReasonInProgressSameVersion
+	}
+	return ReasonInProgressOtherVersion
+}
+
+// retryAfter estimates the seconds until the operation started at since finishes, based on the duration of
+// the recent updates. It is at least one second.
+func retryAfter(service *UIService, since time.Time) int {
+	remaining := defaultRetryAfter
+	if average := averageUpdateDuration(service.operationHistory().Results()); average > 0 {
+		remaining = average
+		if !since.IsZero() {
+			remaining = time.Until(since.Add(average))
+		}
+	}
+	return retryAfterSeconds(remaining)
+}
+
+// writeLocalBusy rejects an operation to version because the service is updating to updatingVersion. The
+// body is a busyResponse for application/json requests and message otherwise, Retry-After is always set.
+func writeLocalBusy(service *UIService, w http.ResponseWriter, r *http.Request, status int, version, updatingVersion, message string) {
+	var since time.Time
+	if inFlight := service.inFlightOperation(); inFlight != nil {
+		since = inFlight.Since
+	}
+	response := busyResponse{
+		Message:    message,
+		ReasonCode: localBusyReason(version, updatingVersion),
+		Version:    updatingVersion,
+		RetryAfter: retryAfter(service, since),
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(response.RetryAfter))
+	if !wantsJSON(r) {
+		http.Error(w, message, status)
+		return
+	}
+	js, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
@@ -0,0 +1,90 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestBusyResponses(t *testing.T) {
+	cases := []struct {
+		name            string
+		method          string
+		uri             string
+		updatingVersion string
+		status          int
+		reasonCode      string
+	}{
+		{"update to the version in progress", "POST", "/api/v1/update/2.24.4/", "2.24.4", http.StatusAccepted, ReasonInProgressSameVersion},
+		{"update to another version", "POST", "/api/v1/update/2.24.4/", "2.24.3", http.StatusConflict, ReasonInProgressOtherVersion},
+		{"update during maintenance", "POST", "/api/v1/update/2.24.4/", maintenanceVersion, http.StatusConflict, ReasonMaintenanceMode},
+		{"reset during a reset", "DELETE", "/api/v1/reset/", "", http.StatusConflict, ReasonInProgressSameVersion},
+		{"reset during an update", "DELETE", "/api/v1/reset/", "2.24.3", http.StatusConflict, ReasonInProgressOtherVersion},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name+" returns "+tt.reasonCode, func(t *testing.T) {
+			defer tearDown(t)
+			service := setupUIServiceWithVersion()
+			service.UpdateManager = UpdateManagerDouble()
+			setServiceUpdating(service, tt.updatingVersion)
+
+			req := httptest.NewRequest(tt.method, tt.uri, nil)
+			req.Header.Set("Accept", "application/json")
+			rr := httptest.NewRecorder()
+			newRouter(service).ServeHTTP(rr, req)
+
+			tests.H(t).IntEql(rr.Code, tt.status)
+			var response busyResponse
+			tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &response))
+			tests.H(t).StringEql(response.ReasonCode, tt.reasonCode)
+			tests.H(t).StringEql(response.Version, tt.updatingVersion)
+			tests.H(t).IntEql(response.RetryAfter, 10)
+			tests.H(t).StringEql(rr.Header().Get("Retry-After"), "10")
+		})
+	}
+
+	t.Run("keeps the plain text message for other clients", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.24.3")
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/update/2.24.4/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+		tests.H(t).StringEql(rr.Body.String(), "Service is currently processing an update request to 2.24.3\n")
+		tests.H(t).StringEql(rr.Header().Get("Retry-After"), "10")
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Run("estimates the remaining time from recent updates", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		now := time.Now().UTC()
+		service.operationHistory().add(ClusterOperationResult{
+			Operation:  OperationUpdate,
+			StartedAt:  now.Add(-time.Hour),
+			FinishedAt: now.Add(-time.Hour + time.Minute),
+		})
+
+		tests.H(t).IntEql(retryAfter(service, now.Add(-30*time.Second)), 30)
+	})
+
+	t.Run("is at least one second", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		now := time.Now().UTC()
+		service.operationHistory().add(ClusterOperationResult{
+			Operation:  OperationUpdate,
+			StartedAt:  now.Add(-time.Hour),
+			FinishedAt: now.Add(-time.Hour + time.Minute),
+		})
+
+		tests.H(t).IntEql(retryAfter(service, now.Add(-time.Hour)), 1)
+	})
+}
@@ -0,0 +1,136 @@
+package uiservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrUpdateActivating occurs if an update is cancelled after it started to serve the new version
+var ErrUpdateActivating = errors.New("The update is already activating the version and can no longer be cancelled")
+
+// operationContext is done once the update running on this master was cancelled, context.Background if the
+// running operation cannot be cancelled
+func operationContext(service *UIService) context.Context {
+	service.Lock()
+	defer service.Unlock()
+	if service.preemptible == nil {
+		return context.Background()
+	}
+	return service.preemptible.ctx
+}
+
+// cancelRunning cancels the update to version running on this master, it returns nil if no update to version
+// runs and ErrUpdateActivating if the update already serves the new version
+func cancelRunning(service *UIService, version string) (*preemptibleOperation, error) {
+	service.Lock()
+	defer service.Unlock()
+	running := service.preemptible
+	if !service.updating || running == nil || service.updatingVersion != version {
+		return nil, nil
+	}
+	if !running.stop() {
+		return nil, ErrUpdateActivating
+	}
+	return running, nil
+}
+
+// stop cancels the operation unless it already activates its version and does not soak it, the service
+// must be locked
+func (op *preemptibleOperation) stop() bool {
+	if op.activating && !op.soaking {
+		return false
+	}
+	op.cancelled = true
+	op.cancel()
+	return true
+}
+
+// cancelQueued removes the queued updates to version, their jobs are cancelled
+func cancelQueued(service *UIService, version string) []string {
+	queue := service.updateQueue()
+	jobs := service.updateJobs()
+	cancelled := []string{}
+	for _, item := range queue.Queued() {
+		if item.Version != version {
+			continue
+		}
+		if _, ok := queue.remove(item.ID); ok {
+			jobs.setState(item.ID, JobCancelled)
+			cancelled = append(cancelled, item.ID)
+		}
+	}
+	return cancelled
+}
+
+// waitForStop is true once the cancelled operation released the service within timeout
+func waitForStop(service *UIService, op *preemptibleOperation, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		service.Lock()
+		stopped := service.preemptible != op
+		service.Unlock()
+		if stopped {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(preemptionPollInterval)
+	}
+}
+
+type cancelUpdateResponse struct {
+	Version string `json:"version"`
+	// Running is true if the update in progress was cancelled
+	Running bool `json:"running"`
+	// Stopped is false if the cancelled update did not stop within the preemption timeout yet
+	Stopped bool `json:"stopped"`
+	// Queued are the job ids of the removed queued updates
+	Queued []string `json:"queued"`
+}
+
+// cancelUpdateHandler aborts the download and unpack of the update to a version on this master and removes the
+// queued updates to it. The partially downloaded version is removed, the cluster operation is released and the
+// update fails with E_CANCELLED, so the masters keep serving the previous version. An update that already
+// serves the new version cannot be cancelled.
+func cancelUpdateHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version := mux.Vars(r)["version"]
+		logger := logrus.WithField("version", version)
+
+		running, err := cancelRunning(service, version)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		response := cancelUpdateResponse{Version: version, Running: running != nil, Stopped: true}
+		response.Queued = cancelQueued(service, version)
+		if running == nil && len(response.Queued) == 0 {
+			http.Error(w, "No update to "+version+" in progress or queued", http.StatusNotFound)
+			return
+		}
+		logger.WithFields(logrus.Fields{"running": response.Running, "queued": len(response.Queued)}).Info("Cancelled update")
+
+		status := http.StatusOK
+		if running != nil && !waitForStop(service, running, preemptionTimeout) {
+			logger.Warn("The cancelled update did not stop yet")
+			response.Stopped = false
+			status = http.StatusAccepted
+		}
+
+		js, err := json.Marshal(response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(js)
+	}
+}
@@ -0,0 +1,158 @@
+package uiservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+)
+
+func TestCancelRunning(t *testing.T) {
+	t.Run("cancels the context of an update that is not activated yet", func(t *testing.T) {
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.25.0")
+		defer resetServiceFromUpdate(service)
+		markPreemptible(service, OperationUpdate)
+		ctx := operationContext(service)
+
+		running, err := cancelRunning(service, "2.25.0")
+
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(running != nil, true)
+		tests.H(t).ErrEql(ctx.Err(), context.Canceled)
+		tests.H(t).ErrEql(beginActivation(service), updatemanager.ErrUpdateCancelled)
+	})
+
+	t.Run("ignores an update to another version", func(t *testing.T) {
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.25.0")
+		defer resetServiceFromUpdate(service)
+		markPreemptible(service, OperationUpdate)
+
+		running, err := cancelRunning(service, "2.24.4")
+
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(running == nil, true)
+		tests.H(t).IsNil(operationContext(service).Err())
+	})
+
+	t.Run("does not cancel an activating update", func(t *testing.T) {
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.25.0")
+		defer resetServiceFromUpdate(service)
+		markPreemptible(service, OperationUpdate)
+		beginActivation(service)
+
+		_, err := cancelRunning(service, "2.25.0")
+
+		tests.H(t).ErrEql(err, ErrUpdateActivating)
+		tests.H(t).IsNil(operationContext(service).Err())
+	})
+}
+
+func TestCancelUpdateHandler(t *testing.T) {
+	queuePollInterval = 5 * time.Millisecond
+	preemptionPollInterval = 5 * time.Millisecond
+
+	t.Run("stops a downloading update and removes the queued updates to the version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		nodes := &fakeClusterNodes{fakeVersionStore: VersionStoreDouble()}
+		service.VersionStore = nodes
+		clusterStatus := &fakeClusterStatus{}
+		service.ClusterStatus = clusterStatus
+		um := UpdateManagerDouble()
+		um.VersionResult = "2.23.0"
+		um.UpdateNewVersionPath = service.Config.DefaultDocRoot()
+		downloading := make(chan struct{})
+		um.UpdateCall = func(string) {
+			close(downloading)
+			<-operationContext(service).Done()
+		}
+		service.UpdateManager = um
+
+		started := startAsyncUpdate(t, service)
+		<-downloading
+		queued := queueUpdate(t, service, "2.24.4")
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/update/2.24.4/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var response cancelUpdateResponse
+		tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &response))
+		tests.H(t).BoolEql(response.Running, true)
+		tests.H(t).BoolEql(response.Stopped, true)
+		tests.H(t).InterfaceEql(response.Queued, []string{queued.ID})
+
+		job, _ := service.updateJobs().get(started.ID)
+		tests.H(t).StringEql(job.State, JobCancelled)
+		tests.H(t).StringEql(job.Result.ErrorCode, ErrorCodeCancelled)
+		job, _ = service.updateJobs().get(queued.ID)
+		tests.H(t).StringEql(job.State, JobCancelled)
+		tests.H(t).IntEql(service.updateQueue().Len(), 0)
+		tests.H(t).IntEql(clusterStatus.Released, 1)
+
+		reported := nodes.Reported[len(nodes.Reported)-1]
+		tests.H(t).StringEql(reported.Version, "2.23.0")
+		tests.H(t).StringEql(reported.Error, "")
+		entries, err := service.auditLog().Entries(context.Background(), 10)
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(entries[0].Outcome, OutcomeCancelled)
+
+		_, err = setServiceUpdating(service, "2.25.0")
+		tests.H(t).IsNil(err)
+		resetServiceFromUpdate(service)
+	})
+
+	t.Run("returns 404 if no update to the version runs or is queued", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.25.0")
+		defer resetServiceFromUpdate(service)
+		markPreemptible(service, OperationUpdate)
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/update/2.24.4/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusNotFound)
+		tests.H(t).IsNil(operationContext(service).Err())
+	})
+
+	t.Run("rejects the cancellation while the update is activating", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.25.0")
+		defer resetServiceFromUpdate(service)
+		markPreemptible(service, OperationUpdate)
+		beginActivation(service)
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/update/2.25.0/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+	})
+
+	t.Run("accepts the cancellation if the update does not stop in time", func(t *testing.T) {
+		defer tearDown(t)
+		defer func(timeout time.Duration) { preemptionTimeout = timeout }(preemptionTimeout)
+		preemptionTimeout = 20 * time.Millisecond
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.25.0")
+		defer resetServiceFromUpdate(service)
+		markPreemptible(service, OperationUpdate)
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/update/2.25.0/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusAccepted)
+		var response cancelUpdateResponse
+		tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &response))
+		tests.H(t).BoolEql(response.Stopped, false)
+	})
+}
@@ -0,0 +1,171 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// clusterEventsNode is the parent of the persistent sequential nodes recording the finished cluster
+	// operations of all masters, they outlive the master that wrote them
+	clusterEventsNode  = "events"
+	clusterEventPrefix = "event-"
+)
+
+// ClusterEvent is the compact record of a finished cluster operation kept in ZooKeeper
+type ClusterEvent struct {
+	OpID string `json:"opId"`
+	Type string `json:"type"`
+	// Initiator is the master that ran the operation
+	Initiator string    `json:"initiator"`
+	Version   string    `json:"version,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Time      time.Time `json:"time"`
+}
+
+// ClusterEvents keeps the recent cluster operations of all masters
+type ClusterEvents interface {
+	// AppendClusterEvent stores the event and removes the oldest events beyond cluster-events-to-keep
+	AppendClusterEvent(event ClusterEvent) error
+	// ClusterEvents returns the last limit events, the most recent first
+	ClusterEvents(limit int) ([]ClusterEvent, error)
+}
+
+func makeClusterEventsPath(basePath string) string {
+	return path.Join(basePath, clusterEventsNode)
+}
+
+// AppendClusterEvent creates a sequential node below the events node and trims the oldest events
+func (zks *zkVersionStore) AppendClusterEvent(event ClusterEvent) error {
+	if zks.eventsToKeep <= 0 {
+		return nil
+	}
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return ErrZookeeperNotConnected
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	eventsPath := makeClusterEventsPath(zks.zkBasePath)
+	if err := zks.client.Create(eventsPath, nil, zookeeper.PermAll); err != nil && err != zookeeper.ErrNodeExists {
+		return errors.Wrap(err, "Failed to create the cluster events node")
+	}
+	if _, err := zks.client.CreateSequential(path.Join(eventsPath, clusterEventPrefix), data, zookeeper.PermAll); err != nil {
+		return errors.Wrap(err, "Failed to store the cluster event")
+	}
+
+	children, _, err := zks.client.Children(eventsPath)
+	if err != nil || len(children) <= zks.eventsToKeep {
+		return nil
+	}
+	sort.Strings(children)
+	for _, child := range children[:len(children)-zks.eventsToKeep] {
+		if err := zks.client.Delete(path.Join(eventsPath, child)); err != nil {
+			// another master may have trimmed the same event already
+			log.WithError(err).WithField("event", child).Debug("Failed to remove old cluster event")
+		}
+	}
+	return nil
+}
+
+// ClusterEvents reads the newest event nodes, events removed while reading are skipped
+func (zks *zkVersionStore) ClusterEvents(limit int) ([]ClusterEvent, error) {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return nil, ErrZookeeperNotConnected
+	}
+	eventsPath := makeClusterEventsPath(zks.zkBasePath)
+	exists, _, err := zks.client.Exists(eventsPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to check the cluster events node")
+	}
+	events := []ClusterEvent{}
+	if !exists {
+		return events, nil
+	}
+	children, _, err := zks.client.Children(eventsPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list the cluster events")
+	}
+	sort.Strings(children)
+	for i := len(children) - 1; i >= 0 && len(events) < limit; i-- {
+		data, _, err := zks.client.Get(path.Join(eventsPath, children[i]))
+		if err != nil {
+			continue
+		}
+		var event ClusterEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.WithError(err).WithField("event", children[i]).Debug("Skipping unreadable cluster event")
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// recordClusterEvent appends the finished operation to the cluster events. Failing to write the event does
+// not fail the operation, it is logged.
+func recordClusterEvent(service *UIService, result *ClusterOperationResult, err error) {
+	events, ok := service.VersionStore.(ClusterEvents)
+	if !ok {
+		return
+	}
+	event := ClusterEvent{
+		OpID:      result.OpID,
+		Type:      result.Operation,
+		Initiator: service.nodeName(),
+		Version:   result.Version,
+		Outcome:   operationOutcome(err),
+		Time:      result.FinishedAt,
+	}
+	if appendErr := events.AppendClusterEvent(event); appendErr != nil {
+		logrus.WithError(appendErr).WithField("opId", result.OpID).Warn("Failed to record the cluster event")
+	}
+}
+
+// clusterHistoryHandler lists the recent cluster operations of all masters, the most recent first
+func clusterHistoryHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		events, ok := service.VersionStore.(ClusterEvents)
+		if !ok {
+			http.Error(w, "The version store keeps no cluster events", http.StatusNotImplemented)
+			return
+		}
+		limit := defaultHistoryLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+		result, err := events.ClusterEvents(limit)
+		if err == ErrZookeeperNotConnected {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			logrus.WithError(err).Error("Failed to read the cluster events")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		js, err := json.Marshal(result)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
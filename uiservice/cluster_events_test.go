@@ -0,0 +1,169 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	"github.com/pkg/errors"
+)
+
+type fakeClusterEvents struct {
+	*fakeVersionStore
+	Appended []ClusterEvent
+}
+
+func (e *fakeClusterEvents) AppendClusterEvent(event ClusterEvent) error {
+	e.Appended = append(e.Appended, event)
+	return nil
+}
+
+func (e *fakeClusterEvents) ClusterEvents(limit int) ([]ClusterEvent, error) {
+	events := []ClusterEvent{}
+	for i := len(e.Appended) - 1; i >= 0 && len(events) < limit; i-- {
+		events = append(events, e.Appended[i])
+	}
+	return events, nil
+}
+
+func TestZKClusterEvents(t *testing.T) {
+	t.Run("appends an event node", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		store.eventsToKeep = 10
+
+		tests.H(t).IsNil(store.AppendClusterEvent(ClusterEvent{OpID: "op-1", Type: OperationUpdate, Outcome: OutcomeSuccess}))
+
+		tests.H(t).IntEql(len(client.SequentialCreated), 1)
+		tests.H(t).BoolEql(strings.HasPrefix(client.SequentialCreated[0], "/dcos/ui-service-test/events/event-"), true)
+	})
+
+	t.Run("removes the oldest events above the limit", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		store.eventsToKeep = 2
+		for i := 3; i >= 0; i-- {
+			client.ChildrenResults = append(client.ChildrenResults, fmt.Sprintf("%s%010d", clusterEventPrefix, i))
+		}
+		var deleted []string
+		client.DeleteCall = func(path string) {
+			deleted = append(deleted, path)
+		}
+
+		tests.H(t).IsNil(store.AppendClusterEvent(ClusterEvent{OpID: "op-1"}))
+
+		tests.H(t).InterfaceEql(deleted, []string{
+			"/dcos/ui-service-test/events/event-0000000000",
+			"/dcos/ui-service-test/events/event-0000000001",
+		})
+	})
+
+	t.Run("writes no events if none are kept", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+
+		tests.H(t).IsNil(store.AppendClusterEvent(ClusterEvent{OpID: "op-1"}))
+
+		tests.H(t).IntEql(len(client.SequentialCreated), 0)
+	})
+
+	t.Run("reads the newest events first", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.ExistsResult = true
+		client.ChildrenResults = []string{"event-0000000000", "event-0000000002", "event-0000000001"}
+		for _, id := range []string{"op-3", "op-2"} {
+			data, _ := json.Marshal(ClusterEvent{OpID: id})
+			client.GetResults = append(client.GetResults, data)
+		}
+
+		events, err := store.ClusterEvents(2)
+
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(len(events), 2)
+		tests.H(t).StringEql(events[0].OpID, "op-3")
+		tests.H(t).StringEql(events[1].OpID, "op-2")
+	})
+
+	t.Run("returns ErrZookeeperNotConnected while disconnected", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		store.eventsToKeep = 10
+		client.ClientStateResult = zookeeper.Disconnected
+
+		tests.H(t).ErrEql(store.AppendClusterEvent(ClusterEvent{OpID: "op-1"}), ErrZookeeperNotConnected)
+		_, err := store.ClusterEvents(10)
+		tests.H(t).ErrEql(err, ErrZookeeperNotConnected)
+	})
+}
+
+func TestRecordClusterEvent(t *testing.T) {
+	defer tearDown(t)
+	service := setupTestUIService()
+	events := &fakeClusterEvents{fakeVersionStore: VersionStoreDouble()}
+	service.VersionStore = events
+
+	result := &ClusterOperationResult{Operation: OperationUpdate, OpID: "op-1", Version: "2.25.0"}
+	result.finish(service, errors.Wrap(ErrSuperseded, "reset requested"))
+
+	tests.H(t).IntEql(len(events.Appended), 1)
+	event := events.Appended[0]
+	tests.H(t).StringEql(event.OpID, "op-1")
+	tests.H(t).StringEql(event.Type, OperationUpdate)
+	tests.H(t).StringEql(event.Initiator, service.nodeName())
+	tests.H(t).StringEql(event.Outcome, OutcomeSuperseded)
+	tests.H(t).BoolEql(event.Time.Equal(result.FinishedAt), true)
+}
+
+func TestOperationResultPackageName(t *testing.T) {
+	defer tearDown(t)
+	service := setupTestUIService()
+	um := UpdateManagerDouble()
+	um.PackageNameResult = "dcos-ui-oss"
+	service.UpdateManager = um
+
+	result := &ClusterOperationResult{Operation: OperationUpdate, OpID: "op-1", Version: "2.25.0"}
+	result.finish(service, nil)
+
+	tests.H(t).StringEql(result.PackageName, "dcos-ui-oss")
+}
+
+func TestClusterHistoryHandler(t *testing.T) {
+	get := func(service *UIService, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		return rr
+	}
+
+	t.Run("lists the cluster events, the most recent first", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.VersionStore = &fakeClusterEvents{
+			fakeVersionStore: VersionStoreDouble(),
+			Appended:         []ClusterEvent{{OpID: "op-1"}, {OpID: "op-2"}, {OpID: "op-3"}},
+		}
+
+		rr := get(service, "/api/v1/history/cluster/?limit=2")
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var events []ClusterEvent
+		tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &events))
+		tests.H(t).IntEql(len(events), 2)
+		tests.H(t).StringEql(events[0].OpID, "op-3")
+	})
+
+	t.Run("returns 501 without cluster events", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		tests.H(t).IntEql(get(service, "/api/v1/history/cluster/").Code, http.StatusNotImplemented)
+	})
+
+	t.Run("returns 400 for an invalid limit", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.VersionStore = &fakeClusterEvents{fakeVersionStore: VersionStoreDouble()}
+
+		tests.H(t).IntEql(get(service, "/api/v1/history/cluster/?limit=0").Code, http.StatusBadRequest)
+	})
+}
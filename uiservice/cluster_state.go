@@ -0,0 +1,108 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// States of the cluster-wide change of the served version
+const (
+	// ClusterStateRequested is an operation started on one master that no master has completed yet
+	ClusterStateRequested = "Requested"
+	// ClusterStateInProgress is a version change some masters have not completed yet
+	ClusterStateInProgress = "InProgress"
+	// ClusterStateComplete is all masters serving the stored version
+	ClusterStateComplete = "Complete"
+	// ClusterStateFailed is a version change that failed on at least one master
+	ClusterStateFailed = "Failed"
+)
+
+type clusterStateResponse struct {
+	State     string            `json:"state"`
+	Operation *ClusterOperation `json:"operation,omitempty"`
+	// Version is the version the masters change to, empty for the pre-bundled ui
+	Version string             `json:"version"`
+	Nodes   []NodeVersionState `json:"nodes"`
+	// Lagging are the masters that did not complete the change to Version
+	Lagging []string `json:"lagging"`
+	// Tainted are the masters whose served files changed after activation
+	Tainted []string `json:"tainted"`
+}
+
+// clusterState derives the state of the change to the stored version or to the version of the active
+// operation from the states reported by the masters
+func clusterState(stored string, op *ClusterOperation, nodes []NodeVersionState) clusterStateResponse {
+	response := clusterStateResponse{Operation: op, Version: stored, Nodes: nodes, Lagging: []string{}, Tainted: []string{}}
+	if op != nil && changesServedVersion(op.Operation) {
+		response.Version = op.Version
+	}
+	failed, completed := false, 0
+	for _, node := range nodes {
+		if node.Tainted {
+			response.Tainted = append(response.Tainted, node.Node)
+		}
+		switch {
+		case node.Reported && node.Version == response.Version && node.Error != "":
+			failed = true
+			response.Lagging = append(response.Lagging, node.Node)
+		case node.Reported && node.Version == response.Version:
+			completed++
+		default:
+			response.Lagging = append(response.Lagging, node.Node)
+		}
+	}
+	switch {
+	case failed:
+		response.State = ClusterStateFailed
+	case op != nil && completed == 0:
+		response.State = ClusterStateRequested
+	case op != nil || len(response.Lagging) > 0:
+		response.State = ClusterStateInProgress
+	default:
+		response.State = ClusterStateComplete
+	}
+	return response
+}
+
+// clusterStateHandler reports the cluster-wide state of the last version change and the masters lagging
+// behind it, so it can be checked on any master
+func clusterStateHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nodes, ok := service.VersionStore.(ClusterNodes)
+		if !ok {
+			http.Error(w, "The version store does not share node states", http.StatusNotImplemented)
+			return
+		}
+		stored, err := service.VersionStore.CurrentVersion()
+		if err != nil {
+			logrus.WithError(err).Error("Failed to read the stored version")
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		states, err := nodes.NodeStates()
+		if err != nil {
+			logrus.WithError(err).Error("Failed to read the node states")
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		var op *ClusterOperation
+		if service.ClusterStatus != nil {
+			if op, err = service.ClusterStatus.ActiveOperation(); err != nil {
+				logrus.WithError(err).Error("Failed to read the active cluster operation")
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		js, err := json.Marshal(clusterState(string(stored), op, states))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
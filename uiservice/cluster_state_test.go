@@ -0,0 +1,163 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+type fakeClusterNodes struct {
+	*fakeVersionStore
+	States   []NodeVersionState
+	Reported []NodeVersionState
+}
+
+func (n *fakeClusterNodes) ReportNodeState(state NodeVersionState) error {
+	n.Reported = append(n.Reported, state)
+	return nil
+}
+
+func (n *fakeClusterNodes) NodeStates() ([]NodeVersionState, error) {
+	return n.States, nil
+}
+
+func TestClusterState(t *testing.T) {
+	synced := NodeVersionState{Node: "10.0.0.1", Version: "2.25.0", Reported: true}
+	lagging := NodeVersionState{Node: "10.0.0.2", Version: "2.24.4", Reported: true}
+	failed := NodeVersionState{Node: "10.0.0.3", Version: "2.25.0", Error: "download failed", Reported: true}
+	update := &ClusterOperation{Operation: OperationUpdate, Version: "2.25.0"}
+
+	t.Run("is complete if all masters changed to the stored version", func(t *testing.T) {
+		state := clusterState("2.25.0", nil, []NodeVersionState{synced})
+
+		tests.H(t).StringEql(state.State, ClusterStateComplete)
+		tests.H(t).IntEql(len(state.Lagging), 0)
+	})
+
+	t.Run("is in progress while masters lag behind the stored version", func(t *testing.T) {
+		state := clusterState("2.25.0", nil, []NodeVersionState{synced, lagging})
+
+		tests.H(t).StringEql(state.State, ClusterStateInProgress)
+		tests.H(t).InterfaceEql(state.Lagging, []string{"10.0.0.2"})
+	})
+
+	t.Run("is requested until a master completed the active operation", func(t *testing.T) {
+		state := clusterState("2.24.4", update, []NodeVersionState{lagging})
+
+		tests.H(t).StringEql(state.State, ClusterStateRequested)
+		tests.H(t).StringEql(state.Version, "2.25.0")
+	})
+
+	t.Run("is in progress once a master completed the active operation", func(t *testing.T) {
+		state := clusterState("2.24.4", update, []NodeVersionState{synced, lagging})
+
+		tests.H(t).StringEql(state.State, ClusterStateInProgress)
+	})
+
+	t.Run("is failed if the change failed on a master", func(t *testing.T) {
+		state := clusterState("2.25.0", nil, []NodeVersionState{synced, failed})
+
+		tests.H(t).StringEql(state.State, ClusterStateFailed)
+		tests.H(t).InterfaceEql(state.Lagging, []string{"10.0.0.3"})
+	})
+
+	t.Run("lists tainted masters", func(t *testing.T) {
+		tainted := synced
+		tainted.Tainted = true
+
+		state := clusterState("2.25.0", nil, []NodeVersionState{tainted, lagging})
+
+		tests.H(t).InterfaceEql(state.Tainted, []string{"10.0.0.1"})
+	})
+
+	t.Run("lists masters without reported state as lagging", func(t *testing.T) {
+		state := clusterState("2.25.0", nil, []NodeVersionState{{Node: "10.0.0.4"}})
+
+		tests.H(t).StringEql(state.State, ClusterStateInProgress)
+	})
+}
+
+func TestClusterStateHandler(t *testing.T) {
+	t.Run("reports the state of the masters", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.VersionStore = &fakeClusterNodes{
+			fakeVersionStore: &fakeVersionStore{VersionResult: "2.24.4"},
+			States:           []NodeVersionState{{Node: "10.0.0.1", Version: "2.25.0", Reported: true}},
+		}
+		service.ClusterStatus = &fakeClusterStatus{Running: &ClusterOperation{ID: "op", Operation: OperationUpdate, Version: "2.25.0"}}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/status/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var response clusterStateResponse
+		tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &response))
+		tests.H(t).StringEql(response.State, ClusterStateInProgress)
+		tests.H(t).StringEql(response.Operation.ID, "op")
+		tests.H(t).IntEql(len(response.Nodes), 1)
+	})
+
+	t.Run("returns 501 if the version store does not share node states", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/status/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusNotImplemented)
+	})
+
+	t.Run("is reported after a sync", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		nodes := &fakeClusterNodes{fakeVersionStore: VersionStoreDouble()}
+		service.VersionStore = nodes
+
+		recordSync(service, "2.24.4", "2.25.0", time.Now(), errors.New("download failed"))
+
+		tests.H(t).IntEql(len(nodes.Reported), 1)
+		tests.H(t).StringEql(nodes.Reported[0].Version, "2.25.0")
+		tests.H(t).StringEql(nodes.Reported[0].Error, "download failed")
+	})
+}
+
+func TestZKNodeStates(t *testing.T) {
+	t.Run("ReportNodeState sets the node-status node of this master", func(t *testing.T) {
+		store, client := makeZKStore("2.25.0")
+		store.nodeIP.ip = net.ParseIP("10.0.0.1")
+		var setPath string
+		var setData []byte
+		client.SetCall = func(path string, data []byte) {
+			setPath, setData = path, data
+		}
+
+		tests.H(t).IsNil(store.ReportNodeState(NodeVersionState{Node: "10.0.0.1", Version: "2.25.0"}))
+
+		tests.H(t).StringEql(setPath, "/dcos/ui-service-test/node-status/10.0.0.1")
+		var state NodeVersionState
+		tests.H(t).IsNil(json.Unmarshal(setData, &state))
+		tests.H(t).BoolEql(state.Reported, true)
+		tests.H(t).StringEql(state.Version, "2.25.0")
+	})
+
+	t.Run("NodeStates lists masters registered without state", func(t *testing.T) {
+		store, client := makeZKStore("2.25.0")
+		client.ChildrenResults = []string{"10.0.0.1", "10.0.0.2"}
+		client.GetResults = [][]byte{[]byte(`{"node":"10.0.0.1","version":"2.25.0","reported":true}`), []byte("10.0.0.2")}
+
+		states, err := store.NodeStates()
+
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(len(states), 2)
+		tests.H(t).StringEql(states[0].Version, "2.25.0")
+		tests.H(t).BoolEql(states[1].Reported, false)
+		tests.H(t).StringEql(states[1].Node, "10.0.0.2")
+	})
+}
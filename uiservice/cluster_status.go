@@ -0,0 +1,266 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// OperationUpdate is the cluster operation of updating to a new version
+	OperationUpdate = "update"
+	// OperationAutoUpdate is the cluster operation of updating to a newer version found by the update check
+	OperationAutoUpdate = "auto-update"
+	// OperationReset is the cluster operation of resetting to the pre-bundled version
+	OperationReset = "reset"
+	// OperationRemove is the cluster operation of removing a downloaded version
+	OperationRemove = "remove"
+	// OperationRollback is the cluster operation of updating to the previously stored version
+	OperationRollback = "rollback"
+	// OperationImport is the cluster operation of storing the version of an imported state document
+	OperationImport = "import"
+
+	clusterOperationNode = "operation"
+)
+
+var (
+	// ErrClusterBusy occurs if another mutating operation is in progress on the cluster
+	ErrClusterBusy = errors.New("Another operation is currently in progress on the cluster")
+)
+
+// ClusterOperation describes a mutating operation in progress on the cluster
+type ClusterOperation struct {
+	ID        string    `json:"id"`
+	Operation string    `json:"operation"`
+	Version   string    `json:"version,omitempty"`
+	Master    string    `json:"master"`
+	Started   time.Time `json:"started"`
+}
+
+// ClusterStatus coordinates mutating operations between the masters of the cluster
+type ClusterStatus interface {
+	// AcquireOperation marks the cluster busy with the operation, if another operation is
+	// in progress it returns ErrClusterBusy together with the active operation
+	AcquireOperation(ClusterOperation) (ClusterOperation, error)
+	// ReleaseOperation marks the cluster as no longer busy
+	ReleaseOperation() error
+	// ActiveOperation returns the operation in progress, nil if the cluster is not busy
+	ActiveOperation() (*ClusterOperation, error)
+}
+
+func newClusterOperation(service *UIService, operation, version string) ClusterOperation {
+	return ClusterOperation{
+		ID:        newID(),
+		Operation: operation,
+		Version:   version,
+		Master:    service.nodeName(),
+		Started:   time.Now().UTC(),
+	}
+}
+
+// nodeName identifies this master by its cached IP, falling back to the hostname
+func (service *UIService) nodeName() string {
+	if service.IPCache != nil {
+		if ip, err := service.IPCache.IP(); err == nil {
+			return ip.String()
+		}
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return hostname
+}
+
+func makeClusterOperationPath(basePath string) string {
+	return path.Join(basePath, "cluster-status", clusterOperationNode)
+}
+
+// AcquireOperation creates an ephemeral node below cluster-status, so the operation is released
+// if this master loses its ZK session. An operation older than the operation timeout is considered
+// abandoned by a hanging master and is taken over.
+func (zks *zkVersionStore) AcquireOperation(op ClusterOperation) (ClusterOperation, error) {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return ClusterOperation{}, ErrZookeeperNotConnected
+	}
+
+	data, err := json.Marshal(op)
+	if err != nil {
+		return ClusterOperation{}, err
+	}
+	operationPath := makeClusterOperationPath(zks.zkBasePath)
+	err = zks.client.CreateEphemeral(operationPath, data, zookeeper.PermAll)
+	if err == nil {
+		zks.setAcquiredOperation(op.ID)
+		return op, nil
+	}
+	if err != zookeeper.ErrNodeExists {
+		return ClusterOperation{}, errors.Wrap(err, "Failed to mark the cluster as busy")
+	}
+
+	var active ClusterOperation
+	activeData, _, getErr := zks.client.Get(operationPath)
+	if getErr != nil {
+		log.WithError(getErr).Warn("Failed to read the active cluster operation")
+		return active, ErrClusterBusy
+	}
+	if jsonErr := json.Unmarshal(activeData, &active); jsonErr != nil {
+		log.WithError(jsonErr).Warn("Failed to parse the active cluster operation")
+		return active, ErrClusterBusy
+	}
+	if zks.operationTimeout > 0 && zks.clock.Now().Sub(active.Started) > zks.operationTimeout {
+		log.WithFields(logrus.Fields{
+			"opId":    active.ID,
+			"master":  active.Master,
+			"started": active.Started,
+		}).Warn("Taking over a cluster operation that exceeded the operation timeout")
+		if err := zks.client.Delete(operationPath); err != nil {
+			return active, ErrClusterBusy
+		}
+		if err := zks.client.CreateEphemeral(operationPath, data, zookeeper.PermAll); err != nil {
+			return active, ErrClusterBusy
+		}
+		zks.setAcquiredOperation(op.ID)
+		return op, nil
+	}
+	return active, ErrClusterBusy
+}
+
+func (zks *zkVersionStore) setAcquiredOperation(id string) {
+	// the version writes of the operation are checked against the version node as read when it started
+	data, stat, err := zks.client.Get(zks.versionPath)
+	zks.operation.Lock()
+	defer zks.operation.Unlock()
+	zks.operation.acquired = id
+	zks.operation.observed = nil
+	if err != nil {
+		log.WithError(err).WithField("opId", id).Warn("Failed to read the version node, the version writes of the operation are not checked against it")
+		return
+	}
+	zks.operation.observed = &zkVersionNode{version: UIVersion(data), stat: stat}
+}
+
+// operationVersionNode returns the version node observed by the acquired operation, nil without one
+func (zks *zkVersionStore) operationVersionNode() *zkVersionNode {
+	zks.operation.Lock()
+	defer zks.operation.Unlock()
+	return zks.operation.observed
+}
+
+func (zks *zkVersionStore) setOperationVersionNode(node *zkVersionNode) {
+	zks.operation.Lock()
+	defer zks.operation.Unlock()
+	if zks.operation.observed != nil {
+		zks.operation.observed = node
+	}
+}
+
+// ReleaseOperation removes the operation node created by AcquireOperation, unless another master took
+// the operation over after it timed out
+func (zks *zkVersionStore) ReleaseOperation() error {
+	// the operation ends on this master even if its node cannot be removed, later writes are not checked
+	// against the version node it observed
+	zks.operation.Lock()
+	acquired := zks.operation.acquired
+	zks.operation.acquired = ""
+	zks.operation.observed = nil
+	zks.operation.Unlock()
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return ErrZookeeperNotConnected
+	}
+	operationPath := makeClusterOperationPath(zks.zkBasePath)
+	if data, _, err := zks.client.Get(operationPath); err == nil && acquired != "" {
+		var active ClusterOperation
+		if json.Unmarshal(data, &active) == nil && active.ID != "" && active.ID != acquired {
+			log.WithFields(logrus.Fields{"opId": acquired, "takenOverBy": active.ID}).Warn("Cluster operation was taken over after timing out, not releasing it")
+			return nil
+		}
+	}
+	if err := zks.client.Delete(operationPath); err != nil {
+		return errors.Wrap(err, "Failed to mark the cluster as no longer busy")
+	}
+	return nil
+}
+
+// ActiveOperation reads the operation node created by AcquireOperation
+func (zks *zkVersionStore) ActiveOperation() (*ClusterOperation, error) {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return nil, ErrZookeeperNotConnected
+	}
+	operationPath := makeClusterOperationPath(zks.zkBasePath)
+	exists, _, err := zks.client.Exists(operationPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to check for an active cluster operation")
+	}
+	if !exists {
+		return nil, nil
+	}
+	data, _, err := zks.client.Get(operationPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read the active cluster operation")
+	}
+	var active ClusterOperation
+	if err := json.Unmarshal(data, &active); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the active cluster operation")
+	}
+	return &active, nil
+}
+
+type clusterBusyResponse struct {
+	Message    string           `json:"message"`
+	ReasonCode string           `json:"reasonCode"`
+	Operation  ClusterOperation `json:"operation"`
+	RetryAfter int              `json:"retryAfter"`
+}
+
+// acquireClusterOperation marks the cluster busy before a mutating request starts local work.
+// It writes the error response and returns false if the operation must not proceed.
+// Without a ClusterStatus, e.g. when running a single instance for development, nothing is coordinated.
+func acquireClusterOperation(service *UIService, w http.ResponseWriter, op ClusterOperation) bool {
+	if service.ClusterStatus == nil {
+		return true
+	}
+
+	active, err := service.ClusterStatus.AcquireOperation(op)
+	switch err {
+	case nil:
+		return true
+	case ErrClusterBusy:
+		response := clusterBusyResponse{
+			Message:    err.Error(),
+			ReasonCode: ReasonClusterLocked,
+			Operation:  active,
+			RetryAfter: retryAfter(service, active.Started),
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(response.RetryAfter))
+		js, jsonErr := json.Marshal(response)
+		if jsonErr != nil {
+			http.Error(w, err.Error(), http.StatusLocked)
+			return false
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusLocked)
+		w.Write(js)
+		return false
+	default:
+		logrus.WithError(err).Error("Failed to check the cluster status")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return false
+	}
+}
+
+func releaseClusterOperation(service *UIService) {
+	if service.ClusterStatus == nil {
+		return
+	}
+	if err := service.ClusterStatus.ReleaseOperation(); err != nil {
+		logrus.WithError(err).Error("Failed to release the cluster operation")
+	}
+}
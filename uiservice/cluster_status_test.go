@@ -0,0 +1,223 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+)
+
+type fakeClusterStatus struct {
+	Active       ClusterOperation
+	AcquireError error
+	Acquired     []ClusterOperation
+	Released     int
+	Running      *ClusterOperation
+}
+
+func (cs *fakeClusterStatus) AcquireOperation(op ClusterOperation) (ClusterOperation, error) {
+	if cs.AcquireError != nil {
+		return cs.Active, cs.AcquireError
+	}
+	cs.Acquired = append(cs.Acquired, op)
+	return op, nil
+}
+
+func (cs *fakeClusterStatus) ReleaseOperation() error {
+	cs.Released++
+	return nil
+}
+
+func (cs *fakeClusterStatus) ActiveOperation() (*ClusterOperation, error) {
+	return cs.Running, nil
+}
+
+func TestZKClusterStatus(t *testing.T) {
+	t.Parallel()
+
+	t.Run("AcquireOperation creates an ephemeral operation node", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		var createdPath string
+		var createdData []byte
+		client.CreateEphemeralCall = func(path string, data []byte, perms []int32) {
+			createdPath = path
+			createdData = data
+		}
+
+		op := newClusterOperation(&UIService{}, OperationUpdate, "2.25.0")
+		_, err := store.AcquireOperation(op)
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(createdPath, "/dcos/ui-service-test/cluster-status/operation")
+		var stored ClusterOperation
+		json.Unmarshal(createdData, &stored)
+		tests.H(t).StringEql(stored.Operation, OperationUpdate)
+		tests.H(t).StringEql(stored.Version, "2.25.0")
+	})
+
+	t.Run("AcquireOperation returns the active operation if the cluster is busy", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.CreateError = zookeeper.ErrNodeExists
+		client.GetResult, _ = json.Marshal(ClusterOperation{
+			Operation: OperationReset,
+			Master:    "master-2",
+			Started:   time.Now(),
+		})
+
+		active, err := store.AcquireOperation(newClusterOperation(&UIService{}, OperationUpdate, "2.25.0"))
+
+		tests.H(t).ErrEql(err, ErrClusterBusy)
+		tests.H(t).StringEql(active.Operation, OperationReset)
+		tests.H(t).StringEql(active.Master, "master-2")
+	})
+
+	t.Run("AcquireOperation takes over an operation exceeding the operation timeout", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		store.operationTimeout = time.Hour
+		client.CreateError = zookeeper.ErrNodeExists
+		client.GetResult, _ = json.Marshal(ClusterOperation{ID: "stuck", Master: "master-2", Started: time.Now().Add(-2 * time.Hour)})
+		client.DeleteCall = func(string) {
+			client.CreateError = nil
+		}
+
+		op := newClusterOperation(&UIService{}, OperationUpdate, "2.25.0")
+		acquired, err := store.AcquireOperation(op)
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(acquired.ID, op.ID)
+	})
+
+	t.Run("AcquireOperation does not take over operations without operation timeout", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.CreateError = zookeeper.ErrNodeExists
+		client.GetResult, _ = json.Marshal(ClusterOperation{ID: "slow", Master: "master-2", Started: time.Now().Add(-48 * time.Hour)})
+
+		_, err := store.AcquireOperation(newClusterOperation(&UIService{}, OperationUpdate, "2.25.0"))
+
+		tests.H(t).ErrEql(err, ErrClusterBusy)
+	})
+
+	t.Run("ReleaseOperation keeps an operation taken over by another master", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		store.setAcquiredOperation("timed-out")
+		client.GetResult, _ = json.Marshal(ClusterOperation{ID: "taken-over", Master: "master-2"})
+		deleted := false
+		client.DeleteCall = func(string) {
+			deleted = true
+		}
+
+		tests.H(t).IsNil(store.ReleaseOperation())
+		tests.H(t).BoolEql(deleted, false)
+	})
+
+	t.Run("AcquireOperation returns ErrZookeeperNotConnected if disconnected", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.ClientStateResult = zookeeper.Disconnected
+
+		_, err := store.AcquireOperation(newClusterOperation(&UIService{}, OperationUpdate, "2.25.0"))
+
+		tests.H(t).ErrEql(err, ErrZookeeperNotConnected)
+	})
+
+	t.Run("ReleaseOperation deletes the operation node", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		var deletedPath string
+		client.DeleteCall = func(path string) {
+			deletedPath = path
+		}
+
+		err := store.ReleaseOperation()
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(deletedPath, "/dcos/ui-service-test/cluster-status/operation")
+	})
+}
+
+func TestClusterMutualExclusion(t *testing.T) {
+	busy := func() *fakeClusterStatus {
+		return &fakeClusterStatus{
+			AcquireError: ErrClusterBusy,
+			Active: ClusterOperation{
+				Operation: OperationReset,
+				Master:    "master-2",
+			},
+		}
+	}
+
+	var testCases = []struct {
+		name   string
+		method string
+		uri    string
+	}{
+		{"update", "POST", "/api/v1/update/2.24.5/"},
+		{"reset", "DELETE", "/api/v1/reset/"},
+		{"remove version", "DELETE", "/api/v1/versions/2.24.3/"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name+" returns 423 if the cluster is busy", func(t *testing.T) {
+			defer tearDown(t)
+			service := setupUIServiceWithVersion()
+			umDouble := UpdateManagerDouble()
+			umDouble.UpdateCall = func(string) {
+				t.Error("update must not start while the cluster is busy")
+			}
+			umDouble.RemoveAllCall = func() error {
+				t.Error("reset must not start while the cluster is busy")
+				return nil
+			}
+			umDouble.ResetCall = func() error {
+				t.Error("remove must not start while the cluster is busy")
+				return nil
+			}
+			service.UpdateManager = umDouble
+			service.ClusterStatus = busy()
+
+			rr := httptest.NewRecorder()
+			newRouter(service).ServeHTTP(rr, httptest.NewRequest(tt.method, tt.uri, nil))
+
+			tests.H(t).IntEql(rr.Code, http.StatusLocked)
+			tests.H(t).StringContains(rr.Body.String(), `"operation":"reset"`)
+			tests.H(t).StringContains(rr.Body.String(), `"master":"master-2"`)
+			tests.H(t).StringContains(rr.Body.String(), `"reasonCode":"CLUSTER_LOCKED"`)
+			tests.H(t).BoolEql(rr.Header().Get("Retry-After") != "", true)
+			tests.H(t).BoolEql(service.updating, false)
+		})
+
+		t.Run(tt.name+" returns 503 if the cluster status cannot be checked", func(t *testing.T) {
+			defer tearDown(t)
+			service := setupUIServiceWithVersion()
+			service.UpdateManager = UpdateManagerDouble()
+			service.ClusterStatus = &fakeClusterStatus{AcquireError: errors.New("zk unavailable")}
+
+			rr := httptest.NewRecorder()
+			newRouter(service).ServeHTTP(rr, httptest.NewRequest(tt.method, tt.uri, nil))
+
+			tests.H(t).IntEql(rr.Code, http.StatusServiceUnavailable)
+		})
+	}
+
+	t.Run("update acquires and releases the cluster operation", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.UpdateNewVersionPath = service.Config.DefaultDocRoot()
+		service.UpdateManager = um
+		clusterStatus := &fakeClusterStatus{}
+		service.ClusterStatus = clusterStatus
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/update/2.24.5/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).IntEql(len(clusterStatus.Acquired), 1)
+		tests.H(t).StringEql(clusterStatus.Acquired[0].Operation, OperationUpdate)
+		tests.H(t).StringEql(clusterStatus.Acquired[0].Version, "2.24.5")
+		tests.H(t).IntEql(clusterStatus.Released, 1)
+	})
+}
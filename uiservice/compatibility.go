@@ -0,0 +1,177 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dcos/dcos-ui-update-service/dcos"
+	"github.com/dcos/dcos-ui-update-service/semver"
+	"github.com/sirupsen/logrus"
+)
+
+// Reasons a version cannot be updated to
+const (
+	reasonServed     = "the version is already served"
+	reasonPreRelease = "legacy package versions are not compatible"
+	reasonFrozen     = "version syncs are frozen"
+)
+
+// VersionCompatibility tells if the cluster can be updated to a version right now and why not
+type VersionCompatibility struct {
+	Version               string   `json:"version"`
+	Installable           bool     `json:"installable"`
+	MinDcosReleaseVersion string   `json:"minDcosReleaseVersion,omitempty"`
+	Reasons               []string `json:"reasons,omitempty"`
+	// RequirementError is set if the minimum DC/OS release could not be retrieved, the
+	// requirement is not checked then
+	RequirementError string `json:"requirementError,omitempty"`
+}
+
+type compatibilityResponse struct {
+	PackageName string                 `json:"packageName"`
+	Installed   string                 `json:"installed"`
+	DcosVersion string                 `json:"dcosVersion,omitempty"`
+	Frozen      *SyncFreeze            `json:"frozen,omitempty"`
+	Pin         *VersionPin            `json:"pin,omitempty"`
+	Versions    []VersionCompatibility `json:"versions"`
+}
+
+// compatibilityHandler lists the available versions from newest to oldest and whether the cluster can be
+// updated to them right now, combining the package source, the DC/OS release of the cluster, the freeze and the pin
+func compatibilityHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		versions, err := service.UpdateManager.AvailableVersions()
+		if err != nil {
+			logrus.WithError(err).Error("Could not list available versions.")
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		cluster, err := loadClusterCompatibility(service)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		response := compatibilityResponse{
+			PackageName: service.UpdateManager.PackageName(""),
+			Installed:   cluster.installed,
+			DcosVersion: cluster.dcosVersion,
+			Frozen:      cluster.freeze,
+			Pin:         cluster.pin,
+			Versions:    make([]VersionCompatibility, 0, len(versions)),
+		}
+
+		semver.SortDescending(versions)
+		for _, version := range versions {
+			response.Versions = append(response.Versions, cluster.check(service, version))
+		}
+
+		js, err := json.Marshal(response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
+
+// clusterCompatibility is the state of the cluster the compatibility of the versions depends on
+type clusterCompatibility struct {
+	// installed is empty while the pre-bundled ui is served
+	installed   string
+	dcosVersion string
+	freeze      *SyncFreeze
+	pin         *VersionPin
+}
+
+// loadClusterCompatibility reads the state of the cluster, it fails if the freeze or the pin cannot be read
+func loadClusterCompatibility(service *UIService) (clusterCompatibility, error) {
+	var cluster clusterCompatibility
+	var err error
+	if cluster.freeze, err = activeFreeze(service); err != nil {
+		logrus.WithError(err).Error("Failed to check if version syncs are frozen")
+		return cluster, err
+	}
+	if cluster.pin, err = activePin(service); err != nil {
+		logrus.WithError(err).Error("Failed to check if the version is pinned")
+		return cluster, err
+	}
+	if cluster.installed, err = service.UpdateManager.CurrentVersion(); err != nil {
+		logrus.WithError(err).Warn("Could not get the installed version.")
+	}
+	if cluster.dcosVersion, err = dcos.ReleaseVersion(service.Config.DcosVersionFile()); err != nil {
+		logrus.WithError(err).Warn("Could not get the DC/OS release version, DC/OS requirements are not checked.")
+	}
+	return cluster, nil
+}
+
+func (c clusterCompatibility) check(service *UIService, version string) VersionCompatibility {
+	return versionCompatibility(service, version, c.installed, c.dcosVersion, c.freeze, c.pin)
+}
+
+// versionCompatibility collects the reasons the cluster cannot be updated to version. The minimum DC/OS
+// release is only requested for versions not excluded otherwise, it is cached by the update manager.
+func versionCompatibility(service *UIService, version, installed, dcosVersion string, freeze *SyncFreeze, pin *VersionPin) VersionCompatibility {
+	result := VersionCompatibility{Version: version}
+	if version == installed {
+		result.Reasons = append(result.Reasons, reasonServed)
+	}
+	if v, err := semver.Parse(version); err != nil || v.IsPreRelease() {
+		result.Reasons = append(result.Reasons, reasonPreRelease)
+	}
+	if freeze != nil {
+		if freeze.Version == version {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("syncing to the version failed on %s", freeze.Master))
+		} else {
+			result.Reasons = append(result.Reasons, reasonFrozen)
+		}
+	}
+
+	if pin != nil && pin.Version != version {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("the ui is pinned to %s", pin.Version))
+	}
+
+	if len(result.Reasons) == 0 {
+		minVersion, err := service.UpdateManager.MinDcosReleaseVersion(version)
+		if err != nil {
+			result.RequirementError = err.Error()
+		}
+		result.MinDcosReleaseVersion = minVersion
+		if minVersion != "" && dcosVersion != "" {
+			satisfied, err := releaseAtLeast(dcosVersion, minVersion)
+			switch {
+			case err != nil:
+				result.RequirementError = err.Error()
+			case !satisfied:
+				result.Reasons = append(result.Reasons, fmt.Sprintf("requires DC/OS %s or later", minVersion))
+			}
+		}
+	}
+	result.Installable = len(result.Reasons) == 0
+	return result
+}
+
+// releaseAtLeast compares DC/OS release versions like 1.13 or 2.1.0-dev by their numeric components
+func releaseAtLeast(version, min string) (bool, error) {
+	v, err := parseRelease(version)
+	if err != nil {
+		return false, err
+	}
+	m, err := parseRelease(min)
+	if err != nil {
+		return false, err
+	}
+	return v.Compare(m) >= 0, nil
+}
+
+func parseRelease(release string) (semver.Version, error) {
+	numeric := strings.SplitN(strings.SplitN(release, "+", 2)[0], "-", 2)[0]
+	for strings.Count(numeric, ".") < 2 {
+		numeric += ".0"
+	}
+	return semver.Parse(numeric)
+}
@@ -0,0 +1,126 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func setupCompatibilityUIService(um *fakeUpdateManager, dcosVersionFile string) *UIService {
+	service := setupTestUIService()
+	service.Config, _ = config.Parse([]string{
+		"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+		"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+		"--dcos-version-file", dcosVersionFile,
+	})
+	service.UpdateManager = um
+	return service
+}
+
+func getCompatibility(t *testing.T, service *UIService) compatibilityResponse {
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/compatibility/", nil))
+	tests.H(t).IntEql(rr.Code, http.StatusOK)
+	var response compatibilityResponse
+	tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &response))
+	return response
+}
+
+func TestCompatibilityHandler(t *testing.T) {
+	t.Run("lists the versions with the reasons they cannot be installed", func(t *testing.T) {
+		defer tearDown(t)
+		um := UpdateManagerDouble()
+		um.AvailableResult = []string{"1.0.5-2.2.5", "2.24.4", "2.25.0", "3.0.0"}
+		um.MinDcosResult = map[string]string{"2.25.0": "2.0", "3.0.0": "2.2"}
+		service := setupCompatibilityUIService(um, "../fixtures/dcos-version.json")
+
+		response := getCompatibility(t, service)
+
+		tests.H(t).StringEql(response.Installed, "2.24.4")
+		tests.H(t).StringEql(response.DcosVersion, "2.1.0")
+		tests.H(t).InterfaceEql(response.Versions, []VersionCompatibility{
+			{Version: "3.0.0", MinDcosReleaseVersion: "2.2", Reasons: []string{"requires DC/OS 2.2 or later"}},
+			{Version: "2.25.0", Installable: true, MinDcosReleaseVersion: "2.0"},
+			{Version: "2.24.4", Reasons: []string{reasonServed}},
+			{Version: "1.0.5-2.2.5", Reasons: []string{reasonPreRelease}},
+		})
+	})
+
+	t.Run("does not check requirements if the DC/OS release is unknown", func(t *testing.T) {
+		defer tearDown(t)
+		um := UpdateManagerDouble()
+		um.AvailableResult = []string{"3.0.0"}
+		um.MinDcosResult = map[string]string{"3.0.0": "2.2"}
+		service := setupCompatibilityUIService(um, "../fixtures/non-existant")
+
+		response := getCompatibility(t, service)
+
+		tests.H(t).StringEql(response.DcosVersion, "")
+		tests.H(t).BoolEql(response.Versions[0].Installable, true)
+	})
+
+	t.Run("reports a failed requirement lookup without excluding the version", func(t *testing.T) {
+		defer tearDown(t)
+		um := UpdateManagerDouble()
+		um.AvailableResult = []string{"3.0.0"}
+		um.MinDcosError = errors.New("cosmos unavailable")
+		service := setupCompatibilityUIService(um, "../fixtures/dcos-version.json")
+
+		response := getCompatibility(t, service)
+
+		tests.H(t).BoolEql(response.Versions[0].Installable, true)
+		tests.H(t).StringEql(response.Versions[0].RequirementError, "cosmos unavailable")
+	})
+
+	t.Run("excludes all versions while syncs are frozen", func(t *testing.T) {
+		defer tearDown(t)
+		um := UpdateManagerDouble()
+		um.AvailableResult = []string{"2.25.0", "3.0.0"}
+		service := setupCompatibilityUIService(um, "../fixtures/dcos-version.json")
+		service.ClusterFreeze = &fakeClusterFreeze{Active: &SyncFreeze{Version: "3.0.0", Master: "master-2"}}
+
+		response := getCompatibility(t, service)
+
+		tests.H(t).StringEql(response.Frozen.Version, "3.0.0")
+		tests.H(t).InterfaceEql(response.Versions[0].Reasons, []string{"syncing to the version failed on master-2"})
+		tests.H(t).InterfaceEql(response.Versions[1].Reasons, []string{reasonFrozen})
+	})
+
+	t.Run("returns 502 if the versions cannot be listed", func(t *testing.T) {
+		defer tearDown(t)
+		um := UpdateManagerDouble()
+		um.AvailableError = errors.New("cosmos unavailable")
+		service := setupCompatibilityUIService(um, "../fixtures/dcos-version.json")
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/compatibility/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusBadGateway)
+	})
+}
+
+func TestReleaseAtLeast(t *testing.T) {
+	var testCases = []struct {
+		version  string
+		min      string
+		expected bool
+	}{
+		{"2.1.0", "2.1", true},
+		{"2.1.0-dev", "2.1", true},
+		{"1.13.9", "2.0", false},
+		{"2.2", "2.1.5", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.version+" >= "+tc.min, func(t *testing.T) {
+			satisfied, err := releaseAtLeast(tc.version, tc.min)
+			tests.H(t).IsNil(err)
+			tests.H(t).BoolEql(satisfied, tc.expected)
+		})
+	}
+}
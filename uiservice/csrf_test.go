@@ -0,0 +1,110 @@
+package uiservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func setupCSRFUIService(args ...string) *UIService {
+	service := setupTestUIService()
+	service.Config, _ = config.Parse(append([]string{
+		"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+		"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+		"--ui-dist-stage-symlink", "../testdata/uiserv-sandbox/new-dcos-ui-dist",
+		"--csrf-header", "X-Requested-With",
+	}, args...))
+	return service
+}
+
+func TestCSRFProtection(t *testing.T) {
+	t.Run("rejects mutating requests without the header", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupCSRFUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/reset/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusForbidden)
+		tests.H(t).StringContains(rr.Body.String(), "X-Requested-With")
+	})
+
+	t.Run("allows mutating requests with the header from the same origin", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupCSRFUIService()
+
+		req := httptest.NewRequest("DELETE", "http://master.mesos/api/v1/reset/", nil)
+		req.Header.Set("X-Requested-With", "XMLHttpRequest")
+		req.Header.Set("Origin", "http://master.mesos")
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+	})
+
+	t.Run("rejects mutating requests from a foreign origin", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupCSRFUIService()
+
+		req := httptest.NewRequest("DELETE", "http://master.mesos/api/v1/reset/", nil)
+		req.Header.Set("X-Requested-With", "XMLHttpRequest")
+		req.Header.Set("Origin", "https://attacker.example")
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusForbidden)
+	})
+
+	t.Run("allows configured origins", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupCSRFUIService("--csrf-allowed-origins", "https://dcos.example/")
+
+		req := httptest.NewRequest("DELETE", "http://master.mesos/api/v1/reset/", nil)
+		req.Header.Set("X-Requested-With", "XMLHttpRequest")
+		req.Header.Set("Origin", "https://dcos.example")
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+	})
+
+	t.Run("does not check reads", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupCSRFUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/operations/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+	})
+
+	t.Run("answers preflights without CORS headers", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupCSRFUIService()
+
+		req := httptest.NewRequest("OPTIONS", "/api/v1/reset/", nil)
+		req.Header.Set("Origin", "https://attacker.example")
+		req.Header.Set("Access-Control-Request-Method", "DELETE")
+		req.Header.Set("Access-Control-Request-Headers", "X-Requested-With")
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).BoolEql(rr.Code >= 400, true)
+		tests.H(t).StringEql(rr.Header().Get("Access-Control-Allow-Origin"), "")
+		tests.H(t).StringEql(rr.Header().Get("Access-Control-Allow-Headers"), "")
+	})
+
+	t.Run("does not check requests without csrf-header", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/reset/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+	})
+}
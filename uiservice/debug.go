@@ -0,0 +1,45 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// debugAssetHandler resolves the bundle URL of a version and reports the artifact host's response
+// to a HEAD request, so operators can check the host without downloading the bundle
+func debugAssetHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version := mux.Vars(r)["version"]
+		probe, err := service.UpdateManager.ProbeAsset(version)
+		switch err {
+		case nil:
+		case updatemanager.ErrRequestedVersionNotFound, updatemanager.ErrUIPackageAssetNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case updatemanager.ErrCosmosRequestFailure, updatemanager.ErrUIPackageAssetBadURI:
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		default:
+			logrus.WithError(err).WithField("version", version).Error("Asset probe failed")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		js, err := json.Marshal(probe)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		if probe.Error != "" {
+			w.WriteHeader(http.StatusBadGateway)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+		w.Write(js)
+	}
+}
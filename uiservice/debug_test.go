@@ -0,0 +1,90 @@
+package uiservice
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+)
+
+func setupDebugUIService(um *fakeUpdateManager) *UIService {
+	service := setupTestUIService()
+	service.Config, _ = config.Parse([]string{
+		"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+		"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+		"--debug-endpoints",
+	})
+	service.UpdateManager = um
+	return service
+}
+
+func TestDebugAssetHandler(t *testing.T) {
+	t.Run("is not registered unless debug endpoints are enabled", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/debug/asset/2.25.0/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusNotFound)
+	})
+
+	var testCases = []struct {
+		name       string
+		probe      *updatemanager.AssetProbe
+		probeError error
+		statusCode int
+		body       string
+	}{
+		{
+			name:       "reports the probe of the artifact host",
+			probe:      &updatemanager.AssetProbe{Version: "2.25.0", URL: "https://downloads.io/ui.tar.gz", StatusCode: 200, Size: 1024},
+			statusCode: http.StatusOK,
+			body:       `"url":"https://downloads.io/ui.tar.gz","statusCode":200,"size":1024`,
+		},
+		{
+			name:       "returns 502 if the artifact host is unreachable",
+			probe:      &updatemanager.AssetProbe{Version: "2.25.0", URL: "https://downloads.io/ui.tar.gz", Size: -1, Error: "connection refused"},
+			statusCode: http.StatusBadGateway,
+			body:       `"error":"connection refused"`,
+		},
+		{
+			name:       "returns 404 for unknown versions",
+			probeError: updatemanager.ErrRequestedVersionNotFound,
+			statusCode: http.StatusNotFound,
+			body:       updatemanager.ErrRequestedVersionNotFound.Error(),
+		},
+		{
+			name:       "returns 502 if cosmos fails",
+			probeError: updatemanager.ErrCosmosRequestFailure,
+			statusCode: http.StatusBadGateway,
+			body:       updatemanager.ErrCosmosRequestFailure.Error(),
+		},
+		{
+			name:       "returns 500 for other errors",
+			probeError: errors.New("unexpected"),
+			statusCode: http.StatusInternalServerError,
+			body:       "unexpected",
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tearDown(t)
+			um := UpdateManagerDouble()
+			um.ProbeResult = tt.probe
+			um.ProbeError = tt.probeError
+			service := setupDebugUIService(um)
+
+			rr := httptest.NewRecorder()
+			newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/debug/asset/2.25.0/", nil))
+
+			tests.H(t).IntEql(rr.Code, tt.statusCode)
+			tests.H(t).StringContains(rr.Body.String(), tt.body)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package uiservice
+
+import (
+	"time"
+)
+
+// Steps of a cluster operation recorded as decisions
+const (
+	DecisionStart        = "start"
+	DecisionActivate     = "activate"
+	DecisionStoreVersion = "store-version"
+	DecisionSoak         = "soak"
+	DecisionPostActivate = "post-activate"
+	DecisionRollback     = "rollback"
+	DecisionPreempted    = "preempted"
+	DecisionFinish       = "finish"
+)
+
+// decisionOK is the outcome of a step that succeeded
+const decisionOK = "ok"
+
+// OperationDecision is a step the master running a cluster operation took, the inputs it was based on
+// and its outcome. The decisions are kept with the operation result and in the audit log, so a failed
+// rollout can be reconstructed step by step.
+type OperationDecision struct {
+	Time    time.Time         `json:"time"`
+	Step    string            `json:"step"`
+	Inputs  map[string]string `json:"inputs,omitempty"`
+	Outcome string            `json:"outcome"`
+}
+
+// decide appends a decision to the result. The steps of an operation run one after another,
+// so the decisions are not synchronized.
+func (result *ClusterOperationResult) decide(step string, inputs map[string]string, outcome string) {
+	result.Decisions = append(result.Decisions, OperationDecision{
+		Time:    time.Now().UTC(),
+		Step:    step,
+		Inputs:  inputs,
+		Outcome: outcome,
+	})
+}
+
+func decisionOutcome(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return decisionOK
+}
+
+// finishOutcome summarizes the state the cluster is left in by the operation
+func finishOutcome(result *ClusterOperationResult) string {
+	if result.ErrorCode == ErrorCodeSuperseded {
+		return "superseded"
+	}
+	if result.ErrorCode == ErrorCodeCancelled {
+		return "cancelled"
+	}
+	if result.Error != "" {
+		return "failed: " + result.ErrorCode
+	}
+	return "succeeded"
+}
@@ -0,0 +1,87 @@
+package uiservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func decisionSteps(decisions []OperationDecision) []string {
+	steps := make([]string, 0, len(decisions))
+	for _, decision := range decisions {
+		steps = append(steps, decision.Step)
+	}
+	return steps
+}
+
+func TestOperationDecisions(t *testing.T) {
+	t.Run("records the steps of an update", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		um := UpdateManagerDouble()
+		um.VersionResult = "2.24.4"
+		um.UpdateNewVersionPath = service.Config.DefaultDocRoot()
+		service.UpdateManager = um
+
+		req := httptest.NewRequest("POST", "/api/v1/update/2.25.0/", nil)
+		req.Header.Set("Accept", "application/json")
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var result ClusterOperationResult
+		tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &result))
+		tests.H(t).InterfaceEql(decisionSteps(result.Decisions), []string{DecisionStart, DecisionActivate, DecisionStoreVersion, DecisionFinish})
+		tests.H(t).StringEql(result.Decisions[0].Inputs["servedVersion"], "2.24.4")
+		tests.H(t).StringEql(result.Decisions[0].Inputs["targetVersion"], "2.25.0")
+		tests.H(t).StringEql(result.Decisions[3].Outcome, "succeeded")
+	})
+
+	t.Run("records the rule of a version conflict with the stored version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		um := UpdateManagerDouble()
+		um.UpdateNewVersionPath = service.Config.DefaultDocRoot()
+		service.UpdateManager = um
+		service.VersionStore.(*fakeVersionStore).Conflict = &VersionConflict{
+			Overwritten: "2.24.9",
+			Version:     "2.25.0",
+			Rule:        VersionConflictRule,
+		}
+
+		newRouter(service).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/update/2.25.0/", nil))
+
+		results := service.operationHistory().Results()
+		tests.H(t).IntEql(len(results), 1)
+		tests.H(t).StringEql(results[0].ErrorCode, "")
+		store := results[0].Decisions[2]
+		tests.H(t).StringEql(store.Step, DecisionStoreVersion)
+		tests.H(t).StringEql(store.Inputs["overwritten"], "2.24.9")
+		tests.H(t).StringEql(store.Inputs["conflictRule"], "latest-write-wins")
+	})
+
+	t.Run("records the failed store and the rollback with the audit entry", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		um := UpdateManagerDouble()
+		um.UpdateNewVersionPath = service.Config.DefaultDocRoot()
+		um.PreviousVersionPath = service.Config.DefaultDocRoot() + "-previous"
+		service.UpdateManager = um
+		service.VersionStore.(*fakeVersionStore).UpdateError = errors.New("zk unavailable")
+
+		newRouter(service).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/update/2.25.0/", nil))
+
+		entries, err := service.auditLog().Entries(context.Background(), 1)
+		tests.H(t).IsNil(err)
+		decisions := entries[0].Decisions
+		tests.H(t).InterfaceEql(decisionSteps(decisions), []string{DecisionStart, DecisionActivate, DecisionStoreVersion, DecisionRollback, DecisionFinish})
+		tests.H(t).StringEql(decisions[2].Outcome, "zk unavailable")
+		tests.H(t).StringEql(decisions[4].Outcome, "failed: "+ErrorCodeVersionStore)
+		tests.H(t).StringContains(decisions[4].Inputs[service.nodeName()], "zk unavailable")
+	})
+}
@@ -0,0 +1,118 @@
+package uiservice
+
+import (
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/hooks"
+	"github.com/dcos/dcos-ui-update-service/scheduler"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultUIInfo is the cached build information of the pre-bundled ui
+type DefaultUIInfo struct {
+	BuildVersion string    `json:"buildVersion"`
+	ModTime      time.Time `json:"modTime"`
+	Size         int64     `json:"size"`
+	CheckedAt    time.Time `json:"checkedAt"`
+}
+
+// defaultUIWatcher polls the index.html of the pre-bundled ui, which can be replaced by a
+// DC/OS component upgrade while the service is running
+type defaultUIWatcher struct {
+	docRoot string
+	hooks   *hooks.Registry
+	info    *DefaultUIInfo
+	sync.Mutex
+}
+
+func newDefaultUIWatcher(docRoot string, registry *hooks.Registry) *defaultUIWatcher {
+	return &defaultUIWatcher{
+		docRoot: docRoot,
+		hooks:   registry,
+	}
+}
+
+// Check refreshes the cached build information if index.html changed since the last check,
+// the default-changed hooks are run for every change after the first check
+func (dw *defaultUIWatcher) Check() (bool, error) {
+	stat, err := os.Stat(path.Join(dw.docRoot, "index.html"))
+	if os.IsNotExist(err) {
+		return false, ErrIndexFileNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+
+	dw.Lock()
+	previous := dw.info
+	if previous != nil && previous.ModTime.Equal(stat.ModTime()) && previous.Size == stat.Size() {
+		previous.CheckedAt = time.Now().UTC()
+		dw.Unlock()
+		return false, nil
+	}
+	dw.Unlock()
+
+	buildVersion, err := buildVersionFromUIIndex(dw.docRoot)
+	if err != nil && err != ErrVersionNotFoundInIndex {
+		return false, err
+	}
+	info := &DefaultUIInfo{
+		BuildVersion: buildVersion,
+		ModTime:      stat.ModTime(),
+		Size:         stat.Size(),
+		CheckedAt:    time.Now().UTC(),
+	}
+	dw.Lock()
+	dw.info = info
+	dw.Unlock()
+
+	if previous == nil {
+		return false, nil
+	}
+	logrus.WithFields(logrus.Fields{
+		"buildVersion":         buildVersion,
+		"previousBuildVersion": previous.BuildVersion,
+	}).Info("Pre-bundled ui changed")
+	dw.hooks.Run(hooks.Event{
+		Point:           hooks.DefaultChanged,
+		Version:         buildVersion,
+		PreviousVersion: previous.BuildVersion,
+		Path:            dw.docRoot,
+	})
+	return true, nil
+}
+
+// Info returns the cached build information, nil before the first successful check
+func (dw *defaultUIWatcher) Info() *DefaultUIInfo {
+	if dw == nil {
+		return nil
+	}
+	dw.Lock()
+	defer dw.Unlock()
+	if dw.info == nil {
+		return nil
+	}
+	info := *dw.info
+	return &info
+}
+
+// defaultUIBuildVersion returns the build version of the pre-bundled ui, checking it for changes first
+func defaultUIBuildVersion(service *UIService) (string, error) {
+	if service.defaultUI == nil {
+		return buildVersionFromUIIndex(service.Config.DefaultDocRoot())
+	}
+	if _, err := service.defaultUI.Check(); err != nil {
+		return "", err
+	}
+	return service.defaultUI.Info().BuildVersion, nil
+}
+
+func defaultUIWatchJob(service *UIService) scheduler.JobFunc {
+	return func() error {
+		_, err := service.defaultUI.Check()
+		return err
+	}
+}
@@ -0,0 +1,89 @@
+package uiservice
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/hooks"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func writeDefaultIndex(t *testing.T, docRoot, version string) {
+	os.MkdirAll(docRoot, 0755)
+	index := "<html><script>window.DCOS_UI_VERSION = \"" + version + "\";</script></html>"
+	if err := ioutil.WriteFile(path.Join(docRoot, "index.html"), []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefaultUIWatcher(t *testing.T) {
+	docRoot := "../testdata/uiserv-sandbox/dcos-ui"
+
+	t.Run("caches the build version on the first check", func(t *testing.T) {
+		defer tearDown(t)
+		writeDefaultIndex(t, docRoot, "2.24.4")
+		watcher := newDefaultUIWatcher(docRoot, nil)
+
+		changed, err := watcher.Check()
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(changed, false)
+		tests.H(t).StringEql(watcher.Info().BuildVersion, "2.24.4")
+	})
+
+	t.Run("runs the default-changed hooks once index.html changed", func(t *testing.T) {
+		defer tearDown(t)
+		writeDefaultIndex(t, docRoot, "2.24.4")
+		registry := hooks.NewRegistry(time.Second)
+		var events []hooks.Event
+		registry.Register(hooks.DefaultChanged, hooks.HookFunc{
+			HookName: "record",
+			Func: func(ctx context.Context, event hooks.Event) error {
+				events = append(events, event)
+				return nil
+			},
+		})
+		watcher := newDefaultUIWatcher(docRoot, registry)
+		watcher.Check()
+
+		changed, _ := watcher.Check()
+		tests.H(t).BoolEql(changed, false)
+
+		writeDefaultIndex(t, docRoot, "2.25.0-upgraded")
+		changed, err := watcher.Check()
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(changed, true)
+		tests.H(t).StringEql(watcher.Info().BuildVersion, "2.25.0-upgraded")
+		tests.H(t).IntEql(len(events), 1)
+		tests.H(t).StringEql(events[0].Version, "2.25.0-upgraded")
+		tests.H(t).StringEql(events[0].PreviousVersion, "2.24.4")
+	})
+
+	t.Run("returns ErrIndexFileNotFound without index.html", func(t *testing.T) {
+		defer tearDown(t)
+		watcher := newDefaultUIWatcher(docRoot, nil)
+
+		_, err := watcher.Check()
+		tests.H(t).ErrEql(err, ErrIndexFileNotFound)
+		tests.H(t).BoolEql(watcher.Info() == nil, true)
+	})
+
+	t.Run("version endpoint reports the refreshed default build version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		writeDefaultIndex(t, docRoot, "2.24.4")
+		service.defaultUI = newDefaultUIWatcher(docRoot, nil)
+		service.defaultUI.Check()
+		um := UpdateManagerDouble()
+		um.VersionResult = ""
+		service.UpdateManager = um
+
+		writeDefaultIndex(t, docRoot, "2.25.0-upgraded")
+		version, err := defaultUIBuildVersion(service)
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(version, "2.25.0-upgraded")
+	})
+}
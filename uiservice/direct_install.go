@@ -0,0 +1,168 @@
+package uiservice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// installRequestLimit is the maximum size of the body of POST /api/v1/update/
+const installRequestLimit = 64 * 1024
+
+// directVersionPrefix starts the names derived for versions installed without a version name
+const directVersionPrefix = "direct-"
+
+// directVersionPattern matches the names of versions installed from a direct URL or a local file, they
+// are used as directory names in versions-root and as znode names
+var directVersionPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]{0,127}$`)
+
+var (
+	// ErrInvalidInstallSource is returned if not exactly one of bundleUrl and path is set
+	ErrInvalidInstallSource = errors.New("Exactly one of bundleUrl and path must be set")
+	// ErrInvalidBundleURL is returned for a bundleUrl that is not an absolute http or https URL
+	ErrInvalidBundleURL = errors.New("bundleUrl must be an absolute http or https URL")
+	// ErrLocalBundlesDisabled is returned for a path if no bundle staging directory is configured
+	ErrLocalBundlesDisabled = errors.New("Local bundles cannot be installed without bundle-staging-dir")
+	// ErrInvalidBundlePath is returned for a path that is not a .tar.gz file in the bundle staging directory
+	ErrInvalidBundlePath = errors.New("path must be a .tar.gz or .tgz file in bundle-staging-dir")
+	// ErrInvalidVersionName is returned for a version name that cannot be used as a directory name
+	ErrInvalidVersionName = errors.New("version must start with a letter or digit and consist of letters, digits, '.', '_', '+' and '-'")
+)
+
+// installRequest is the body of POST /api/v1/update/, exactly one of BundleURL and Path is set
+type installRequest struct {
+	BundleURL string `json:"bundleUrl"`
+	// Path is the absolute path of a bundle file staged in the bundle staging directory
+	Path string `json:"path"`
+	// Version names the installed version, a name derived from the bundle is used if empty
+	Version string `json:"version"`
+}
+
+// parseInstallRequest validates the request and returns the name and the bundle URL of the version to install
+func parseInstallRequest(service *UIService, request installRequest) (string, *url.URL, error) {
+	if (request.BundleURL == "") == (request.Path == "") {
+		return "", nil, ErrInvalidInstallSource
+	}
+	if request.Version != "" && !directVersionPattern.MatchString(request.Version) {
+		return "", nil, ErrInvalidVersionName
+	}
+	var bundleURL *url.URL
+	var digest string
+	if request.BundleURL != "" {
+		parsed, err := url.Parse(request.BundleURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return "", nil, ErrInvalidBundleURL
+		}
+		bundleURL = parsed
+		digest = sha256Hex(strings.NewReader(request.BundleURL))
+	} else {
+		bundlePath, err := stagedBundlePath(service.Config.BundleStagingDir(), request.Path)
+		if err != nil {
+			return "", nil, err
+		}
+		file, err := os.Open(bundlePath)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "unable to read the staged bundle")
+		}
+		defer file.Close()
+		// the content names the version, so a bundle staged again under the same path is not reused
+		digest = sha256Hex(file)
+		bundleURL = &url.URL{Scheme: "file", Path: bundlePath}
+	}
+	version := request.Version
+	if version == "" {
+		version = directVersionPrefix + digest[:12]
+	}
+	return version, bundleURL, nil
+}
+
+// stagedBundlePath resolves bundlePath and checks that it is a bundle file below stagingDir
+func stagedBundlePath(stagingDir, bundlePath string) (string, error) {
+	if stagingDir == "" {
+		return "", ErrLocalBundlesDisabled
+	}
+	if !filepath.IsAbs(bundlePath) || !(strings.HasSuffix(bundlePath, ".tar.gz") || strings.HasSuffix(bundlePath, ".tgz")) {
+		return "", ErrInvalidBundlePath
+	}
+	resolvedDir, err := filepath.EvalSymlinks(stagingDir)
+	if err != nil {
+		return "", ErrInvalidBundlePath
+	}
+	resolved, err := filepath.EvalSymlinks(bundlePath)
+	if err != nil {
+		return "", ErrInvalidBundlePath
+	}
+	if rel, err := filepath.Rel(resolvedDir, resolved); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", ErrInvalidBundlePath
+	}
+	if info, err := os.Stat(resolved); err != nil || !info.Mode().IsRegular() {
+		return "", ErrInvalidBundlePath
+	}
+	return resolved, nil
+}
+
+func sha256Hex(reader io.Reader) string {
+	hash := sha256.New()
+	io.Copy(hash, reader)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// installBundleHandler installs a version from a direct bundle URL or a bundle file staged on the masters,
+// for clusters without a reachable package source. It runs the flow of POST /api/v1/update/{version}/ and
+// stores the bundle URL, so the other masters download the same bundle when they sync.
+func installBundleHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request installRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, installRequestLimit)).Decode(&request); err != nil {
+			http.Error(w, "install request could not be decoded", http.StatusBadRequest)
+			return
+		}
+		version, bundleURL, err := parseInstallRequest(service, request)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger := logrus.WithFields(logrus.Fields{"version": version, "url": bundleURL})
+		logger.Debug("Received install request.")
+
+		if updatingVersion, err := setServiceUpdating(service, version); err != nil {
+			writeLocalBusy(service, w, r, http.StatusConflict, version, updatingVersion,
+				fmt.Sprintf("Service is currently processing an update request to %s", updatingVersion))
+			return
+		}
+		defer resetServiceFromUpdate(service)
+		markPreemptible(service, OperationUpdate)
+		defer cancelOnTimeout(service, r)()
+		op := newClusterOperation(service, OperationUpdate, version)
+		if rejectIfStale(service, w, r) || rejectIfFrozen(service, w) || rejectIfPinned(service, w, version) || !acquireClusterOperation(service, w, op) {
+			return
+		}
+		defer releaseClusterOperation(service)
+		result := newOperationResult(service, op)
+
+		if sources, ok := service.VersionStore.(BundleSources); ok {
+			if err := sources.StoreBundleSource(version, bundleURL.String()); err != nil {
+				logger.WithError(err).Error("Failed to store the bundle source")
+				writeOperationResult(w, r, http.StatusInternalServerError, result.finish(service, err), err.Error())
+				return
+			}
+		}
+		activator := newClusterActivator(service, UIVersion(version), result)
+		err = service.UpdateManager.UpdateFromBundle(version, bundleURL, activator)
+		if err == nil {
+			err = activator.promote()
+		}
+		writeUpdateResult(w, r, result.finish(service, err), err, fmt.Sprintf("Installed %s from %s", version, bundleURL))
+	}
+}
@@ -0,0 +1,185 @@
+package uiservice
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+// bundleSourceStore is a version store keeping bundle sources in memory
+type bundleSourceStore struct {
+	*fakeVersionStore
+	sources map[string]string
+}
+
+func (s *bundleSourceStore) StoreBundleSource(version, bundleURL string) error {
+	s.sources[version] = bundleURL
+	return nil
+}
+
+func (s *bundleSourceStore) BundleSource(version string) (string, bool, error) {
+	source, ok := s.sources[version]
+	return source, ok, nil
+}
+
+func postInstall(service *UIService, body string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/update/", strings.NewReader(body))
+	newRouter(service).ServeHTTP(rr, req)
+	return rr
+}
+
+func setupInstallService() (*UIService, *fakeUpdateManager, *bundleSourceStore) {
+	service := setupTestUIService()
+	um := UpdateManagerDouble()
+	um.UpdateNewVersionPath = service.Config.DefaultDocRoot()
+	service.UpdateManager = um
+	store := &bundleSourceStore{fakeVersionStore: VersionStoreDouble(), sources: map[string]string{}}
+	service.VersionStore = store
+	return service, um, store
+}
+
+func TestInstallBundleHandler(t *testing.T) {
+	t.Run("installs a version from a bundle url", func(t *testing.T) {
+		defer tearDown(t)
+		service, um, store := setupInstallService()
+
+		rr := postInstall(service, `{"bundleUrl":"https://mirror.example.com/acme-ui.tar.gz","version":"acme-1.0.0"}`)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringEql(um.UpdateBundleURL, "https://mirror.example.com/acme-ui.tar.gz")
+		tests.H(t).StringEql(store.sources["acme-1.0.0"], "https://mirror.example.com/acme-ui.tar.gz")
+		tests.H(t).IntEql(len(store.UpdatedVersions), 1)
+		tests.H(t).StringEql(string(store.UpdatedVersions[0]), "acme-1.0.0")
+	})
+
+	t.Run("derives the version name from the bundle url", func(t *testing.T) {
+		defer tearDown(t)
+		service, _, store := setupInstallService()
+
+		rr := postInstall(service, `{"bundleUrl":"https://mirror.example.com/acme-ui.tar.gz"}`)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).IntEql(len(store.UpdatedVersions), 1)
+		version := string(store.UpdatedVersions[0])
+		tests.H(t).BoolEql(strings.HasPrefix(version, directVersionPrefix), true)
+		tests.H(t).IntEql(len(version), len(directVersionPrefix)+12)
+	})
+
+	t.Run("installs a staged bundle file", func(t *testing.T) {
+		defer tearDown(t)
+		service, um, store := setupInstallService()
+		stagingDir, _ := filepath.Abs("../testdata/uiserv-sandbox/staged")
+		os.MkdirAll(stagingDir, 0755)
+		bundlePath := filepath.Join(stagingDir, "acme-ui.tar.gz")
+		ioutil.WriteFile(bundlePath, []byte("bundle"), 0644)
+		service.Config, _ = config.Parse([]string{
+			"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+			"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+			"--ui-dist-stage-symlink", "../testdata/uiserv-sandbox/new-dcos-ui-dist",
+			"--master-count-file", "../fixtures/single-master",
+			"--bundle-staging-dir", stagingDir,
+		})
+
+		rr := postInstall(service, `{"path":"`+bundlePath+`"}`)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringEql(um.UpdateBundleURL, "file://"+bundlePath)
+		// sha256 of "bundle"
+		tests.H(t).StringEql(store.sources["direct-1e6ed65d77d6"], "file://"+bundlePath)
+	})
+
+	t.Run("rejects a staged bundle without a staging directory", func(t *testing.T) {
+		defer tearDown(t)
+		service, _, _ := setupInstallService()
+
+		rr := postInstall(service, `{"path":"/etc/passwd.tar.gz"}`)
+
+		tests.H(t).IntEql(rr.Code, http.StatusBadRequest)
+		tests.H(t).StringContains(rr.Body.String(), ErrLocalBundlesDisabled.Error())
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		defer tearDown(t)
+		service, um, _ := setupInstallService()
+
+		for _, body := range []string{
+			`{}`,
+			`{"bundleUrl":"https://mirror.example.com/a.tar.gz","path":"/tmp/a.tar.gz"}`,
+			`{"bundleUrl":"ftp://mirror.example.com/a.tar.gz"}`,
+			`{"bundleUrl":"https://mirror.example.com/a.tar.gz","version":"../etc"}`,
+			`not json`,
+		} {
+			rr := postInstall(service, body)
+			tests.H(t).IntEql(rr.Code, http.StatusBadRequest)
+		}
+		tests.H(t).StringEql(um.UpdateBundleURL, "")
+	})
+}
+
+func TestStagedBundlePath(t *testing.T) {
+	defer tearDown(t)
+	stagingDir, _ := filepath.Abs("../testdata/uiserv-sandbox/staged")
+	os.MkdirAll(stagingDir, 0755)
+	ioutil.WriteFile(filepath.Join(stagingDir, "acme-ui.tar.gz"), []byte("bundle"), 0644)
+	outside, _ := filepath.Abs("../testdata/uiserv-sandbox/outside.tar.gz")
+	ioutil.WriteFile(outside, []byte("bundle"), 0644)
+	os.Symlink(outside, filepath.Join(stagingDir, "link.tar.gz"))
+
+	_, err := stagedBundlePath(stagingDir, filepath.Join(stagingDir, "acme-ui.tar.gz"))
+	tests.H(t).IsNil(err)
+	_, err = stagedBundlePath(stagingDir, outside)
+	tests.H(t).ErrEql(err, ErrInvalidBundlePath)
+	_, err = stagedBundlePath(stagingDir, filepath.Join(stagingDir, "..", "outside.tar.gz"))
+	tests.H(t).ErrEql(err, ErrInvalidBundlePath)
+	_, err = stagedBundlePath(stagingDir, filepath.Join(stagingDir, "link.tar.gz"))
+	tests.H(t).ErrEql(err, ErrInvalidBundlePath)
+	_, err = stagedBundlePath(stagingDir, filepath.Join(stagingDir, "missing.tar.gz"))
+	tests.H(t).ErrEql(err, ErrInvalidBundlePath)
+}
+
+func TestUpdateToVersionFromBundleSource(t *testing.T) {
+	defer tearDown(t)
+	service, um, store := setupInstallService()
+	store.sources["acme-1.0.0"] = "https://mirror.example.com/acme-ui.tar.gz"
+
+	tests.H(t).IsNil(updateToVersion(service, "acme-1.0.0", newLocalActivator(service)))
+	tests.H(t).StringEql(um.UpdateBundleURL, "https://mirror.example.com/acme-ui.tar.gz")
+
+	um.UpdateBundleURL = ""
+	tests.H(t).IsNil(updateToVersion(service, "2.25.0", newLocalActivator(service)))
+	tests.H(t).StringEql(um.UpdateBundleURL, "")
+}
+
+func TestZKBundleSources(t *testing.T) {
+	store, client := makeZKStore("2.24.4")
+	var created []string
+	client.CreateCall = func(path string, data []byte, perms []int32) {
+		created = append(created, path+"="+string(data))
+	}
+
+	tests.H(t).IsNil(store.StoreBundleSource("acme-1.0.0", "https://mirror.example.com/acme-ui.tar.gz"))
+	tests.H(t).InterfaceEql(created, []string{
+		"/dcos/ui-service-test/bundles=",
+		"/dcos/ui-service-test/bundles/acme-1.0.0=https://mirror.example.com/acme-ui.tar.gz",
+	})
+
+	client.ExistsResult = true
+	client.GetResult = []byte("https://mirror.example.com/acme-ui.tar.gz")
+	source, found, err := store.BundleSource("acme-1.0.0")
+	tests.H(t).IsNil(err)
+	tests.H(t).BoolEql(found, true)
+	tests.H(t).StringEql(source, "https://mirror.example.com/acme-ui.tar.gz")
+
+	store.client = nil
+	_, _, err = store.BundleSource("acme-1.0.0")
+	tests.H(t).ErrEql(err, ErrZookeeperNotConnected)
+}
@@ -0,0 +1,113 @@
+package uiservice
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// maxSymlinkInitAttempts bounds how often the ui dist symlink is checked again after another
+// process created it concurrently
+const maxSymlinkInitAttempts = 3
+
+// ErrUIDistSymlinkDangling occurs if the ui dist symlink points to a path that does not exist
+var ErrUIDistSymlinkDangling = errors.New("ui dist symlink target does not exist")
+
+// initServingPaths prepares the paths the ui is served from at startup. It is idempotent, so
+// concurrent processes and restarts converge on the same state instead of failing with EEXIST.
+func initServingPaths(cfg *config.Config) {
+	initVersionsRoot(cfg.VersionsRoot())
+	removeStageSymlink(cfg.UIDistStageSymlink())
+	if err := initUIDistSymlink(cfg.UIDistSymlink(), cfg.DefaultDocRoot(), cfg.InitUIDistSymlink()); err != nil {
+		logrus.WithError(err).WithField("UIDistSymlink", cfg.UIDistSymlink()).Error("Failed to initialize UI dist symlink")
+	}
+}
+
+// initUIDistSymlink verifies that symlink points to an existing path. If create is set a missing
+// symlink is created and a dangling one is replaced, both pointing to defaultDocRoot.
+func initUIDistSymlink(symlink, defaultDocRoot string, create bool) error {
+	for attempt := 1; ; attempt++ {
+		target, err := os.Readlink(symlink)
+		switch {
+		case err == nil:
+			if _, statErr := os.Stat(symlink); statErr == nil {
+				logrus.WithFields(logrus.Fields{
+					"UIDistSymlink":        symlink,
+					"UIDistSymlink-Target": target,
+				}).Info("Current UI dist symlink target")
+				return nil
+			}
+			if !create {
+				return errors.Wrap(ErrUIDistSymlinkDangling, target)
+			}
+			logrus.WithField("UIDistSymlink-Target", target).Warn("UI dist symlink target does not exist, pointing it to the pre-bundled ui")
+			return replaceSymlink(symlink, defaultDocRoot)
+		case os.IsNotExist(err):
+			if !create {
+				return errors.Wrap(err, "failed to read UI dist symlink")
+			}
+			err = os.Symlink(defaultDocRoot, symlink)
+			if err == nil {
+				logrus.WithFields(logrus.Fields{
+					"UIDistSymlink":        symlink,
+					"UIDistSymlink-Target": defaultDocRoot,
+				}).Info("Initialized UI dist symlink")
+				return nil
+			}
+			if !os.IsExist(err) || attempt == maxSymlinkInitAttempts {
+				return errors.Wrap(err, "failed to create UI dist symlink")
+			}
+			// another process created the symlink concurrently, verify its target
+		default:
+			// e.g. the path exists but is not a symlink, which is never replaced
+			return errors.Wrap(err, "failed to read UI dist symlink")
+		}
+	}
+}
+
+// replaceSymlink atomically points symlink to target by renaming a temporary symlink over it
+func replaceSymlink(symlink, target string) error {
+	tmp := fmt.Sprintf("%s.init-%d", symlink, os.Getpid())
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return errors.Wrap(err, "failed to create temporary UI dist symlink")
+	}
+	if err := os.Rename(tmp, symlink); err != nil {
+		os.Remove(tmp)
+		return errors.Wrap(err, "failed to replace UI dist symlink")
+	}
+	logrus.WithFields(logrus.Fields{
+		"UIDistSymlink":        symlink,
+		"UIDistSymlink-Target": target,
+	}).Info("Replaced UI dist symlink")
+	return nil
+}
+
+func initVersionsRoot(versionsRoot string) {
+	logger := logrus.WithFields(logrus.Fields{"VersionsRoot": versionsRoot})
+	if _, err := os.Stat(versionsRoot); os.IsNotExist(err) {
+		logger.Warn("VersionsRoot directory does not exist, trying to create it.")
+		// MkdirAll succeeds if the directory was created concurrently
+		if mkdirErr := os.MkdirAll(versionsRoot, 0775); mkdirErr != nil {
+			logger.WithError(mkdirErr).Error("Failed to create VersionsRoot directory.")
+			return
+		}
+	} else {
+		logger.Info("Current VersionsRoot directory.")
+	}
+}
+
+// removeStageSymlink removes a staging symlink left behind by an interrupted symlink swap
+func removeStageSymlink(stage string) {
+	if _, err := os.Lstat(stage); err != nil {
+		return
+	}
+	if err := os.Remove(stage); err != nil && !os.IsNotExist(err) {
+		logrus.WithError(err).WithField("symlink", stage).Warn("Failed to remove dangling stage symlink")
+		return
+	}
+	logrus.WithField("symlink", stage).Info("Removed dangling stage symlink")
+}
@@ -0,0 +1,141 @@
+package uiservice
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestInitUIDistSymlink(t *testing.T) {
+	setup := func(t *testing.T) (dir, symlink, docRoot string) {
+		dir, err := ioutil.TempDir("", "dist-symlink")
+		tests.H(t).IsNil(err)
+		docRoot = path.Join(dir, "dcos-ui")
+		tests.H(t).IsNil(os.Mkdir(docRoot, 0755))
+		return dir, path.Join(dir, "dcos-ui-dist"), docRoot
+	}
+	readlink := func(t *testing.T, symlink string) string {
+		target, err := os.Readlink(symlink)
+		tests.H(t).IsNil(err)
+		return target
+	}
+
+	t.Run("creates a missing symlink", func(t *testing.T) {
+		dir, symlink, docRoot := setup(t)
+		defer os.RemoveAll(dir)
+
+		tests.H(t).IsNil(initUIDistSymlink(symlink, docRoot, true))
+
+		tests.H(t).StringEql(readlink(t, symlink), docRoot)
+	})
+
+	t.Run("keeps a symlink pointing to an existing version", func(t *testing.T) {
+		dir, symlink, docRoot := setup(t)
+		defer os.RemoveAll(dir)
+		version := path.Join(dir, "2.25.1")
+		os.Mkdir(version, 0755)
+		os.Symlink(version, symlink)
+
+		tests.H(t).IsNil(initUIDistSymlink(symlink, docRoot, true))
+
+		tests.H(t).StringEql(readlink(t, symlink), version)
+	})
+
+	t.Run("replaces a dangling symlink", func(t *testing.T) {
+		dir, symlink, docRoot := setup(t)
+		defer os.RemoveAll(dir)
+		os.Symlink(path.Join(dir, "removed-version"), symlink)
+
+		tests.H(t).IsNil(initUIDistSymlink(symlink, docRoot, true))
+
+		tests.H(t).StringEql(readlink(t, symlink), docRoot)
+	})
+
+	t.Run("converges when initialized concurrently", func(t *testing.T) {
+		dir, symlink, docRoot := setup(t)
+		defer os.RemoveAll(dir)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- initUIDistSymlink(symlink, docRoot, true)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			tests.H(t).IsNil(err)
+		}
+		tests.H(t).StringEql(readlink(t, symlink), docRoot)
+	})
+
+	t.Run("returns ErrUIDistSymlinkDangling without init", func(t *testing.T) {
+		dir, symlink, docRoot := setup(t)
+		defer os.RemoveAll(dir)
+		os.Symlink(path.Join(dir, "removed-version"), symlink)
+
+		err := initUIDistSymlink(symlink, docRoot, false)
+
+		tests.H(t).StringContains(err.Error(), ErrUIDistSymlinkDangling.Error())
+	})
+
+	t.Run("does not create a missing symlink without init", func(t *testing.T) {
+		dir, symlink, docRoot := setup(t)
+		defer os.RemoveAll(dir)
+
+		tests.H(t).NotNil(initUIDistSymlink(symlink, docRoot, false))
+
+		_, err := os.Lstat(symlink)
+		tests.H(t).BoolEql(os.IsNotExist(err), true)
+	})
+
+	t.Run("never replaces a directory", func(t *testing.T) {
+		dir, symlink, docRoot := setup(t)
+		defer os.RemoveAll(dir)
+		os.Mkdir(symlink, 0755)
+
+		tests.H(t).NotNil(initUIDistSymlink(symlink, docRoot, true))
+
+		info, _ := os.Lstat(symlink)
+		tests.H(t).BoolEql(info.IsDir(), true)
+	})
+}
+
+func TestInitServingPaths(t *testing.T) {
+	t.Run("creates versions-root and the symlink and removes a stale stage symlink", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "serving-paths")
+		tests.H(t).IsNil(err)
+		defer os.RemoveAll(dir)
+		docRoot := path.Join(dir, "dcos-ui")
+		os.Mkdir(docRoot, 0755)
+		stage := path.Join(dir, "new-dcos-ui-dist")
+		os.Symlink(path.Join(dir, "2.25.1"), stage)
+		cfg, err := config.Parse([]string{
+			"--versions-root", path.Join(dir, "ui-versions"),
+			"--ui-dist-symlink", path.Join(dir, "dcos-ui-dist"),
+			"--ui-dist-stage-symlink", stage,
+			"--default-ui-path", docRoot,
+			"--init-ui-dist-symlink",
+		})
+		tests.H(t).IsNil(err)
+
+		initServingPaths(cfg)
+
+		info, err := os.Stat(cfg.VersionsRoot())
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(info.IsDir(), true)
+		target, _ := os.Readlink(cfg.UIDistSymlink())
+		tests.H(t).StringEql(target, docRoot)
+		_, err = os.Lstat(stage)
+		tests.H(t).BoolEql(os.IsNotExist(err), true)
+	})
+}
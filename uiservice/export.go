@@ -0,0 +1,136 @@
+package uiservice
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/dcos/dcos-ui-update-service/memory"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// exportSizeLimit is the maximum size of the uncompressed files included in a version export
+	exportSizeLimit int64 = 256 << 20
+	// exportMemory is reserved for the gzip and tar writers of an export
+	exportMemory int64 = 1 << 20
+
+	// ErrExportTooLarge occurs if the served ui dist exceeds the export size limit
+	ErrExportTooLarge = errors.New("served ui dist exceeds the export size limit")
+	// ErrServedDistNotFound occurs if the ui dist symlink cannot be resolved
+	ErrServedDistNotFound = errors.New("served ui dist could not be found")
+)
+
+func exportVersionHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version, err := service.UpdateManager.CurrentVersion()
+		if err != nil {
+			logrus.WithError(err).Error("Could not get current version.")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if len(version) == 0 {
+			// the pre-bundled ui has no package version
+			version = "default"
+		}
+
+		distRoot, err := filepath.EvalSymlinks(service.Config.UIDistSymlink())
+		if err != nil {
+			logrus.WithError(err).Error("Could not resolve the ui dist symlink.")
+			http.Error(w, ErrServedDistNotFound.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		size, err := distSize(distRoot)
+		if err != nil {
+			logrus.WithError(err).Error("Could not read the served ui dist.")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if size > exportSizeLimit {
+			http.Error(w, ErrExportTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		reservation, err := memory.DefaultAccountant.Reserve(r.Context(), "export", exportMemory)
+		if err != nil {
+			// the client went away while the export waited for memory
+			return
+		}
+		defer reservation.Release()
+
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"dcos-ui-%s.tar.gz\"", version))
+		w.WriteHeader(http.StatusOK)
+		if err := writeDistArchive(w, distRoot); err != nil {
+			// headers are already sent, the client receives a truncated archive
+			logrus.WithError(err).Error("Failed to stream the served ui dist.")
+		}
+	}
+}
+
+func distSize(root string) (int64, error) {
+	var size int64
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// writeDistArchive writes a gzipped tarball of the directories and regular files below root,
+// entries are stored relative to a top level "dist" directory
+func writeDistArchive(w io.Writer, root string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join("dist", rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
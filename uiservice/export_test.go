@@ -0,0 +1,126 @@
+package uiservice
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/memory"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+// linkDistAbsolute points the ui dist symlink to the absolute target, the setup helpers
+// create links with targets relative to the working directory which cannot be resolved.
+func linkDistAbsolute(t *testing.T, service *UIService, target string) {
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(service.Config.UIDistSymlink())
+	if err := os.Symlink(abs, service.Config.UIDistSymlink()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportVersion(t *testing.T) {
+	t.Run("streams the served dist as tar.gz", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.VersionResult = ""
+		service.UpdateManager = um
+		os.MkdirAll(path.Join(service.Config.DefaultDocRoot(), "assets"), 0755)
+		ioutil.WriteFile(path.Join(service.Config.DefaultDocRoot(), "index.html"), []byte("<html></html>"), 0644)
+		ioutil.WriteFile(path.Join(service.Config.DefaultDocRoot(), "assets", "app.js"), []byte("app()"), 0644)
+		linkDistAbsolute(t, service, service.Config.DefaultDocRoot())
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/version/export/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringEql(rr.Header().Get("Content-Type"), "application/gzip")
+		tests.H(t).StringContains(rr.Header().Get("Content-Disposition"), "dcos-ui-default.tar.gz")
+
+		gz, err := gzip.NewReader(rr.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := map[string]string{}
+		tr := tar.NewReader(gz)
+		for {
+			header, err := tr.Next()
+			if err != nil {
+				break
+			}
+			content, _ := ioutil.ReadAll(tr)
+			files[header.Name] = string(content)
+		}
+		tests.H(t).StringEql(files["dist/index.html"], "<html></html>")
+		tests.H(t).StringEql(files["dist/assets/app.js"], "app()")
+		_, hasAssetsDir := files["dist/assets/"]
+		tests.H(t).BoolEql(hasAssetsDir, true)
+	})
+
+	t.Run("names the archive after the served version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		um := UpdateManagerDouble()
+		um.VersionResult = "2.24.4"
+		service.UpdateManager = um
+		linkDistAbsolute(t, service, path.Join(service.Config.VersionsRoot(), "2.24.4", "dist"))
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/version/export/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringContains(rr.Header().Get("Content-Disposition"), "dcos-ui-2.24.4.tar.gz")
+	})
+
+	t.Run("refuses to export a dist above the size limit", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.VersionResult = ""
+		service.UpdateManager = um
+		ioutil.WriteFile(path.Join(service.Config.DefaultDocRoot(), "index.html"), []byte("<html></html>"), 0644)
+		linkDistAbsolute(t, service, service.Config.DefaultDocRoot())
+
+		defer func(limit int64) { exportSizeLimit = limit }(exportSizeLimit)
+		exportSizeLimit = 4
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/version/export/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusRequestEntityTooLarge)
+	})
+
+	t.Run("waits for the memory budget", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.VersionResult = ""
+		service.UpdateManager = um
+		ioutil.WriteFile(path.Join(service.Config.DefaultDocRoot(), "index.html"), []byte("<html></html>"), 0644)
+		linkDistAbsolute(t, service, service.Config.DefaultDocRoot())
+		memory.DefaultAccountant.SetLimit(exportMemory)
+		defer memory.DefaultAccountant.SetLimit(0)
+		running, _ := memory.DefaultAccountant.Reserve(context.Background(), "download", exportMemory)
+		defer running.Release()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/version/export/", nil).WithContext(ctx))
+
+		tests.H(t).IntEql(rr.Body.Len(), 0)
+		tests.H(t).StringEql(rr.Header().Get("Content-Type"), "")
+	})
+}
@@ -0,0 +1,216 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/health"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var fileStoreLog = logrus.WithFields(logrus.Fields{"package": "FileVersionStore"})
+
+// fileVersionStore keeps the served version in a JSON file, so a single master or a standalone installation
+// runs without zookeeper. Changes of the file by other processes, e.g. an administrator replacing it, are
+// picked up by watching its directory.
+type fileVersionStore struct {
+	path      string
+	state     fileVersionState
+	listeners []*versionDelivery
+	watcher   *fsnotify.Watcher
+	closed    bool
+	sync.Mutex
+}
+
+// fileVersionState is the content of the version store file
+type fileVersionState struct {
+	Version UIVersion `json:"version"`
+	// PreviousVersion is nil until the version changed once, the pre-bundled version is stored as ""
+	PreviousVersion *UIVersion `json:"previousVersion,omitempty"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+// NewFileVersionStore creates a version store keeping the version in the JSON file at path, the file is
+// created with the pre-bundled version if it does not exist
+func NewFileVersionStore(path string) (VersionStore, error) {
+	store := &fileVersionStore{path: filepath.Clean(path)}
+	if err := os.MkdirAll(filepath.Dir(store.path), 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create the version store directory")
+	}
+	state, err := store.read()
+	if os.IsNotExist(err) {
+		state = fileVersionState{Version: PreBundledUIVersion, UpdatedAt: time.Now().UTC()}
+		err = store.write(state)
+	}
+	if err != nil {
+		return nil, err
+	}
+	store.state = state
+
+	// the directory is watched, the file itself is replaced on every write
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to watch the version store file")
+	}
+	if err := watcher.Add(filepath.Dir(store.path)); err != nil {
+		watcher.Close()
+		return nil, errors.Wrap(err, "failed to watch the version store file")
+	}
+	store.watcher = watcher
+	go store.watch()
+	fileStoreLog.WithFields(logrus.Fields{"path": store.path, "version": state.Version}).Info("Using the file version store")
+	return store, nil
+}
+
+// CurrentVersion returns the version last read from or written to the file
+func (fvs *fileVersionStore) CurrentVersion() (UIVersion, error) {
+	fvs.Lock()
+	defer fvs.Unlock()
+	return fvs.state.Version, nil
+}
+
+// UpdateCurrentVersion replaces the file with the new version and notifies the listeners
+func (fvs *fileVersionStore) UpdateCurrentVersion(newVersion UIVersion) error {
+	fvs.Lock()
+	defer fvs.Unlock()
+	state := fvs.state
+	if newVersion != state.Version {
+		previous := state.Version
+		state.PreviousVersion = &previous
+	}
+	state.Version = newVersion
+	state.UpdatedAt = time.Now().UTC()
+	err := fvs.write(state)
+	health.DefaultTracker.Record(health.VersionStore, err)
+	if err != nil {
+		return err
+	}
+	fvs.setState(state)
+	return nil
+}
+
+// WatchForVersionChange registers the listener, it is called with the current version right away
+func (fvs *fileVersionStore) WatchForVersionChange(listener VersionChangeListener) error {
+	fvs.Lock()
+	defer fvs.Unlock()
+	delivery := newVersionDelivery(listener)
+	fvs.listeners = append(fvs.listeners, delivery)
+	delivery.deliver(fvs.state.Version)
+	return nil
+}
+
+// PreviousVersion makes rollbacks available with the file store
+func (fvs *fileVersionStore) PreviousVersion() (UIVersion, bool, error) {
+	fvs.Lock()
+	defer fvs.Unlock()
+	if fvs.state.PreviousVersion == nil {
+		return "", false, nil
+	}
+	return *fvs.state.PreviousVersion, true, nil
+}
+
+// Close stops watching the file
+func (fvs *fileVersionStore) Close() {
+	fvs.Lock()
+	defer fvs.Unlock()
+	if fvs.closed {
+		return
+	}
+	fvs.closed = true
+	fvs.watcher.Close()
+}
+
+// setState stores state and queues its version for the listeners if it changed, the store must be locked
+func (fvs *fileVersionStore) setState(state fileVersionState) {
+	changed := state.Version != fvs.state.Version
+	fvs.state = state
+	if !changed {
+		return
+	}
+	for _, delivery := range fvs.listeners {
+		delivery.deliver(state.Version)
+	}
+}
+
+func (fvs *fileVersionStore) watch() {
+	for {
+		select {
+		case event, ok := <-fvs.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) == fvs.path {
+				fvs.reload()
+			}
+		case err, ok := <-fvs.watcher.Errors:
+			if !ok {
+				return
+			}
+			fileStoreLog.WithError(err).Warn("Error watching the version store file")
+		}
+	}
+}
+
+// reload reads the file after it changed, the last version is kept if it was removed or cannot be parsed
+func (fvs *fileVersionStore) reload() {
+	fvs.Lock()
+	defer fvs.Unlock()
+	state, err := fvs.read()
+	if os.IsNotExist(err) {
+		// a file replaced by a rename is gone until the new file is moved in place
+		return
+	}
+	health.DefaultTracker.Record(health.VersionStore, err)
+	if err != nil {
+		fileStoreLog.WithError(err).WithField("path", fvs.path).Warn("Failed to read the changed version store file, keeping the last version")
+		return
+	}
+	if state.Version != fvs.state.Version {
+		fileStoreLog.WithField("version", state.Version).Info("Version store file changed")
+	}
+	fvs.setState(state)
+}
+
+func (fvs *fileVersionStore) read() (fileVersionState, error) {
+	var state fileVersionState
+	data, err := ioutil.ReadFile(fvs.path)
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, errors.Wrap(err, "failed to parse the version store file")
+	}
+	return state, nil
+}
+
+// write replaces the file atomically, so a crash or another reader never sees a partial file
+func (fvs *fileVersionStore) write(state fileVersionState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(fvs.path), "."+filepath.Base(fvs.path)+".")
+	if err != nil {
+		return errors.Wrap(err, "failed to write the version store file")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fvs.path)
+	}
+	return errors.Wrap(err, "failed to write the version store file")
+}
@@ -0,0 +1,124 @@
+package uiservice
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestFileVersionStore(t *testing.T) {
+	t.Run("creates the file with the pre-bundled version", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "file-version-store")
+		defer os.RemoveAll(dir)
+		file := path.Join(dir, "state", "version.json")
+
+		store, err := NewFileVersionStore(file)
+		tests.H(t).IsNil(err)
+		defer store.(VersionStoreCloser).Close()
+
+		version, _ := store.CurrentVersion()
+		tests.H(t).StringEql(string(version), string(PreBundledUIVersion))
+		_, hasPrevious, _ := store.(VersionHistory).PreviousVersion()
+		tests.H(t).BoolEql(hasPrevious, false)
+		_, err = os.Stat(file)
+		tests.H(t).IsNil(err)
+	})
+
+	t.Run("persists updates and keeps the previous version", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "file-version-store")
+		defer os.RemoveAll(dir)
+		file := path.Join(dir, "version.json")
+		store, _ := NewFileVersionStore(file)
+
+		tests.H(t).IsNil(store.UpdateCurrentVersion("2.25.1"))
+		tests.H(t).IsNil(store.UpdateCurrentVersion("2.25.2"))
+		store.(VersionStoreCloser).Close()
+
+		reopened, err := NewFileVersionStore(file)
+		tests.H(t).IsNil(err)
+		defer reopened.(VersionStoreCloser).Close()
+		version, _ := reopened.CurrentVersion()
+		tests.H(t).StringEql(string(version), "2.25.2")
+		previous, hasPrevious, _ := reopened.(VersionHistory).PreviousVersion()
+		tests.H(t).BoolEql(hasPrevious, true)
+		tests.H(t).StringEql(string(previous), "2.25.1")
+	})
+
+	t.Run("notifies listeners of updates and changes of the file", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "file-version-store")
+		defer os.RemoveAll(dir)
+		file := path.Join(dir, "version.json")
+		store, _ := NewFileVersionStore(file)
+		defer store.(VersionStoreCloser).Close()
+
+		versions := make(chan UIVersion, 10)
+		store.WatchForVersionChange(func(version UIVersion) {
+			versions <- version
+		})
+		expectVersion := func(want string) {
+			select {
+			case version := <-versions:
+				tests.H(t).StringEql(string(version), want)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Expected the listener to be called with %q", want)
+			}
+		}
+		expectVersion("")
+
+		store.UpdateCurrentVersion("2.25.1")
+		expectVersion("2.25.1")
+
+		tmp := path.Join(dir, "replacement.json")
+		ioutil.WriteFile(tmp, []byte(`{"version":"2.25.2"}`), 0644)
+		os.Rename(tmp, file)
+		expectVersion("2.25.2")
+		version, _ := store.CurrentVersion()
+		tests.H(t).StringEql(string(version), "2.25.2")
+	})
+
+	t.Run("keeps the last version if the file cannot be parsed", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "file-version-store")
+		defer os.RemoveAll(dir)
+		file := path.Join(dir, "version.json")
+		store, _ := NewFileVersionStore(file)
+		defer store.(VersionStoreCloser).Close()
+		store.UpdateCurrentVersion("2.25.1")
+
+		ioutil.WriteFile(file, []byte("2.25.2"), 0644)
+		time.Sleep(100 * time.Millisecond)
+
+		version, _ := store.CurrentVersion()
+		tests.H(t).StringEql(string(version), "2.25.1")
+	})
+
+	t.Run("fails for a file that cannot be parsed", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "file-version-store")
+		defer os.RemoveAll(dir)
+		file := path.Join(dir, "version.json")
+		ioutil.WriteFile(file, []byte("2.25.2"), 0644)
+
+		_, err := NewFileVersionStore(file)
+
+		tests.H(t).NotNil(err)
+	})
+}
+
+func TestNewVersionStore(t *testing.T) {
+	t.Run("creates the file version store", func(t *testing.T) {
+		dir, _ := ioutil.TempDir("", "file-version-store")
+		defer os.RemoveAll(dir)
+		cfg, _ := config.Parse([]string{"--version-store", "file", "--version-store-file", path.Join(dir, "version.json")})
+
+		store, err := NewVersionStore(cfg)
+
+		tests.H(t).IsNil(err)
+		defer store.(VersionStoreCloser).Close()
+		_, ok := store.(*fileVersionStore)
+		tests.H(t).BoolEql(ok, true)
+	})
+}
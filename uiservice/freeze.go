@@ -0,0 +1,195 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const clusterFreezeNode = "freeze"
+
+// SyncFreeze describes the failed sync that froze automatic syncs on the cluster
+type SyncFreeze struct {
+	Version  string    `json:"version"`
+	Master   string    `json:"master"`
+	Error    string    `json:"error"`
+	FrozenAt time.Time `json:"frozenAt"`
+}
+
+// ClusterFreeze stops all masters from syncing to a new version after a sync failed on one of them
+type ClusterFreeze interface {
+	// Freeze stores the failed sync, if the cluster is frozen already the first failure is kept
+	Freeze(SyncFreeze) error
+	// ActiveFreeze returns the failed sync freezing the cluster, nil if syncs are not frozen
+	ActiveFreeze() (*SyncFreeze, error)
+	// Unfreeze allows syncs again
+	Unfreeze() error
+}
+
+func makeClusterFreezePath(basePath string) string {
+	return path.Join(basePath, "cluster-status", clusterFreezeNode)
+}
+
+// Freeze creates a persistent node below cluster-status, so the freeze survives restarts of all masters
+func (zks *zkVersionStore) Freeze(freeze SyncFreeze) error {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return ErrZookeeperNotConnected
+	}
+	data, err := json.Marshal(freeze)
+	if err != nil {
+		return err
+	}
+	err = zks.client.Create(makeClusterFreezePath(zks.zkBasePath), data, zookeeper.PermAll)
+	if err != nil && err != zookeeper.ErrNodeExists {
+		return errors.Wrap(err, "Failed to freeze version syncs")
+	}
+	return nil
+}
+
+// ActiveFreeze reads the freeze node created by Freeze
+func (zks *zkVersionStore) ActiveFreeze() (*SyncFreeze, error) {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return nil, ErrZookeeperNotConnected
+	}
+	freezePath := makeClusterFreezePath(zks.zkBasePath)
+	exists, _, err := zks.client.Exists(freezePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to check if version syncs are frozen")
+	}
+	if !exists {
+		return nil, nil
+	}
+	data, _, err := zks.client.Get(freezePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read the version sync freeze")
+	}
+	var freeze SyncFreeze
+	if err := json.Unmarshal(data, &freeze); err != nil {
+		log.WithError(err).Warn("Failed to parse the version sync freeze")
+	}
+	return &freeze, nil
+}
+
+// Unfreeze removes the freeze node created by Freeze
+func (zks *zkVersionStore) Unfreeze() error {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return ErrZookeeperNotConnected
+	}
+	freezePath := makeClusterFreezePath(zks.zkBasePath)
+	exists, _, err := zks.client.Exists(freezePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to check if version syncs are frozen")
+	}
+	if !exists {
+		return nil
+	}
+	if err := zks.client.Delete(freezePath); err != nil {
+		return errors.Wrap(err, "Failed to unfreeze version syncs")
+	}
+	return nil
+}
+
+// activeFreeze returns the freeze blocking automatic syncs, nil if freeze on failure is disabled
+func activeFreeze(service *UIService) (*SyncFreeze, error) {
+	if service.ClusterFreeze == nil {
+		return nil, nil
+	}
+	return service.ClusterFreeze.ActiveFreeze()
+}
+
+// freezeSyncs freezes automatic syncs on all masters after syncing to version failed on this master
+func freezeSyncs(service *UIService, version string, syncErr error) {
+	// a replica follows the freezes of the masters but never freezes them
+	if service.ClusterFreeze == nil || service.Config.Replica() {
+		return
+	}
+	err := service.ClusterFreeze.Freeze(SyncFreeze{
+		Version:  version,
+		Master:   service.nodeName(),
+		Error:    syncErr.Error(),
+		FrozenAt: time.Now().UTC(),
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to freeze version syncs after a failed sync")
+		return
+	}
+	logrus.WithField("version", version).Warn("Froze version syncs on all masters, acknowledge the failure to resume")
+}
+
+// rejectIfFrozen writes the error response and returns true if a new version must not be stored while syncs are frozen
+func rejectIfFrozen(service *UIService, w http.ResponseWriter) bool {
+	freeze, err := activeFreeze(service)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to check if version syncs are frozen")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return true
+	}
+	if freeze == nil {
+		return false
+	}
+	http.Error(
+		w,
+		fmt.Sprintf("Version syncs are frozen since syncing to %s failed on %s, acknowledge the failure first", freeze.Version, freeze.Master),
+		http.StatusConflict,
+	)
+	return true
+}
+
+type acknowledgeFailureResponse struct {
+	Acknowledged *SyncFreeze `json:"acknowledged,omitempty"`
+}
+
+// acknowledgeFailureHandler lifts the freeze and syncs this master to the stored version,
+// the other masters follow with the resync job
+func acknowledgeFailureHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if service.ClusterFreeze == nil {
+			http.Error(w, "freeze-on-failure is not enabled", http.StatusNotImplemented)
+			return
+		}
+		freeze, err := service.ClusterFreeze.ActiveFreeze()
+		if err == nil && freeze != nil {
+			err = service.ClusterFreeze.Unfreeze()
+		}
+		if err != nil {
+			logrus.WithError(err).Error("Failed to acknowledge the failed sync")
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if freeze != nil {
+			logrus.WithField("version", freeze.Version).Info("Failed sync acknowledged, version syncs resumed")
+			go resyncStoredVersion(service)
+		}
+
+		js, err := json.Marshal(acknowledgeFailureResponse{Acknowledged: freeze})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
+
+// resyncStoredVersion syncs to the stored version if a sync was skipped or failed while frozen
+func resyncStoredVersion(service *UIService) error {
+	stored, err := service.VersionStore.CurrentVersion()
+	if err != nil {
+		return err
+	}
+	local, err := service.UpdateManager.CurrentVersion()
+	if err != nil {
+		return err
+	}
+	if string(stored) != local {
+		handleVersionChange(service, string(stored))
+	}
+	return nil
+}
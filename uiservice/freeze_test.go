@@ -0,0 +1,182 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+type fakeClusterFreeze struct {
+	Active      *SyncFreeze
+	ActiveError error
+	Unfrozen    int
+}
+
+func (cf *fakeClusterFreeze) Freeze(freeze SyncFreeze) error {
+	if cf.Active == nil {
+		cf.Active = &freeze
+	}
+	return nil
+}
+
+func (cf *fakeClusterFreeze) ActiveFreeze() (*SyncFreeze, error) {
+	return cf.Active, cf.ActiveError
+}
+
+func (cf *fakeClusterFreeze) Unfreeze() error {
+	cf.Active = nil
+	cf.Unfrozen++
+	return nil
+}
+
+func TestZKClusterFreeze(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Freeze creates a persistent freeze node", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		var createdPath string
+		var createdData []byte
+		client.CreateCall = func(path string, data []byte, perms []int32) {
+			createdPath = path
+			createdData = data
+		}
+
+		err := store.Freeze(SyncFreeze{Version: "2.25.0", Master: "10.0.0.1", Error: "download failed"})
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(createdPath, "/dcos/ui-service-test/cluster-status/freeze")
+		var stored SyncFreeze
+		json.Unmarshal(createdData, &stored)
+		tests.H(t).StringEql(stored.Version, "2.25.0")
+		tests.H(t).StringEql(stored.Master, "10.0.0.1")
+	})
+
+	t.Run("ActiveFreeze returns nil if the freeze node does not exist", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.ExistsResult = false
+
+		freeze, err := store.ActiveFreeze()
+
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(freeze == nil, true)
+	})
+
+	t.Run("ActiveFreeze returns the stored freeze", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.ExistsResult = true
+		client.GetResult = []byte(`{"version":"2.25.0","master":"10.0.0.1","error":"download failed"}`)
+
+		freeze, err := store.ActiveFreeze()
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(freeze.Version, "2.25.0")
+		tests.H(t).StringEql(freeze.Error, "download failed")
+	})
+
+	t.Run("Unfreeze deletes the freeze node", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.ExistsResult = true
+		var deletedPath string
+		client.DeleteCall = func(path string) {
+			deletedPath = path
+		}
+
+		tests.H(t).IsNil(store.Unfreeze())
+		tests.H(t).StringEql(deletedPath, "/dcos/ui-service-test/cluster-status/freeze")
+	})
+}
+
+func TestFreezeOnFailure(t *testing.T) {
+	t.Run("a failed sync freezes syncs", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		freeze := &fakeClusterFreeze{}
+		service.ClusterFreeze = freeze
+		um := UpdateManagerDouble()
+		um.UpdateError = errors.New("download failed")
+		service.UpdateManager = um
+
+		handleVersionChange(service, "2.24.5")
+
+		tests.H(t).BoolEql(freeze.Active != nil, true)
+		tests.H(t).StringEql(freeze.Active.Version, "2.24.5")
+		tests.H(t).StringEql(freeze.Active.Error, "download failed")
+	})
+
+	t.Run("syncs are skipped while frozen", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		service.ClusterFreeze = &fakeClusterFreeze{Active: &SyncFreeze{Version: "2.24.5"}}
+		updateCalled := false
+		um := UpdateManagerDouble()
+		um.UpdateCall = func(string) {
+			updateCalled = true
+		}
+		service.UpdateManager = um
+
+		handleVersionChange(service, "2.24.5")
+
+		tests.H(t).BoolEql(updateCalled, false)
+	})
+
+	t.Run("updates via the API are rejected while frozen", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.ClusterFreeze = &fakeClusterFreeze{Active: &SyncFreeze{Version: "2.24.5", Master: "10.0.0.1"}}
+		service.UpdateManager = UpdateManagerDouble()
+		req, _ := http.NewRequest("POST", "/api/v1/update/2.24.6/", nil)
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+		tests.H(t).StringContains(rr.Body.String(), "syncing to 2.24.5 failed on 10.0.0.1")
+	})
+}
+
+func TestAcknowledgeFailureHandler(t *testing.T) {
+	t.Run("returns 501 if freeze on failure is disabled", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		req, _ := http.NewRequest("POST", "/api/v1/acknowledge-failure/", nil)
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusNotImplemented)
+	})
+
+	t.Run("lifts the freeze and returns the acknowledged failure", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		freeze := &fakeClusterFreeze{Active: &SyncFreeze{Version: "2.24.5", Master: "10.0.0.1"}}
+		service.ClusterFreeze = freeze
+		service.UpdateManager = UpdateManagerDouble()
+		req, _ := http.NewRequest("POST", "/api/v1/acknowledge-failure/", nil)
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).IntEql(freeze.Unfrozen, 1)
+		var response acknowledgeFailureResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		tests.H(t).StringEql(response.Acknowledged.Version, "2.24.5")
+	})
+
+	t.Run("returns 503 if the freeze cannot be read", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.ClusterFreeze = &fakeClusterFreeze{ActiveError: ErrZookeeperNotConnected}
+		req, _ := http.NewRequest("POST", "/api/v1/acknowledge-failure/", nil)
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusServiceUnavailable)
+	})
+}
@@ -0,0 +1,57 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/sirupsen/logrus"
+)
+
+// hookRerunResponse reports the result of each post-activate hook, Error is the first failure
+type hookRerunResponse struct {
+	*updatemanager.HookRerun
+	Error string `json:"error,omitempty"`
+}
+
+// rerunHooksHandler runs the post-activate hooks of the served version again, so a failed cache purge or
+// unit reload is repeated without redoing the update. It responds with 500 if a hook failed again.
+func rerunHooksHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version, err := service.UpdateManager.CurrentVersion()
+		if err != nil {
+			logrus.WithError(err).Error("Failed to check the current version")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// the hooks must not see a version that is replaced while they run
+		if updatingVersion, lockErr := setServiceUpdating(service, version); lockErr != nil {
+			writeLocalBusy(service, w, r, http.StatusConflict, version, updatingVersion,
+				fmt.Sprintf("Cannot run the hooks, an operation for %s is in progress", updatingVersion))
+			return
+		}
+		defer resetServiceFromUpdate(service)
+
+		rerun, err := service.UpdateManager.RerunPostActivateHooks(r.Context())
+		if rerun == nil {
+			logrus.WithError(err).Error("Failed to run the post-activate hooks again")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		status := http.StatusOK
+		response := hookRerunResponse{HookRerun: rerun}
+		if err != nil {
+			status = http.StatusInternalServerError
+			response.Error = err.Error()
+		}
+		js, err := json.Marshal(response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(js)
+	}
+}
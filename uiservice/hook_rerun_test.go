@@ -0,0 +1,69 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/hooks"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+)
+
+func TestRerunHooksHandler(t *testing.T) {
+	t.Run("reports the result of each hook", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.HookRerunResult = &updatemanager.HookRerun{
+			Version: "2.24.4",
+			Results: []hooks.Result{{Name: "purge-cache", Duration: "1ms"}},
+		}
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/hooks/rerun/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var response hookRerunResponse
+		tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &response))
+		tests.H(t).StringEql(response.Version, "2.24.4")
+		tests.H(t).StringEql(response.Results[0].Name, "purge-cache")
+		tests.H(t).StringEql(response.Error, "")
+	})
+
+	t.Run("responds with 500 and the results if a hook failed", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.HookRerunResult = &updatemanager.HookRerun{
+			Version: "2.24.4",
+			Results: []hooks.Result{{Name: "purge-cache", Error: "purge failed"}, {Name: "reload"}},
+		}
+		um.HookRerunError = errors.New("post-activate hook purge-cache failed: purge failed")
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/hooks/rerun/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusInternalServerError)
+		var response hookRerunResponse
+		tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &response))
+		tests.H(t).IntEql(len(response.Results), 2)
+		tests.H(t).StringContains(response.Error, "purge-cache")
+	})
+
+	t.Run("rejects the rerun while an update is in progress", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.UpdateManager = UpdateManagerDouble()
+		setServiceUpdating(service, "2.25.0")
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/hooks/rerun/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+	})
+}
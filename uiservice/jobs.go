@@ -0,0 +1,274 @@
+package uiservice
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/downloader"
+	"github.com/dcos/dcos-ui-update-service/hooks"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// maxUpdateJobs is the number of update jobs kept, the oldest job is dropped first
+const maxUpdateJobs = 100
+
+// States of an update job
+const (
+	JobPending     = "pending"
+	JobQueued      = "queued"
+	JobDownloading = updatemanager.PhaseDownloading
+	JobUnpacking   = updatemanager.PhaseUnpacking
+	JobSwapping    = updatemanager.PhaseSwapping
+	JobDone        = "done"
+	JobFailed      = "failed"
+	JobCancelled   = "cancelled"
+	JobSuperseded  = "superseded"
+)
+
+// UpdateJob is an update started with ?async=true
+type UpdateJob struct {
+	ID      string    `json:"id"`
+	Version string    `json:"version"`
+	State   string    `json:"state"`
+	Error   string    `json:"error,omitempty"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	// Progress is set once the download of the bundle started
+	Progress *DownloadProgress `json:"progress,omitempty"`
+	// Result is set once the job is done or failed
+	Result *ClusterOperationResult `json:"result,omitempty"`
+}
+
+// DownloadProgress is the progress of the bundle download of an update job
+type DownloadProgress struct {
+	DownloadedBytes int64 `json:"downloadedBytes"`
+	// TotalBytes is -1 if the size of the bundle is unknown
+	TotalBytes int64 `json:"totalBytes"`
+	// Percent is -1 if the size of the bundle is unknown
+	Percent int `json:"percent"`
+}
+
+// jobStore keeps the last update jobs of this master in memory
+type jobStore struct {
+	jobs  map[string]*UpdateJob
+	order []string
+	limit int
+	sync.Mutex
+}
+
+func newJobStore(limit int) *jobStore {
+	return &jobStore{
+		jobs:  make(map[string]*UpdateJob),
+		limit: limit,
+	}
+}
+
+// updateJobs returns the job store of the service, creating it on first use
+func (service *UIService) updateJobs() *jobStore {
+	service.Lock()
+	defer service.Unlock()
+	if service.jobs == nil {
+		service.jobs = newJobStore(maxUpdateJobs)
+	}
+	return service.jobs
+}
+
+// newID returns a random identifier for update jobs and cluster operations
+func newID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return hex.EncodeToString(id)
+}
+
+// create adds a pending job for version
+func (s *jobStore) create(version string) UpdateJob {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now().UTC()
+	job := &UpdateJob{
+		ID:      newID(),
+		Version: version,
+		State:   JobPending,
+		Created: now,
+		Updated: now,
+	}
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+	if len(s.order) > s.limit {
+		delete(s.jobs, s.order[0])
+		s.order = s.order[1:]
+	}
+	return *job
+}
+
+// setState moves the job to state if jobStateMachine allows it
+func (s *jobStore) setState(id, state string) {
+	s.Lock()
+	defer s.Unlock()
+	job, ok := s.jobs[id]
+	if !ok || !s.allows(job, state) {
+		return
+	}
+	job.State = state
+	job.Updated = time.Now().UTC()
+}
+
+// cancel moves the job to cancelled with the reason as its error
+func (s *jobStore) cancel(id string, reason error) {
+	s.Lock()
+	defer s.Unlock()
+	job, ok := s.jobs[id]
+	if !ok || !s.allows(job, JobCancelled) {
+		return
+	}
+	job.State = JobCancelled
+	job.Error = reason.Error()
+	job.Updated = time.Now().UTC()
+}
+
+// setProgress records the download progress of the job
+func (s *jobStore) setProgress(id string, progress downloader.Progress) {
+	s.Lock()
+	defer s.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return
+	}
+	job.Progress = &DownloadProgress{
+		DownloadedBytes: progress.Downloaded,
+		TotalBytes:      progress.Total,
+		Percent:         progress.Percent(),
+	}
+	job.Updated = time.Now().UTC()
+}
+
+// complete moves the job to done, failed or superseded depending on the result
+func (s *jobStore) complete(id string, result *ClusterOperationResult) {
+	s.Lock()
+	defer s.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return
+	}
+	state := JobDone
+	if result.Error != "" {
+		state = JobFailed
+	}
+	if result.ErrorCode == ErrorCodeSuperseded {
+		state = JobSuperseded
+	}
+	if result.ErrorCode == ErrorCodeCancelled {
+		state = JobCancelled
+	}
+	if !s.allows(job, state) {
+		return
+	}
+	job.State = state
+	job.Error = result.Error
+	job.Updated = time.Now().UTC()
+	job.Result = result
+}
+
+// allows logs and rejects the transitions of the job jobStateMachine does not allow, the lock must be held
+func (s *jobStore) allows(job *UpdateJob, state string) bool {
+	if jobStateMachine.allows(job.State, state) {
+		return true
+	}
+	logrus.WithFields(logrus.Fields{"job": job.ID, "from": job.State, "to": state}).Warn("Ignoring invalid update job transition")
+	return false
+}
+
+func (s *jobStore) get(id string) (UpdateJob, bool) {
+	s.Lock()
+	defer s.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return UpdateJob{}, false
+	}
+	return *job, true
+}
+
+// jobActivator reports the update phases of the wrapped activator to the job
+type jobActivator struct {
+	updatemanager.Activator
+	jobs *jobStore
+	id   string
+}
+
+func (a *jobActivator) ReportProgress(phase string) {
+	a.jobs.setState(a.id, phase)
+}
+
+func (a *jobActivator) ReportDownloadProgress(progress downloader.Progress) {
+	a.jobs.setProgress(a.id, progress)
+}
+
+// ReportHookResults forwards the hook results to the wrapped activator
+func (a *jobActivator) ReportHookResults(point hooks.Point, results []hooks.Result) {
+	if reporter, ok := a.Activator.(updatemanager.HookReporter); ok {
+		reporter.ReportHookResults(point, results)
+	}
+}
+
+// Context forwards the cancellation of the wrapped activator
+func (a *jobActivator) Context() context.Context {
+	if cancellable, ok := a.Activator.(updatemanager.Cancellable); ok {
+		return cancellable.Context()
+	}
+	return context.Background()
+}
+
+// PublishToMirror forwards the decision of the wrapped activator
+func (a *jobActivator) PublishToMirror() bool {
+	publishing, ok := a.Activator.(updatemanager.MirrorPublishing)
+	return ok && publishing.PublishToMirror()
+}
+
+// finish records the outcome of the update
+func (a *jobActivator) finish(result *ClusterOperationResult) {
+	a.jobs.complete(a.id, result)
+}
+
+type updateJobResponse struct {
+	UpdateJob
+	StatusURL string `json:"statusUrl"`
+}
+
+func writeUpdateJob(w http.ResponseWriter, status int, job UpdateJob) {
+	js, err := json.Marshal(updateJobResponse{
+		UpdateJob: job,
+		StatusURL: "/api/v1/update/status/" + job.ID + "/",
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
+
+// updateStatusHandler returns the state of an update job started on this master, the jobs of forwarded
+// updates are looked up on the leader
+func updateStatusHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, ok := service.updateJobs().get(mux.Vars(r)["jobID"])
+		if !ok && forwardToLeader(service, w, r) {
+			return
+		}
+		if !ok {
+			http.Error(w, "Update job not found", http.StatusNotFound)
+			return
+		}
+		writeUpdateJob(w, http.StatusOK, job)
+	}
+}
@@ -0,0 +1,133 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/downloader"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func pollUpdateJob(t *testing.T, service *UIService, statusURL string) updateJobResponse {
+	var job updateJobResponse
+	for i := 0; i < 100; i++ {
+		req, _ := http.NewRequest("GET", statusURL, nil)
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+			t.Fatal(err)
+		}
+		if job.State == JobDone || job.State == JobFailed {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("update job did not finish, last state %v", job.State)
+	return job
+}
+
+func startAsyncUpdate(t *testing.T, service *UIService) updateJobResponse {
+	req, _ := http.NewRequest("POST", "/api/v1/update/2.24.4/?async=true", nil)
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, req)
+	tests.H(t).IntEql(rr.Code, http.StatusAccepted)
+
+	var job updateJobResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestAsyncUpdate(t *testing.T) {
+	t.Run("returns a job that finishes as done", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		newVersionPath := path.Join(service.Config.VersionsRoot(), "2.24.4", "dist")
+		os.MkdirAll(newVersionPath, 0755)
+		um := UpdateManagerDouble()
+		um.UpdateNewVersionPath = newVersionPath
+		service.UpdateManager = um
+
+		job := startAsyncUpdate(t, service)
+		tests.H(t).StringEql(job.State, JobPending)
+		tests.H(t).StringEql(job.Version, "2.24.4")
+		tests.H(t).StringEql(job.StatusURL, "/api/v1/update/status/"+job.ID+"/")
+
+		job = pollUpdateJob(t, service, job.StatusURL)
+		tests.H(t).StringEql(job.State, JobDone)
+		tests.H(t).StringEql(job.Error, "")
+	})
+
+	t.Run("records the error of a failed update", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.UpdateError = errors.New("download failed")
+		service.UpdateManager = um
+
+		job := pollUpdateJob(t, service, startAsyncUpdate(t, service).StatusURL)
+		tests.H(t).StringEql(job.State, JobFailed)
+		tests.H(t).StringEql(job.Error, "download failed")
+		tests.H(t).StringEql(job.Result.ErrorCode, ErrorCodeInternal)
+	})
+
+	t.Run("rejects an invalid async parameter", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		req, _ := http.NewRequest("POST", "/api/v1/update/2.24.4/?async=maybe", nil)
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+		tests.H(t).IntEql(rr.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 404 for an unknown job", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		req, _ := http.NewRequest("GET", "/api/v1/update/status/unknown/", nil)
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+		tests.H(t).IntEql(rr.Code, http.StatusNotFound)
+	})
+}
+
+func TestJobStore(t *testing.T) {
+	t.Run("drops the oldest job once the limit is reached", func(t *testing.T) {
+		store := newJobStore(2)
+		first := store.create("2.24.4")
+		store.create("2.24.5")
+		last := store.create("2.24.6")
+
+		_, ok := store.get(first.ID)
+		tests.H(t).BoolEql(ok, false)
+		_, ok = store.get(last.ID)
+		tests.H(t).BoolEql(ok, true)
+	})
+
+	t.Run("follows the reported phases", func(t *testing.T) {
+		store := newJobStore(2)
+		job := store.create("2.24.4")
+		activator := &jobActivator{jobs: store, id: job.ID}
+
+		activator.ReportProgress(JobUnpacking)
+		job, _ = store.get(job.ID)
+		tests.H(t).StringEql(job.State, JobUnpacking)
+
+		activator.ReportDownloadProgress(downloader.Progress{Downloaded: 512, Total: 2048})
+		job, _ = store.get(job.ID)
+		tests.H(t).InterfaceEql(*job.Progress, DownloadProgress{DownloadedBytes: 512, TotalBytes: 2048, Percent: 25})
+
+		activator.finish(&ClusterOperationResult{Operation: OperationUpdate})
+		job, _ = store.get(job.ID)
+		tests.H(t).StringEql(job.State, JobDone)
+	})
+}
@@ -0,0 +1,101 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// labelPattern matches the labels of local versions like "rc" or "previous-good"
+var labelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,62}$`)
+
+// ErrInvalidLabel is returned for a label not matching labelPattern
+var ErrInvalidLabel = errors.New("label must start with a lowercase letter or digit and consist of at most 63 lowercase letters, digits, '.', '_' and '-'")
+
+// localVersionsHandler lists the versions installed on this master with their labels
+func localVersionsHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		versions, err := service.UpdateManager.LocalVersions()
+		if err != nil {
+			logrus.WithError(err).Error("Failed to list the local versions")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		js, err := json.Marshal(versions)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
+
+// setLabelHandler points a label of this master to an installed version, moving it from its previous version
+func setLabelHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		version, label := vars["version"], vars["label"]
+		if !directVersionPattern.MatchString(version) {
+			http.Error(w, updatemanager.ErrVersionNotInstalled.Error(), http.StatusNotFound)
+			return
+		}
+		if !labelPattern.MatchString(label) {
+			http.Error(w, ErrInvalidLabel.Error(), http.StatusBadRequest)
+			return
+		}
+		writeLabelResult(w, version, label, service.UpdateManager.SetVersionLabel(version, label))
+	}
+}
+
+// removeLabelHandler removes a label of this master from a version
+func removeLabelHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		version, label := vars["version"], vars["label"]
+		if !directVersionPattern.MatchString(version) || !labelPattern.MatchString(label) {
+			http.Error(w, updatemanager.ErrLabelNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		writeLabelResult(w, version, label, service.UpdateManager.RemoveVersionLabel(version, label))
+	}
+}
+
+func writeLabelResult(w http.ResponseWriter, version, label string, err error) {
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case updatemanager.ErrVersionNotInstalled, updatemanager.ErrLabelNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case updatemanager.ErrReadOnlyFilesystem:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		logrus.WithError(err).WithFields(logrus.Fields{"version": version, "label": label}).Error("Failed to change the label")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// activateByLabelHandler updates the cluster to the version a label of this master points to, like
+// POST /api/v1/update/{version}/ does
+func activateByLabelHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		label := mux.Vars(r)["label"]
+		if !labelPattern.MatchString(label) {
+			http.Error(w, updatemanager.ErrLabelNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		version, err := service.UpdateManager.LabeledVersion(label)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logrus.WithFields(logrus.Fields{"label": label, "version": version}).Info("Activating labeled version")
+		updateVersion(service, w, r, version)
+	}
+}
@@ -0,0 +1,109 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+)
+
+func TestLocalVersionsHandler(t *testing.T) {
+	defer tearDown(t)
+	service := setupTestUIService()
+	um := UpdateManagerDouble()
+	um.LocalResult = []updatemanager.LocalVersion{
+		{Version: "2.24.4", Served: true, Labels: []string{"previous-good"}},
+		{Version: "2.25.0", Labels: []string{"rc"}},
+	}
+	service.UpdateManager = um
+
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/versions/local/", nil))
+
+	tests.H(t).IntEql(rr.Code, http.StatusOK)
+	var versions []updatemanager.LocalVersion
+	tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &versions))
+	tests.H(t).InterfaceEql(versions, um.LocalResult)
+}
+
+func TestVersionLabelHandlers(t *testing.T) {
+	label := func(service *UIService, method, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+
+	t.Run("sets and removes a label", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		service.UpdateManager = um
+
+		tests.H(t).IntEql(label(service, "PUT", "/api/v1/versions/local/2.25.0/labels/rc/").Code, http.StatusNoContent)
+		tests.H(t).InterfaceEql(um.Labels, map[string]string{"rc": "2.25.0"})
+
+		tests.H(t).IntEql(label(service, "DELETE", "/api/v1/versions/local/2.25.0/labels/rc/").Code, http.StatusNoContent)
+		tests.H(t).IntEql(len(um.Labels), 0)
+	})
+
+	t.Run("returns 404 for a version which is not installed", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.LabelError = updatemanager.ErrVersionNotInstalled
+		service.UpdateManager = um
+
+		tests.H(t).IntEql(label(service, "PUT", "/api/v1/versions/local/2.25.0/labels/rc/").Code, http.StatusNotFound)
+	})
+
+	t.Run("returns 404 when removing a missing label", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.UpdateManager = UpdateManagerDouble()
+
+		tests.H(t).IntEql(label(service, "DELETE", "/api/v1/versions/local/2.25.0/labels/rc/").Code, http.StatusNotFound)
+	})
+
+	t.Run("rejects invalid labels", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.UpdateManager = UpdateManagerDouble()
+
+		tests.H(t).IntEql(label(service, "PUT", "/api/v1/versions/local/2.25.0/labels/Release_Candidate/").Code, http.StatusBadRequest)
+	})
+}
+
+func TestActivateByLabelHandler(t *testing.T) {
+	t.Run("updates to the labeled version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		newVersionPath := path.Join(service.Config.VersionsRoot(), "2.24.4", "dist")
+		os.MkdirAll(newVersionPath, 0755)
+		um := UpdateManagerDouble()
+		um.UpdateNewVersionPath = newVersionPath
+		um.Labels = map[string]string{"rc": "2.24.4"}
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/activate/by-label/rc/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringContains(rr.Body.String(), "Update to 2.24.4 completed")
+	})
+
+	t.Run("returns 404 for an unknown label", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.UpdateManager = UpdateManagerDouble()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/activate/by-label/rc/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusNotFound)
+	})
+}
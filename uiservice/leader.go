@@ -0,0 +1,168 @@
+package uiservice
+
+import (
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"sync"
+
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	leaderElectionNode = "leader"
+
+	// forwardedByHeader marks a request forwarded to the leader, the leader handles it even if it lost the
+	// leadership meanwhile so requests are never forwarded in a loop. It is only accepted from the masters
+	// of the election.
+	forwardedByHeader = "X-DCOS-UI-Update-Forwarded-By"
+)
+
+// zkLeader is the leader election of this master, created once ZK is connected
+type zkLeader struct {
+	election *zookeeper.Election
+	sync.Mutex
+}
+
+// joinElection joins the leader election with the advertise URL, it is called again after reconnecting so
+// the member node is restored once the session expired
+func (zks *zkVersionStore) joinElection() {
+	if zks.advertiseURL == "" {
+		return
+	}
+	zks.leader.Lock()
+	if zks.leader.election == nil {
+		zks.leader.election = zookeeper.NewElection(zks.client, path.Join(zks.zkBasePath, leaderElectionNode), []byte(zks.advertiseURL))
+	}
+	election := zks.leader.election
+	zks.leader.Unlock()
+	if err := election.Join(); err != nil {
+		log.WithError(err).Warn("Failed to join the leader election")
+	}
+}
+
+func (zks *zkVersionStore) currentElection() (*zookeeper.Election, error) {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return nil, ErrZookeeperNotConnected
+	}
+	zks.leader.Lock()
+	defer zks.leader.Unlock()
+	if zks.leader.election == nil {
+		return nil, zookeeper.ErrNoLeader
+	}
+	return zks.leader.election, nil
+}
+
+// IsLeader is true while this master leads the election
+func (zks *zkVersionStore) IsLeader() (bool, error) {
+	election, err := zks.currentElection()
+	if err != nil {
+		return false, err
+	}
+	return election.IsLeader()
+}
+
+// LeaderData returns the advertise URL of the leading master
+func (zks *zkVersionStore) LeaderData() ([]byte, error) {
+	election, err := zks.currentElection()
+	if err != nil {
+		return nil, err
+	}
+	return election.LeaderData()
+}
+
+// MemberData returns the advertise URLs of the masters in the election
+func (zks *zkVersionStore) MemberData() ([][]byte, error) {
+	election, err := zks.currentElection()
+	if err != nil {
+		return nil, err
+	}
+	return election.MemberData()
+}
+
+// leaderOnly forwards the requests to the leading master, so the cluster operations are coordinated by one
+// master. Without a leader election or if the leader is unknown the request is handled by this master,
+// the cluster-status lock still keeps two operations from running at once.
+func leaderOnly(service *UIService, handler func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !forwardToLeader(service, w, r) {
+			handler(w, r)
+		}
+	}
+}
+
+// forwardToLeader proxies the request to the leader unless this master leads, it returns false if the
+// request must be handled by this master
+func forwardToLeader(service *UIService, w http.ResponseWriter, r *http.Request) bool {
+	if service.Leader == nil {
+		return false
+	}
+	if forwardedBy := r.Header.Get(forwardedByHeader); forwardedBy != "" {
+		if forwardedByMaster(service, r) {
+			return false
+		}
+		logrus.WithFields(logrus.Fields{"forwardedBy": forwardedBy, "remoteAddr": r.RemoteAddr}).Warn("Ignoring the forwarded header of a request not sent by a master")
+		r.Header.Del(forwardedByHeader)
+	}
+	leader, err := service.Leader.IsLeader()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to check the leadership, handling the request on this master")
+		return false
+	}
+	if leader {
+		return false
+	}
+	data, err := service.Leader.LeaderData()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to read the leader, handling the request on this master")
+		return false
+	}
+	target, err := url.Parse(string(data))
+	if err != nil || target.Host == "" {
+		logrus.WithField("leader", string(data)).Warn("Invalid advertise URL of the leader, handling the request on this master")
+		return false
+	}
+
+	logrus.WithFields(logrus.Fields{"leader": target.Host, "path": r.URL.Path}).Info("Forwarding request to the leader")
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		logrus.WithError(err).WithField("leader", target.Host).Error("Failed to forward request to the leader")
+		http.Error(w, "Failed to forward the request to the leader: "+err.Error(), http.StatusBadGateway)
+	}
+	r.Header.Set(forwardedByHeader, service.nodeName())
+	proxy.ServeHTTP(w, r)
+	return true
+}
+
+// forwardedByMaster is true if the request was sent from the host of the advertise URL of a master in the
+// election, other clients cannot skip the forwarding with the forwarded header
+func forwardedByMaster(service *UIService, r *http.Request) bool {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	members, err := service.Leader.MemberData()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to read the masters of the election")
+		return false
+	}
+	for _, member := range members {
+		advertised, err := url.Parse(string(member))
+		if err != nil || advertised.Hostname() == "" {
+			continue
+		}
+		addresses, err := net.LookupHost(advertised.Hostname())
+		if err != nil {
+			continue
+		}
+		for _, address := range addresses {
+			if address == peer {
+				return true
+			}
+		}
+	}
+	return false
+}
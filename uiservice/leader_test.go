@@ -0,0 +1,197 @@
+package uiservice
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+)
+
+type fakeLeader struct {
+	leader    bool
+	data      string
+	members   []string
+	leaderErr error
+}
+
+func (l *fakeLeader) IsLeader() (bool, error) {
+	return l.leader, l.leaderErr
+}
+
+func (l *fakeLeader) LeaderData() ([]byte, error) {
+	return []byte(l.data), nil
+}
+
+func (l *fakeLeader) MemberData() ([][]byte, error) {
+	var data [][]byte
+	for _, member := range l.members {
+		data = append(data, []byte(member))
+	}
+	return data, nil
+}
+
+// setupLeaderServer returns a server standing in for the leading master and the requests it received
+func setupLeaderServer() (*httptest.Server, *[]*http.Request) {
+	received := &[]*http.Request{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*received = append(*received, r)
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("handled by the leader"))
+	}))
+	return server, received
+}
+
+func TestLeaderOnly(t *testing.T) {
+	t.Run("forwards updates to the leader", func(t *testing.T) {
+		defer tearDown(t)
+		server, received := setupLeaderServer()
+		defer server.Close()
+		service := setupTestUIService()
+		service.UpdateManager = UpdateManagerDouble()
+		service.Leader = &fakeLeader{data: server.URL}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/update/2.25.0/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusAccepted)
+		tests.H(t).StringEql(rr.Body.String(), "handled by the leader")
+		tests.H(t).IntEql(len(*received), 1)
+		tests.H(t).StringEql((*received)[0].URL.Path, "/api/v1/update/2.25.0/")
+		tests.H(t).BoolEql((*received)[0].Header.Get(forwardedByHeader) != "", true)
+	})
+
+	t.Run("forwards resets to the leader", func(t *testing.T) {
+		defer tearDown(t)
+		server, received := setupLeaderServer()
+		defer server.Close()
+		service := setupTestUIService()
+		service.Leader = &fakeLeader{data: server.URL}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/reset/", nil))
+
+		tests.H(t).IntEql(len(*received), 1)
+		tests.H(t).StringEql((*received)[0].Method, "DELETE")
+	})
+
+	t.Run("forwards the other cluster operations to the leader", func(t *testing.T) {
+		for _, route := range []struct{ method, path string }{
+			{"POST", "/api/v1/rollback/"},
+			{"DELETE", "/api/v1/versions/2.24.3/"},
+			{"POST", "/api/v1/hooks/rerun/"},
+			{"POST", "/api/v1/pin/2.25.0/"},
+			{"DELETE", "/api/v1/pin/"},
+			{"POST", "/api/v1/acknowledge-failure/"},
+			{"POST", "/api/v1/state/import/"},
+		} {
+			server, received := setupLeaderServer()
+			service := setupTestUIService()
+			service.Leader = &fakeLeader{data: server.URL}
+
+			newRouter(service).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(route.method, route.path, nil))
+
+			tests.H(t).BoolEqlWithMessage(len(*received) == 1, true, route.method+" "+route.path+" should be forwarded")
+			server.Close()
+			tearDown(t)
+		}
+	})
+
+	var localCases = []struct {
+		name      string
+		leader    *fakeLeader
+		forwarded bool
+	}{
+		{name: "handles the request while leading", leader: &fakeLeader{leader: true}},
+		{name: "handles requests forwarded by a master", leader: &fakeLeader{members: []string{"https://192.0.2.1/service/dcos-ui-update-service"}}, forwarded: true},
+		{name: "handles the request if the leadership is unknown", leader: &fakeLeader{leaderErr: errors.New("not connected")}},
+	}
+	for _, tc := range localCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer tearDown(t)
+			server, received := setupLeaderServer()
+			defer server.Close()
+			service := setupTestUIService()
+			service.UpdateManager = UpdateManagerDouble()
+			tc.leader.data = server.URL
+			service.Leader = tc.leader
+
+			req := httptest.NewRequest("POST", "/api/v1/update/2.25.0/", nil)
+			if tc.forwarded {
+				req.Header.Set(forwardedByHeader, "10.0.0.2")
+			}
+			newRouter(service).ServeHTTP(httptest.NewRecorder(), req)
+
+			tests.H(t).IntEql(len(*received), 0)
+		})
+	}
+
+	t.Run("forwards requests with a forwarded header not sent by a master", func(t *testing.T) {
+		defer tearDown(t)
+		server, received := setupLeaderServer()
+		defer server.Close()
+		service := setupTestUIService()
+		service.Leader = &fakeLeader{data: server.URL, members: []string{"https://10.0.0.2/service/dcos-ui-update-service"}}
+
+		req := httptest.NewRequest("POST", "/api/v1/update/2.25.0/", nil)
+		req.Header.Set(forwardedByHeader, "10.0.0.2")
+		newRouter(service).ServeHTTP(httptest.NewRecorder(), req)
+
+		tests.H(t).IntEql(len(*received), 1)
+		tests.H(t).StringEql((*received)[0].Header.Get(forwardedByHeader), service.nodeName())
+	})
+
+	t.Run("reports an unreachable leader", func(t *testing.T) {
+		defer tearDown(t)
+		server, _ := setupLeaderServer()
+		server.Close()
+		service := setupTestUIService()
+		service.Leader = &fakeLeader{data: server.URL}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/update/2.25.0/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusBadGateway)
+	})
+
+	t.Run("looks up unknown update jobs on the leader", func(t *testing.T) {
+		defer tearDown(t)
+		server, received := setupLeaderServer()
+		defer server.Close()
+		service := setupTestUIService()
+		service.Leader = &fakeLeader{data: server.URL}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/update/status/0123456789abcdef/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusAccepted)
+		tests.H(t).IntEql(len(*received), 1)
+	})
+}
+
+func TestZKLeaderElection(t *testing.T) {
+	t.Run("joins the election with the advertise URL", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		store.advertiseURL = "http://10.0.0.1:5000"
+
+		store.joinElection()
+		client.ChildrenResults = []string{"member-0000000000"}
+
+		tests.H(t).InterfaceEql(client.SequentialCreated, []string{"/dcos/ui-service-test/leader/member-0000000000"})
+		leader, err := store.IsLeader()
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(leader, true)
+	})
+
+	t.Run("does not join without advertise URL", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+
+		store.joinElection()
+
+		tests.H(t).IntEql(len(client.SequentialCreated), 0)
+		_, err := store.LeaderData()
+		tests.H(t).ErrEql(err, zookeeper.ErrNoLeader)
+	})
+}
@@ -0,0 +1,114 @@
+package uiservice
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dcos/dcos-ui-update-service/metrics"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	openConnections = metrics.DefaultRegistry.Gauge(
+		"ui_update_connections_open",
+		"Number of open API connections.",
+	)
+	acceptedConnections = metrics.DefaultRegistry.Counter(
+		"ui_update_connections_accepted_total",
+		"Number of accepted API connections.",
+	)
+	limitedConnections = metrics.DefaultRegistry.Counter(
+		"ui_update_connections_limited_total",
+		"Number of API connections that waited for a free slot because max-connections was reached.",
+	)
+)
+
+// errListenerClosed is returned by Accept once the listener was closed while waiting for a free slot
+var errListenerClosed = errors.New("listener closed")
+
+// connLimitListener counts the open connections and, if max is positive, stops accepting
+// connections while max connections are open, so they queue in the socket backlog
+type connLimitListener struct {
+	net.Listener
+	slots     chan struct{}
+	open      int64
+	saturated int32
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newConnLimitListener(l net.Listener, max int) *connLimitListener {
+	listener := &connLimitListener{
+		Listener: l,
+		done:     make(chan struct{}),
+	}
+	if max > 0 {
+		listener.slots = make(chan struct{}, max)
+	}
+	return listener
+}
+
+func (l *connLimitListener) acquire() bool {
+	if l.slots == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+	limitedConnections.Inc()
+	if atomic.CompareAndSwapInt32(&l.saturated, 0, 1) {
+		logrus.WithField("maxConnections", cap(l.slots)).Warn("Connection limit reached, new connections wait for open connections to close")
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *connLimitListener) release() {
+	openConnections.Set(float64(atomic.AddInt64(&l.open, -1)))
+	if l.slots != nil {
+		<-l.slots
+		atomic.StoreInt32(&l.saturated, 0)
+	}
+}
+
+func (l *connLimitListener) Accept() (net.Conn, error) {
+	if !l.acquire() {
+		return nil, errListenerClosed
+	}
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		if l.slots != nil {
+			<-l.slots
+		}
+		return nil, err
+	}
+	acceptedConnections.Inc()
+	openConnections.Set(float64(atomic.AddInt64(&l.open, 1)))
+	return &limitedConn{Conn: conn, release: l.release}, nil
+}
+
+func (l *connLimitListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+// limitedConn frees its slot of the connLimitListener when it is closed
+type limitedConn struct {
+	net.Conn
+	release   func()
+	closeOnce sync.Once
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(c.release)
+	return err
+}
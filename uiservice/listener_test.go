@@ -0,0 +1,77 @@
+package uiservice
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestConnLimitListener(t *testing.T) {
+	t.Run("waits for an open connection to close once the limit is reached", func(t *testing.T) {
+		inner, err := net.Listen("tcp", "127.0.0.1:0")
+		tests.H(t).IsNil(err)
+		l := newConnLimitListener(inner, 1)
+		defer l.Close()
+		limitedBefore := limitedConnections.Value()
+
+		accepted := make(chan net.Conn, 2)
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				accepted <- conn
+			}
+		}()
+
+		first, _ := net.Dial("tcp", inner.Addr().String())
+		defer first.Close()
+		second, _ := net.Dial("tcp", inner.Addr().String())
+		defer second.Close()
+
+		conn := <-accepted
+		select {
+		case <-accepted:
+			t.Fatal("Expected the second connection to wait for a free slot")
+		case <-time.After(50 * time.Millisecond):
+		}
+		tests.H(t).BoolEql(limitedConnections.Value() > limitedBefore, true)
+
+		conn.Close()
+		select {
+		case conn := <-accepted:
+			conn.Close()
+		case <-time.After(time.Second):
+			t.Fatal("Expected the second connection to be accepted after the first was closed")
+		}
+	})
+
+	t.Run("Accept returns once the listener is closed while waiting", func(t *testing.T) {
+		inner, err := net.Listen("tcp", "127.0.0.1:0")
+		tests.H(t).IsNil(err)
+		l := newConnLimitListener(inner, 1)
+
+		client, _ := net.Dial("tcp", inner.Addr().String())
+		defer client.Close()
+		conn, err := l.Accept()
+		tests.H(t).IsNil(err)
+		defer conn.Close()
+
+		done := make(chan error)
+		go func() {
+			_, err := l.Accept()
+			done <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+		l.Close()
+		select {
+		case err := <-done:
+			tests.H(t).ErrEql(err, errListenerClosed)
+		case <-time.After(time.Second):
+			t.Fatal("Expected Accept to return after Close")
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/logring"
+	"github.com/sirupsen/logrus"
+)
+
+type logsResponse struct {
+	Enabled bool             `json:"enabled"`
+	Records []logring.Record `json:"records"`
+}
+
+// logsHandler returns the recent warning and error log records. ?level= restricts the records to the
+// given minimum severity, ?since= accepts an RFC3339 timestamp or a duration like 15m.
+func logsHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		minLevel := logrus.WarnLevel
+		if value := r.URL.Query().Get("level"); value != "" {
+			level, err := logrus.ParseLevel(value)
+			if err != nil {
+				http.Error(w, "level must be a log level like warning or error", http.StatusBadRequest)
+				return
+			}
+			minLevel = level
+		}
+		since, err := parseSinceParam(r.URL.Query().Get("since"))
+		if err != nil {
+			http.Error(w, "since must be an RFC3339 timestamp or a duration", http.StatusBadRequest)
+			return
+		}
+
+		response := logsResponse{
+			Enabled: service.LogRing != nil,
+			Records: service.LogRing.Records(minLevel, since),
+		}
+		js, err := json.Marshal(response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
+
+func parseSinceParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if since, err := time.Parse(time.RFC3339, value); err == nil {
+		return since, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-duration), nil
+}
@@ -0,0 +1,99 @@
+package uiservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/logring"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogsHandler(t *testing.T) {
+	now := time.Now()
+	setupLogRing := func() *logring.Ring {
+		ring := logring.New(10)
+		ring.Fire(&logrus.Entry{Time: now.Add(-time.Hour), Level: logrus.ErrorLevel, Message: "old failure"})
+		ring.Fire(&logrus.Entry{Time: now, Level: logrus.WarnLevel, Message: "slow cosmos"})
+		ring.Fire(&logrus.Entry{Time: now, Level: logrus.ErrorLevel, Message: "download failed"})
+		return ring
+	}
+
+	var testCases = []struct {
+		name       string
+		uri        string
+		statusCode int
+		contains   []string
+		excludes   []string
+	}{
+		{
+			name:       "returns all records",
+			uri:        "/api/v1/logs/",
+			statusCode: http.StatusOK,
+			contains:   []string{`"enabled":true`, "old failure", "slow cosmos", "download failed"},
+		},
+		{
+			name:       "filters by level",
+			uri:        "/api/v1/logs/?level=error",
+			statusCode: http.StatusOK,
+			contains:   []string{"old failure", "download failed"},
+			excludes:   []string{"slow cosmos"},
+		},
+		{
+			name:       "filters by since duration",
+			uri:        "/api/v1/logs/?since=10m",
+			statusCode: http.StatusOK,
+			contains:   []string{"slow cosmos", "download failed"},
+			excludes:   []string{"old failure"},
+		},
+		{
+			name:       "filters by since timestamp",
+			uri:        "/api/v1/logs/?since=" + now.Add(-time.Minute).UTC().Format(time.RFC3339),
+			statusCode: http.StatusOK,
+			contains:   []string{"download failed"},
+			excludes:   []string{"old failure"},
+		},
+		{
+			name:       "rejects unknown levels",
+			uri:        "/api/v1/logs/?level=loud",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "rejects invalid since",
+			uri:        "/api/v1/logs/?since=yesterday",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tearDown(t)
+			service := setupTestUIService()
+			service.LogRing = setupLogRing()
+
+			rr := httptest.NewRecorder()
+			newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", tt.uri, nil))
+
+			tests.H(t).IntEql(rr.Code, tt.statusCode)
+			for _, expected := range tt.contains {
+				tests.H(t).StringContains(rr.Body.String(), expected)
+			}
+			for _, unexpected := range tt.excludes {
+				tests.H(t).BoolEqlWithMessage(strings.Contains(rr.Body.String(), unexpected), false, "Expected response not to contain "+unexpected)
+			}
+		})
+	}
+
+	t.Run("reports a disabled log buffer", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/logs/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringEql(rr.Body.String(), `{"enabled":false,"records":[]}`)
+	})
+}
@@ -0,0 +1,204 @@
+package uiservice
+
+import (
+	"github.com/dcos/dcos-ui-update-service/metrics"
+	"github.com/dcos/dcos-ui-update-service/scheduler"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/sirupsen/logrus"
+)
+
+// Names of the periodic maintenance jobs as reported by the diagnostics endpoint
+const (
+	jobIPRefresh      = "ip-refresh"
+	jobVersionGC      = "version-gc"
+	jobIntegrity      = "integrity-check"
+	jobCosmosProbe    = "cosmos-probe"
+	jobNodeHeartbeat  = "node-heartbeat"
+	jobMetricsRefresh = "metrics-refresh"
+	jobResync         = "resync"
+	jobReconcile      = "reconcile"
+	jobDefaultUIWatch = "default-ui-watch"
+	jobAutoUpdate     = "auto-update"
+)
+
+// maintenanceVersion marks the service as busy while a maintenance job changes versions on disk
+const maintenanceVersion = "maintenance"
+
+var (
+	cosmosReachable = metrics.DefaultRegistry.Gauge(
+		"ui_update_cosmos_reachable",
+		"1 if the last Cosmos reachability probe succeeded, 0 otherwise.",
+	)
+	servingDefault = metrics.DefaultRegistry.Gauge(
+		"ui_update_serving_default",
+		"1 if the pre-bundled ui is served, 0 if a downloaded version is served.",
+	)
+	serviceUpdating = metrics.DefaultRegistry.Gauge(
+		"ui_update_updating",
+		"1 while an update or maintenance job is changing the served version.",
+	)
+)
+
+func registerMaintenanceJobs(service *UIService) {
+	cfg := service.Config
+	s := service.Scheduler
+	if service.IPCache != nil {
+		s.Add(jobIPRefresh, cfg.IPRefreshInterval(), refreshIPJob(service))
+		if _, ok := service.VersionStore.(NodeStatus); ok && !cfg.Replica() {
+			s.Add(jobNodeHeartbeat, cfg.NodeHeartbeatInterval(), nodeHeartbeatJob(service))
+		}
+	}
+	s.Add(jobVersionGC, cfg.GCInterval(), versionGCJob(service))
+	s.Add(jobIntegrity, cfg.VerifyInterval(), integrityCheckJob(service))
+	s.Add(jobCosmosProbe, cfg.CosmosProbeInterval(), cosmosProbeJob(service))
+	s.Add(jobMetricsRefresh, cfg.MetricsRefreshInterval(), metricsRefreshJob(service))
+	s.Add(jobReconcile, cfg.ReconcileInterval(), reconcileJob(service))
+	if service.defaultUI != nil {
+		s.Add(jobDefaultUIWatch, cfg.DefaultUIPollInterval(), defaultUIWatchJob(service))
+	}
+	if cfg.Replica() {
+		// storing versions is left to the masters
+		return
+	}
+	s.Add(jobAutoUpdate, cfg.AutoUpdateCheckInterval(), autoUpdateJob(service))
+	if service.ClusterFreeze != nil {
+		s.Add(jobResync, cfg.ResyncInterval(), resyncJob(service))
+	}
+}
+
+func refreshIPJob(service *UIService) scheduler.JobFunc {
+	return func() error {
+		_, err := service.IPCache.Refresh()
+		return err
+	}
+}
+
+// nodeHeartbeatJob re-creates the node-status registration in case it was lost
+func nodeHeartbeatJob(service *UIService) scheduler.JobFunc {
+	return func() error {
+		ip, err := service.IPCache.IP()
+		if err != nil {
+			return err
+		}
+		return service.VersionStore.(NodeStatus).RegisterNode(ip, ip)
+	}
+}
+
+// versionGCJob removes all versions except the served one and the versions kept for rollbacks and purges
+// expired versions from the trash, it is skipped while an update is in progress
+func versionGCJob(service *UIService) scheduler.JobFunc {
+	return func() error {
+		if _, err := setServiceUpdating(service, maintenanceVersion); err != nil {
+			logrus.Debug("Skipping version garbage collection, update in progress")
+			return nil
+		}
+		defer resetServiceFromUpdate(service)
+
+		version, err := service.UpdateManager.CurrentVersion()
+		if err != nil {
+			return err
+		}
+		if err := service.UpdateManager.PruneVersions(version); err != nil {
+			return err
+		}
+		return service.UpdateManager.PurgeTrash()
+	}
+}
+
+// integrityCheckJob verifies the served version against its manifest. A modified version taints the master
+// in the health and cluster status and, with integrity-auto-restore, is downloaded again.
+func integrityCheckJob(service *UIService) scheduler.JobFunc {
+	return func() error {
+		version, err := service.UpdateManager.CurrentVersion()
+		if err != nil {
+			return err
+		}
+		if version == "" {
+			untaint(service, version)
+			return nil
+		}
+		switch err := service.UpdateManager.VerifyVersion(version); err {
+		case nil, updatemanager.ErrVersionNotImmutable:
+			untaint(service, version)
+			return nil
+		case updatemanager.ErrVersionCorrupted:
+		default:
+			return err
+		}
+
+		restore := service.Config.IntegrityAutoRestore()
+		if service.markTainted(version, restore) {
+			reportNodeState(service, version, nil)
+		}
+		logger := logrus.WithField("version", version)
+		if !restore {
+			logger.Warn("Served version is corrupted, integrity-auto-restore is disabled")
+			return nil
+		}
+
+		if _, err := setServiceUpdating(service, version); err != nil {
+			logrus.Debug("Skipping repair of corrupted version, update in progress")
+			return nil
+		}
+		defer resetServiceFromUpdate(service)
+
+		logger.Warn("Served version is corrupted, downloading it again")
+		if err := updateToVersion(service, version, newLocalActivator(service)); err != nil {
+			return err
+		}
+		untaint(service, version)
+		return nil
+	}
+}
+
+// untaint clears the taint once the served version is unchanged and shares it with the other masters
+func untaint(service *UIService, version string) {
+	if service.clearTaint() {
+		logrus.WithField("version", version).Info("Served version matches its manifest again")
+		reportNodeState(service, version, nil)
+	}
+}
+
+// resyncJob catches up with the stored version after a freeze was acknowledged on another master
+func resyncJob(service *UIService) scheduler.JobFunc {
+	return func() error {
+		if freeze, err := activeFreeze(service); err != nil || freeze != nil {
+			return err
+		}
+		return resyncStoredVersion(service)
+	}
+}
+
+func cosmosProbeJob(service *UIService) scheduler.JobFunc {
+	return func() error {
+		if _, err := service.UpdateManager.AvailableVersions(); err != nil {
+			cosmosReachable.Set(0)
+			return err
+		}
+		cosmosReachable.Set(1)
+		return nil
+	}
+}
+
+func metricsRefreshJob(service *UIService) scheduler.JobFunc {
+	return func() error {
+		service.Lock()
+		updating := service.updating
+		service.Unlock()
+		serviceUpdating.Set(boolToFloat(updating))
+
+		version, err := service.UpdateManager.CurrentVersion()
+		if err != nil {
+			return err
+		}
+		servingDefault.Set(boolToFloat(version == ""))
+		return nil
+	}
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
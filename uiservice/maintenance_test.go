@@ -0,0 +1,158 @@
+package uiservice
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/scheduler"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+)
+
+func TestMaintenanceJobs(t *testing.T) {
+	t.Run("registers the enabled jobs", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.Scheduler = scheduler.New()
+
+		registerMaintenanceJobs(service)
+
+		var names []string
+		for _, status := range service.Scheduler.Status() {
+			names = append(names, status.Name)
+		}
+		tests.H(t).InterfaceEql(names, []string{jobCosmosProbe, jobIntegrity, jobMetricsRefresh, jobReconcile, jobVersionGC})
+	})
+
+	t.Run("version gc prunes all versions except the served one and the kept versions", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.VersionResult = "2.25.3"
+		service.UpdateManager = um
+
+		tests.H(t).ErrEql(versionGCJob(service)(), nil)
+		tests.H(t).InterfaceEql(um.PrunedServed, []string{"2.25.3"})
+		tests.H(t).BoolEql(um.PurgeCalled, true)
+	})
+
+	t.Run("version gc is skipped while updating", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		service.UpdateManager = um
+		setServiceUpdating(service, "2.25.0")
+
+		tests.H(t).ErrEql(versionGCJob(service)(), nil)
+		tests.H(t).IntEql(len(um.PrunedServed), 0)
+	})
+
+	t.Run("integrity check downloads a corrupted version again", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.VerifyError = updatemanager.ErrVersionCorrupted
+		um.UpdateNewVersionPath = service.Config.DefaultDocRoot()
+		var updatedTo string
+		um.UpdateCall = func(version string) {
+			updatedTo = version
+		}
+		service.UpdateManager = um
+
+		tests.H(t).ErrEql(integrityCheckJob(service)(), nil)
+		tests.H(t).StringEql(updatedTo, "2.24.4")
+	})
+
+	t.Run("integrity check only taints the master without integrity-auto-restore", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupAPIAuthUIService(t, "--integrity-auto-restore=false")
+		nodes := &fakeClusterNodes{fakeVersionStore: VersionStoreDouble()}
+		service.VersionStore = nodes
+		um := UpdateManagerDouble()
+		um.VersionResult = "2.24.4"
+		um.VerifyError = updatemanager.ErrVersionCorrupted
+		updated := false
+		um.UpdateCall = func(string) {
+			updated = true
+		}
+		service.UpdateManager = um
+
+		tests.H(t).ErrEql(integrityCheckJob(service)(), nil)
+		tests.H(t).BoolEql(updated, false)
+		tests.H(t).StringEql(service.distTaint().Version, "2.24.4")
+		tests.H(t).BoolEql(nodes.Reported[0].Tainted, true)
+
+		um.VerifyError = nil
+		tests.H(t).ErrEql(integrityCheckJob(service)(), nil)
+		tests.H(t).BoolEql(service.distTaint() == nil, true)
+		tests.H(t).BoolEql(nodes.Reported[1].Tainted, false)
+	})
+
+	t.Run("integrity check ignores versions that are not immutable", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.VerifyError = updatemanager.ErrVersionNotImmutable
+		updated := false
+		um.UpdateCall = func(string) {
+			updated = true
+		}
+		service.UpdateManager = um
+
+		tests.H(t).ErrEql(integrityCheckJob(service)(), nil)
+		tests.H(t).BoolEql(updated, false)
+	})
+
+	t.Run("cosmos probe reports unreachable cosmos", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.AvailableError = updatemanager.ErrCosmosRequestFailure
+		service.UpdateManager = um
+
+		tests.H(t).ErrEql(cosmosProbeJob(service)(), updatemanager.ErrCosmosRequestFailure)
+		tests.H(t).BoolEql(cosmosReachable.Value() == 0, true)
+	})
+
+	t.Run("metrics refresh fails if the current version is unknown", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.VersionError = errors.New("no symlink")
+		service.UpdateManager = um
+
+		tests.H(t).NotNil(metricsRefreshJob(service)())
+	})
+}
+
+func TestDiagnosticsHandlerJobs(t *testing.T) {
+	defer tearDown(t)
+	service := setupTestUIService()
+	service.Scheduler = scheduler.New()
+	service.Scheduler.Add(jobVersionGC, 1, func() error { return nil })
+
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/diagnostics/", nil))
+
+	tests.H(t).IntEql(rr.Code, http.StatusOK)
+	tests.H(t).StringContains(rr.Body.String(), `"name":"version-gc"`)
+}
+
+func TestDiagnosticsHandlerQuarantinedVersions(t *testing.T) {
+	defer tearDown(t)
+	service := setupTestUIService()
+	um := UpdateManagerDouble()
+	um.QuarantinedResult = []updatemanager.QuarantinedVersion{
+		{Version: "2.25.3", Reason: updatemanager.QuarantineMissingManifest},
+	}
+	service.UpdateManager = um
+
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/diagnostics/", nil))
+
+	tests.H(t).IntEql(rr.Code, http.StatusOK)
+	tests.H(t).StringContains(rr.Body.String(), `"version":"2.25.3"`)
+	tests.H(t).StringContains(rr.Body.String(), `"reason":"missing manifest"`)
+}
@@ -0,0 +1,196 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// detectIPTimeout is the maximum time the detect ip script may run
+const detectIPTimeout = 10 * time.Second
+
+// NodeStatus registers this master with the cluster
+type NodeStatus interface {
+	// RegisterNode replaces the registration of the previous IP with the current IP
+	RegisterNode(previous, current net.IP) error
+}
+
+// NodeVersionState is the last version change of a master, stored in its node-status node
+type NodeVersionState struct {
+	Node string `json:"node"`
+	// Version is the version the master last changed to, empty for the pre-bundled ui
+	Version string `json:"version"`
+	// Error is set if the change to Version failed on the master
+	Error string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+	// Reported is false for masters that registered without reporting a version change yet
+	Reported bool `json:"reported"`
+	// Tainted is true if the files served by the master changed after Version was activated
+	Tainted bool `json:"tainted,omitempty"`
+}
+
+// ClusterNodes shares the version changes of the masters with the cluster
+type ClusterNodes interface {
+	// ReportNodeState stores the last version change of this master with its registration
+	ReportNodeState(NodeVersionState) error
+	// NodeStates returns the last version changes of the registered masters
+	NodeStates() ([]NodeVersionState, error)
+}
+
+func makeNodeStatusPath(basePath string, ip net.IP) string {
+	return path.Join(makeNodeStatusDir(basePath), ip.String())
+}
+
+func makeNodeStatusDir(basePath string) string {
+	return path.Join(basePath, "node-status")
+}
+
+// RegisterNode creates an ephemeral node for the current IP below node-status and removes the
+// node of the previous IP. The registration is restored when the ZK session is re-established.
+func (zks *zkVersionStore) RegisterNode(previous, current net.IP) error {
+	zks.nodeIP.Lock()
+	zks.nodeIP.ip = current
+	zks.nodeIP.Unlock()
+
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return ErrZookeeperNotConnected
+	}
+	if previous != nil && !previous.Equal(current) {
+		if err := zks.client.Delete(makeNodeStatusPath(zks.zkBasePath, previous)); err != nil {
+			log.WithError(err).WithField("ip", previous.String()).Warn("Failed to remove node registration of previous IP")
+		}
+	}
+	return zks.registerCurrentNode()
+}
+
+func (zks *zkVersionStore) registerCurrentNode() error {
+	zks.nodeIP.Lock()
+	ip := zks.nodeIP.ip
+	data := zks.nodeIP.state
+	zks.nodeIP.Unlock()
+	if ip == nil {
+		return nil
+	}
+	if data == nil {
+		data = []byte(ip.String())
+	}
+
+	err := zks.client.CreateEphemeral(makeNodeStatusPath(zks.zkBasePath, ip), data, zookeeper.PermAll)
+	if err != nil && err != zookeeper.ErrNodeExists {
+		return errors.Wrap(err, "Failed to register node in ZK")
+	}
+	return nil
+}
+
+// ReportNodeState writes the state to the node-status node of this master, creating it if it is missing.
+// The state is kept, so it is restored when the registration is re-created.
+func (zks *zkVersionStore) ReportNodeState(state NodeVersionState) error {
+	state.Reported = true
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	zks.nodeIP.Lock()
+	zks.nodeIP.state = data
+	ip := zks.nodeIP.ip
+	zks.nodeIP.Unlock()
+
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return ErrZookeeperNotConnected
+	}
+	if ip == nil {
+		return nil
+	}
+	if _, err := zks.client.Set(makeNodeStatusPath(zks.zkBasePath, ip), data); err != nil {
+		return zks.registerCurrentNode()
+	}
+	return nil
+}
+
+// NodeStates reads the node-status nodes of all registered masters. Masters registered by releases not
+// reporting their state are listed as not reported.
+func (zks *zkVersionStore) NodeStates() ([]NodeVersionState, error) {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return nil, ErrZookeeperNotConnected
+	}
+	dir := makeNodeStatusDir(zks.zkBasePath)
+	children, _, err := zks.client.Children(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list the registered nodes")
+	}
+	states := make([]NodeVersionState, 0, len(children))
+	for _, child := range children {
+		data, _, err := zks.client.Get(path.Join(dir, child))
+		if err != nil {
+			// the master lost its session since the nodes were listed
+			log.WithError(err).WithField("node", child).Debug("Skipping unreadable node registration")
+			continue
+		}
+		state := NodeVersionState{Node: child}
+		if jsonErr := json.Unmarshal(data, &state); jsonErr != nil || !state.Reported {
+			state = NodeVersionState{Node: child}
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// reportNodeState shares the outcome of a change of the served version to version with the other masters
+func reportNodeState(service *UIService, version string, err error) {
+	nodes, ok := service.VersionStore.(ClusterNodes)
+	if !ok {
+		return
+	}
+	state := NodeVersionState{Node: service.nodeName(), Version: version, Time: time.Now().UTC()}
+	state.Tainted = service.distTaint() != nil
+	if err != nil {
+		state.Error = err.Error()
+	}
+	if reportErr := nodes.ReportNodeState(state); reportErr != nil {
+		logrus.WithError(reportErr).Warn("Failed to report the node state")
+	}
+}
+
+type nodeResponse struct {
+	IP string `json:"ip"`
+}
+
+// nodeHandler returns the cached IP of this master, ?refresh=true detects it again
+func nodeHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if service.IPCache == nil {
+			http.Error(w, "IP detection is not configured", http.StatusNotImplemented)
+			return
+		}
+		refresh, err := parseBoolParam(r, "refresh")
+		if err != nil {
+			http.Error(w, "refresh must be a boolean", http.StatusBadRequest)
+			return
+		}
+		if refresh {
+			service.IPCache.Invalidate()
+		}
+
+		ip, err := service.IPCache.IP()
+		if err != nil {
+			logrus.WithError(err).Error("Could not detect the node IP.")
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		js, err := json.Marshal(nodeResponse{IP: ip.String()})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
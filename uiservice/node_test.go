@@ -0,0 +1,120 @@
+package uiservice
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/dcos"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+)
+
+func TestNodeHandler(t *testing.T) {
+	t.Run("returns the cached node IP", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		detections := 0
+		service.IPCache = dcos.NewIPCache(func() (net.IP, error) {
+			detections++
+			return net.ParseIP("10.0.0.1"), nil
+		})
+
+		for i := 0; i < 2; i++ {
+			rr := httptest.NewRecorder()
+			newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/node/", nil))
+
+			tests.H(t).IntEql(rr.Code, http.StatusOK)
+			tests.H(t).StringContains(rr.Body.String(), `"ip":"10.0.0.1"`)
+		}
+		tests.H(t).IntEql(detections, 1)
+	})
+
+	t.Run("detects the IP again with refresh", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		detections := 0
+		service.IPCache = dcos.NewIPCache(func() (net.IP, error) {
+			detections++
+			return net.ParseIP("10.0.0.1"), nil
+		})
+		service.IPCache.IP()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/node/?refresh=true", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).IntEql(detections, 2)
+	})
+
+	t.Run("returns 503 if the IP cannot be detected", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.IPCache = dcos.NewIPCache(func() (net.IP, error) {
+			return nil, errors.New("detect_ip failed")
+		})
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/node/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusServiceUnavailable)
+	})
+}
+
+func TestZKNodeStatus(t *testing.T) {
+	t.Run("RegisterNode creates an ephemeral node for the IP", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		var created []string
+		client.CreateEphemeralCall = func(path string, data []byte, perms []int32) {
+			created = append(created, path)
+		}
+
+		err := store.RegisterNode(nil, net.ParseIP("10.0.0.1"))
+
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(len(created), 1)
+		tests.H(t).StringEql(created[0], "/dcos/ui-service-test/node-status/10.0.0.1")
+	})
+
+	t.Run("RegisterNode removes the node of the previous IP", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		var deleted []string
+		client.DeleteCall = func(path string) {
+			deleted = append(deleted, path)
+		}
+
+		err := store.RegisterNode(net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"))
+
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(len(deleted), 1)
+		tests.H(t).StringEql(deleted[0], "/dcos/ui-service-test/node-status/10.0.0.1")
+	})
+
+	t.Run("RegisterNode ignores an existing registration", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.CreateError = zookeeper.ErrNodeExists
+
+		err := store.RegisterNode(nil, net.ParseIP("10.0.0.1"))
+
+		tests.H(t).IsNil(err)
+	})
+
+	t.Run("registration is restored after reconnecting", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.ClientStateResult = zookeeper.Disconnected
+		store.RegisterNode(nil, net.ParseIP("10.0.0.1"))
+
+		registered := make(chan string, 1)
+		client.CreateEphemeralCall = func(path string, data []byte, perms []int32) {
+			registered <- path
+		}
+		client.ClientStateResult = zookeeper.Connected
+		client.ExistsResult = true
+		store.zkClientState = zookeeper.Disconnected
+		store.handleZKStateChange(zookeeper.Connected)
+
+		tests.H(t).StringEql(<-registered, "/dcos/ui-service-test/node-status/10.0.0.1")
+	})
+}
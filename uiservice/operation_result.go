@@ -0,0 +1,246 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/hooks"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// maxOperationHistory is the number of cluster operation results kept, the oldest result is dropped first
+const maxOperationHistory = 50
+
+// Error codes of cluster operation results
+const (
+	ErrorCodeVersionNotFound = "E_VERSION_NOT_FOUND"
+	ErrorCodeReadOnlyFS      = "E_READONLY_FS"
+	ErrorCodeVersionStore    = "E_VERSION_STORE"
+	ErrorCodeInternal        = "E_INTERNAL"
+	ErrorCodeSoakFailed      = "E_SOAK_FAILED"
+	ErrorCodeSuperseded      = "E_SUPERSEDED"
+	ErrorCodeInvalidBundle   = "E_INVALID_BUNDLE"
+	ErrorCodeCancelled       = "E_CANCELLED"
+	ErrorCodeSignature       = "E_SIGNATURE_INVALID"
+)
+
+// NodeResult is the outcome of a cluster operation on a single master
+type NodeResult struct {
+	Node  string `json:"node"`
+	Error string `json:"error,omitempty"`
+}
+
+// ClusterOperationResult is the outcome of an update, reset or remove operation, it is returned by
+// the API for application/json requests and kept in the operation history
+type ClusterOperationResult struct {
+	Operation      string       `json:"operation"`
+	OpID           string       `json:"opId"`
+	Version        string       `json:"version,omitempty"`
+	PackageName    string       `json:"packageName,omitempty"`
+	StartedAt      time.Time    `json:"startedAt"`
+	FinishedAt     time.Time    `json:"finishedAt"`
+	PerNodeResults []NodeResult `json:"perNodeResults"`
+	ErrorCode      string       `json:"errorCode,omitempty"`
+	Error          string       `json:"error,omitempty"`
+	// Decisions are the steps this master took, in order
+	Decisions []OperationDecision `json:"decisions,omitempty"`
+	// PostActivateHooks are the results of the hooks run after serving the version, a failed hook does not
+	// fail the operation
+	PostActivateHooks []hooks.Result `json:"postActivateHooks,omitempty"`
+	// fromVersion is the version served when the operation started, for the audit log
+	fromVersion string
+}
+
+func newOperationResult(service *UIService, op ClusterOperation) *ClusterOperationResult {
+	result := &ClusterOperationResult{
+		Operation:      op.Operation,
+		OpID:           op.ID,
+		Version:        op.Version,
+		StartedAt:      op.Started,
+		PerNodeResults: []NodeResult{},
+	}
+	if changesServedVersion(op.Operation) {
+		if current, err := service.UpdateManager.CurrentVersion(); err == nil {
+			result.fromVersion = current
+		}
+	}
+	result.decide(DecisionStart, map[string]string{
+		"master":        op.Master,
+		"servedVersion": result.fromVersion,
+		"targetVersion": op.Version,
+	}, decisionOK)
+	return result
+}
+
+// finish records the outcome of the operation on this master
+func (result *ClusterOperationResult) finish(service *UIService, err error) *ClusterOperationResult {
+	result.FinishedAt = time.Now().UTC()
+	node := NodeResult{Node: service.nodeName()}
+	if err != nil {
+		node.Error = err.Error()
+		result.Error = err.Error()
+		result.ErrorCode = operationErrorCode(err)
+	}
+	result.PerNodeResults = append(result.PerNodeResults, node)
+	nodeStates := make(map[string]string, len(result.PerNodeResults))
+	for _, n := range result.PerNodeResults {
+		nodeStates[n.Node] = decisionOK
+		if n.Error != "" {
+			nodeStates[n.Node] = n.Error
+		}
+	}
+	result.decide(DecisionFinish, nodeStates, finishOutcome(result))
+	if err == nil && result.Version != "" && changesServedVersion(result.Operation) {
+		result.PackageName = service.UpdateManager.PackageName(result.Version)
+	}
+	service.operationHistory().add(*result)
+	recordClusterEvent(service, result, err)
+	if changesServedVersion(result.Operation) {
+		if result.ErrorCode == ErrorCodeCancelled {
+			// the master still serves the version it served before, the cancelled version is not failed
+			reportNodeState(service, result.fromVersion, nil)
+		} else {
+			reportNodeState(service, result.Version, err)
+		}
+		trigger := TriggerAPI
+		if result.Operation == OperationAutoUpdate {
+			trigger = TriggerAutoUpdate
+		}
+		entry := newAuditEntry(service, result.Operation, trigger, result.fromVersion, result.Version, result.StartedAt, err)
+		entry.OpID = result.OpID
+		entry.Decisions = result.Decisions
+		service.auditLog().record(entry)
+	}
+	logger := logrus.WithFields(logrus.Fields{
+		"operation": result.Operation,
+		"opId":      result.OpID,
+		"version":   result.Version,
+		"package":   result.PackageName,
+	})
+	if err != nil {
+		logger = logger.WithError(err)
+	}
+	logger.Info("Cluster operation finished")
+	return result
+}
+
+func operationErrorCode(err error) string {
+	switch err = errors.Cause(err); err {
+	case updatemanager.ErrRequestedVersionNotFound:
+		return ErrorCodeVersionNotFound
+	case updatemanager.ErrReadOnlyFilesystem:
+		return ErrorCodeReadOnlyFS
+	case updatemanager.ErrInvalidBundleLayout:
+		return ErrorCodeInvalidBundle
+	case ErrSoakFailed:
+		return ErrorCodeSoakFailed
+	case ErrSuperseded:
+		return ErrorCodeSuperseded
+	case updatemanager.ErrUpdateCancelled:
+		return ErrorCodeCancelled
+	case updatemanager.ErrBundleSignatureNotFound, updatemanager.ErrBundleSignatureInvalid:
+		return ErrorCodeSignature
+	}
+	if _, ok := err.(versionStoreError); ok {
+		return ErrorCodeVersionStore
+	}
+	return ErrorCodeInternal
+}
+
+// versionStoreError marks a failure to write the version store
+type versionStoreError struct {
+	error
+}
+
+// operationHistory keeps the last cluster operation results of this master in memory
+type operationHistory struct {
+	results []ClusterOperationResult
+	limit   int
+	sync.Mutex
+}
+
+// operationHistory returns the operation history of the service, creating it on first use
+func (service *UIService) operationHistory() *operationHistory {
+	service.Lock()
+	defer service.Unlock()
+	if service.history == nil {
+		service.history = &operationHistory{limit: maxOperationHistory}
+	}
+	return service.history
+}
+
+func (h *operationHistory) add(result ClusterOperationResult) {
+	h.Lock()
+	defer h.Unlock()
+	h.results = append(h.results, result)
+	if len(h.results) > h.limit {
+		h.results = h.results[len(h.results)-h.limit:]
+	}
+}
+
+// Results returns the results, the most recent first
+func (h *operationHistory) Results() []ClusterOperationResult {
+	h.Lock()
+	defer h.Unlock()
+	results := make([]ClusterOperationResult, 0, len(h.results))
+	for i := len(h.results) - 1; i >= 0; i-- {
+		results = append(results, h.results[i])
+	}
+	return results
+}
+
+// wantsJSON is true if the request accepts application/json, other clients keep the plain text responses
+func wantsJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted)); err == nil && mediaType == "application/json" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOperationResult responds with the result for application/json requests and with message otherwise
+func writeOperationResult(w http.ResponseWriter, r *http.Request, status int, result *ClusterOperationResult, message string) {
+	if wantsJSON(r) {
+		js, err := json.Marshal(result)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(js)
+		return
+	}
+	if status >= http.StatusBadRequest {
+		if message == "" {
+			w.WriteHeader(status)
+			return
+		}
+		http.Error(w, message, status)
+		return
+	}
+	if failed := result.failedHook(); failed != nil {
+		message += fmt.Sprintf("\nPost-activate hook %s failed: %s", failed.Name, failed.Error)
+	}
+	w.Header().Add("Content-Type", "text/plain")
+	w.WriteHeader(status)
+	w.Write([]byte(message))
+}
+
+// failedHook returns the first post-activate hook that failed, nil if all succeeded
+func (result *ClusterOperationResult) failedHook() *hooks.Result {
+	for i := range result.PostActivateHooks {
+		if result.PostActivateHooks[i].Error != "" {
+			return &result.PostActivateHooks[i]
+		}
+	}
+	return nil
+}
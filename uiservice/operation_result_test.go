@@ -0,0 +1,91 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+)
+
+func TestClusterOperationResult(t *testing.T) {
+	t.Run("update returns the result for json requests", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		newVersionPath := path.Join(service.Config.VersionsRoot(), "2.24.5", "dist")
+		os.MkdirAll(newVersionPath, 0755)
+		um := UpdateManagerDouble()
+		um.UpdateNewVersionPath = newVersionPath
+		service.UpdateManager = um
+
+		req, _ := http.NewRequest("POST", "/api/v1/update/2.24.5/", nil)
+		req.Header.Set("Accept", "application/json")
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var result ClusterOperationResult
+		if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		tests.H(t).StringEql(result.Operation, OperationUpdate)
+		tests.H(t).StringEql(result.Version, "2.24.5")
+		tests.H(t).BoolEql(result.OpID != "", true)
+		tests.H(t).BoolEql(result.FinishedAt.Before(result.StartedAt), false)
+		tests.H(t).IntEql(len(result.PerNodeResults), 1)
+		tests.H(t).StringEql(result.ErrorCode, "")
+	})
+
+	t.Run("reset and update results share the history", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		um := UpdateManagerDouble()
+		um.UpdateError = updatemanager.ErrRequestedVersionNotFound
+		service.UpdateManager = um
+
+		req, _ := http.NewRequest("POST", "/api/v1/update/2.24.5/", nil)
+		newRouter(service).ServeHTTP(httptest.NewRecorder(), req)
+		req, _ = http.NewRequest("DELETE", "/api/v1/reset/", nil)
+		newRouter(service).ServeHTTP(httptest.NewRecorder(), req)
+
+		req, _ = http.NewRequest("GET", "/api/v1/operations/", nil)
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var response operationsResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		results := response.Recent
+		tests.H(t).IntEql(len(results), 2)
+		tests.H(t).StringEql(results[0].Operation, OperationReset)
+		tests.H(t).StringEql(results[1].Operation, OperationUpdate)
+		tests.H(t).StringEql(results[1].ErrorCode, ErrorCodeVersionNotFound)
+	})
+
+	t.Run("maps errors to error codes", func(t *testing.T) {
+		tests.H(t).StringEql(operationErrorCode(updatemanager.ErrReadOnlyFilesystem), ErrorCodeReadOnlyFS)
+		tests.H(t).StringEql(operationErrorCode(updatemanager.ErrInvalidBundleLayout), ErrorCodeInvalidBundle)
+		tests.H(t).StringEql(operationErrorCode(updatemanager.ErrBundleSignatureNotFound), ErrorCodeSignature)
+		tests.H(t).StringEql(operationErrorCode(versionStoreError{errors.New("zk down")}), ErrorCodeVersionStore)
+		tests.H(t).StringEql(operationErrorCode(errors.New("boom")), ErrorCodeInternal)
+	})
+
+	t.Run("history keeps the most recent results", func(t *testing.T) {
+		history := &operationHistory{limit: 2}
+		history.add(ClusterOperationResult{OpID: "1"})
+		history.add(ClusterOperationResult{OpID: "2"})
+		history.add(ClusterOperationResult{OpID: "3"})
+
+		results := history.Results()
+		tests.H(t).IntEql(len(results), 2)
+		tests.H(t).StringEql(results[0].OpID, "3")
+		tests.H(t).StringEql(results[1].OpID, "2")
+	})
+}
@@ -0,0 +1,204 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const clusterPinNode = "pin"
+
+// VersionPin holds the ui at a version, e.g. during an incident window
+type VersionPin struct {
+	Version  string    `json:"version"`
+	Master   string    `json:"master"`
+	PinnedAt time.Time `json:"pinnedAt"`
+}
+
+// ClusterPin stops all masters from changing the served version to another than the pinned one
+type ClusterPin interface {
+	// Pin stores the pin, replacing an existing one
+	Pin(VersionPin) error
+	// ActivePin returns the pin, nil if the version is not pinned
+	ActivePin() (*VersionPin, error)
+	// Unpin allows version changes again
+	Unpin() error
+}
+
+func makeClusterPinPath(basePath string) string {
+	return path.Join(basePath, "cluster-status", clusterPinNode)
+}
+
+// Pin creates or replaces a persistent node below cluster-status, so the pin survives restarts of all masters
+func (zks *zkVersionStore) Pin(pin VersionPin) error {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return ErrZookeeperNotConnected
+	}
+	data, err := json.Marshal(pin)
+	if err != nil {
+		return err
+	}
+	pinPath := makeClusterPinPath(zks.zkBasePath)
+	err = zks.client.Create(pinPath, data, zookeeper.PermAll)
+	if err == zookeeper.ErrNodeExists {
+		_, err = zks.client.Set(pinPath, data)
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to pin the version")
+	}
+	return nil
+}
+
+// ActivePin reads the pin node created by Pin
+func (zks *zkVersionStore) ActivePin() (*VersionPin, error) {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return nil, ErrZookeeperNotConnected
+	}
+	pinPath := makeClusterPinPath(zks.zkBasePath)
+	exists, _, err := zks.client.Exists(pinPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to check if the version is pinned")
+	}
+	if !exists {
+		return nil, nil
+	}
+	data, _, err := zks.client.Get(pinPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read the version pin")
+	}
+	var pin VersionPin
+	if err := json.Unmarshal(data, &pin); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the version pin")
+	}
+	return &pin, nil
+}
+
+// Unpin removes the pin node created by Pin
+func (zks *zkVersionStore) Unpin() error {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return ErrZookeeperNotConnected
+	}
+	pinPath := makeClusterPinPath(zks.zkBasePath)
+	exists, _, err := zks.client.Exists(pinPath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to check if the version is pinned")
+	}
+	if !exists {
+		return nil
+	}
+	if err := zks.client.Delete(pinPath); err != nil {
+		return errors.Wrap(err, "Failed to unpin the version")
+	}
+	return nil
+}
+
+// activePin returns the pin holding the served version, nil if the version store cannot pin versions
+func activePin(service *UIService) (*VersionPin, error) {
+	if service.ClusterPin == nil {
+		return nil, nil
+	}
+	return service.ClusterPin.ActivePin()
+}
+
+// rejectIfPinned writes the error response and returns true if the served version must not change to version
+// while pinned. Changing to the pinned version itself is allowed, so a version can be pinned before updating to it.
+func rejectIfPinned(service *UIService, w http.ResponseWriter, version string) bool {
+	pin, err := activePin(service)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to check if the version is pinned")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return true
+	}
+	if pin == nil || pin.Version == version {
+		return false
+	}
+	http.Error(
+		w,
+		fmt.Sprintf("The ui is pinned to %s by %s, remove the pin first", pin.Version, pin.Master),
+		http.StatusConflict,
+	)
+	return true
+}
+
+type pinResponse struct {
+	Pin *VersionPin `json:"pin,omitempty"`
+}
+
+// pinHandler pins the ui to a version on all masters
+func pinHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if service.ClusterPin == nil {
+			http.Error(w, "version pinning is not supported by the version store", http.StatusNotImplemented)
+			return
+		}
+		pin := VersionPin{
+			Version:  mux.Vars(r)["version"],
+			Master:   service.nodeName(),
+			PinnedAt: time.Now().UTC(),
+		}
+		if err := service.ClusterPin.Pin(pin); err != nil {
+			logrus.WithError(err).Error("Failed to pin the version")
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		logrus.WithField("version", pin.Version).Warn("Pinned the ui version, version changes are refused until it is unpinned")
+		writePinResponse(w, &pin)
+	}
+}
+
+// unpinHandler removes the pin and returns the removed pin
+func unpinHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if service.ClusterPin == nil {
+			http.Error(w, "version pinning is not supported by the version store", http.StatusNotImplemented)
+			return
+		}
+		pin, err := service.ClusterPin.ActivePin()
+		if err == nil && pin != nil {
+			err = service.ClusterPin.Unpin()
+		}
+		if err != nil {
+			logrus.WithError(err).Error("Failed to unpin the version")
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if pin != nil {
+			logrus.WithField("version", pin.Version).Info("Unpinned the ui version, syncing to the stored version")
+			go resyncStoredVersion(service)
+		}
+		writePinResponse(w, pin)
+	}
+}
+
+func writePinResponse(w http.ResponseWriter, pin *VersionPin) {
+	js, err := json.Marshal(pinResponse{Pin: pin})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+}
+
+// rejectIfPinnedVersionRemoved writes the error response and returns true if version is pinned and must not be removed
+func rejectIfPinnedVersionRemoved(service *UIService, w http.ResponseWriter, version string) bool {
+	pin, err := activePin(service)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to check if the version is pinned")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return true
+	}
+	if pin == nil || pin.Version != version {
+		return false
+	}
+	http.Error(w, fmt.Sprintf("Cannot remove version %s, the ui is pinned to it", version), http.StatusConflict)
+	return true
+}
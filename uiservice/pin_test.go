@@ -0,0 +1,229 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+)
+
+type fakeClusterPin struct {
+	Active      *VersionPin
+	ActiveError error
+	Unpinned    int
+}
+
+func (cp *fakeClusterPin) Pin(pin VersionPin) error {
+	cp.Active = &pin
+	return nil
+}
+
+func (cp *fakeClusterPin) ActivePin() (*VersionPin, error) {
+	return cp.Active, cp.ActiveError
+}
+
+func (cp *fakeClusterPin) Unpin() error {
+	cp.Active = nil
+	cp.Unpinned++
+	return nil
+}
+
+func TestZKClusterPin(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Pin creates a persistent pin node", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		var createdPath string
+		var createdData []byte
+		client.CreateCall = func(path string, data []byte, perms []int32) {
+			createdPath = path
+			createdData = data
+		}
+
+		err := store.Pin(VersionPin{Version: "2.25.0", Master: "10.0.0.1"})
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(createdPath, "/dcos/ui-service-test/cluster-status/pin")
+		var stored VersionPin
+		json.Unmarshal(createdData, &stored)
+		tests.H(t).StringEql(stored.Version, "2.25.0")
+	})
+
+	t.Run("Pin replaces an existing pin", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.CreateError = zookeeper.ErrNodeExists
+		var setPath string
+		client.SetCall = func(path string, data []byte) {
+			setPath = path
+		}
+
+		err := store.Pin(VersionPin{Version: "2.25.1"})
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(setPath, "/dcos/ui-service-test/cluster-status/pin")
+	})
+
+	t.Run("ActivePin returns nil if the pin node does not exist", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.ExistsResult = false
+
+		pin, err := store.ActivePin()
+
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(pin == nil, true)
+	})
+
+	t.Run("ActivePin returns the stored pin", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.ExistsResult = true
+		client.GetResult = []byte(`{"version":"2.25.0","master":"10.0.0.1"}`)
+
+		pin, err := store.ActivePin()
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(pin.Version, "2.25.0")
+	})
+
+	t.Run("Unpin deletes the pin node", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.ExistsResult = true
+		var deletedPath string
+		client.DeleteCall = func(path string) {
+			deletedPath = path
+		}
+
+		tests.H(t).IsNil(store.Unpin())
+		tests.H(t).StringEql(deletedPath, "/dcos/ui-service-test/cluster-status/pin")
+	})
+}
+
+func TestVersionPin(t *testing.T) {
+	t.Run("syncs to another version are skipped while pinned", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		service.ClusterPin = &fakeClusterPin{Active: &VersionPin{Version: "2.24.4"}}
+		updateCalled := false
+		um := UpdateManagerDouble()
+		um.UpdateCall = func(string) {
+			updateCalled = true
+		}
+		service.UpdateManager = um
+
+		handleVersionChange(service, "2.24.5")
+
+		tests.H(t).BoolEql(updateCalled, false)
+	})
+
+	t.Run("syncs to the pinned version are allowed", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		service.ClusterPin = &fakeClusterPin{Active: &VersionPin{Version: "2.24.5"}}
+		var updatedTo string
+		um := UpdateManagerDouble()
+		um.UpdateNewVersionPath = service.Config.DefaultDocRoot()
+		um.UpdateCall = func(version string) {
+			updatedTo = version
+		}
+		service.UpdateManager = um
+
+		syncToVersion(service, "2.24.5")
+
+		tests.H(t).StringEql(updatedTo, "2.24.5")
+	})
+
+	t.Run("updates via the API are rejected while pinned to another version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.ClusterPin = &fakeClusterPin{Active: &VersionPin{Version: "2.24.4", Master: "10.0.0.1"}}
+		service.UpdateManager = UpdateManagerDouble()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/update/2.24.6/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+		tests.H(t).StringContains(rr.Body.String(), "pinned to 2.24.4 by 10.0.0.1")
+	})
+
+	t.Run("resets via the API are rejected while pinned", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.ClusterPin = &fakeClusterPin{Active: &VersionPin{Version: "2.24.4"}}
+		service.UpdateManager = UpdateManagerDouble()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/reset/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+	})
+
+	t.Run("the pinned version cannot be removed", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.ClusterPin = &fakeClusterPin{Active: &VersionPin{Version: "2.24.3"}}
+		service.UpdateManager = UpdateManagerDouble()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/versions/2.24.3/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+	})
+}
+
+func TestPinHandlers(t *testing.T) {
+	t.Run("returns 501 if the version store cannot pin versions", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/pin/2.25.0/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusNotImplemented)
+	})
+
+	t.Run("pins the version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		pin := &fakeClusterPin{}
+		service.ClusterPin = pin
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/pin/2.25.0/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringEql(pin.Active.Version, "2.25.0")
+		var response pinResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		tests.H(t).StringEql(response.Pin.Version, "2.25.0")
+	})
+
+	t.Run("removes the pin and returns it", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		pin := &fakeClusterPin{Active: &VersionPin{Version: "2.25.0"}}
+		service.ClusterPin = pin
+		service.UpdateManager = UpdateManagerDouble()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/pin/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).IntEql(pin.Unpinned, 1)
+		var response pinResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		tests.H(t).StringEql(response.Pin.Version, "2.25.0")
+	})
+
+	t.Run("returns 503 if the pin cannot be read", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.ClusterPin = &fakeClusterPin{ActiveError: ErrZookeeperNotConnected}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/pin/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusServiceUnavailable)
+	})
+}
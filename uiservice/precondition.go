@@ -0,0 +1,61 @@
+package uiservice
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultPackageVersion is the packageVersion reported while the pre-bundled ui is served
+const defaultPackageVersion = "Default"
+
+// packageVersionETag is the entity tag of the served packageVersion
+func packageVersionETag(packageVersion string) string {
+	return strconv.Quote(packageVersion)
+}
+
+// ifMatchVersions returns the package versions listed in the If-Match header, "*" matches any version
+func ifMatchVersions(r *http.Request) []string {
+	var versions []string
+	for _, header := range r.Header["If-Match"] {
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if unquoted, err := strconv.Unquote(tag); err == nil {
+				tag = unquoted
+			}
+			if tag != "" {
+				versions = append(versions, tag)
+			}
+		}
+	}
+	return versions
+}
+
+// rejectIfStale responds with 412 and returns true if the request has an If-Match header
+// that does not list the packageVersion currently served
+func rejectIfStale(service *UIService, w http.ResponseWriter, r *http.Request) bool {
+	expected := ifMatchVersions(r)
+	if len(expected) == 0 {
+		return false
+	}
+	current, err := service.UpdateManager.CurrentVersion()
+	if err != nil {
+		logrus.WithError(err).Error("Could not get current version.")
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+	if current == "" {
+		current = defaultPackageVersion
+	}
+	for _, version := range expected {
+		if version == "*" || version == current {
+			return false
+		}
+	}
+	w.Header().Set("ETag", packageVersionETag(current))
+	http.Error(w, fmt.Sprintf("Current version %s does not match If-Match", current), http.StatusPreconditionFailed)
+	return true
+}
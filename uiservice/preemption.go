@@ -0,0 +1,135 @@
+package uiservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrSuperseded is the error of an update that was cancelled before its activation because a reset preempted it
+var ErrSuperseded = errors.New("The operation was superseded by a reset")
+
+// preemptionTimeout is how long a reset waits for a preempted update to stop before it is rejected
+var preemptionTimeout = 30 * time.Second
+
+// preemptionPollInterval is how often a reset checks whether the preempted update stopped
+var preemptionPollInterval = 50 * time.Millisecond
+
+// operationPriority ranks the operations changing the served version. An operation can preempt the
+// operations of a lower priority that are queued or have not been activated yet.
+func operationPriority(operation string) int {
+	switch operation {
+	case OperationReset, OperationRollback:
+		// recovery actions
+		return 2
+	case OperationUpdate, OperationAutoUpdate:
+		return 1
+	}
+	return 0
+}
+
+// canPreempt is true if the incoming operation may cancel the running one
+func canPreempt(incoming, running string) bool {
+	return operationPriority(incoming) > operationPriority(running)
+}
+
+// preemptibleOperation is the update running on this master that a higher priority operation can cancel
+type preemptibleOperation struct {
+	operation string
+	// activating is set once the update serves the new version, it can no longer be cancelled
+	activating bool
+	// soaking is set while the activated version soaks on this master only, it can be cancelled again
+	soaking bool
+	// preempted is set by the operation cancelling the update, the update stops before activating
+	preempted bool
+	// cancelled is set by cancelUpdate, the download is aborted and the update stops before activating
+	cancelled bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// markPreemptible registers the operation the service is updating for as preemptible, it is cleared by
+// resetServiceFromUpdate
+func markPreemptible(service *UIService, operation string) {
+	service.Lock()
+	defer service.Unlock()
+	if service.updating {
+		ctx, cancel := context.WithCancel(context.Background())
+		service.preemptible = &preemptibleOperation{operation: operation, ctx: ctx, cancel: cancel}
+	}
+}
+
+// beginActivation returns ErrSuperseded if the running update was preempted and ErrUpdateCancelled if it was
+// cancelled, otherwise the update can no longer be preempted or cancelled
+func beginActivation(service *UIService) error {
+	service.Lock()
+	defer service.Unlock()
+	if service.preemptible == nil {
+		return nil
+	}
+	if service.preemptible.cancelled {
+		return updatemanager.ErrUpdateCancelled
+	}
+	if service.preemptible.preempted {
+		return ErrSuperseded
+	}
+	service.preemptible.activating = true
+	return nil
+}
+
+// preemptRunning marks the update running on this master as preempted by the incoming operation, false if
+// nothing runs that the incoming operation can cancel
+func preemptRunning(service *UIService, incoming string) bool {
+	service.Lock()
+	defer service.Unlock()
+	running := service.preemptible
+	if !service.updating || running == nil || running.activating || !canPreempt(incoming, running.operation) {
+		return false
+	}
+	running.preempted = true
+	return true
+}
+
+// supersedeQueued removes the queued updates the incoming operation can cancel, their jobs are superseded
+func supersedeQueued(service *UIService, incoming string) int {
+	queue := service.updateQueue()
+	jobs := service.updateJobs()
+	superseded := 0
+	for _, item := range queue.Queued() {
+		if !canPreempt(incoming, item.Operation) {
+			continue
+		}
+		if _, ok := queue.remove(item.ID); ok {
+			jobs.setState(item.ID, JobSuperseded)
+			superseded++
+		}
+	}
+	return superseded
+}
+
+// preemptForOperation cancels the queued and not yet activated updates for the incoming operation and sets
+// the service to updating once the preempted update stopped. It returns false if nothing runs that can be
+// preempted or the preempted update does not stop within preemptionTimeout.
+func preemptForOperation(service *UIService, incoming string, version string) bool {
+	if !preemptRunning(service, incoming) {
+		return false
+	}
+	logger := logrus.WithField("operation", incoming)
+	if superseded := supersedeQueued(service, incoming); superseded > 0 {
+		logger.WithField("queued", superseded).Info("Superseded queued updates")
+	}
+	logger.Info("Preempting the update in progress")
+	deadline := time.Now().Add(preemptionTimeout)
+	for {
+		if _, err := setServiceUpdating(service, version); err == nil {
+			return true
+		} else if err == ErrShuttingDown || time.Now().After(deadline) {
+			logger.WithError(err).Warn("The preempted update did not stop")
+			return false
+		}
+		time.Sleep(preemptionPollInterval)
+	}
+}
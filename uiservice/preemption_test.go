@@ -0,0 +1,147 @@
+package uiservice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func waitForPreemption(service *UIService) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		service.Lock()
+		preempted := service.preemptible != nil && service.preemptible.preempted
+		service.Unlock()
+		if preempted {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCanPreempt(t *testing.T) {
+	tests.H(t).BoolEql(canPreempt(OperationReset, OperationUpdate), true)
+	tests.H(t).BoolEql(canPreempt(OperationReset, OperationAutoUpdate), true)
+	tests.H(t).BoolEql(canPreempt(OperationReset, OperationRollback), false)
+	tests.H(t).BoolEql(canPreempt(OperationReset, OperationReset), false)
+	tests.H(t).BoolEql(canPreempt(OperationUpdate, OperationUpdate), false)
+	tests.H(t).BoolEql(canPreempt(OperationUpdate, OperationReset), false)
+}
+
+func TestPreemptRunning(t *testing.T) {
+	t.Run("preempts an update that is not activated yet", func(t *testing.T) {
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.25.0")
+		markPreemptible(service, OperationUpdate)
+
+		tests.H(t).BoolEql(preemptRunning(service, OperationReset), true)
+		tests.H(t).ErrEql(beginActivation(service), ErrSuperseded)
+	})
+
+	t.Run("does not preempt an activating update", func(t *testing.T) {
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.25.0")
+		markPreemptible(service, OperationUpdate)
+		tests.H(t).IsNil(beginActivation(service))
+
+		tests.H(t).BoolEql(preemptRunning(service, OperationReset), false)
+	})
+
+	t.Run("does not preempt an operation that is not preemptible", func(t *testing.T) {
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.25.0")
+
+		tests.H(t).BoolEql(preemptRunning(service, OperationReset), false)
+	})
+
+	t.Run("clears the preemptible operation with the update", func(t *testing.T) {
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.25.0")
+		markPreemptible(service, OperationUpdate)
+		resetServiceFromUpdate(service)
+
+		tests.H(t).BoolEql(preemptRunning(service, OperationReset), false)
+	})
+}
+
+func TestResetPreemptsUpdate(t *testing.T) {
+	queuePollInterval = 5 * time.Millisecond
+	preemptionPollInterval = 5 * time.Millisecond
+
+	t.Run("supersedes a downloading update and the queued updates", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.UpdateNewVersionPath = service.Config.DefaultDocRoot()
+		downloading := make(chan struct{})
+		downloaded := make(chan struct{})
+		um.UpdateCall = func(string) {
+			close(downloading)
+			<-downloaded
+		}
+		service.UpdateManager = um
+
+		started := startAsyncUpdate(t, service)
+		<-downloading
+		queued := queueUpdate(t, service, "2.26.0")
+
+		rr := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/reset/", nil))
+		}()
+		waitForPreemption(service)
+		close(downloaded)
+		<-done
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		job, _ := service.updateJobs().get(started.ID)
+		tests.H(t).StringEql(job.State, JobSuperseded)
+		tests.H(t).StringEql(job.Result.ErrorCode, ErrorCodeSuperseded)
+		job, _ = service.updateJobs().get(queued.ID)
+		tests.H(t).StringEql(job.State, JobSuperseded)
+		tests.H(t).IntEql(service.updateQueue().Len(), 0)
+
+		entries, err := service.auditLog().Entries(context.Background(), 10)
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(len(entries), 2)
+		tests.H(t).StringEql(entries[0].Operation, OperationReset)
+		tests.H(t).StringEql(entries[0].Outcome, OutcomeSuccess)
+		tests.H(t).StringEql(entries[1].Operation, OperationUpdate)
+		tests.H(t).StringEql(entries[1].Outcome, OutcomeSuperseded)
+	})
+
+	t.Run("rejects the reset if the update does not stop in time", func(t *testing.T) {
+		defer tearDown(t)
+		defer func(timeout time.Duration) { preemptionTimeout = timeout }(preemptionTimeout)
+		preemptionTimeout = 20 * time.Millisecond
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.25.0")
+		defer resetServiceFromUpdate(service)
+		markPreemptible(service, OperationUpdate)
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/reset/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+	})
+
+	t.Run("rejects the reset while an update is activating", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.25.0")
+		defer resetServiceFromUpdate(service)
+		markPreemptible(service, OperationUpdate)
+		beginActivation(service)
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/reset/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+	})
+}
@@ -0,0 +1,98 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"unicode"
+
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// approvalRequestLimit is the maximum size of the body of PUT /api/v1/versions/local/{version}/provenance/
+	approvalRequestLimit = 4 * 1024
+	// maxApprovalTicketLength is the maximum length of an approval ticket ID
+	maxApprovalTicketLength = 256
+)
+
+// ErrInvalidApprovalTicket is returned for an approval ticket that is too long or contains control characters
+var ErrInvalidApprovalTicket = errors.New("approvalTicket must be at most 256 characters without control characters")
+
+type approvalRequest struct {
+	ApprovalTicket string `json:"approvalTicket"`
+}
+
+func validApprovalTicket(ticket string) bool {
+	if len(ticket) > maxApprovalTicketLength {
+		return false
+	}
+	for _, c := range ticket {
+		if unicode.IsControl(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// provenanceHandler returns the provenance recorded when the version was installed on this master
+func provenanceHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version := mux.Vars(r)["version"]
+		if !directVersionPattern.MatchString(version) {
+			http.Error(w, updatemanager.ErrProvenanceNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		provenance, err := service.UpdateManager.VersionProvenance(version)
+		writeProvenance(w, version, provenance, err)
+	}
+}
+
+// approvalTicketHandler records the approval ticket in the provenance of the version on this master,
+// an empty ticket removes it
+func approvalTicketHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version := mux.Vars(r)["version"]
+		if !directVersionPattern.MatchString(version) {
+			http.Error(w, updatemanager.ErrProvenanceNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		var request approvalRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, approvalRequestLimit)).Decode(&request); err != nil {
+			http.Error(w, "approval request could not be decoded", http.StatusBadRequest)
+			return
+		}
+		if !validApprovalTicket(request.ApprovalTicket) {
+			http.Error(w, ErrInvalidApprovalTicket.Error(), http.StatusBadRequest)
+			return
+		}
+		provenance, err := service.UpdateManager.SetApprovalTicket(version, request.ApprovalTicket)
+		writeProvenance(w, version, provenance, err)
+	}
+}
+
+func writeProvenance(w http.ResponseWriter, version string, provenance *updatemanager.Provenance, err error) {
+	switch err {
+	case nil:
+	case updatemanager.ErrProvenanceNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case updatemanager.ErrReadOnlyFilesystem:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	default:
+		logrus.WithError(err).WithField("version", version).Error("Failed to access the provenance of the version")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	js, err := json.Marshal(provenance)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+}
@@ -0,0 +1,114 @@
+package uiservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+)
+
+func TestProvenanceHandlers(t *testing.T) {
+	provenanceDouble := func() *fakeUpdateManager {
+		um := UpdateManagerDouble()
+		um.ProvenanceResult = map[string]*updatemanager.Provenance{
+			"2.25.2": {
+				Version:        "2.25.2",
+				BundleURL:      "https://downloads.example.com/dcos-ui/release.tar.gz",
+				SourceRepo:     "https://github.com/dcos/dcos-ui.git",
+				PackageRelease: 7,
+				AssetChecksum:  "sha256:abc123",
+				VerifiedBy:     "master-1",
+				InstalledAt:    time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+			},
+		}
+		return um
+	}
+
+	t.Run("returns the provenance of a version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.UpdateManager = provenanceDouble()
+
+		req, _ := http.NewRequest("GET", "/api/v1/versions/local/2.25.2/provenance/", nil)
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringEql(rr.Body.String(), `{"version":"2.25.2","bundleUrl":"https://downloads.example.com/dcos-ui/release.tar.gz",`+
+			`"sourceRepo":"https://github.com/dcos/dcos-ui.git","packageRelease":7,"assetChecksum":"sha256:abc123",`+
+			`"verifiedBy":"master-1","installedAt":"2026-10-01T12:00:00Z"}`)
+	})
+
+	t.Run("returns 404 for a version without provenance", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.UpdateManager = provenanceDouble()
+
+		for _, version := range []string{"2.24.4", ".hidden"} {
+			req, _ := http.NewRequest("GET", "/api/v1/versions/local/"+version+"/provenance/", nil)
+			rr := httptest.NewRecorder()
+			newRouter(service).ServeHTTP(rr, req)
+
+			tests.H(t).IntEql(rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("records an approval ticket", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := provenanceDouble()
+		service.UpdateManager = um
+
+		req, _ := http.NewRequest("PUT", "/api/v1/versions/local/2.25.2/provenance/", strings.NewReader(`{"approvalTicket":"CHG-1234"}`))
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringContains(rr.Body.String(), `"approvalTicket":"CHG-1234"`)
+		tests.H(t).StringEql(um.ProvenanceResult["2.25.2"].ApprovalTicket, "CHG-1234")
+	})
+
+	t.Run("rejects invalid approval tickets", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := provenanceDouble()
+		service.UpdateManager = um
+
+		for _, body := range []string{`{"approvalTicket":"CHG\n1234"}`, `{"approvalTicket":"` + strings.Repeat("x", 257) + `"}`, `not json`} {
+			req, _ := http.NewRequest("PUT", "/api/v1/versions/local/2.25.2/provenance/", strings.NewReader(body))
+			rr := httptest.NewRecorder()
+			newRouter(service).ServeHTTP(rr, req)
+
+			tests.H(t).IntEql(rr.Code, http.StatusBadRequest)
+		}
+		tests.H(t).StringEql(um.ProvenanceResult["2.25.2"].ApprovalTicket, "")
+	})
+
+	t.Run("maps approval errors to status codes", func(t *testing.T) {
+		var testCases = []struct {
+			err        error
+			statusCode int
+		}{
+			{updatemanager.ErrProvenanceNotFound, http.StatusNotFound},
+			{updatemanager.ErrReadOnlyFilesystem, http.StatusServiceUnavailable},
+			{updatemanager.ErrWritingProvenance, http.StatusInternalServerError},
+		}
+		for _, tt := range testCases {
+			service := setupTestUIService()
+			um := provenanceDouble()
+			um.ApprovalError = tt.err
+			service.UpdateManager = um
+
+			req, _ := http.NewRequest("PUT", "/api/v1/versions/local/2.25.2/provenance/", strings.NewReader(`{"approvalTicket":"CHG-1234"}`))
+			rr := httptest.NewRecorder()
+			newRouter(service).ServeHTTP(rr, req)
+
+			tests.H(t).IntEql(rr.Code, tt.statusCode)
+			tearDown(t)
+		}
+	})
+}
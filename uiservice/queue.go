@@ -0,0 +1,319 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// queuePollInterval is how often the queue worker tries to start the next update while the service is busy
+var queuePollInterval = time.Second
+
+// InFlightOperation is the update, reset or rollback currently running on this master
+type InFlightOperation struct {
+	Version string    `json:"version"`
+	Since   time.Time `json:"since"`
+}
+
+// QueuedOperation is an update requested with ?async=true&queue=true that has not started yet
+type QueuedOperation struct {
+	// ID is the id of the update job, the job stays queued until the update starts
+	ID        string    `json:"id"`
+	Operation string    `json:"operation"`
+	Version   string    `json:"version"`
+	Position  int       `json:"position"`
+	QueuedAt  time.Time `json:"queuedAt"`
+	// EstimatedStart is derived from the duration of recent updates, it is omitted without one
+	EstimatedStart *time.Time `json:"estimatedStart,omitempty"`
+}
+
+// updateQueue runs queued updates one after another on this master, in the order they were requested
+type updateQueue struct {
+	items   []QueuedOperation
+	running bool
+	sync.Mutex
+}
+
+// updateQueue returns the update queue of the service, creating it on first use
+func (service *UIService) updateQueue() *updateQueue {
+	service.Lock()
+	defer service.Unlock()
+	if service.queue == nil {
+		service.queue = &updateQueue{}
+	}
+	return service.queue
+}
+
+// Len returns the number of queued updates
+func (q *updateQueue) Len() int {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.items)
+}
+
+// enqueue adds an update job for version and starts the worker if it is not running
+func (q *updateQueue) enqueue(service *UIService, version string) (UpdateJob, int) {
+	jobs := service.updateJobs()
+	job := jobs.create(version)
+	jobs.setState(job.ID, JobQueued)
+	job, _ = jobs.get(job.ID)
+	q.Lock()
+	defer q.Unlock()
+	q.items = append(q.items, QueuedOperation{
+		ID:        job.ID,
+		Operation: OperationUpdate,
+		Version:   version,
+		QueuedAt:  job.Created,
+	})
+	if !q.running {
+		q.running = true
+		go q.run(service)
+	}
+	return job, len(q.items)
+}
+
+// remove dequeues the update job id, false if it is not queued
+func (q *updateQueue) remove(id string) (QueuedOperation, bool) {
+	q.Lock()
+	defer q.Unlock()
+	for i, item := range q.items {
+		if item.ID == id {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return item, true
+		}
+	}
+	return QueuedOperation{}, false
+}
+
+// Queued returns the queued updates with their positions, the next one first
+func (q *updateQueue) Queued() []QueuedOperation {
+	q.Lock()
+	defer q.Unlock()
+	queued := make([]QueuedOperation, 0, len(q.items))
+	for i, item := range q.items {
+		item.Position = i + 1
+		queued = append(queued, item)
+	}
+	return queued
+}
+
+// next takes the first queued update once the service is no longer updating. The service is set to updating
+// before the update leaves the queue, so no other request can start in between. It returns ErrShuttingDown
+// once the service shuts down.
+func (q *updateQueue) next(service *UIService) (QueuedOperation, bool, error) {
+	q.Lock()
+	defer q.Unlock()
+	if len(q.items) == 0 {
+		return QueuedOperation{}, false, nil
+	}
+	item := q.items[0]
+	if _, err := setServiceUpdating(service, item.Version); err != nil {
+		return QueuedOperation{}, false, err
+	}
+	q.items = q.items[1:]
+	return item, true, nil
+}
+
+// cancelAll empties the queue and cancels the jobs of the queued updates with reason
+func (q *updateQueue) cancelAll(service *UIService, reason error) {
+	q.Lock()
+	items := q.items
+	q.items = nil
+	q.running = false
+	q.Unlock()
+	jobs := service.updateJobs()
+	for _, item := range items {
+		jobs.cancel(item.ID, reason)
+		logrus.WithError(reason).WithFields(logrus.Fields{"version": item.Version, "job": item.ID}).Warn("Queued update cancelled")
+	}
+}
+
+// run starts the queued updates until the queue is empty, the queued updates are cancelled once the service
+// shuts down
+func (q *updateQueue) run(service *UIService) {
+	for {
+		item, ok, err := q.next(service)
+		if ok {
+			runQueuedUpdate(service, item)
+			continue
+		}
+		if err == ErrShuttingDown {
+			q.cancelAll(service, err)
+			return
+		}
+		q.Lock()
+		if len(q.items) == 0 {
+			q.running = false
+			q.Unlock()
+			return
+		}
+		q.Unlock()
+		time.Sleep(queuePollInterval)
+	}
+}
+
+// enqueueUpdate queues an update to version and responds with its job and position
+func enqueueUpdate(service *UIService, w http.ResponseWriter, version string) {
+	job, position := service.updateQueue().enqueue(service, version)
+	logrus.WithFields(logrus.Fields{"version": version, "job": job.ID, "position": position}).Info("Queued update")
+	writeUpdateJob(w, http.StatusAccepted, job)
+}
+
+// runQueuedUpdate runs the update flow of POST /api/v1/update/{version}/?async=true for a dequeued update.
+// The freeze, the pin and the cluster operation are checked when the update starts, not when it was queued.
+func runQueuedUpdate(service *UIService, item QueuedOperation) {
+	defer resetServiceFromUpdate(service)
+	markPreemptible(service, item.Operation)
+	logger := logrus.WithFields(logrus.Fields{"version": item.Version, "job": item.ID})
+	jobs := service.updateJobs()
+	op := newClusterOperation(service, OperationUpdate, item.Version)
+	result := newOperationResult(service, op)
+
+	if err := queuedUpdateBlocked(service, item.Version); err != nil {
+		logger.WithError(err).Warn("Queued update not started")
+		jobs.complete(item.ID, result.finish(service, err))
+		return
+	}
+	if service.ClusterStatus != nil {
+		if _, err := service.ClusterStatus.AcquireOperation(op); err != nil {
+			logger.WithError(err).Warn("Queued update not started")
+			jobs.complete(item.ID, result.finish(service, err))
+			return
+		}
+		defer releaseClusterOperation(service)
+	}
+
+	logger.Info("Starting queued update")
+	clusterActivator := newClusterActivator(service, UIVersion(item.Version), result)
+	activator := &jobActivator{Activator: clusterActivator, jobs: jobs, id: item.ID}
+	err := updateToVersion(service, item.Version, activator)
+	if err == nil {
+		err = clusterActivator.promote()
+	}
+	if err != nil {
+		logger.WithError(err).Error("Update failed")
+	}
+	activator.finish(result.finish(service, err))
+}
+
+// queuedUpdateBlocked returns why the cluster cannot be updated to version now, nil if it can
+func queuedUpdateBlocked(service *UIService, version string) error {
+	freeze, err := activeFreeze(service)
+	if err != nil {
+		return err
+	}
+	if freeze != nil {
+		return fmt.Errorf("Version syncs are frozen after syncing to %s failed on %s", freeze.Version, freeze.Master)
+	}
+	pin, err := activePin(service)
+	if err != nil {
+		return err
+	}
+	if pin != nil && pin.Version != version {
+		return fmt.Errorf("The ui is pinned to %s by %s", pin.Version, pin.Master)
+	}
+	return nil
+}
+
+// inFlightOperation returns the operation running on this master, nil if the service is not updating
+func (service *UIService) inFlightOperation() *InFlightOperation {
+	service.Lock()
+	defer service.Unlock()
+	if !service.updating {
+		return nil
+	}
+	return &InFlightOperation{Version: service.updatingVersion, Since: service.updatingSince}
+}
+
+// averageUpdateDuration returns the mean duration of the recent successful updates, 0 without one
+func averageUpdateDuration(results []ClusterOperationResult) time.Duration {
+	var total time.Duration
+	count := 0
+	for _, result := range results {
+		switch result.Operation {
+		case OperationUpdate, OperationAutoUpdate, OperationRollback:
+		default:
+			continue
+		}
+		if result.Error != "" {
+			continue
+		}
+		total += result.FinishedAt.Sub(result.StartedAt)
+		count++
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / time.Duration(count)
+}
+
+// estimateStarts sets the estimated start of the queued updates, assuming each of them and the in-flight
+// operation takes as long as the recent updates did on average
+func estimateStarts(queued []QueuedOperation, inFlight *InFlightOperation, average time.Duration, now time.Time) {
+	if average <= 0 {
+		return
+	}
+	start := now
+	if inFlight != nil {
+		if end := inFlight.Since.Add(average); end.After(now) {
+			start = end
+		}
+	}
+	for i := range queued {
+		estimate := start.Add(time.Duration(i) * average)
+		queued[i].EstimatedStart = &estimate
+	}
+}
+
+type operationsResponse struct {
+	InFlight *InFlightOperation       `json:"inFlight,omitempty"`
+	Queued   []QueuedOperation        `json:"queued"`
+	Recent   []ClusterOperationResult `json:"recent"`
+}
+
+// operationsHandler lists the operation running on this master, the queued updates and the results
+// of the recent cluster operations started on this master
+func operationsHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := operationsResponse{
+			InFlight: service.inFlightOperation(),
+			Queued:   service.updateQueue().Queued(),
+			Recent:   service.operationHistory().Results(),
+		}
+		estimateStarts(response.Queued, response.InFlight, averageUpdateDuration(response.Recent), time.Now().UTC())
+
+		js, err := json.Marshal(response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
+
+// dequeueHandler removes a queued update that has not started yet, its job is cancelled
+func dequeueHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		jobs := service.updateJobs()
+		if _, ok := service.updateQueue().remove(id); !ok {
+			if _, exists := jobs.get(id); exists {
+				http.Error(w, "The operation has already started", http.StatusConflict)
+				return
+			}
+			http.Error(w, "Queued operation not found", http.StatusNotFound)
+			return
+		}
+		jobs.setState(id, JobCancelled)
+		logrus.WithField("job", id).Info("Removed update from the queue")
+		job, _ := jobs.get(id)
+		writeUpdateJob(w, http.StatusOK, job)
+	}
+}
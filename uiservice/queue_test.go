@@ -0,0 +1,217 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func getOperations(t *testing.T, service *UIService) operationsResponse {
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/operations/", nil))
+	tests.H(t).IntEql(rr.Code, http.StatusOK)
+	var response operationsResponse
+	tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &response))
+	return response
+}
+
+func queueUpdate(t *testing.T, service *UIService, version string) UpdateJob {
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/update/"+version+"/?async=true&queue=true", nil))
+	tests.H(t).IntEql(rr.Code, http.StatusAccepted)
+	var job UpdateJob
+	tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &job))
+	return job
+}
+
+// drainQueue removes the queued updates, so they do not start after the test
+func drainQueue(service *UIService) {
+	queue := service.updateQueue()
+	for _, item := range queue.Queued() {
+		queue.remove(item.ID)
+	}
+}
+
+func waitForJob(t *testing.T, service *UIService, id string) UpdateJob {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, _ := service.updateJobs().get(id)
+		if job.State == JobDone || job.State == JobFailed || time.Now().After(deadline) {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestUpdateQueue(t *testing.T) {
+	queuePollInterval = 5 * time.Millisecond
+
+	t.Run("queues updates while an update is in progress", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.24.5")
+		defer resetServiceFromUpdate(service)
+		defer drainQueue(service)
+
+		first := queueUpdate(t, service, "2.25.0")
+		second := queueUpdate(t, service, "2.26.0")
+
+		tests.H(t).StringEql(first.State, JobQueued)
+		response := getOperations(t, service)
+		tests.H(t).StringEql(response.InFlight.Version, "2.24.5")
+		tests.H(t).IntEql(len(response.Queued), 2)
+		tests.H(t).StringEql(response.Queued[0].ID, first.ID)
+		tests.H(t).IntEql(response.Queued[0].Position, 1)
+		tests.H(t).StringEql(response.Queued[1].ID, second.ID)
+		tests.H(t).IntEql(response.Queued[1].Position, 2)
+		tests.H(t).BoolEql(response.Queued[0].EstimatedStart == nil, true)
+	})
+
+	t.Run("runs queued updates in order once the service is idle", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.UpdateNewVersionPath = service.Config.DefaultDocRoot()
+		var updatedTo []string
+		um.UpdateCall = func(version string) {
+			updatedTo = append(updatedTo, version)
+		}
+		service.UpdateManager = um
+		setServiceUpdating(service, "2.24.5")
+
+		queueUpdate(t, service, "2.25.0")
+		last := queueUpdate(t, service, "2.26.0")
+		resetServiceFromUpdate(service)
+
+		job := waitForJob(t, service, last.ID)
+		tests.H(t).StringEql(job.State, JobDone)
+		tests.H(t).InterfaceEql(updatedTo, []string{"2.25.0", "2.26.0"})
+		response := getOperations(t, service)
+		tests.H(t).IntEql(len(response.Queued), 0)
+		tests.H(t).IntEql(len(response.Recent), 2)
+	})
+
+	t.Run("fails a queued update if the ui got pinned meanwhile", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		updated := false
+		um := UpdateManagerDouble()
+		um.UpdateCall = func(string) {
+			updated = true
+		}
+		service.UpdateManager = um
+		service.ClusterPin = &fakeClusterPin{Active: &VersionPin{Version: "2.24.4"}}
+		setServiceUpdating(service, "2.24.4")
+
+		queued := queueUpdate(t, service, "2.25.0")
+		resetServiceFromUpdate(service)
+
+		job := waitForJob(t, service, queued.ID)
+		tests.H(t).StringEql(job.State, JobFailed)
+		tests.H(t).StringContains(job.Error, "pinned to 2.24.4")
+		tests.H(t).BoolEql(updated, false)
+	})
+
+	t.Run("cancels the queued updates once the service shuts down", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		updated := false
+		um := UpdateManagerDouble()
+		um.UpdateCall = func(string) {
+			updated = true
+		}
+		service.UpdateManager = um
+		setServiceUpdating(service, "2.24.5")
+
+		first := queueUpdate(t, service, "2.25.0")
+		second := queueUpdate(t, service, "2.26.0")
+		service.Lock()
+		service.shuttingDown = true
+		service.Unlock()
+		resetServiceFromUpdate(service)
+
+		deadline := time.Now().Add(5 * time.Second)
+		job, _ := service.updateJobs().get(second.ID)
+		for job.State != JobCancelled && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+			job, _ = service.updateJobs().get(second.ID)
+		}
+		tests.H(t).StringEql(job.State, JobCancelled)
+		tests.H(t).StringEql(job.Error, ErrShuttingDown.Error())
+		job, _ = service.updateJobs().get(first.ID)
+		tests.H(t).StringEql(job.State, JobCancelled)
+		tests.H(t).IntEql(service.updateQueue().Len(), 0)
+		tests.H(t).BoolEql(updated, false)
+	})
+
+	t.Run("requires async", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/update/2.25.0/?queue=true", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusBadRequest)
+	})
+}
+
+func TestDequeueHandler(t *testing.T) {
+	t.Run("removes a queued update", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.24.5")
+		defer resetServiceFromUpdate(service)
+		defer drainQueue(service)
+		queued := queueUpdate(t, service, "2.25.0")
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/operations/"+queued.ID+"/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var job UpdateJob
+		tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &job))
+		tests.H(t).StringEql(job.State, JobCancelled)
+		tests.H(t).IntEql(len(getOperations(t, service).Queued), 0)
+	})
+
+	t.Run("returns 409 for a started update", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		job := service.updateJobs().create("2.25.0")
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/operations/"+job.ID+"/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+	})
+
+	t.Run("returns 404 for an unknown id", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("DELETE", "/api/v1/operations/unknown/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusNotFound)
+	})
+}
+
+func TestEstimateStarts(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	results := []ClusterOperationResult{
+		{Operation: OperationUpdate, StartedAt: now.Add(-time.Hour), FinishedAt: now.Add(-time.Hour + time.Minute)},
+		{Operation: OperationUpdate, StartedAt: now.Add(-time.Hour), FinishedAt: now.Add(-time.Hour + 3*time.Minute)},
+		{Operation: OperationReset, StartedAt: now.Add(-time.Hour), FinishedAt: now},
+		{Operation: OperationUpdate, StartedAt: now.Add(-time.Hour), FinishedAt: now, Error: "failed"},
+	}
+	queued := []QueuedOperation{{ID: "a"}, {ID: "b"}}
+
+	estimateStarts(queued, &InFlightOperation{Since: now.Add(-time.Minute)}, averageUpdateDuration(results), now)
+
+	tests.H(t).BoolEql(queued[0].EstimatedStart.Equal(now.Add(time.Minute)), true)
+	tests.H(t).BoolEql(queued[1].EstimatedStart.Equal(now.Add(3*time.Minute)), true)
+}
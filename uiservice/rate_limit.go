@@ -0,0 +1,177 @@
+package uiservice
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/metrics"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// rateLimiterSweepInterval is how often the buckets of clients that stopped sending requests are dropped
+const rateLimiterSweepInterval = time.Minute
+
+var (
+	rateLimitedRequests = metrics.DefaultRegistry.Counter(
+		"ui_update_api_rate_limited_total",
+		"Number of API requests rejected with 429 because a client exceeded api-rate-limit.",
+	)
+	oversizedRequests = metrics.DefaultRegistry.Counter(
+		"ui_update_api_oversized_requests_total",
+		"Number of API requests rejected with 413 because their body exceeded api-max-request-body.",
+	)
+)
+
+// tokenBucket holds the tokens of a client at the time of its last request
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a token bucket per client, each bucket holds up to burst tokens and is refilled with
+// rate tokens per second. A request takes one token.
+type rateLimiter struct {
+	rate      float64
+	burst     float64
+	clock     clock.Clock
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	// trustedProxy identifies the local clients by X-Forwarded-For, see requestClient
+	trustedProxy bool
+	sync.Mutex
+}
+
+func newRateLimiter(rate float64, burst int, clk clock.Clock) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:      rate,
+		burst:     float64(burst),
+		clock:     clk,
+		buckets:   map[string]*tokenBucket{},
+		lastSweep: clk.Now(),
+	}
+}
+
+// take takes a token of client, if there is none it returns how long the client has to wait for one
+func (l *rateLimiter) take(client string) (bool, time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	now := l.clock.Now()
+	l.sweep(now)
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep drops the buckets that were refilled completely, the limiter must be locked
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimiterSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for client, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// requestClient identifies the client of r by the address of its connection, all clients of the unix socket
+// and loopback share one key. Behind a trusted proxy, like Admin Router, the local requests are identified by
+// the last X-Forwarded-For address, which the proxy appended. Without a proxy the header is set by the client
+// and ignored.
+func requestClient(r *http.Request, trustedProxy bool) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); (ip != nil && !ip.IsLoopback()) || !trustedProxy {
+		return host
+	}
+	forwarded := r.Header.Get("X-Forwarded-For")
+	if i := strings.LastIndex(forwarded, ","); i >= 0 {
+		forwarded = forwarded[i+1:]
+	}
+	if forwarded = strings.TrimSpace(forwarded); forwarded != "" {
+		return forwarded
+	}
+	return host
+}
+
+// retryAfterSeconds rounds wait up to whole seconds for the Retry-After header
+func retryAfterSeconds(wait time.Duration) int {
+	if wait < time.Second {
+		return 1
+	}
+	return int((wait + time.Second - 1) / time.Second)
+}
+
+// apiRateLimit rejects API requests of clients that ran out of tokens with 429 and a Retry-After header,
+// so a client retrying in a tight loop cannot keep the service busy with Cosmos requests and downloads.
+// The ui files and the health endpoint are not limited.
+func apiRateLimit(limiter *rateLimiter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == healthPath {
+				next.ServeHTTP(w, r)
+				return
+			}
+			client := requestClient(r, limiter.trustedProxy)
+			if ok, wait := limiter.take(client); !ok {
+				rateLimitedRequests.Inc()
+				logrus.WithFields(logrus.Fields{
+					"method": r.Method,
+					"path":   r.URL.Path,
+					"client": client,
+				}).Warn("Rejected request exceeding the API rate limit")
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(wait)))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// apiRequestBodyLimit rejects API requests announcing a body larger than max with 413 and stops reading
+// longer bodies at max, so the handlers decoding them fail
+func apiRequestBodyLimit(max int64) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") || r.Body == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.ContentLength > max {
+				oversizedRequests.Inc()
+				logrus.WithFields(logrus.Fields{
+					"method": r.Method,
+					"path":   r.URL.Path,
+					"size":   r.ContentLength,
+				}).Warn("Rejected request exceeding the maximum body size")
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, max)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package uiservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("allows a burst and refills the bucket over time", func(t *testing.T) {
+		clk := clock.NewFake(time.Now())
+		limiter := newRateLimiter(2, 3, clk)
+
+		for i := 0; i < 3; i++ {
+			ok, _ := limiter.take("10.0.0.1")
+			tests.H(t).BoolEql(ok, true)
+		}
+		ok, wait := limiter.take("10.0.0.1")
+		tests.H(t).BoolEql(ok, false)
+		tests.H(t).Int64Eql(int64(wait), int64(500*time.Millisecond))
+
+		clk.Advance(500 * time.Millisecond)
+		ok, _ = limiter.take("10.0.0.1")
+		tests.H(t).BoolEql(ok, true)
+	})
+
+	t.Run("keeps a bucket per client", func(t *testing.T) {
+		limiter := newRateLimiter(1, 1, clock.NewFake(time.Now()))
+
+		first, _ := limiter.take("10.0.0.1")
+		second, _ := limiter.take("10.0.0.2")
+
+		tests.H(t).BoolEql(first, true)
+		tests.H(t).BoolEql(second, true)
+	})
+
+	t.Run("drops the buckets of idle clients", func(t *testing.T) {
+		clk := clock.NewFake(time.Now())
+		limiter := newRateLimiter(1, 1, clk)
+		limiter.take("10.0.0.1")
+
+		clk.Advance(rateLimiterSweepInterval)
+		limiter.take("10.0.0.2")
+
+		tests.H(t).IntEql(len(limiter.buckets), 1)
+	})
+}
+
+func TestRequestClient(t *testing.T) {
+	t.Run("identifies remote clients by their address", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/version/", nil)
+		req.RemoteAddr = "10.0.0.1:51234"
+		req.Header.Set("X-Forwarded-For", "10.9.9.9")
+
+		tests.H(t).StringEql(requestClient(req, true), "10.0.0.1")
+	})
+
+	t.Run("identifies clients behind a trusted proxy by the address the proxy appended", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/version/", nil)
+		req.RemoteAddr = "@"
+		req.Header.Set("X-Forwarded-For", "10.9.9.9, 10.0.0.1")
+
+		tests.H(t).StringEql(requestClient(req, true), "10.0.0.1")
+	})
+
+	t.Run("ignores X-Forwarded-For of local clients without a trusted proxy", func(t *testing.T) {
+		for _, remoteAddr := range []string{"@", "127.0.0.1:51234"} {
+			req := httptest.NewRequest("GET", "/api/v1/version/", nil)
+			req.RemoteAddr = remoteAddr
+			req.Header.Set("X-Forwarded-For", "10.0.0.1")
+			first := requestClient(req, false)
+			req.Header.Set("X-Forwarded-For", "10.0.0.2")
+
+			tests.H(t).StringEql(requestClient(req, false), first)
+			tests.H(t).BoolEql(strings.HasPrefix(first, "10."), false)
+		}
+	})
+}
+
+func TestAPIRateLimit(t *testing.T) {
+	t.Run("rejects requests exceeding the burst with 429 and Retry-After", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupAPIAuthUIService(t, "--api-rate-limit", "0.5", "--api-rate-burst", "2")
+		router := newRouter(service)
+
+		var rr *httptest.ResponseRecorder
+		for i := 0; i < 3; i++ {
+			rr = httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/operations/", nil))
+		}
+
+		tests.H(t).IntEql(rr.Code, http.StatusTooManyRequests)
+		tests.H(t).StringEql(rr.Header().Get("Retry-After"), "2")
+	})
+
+	t.Run("does not limit the health endpoint", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupAPIAuthUIService(t, "--api-rate-limit", "0.5", "--api-rate-burst", "1")
+		router := newRouter(service)
+
+		for i := 0; i < 3; i++ {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/health/", nil))
+			tests.H(t).IntEql(rr.Code, http.StatusOK)
+		}
+	})
+}
+
+func TestAPIRequestBodyLimit(t *testing.T) {
+	t.Run("rejects bodies larger than the limit with 413", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupAPIAuthUIService(t, "--api-max-request-body", "16")
+
+		rr := httptest.NewRecorder()
+		body := strings.NewReader(`{"version":"a-version-with-a-long-name"}`)
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/state/import/", body))
+
+		tests.H(t).IntEql(rr.Code, http.StatusRequestEntityTooLarge)
+	})
+}
@@ -0,0 +1,63 @@
+package uiservice
+
+import (
+	"github.com/dcos/dcos-ui-update-service/metrics"
+	"github.com/dcos/dcos-ui-update-service/scheduler"
+	"github.com/sirupsen/logrus"
+)
+
+var versionDrifts = metrics.DefaultRegistry.Counter(
+	"ui_update_version_drifts_total",
+	"Number of times the reconciler synced this master because its served version differed from the stored version.",
+)
+
+// versionDrift is a served version differing from the stored version
+type versionDrift struct {
+	stored string
+	served string
+}
+
+// reconcileJob syncs a master that missed a version change, e.g. while it was down or ZK flapped, to the
+// stored version. A drift is only acted on once two runs in a row saw it, so a version change that is being
+// delivered is not synced twice. It is skipped while the served version is changing, e.g. while a new
+// version soaks on this master, and while syncs are frozen.
+func reconcileJob(service *UIService) scheduler.JobFunc {
+	var last *versionDrift
+	return func() error {
+		service.Lock()
+		updating := service.updating
+		service.Unlock()
+		if updating {
+			last = nil
+			return nil
+		}
+		if freeze, err := activeFreeze(service); err != nil || freeze != nil {
+			last = nil
+			return err
+		}
+		stored, err := service.VersionStore.CurrentVersion()
+		if err != nil {
+			return err
+		}
+		served, err := service.UpdateManager.CurrentVersion()
+		if err != nil {
+			return err
+		}
+		drift := &versionDrift{stored: string(stored), served: served}
+		if drift.stored == drift.served {
+			last = nil
+			return nil
+		}
+		if last == nil || *last != *drift {
+			logrus.WithFields(logrus.Fields{"storedVersion": drift.stored, "servedVersion": drift.served}).Debug("Served version differs from the stored version, checking again on the next run")
+			last = drift
+			return nil
+		}
+
+		last = nil
+		versionDrifts.Inc()
+		logrus.WithFields(logrus.Fields{"storedVersion": drift.stored, "servedVersion": drift.served}).Warn("Served version drifted from the stored version, syncing to the stored version")
+		handleVersionChange(service, drift.stored)
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package uiservice
+
+import (
+	"os"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func setupDriftedService() (*UIService, *fakeUpdateManager, *[]string) {
+	service := setupTestUIService()
+	store := VersionStoreDouble()
+	store.VersionResult = "2.25.0"
+	service.VersionStore = store
+	um := UpdateManagerDouble()
+	um.VersionResult = "2.24.4"
+	um.UpdateNewVersionPath = "../testdata/uiserv-sandbox/ui-versions/2.25.0/dist"
+	synced := &[]string{}
+	um.UpdateCall = func(version string) {
+		os.MkdirAll(um.UpdateNewVersionPath, 0755)
+		*synced = append(*synced, version)
+	}
+	service.UpdateManager = um
+	return service, um, synced
+}
+
+func TestReconcileJob(t *testing.T) {
+	t.Run("syncs to the stored version once the drift was seen twice", func(t *testing.T) {
+		defer tearDown(t)
+		service, _, synced := setupDriftedService()
+		job := reconcileJob(service)
+
+		tests.H(t).IsNil(job())
+		tests.H(t).IntEql(len(*synced), 0)
+
+		tests.H(t).IsNil(job())
+		tests.H(t).InterfaceEql(*synced, []string{"2.25.0"})
+	})
+
+	t.Run("does nothing while the served version matches the stored version", func(t *testing.T) {
+		defer tearDown(t)
+		service, um, synced := setupDriftedService()
+		um.VersionResult = "2.25.0"
+		job := reconcileJob(service)
+
+		job()
+		job()
+
+		tests.H(t).IntEql(len(*synced), 0)
+	})
+
+	t.Run("starts over if the drift changed between the runs", func(t *testing.T) {
+		defer tearDown(t)
+		service, um, synced := setupDriftedService()
+		job := reconcileJob(service)
+
+		job()
+		um.VersionResult = "2.23.0"
+		job()
+
+		tests.H(t).IntEql(len(*synced), 0)
+	})
+
+	t.Run("is skipped while the served version is changing", func(t *testing.T) {
+		defer tearDown(t)
+		service, _, synced := setupDriftedService()
+		job := reconcileJob(service)
+
+		job()
+		setServiceUpdating(service, "2.26.0")
+		job()
+		resetServiceFromUpdate(service)
+		job()
+
+		tests.H(t).IntEql(len(*synced), 0)
+	})
+}
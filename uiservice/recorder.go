@@ -0,0 +1,169 @@
+package uiservice
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// recordedBodyLimit is the maximum number of body bytes kept for a recorded request or response
+	recordedBodyLimit = 2048
+	redactedValue     = "<redacted>"
+	diagnosticsPath   = "/api/v1/diagnostics/"
+)
+
+var (
+	redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Auth-Token"}
+	// a secret cut off at the end of the recorded body has no closing quote
+	secretBodyRe = regexp.MustCompile(`(?i)("(?:password|secret|token|private_key|uid_secret)"\s*:\s*)"[^"]*(?:"|$)`)
+)
+
+type recordedExchange struct {
+	Time            time.Time   `json:"time"`
+	Method          string      `json:"method"`
+	URI             string      `json:"uri"`
+	RequestHeaders  http.Header `json:"requestHeaders"`
+	RequestBody     string      `json:"requestBody"`
+	Status          int         `json:"status"`
+	ResponseHeaders http.Header `json:"responseHeaders"`
+	ResponseBody    string      `json:"responseBody"`
+	Duration        string      `json:"duration"`
+}
+
+// requestRecorder keeps the last N API exchanges in a ring buffer
+type requestRecorder struct {
+	exchanges []recordedExchange
+	next      int
+	full      bool
+	sync.Mutex
+}
+
+// newRequestRecorder creates a recorder keeping size exchanges, returns nil if size is not positive
+func newRequestRecorder(size int) *requestRecorder {
+	if size <= 0 {
+		return nil
+	}
+	return &requestRecorder{
+		exchanges: make([]recordedExchange, size),
+	}
+}
+
+func (rec *requestRecorder) record(exchange recordedExchange) {
+	rec.Lock()
+	defer rec.Unlock()
+
+	rec.exchanges[rec.next] = exchange
+	rec.next = (rec.next + 1) % len(rec.exchanges)
+	if rec.next == 0 {
+		rec.full = true
+	}
+}
+
+// Exchanges returns the recorded exchanges, oldest first
+func (rec *requestRecorder) Exchanges() []recordedExchange {
+	if rec == nil {
+		return []recordedExchange{}
+	}
+	rec.Lock()
+	defer rec.Unlock()
+
+	if !rec.full {
+		result := make([]recordedExchange, rec.next)
+		copy(result, rec.exchanges[:rec.next])
+		return result
+	}
+	result := make([]recordedExchange, 0, len(rec.exchanges))
+	result = append(result, rec.exchanges[rec.next:]...)
+	return append(result, rec.exchanges[:rec.next]...)
+}
+
+func (rec *requestRecorder) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, diagnosticsPath) {
+			// Don't let reading the diagnostics evict the requests we want to look at
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var requestBody []byte
+		if r.Body != nil {
+			// only the recorded part of the body is buffered, large uploads are still streamed to the handler. One
+			// byte more than recorded is read, so redactBody sees if a secret continues past the limit.
+			requestBody, _ = ioutil.ReadAll(io.LimitReader(r.Body, recordedBodyLimit+1))
+			r.Body = &replayedBody{Reader: io.MultiReader(bytes.NewReader(requestBody), r.Body), Closer: r.Body}
+		}
+
+		start := time.Now()
+		rw := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		rec.record(recordedExchange{
+			Time:            start,
+			Method:          r.Method,
+			URI:             r.RequestURI,
+			RequestHeaders:  redactHeaders(r.Header),
+			RequestBody:     redactBody(requestBody),
+			Status:          rw.status,
+			ResponseHeaders: redactHeaders(w.Header()),
+			ResponseBody:    redactBody(rw.body.Bytes()),
+			Duration:        time.Since(start).String(),
+		})
+	})
+}
+
+// replayedBody is a request body whose recorded start is read again before the rest
+type replayedBody struct {
+	io.Reader
+	io.Closer
+}
+
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingResponseWriter) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+	if remaining := recordedBodyLimit + 1 - rw.body.Len(); remaining > 0 {
+		if len(b) > remaining {
+			rw.body.Write(b[:remaining])
+		} else {
+			rw.body.Write(b)
+		}
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+func redactHeaders(headers http.Header) http.Header {
+	result := make(http.Header, len(headers))
+	for key, values := range headers {
+		result[key] = append([]string(nil), values...)
+	}
+	for _, key := range redactedHeaders {
+		if _, found := result[http.CanonicalHeaderKey(key)]; found {
+			result.Set(key, redactedValue)
+		}
+	}
+	return result
+}
+
+// redactBody replaces the secrets of the captured body before it is truncated to recordedBodyLimit, so a secret
+// crossing the limit is not kept in clear
+func redactBody(body []byte) string {
+	redacted := secretBodyRe.ReplaceAllString(string(body), `${1}"`+redactedValue+`"`)
+	if len(redacted) > recordedBodyLimit {
+		redacted = redacted[:recordedBodyLimit]
+	}
+	return redacted
+}
@@ -0,0 +1,112 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestRequestRecorder(t *testing.T) {
+	t.Run("returns nil recorder if size is zero", func(t *testing.T) {
+		tests.H(t).BoolEql(newRequestRecorder(0) == nil, true)
+	})
+
+	t.Run("keeps only the last N exchanges, oldest first", func(t *testing.T) {
+		rec := newRequestRecorder(2)
+		rec.record(recordedExchange{URI: "/1"})
+		rec.record(recordedExchange{URI: "/2"})
+		rec.record(recordedExchange{URI: "/3"})
+
+		exchanges := rec.Exchanges()
+		tests.H(t).IntEql(len(exchanges), 2)
+		tests.H(t).StringEql(exchanges[0].URI, "/2")
+		tests.H(t).StringEql(exchanges[1].URI, "/3")
+	})
+
+	t.Run("redacts secret headers and body fields", func(t *testing.T) {
+		rec := newRequestRecorder(5)
+		handler := rec.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"token":"abc123"}`))
+		}))
+
+		req := httptest.NewRequest("POST", "/api/v1/update/2.24.4/", strings.NewReader(`{"password": "hunter2"}`))
+		req.Header.Set("Authorization", "token=secret")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		exchanges := rec.Exchanges()
+		tests.H(t).IntEql(len(exchanges), 1)
+		tests.H(t).IntEql(exchanges[0].Status, http.StatusInternalServerError)
+		tests.H(t).StringEql(exchanges[0].RequestHeaders.Get("Authorization"), redactedValue)
+		tests.H(t).StringEql(exchanges[0].RequestBody, `{"password": "<redacted>"}`)
+		tests.H(t).StringEql(exchanges[0].ResponseBody, `{"token":"<redacted>"}`)
+	})
+
+	t.Run("truncates large bodies", func(t *testing.T) {
+		rec := newRequestRecorder(1)
+		handler := rec.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(strings.Repeat("a", recordedBodyLimit+10)))
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/version/", nil))
+
+		tests.H(t).IntEql(len(rec.Exchanges()[0].ResponseBody), recordedBodyLimit)
+	})
+	t.Run("passes the whole request body to the handler", func(t *testing.T) {
+		rec := newRequestRecorder(1)
+		var received int
+		handler := rec.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			received = len(body)
+		}))
+		body := strings.Repeat("a", 3*recordedBodyLimit)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/update/", strings.NewReader(body)))
+
+		tests.H(t).IntEql(received, len(body))
+		tests.H(t).IntEql(len(rec.Exchanges()[0].RequestBody), recordedBodyLimit)
+	})
+
+	t.Run("redacts secrets crossing the body limit", func(t *testing.T) {
+		prefix := `{"padding":"` + strings.Repeat("a", recordedBodyLimit-40) + `","password":"`
+		for _, secret := range []string{"s3cret-crossing-the-limit", strings.Repeat("s", 2*recordedBodyLimit)} {
+			rec := newRequestRecorder(1)
+			handler := rec.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ioutil.ReadAll(r.Body)
+				w.Write([]byte(prefix + secret + `"}`))
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/update/", strings.NewReader(prefix+secret+`"}`)))
+
+			exchange := rec.Exchanges()[0]
+			for _, recorded := range []string{exchange.RequestBody, exchange.ResponseBody} {
+				tests.H(t).BoolEql(strings.Contains(recorded, "s3cret") || strings.Contains(recorded, "sss"), false)
+				tests.H(t).StringContains(recorded, `"password":"<redacted>"`)
+				tests.H(t).BoolEql(len(recorded) <= recordedBodyLimit, true)
+			}
+		}
+	})
+}
+
+func TestDiagnosticsHandler(t *testing.T) {
+	t.Run("lists recorded requests", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.recorder = newRequestRecorder(10)
+		router := newRouter(service)
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/version/", nil))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/diagnostics/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var response diagnosticsResponse
+		err := json.Unmarshal(rr.Body.Bytes(), &response)
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(response.RecordingEnabled, true)
+		tests.H(t).IntEql(len(response.Requests), 1)
+		tests.H(t).StringEql(response.Requests[0].URI, "/api/v1/version/")
+	})
+}
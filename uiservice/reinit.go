@@ -0,0 +1,105 @@
+package uiservice
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	"github.com/sirupsen/logrus"
+)
+
+// zkReinit staggers the ZK reads of the masters after their sessions are re-established. Each master waits
+// its random offset below the jitter before it reads and watches the version node, and the jitter plus its
+// offset before the status nodes, so the version reads of all masters come first.
+type zkReinit struct {
+	jitter time.Duration
+	offset time.Duration
+	// connected is set once the first connection was initialized, it is not delayed
+	connected bool
+	// generation counts the connections, a delayed initialization stops once a newer connection started
+	generation int
+	sync.Mutex
+}
+
+// randomReinitOffset picks the offset of this master below jitter
+func randomReinitOffset(jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return 0
+	}
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return time.Duration(random.Int63n(int64(jitter)))
+}
+
+// versionDelay is the delay before the version node is read and watched again after a reconnect
+func (r *zkReinit) versionDelay() time.Duration {
+	return r.offset
+}
+
+// statusDelay is the delay before the access check, the node registration and the trigger watch after a reconnect
+func (r *zkReinit) statusDelay() time.Duration {
+	if r.jitter <= 0 {
+		return 0
+	}
+	return r.jitter + r.offset
+}
+
+// begin starts the initialization of a connection, it returns its generation and if it is a reconnect
+func (r *zkReinit) begin() (int, bool) {
+	r.Lock()
+	defer r.Unlock()
+	reconnect := r.connected
+	r.connected = true
+	r.generation++
+	return r.generation, reconnect
+}
+
+func (r *zkReinit) current(generation int) bool {
+	r.Lock()
+	defer r.Unlock()
+	return r.generation == generation
+}
+
+// initializeConnection reads and watches the ZK nodes once the connection is established. After a reconnect
+// the version node is read after versionDelay and the status nodes after statusDelay.
+func (zks *zkVersionStore) initializeConnection() {
+	generation, reconnect := zks.reinit.begin()
+	var versionDelay, statusDelay time.Duration
+	if reconnect {
+		versionDelay, statusDelay = zks.reinit.versionDelay(), zks.reinit.statusDelay()
+		log.WithFields(logrus.Fields{
+			"versionDelay": versionDelay.String(),
+			"statusDelay":  statusDelay.String(),
+		}).Info("Re-initializing ZK nodes after reconnect")
+	}
+
+	if !zks.waitReinit(generation, versionDelay) {
+		return
+	}
+	zks.initCurrentVersion()
+	if zks.readOnly {
+		// a replica neither registers as a master nor joins the leader election
+		return
+	}
+
+	if !zks.waitReinit(generation, statusDelay-versionDelay) {
+		return
+	}
+	zks.verifyAccess()
+	if err := zks.registerCurrentNode(); err != nil {
+		log.WithError(err).Warn("Failed to restore node registration after connecting")
+	}
+	zks.joinElection()
+}
+
+// waitReinit waits delay, it returns false if the connection was lost or re-established meanwhile
+func (zks *zkVersionStore) waitReinit(generation int, delay time.Duration) bool {
+	if delay > 0 {
+		<-zks.clock.After(delay)
+	}
+	if !zks.reinit.current(generation) || zks.client.ClientState() != zookeeper.Connected {
+		log.Debug("ZK connection changed while waiting to re-initialize, skipping")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package uiservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+)
+
+func makeReconnectingZKStore() (*zkVersionStore, *zookeeper.FakeZKClient, *clock.Fake) {
+	store, client := makeZKStore("1.0.0")
+	client.ExistsResult = true
+	client.GetResult = []byte("2.25.0")
+	clk := clock.NewFake(time.Now())
+	store.clock = clk
+	store.reinit.jitter = 10 * time.Second
+	store.reinit.offset = 2 * time.Second
+	return store, client, clk
+}
+
+func accessChecks(client *zookeeper.FakeZKClient) int {
+	client.Lock()
+	defer client.Unlock()
+	return len(client.SequentialCreated)
+}
+
+func TestZKReinit(t *testing.T) {
+	t.Run("initializes the first connection right away", func(t *testing.T) {
+		store, client, _ := makeReconnectingZKStore()
+		defer store.Close()
+
+		store.initializeConnection()
+
+		tests.H(t).StringEql(string(store.localVersion()), "2.25.0")
+		tests.H(t).IntEql(accessChecks(client), 1)
+	})
+
+	t.Run("reads the version node and then the status nodes after a reconnect", func(t *testing.T) {
+		store, client, clk := makeReconnectingZKStore()
+		defer store.Close()
+		store.reinit.connected = true
+		done := make(chan struct{})
+
+		go func() {
+			store.initializeConnection()
+			close(done)
+		}()
+
+		clk.BlockUntil(1)
+		tests.H(t).StringEql(string(store.localVersion()), "1.0.0")
+		clk.Advance(2 * time.Second)
+		// the status delay starts once the version node was read
+		clk.BlockUntil(1)
+		tests.H(t).StringEql(string(store.localVersion()), "2.25.0")
+		tests.H(t).IntEql(accessChecks(client), 0)
+		clk.Advance(10 * time.Second)
+		<-done
+		tests.H(t).IntEql(accessChecks(client), 1)
+	})
+
+	t.Run("stops a delayed initialization once the connection is re-established again", func(t *testing.T) {
+		store, client, clk := makeReconnectingZKStore()
+		defer store.Close()
+		store.reinit.connected = true
+		done := make(chan struct{})
+
+		go func() {
+			store.initializeConnection()
+			close(done)
+		}()
+
+		clk.BlockUntil(1)
+		store.reinit.begin()
+		clk.Advance(2 * time.Second)
+		<-done
+
+		tests.H(t).StringEql(string(store.localVersion()), "1.0.0")
+		tests.H(t).IntEql(accessChecks(client), 0)
+	})
+
+	t.Run("picks an offset below the jitter", func(t *testing.T) {
+		offset := randomReinitOffset(time.Second)
+
+		tests.H(t).BoolEql(offset >= 0 && offset < time.Second, true)
+		tests.H(t).Int64Eql(int64(randomReinitOffset(0)), 0)
+	})
+}
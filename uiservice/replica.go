@@ -0,0 +1,27 @@
+package uiservice
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrReadOnlyReplica occurs if a replica is asked to change the version, only the masters store versions
+var ErrReadOnlyReplica = errors.New("This node is a read-only replica, send the request to a master")
+
+// replicaReadOnly rejects the mutating API requests with 403 on a replica, the ui files and reading
+// routes are served as on a master
+func replicaReadOnly() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !mutatingMethod(r.Method) || notAPIPath(r, nil) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			logrus.WithFields(logrus.Fields{"method": r.Method, "path": r.URL.Path}).Warn("Rejected mutating request on a replica")
+			http.Error(w, ErrReadOnlyReplica.Error(), http.StatusForbidden)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package uiservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/scheduler"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+)
+
+func setupReplicaService(extraArgs ...string) *UIService {
+	service := setupTestUIService()
+	args := append([]string{
+		"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+		"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+		"--ui-dist-stage-symlink", "../testdata/uiserv-sandbox/new-dcos-ui-dist",
+		"--role", "replica",
+	}, extraArgs...)
+	service.Config, _ = config.Parse(args)
+	return service
+}
+
+func TestReplicaAPI(t *testing.T) {
+	t.Run("rejects mutating requests with 403", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupReplicaService()
+		um := UpdateManagerDouble()
+		updated := false
+		um.UpdateCall = func(string) { updated = true }
+		service.UpdateManager = um
+		router := newRouter(service)
+
+		for _, req := range []*http.Request{
+			httptest.NewRequest("POST", "/api/v1/update/2.25.0/", nil),
+			httptest.NewRequest("DELETE", "/api/v1/reset/", nil),
+			httptest.NewRequest("POST", "/api/v1/pin/2.25.0/", nil),
+		} {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			tests.H(t).IntEql(rr.Code, http.StatusForbidden)
+			tests.H(t).StringContains(rr.Body.String(), ErrReadOnlyReplica.Error())
+		}
+		tests.H(t).BoolEql(updated, false)
+	})
+
+	t.Run("answers reading requests", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupReplicaService()
+		service.UpdateManager = UpdateManagerDouble()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/version/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+	})
+}
+
+func TestReplicaMaintenanceJobs(t *testing.T) {
+	t.Run("does not update automatically or heartbeat as a master", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupReplicaService("--auto-update-check-interval", "1h")
+		service.Scheduler = scheduler.New()
+
+		registerMaintenanceJobs(service)
+
+		for _, status := range service.Scheduler.Status() {
+			tests.H(t).BoolEqlWithMessage(status.Name != jobAutoUpdate && status.Name != jobNodeHeartbeat, true, status.Name)
+		}
+	})
+}
+
+func TestReplicaZKVersionStore(t *testing.T) {
+	t.Run("never writes to ZK", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		store.client = nil
+		store.readOnly = true
+		store.advertiseURL = "http://10.0.0.3:5000"
+		var written []string
+		client.CreateCall = func(path string, data []byte, perms []int32) { written = append(written, path) }
+		client.CreateEphemeralCall = func(path string, data []byte, perms []int32) { written = append(written, path) }
+		client.SetVersionedCall = func(path string, data []byte, version int32) { written = append(written, path) }
+		client.ClientStateResult = zookeeper.Disconnected
+		store.initZKVersionStore(client)
+		client.ClientStateResult = zookeeper.Connected
+		client.ExistsResult = false
+
+		store.handleZKStateChange(zookeeper.Connected)
+		defer store.handleZKStateChange(zookeeper.Disconnected)
+
+		tests.H(t).ErrEql(store.UpdateCurrentVersion("2.25.0"), ErrReadOnlyReplica)
+		tests.H(t).IntEql(len(written), 0)
+		tests.H(t).IntEql(len(client.SequentialCreated), 0)
+		cv, _ := store.CurrentVersion()
+		tests.H(t).StringEql(string(cv), string(PreBundledUIVersion))
+	})
+}
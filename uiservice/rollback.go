@@ -0,0 +1,108 @@
+package uiservice
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const previousVersionNode = "previous-version"
+
+var (
+	// ErrNoPreviousVersion occurs if a rollback is requested before a version was replaced
+	ErrNoPreviousVersion = errors.New("No previous version was recorded")
+)
+
+func makePreviousVersionPath(basePath string) string {
+	return path.Join(basePath, previousVersionNode)
+}
+
+// setPreviousVersion stores the version replaced by the last version write
+func (zks *zkVersionStore) setPreviousVersion(version UIVersion) error {
+	previousPath := makePreviousVersionPath(zks.zkBasePath)
+	err := zks.client.Create(previousPath, []byte(version), zookeeper.PermAll)
+	if err == zookeeper.ErrNodeExists {
+		_, err = zks.client.Set(previousPath, []byte(version))
+	}
+	return err
+}
+
+// PreviousVersion reads the version recorded by setPreviousVersion
+func (zks *zkVersionStore) PreviousVersion() (UIVersion, bool, error) {
+	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
+		return PreBundledUIVersion, false, ErrZookeeperNotConnected
+	}
+	previousPath := makePreviousVersionPath(zks.zkBasePath)
+	exists, _, err := zks.client.Exists(previousPath)
+	if err != nil {
+		return PreBundledUIVersion, false, errors.Wrap(err, "Failed to check for the previous version")
+	}
+	if !exists {
+		return PreBundledUIVersion, false, nil
+	}
+	data, _, err := zks.client.Get(previousPath)
+	if err != nil {
+		return PreBundledUIVersion, false, errors.Wrap(err, "Failed to read the previous version")
+	}
+	return UIVersion(data), true, nil
+}
+
+// rollbackHandler updates the cluster to the version stored before the current one. Kept versions are
+// activated without downloading them again, see --versions-to-keep.
+func rollbackHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		history, ok := service.VersionStore.(VersionHistory)
+		if !ok {
+			http.Error(w, "The version store does not record previous versions", http.StatusNotImplemented)
+			return
+		}
+		previous, found, err := history.PreviousVersion()
+		switch {
+		case err == ErrZookeeperNotConnected:
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		case err != nil:
+			logrus.WithError(err).Error("Failed to read the previous version")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		case !found:
+			http.Error(w, ErrNoPreviousVersion.Error(), http.StatusConflict)
+			return
+		case previous == PreBundledUIVersion:
+			http.Error(w, "The previous version is the pre-bundled ui, reset to roll back to it", http.StatusConflict)
+			return
+		}
+		version := string(previous)
+		logrus.WithField("version", version).Debug("Received rollback request.")
+
+		if updatingVersion, lockErr := setServiceUpdating(service, version); lockErr != nil {
+			http.Error(
+				w,
+				fmt.Sprintf("Cannot roll back, an update to %s is currently in progress", updatingVersion),
+				http.StatusConflict,
+			)
+			return
+		}
+		markPreemptible(service, OperationRollback)
+		op := newClusterOperation(service, OperationRollback, version)
+		if rejectIfStale(service, w, r) || rejectIfFrozen(service, w) || rejectIfPinned(service, w, version) || !acquireClusterOperation(service, w, op) {
+			resetServiceFromUpdate(service)
+			return
+		}
+		defer resetServiceFromUpdate(service)
+		defer releaseClusterOperation(service)
+		defer cancelOnTimeout(service, r)()
+		result := newOperationResult(service, op)
+
+		activator := newClusterActivator(service, previous, result)
+		err = updateToVersion(service, version, activator)
+		if err == nil {
+			err = activator.promote()
+		}
+		writeUpdateResult(w, r, result.finish(service, err), err, fmt.Sprintf("Rolled back to %s", version))
+	}
+}
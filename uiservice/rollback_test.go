@@ -0,0 +1,92 @@
+package uiservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+type historyVersionStore struct {
+	*fakeVersionStore
+	previous UIVersion
+	found    bool
+}
+
+func (vs *historyVersionStore) PreviousVersion() (UIVersion, bool, error) {
+	return vs.previous, vs.found, nil
+}
+
+func TestZKVersionStorePreviousVersion(t *testing.T) {
+	t.Run("records the replaced version when setting a new version", func(t *testing.T) {
+		store, client := makeZKStore("2.25.1")
+		client.GetResult = []byte("2.25.1")
+		var createdPath, createdData string
+		client.CreateCall = func(path string, data []byte, perms []int32) {
+			createdPath = path
+			createdData = string(data)
+		}
+
+		tests.H(t).IsNil(store.UpdateCurrentVersion(UIVersion("2.25.2")))
+
+		tests.H(t).StringEql(createdPath, "/dcos/ui-service-test/previous-version")
+		tests.H(t).StringEql(createdData, "2.25.1")
+	})
+
+	t.Run("PreviousVersion() reports no version if none was recorded", func(t *testing.T) {
+		store, client := makeZKStore("2.25.1")
+		client.ExistsResult = false
+
+		_, found, err := store.PreviousVersion()
+
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(found, false)
+	})
+}
+
+func TestRollbackHandler(t *testing.T) {
+	t.Run("updates to the previous version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.VersionStore = &historyVersionStore{fakeVersionStore: VersionStoreDouble(), previous: "2.25.1", found: true}
+		um := UpdateManagerDouble()
+		um.UpdateNewVersionPath = path.Join(service.Config.VersionsRoot(), "2.25.1", "dist")
+		var updatedTo string
+		um.UpdateCall = func(version string) {
+			os.MkdirAll(um.UpdateNewVersionPath, 0755)
+			updatedTo = version
+		}
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/rollback/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringEql(updatedTo, "2.25.1")
+		tests.H(t).StringContains(rr.Body.String(), "Rolled back to 2.25.1")
+	})
+
+	t.Run("returns 409 if no previous version was recorded", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.VersionStore = &historyVersionStore{fakeVersionStore: VersionStoreDouble()}
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/rollback/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+	})
+
+	t.Run("returns 501 if the version store does not record previous versions", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/rollback/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusNotImplemented)
+	})
+}
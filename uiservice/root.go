@@ -1,21 +1,27 @@
 package uiservice
 
 import (
+	"crypto/tls"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
-	"path"
-	"regexp"
 	"sync"
+	"time"
 
+	"github.com/dcos/dcos-ui-update-service/auth"
+	"github.com/dcos/dcos-ui-update-service/clock"
 	"github.com/dcos/dcos-ui-update-service/config"
 	"github.com/dcos/dcos-ui-update-service/dcos"
+	"github.com/dcos/dcos-ui-update-service/logring"
+	"github.com/dcos/dcos-ui-update-service/memory"
+	"github.com/dcos/dcos-ui-update-service/scheduler"
 	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
 	"github.com/gorilla/handlers"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
 )
 
 type UIService struct {
@@ -27,34 +33,168 @@ type UIService struct {
 
 	VersionStore VersionStore
 
+	ClusterStatus ClusterStatus
+
+	ClusterFreeze ClusterFreeze
+
+	ClusterPin ClusterPin
+
+	// Leader is the leader election update and reset requests are forwarded by, nil without advertise-url
+	Leader zookeeper.Leader
+
+	IPCache *dcos.IPCache
+
+	Scheduler *scheduler.Scheduler
+
+	LogRing *logring.Ring
+
 	updating bool
 
 	updatingVersion string
 
+	updatingSince time.Time
+
+	preemptible *preemptibleOperation
+
+	// missedVersion is the latest version received from the version store while the service was locked by
+	// another operation, it is synced once that operation finished
+	missedVersion *string
+
+	recorder *requestRecorder
+
+	jobs *jobStore
+
+	queue *updateQueue
+
+	defaultUI *defaultUIWatcher
+
+	history *operationHistory
+
+	audit *auditLog
+
+	uiFiles *uiFileHandler
+
+	updateCheck *UpdateCheck
+
+	server *http.Server
+
+	certs *certReloader
+
+	apiAuth apiAuthenticator
+
+	taint *DistTaint
+
+	assets assetsManifest
+
+	shuttingDown bool
+
+	// shutdown is closed by Shutdown, a soaking version stops waiting for the soak duration
+	shutdown chan struct{}
+
+	// clock times the soak, the real clock if nil
+	clock clock.Clock
+
 	sync.Mutex
 }
 
 func SetupService(cfg *config.Config) (*UIService, error) {
-	updateManager, err := updatemanager.NewClient(cfg)
+	var credentials *auth.CredentialProvider
+	if cfg.IAMConfig() != "" {
+		var err error
+		if credentials, err = auth.NewCredentialProvider(cfg.IAMConfig()); err != nil {
+			return nil, errors.Wrap(err, "failed to load service account")
+		}
+	}
+	packageSource, err := updatemanager.NewPackageSource(cfg, credentials)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create package source")
+	}
+	fetcher, err := updatemanager.NewBundleFetcher(cfg, afero.NewOsFs(), credentials)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create bundle fetcher")
+	}
+	ipCache := dcos.NewIPCache(func() (net.IP, error) {
+		return dcos.DetectIP(cfg.DetectIPPath(), detectIPTimeout)
+	})
+	versionStore, err := NewVersionStore(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create version store")
+	}
+	return setupService(cfg, packageSource, fetcher, versionStore, ipCache)
+}
+
+// SetupDevService creates a service for local ui development, which lists and fetches versions from
+// source and fetcher and keeps the served version in versionStore. It does not detect the node IP.
+func SetupDevService(cfg *config.Config, source updatemanager.PackageSource, fetcher updatemanager.BundleFetcher, versionStore VersionStore) (*UIService, error) {
+	return setupService(cfg, source, fetcher, versionStore, nil)
+}
+
+func setupService(cfg *config.Config, packageSource updatemanager.PackageSource, fetcher updatemanager.BundleFetcher, versionStore VersionStore, ipCache *dcos.IPCache) (*UIService, error) {
+	memory.DefaultAccountant.SetLimit(cfg.MemoryBudget())
+	updateManager, err := updatemanager.NewClient(cfg, packageSource, fetcher)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create update manager")
 	}
-	dcos := dcos.DCOS{
+	masterCounter := dcos.DCOS{
 		MasterCountLocation: cfg.MasterCountFile(),
 	}
 
-	versionStore := NewZKVersionStore(cfg)
+	coordination := coordinatingStore(versionStore)
+	clusterStatus, _ := coordination.(ClusterStatus)
+	clusterPin, _ := coordination.(ClusterPin)
+	var clusterFreeze ClusterFreeze
+	if cfg.FreezeOnFailure() {
+		clusterFreeze, _ = coordination.(ClusterFreeze)
+	}
+	var leader zookeeper.Leader
+	if cfg.AdvertiseURL() != "" && !cfg.Replica() {
+		leader, _ = coordination.(zookeeper.Leader)
+	}
+	// a replica is not registered as a master, the cluster status only lists the masters
+	if nodeStatus, ok := coordination.(NodeStatus); ok && ipCache != nil && !cfg.Replica() {
+		ipCache.OnChange(func(previous, current net.IP) {
+			if err := nodeStatus.RegisterNode(previous, current); err != nil {
+				logrus.WithError(err).Warn("Failed to register node IP")
+			}
+		})
+	}
 
 	service := &UIService{
 		Config:        cfg,
 		UpdateManager: updateManager,
-		MasterCounter: dcos,
+		MasterCounter: masterCounter,
 		VersionStore:  versionStore,
+		ClusterStatus: clusterStatus,
+		ClusterFreeze: clusterFreeze,
+		ClusterPin:    clusterPin,
+		Leader:        leader,
+		IPCache:       ipCache,
+		Scheduler:     scheduler.New(),
+		recorder:      newRequestRecorder(cfg.RecordedRequests()),
+		defaultUI:     newDefaultUIWatcher(cfg.DefaultDocRoot(), updateManager.Hooks),
+	}
+	if _, err := service.defaultUI.Check(); err != nil {
+		logrus.WithError(err).Warn("Failed to read the pre-bundled ui build version")
+	}
+	if cfg.TLSEnabled() {
+		if service.certs, err = newCertReloader(cfg.TLSCertFile(), cfg.TLSKeyFile()); err != nil {
+			return nil, err
+		}
+	}
+	if service.apiAuth, err = newAPIAuthenticator(cfg); err != nil {
+		return nil, err
+	}
+	registerMaintenanceJobs(service)
+	if cfg.Replica() {
+		logrus.Info("Running as read-only replica, the version stored by the masters is served and mutating requests are rejected")
 	}
 
-	checkUIDistSymlink(cfg)
+	initServingPaths(cfg)
 	checkCurrentVersion(updateManager)
-	checkVersionsRoot(cfg)
+	if writableErr := updateManager.CheckWritable(); writableErr != nil {
+		logrus.WithError(writableErr).Error("Filesystem is not writable, updates will be rejected until this is resolved")
+	}
+	quarantineIncompleteVersions(updateManager)
 	err = deleteOrphanedVersions(updateManager)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to clean up unused versions")
@@ -65,40 +205,40 @@ func SetupService(cfg *config.Config) (*UIService, error) {
 
 func (service *UIService) Run(l net.Listener) error {
 	registerForVersionChanges(service)
+	if service.IPCache != nil {
+		if _, err := service.IPCache.Refresh(); err != nil {
+			logrus.WithError(err).Warn("Failed to detect node IP")
+		}
+	}
+	if service.Scheduler != nil {
+		service.Scheduler.Start()
+	}
 
 	r := newRouter(service)
 	loggedRouter := handlers.LoggingHandler(os.Stdout, r)
 	http.Handle("/", loggedRouter)
-	return http.Serve(l, loggedRouter)
+	server := &http.Server{
+		Handler:     loggedRouter,
+		IdleTimeout: service.Config.ConnIdleTimeout(),
+	}
+	service.Lock()
+	service.server = server
+	service.Unlock()
+	var listener net.Listener = newConnLimitListener(l, service.Config.MaxConnections())
+	if service.certs != nil {
+		listener = tls.NewListener(listener, service.certs.tlsConfig())
+	}
+	return server.Serve(listener)
 }
 
-func checkUIDistSymlink(cfg *config.Config) {
-	uiDistTarget, err := os.Readlink(cfg.UIDistSymlink())
-	if err != nil {
-		if cfg.InitUIDistSymlink() {
-			logrus.Info("Attempting to initialize UI dist symlink")
-			createErr := os.Symlink(cfg.DefaultDocRoot(), cfg.UIDistSymlink())
-			if createErr != nil {
-				logrus.WithError(createErr).Error("Failed to initialize UI dist symlink")
-			} else {
-				logrus.WithFields(
-					logrus.Fields{
-						"UIDistSymlink":        cfg.UIDistSymlink(),
-						"UIDistSymlink-Target": cfg.DefaultDocRoot(),
-					},
-				).Info("Current UI dist symlink target.")
-			}
-		} else {
-			logrus.WithError(err).Error("Failed to read UI dist symlink")
-		}
-	} else {
-		logrus.WithFields(
-			logrus.Fields{
-				"UIDistSymlink":        cfg.UIDistSymlink(),
-				"UIDistSymlink-Target": uiDistTarget,
-			},
-		).Info("Current UI dist symlink target")
+// serviceClock returns the clock timing the soak
+func (service *UIService) serviceClock() clock.Clock {
+	service.Lock()
+	defer service.Unlock()
+	if service.clock == nil {
+		service.clock = clock.New()
 	}
+	return service.clock
 }
 
 func checkCurrentVersion(updateManager *updatemanager.Client) {
@@ -116,27 +256,25 @@ func checkCurrentVersion(updateManager *updatemanager.Client) {
 	}
 }
 
-func deleteOrphanedVersions(updateManager *updatemanager.Client) error {
+// quarantineIncompleteVersions moves versions left behind by a crash during an install out of
+// versions-root before the orphaned versions are removed, so they are kept for inspection
+func quarantineIncompleteVersions(updateManager *updatemanager.Client) {
 	version, err := updateManager.CurrentVersion()
 	if err != nil {
-		return err
+		return
+	}
+	if _, err := updateManager.QuarantinePartialVersions(version); err != nil {
+		logrus.WithError(err).Warn("Failed to check versions-root for incomplete versions")
 	}
-
-	err = updateManager.RemoveAllVersionsExcept(version)
-	return err
 }
 
-func checkVersionsRoot(cfg *config.Config) {
-	logger := logrus.WithFields(logrus.Fields{"VersionsRoot": cfg.VersionsRoot()})
-	if _, err := os.Stat(cfg.VersionsRoot()); os.IsNotExist(err) {
-		logger.Warn("VersionsRoot directory does not exist, trying to create it.")
-		if mkdirErr := os.MkdirAll(cfg.VersionsRoot(), 0775); mkdirErr != nil {
-			logger.WithError(mkdirErr).Error("Failed to create VersionsRoot directory.")
-			return
-		}
-	} else {
-		logger.Info("Current VersionsRoot directory.")
+func deleteOrphanedVersions(updateManager *updatemanager.Client) error {
+	version, err := updateManager.CurrentVersion()
+	if err != nil {
+		return err
 	}
+
+	return updateManager.PruneVersions(version)
 }
 
 func registerForVersionChanges(service *UIService) {
@@ -146,7 +284,21 @@ func registerForVersionChanges(service *UIService) {
 }
 
 func handleVersionChange(service *UIService, newVersion string) {
-	logrus.WithField("newVersion", newVersion).Info("Received version change from version store.")
+	syncToVersion(service, newVersion)
+
+	// a version stored while this master was syncing is dropped by the update lock,
+	// sync again so the master ends up serving the latest write
+	if stored, err := service.VersionStore.CurrentVersion(); err == nil && string(stored) != newVersion {
+		logrus.WithFields(logrus.Fields{
+			"syncedVersion": newVersion,
+			"storedVersion": stored,
+		}).Warn("Stored version changed during version sync, syncing to the latest version")
+		handleVersionChange(service, string(stored))
+	}
+}
+
+func syncToVersion(service *UIService, newVersion string) {
+	logrus.WithField("version", newVersion).Info("Received version change from version store.")
 	currentLocalVersion, err := service.UpdateManager.CurrentVersion()
 	if err != nil {
 		logrus.WithError(err).Error("Failed to handle version change, error getting the current local version.")
@@ -154,25 +306,39 @@ func handleVersionChange(service *UIService, newVersion string) {
 	}
 	if currentLocalVersion != newVersion {
 		logrus.WithFields(logrus.Fields{
-			"newVersion":     newVersion,
+			"version":        newVersion,
 			"currentVersion": currentLocalVersion,
 		}).Info("Initiating a version sync.")
+		if pin, err := activePin(service); err != nil || (pin != nil && pin.Version != newVersion) {
+			logger := logrus.WithError(err).WithField("version", newVersion)
+			if pin != nil {
+				logger = logger.WithField("pinnedVersion", pin.Version)
+			}
+			logger.Warn("Skipping version sync, the ui is pinned")
+			return
+		}
 		_, err := setServiceUpdating(service, newVersion)
 		if err != nil {
-			logrus.WithError(err).Error("Failed to handle version change, could not lock service for update. ")
+			if err != ErrShuttingDown {
+				recordMissedVersion(service, newVersion)
+			}
+			logrus.WithError(err).WithField("version", newVersion).Warn("Could not lock service for the version sync, syncing once the running operation finished")
 			return
 		}
 		defer resetServiceFromUpdate(service)
+		started := time.Now().UTC()
 
 		if UIVersion(newVersion) == PreBundledUIVersion {
 			// Reset to Pre-bundled version
 			err = updateServedVersion(service, service.Config.DefaultDocRoot())
 			if err != nil {
 				logrus.WithError(err).Error("Failed to reset to default document root.")
+				recordSync(service, currentLocalVersion, newVersion, started, err)
 				return
 			}
 
 			err = service.UpdateManager.RemoveAllVersionsExcept("")
+			recordSync(service, currentLocalVersion, newVersion, started, err)
 			if err != nil {
 				logrus.WithError(err).Error("Failed to removed current version when reseting to default document root.")
 				return
@@ -182,16 +348,21 @@ func handleVersionChange(service *UIService, newVersion string) {
 			return
 		}
 
-		err = service.UpdateManager.UpdateToVersion(newVersion, func(newVersionPath string) error {
-			return updateServedVersion(service, newVersionPath)
-		})
+		if freeze, err := activeFreeze(service); err != nil || freeze != nil {
+			logrus.WithError(err).WithField("version", newVersion).Warn("Skipping version sync, syncs are frozen after a failed sync")
+			return
+		}
+
+		err = updateToVersion(service, newVersion, newLocalActivator(service))
+		recordSync(service, currentLocalVersion, newVersion, started, err)
 
 		if err != nil {
-			logrus.WithError(err).WithFields(logrus.Fields{"newVersion": newVersion}).Error("Version sync failed")
+			logrus.WithError(err).WithFields(logrus.Fields{"version": newVersion}).Error("Version sync failed")
+			freezeSyncs(service, newVersion, err)
 			return
 		}
 
-		logrus.WithFields(logrus.Fields{"newVersion": newVersion}).Info("Version sync completed successfully")
+		logrus.WithFields(logrus.Fields{"version": newVersion}).Info("Version sync completed successfully")
 	}
 }
 
@@ -199,6 +370,9 @@ func setServiceUpdating(service *UIService, version string) (string, error) {
 	service.Lock()
 	defer service.Unlock()
 
+	if service.shuttingDown {
+		return "", ErrShuttingDown
+	}
 	if service.updating {
 		return service.updatingVersion, fmt.Errorf(
 			"Cannot set service to updating to version %s because another update is already in progress for version: %s",
@@ -208,16 +382,37 @@ func setServiceUpdating(service *UIService, version string) (string, error) {
 	}
 	service.updating = true
 	service.updatingVersion = version
+	service.updatingSince = time.Now().UTC()
 
 	return version, nil
 }
 
+// resetServiceFromUpdate unlocks the service and syncs the version missed while it was locked
 func resetServiceFromUpdate(service *UIService) {
 	service.Lock()
-	defer service.Unlock()
-
 	service.updating = false
 	service.updatingVersion = ""
+	if service.preemptible != nil {
+		service.preemptible.cancel()
+	}
+	service.preemptible = nil
+	missed := service.missedVersion
+	service.missedVersion = nil
+	shuttingDown := service.shuttingDown
+	service.Unlock()
+
+	if missed != nil && !shuttingDown {
+		logrus.WithField("version", *missed).Info("Syncing to the version received while the service was locked")
+		go handleVersionChange(service, *missed)
+	}
+}
+
+// recordMissedVersion keeps the version the service could not sync to because it was locked, a later
+// version replaces it so only the latest version is synced
+func recordMissedVersion(service *UIService, version string) {
+	service.Lock()
+	defer service.Unlock()
+	service.missedVersion = &version
 }
 
 func updateServedVersion(service *UIService, newVersionPath string) error {
@@ -233,32 +428,21 @@ func updateServedVersion(service *UIService, newVersionPath string) error {
 		}
 		return errors.Wrap(err, "unable to swap staged new version symlink with dist symlink")
 	}
+	if service.Config.ServeUI() {
+		service.uiFileHandler().setDocRoot(newVersionPath)
+	}
 	return nil
 }
 
 var (
 	// ErrIndexFileNotFound occurs if the provided uiDistPath doesn't contain an index.html file
-	ErrIndexFileNotFound = errors.New("index.html file was not found in the ui dist path")
+	ErrIndexFileNotFound = updatemanager.ErrIndexFileNotFound
 	// ErrIndexFileCouldNotBeRead occurs if there is an error reading the index.html file
-	ErrIndexFileCouldNotBeRead = errors.New("index.html file could not be read")
+	ErrIndexFileCouldNotBeRead = updatemanager.ErrIndexFileCouldNotBeRead
 	// ErrVersionNotFoundInIndex occurs if we can't find the DCOS_UI_VERSION in the ui dist's index.html
-	ErrVersionNotFoundInIndex = errors.New("DCOS_UI_VERSION not found in ui dist's index.html")
+	ErrVersionNotFoundInIndex = updatemanager.ErrVersionNotFoundInIndex
 )
 
 func buildVersionFromUIIndex(uiDistPath string) (string, error) {
-	indexFilePath := path.Join(uiDistPath, "index.html")
-	if _, err := os.Stat(indexFilePath); os.IsNotExist(err) {
-		return "", ErrIndexFileNotFound
-	}
-
-	indexFileBytes, err := ioutil.ReadFile(indexFilePath)
-	if err != nil {
-		return "", ErrIndexFileCouldNotBeRead
-	}
-	re := regexp.MustCompile(`window.DCOS_UI_VERSION\s*=\s*["'](?P<version>[\w\d.\-+]+)["'];`)
-	matches := re.FindSubmatch(indexFileBytes)
-	if len(matches) < 2 {
-		return "", ErrVersionNotFoundInIndex
-	}
-	return string(matches[1]), nil
+	return updatemanager.BuildVersionFromIndex(uiDistPath)
 }
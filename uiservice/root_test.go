@@ -1,10 +1,13 @@
 package uiservice
 
 import (
+	"context"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/dcos/dcos-ui-update-service/config"
 	"github.com/dcos/dcos-ui-update-service/dcos"
@@ -27,7 +30,7 @@ func setupTestUIService() *UIService {
 		"--master-count-file", "../fixtures/single-master",
 	})
 
-	um, _ := updatemanager.NewClient(cfg)
+	um, _ := updatemanager.NewClient(cfg, &updatemanager.FakePackageSource{}, &updatemanager.FakeBundleFetcher{})
 	um.Fs = afero.NewOsFs()
 	os.MkdirAll(cfg.VersionsRoot(), 0755)
 	os.MkdirAll(cfg.DefaultDocRoot(), 0755)
@@ -52,7 +55,7 @@ func setupUIServiceWithVersion() *UIService {
 		"--master-count-file", "../fixtures/single-master",
 	})
 
-	um, _ := updatemanager.NewClient(cfg)
+	um, _ := updatemanager.NewClient(cfg, &updatemanager.FakePackageSource{}, &updatemanager.FakeBundleFetcher{})
 	um.Fs = afero.NewOsFs()
 	versionPath := path.Join(path.Join(cfg.VersionsRoot(), "2.24.4"), "dist")
 	os.MkdirAll(cfg.VersionsRoot(), 0755)
@@ -70,6 +73,20 @@ func setupUIServiceWithVersion() *UIService {
 	}
 }
 
+// waitForIdle waits until no operation holds the service lock
+func waitForIdle(service *UIService) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		service.Lock()
+		updating := service.updating
+		service.Unlock()
+		if !updating {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
 func TestVersionChange(t *testing.T) {
 	t.Run("Reset if new version is empty", func(t *testing.T) {
 		var removeAllCalled, updateCalled bool
@@ -118,6 +135,86 @@ func TestVersionChange(t *testing.T) {
 		tests.H(t).BoolEql(updateCalled, true)
 	})
 
+	t.Run("syncs again if the stored version changed during the sync", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		store := VersionStoreDouble()
+		store.VersionResult = "2.24.6"
+		service.VersionStore = store
+
+		var updatedTo []string
+		um := UpdateManagerDouble()
+		um.VersionResult = "2.24.4"
+		um.UpdateNewVersionPath = path.Join(service.Config.VersionsRoot(), "2.24.6", "dist")
+		um.UpdateCall = func(newVer string) {
+			os.MkdirAll(um.UpdateNewVersionPath, 0755)
+			updatedTo = append(updatedTo, newVer)
+		}
+		service.UpdateManager = um
+
+		handleVersionChange(service, "2.24.5")
+
+		tests.H(t).InterfaceEql(updatedTo, []string{"2.24.5", "2.24.6"})
+	})
+
+	t.Run("syncs to the latest version received while the service was locked once it is unlocked", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		store := VersionStoreDouble()
+		store.VersionResult = "2.24.7"
+		service.VersionStore = store
+
+		updated := make(chan string, 3)
+		um := UpdateManagerDouble()
+		um.VersionResult = "2.24.4"
+		um.UpdateNewVersionPath = path.Join(service.Config.VersionsRoot(), "2.24.7", "dist")
+		um.UpdateCall = func(newVer string) {
+			os.MkdirAll(um.UpdateNewVersionPath, 0755)
+			updated <- newVer
+		}
+		service.UpdateManager = um
+
+		// another operation holds the lock while the version store changes in quick succession
+		setServiceUpdating(service, "2.30.0")
+		handleVersionChange(service, "2.24.5")
+		handleVersionChange(service, "2.24.6")
+		handleVersionChange(service, "2.24.7")
+		tests.H(t).IntEql(len(updated), 0)
+		resetServiceFromUpdate(service)
+
+		select {
+		case version := <-updated:
+			tests.H(t).StringEql(version, "2.24.7")
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the missed version to be synced")
+		}
+		waitForIdle(service)
+		tests.H(t).IntEql(len(updated), 0)
+		tests.H(t).BoolEql(service.missedVersion == nil, true)
+	})
+
+	t.Run("does not sync a missed version while shutting down", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		updateCalled := false
+		um := UpdateManagerDouble()
+		um.VersionResult = "2.24.4"
+		um.UpdateCall = func(newVer string) {
+			updateCalled = true
+		}
+		service.UpdateManager = um
+
+		setServiceUpdating(service, "2.30.0")
+		handleVersionChange(service, "2.24.5")
+		service.Lock()
+		service.shuttingDown = true
+		service.Unlock()
+		resetServiceFromUpdate(service)
+
+		tests.H(t).BoolEql(service.missedVersion == nil, true)
+		tests.H(t).BoolEql(updateCalled, false)
+	})
+
 	t.Run("do nothing if version matches current", func(t *testing.T) {
 		var resetCalled, updateCalled bool
 		defer tearDown(t)
@@ -183,6 +280,41 @@ type fakeUpdateManager struct {
 	UpdateError          error
 	UpdateCall           func(string)
 	UpdateNewVersionPath string
+	// UpdateBundleURL is the bundle URL of the last UpdateFromBundle call
+	UpdateBundleURL     string
+	InstallError        error
+	InstalledVersions   []string
+	PreviousVersionPath string
+	AvailableResult     []string
+	AvailableError      error
+	WritableError       error
+	VerifyError         error
+	ProbeResult         *updatemanager.AssetProbe
+	ProbeError          error
+	TrashedResult       []updatemanager.TrashedVersion
+	TrashedError        error
+	RestoreError        error
+	RestoredVersions    []string
+	PurgeError          error
+	PurgeCalled         bool
+	QuarantinedResult   []updatemanager.QuarantinedVersion
+	PruneError          error
+	PrunedServed        []string
+	MinDcosResult       map[string]string
+	MinDcosError        error
+	// ProvenanceResult maps versions to their provenance, versions without entry have none
+	ProvenanceResult map[string]*updatemanager.Provenance
+	ApprovalError    error
+	HookRerunResult  *updatemanager.HookRerun
+	HookRerunError   error
+	// HookRerunCall is called with the context of the hooks rerun
+	HookRerunCall func(context.Context)
+	LocalResult   []updatemanager.LocalVersion
+	// PackageNameResult is returned by PackageName, dcos-ui if empty
+	PackageNameResult string
+	// Labels maps labels to versions
+	Labels     map[string]string
+	LabelError error
 }
 
 func UpdateManagerDouble() *fakeUpdateManager {
@@ -191,19 +323,36 @@ func UpdateManagerDouble() *fakeUpdateManager {
 	}
 }
 
-func (um *fakeUpdateManager) UpdateToVersion(newVer string, cb func(string) error) error {
+func (um *fakeUpdateManager) UpdateToVersion(newVer string, activator updatemanager.Activator) error {
 	if um.UpdateError != nil {
 		return um.UpdateError
 	}
 	if um.UpdateCall != nil {
 		um.UpdateCall(newVer)
 	}
-	if cberr := cb(um.UpdateNewVersionPath); cberr != nil {
-		return cberr
+	if err := activator.Prepare(um.UpdateNewVersionPath); err != nil {
+		return err
+	}
+	if err := activator.Commit(um.UpdateNewVersionPath); err != nil {
+		activator.Rollback(um.PreviousVersionPath)
+		return err
 	}
 	return nil
 }
 
+func (um *fakeUpdateManager) UpdateFromBundle(newVer string, bundleURL *url.URL, activator updatemanager.Activator) error {
+	um.UpdateBundleURL = bundleURL.String()
+	return um.UpdateToVersion(newVer, activator)
+}
+
+func (um *fakeUpdateManager) InstallVersion(version string) error {
+	if um.InstallError != nil {
+		return um.InstallError
+	}
+	um.InstalledVersions = append(um.InstalledVersions, version)
+	return nil
+}
+
 func (um *fakeUpdateManager) RemoveVersion(version string) error {
 	if um.ResetError != nil {
 		return um.ResetError
@@ -240,9 +389,132 @@ func (um *fakeUpdateManager) PathToCurrentVersion() (string, error) {
 	return um.VersionPathResult, nil
 }
 
+func (um *fakeUpdateManager) AvailableVersions() ([]string, error) {
+	if um.AvailableError != nil {
+		return nil, um.AvailableError
+	}
+	return um.AvailableResult, nil
+}
+
+func (um *fakeUpdateManager) CheckWritable() error {
+	return um.WritableError
+}
+
+func (um *fakeUpdateManager) VerifyVersion(string) error {
+	return um.VerifyError
+}
+
+func (um *fakeUpdateManager) ProbeAsset(string) (*updatemanager.AssetProbe, error) {
+	return um.ProbeResult, um.ProbeError
+}
+
+func (um *fakeUpdateManager) TrashedVersions() ([]updatemanager.TrashedVersion, error) {
+	if um.TrashedError != nil {
+		return nil, um.TrashedError
+	}
+	return um.TrashedResult, nil
+}
+
+func (um *fakeUpdateManager) RestoreVersion(version string) error {
+	if um.RestoreError != nil {
+		return um.RestoreError
+	}
+	um.RestoredVersions = append(um.RestoredVersions, version)
+	return nil
+}
+
+func (um *fakeUpdateManager) PurgeTrash() error {
+	um.PurgeCalled = true
+	return um.PurgeError
+}
+
+func (um *fakeUpdateManager) PruneVersions(servedVersion string) error {
+	um.PrunedServed = append(um.PrunedServed, servedVersion)
+	return um.PruneError
+}
+
+func (um *fakeUpdateManager) MinDcosReleaseVersion(version string) (string, error) {
+	if um.MinDcosError != nil {
+		return "", um.MinDcosError
+	}
+	return um.MinDcosResult[version], nil
+}
+
+func (um *fakeUpdateManager) QuarantinedVersions() ([]updatemanager.QuarantinedVersion, error) {
+	return um.QuarantinedResult, nil
+}
+
+func (um *fakeUpdateManager) VersionProvenance(version string) (*updatemanager.Provenance, error) {
+	provenance, ok := um.ProvenanceResult[version]
+	if !ok {
+		return nil, updatemanager.ErrProvenanceNotFound
+	}
+	return provenance, nil
+}
+
+func (um *fakeUpdateManager) SetApprovalTicket(version, ticket string) (*updatemanager.Provenance, error) {
+	if um.ApprovalError != nil {
+		return nil, um.ApprovalError
+	}
+	provenance, err := um.VersionProvenance(version)
+	if err != nil {
+		return nil, err
+	}
+	provenance.ApprovalTicket = ticket
+	return provenance, nil
+}
+
+func (um *fakeUpdateManager) RerunPostActivateHooks(ctx context.Context) (*updatemanager.HookRerun, error) {
+	if um.HookRerunCall != nil {
+		um.HookRerunCall(ctx)
+	}
+	return um.HookRerunResult, um.HookRerunError
+}
+
+func (um *fakeUpdateManager) PackageName(version string) string {
+	if um.PackageNameResult != "" {
+		return um.PackageNameResult
+	}
+	return "dcos-ui"
+}
+
+func (um *fakeUpdateManager) LocalVersions() ([]updatemanager.LocalVersion, error) {
+	return um.LocalResult, nil
+}
+
+func (um *fakeUpdateManager) SetVersionLabel(version, label string) error {
+	if um.LabelError != nil {
+		return um.LabelError
+	}
+	if um.Labels == nil {
+		um.Labels = map[string]string{}
+	}
+	um.Labels[label] = version
+	return nil
+}
+
+func (um *fakeUpdateManager) RemoveVersionLabel(version, label string) error {
+	if um.Labels[label] != version {
+		return updatemanager.ErrLabelNotFound
+	}
+	delete(um.Labels, label)
+	return nil
+}
+
+func (um *fakeUpdateManager) LabeledVersion(label string) (string, error) {
+	version, ok := um.Labels[label]
+	if !ok {
+		return "", updatemanager.ErrLabelNotFound
+	}
+	return version, nil
+}
+
 type fakeVersionStore struct {
-	VersionResult UIVersion
-	UpdateError   error
+	VersionResult   UIVersion
+	UpdateError     error
+	UpdatedVersions []UIVersion
+	// Conflict is reported by LastVersionConflict once a version was stored
+	Conflict *VersionConflict
 }
 
 func VersionStoreDouble() *fakeVersionStore {
@@ -259,9 +531,17 @@ func (vs *fakeVersionStore) UpdateCurrentVersion(newVersion UIVersion) error {
 	if vs.UpdateError != nil {
 		return vs.UpdateError
 	}
+	vs.UpdatedVersions = append(vs.UpdatedVersions, newVersion)
 	return nil
 }
 
 func (vs *fakeVersionStore) WatchForVersionChange(listener VersionChangeListener) error {
 	return nil
 }
+
+func (vs *fakeVersionStore) LastVersionConflict() *VersionConflict {
+	if len(vs.UpdatedVersions) == 0 {
+		return nil
+	}
+	return vs.Conflict
+}
@@ -0,0 +1,132 @@
+package uiservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// shutdownPollInterval is the interval to check if in-flight updates finished during shutdown
+const shutdownPollInterval = 100 * time.Millisecond
+
+var (
+	// ErrShuttingDown occurs if an update or maintenance operation is started while the service shuts down
+	ErrShuttingDown = errors.New("Service is shutting down")
+	// ErrShutdownTimeout occurs if in-flight updates did not finish before the shutdown deadline
+	ErrShutdownTimeout = errors.New("In-flight updates did not finish before the shutdown deadline")
+	// ErrRestartDuringUpdate occurs if a warm restart is requested while an update is running
+	ErrRestartDuringUpdate = errors.New("Cannot restart while an update is in progress")
+)
+
+// PrepareRestart stops accepting updates before a warm restart starts a new process. The new process removes
+// the stage symlink and incomplete versions when it starts, so the restart is refused with ErrRestartDuringUpdate
+// while an update is running. CancelRestart accepts updates again if the restart failed.
+func (service *UIService) PrepareRestart() error {
+	service.Lock()
+	defer service.Unlock()
+	if service.updating {
+		return ErrRestartDuringUpdate
+	}
+	service.shuttingDown = true
+	return nil
+}
+
+// CancelRestart accepts updates again after a failed warm restart
+func (service *UIService) CancelRestart() {
+	service.Lock()
+	defer service.Unlock()
+	service.shuttingDown = false
+}
+
+// Shutdown stops accepting API requests and new operations, waits until ctx is done for in-flight
+// requests and updates to finish or roll back, then closes the version store and removes a
+// dangling stage symlink. Run returns http.ErrServerClosed once the server stopped.
+func (service *UIService) Shutdown(ctx context.Context) error {
+	service.Lock()
+	service.shuttingDown = true
+	server := service.server
+	if shutdown := service.shutdownChannel(); !isClosed(shutdown) {
+		close(shutdown)
+	}
+	service.Unlock()
+	logrus.Info("Shutting down ui service")
+
+	var err error
+	if server != nil {
+		if shutdownErr := server.Shutdown(ctx); shutdownErr != nil {
+			logrus.WithError(shutdownErr).Warn("API requests did not finish before the shutdown deadline")
+			err = shutdownErr
+		}
+	}
+	if service.Scheduler != nil {
+		service.Scheduler.Stop()
+	}
+
+	drained := waitForOperations(ctx, service)
+	if !drained {
+		logrus.Error(ErrShutdownTimeout.Error())
+		err = ErrShutdownTimeout
+	}
+
+	if closer, ok := service.VersionStore.(VersionStoreCloser); ok {
+		closer.Close()
+	}
+	// an update still running uses the stage symlink, it is cleaned up by the next update otherwise
+	if drained {
+		removeStageSymlink(service.Config.UIDistStageSymlink())
+	}
+	logrus.Info("Ui service shut down")
+	return err
+}
+
+// shutdownSignal is closed once Shutdown was called
+func (service *UIService) shutdownSignal() <-chan struct{} {
+	service.Lock()
+	defer service.Unlock()
+	return service.shutdownChannel()
+}
+
+// shutdownChannel returns the channel closed by Shutdown, the service must be locked
+func (service *UIService) shutdownChannel() chan struct{} {
+	if service.shutdown == nil {
+		service.shutdown = make(chan struct{})
+	}
+	return service.shutdown
+}
+
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitForOperations returns true once no update or maintenance operation is running,
+// false if ctx was done before
+func waitForOperations(ctx context.Context, service *UIService) bool {
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	logged := false
+	for {
+		service.Lock()
+		updating := service.updating
+		version := service.updatingVersion
+		service.Unlock()
+		if !updating {
+			return true
+		}
+		if !logged {
+			logrus.WithField("version", version).Info("Waiting for in-flight update to finish")
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package uiservice
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+type closableVersionStore struct {
+	*fakeVersionStore
+	closed bool
+}
+
+func (vs *closableVersionStore) Close() {
+	vs.closed = true
+}
+
+func TestShutdown(t *testing.T) {
+	t.Run("stops the server, closes the version store and removes the stage symlink", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		store := &closableVersionStore{fakeVersionStore: VersionStoreDouble()}
+		service.VersionStore = store
+		os.Symlink(service.Config.DefaultDocRoot(), service.Config.UIDistStageSymlink())
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		runResult := make(chan error, 1)
+		go func() { runResult <- service.Run(l) }()
+		for i := 0; i < 100; i++ {
+			service.Lock()
+			started := service.server != nil
+			service.Unlock()
+			if started {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		tests.H(t).IsNil(service.Shutdown(context.Background()))
+		tests.H(t).ErrEql(<-runResult, http.ErrServerClosed)
+		tests.H(t).BoolEql(store.closed, true)
+		_, statErr := os.Lstat(service.Config.UIDistStageSymlink())
+		tests.H(t).BoolEql(os.IsNotExist(statErr), true)
+	})
+
+	t.Run("waits for an in-flight update", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.24.5")
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			resetServiceFromUpdate(service)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		tests.H(t).IsNil(service.Shutdown(ctx))
+	})
+
+	t.Run("gives up on updates running past the deadline", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		os.Symlink(service.Config.DefaultDocRoot(), service.Config.UIDistStageSymlink())
+		setServiceUpdating(service, "2.24.5")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		tests.H(t).ErrEql(service.Shutdown(ctx), ErrShutdownTimeout)
+		_, statErr := os.Lstat(service.Config.UIDistStageSymlink())
+		tests.H(t).IsNil(statErr)
+	})
+
+	t.Run("rejects operations once shutting down", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.Shutdown(context.Background())
+
+		_, err := setServiceUpdating(service, "2.24.5")
+		tests.H(t).ErrEql(err, ErrShuttingDown)
+	})
+}
+
+func TestPrepareRestart(t *testing.T) {
+	t.Run("refuses a warm restart while an update is running", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		setServiceUpdating(service, "2.24.5")
+
+		tests.H(t).ErrEql(service.PrepareRestart(), ErrRestartDuringUpdate)
+
+		resetServiceFromUpdate(service)
+		_, err := setServiceUpdating(service, "2.24.6")
+		tests.H(t).IsNil(err)
+	})
+
+	t.Run("rejects updates until the restart is cancelled", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		tests.H(t).IsNil(service.PrepareRestart())
+		_, err := setServiceUpdating(service, "2.24.5")
+		tests.H(t).ErrEql(err, ErrShuttingDown)
+
+		service.CancelRestart()
+		_, err = setServiceUpdating(service, "2.24.5")
+		tests.H(t).IsNil(err)
+	})
+}
@@ -0,0 +1,80 @@
+package uiservice
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrSoakFailed occurs if the probe failed too often while a new version was served by this master only
+var ErrSoakFailed = errors.New("The probe failed while the new version soaked")
+
+// soakVersion probes the soak probe URL every interval until the soak duration passed. It returns
+// ErrSoakFailed once the configured number of probes failed, the number of probes and failures are
+// returned in either case. The soak stops with ErrUpdateCancelled if the update is cancelled and with
+// ErrShuttingDown if the service shuts down, the caller restores the previous version.
+func soakVersion(service *UIService, version UIVersion) (probes, failures int, err error) {
+	cfg := service.Config
+	clk := service.serviceClock()
+	client := &http.Client{Timeout: cfg.SoakProbeInterval()}
+	logger := logrus.WithFields(logrus.Fields{"version": version, "url": cfg.SoakProbeURL()})
+	logger.WithField("duration", cfg.SoakDuration().String()).Info("Soaking the new version on this master")
+
+	// the version is served by this master only, so the soak can still be cancelled
+	setSoaking(service, true)
+	defer setSoaking(service, false)
+	ctx := operationContext(service)
+	shutdown := service.shutdownSignal()
+	deadline := clk.After(cfg.SoakDuration())
+	for {
+		select {
+		case <-deadline:
+			logger.WithFields(logrus.Fields{"probes": probes, "failures": failures}).Info("The new version soaked successfully")
+			return probes, failures, nil
+		case <-ctx.Done():
+			logger.Warn("The soak was cancelled")
+			return probes, failures, errors.Wrap(updatemanager.ErrUpdateCancelled, "the soak was cancelled")
+		case <-shutdown:
+			logger.Warn("The soak was stopped by the shutdown")
+			return probes, failures, errors.Wrap(ErrShuttingDown, "the soak was stopped")
+		case <-clk.After(cfg.SoakProbeInterval()):
+		}
+		probes++
+		if probeErr := probeSoakURL(ctx, client, cfg.SoakProbeURL()); probeErr != nil {
+			failures++
+			logger.WithError(probeErr).WithField("failures", failures).Warn("Soak probe failed")
+		}
+		if failures >= cfg.SoakMaxFailures() {
+			return probes, failures, errors.Wrapf(ErrSoakFailed, "%d of %d probes failed", failures, probes)
+		}
+	}
+}
+
+// setSoaking marks the running update as soaking, it can be cancelled although it serves the new version
+func setSoaking(service *UIService, soaking bool) {
+	service.Lock()
+	defer service.Unlock()
+	if service.preemptible != nil {
+		service.preemptible.soaking = soaking
+	}
+}
+
+func probeSoakURL(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return errors.New("probe returned status " + strconv.Itoa(resp.StatusCode))
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package uiservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func setupSoakUIService(probeURL string) *UIService {
+	service := setupUIServiceWithVersion()
+	service.Config, _ = config.Parse([]string{
+		"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+		"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+		"--ui-dist-stage-symlink", "../testdata/uiserv-sandbox/new-dcos-ui-dist",
+		"--soak-duration", "50ms",
+		"--soak-probe-url", probeURL,
+		"--soak-probe-interval", "5ms",
+		"--soak-max-failures", "2",
+	})
+	newVersionPath := path.Join(service.Config.VersionsRoot(), "2.25.0", "dist")
+	os.MkdirAll(newVersionPath, 0755)
+	um := UpdateManagerDouble()
+	um.UpdateNewVersionPath = newVersionPath
+	service.UpdateManager = um
+	return service
+}
+
+func soakUpdate(t *testing.T, service *UIService) ClusterOperationResult {
+	req := httptest.NewRequest("POST", "/api/v1/update/2.25.0/", nil)
+	req.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, req)
+	var result ClusterOperationResult
+	tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &result))
+	return result
+}
+
+func TestSoak(t *testing.T) {
+	t.Run("stores the version after it soaked", func(t *testing.T) {
+		defer tearDown(t)
+		probe := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer probe.Close()
+		service := setupSoakUIService(probe.URL)
+
+		result := soakUpdate(t, service)
+
+		tests.H(t).StringEql(result.Error, "")
+		tests.H(t).InterfaceEql(decisionSteps(result.Decisions), []string{DecisionStart, DecisionActivate, DecisionSoak, DecisionStoreVersion, DecisionFinish})
+		tests.H(t).StringEql(result.Decisions[2].Inputs["failures"], "0")
+		tests.H(t).InterfaceEql(service.VersionStore.(*fakeVersionStore).UpdatedVersions, []UIVersion{"2.25.0"})
+		target, _ := os.Readlink(service.Config.UIDistSymlink())
+		tests.H(t).StringEql(target, path.Join(service.Config.VersionsRoot(), "2.25.0", "dist"))
+	})
+
+	t.Run("restores the previous version if the probe fails", func(t *testing.T) {
+		defer tearDown(t)
+		probe := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer probe.Close()
+		service := setupSoakUIService(probe.URL)
+
+		result := soakUpdate(t, service)
+
+		tests.H(t).StringEql(result.ErrorCode, ErrorCodeSoakFailed)
+		tests.H(t).InterfaceEql(decisionSteps(result.Decisions), []string{DecisionStart, DecisionActivate, DecisionSoak, DecisionRollback, DecisionFinish})
+		tests.H(t).IntEql(len(service.VersionStore.(*fakeVersionStore).UpdatedVersions), 0)
+		target, _ := os.Readlink(service.Config.UIDistSymlink())
+		tests.H(t).StringEql(target, path.Join(service.Config.VersionsRoot(), "2.24.4", "dist"))
+	})
+	t.Run("restores the previous version if the soak is cancelled", func(t *testing.T) {
+		defer tearDown(t)
+		probe := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer probe.Close()
+		service := setupSoakUIService(probe.URL)
+		clk := clock.NewFake(time.Now())
+		service.clock = clk
+
+		results := make(chan ClusterOperationResult, 1)
+		go func() { results <- soakUpdate(t, service) }()
+		// the soak waits for its duration and the first probe
+		clk.BlockUntil(2)
+		running, err := cancelRunning(service, "2.25.0")
+		result := <-results
+
+		tests.H(t).IsNil(err)
+		tests.H(t).NotNil(running)
+		tests.H(t).StringEql(result.ErrorCode, ErrorCodeCancelled)
+		tests.H(t).InterfaceEql(decisionSteps(result.Decisions), []string{DecisionStart, DecisionActivate, DecisionSoak, DecisionRollback, DecisionFinish})
+		tests.H(t).IntEql(len(service.VersionStore.(*fakeVersionStore).UpdatedVersions), 0)
+		target, _ := os.Readlink(service.Config.UIDistSymlink())
+		tests.H(t).StringEql(target, path.Join(service.Config.VersionsRoot(), "2.24.4", "dist"))
+	})
+
+	t.Run("stops the soak when the service shuts down", func(t *testing.T) {
+		defer tearDown(t)
+		probe := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer probe.Close()
+		service := setupSoakUIService(probe.URL)
+		clk := clock.NewFake(time.Now())
+		service.clock = clk
+
+		results := make(chan ClusterOperationResult, 1)
+		go func() { results <- soakUpdate(t, service) }()
+		clk.BlockUntil(2)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		tests.H(t).IsNil(service.Shutdown(ctx))
+		result := <-results
+
+		tests.H(t).StringContains(result.Error, "the soak was stopped")
+		tests.H(t).IntEql(len(service.VersionStore.(*fakeVersionStore).UpdatedVersions), 0)
+		target, _ := os.Readlink(service.Config.UIDistSymlink())
+		tests.H(t).StringEql(target, path.Join(service.Config.VersionsRoot(), "2.24.4", "dist"))
+	})
+}
@@ -0,0 +1,190 @@
+package uiservice
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// stateFormatVersion is the version of the exported state document format
+const stateFormatVersion = 1
+
+// stateImportLimit is the maximum size of an imported state document
+const stateImportLimit = 1 << 20
+
+var (
+	// ErrStateSigningKeyNotConfigured occurs if state is exported or imported without a signing key file
+	ErrStateSigningKeyNotConfigured = errors.New("state-signing-key-file is not configured")
+	// ErrStateSignatureInvalid occurs if the signature of an imported state document does not match its payload
+	ErrStateSignatureInvalid = errors.New("state document signature is invalid")
+	// ErrStateFormatUnsupported occurs if an imported state document has an unknown format version
+	ErrStateFormatUnsupported = errors.New("state document format version is not supported")
+)
+
+// serviceState is the logical state restored on a cluster restored from a backup
+type serviceState struct {
+	FormatVersion int       `json:"formatVersion"`
+	ExportedAt    time.Time `json:"exportedAt"`
+	Version       UIVersion `json:"version"`
+}
+
+// stateDocument carries the serialized state and its HMAC-SHA256 signature,
+// the signature is computed over the payload bytes exactly as they appear in the document
+type stateDocument struct {
+	Payload   json.RawMessage `json:"payload"`
+	Signature string          `json:"signature"`
+}
+
+func readStateSigningKey(service *UIService) ([]byte, error) {
+	keyFile := service.Config.StateSigningKeyFile()
+	if keyFile == "" {
+		return nil, ErrStateSigningKeyNotConfigured
+	}
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read state signing key")
+	}
+	return bytes.TrimSpace(key), nil
+}
+
+func signState(key, payload []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifyStateDocument(key []byte, document stateDocument) (serviceState, error) {
+	var state serviceState
+	signature, err := hex.DecodeString(document.Signature)
+	if err != nil {
+		return state, ErrStateSignatureInvalid
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(document.Payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return state, ErrStateSignatureInvalid
+	}
+	if err := json.Unmarshal(document.Payload, &state); err != nil {
+		return state, errors.Wrap(err, "could not decode state payload")
+	}
+	if state.FormatVersion != stateFormatVersion {
+		return state, ErrStateFormatUnsupported
+	}
+	return state, nil
+}
+
+// exportStateHandler returns the signed state document for backups
+func exportStateHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := readStateSigningKey(service)
+		if err == ErrStateSigningKeyNotConfigured {
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+			return
+		}
+		if err != nil {
+			logrus.WithError(err).Error("Could not read state signing key.")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		version, err := service.VersionStore.CurrentVersion()
+		if err != nil {
+			logrus.WithError(err).Error("Could not get version from version store.")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		payload, err := json.Marshal(serviceState{
+			FormatVersion: stateFormatVersion,
+			ExportedAt:    time.Now().UTC(),
+			Version:       version,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		js, err := json.Marshal(stateDocument{Payload: payload, Signature: signState(key, payload)})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
+
+// importStateHandler verifies a signed state document and stores its version in the version store,
+// the masters sync to it asynchronously like to any other version change
+func importStateHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := readStateSigningKey(service)
+		if err == ErrStateSigningKeyNotConfigured {
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+			return
+		}
+		if err != nil {
+			logrus.WithError(err).Error("Could not read state signing key.")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var document stateDocument
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, stateImportLimit)).Decode(&document); err != nil {
+			http.Error(w, "state document could not be decoded", http.StatusBadRequest)
+			return
+		}
+		state, err := verifyStateDocument(key, document)
+		switch err {
+		case nil:
+		case ErrStateSignatureInvalid:
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// the import changes the stored version like an update, it holds the update lock and the cluster operation
+		version := string(state.Version)
+		if updatingVersion, lockErr := setServiceUpdating(service, version); lockErr != nil {
+			writeLocalBusy(service, w, r, http.StatusConflict, version, updatingVersion, "Cannot import state, an update is currently in progress.")
+			return
+		}
+		defer resetServiceFromUpdate(service)
+		if rejectIfFrozen(service, w) || rejectIfPinned(service, w, version) {
+			return
+		}
+		op := newClusterOperation(service, OperationImport, version)
+		if !acquireClusterOperation(service, w, op) {
+			return
+		}
+		defer releaseClusterOperation(service)
+		result := newOperationResult(service, op)
+
+		storedVersion, _ := service.VersionStore.CurrentVersion()
+		err = service.VersionStore.UpdateCurrentVersion(state.Version)
+		service.auditLog().record(newAuditEntry(service, OperationImport, TriggerAPI, string(storedVersion), version, op.Started, err))
+		result.finish(service, err)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to store imported version in the version store.")
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		logrus.WithFields(logrus.Fields{
+			"version":    state.Version,
+			"exportedAt": state.ExportedAt,
+		}).Info("Imported service state")
+
+		w.Header().Add("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("Accepted"))
+	}
+}
@@ -0,0 +1,147 @@
+package uiservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func setupStateUIService(t *testing.T, key string) (*UIService, *fakeVersionStore) {
+	service := setupTestUIService()
+	keyFile := filepath.Join(os.TempDir(), "ui-update-state-key")
+	if err := ioutil.WriteFile(keyFile, []byte(key+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	service.Config, _ = config.Parse([]string{
+		"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+		"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+		"--state-signing-key-file", keyFile,
+	})
+	store := VersionStoreDouble()
+	service.VersionStore = store
+	return service, store
+}
+
+func exportState(t *testing.T, service *UIService) []byte {
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/state/export/", nil))
+	tests.H(t).IntEql(rr.Code, http.StatusOK)
+	return rr.Body.Bytes()
+}
+
+func importState(service *UIService, document []byte) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/state/import/", bytes.NewReader(document)))
+	return rr
+}
+
+func TestStateExportImport(t *testing.T) {
+	t.Run("exports a signed document with the stored version", func(t *testing.T) {
+		defer tearDown(t)
+		service, _ := setupStateUIService(t, "secret")
+
+		var document stateDocument
+		tests.H(t).ErrEql(json.Unmarshal(exportState(t, service), &document), nil)
+		tests.H(t).StringContains(string(document.Payload), `"version":"2.24.4"`)
+		tests.H(t).StringEql(document.Signature, signState([]byte("secret"), document.Payload))
+	})
+
+	t.Run("imports an exported document", func(t *testing.T) {
+		defer tearDown(t)
+		service, store := setupStateUIService(t, "secret")
+		document := exportState(t, service)
+
+		rr := importState(service, document)
+
+		tests.H(t).IntEql(rr.Code, http.StatusAccepted)
+		tests.H(t).InterfaceEql(store.UpdatedVersions, []UIVersion{"2.24.4"})
+	})
+
+	t.Run("rejects documents signed with another key", func(t *testing.T) {
+		defer tearDown(t)
+		service, _ := setupStateUIService(t, "other-secret")
+		document := exportState(t, service)
+		service, store := setupStateUIService(t, "secret")
+
+		rr := importState(service, document)
+
+		tests.H(t).IntEql(rr.Code, http.StatusForbidden)
+		tests.H(t).IntEql(len(store.UpdatedVersions), 0)
+	})
+
+	t.Run("rejects modified documents", func(t *testing.T) {
+		defer tearDown(t)
+		service, _ := setupStateUIService(t, "secret")
+		document := bytes.Replace(exportState(t, service), []byte("2.24.4"), []byte("2.25.0"), 1)
+
+		rr := importState(service, document)
+
+		tests.H(t).IntEql(rr.Code, http.StatusForbidden)
+	})
+
+	t.Run("rejects unsupported format versions", func(t *testing.T) {
+		defer tearDown(t)
+		service, _ := setupStateUIService(t, "secret")
+		payload := []byte(`{"formatVersion":2,"version":"2.25.0"}`)
+		document, _ := json.Marshal(stateDocument{Payload: payload, Signature: signState([]byte("secret"), payload)})
+
+		rr := importState(service, document)
+
+		tests.H(t).IntEql(rr.Code, http.StatusBadRequest)
+		tests.H(t).StringContains(rr.Body.String(), ErrStateFormatUnsupported.Error())
+	})
+
+	t.Run("returns 409 while updating", func(t *testing.T) {
+		defer tearDown(t)
+		service, _ := setupStateUIService(t, "secret")
+		document := exportState(t, service)
+		setServiceUpdating(service, "2.25.0")
+
+		rr := importState(service, document)
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+	})
+
+	t.Run("imports through the cluster operation", func(t *testing.T) {
+		defer tearDown(t)
+		service, _ := setupStateUIService(t, "secret")
+		clusterStatus := &fakeClusterStatus{}
+		service.ClusterStatus = clusterStatus
+		document := exportState(t, service)
+
+		rr := importState(service, document)
+
+		tests.H(t).IntEql(rr.Code, http.StatusAccepted)
+		tests.H(t).IntEql(len(clusterStatus.Acquired), 1)
+		tests.H(t).StringEql(clusterStatus.Acquired[0].Operation, OperationImport)
+		tests.H(t).IntEql(clusterStatus.Released, 1)
+		results := service.operationHistory().Results()
+		tests.H(t).IntEql(len(results), 1)
+		tests.H(t).StringEql(results[0].Operation, OperationImport)
+		entries, err := service.auditLog().Entries(context.Background(), 10)
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(len(entries), 1)
+		tests.H(t).StringEql(entries[0].Operation, OperationImport)
+		tests.H(t).StringEql(entries[0].ToVersion, "2.24.4")
+	})
+
+	t.Run("returns 501 without signing key", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/state/export/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusNotImplemented)
+	})
+}
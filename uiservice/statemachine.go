@@ -0,0 +1,178 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// stateMachine is a transition table, the states are listed in the order they are usually passed
+type stateMachine struct {
+	Name    string   `json:"name"`
+	Initial string   `json:"initial"`
+	States  []string `json:"states"`
+	// Transitions are the states each state may move to, terminal states have none
+	Transitions map[string][]string `json:"transitions"`
+}
+
+// allows is true if the machine may move from one state to the other, staying in a state is always allowed
+func (m stateMachine) allows(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, next := range m.Transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// jobStateMachine is the update job of this master, jobStore rejects the transitions it does not allow
+var jobStateMachine = stateMachine{
+	Name:    "node",
+	Initial: JobPending,
+	States:  []string{JobPending, JobQueued, JobDownloading, JobUnpacking, JobSwapping, JobDone, JobFailed, JobCancelled, JobSuperseded},
+	Transitions: map[string][]string{
+		JobPending:     {JobQueued, JobDownloading, JobUnpacking, JobSwapping, JobDone, JobFailed, JobCancelled, JobSuperseded},
+		JobQueued:      {JobDownloading, JobUnpacking, JobSwapping, JobDone, JobFailed, JobCancelled, JobSuperseded},
+		JobDownloading: {JobUnpacking, JobDone, JobFailed, JobCancelled, JobSuperseded},
+		JobUnpacking:   {JobSwapping, JobDone, JobFailed, JobCancelled, JobSuperseded},
+		JobSwapping:    {JobDone, JobFailed, JobCancelled},
+	},
+}
+
+// clusterStateMachine is the cluster-wide change of the served version. clusterState derives the state from
+// the node states on each request, the transitions are those between two consecutive derivations.
+var clusterStateMachine = stateMachine{
+	Name:    "cluster",
+	Initial: ClusterStateComplete,
+	States:  []string{ClusterStateRequested, ClusterStateInProgress, ClusterStateComplete, ClusterStateFailed},
+	Transitions: map[string][]string{
+		ClusterStateRequested:  {ClusterStateInProgress, ClusterStateComplete, ClusterStateFailed},
+		ClusterStateInProgress: {ClusterStateComplete, ClusterStateFailed},
+		ClusterStateComplete:   {ClusterStateRequested, ClusterStateInProgress},
+		ClusterStateFailed:     {ClusterStateRequested, ClusterStateInProgress},
+	},
+}
+
+// stateMachineView is a state machine with the states it is currently in
+type stateMachineView struct {
+	stateMachine
+	// Current are the states of the running jobs of the node machine, the derived state of the cluster machine.
+	// It is empty if no job runs or the cluster state is unknown.
+	Current []string `json:"current"`
+}
+
+// currentJobStates returns the distinct states of the jobs that are not in a terminal state
+func (s *jobStore) currentJobStates() []string {
+	s.Lock()
+	defer s.Unlock()
+	seen := map[string]bool{}
+	current := []string{}
+	for _, id := range s.order {
+		state := s.jobs[id].State
+		if len(jobStateMachine.Transitions[state]) > 0 && !seen[state] {
+			seen[state] = true
+			current = append(current, state)
+		}
+	}
+	sort.Strings(current)
+	return current
+}
+
+// currentClusterState derives the cluster state like GET /api/v1/status/ does, "" if it cannot be read
+func currentClusterState(service *UIService) string {
+	nodes, ok := service.VersionStore.(ClusterNodes)
+	if !ok {
+		return ""
+	}
+	stored, err := service.VersionStore.CurrentVersion()
+	if err != nil {
+		return ""
+	}
+	states, err := nodes.NodeStates()
+	if err != nil {
+		return ""
+	}
+	var op *ClusterOperation
+	if service.ClusterStatus != nil {
+		if op, err = service.ClusterStatus.ActiveOperation(); err != nil {
+			return ""
+		}
+	}
+	return clusterState(string(stored), op, states).State
+}
+
+// dot renders the state machines as a graphviz digraph with one cluster per machine, current states are filled
+func dot(machines []stateMachineView) string {
+	var b strings.Builder
+	b.WriteString("digraph statemachines {\n\trankdir=LR;\n")
+	for _, m := range machines {
+		fmt.Fprintf(&b, "\tsubgraph cluster_%s {\n\t\tlabel=%q;\n", m.Name, m.Name)
+		current := map[string]bool{}
+		for _, state := range m.Current {
+			current[state] = true
+		}
+		for _, state := range m.States {
+			attrs := ""
+			if len(m.Transitions[state]) == 0 {
+				attrs = " shape=doublecircle"
+			}
+			if current[state] {
+				attrs += " style=filled"
+			}
+			fmt.Fprintf(&b, "\t\t%q [label=%q%s];\n", m.Name+"."+state, state, attrs)
+		}
+		fmt.Fprintf(&b, "\t\t%q [shape=point];\n\t\t%q -> %q;\n", m.Name+".start", m.Name+".start", m.Name+"."+m.Initial)
+		for _, state := range m.States {
+			for _, next := range m.Transitions[state] {
+				fmt.Fprintf(&b, "\t\t%q -> %q;\n", m.Name+"."+state, m.Name+"."+next)
+			}
+		}
+		b.WriteString("\t}\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// stateMachineHandler returns the transition tables of the update jobs of this master and of the cluster-wide
+// version change with their current states, as JSON or with ?format=dot as a graphviz digraph
+func stateMachineHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cluster := []string{}
+		if state := currentClusterState(service); state != "" {
+			cluster = append(cluster, state)
+		}
+		machines := []stateMachineView{
+			{stateMachine: jobStateMachine, Current: service.updateJobs().currentJobStates()},
+			{stateMachine: clusterStateMachine, Current: cluster},
+		}
+
+		switch r.URL.Query().Get("format") {
+		case "", "json":
+		case "dot":
+			w.Header().Add("Content-Type", "text/vnd.graphviz; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(dot(machines)))
+			return
+		default:
+			http.Error(w, "format must be json or dot", http.StatusBadRequest)
+			return
+		}
+
+		js, err := json.Marshal(machines)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to marshal the state machines")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
@@ -0,0 +1,102 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func getStateMachines(service *UIService, query string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/debug/statemachine/"+query, nil))
+	return rr
+}
+
+func TestStateMachineHandler(t *testing.T) {
+	t.Run("is not registered unless debug endpoints are enabled", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := getStateMachines(service, "")
+
+		tests.H(t).IntEql(rr.Code, http.StatusNotFound)
+	})
+
+	t.Run("returns the transition tables with the states of the running jobs", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupDebugUIService(UpdateManagerDouble())
+		jobs := service.updateJobs()
+		running := jobs.create("2.25.0")
+		jobs.setState(running.ID, JobDownloading)
+		done := jobs.create("2.24.4")
+		jobs.complete(done.ID, &ClusterOperationResult{Operation: OperationUpdate})
+
+		rr := getStateMachines(service, "")
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var machines []stateMachineView
+		tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &machines))
+		tests.H(t).IntEql(len(machines), 2)
+		tests.H(t).StringEql(machines[0].Name, "node")
+		tests.H(t).InterfaceEql(machines[0].Transitions, jobStateMachine.Transitions)
+		tests.H(t).InterfaceEql(machines[0].Current, []string{JobDownloading})
+		tests.H(t).StringEql(machines[1].Name, "cluster")
+		tests.H(t).InterfaceEql(machines[1].Current, []string{})
+	})
+
+	t.Run("renders the state machines as graphviz digraph", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupDebugUIService(UpdateManagerDouble())
+
+		rr := getStateMachines(service, "?format=dot")
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringContains(rr.Body.String(), "digraph statemachines {")
+		tests.H(t).StringContains(rr.Body.String(), `"node.swapping" -> "node.done";`)
+		tests.H(t).StringContains(rr.Body.String(), `"cluster.Failed" -> "cluster.Requested";`)
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupDebugUIService(UpdateManagerDouble())
+
+		rr := getStateMachines(service, "?format=svg")
+
+		tests.H(t).IntEql(rr.Code, http.StatusBadRequest)
+	})
+}
+
+func TestJobStateMachine(t *testing.T) {
+	t.Run("ignores transitions out of terminal states", func(t *testing.T) {
+		store := newJobStore(2)
+		job := store.create("2.24.4")
+		store.setState(job.ID, JobQueued)
+		store.setState(job.ID, JobCancelled)
+
+		store.setState(job.ID, JobDownloading)
+		store.complete(job.ID, &ClusterOperationResult{Operation: OperationUpdate})
+
+		job, _ = store.get(job.ID)
+		tests.H(t).StringEql(job.State, JobCancelled)
+		tests.H(t).BoolEql(job.Result == nil, true)
+	})
+
+	t.Run("lists every state of the transitions", func(t *testing.T) {
+		for _, machine := range []stateMachine{jobStateMachine, clusterStateMachine} {
+			states := map[string]bool{}
+			for _, state := range machine.States {
+				states[state] = true
+			}
+			tests.H(t).BoolEqlWithMessage(states[machine.Initial], true, machine.Name+" initial state")
+			for from, next := range machine.Transitions {
+				tests.H(t).BoolEqlWithMessage(states[from], true, machine.Name+" state "+from)
+				for _, to := range next {
+					tests.H(t).BoolEqlWithMessage(states[to], true, machine.Name+" state "+to)
+				}
+			}
+		}
+	})
+}
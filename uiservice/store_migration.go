@@ -0,0 +1,189 @@
+package uiservice
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// Backends of a version store migration
+const (
+	MigrationSource = "source"
+	MigrationTarget = "target"
+)
+
+var versionStoreDivergences = metrics.DefaultRegistry.Counter(
+	"ui_update_version_store_divergences_total",
+	"Number of times the backends of a version store migration were found storing different versions.",
+)
+
+// VersionStoreDivergence is a difference between the versions stored by the backends of a migration
+type VersionStoreDivergence struct {
+	Source UIVersion `json:"source"`
+	Target UIVersion `json:"target"`
+	Time   time.Time `json:"time"`
+}
+
+// MigrationStatus describes a running migration between version store backends
+type MigrationStatus struct {
+	// Until is the end of the dual-write period, reads and writes go to the target afterwards
+	Until       time.Time `json:"until"`
+	ReadingFrom string    `json:"readingFrom"`
+	// Divergence is set while the backends store different versions
+	Divergence *VersionStoreDivergence `json:"divergence,omitempty"`
+}
+
+// VersionStoreMigration is implemented by version stores migrating between backends
+type VersionStoreMigration interface {
+	MigrationStatus() MigrationStatus
+}
+
+// migrationVersionStore moves the stored version from one backend to another without resetting the ui.
+// Until the end of the period versions are read from source and written to both, afterwards they are read
+// from and written to target only. Every read and every change seen by a watch compares both backends.
+// The optional interfaces coordinating the masters, like ClusterStatus, are served by the coordinating backend.
+type migrationVersionStore struct {
+	source     VersionStore
+	target     VersionStore
+	until      time.Time
+	clock      clock.Clock
+	divergence *VersionStoreDivergence
+	sync.Mutex
+}
+
+// NewMigrationVersionStore reads from source and writes to source and target for period, then switches to target
+func NewMigrationVersionStore(source, target VersionStore, period time.Duration) VersionStore {
+	return newMigrationVersionStore(source, target, period, clock.New())
+}
+
+func newMigrationVersionStore(source, target VersionStore, period time.Duration, clk clock.Clock) *migrationVersionStore {
+	return &migrationVersionStore{
+		source: source,
+		target: target,
+		until:  clk.Now().Add(period),
+		clock:  clk,
+	}
+}
+
+// coordination returns the backend coordinating the masters during the whole migration, the one keeping the
+// cluster status, so the cluster operations stay locked when the reads switch to target
+func (s *migrationVersionStore) coordination() VersionStore {
+	for _, store := range []VersionStore{s.source, s.target} {
+		if _, ok := store.(ClusterStatus); ok {
+			return store
+		}
+	}
+	return s.source
+}
+
+// coordinatingStore returns the backend of versionStore coordinating the masters, versionStore itself
+// unless it is migrating
+func coordinatingStore(versionStore VersionStore) VersionStore {
+	if migration, ok := versionStore.(*migrationVersionStore); ok {
+		return migration.coordination()
+	}
+	return versionStore
+}
+
+// dualWriting is true until the end of the migration period
+func (s *migrationVersionStore) dualWriting() bool {
+	return s.clock.Now().Before(s.until)
+}
+
+// CurrentVersion returns the version of the backend read from and reports a divergence of the other one
+func (s *migrationVersionStore) CurrentVersion() (UIVersion, error) {
+	if !s.dualWriting() {
+		return s.target.CurrentVersion()
+	}
+	version, err := s.source.CurrentVersion()
+	if err != nil {
+		return version, err
+	}
+	s.verify(version)
+	return version, nil
+}
+
+// UpdateCurrentVersion writes to source first while dual-writing, a failed write to target does not fail
+// the update, it is reported as divergence
+func (s *migrationVersionStore) UpdateCurrentVersion(version UIVersion) error {
+	if !s.dualWriting() {
+		return s.target.UpdateCurrentVersion(version)
+	}
+	if err := s.source.UpdateCurrentVersion(version); err != nil {
+		return err
+	}
+	if err := s.target.UpdateCurrentVersion(version); err != nil {
+		logrus.WithError(err).WithField("version", version).Warn("Failed to write the version to the migration target")
+	}
+	s.verify(version)
+	return nil
+}
+
+// WatchForVersionChange watches both backends, listener is called for the changes of the backend read from
+func (s *migrationVersionStore) WatchForVersionChange(listener VersionChangeListener) error {
+	if err := s.source.WatchForVersionChange(func(version UIVersion) {
+		if s.dualWriting() {
+			s.verify(version)
+			listener(version)
+		}
+	}); err != nil {
+		return err
+	}
+	return s.target.WatchForVersionChange(func(version UIVersion) {
+		if !s.dualWriting() {
+			listener(version)
+			return
+		}
+		if source, err := s.source.CurrentVersion(); err == nil {
+			s.verify(source)
+		}
+	})
+}
+
+// verify compares the version of source with the one stored by target
+func (s *migrationVersionStore) verify(source UIVersion) {
+	target, err := s.target.CurrentVersion()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to read the version of the migration target")
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	if source == target {
+		if s.divergence != nil {
+			logrus.WithField("version", source).Info("Version store backends converged")
+		}
+		s.divergence = nil
+		return
+	}
+	if s.divergence != nil && s.divergence.Source == source && s.divergence.Target == target {
+		return
+	}
+	s.divergence = &VersionStoreDivergence{Source: source, Target: target, Time: s.clock.Now().UTC()}
+	versionStoreDivergences.Inc()
+	logrus.WithFields(logrus.Fields{"source": source, "target": target}).Warn("Version store backends diverged")
+}
+
+// MigrationStatus returns the end of the dual-write period, the backend read from and the divergence
+func (s *migrationVersionStore) MigrationStatus() MigrationStatus {
+	status := MigrationStatus{Until: s.until.UTC(), ReadingFrom: MigrationTarget}
+	if s.dualWriting() {
+		status.ReadingFrom = MigrationSource
+	}
+	s.Lock()
+	defer s.Unlock()
+	status.Divergence = s.divergence
+	return status
+}
+
+// Close closes the connections of both backends
+func (s *migrationVersionStore) Close() {
+	for _, store := range []VersionStore{s.source, s.target} {
+		if closer, ok := store.(VersionStoreCloser); ok {
+			closer.Close()
+		}
+	}
+}
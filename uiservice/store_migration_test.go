@@ -0,0 +1,178 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+// memVersionStore keeps the version in memory and notifies its listeners on writes
+type memVersionStore struct {
+	version     UIVersion
+	updateError error
+	listeners   []VersionChangeListener
+}
+
+func (s *memVersionStore) CurrentVersion() (UIVersion, error) {
+	return s.version, nil
+}
+
+func (s *memVersionStore) UpdateCurrentVersion(version UIVersion) error {
+	if s.updateError != nil {
+		return s.updateError
+	}
+	s.version = version
+	for _, listener := range s.listeners {
+		listener(version)
+	}
+	return nil
+}
+
+func (s *memVersionStore) WatchForVersionChange(listener VersionChangeListener) error {
+	s.listeners = append(s.listeners, listener)
+	return nil
+}
+
+// coordinatingVersionStore is a memVersionStore keeping the cluster status
+type coordinatingVersionStore struct {
+	memVersionStore
+	fakeClusterStatus
+}
+
+func TestMigrationVersionStore(t *testing.T) {
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("reads from source and writes to both during the period", func(t *testing.T) {
+		source := &memVersionStore{version: "2.24.4"}
+		target := &memVersionStore{version: "2.24.4"}
+		store := newMigrationVersionStore(source, target, time.Hour, clock.NewFake(start))
+
+		tests.H(t).IsNil(store.UpdateCurrentVersion("2.25.0"))
+		source.version = "2.25.1"
+		version, err := store.CurrentVersion()
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(string(version), "2.25.1")
+		tests.H(t).StringEql(string(target.version), "2.25.0")
+		tests.H(t).StringEql(store.MigrationStatus().ReadingFrom, MigrationSource)
+	})
+
+	t.Run("switches to target after the period", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		source := &memVersionStore{version: "2.24.4"}
+		target := &memVersionStore{version: "2.24.4"}
+		store := newMigrationVersionStore(source, target, time.Hour, clk)
+
+		clk.Advance(time.Hour)
+		tests.H(t).IsNil(store.UpdateCurrentVersion("2.25.0"))
+
+		version, _ := store.CurrentVersion()
+		tests.H(t).StringEql(string(version), "2.25.0")
+		tests.H(t).StringEql(string(source.version), "2.24.4")
+		tests.H(t).StringEql(store.MigrationStatus().ReadingFrom, MigrationTarget)
+	})
+
+	t.Run("reports a divergence until the backends converge", func(t *testing.T) {
+		source := &memVersionStore{version: "2.24.4"}
+		target := &memVersionStore{version: "2.24.4", updateError: errors.New("unavailable")}
+		store := newMigrationVersionStore(source, target, time.Hour, clock.NewFake(start))
+		diverged := versionStoreDivergences.Value()
+
+		tests.H(t).IsNil(store.UpdateCurrentVersion("2.25.0"))
+
+		divergence := store.MigrationStatus().Divergence
+		tests.H(t).NotNil(divergence)
+		tests.H(t).StringEql(string(divergence.Source), "2.25.0")
+		tests.H(t).StringEql(string(divergence.Target), "2.24.4")
+		tests.H(t).Int64Eql(int64(versionStoreDivergences.Value()-diverged), 1)
+
+		target.updateError = nil
+		tests.H(t).IsNil(target.UpdateCurrentVersion("2.25.0"))
+		store.CurrentVersion()
+		tests.H(t).BoolEql(store.MigrationStatus().Divergence == nil, true)
+	})
+
+	t.Run("fails the update if the source write fails", func(t *testing.T) {
+		source := &memVersionStore{version: "2.24.4", updateError: errors.New("unavailable")}
+		target := &memVersionStore{version: "2.24.4"}
+		store := newMigrationVersionStore(source, target, time.Hour, clock.NewFake(start))
+
+		tests.H(t).NotNil(store.UpdateCurrentVersion("2.25.0"))
+		tests.H(t).StringEql(string(target.version), "2.24.4")
+	})
+
+	t.Run("notifies the changes of the backend read from", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		source := &memVersionStore{version: "2.24.4"}
+		target := &memVersionStore{version: "2.24.4"}
+		store := newMigrationVersionStore(source, target, time.Hour, clk)
+		var changes []UIVersion
+		tests.H(t).IsNil(store.WatchForVersionChange(func(version UIVersion) {
+			changes = append(changes, version)
+		}))
+
+		store.UpdateCurrentVersion("2.25.0")
+		clk.Advance(time.Hour)
+		source.UpdateCurrentVersion("2.26.0")
+		store.UpdateCurrentVersion("2.27.0")
+
+		tests.H(t).InterfaceEql(changes, []UIVersion{"2.25.0", "2.27.0"})
+	})
+
+	t.Run("locks the cluster operations with the coordinating backend after the period", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		target := &coordinatingVersionStore{}
+		store := newMigrationVersionStore(&memVersionStore{}, target, time.Hour, clk)
+
+		clk.Advance(2 * time.Hour)
+		clusterStatus, ok := coordinatingStore(store).(ClusterStatus)
+
+		tests.H(t).BoolEql(ok, true)
+		clusterStatus.AcquireOperation(ClusterOperation{ID: "op-1"})
+		tests.H(t).IntEql(len(target.Acquired), 1)
+	})
+
+	t.Run("is created from the config", func(t *testing.T) {
+		source, target := path.Join(os.TempDir(), "ui-update-migration-source.json"), path.Join(os.TempDir(), "ui-update-migration-target.json")
+		defer os.Remove(target)
+		cfg, err := config.Parse([]string{
+			"--version-store", "file",
+			"--version-store-file", target,
+			"--version-store-migrate-from", "file",
+		})
+		tests.H(t).ErrEql(err, config.ErrInvalidVersionStoreMigration)
+		cfg, err = config.Parse([]string{"--version-store-file", source, "--version-store-migrate-from", "file", "--zk-addr", "127.0.0.1:1"})
+		tests.H(t).IsNil(err)
+		defer os.Remove(source)
+
+		store, err := NewVersionStore(cfg)
+
+		tests.H(t).IsNil(err)
+		migration, ok := store.(VersionStoreMigration)
+		tests.H(t).BoolEql(ok, true)
+		tests.H(t).StringEql(migration.MigrationStatus().ReadingFrom, MigrationSource)
+		store.(VersionStoreCloser).Close()
+	})
+
+	t.Run("is listed in the diagnostics", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.VersionStore = newMigrationVersionStore(&memVersionStore{}, &memVersionStore{}, time.Hour, clock.NewFake(start))
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", diagnosticsPath, nil))
+
+		var response diagnosticsResponse
+		tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &response))
+		tests.H(t).NotNil(response.StoreMigration)
+		tests.H(t).StringEql(response.StoreMigration.ReadingFrom, MigrationSource)
+	})
+}
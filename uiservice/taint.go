@@ -0,0 +1,60 @@
+package uiservice
+
+import (
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/metrics"
+	"github.com/pkg/errors"
+)
+
+// ErrDistTainted occurs if the files of the served version changed after it was activated
+var ErrDistTainted = errors.New("files of the served version changed after activation")
+
+var distTainted = metrics.DefaultRegistry.Gauge(
+	"ui_update_dist_tainted",
+	"1 if the files of the served version changed after it was activated, 0 otherwise.",
+)
+
+// DistTaint is a served version whose files were changed on disk, e.g. by hand-editing the dist directory
+type DistTaint struct {
+	Version    string    `json:"version"`
+	DetectedAt time.Time `json:"detectedAt"`
+	// Restoring is true if integrity-auto-restore replaces the changed files
+	Restoring bool `json:"restoring"`
+}
+
+// distTaint returns the taint of the served version, nil if its files are unchanged
+func (service *UIService) distTaint() *DistTaint {
+	service.Lock()
+	defer service.Unlock()
+	if service.taint == nil {
+		return nil
+	}
+	taint := *service.taint
+	return &taint
+}
+
+// markTainted records that the files of version changed, keeping the time it was first detected. It returns
+// true if the taint is new.
+func (service *UIService) markTainted(version string, restoring bool) bool {
+	service.Lock()
+	defer service.Unlock()
+	distTainted.Set(1)
+	if service.taint != nil && service.taint.Version == version {
+		service.taint.Restoring = restoring
+		return false
+	}
+	service.taint = &DistTaint{Version: version, DetectedAt: time.Now().UTC(), Restoring: restoring}
+	return true
+}
+
+// clearTaint forgets the taint once the served files match their manifest again, it returns true if the
+// service was tainted
+func (service *UIService) clearTaint() bool {
+	service.Lock()
+	defer service.Unlock()
+	distTainted.Set(0)
+	tainted := service.taint != nil
+	service.taint = nil
+	return tainted
+}
@@ -0,0 +1,166 @@
+package uiservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// ReasonTimeout is the reason code of a request that exceeded its route timeout
+const ReasonTimeout = "TIMEOUT"
+
+var timedOutRequests = metrics.DefaultRegistry.Counter(
+	"ui_update_api_timeouts_total",
+	"Number of API requests answered with 503 because they exceeded api-read-timeout or api-update-timeout.",
+)
+
+// timeoutResponse is the body of a request that exceeded its route timeout
+type timeoutResponse struct {
+	Message    string `json:"message"`
+	ReasonCode string `json:"reasonCode"`
+	// Timeout is the limit of the route, e.g. "30s"
+	Timeout string `json:"timeout"`
+}
+
+// timeoutWriter buffers the response of a handler, so it can be dropped once the route timeout expired
+type timeoutWriter struct {
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+	sync.Mutex
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.Lock()
+	defer tw.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.body.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.Lock()
+	defer tw.Unlock()
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = status
+}
+
+// routeTimeout answers requests the handler does not finish within timeout with 503 and a timeoutResponse, like
+// http.TimeoutHandler. The request context of the handler is done once the timeout expired, so the handler
+// stops its work instead of running on unnoticed. A timeout of 0 disables the limit.
+func routeTimeout(timeout time.Duration, handler func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	if timeout <= 0 {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		tw := &timeoutWriter{header: http.Header{}}
+		done := make(chan struct{})
+		panicked := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			handler(tw, r)
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+			tw.Lock()
+			defer tw.Unlock()
+			for key, values := range tw.header {
+				w.Header()[key] = values
+			}
+			if tw.status == 0 {
+				tw.status = http.StatusOK
+			}
+			w.WriteHeader(tw.status)
+			w.Write(tw.body.Bytes())
+		case <-ctx.Done():
+			tw.Lock()
+			tw.timedOut = true
+			tw.Unlock()
+			if ctx.Err() != context.DeadlineExceeded {
+				// the client went away, nobody reads the response
+				return
+			}
+			timedOutRequests.Inc()
+			logrus.WithFields(logrus.Fields{
+				"method":  r.Method,
+				"path":    r.URL.Path,
+				"timeout": timeout.String(),
+			}).Warn("API request exceeded its route timeout")
+			writeTimeout(w, timeout)
+		}
+	}
+}
+
+func writeTimeout(w http.ResponseWriter, timeout time.Duration) {
+	response := timeoutResponse{
+		Message:    "The request did not finish within " + timeout.String(),
+		ReasonCode: ReasonTimeout,
+		Timeout:    timeout.String(),
+	}
+	js, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, response.Message, http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write(js)
+}
+
+// cancelOnTimeout cancels the update running on this master once the route timeout of r expired, so a
+// synchronous update that is answered with 503 does not keep downloading. The returned func stops watching,
+// it is called once the handler finished.
+func cancelOnTimeout(service *UIService, r *http.Request) func() {
+	service.Lock()
+	running := service.preemptible
+	service.Unlock()
+	if running == nil {
+		return func() {}
+	}
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-r.Context().Done():
+			if r.Context().Err() != context.DeadlineExceeded {
+				return
+			}
+			service.Lock()
+			stopped := service.preemptible == running && running.stop()
+			service.Unlock()
+			if stopped {
+				logrus.WithField("path", r.URL.Path).Warn("Cancelled the update of a request that exceeded its route timeout")
+			}
+		case <-finished:
+		}
+	}()
+	return func() { close(finished) }
+}
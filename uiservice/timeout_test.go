@@ -0,0 +1,122 @@
+package uiservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+)
+
+func TestRouteTimeout(t *testing.T) {
+	t.Run("passes the response of a handler finishing in time", func(t *testing.T) {
+		handler := routeTimeout(time.Second, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Test", "yes")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("accepted"))
+		})
+
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", "/api/v1/version/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusAccepted)
+		tests.H(t).StringEql(rr.Header().Get("X-Test"), "yes")
+		tests.H(t).StringEql(rr.Body.String(), "accepted")
+	})
+
+	t.Run("answers with 503 and ends the request context once the timeout expired", func(t *testing.T) {
+		ended := make(chan error, 1)
+		handler := routeTimeout(10*time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			ended <- r.Context().Err()
+			w.Write([]byte("too late"))
+		})
+
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest("GET", "/api/v1/version/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusServiceUnavailable)
+		var response timeoutResponse
+		tests.H(t).IsNil(json.Unmarshal(rr.Body.Bytes(), &response))
+		tests.H(t).StringEql(response.ReasonCode, ReasonTimeout)
+		tests.H(t).StringEql(response.Timeout, "10ms")
+		tests.H(t).ErrEql(<-ended, context.DeadlineExceeded)
+	})
+
+	t.Run("does not limit the handler without a timeout", func(t *testing.T) {
+		handler := routeTimeout(0, func(w http.ResponseWriter, r *http.Request) {
+			_, limited := r.Context().Deadline()
+			tests.H(t).BoolEql(limited, false)
+		})
+
+		handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/version/", nil))
+	})
+}
+
+func TestUpdateRouteTimeout(t *testing.T) {
+	t.Run("cancels a synchronous update exceeding the update timeout", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.Config, _ = config.Parse([]string{
+			"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+			"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+			"--ui-dist-stage-symlink", "../testdata/uiserv-sandbox/new-dcos-ui-dist",
+			"--api-update-timeout", "20ms",
+		})
+		um := UpdateManagerDouble()
+		um.UpdateNewVersionPath = service.Config.DefaultDocRoot()
+		um.UpdateCall = func(string) {
+			<-operationContext(service).Done()
+		}
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/update/2.25.0/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusServiceUnavailable)
+		// the update stops in the background after the response
+		deadline := time.Now().Add(5 * time.Second)
+		results := service.operationHistory().Results()
+		for len(results) == 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+			results = service.operationHistory().Results()
+		}
+		tests.H(t).IntEql(len(results), 1)
+		tests.H(t).StringEql(results[0].ErrorCode, ErrorCodeCancelled)
+	})
+	t.Run("cancels a hooks rerun exceeding the update timeout", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.Config, _ = config.Parse([]string{
+			"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+			"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+			"--ui-dist-stage-symlink", "../testdata/uiserv-sandbox/new-dcos-ui-dist",
+			"--api-update-timeout", "20ms",
+		})
+		um := UpdateManagerDouble()
+		um.HookRerunResult = &updatemanager.HookRerun{Version: "2.24.4"}
+		cancelled := make(chan struct{})
+		um.HookRerunCall = func(ctx context.Context) {
+			<-ctx.Done()
+			close(cancelled)
+		}
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/hooks/rerun/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusServiceUnavailable)
+		select {
+		case <-cancelled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the hooks rerun was not cancelled")
+		}
+	})
+}
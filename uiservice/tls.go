@@ -0,0 +1,67 @@
+package uiservice
+
+import (
+	"crypto/tls"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrTLSDisabled is returned when the certificate is reloaded although the listener does not serve HTTPS
+var ErrTLSDisabled = errors.New("tls-cert-file is not configured")
+
+// certReloader serves the certificate loaded from certFile and keyFile, it is replaced by
+// Reload so that a renewed certificate is used for new connections without a restart
+type certReloader struct {
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	sync.RWMutex
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	reloader := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reload reads the certificate and key again, the previous certificate is kept if they are invalid
+func (r *certReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load TLS certificate")
+	}
+	r.Lock()
+	r.cert = &cert
+	r.Unlock()
+	logrus.WithField("certFile", r.certFile).Info("Loaded TLS certificate")
+	return nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.RLock()
+	defer r.RUnlock()
+	return r.cert, nil
+}
+
+func (r *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: r.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+}
+
+// ReloadCertificate loads the TLS certificate and key from disk again, e.g. after they were renewed
+func (service *UIService) ReloadCertificate() error {
+	if service.certs == nil {
+		return ErrTLSDisabled
+	}
+	return service.certs.Reload()
+}
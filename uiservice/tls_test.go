@@ -0,0 +1,112 @@
+package uiservice
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestCertReloader(t *testing.T) {
+	t.Run("serves the certificate loaded on creation", func(t *testing.T) {
+		dir := tempCertDir(t)
+		defer os.RemoveAll(dir)
+		certFile, keyFile := writeTestCert(t, dir, "first")
+
+		reloader, err := newCertReloader(certFile, keyFile)
+		tests.H(t).IsNil(err)
+
+		tests.H(t).StringEql(servedCommonName(t, reloader), "first")
+	})
+
+	t.Run("returns an error for a missing certificate", func(t *testing.T) {
+		dir := tempCertDir(t)
+		defer os.RemoveAll(dir)
+
+		_, err := newCertReloader(path.Join(dir, "missing.crt"), path.Join(dir, "missing.key"))
+		tests.H(t).NotNil(err)
+	})
+
+	t.Run("serves the renewed certificate after a reload", func(t *testing.T) {
+		dir := tempCertDir(t)
+		defer os.RemoveAll(dir)
+		certFile, keyFile := writeTestCert(t, dir, "first")
+		reloader, err := newCertReloader(certFile, keyFile)
+		tests.H(t).IsNil(err)
+
+		writeTestCert(t, dir, "renewed")
+		tests.H(t).IsNil(reloader.Reload())
+
+		tests.H(t).StringEql(servedCommonName(t, reloader), "renewed")
+	})
+
+	t.Run("keeps the previous certificate if the reload fails", func(t *testing.T) {
+		dir := tempCertDir(t)
+		defer os.RemoveAll(dir)
+		certFile, keyFile := writeTestCert(t, dir, "first")
+		reloader, err := newCertReloader(certFile, keyFile)
+		tests.H(t).IsNil(err)
+
+		tests.H(t).IsNil(ioutil.WriteFile(certFile, []byte("garbage"), 0600))
+		tests.H(t).NotNil(reloader.Reload())
+
+		tests.H(t).StringEql(servedCommonName(t, reloader), "first")
+	})
+}
+
+func TestReloadCertificate(t *testing.T) {
+	t.Run("returns ErrTLSDisabled without certificate", func(t *testing.T) {
+		service := &UIService{}
+
+		tests.H(t).ErrEql(service.ReloadCertificate(), ErrTLSDisabled)
+	})
+}
+
+func tempCertDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "ui-update-tls")
+	tests.H(t).IsNil(err)
+	return dir
+}
+
+func servedCommonName(t *testing.T, reloader *certReloader) string {
+	cert, err := reloader.GetCertificate(&tls.ClientHelloInfo{})
+	tests.H(t).IsNil(err)
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	tests.H(t).IsNil(err)
+	return parsed.Subject.CommonName
+}
+
+// writeTestCert writes a self-signed certificate for commonName to tls.crt and tls.key in dir
+func writeTestCert(t *testing.T, dir, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tests.H(t).IsNil(err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	tests.H(t).IsNil(err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	tests.H(t).IsNil(err)
+
+	certFile := path.Join(dir, "tls.crt")
+	keyFile := path.Join(dir, "tls.key")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	tests.H(t).IsNil(ioutil.WriteFile(certFile, certPEM, 0600))
+	tests.H(t).IsNil(ioutil.WriteFile(keyFile, keyPEM, 0600))
+	return certFile, keyFile
+}
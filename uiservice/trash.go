@@ -0,0 +1,65 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// trashHandler lists the removed versions kept in the trash
+func trashHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		trashed, err := service.UpdateManager.TrashedVersions()
+		if err != nil {
+			logrus.WithError(err).Error("Failed to list the trash")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		js, err := json.Marshal(trashed)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(js)
+	}
+}
+
+// restoreVersionHandler moves a removed version back out of the trash, it is not served until
+// it is selected with an update
+func restoreVersionHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version := mux.Vars(r)["version"]
+		logrus.WithField("version", version).Debug("Received restore version request.")
+
+		if _, lockErr := setServiceUpdating(service, version); lockErr != nil {
+			http.Error(w, "Cannot restore version, an update is currently in progress.", http.StatusConflict)
+			return
+		}
+		defer resetServiceFromUpdate(service)
+
+		if writableErr := service.UpdateManager.CheckWritable(); writableErr != nil {
+			http.Error(w, writableErr.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		switch err := service.UpdateManager.RestoreVersion(version); err {
+		case nil:
+			w.Header().Add("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(fmt.Sprintf("Restored version %s", version)))
+		case updatemanager.ErrVersionNotTrashed:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case updatemanager.ErrVersionAlreadyExists:
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			logrus.WithError(err).WithField("version", version).Error("Failed to restore version")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package uiservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+)
+
+func TestTrashHandlers(t *testing.T) {
+	t.Run("lists the trash", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.TrashedResult = []updatemanager.TrashedVersion{
+			{Version: "2.24.3", TrashedAt: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC), Size: 42},
+		}
+		service.UpdateManager = um
+
+		req, _ := http.NewRequest("GET", "/api/v1/trash/", nil)
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringEql(rr.Body.String(), `[{"version":"2.24.3","trashedAt":"2026-10-01T12:00:00Z","size":42}]`)
+	})
+
+	t.Run("restores a trashed version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		service.UpdateManager = um
+
+		req, _ := http.NewRequest("POST", "/api/v1/trash/2.24.3/restore/", nil)
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).InterfaceEql(um.RestoredVersions, []string{"2.24.3"})
+		tests.H(t).BoolEql(service.updating, false)
+	})
+
+	t.Run("maps restore errors to status codes", func(t *testing.T) {
+		var testCases = []struct {
+			err        error
+			statusCode int
+		}{
+			{updatemanager.ErrVersionNotTrashed, http.StatusNotFound},
+			{updatemanager.ErrVersionAlreadyExists, http.StatusConflict},
+			{updatemanager.ErrRestoringVersion, http.StatusInternalServerError},
+		}
+		for _, tt := range testCases {
+			service := setupTestUIService()
+			um := UpdateManagerDouble()
+			um.RestoreError = tt.err
+			service.UpdateManager = um
+
+			req, _ := http.NewRequest("POST", "/api/v1/trash/2.24.3/restore/", nil)
+			rr := httptest.NewRecorder()
+			newRouter(service).ServeHTTP(rr, req)
+
+			tests.H(t).IntEql(rr.Code, tt.statusCode)
+			tearDown(t)
+		}
+	})
+
+	t.Run("rejects restoring during an update", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		service.UpdateManager = UpdateManagerDouble()
+		setServiceUpdating(service, "2.24.5")
+
+		req, _ := http.NewRequest("POST", "/api/v1/trash/2.24.3/restore/", nil)
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, req)
+
+		tests.H(t).IntEql(rr.Code, http.StatusConflict)
+	})
+}
@@ -0,0 +1,131 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/metrics"
+	"github.com/dcos/dcos-ui-update-service/zookeeper"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// versionTriggerNode is the parent of the ephemeral sequential nodes created after a version write,
+	// followers watch its children and read the version node right away instead of waiting for the poll
+	versionTriggerNode   = "version-trigger"
+	versionTriggerPrefix = "trigger-"
+	// maxVersionTriggers is the number of trigger nodes kept, older triggers are removed by the writer
+	maxVersionTriggers = 10
+)
+
+var (
+	versionPropagation = metrics.DefaultRegistry.Summary(
+		"ui_update_version_propagation_seconds",
+		"Time from a version write on one master until another master received its trigger.",
+	)
+	versionTriggers = metrics.DefaultRegistry.Counter(
+		"ui_update_version_triggers_total",
+		"Number of version triggers received from other masters.",
+	)
+)
+
+// versionTrigger is the content of a trigger node
+type versionTrigger struct {
+	Version   UIVersion `json:"version"`
+	WrittenAt time.Time `json:"writtenAt"`
+}
+
+func makeVersionTriggerPath(basePath string) string {
+	return path.Join(basePath, versionTriggerNode)
+}
+
+// publishVersionTrigger notifies the other masters of a version write and removes old triggers
+func (zks *zkVersionStore) publishVersionTrigger(version UIVersion) {
+	triggerPath := makeVersionTriggerPath(zks.zkBasePath)
+	data, err := json.Marshal(versionTrigger{Version: version, WrittenAt: zks.clock.Now().UTC()})
+	if err != nil {
+		return
+	}
+	created, err := zks.client.CreateEphemeralSequential(path.Join(triggerPath, versionTriggerPrefix), data, zookeeper.PermAll)
+	if err != nil {
+		log.WithError(err).WithField("version", version).Warn("Failed to publish the version trigger, other masters pick up the version when polling")
+		return
+	}
+	zks.trigger.Lock()
+	zks.trigger.published = path.Base(created)
+	zks.trigger.Unlock()
+
+	children, _, err := zks.client.Children(triggerPath)
+	if err != nil || len(children) <= maxVersionTriggers {
+		return
+	}
+	sort.Strings(children)
+	for _, child := range children[:len(children)-maxVersionTriggers] {
+		if err := zks.client.Delete(path.Join(triggerPath, child)); err != nil {
+			log.WithError(err).WithField("trigger", child).Debug("Failed to remove old version trigger")
+		}
+	}
+}
+
+// createTriggerWatcher watches the trigger nodes, the parent node is created if it is missing
+func (zks *zkVersionStore) createTriggerWatcher() {
+	if zks.triggerWatcher != nil {
+		return
+	}
+	triggerPath := makeVersionTriggerPath(zks.zkBasePath)
+	if zks.readOnly {
+		// a replica watches the node once a master created it
+		if found, _, err := zks.client.Exists(triggerPath); err != nil || !found {
+			log.WithError(err).Warn("The version trigger node does not exist yet, version changes are picked up when polling")
+			return
+		}
+	} else if err := zks.client.Create(triggerPath, []byte{}, zookeeper.PermAll); err != nil && err != zookeeper.ErrNodeExists {
+		log.WithError(err).Warn("Failed to create the version trigger node, version changes are picked up when polling")
+		return
+	}
+	watcher, err := zookeeper.CreateParentNodeWatcher(zks.client, triggerPath, zks.zkPollingInterval, zks.triggerWatcherCallback)
+	if err != nil {
+		log.WithError(err).Warn("Failed to create ZK trigger watcher")
+		return
+	}
+	watcher.SetRestartDelay(zks.reinit.statusDelay())
+	zks.triggerWatcher = watcher
+}
+
+// triggerWatcherCallback reads the version node right away once another master published a trigger
+func (zks *zkVersionStore) triggerWatcherCallback(children []string) {
+	if len(children) == 0 {
+		return
+	}
+	sort.Strings(children)
+	latest := children[len(children)-1]
+
+	zks.trigger.Lock()
+	seen := latest <= zks.trigger.seen || latest == zks.trigger.published
+	if latest > zks.trigger.seen {
+		zks.trigger.seen = latest
+	}
+	zks.trigger.Unlock()
+	if seen {
+		return
+	}
+
+	versionTriggers.Inc()
+	if data, _, err := zks.client.Get(path.Join(makeVersionTriggerPath(zks.zkBasePath), latest)); err == nil {
+		var trigger versionTrigger
+		if json.Unmarshal(data, &trigger) == nil && !trigger.WrittenAt.IsZero() {
+			latency := zks.clock.Now().Sub(trigger.WrittenAt)
+			versionPropagation.Observe(latency.Seconds())
+			log.WithFields(logrus.Fields{"version": trigger.Version, "latency": latency.String()}).Debug("Received version trigger")
+		}
+	}
+
+	version, err := zks.getVersionFromZK()
+	if err != nil {
+		log.WithError(err).Warn("Failed to read the version after a version trigger")
+		return
+	}
+	zks.updateLocalCurrentVersion(version)
+}
@@ -0,0 +1,65 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestVersionTrigger(t *testing.T) {
+	t.Run("UpdateCurrentVersion() publishes a trigger node", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.GetResult = []byte("1.0.0")
+
+		tests.H(t).IsNil(store.UpdateCurrentVersion(UIVersion("1.1.0")))
+
+		tests.H(t).IntEql(len(client.SequentialCreated), 1)
+		tests.H(t).BoolEql(strings.HasPrefix(client.SequentialCreated[0], "/dcos/ui-service-test/version-trigger/trigger-"), true)
+	})
+
+	t.Run("removes the oldest triggers above the limit", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		for i := 0; i < maxVersionTriggers+2; i++ {
+			client.ChildrenResults = append(client.ChildrenResults, fmt.Sprintf("%s%010d", versionTriggerPrefix, i))
+		}
+		var deleted []string
+		client.DeleteCall = func(path string) {
+			deleted = append(deleted, path)
+		}
+
+		store.publishVersionTrigger(UIVersion("1.1.0"))
+
+		tests.H(t).InterfaceEql(deleted, []string{
+			"/dcos/ui-service-test/version-trigger/trigger-0000000000",
+			"/dcos/ui-service-test/version-trigger/trigger-0000000001",
+		})
+	})
+
+	t.Run("a trigger of another master reads the version right away", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		trigger, _ := json.Marshal(versionTrigger{Version: "1.1.0", WrittenAt: time.Now().UTC()})
+		client.GetResults = [][]byte{trigger, []byte("1.1.0")}
+		received := versionTriggers.Value()
+
+		store.triggerWatcherCallback([]string{"trigger-0000000001"})
+
+		tests.H(t).StringEql(string(store.localVersion()), "1.1.0")
+		tests.H(t).BoolEql(versionTriggers.Value() == received+1, true)
+	})
+
+	t.Run("ignores triggers published by this master and triggers seen before", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.GetResult = []byte("1.1.0")
+		store.trigger.published = "trigger-0000000002"
+		store.trigger.seen = "trigger-0000000001"
+
+		store.triggerWatcherCallback([]string{"trigger-0000000001"})
+		store.triggerWatcherCallback([]string{"trigger-0000000001", "trigger-0000000002"})
+
+		tests.H(t).StringEql(string(store.localVersion()), "1.0.0")
+	})
+}
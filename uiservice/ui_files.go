@@ -0,0 +1,189 @@
+package uiservice
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// uiFileHandler serves the files of the served ui version with --serve-ui. Its document root is the target
+// of the ui dist symlink, it is switched after the symlink swap so a request never reads from two versions.
+type uiFileHandler struct {
+	prefix  string
+	docRoot string
+	files   uiFileRules
+	// selectVersions lets requests select an installed version below versionsRoot with --ui-version-selection
+	selectVersions bool
+	versionsRoot   string
+	sync.RWMutex
+}
+
+// uiFileRules are the files of the document root the ui file handler does not serve
+type uiFileRules struct {
+	hideDotfiles bool
+	hiddenFiles  []string
+	listDirs     bool
+}
+
+// uiFileHandler returns the ui file handler of the service, creating it on first use
+func (service *UIService) uiFileHandler() *uiFileHandler {
+	service.Lock()
+	defer service.Unlock()
+	if service.uiFiles == nil {
+		docRoot, err := os.Readlink(service.Config.UIDistSymlink())
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to read the ui dist symlink, serving the default ui files")
+			docRoot = service.Config.DefaultDocRoot()
+		}
+		service.uiFiles = &uiFileHandler{
+			prefix:  service.Config.UIAssetPrefix(),
+			docRoot: docRoot,
+			files: uiFileRules{
+				hideDotfiles: service.Config.UIHideDotfiles(),
+				hiddenFiles:  service.Config.UIHiddenFiles(),
+				listDirs:     service.Config.UIDirectoryListing(),
+			},
+			selectVersions: service.Config.UIVersionSelection(),
+			versionsRoot:   service.Config.VersionsRoot(),
+		}
+	}
+	return service.uiFiles
+}
+
+// DocRoot returns the directory the ui files are served from
+func (h *uiFileHandler) DocRoot() string {
+	h.RLock()
+	defer h.RUnlock()
+	return h.docRoot
+}
+
+func (h *uiFileHandler) setDocRoot(docRoot string) {
+	h.Lock()
+	defer h.Unlock()
+	h.docRoot = docRoot
+	logrus.WithField("docRoot", docRoot).Info("Switched the served ui files")
+}
+
+func (h *uiFileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !validUIPath(r.URL.Path) {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+	docRoot, ok := h.selectDocRoot(w, r)
+	if !ok {
+		return
+	}
+	prefix := strings.TrimSuffix(h.prefix, "/")
+	fileSystem := uiFileSystem{root: docRoot, rules: h.files}
+	http.StripPrefix(prefix, http.FileServer(fileSystem)).ServeHTTP(w, r)
+}
+
+// validUIPath rejects request paths that climb above the document root, also when the dots or slashes are
+// percent-encoded, as well as backslashes and NUL bytes which some file systems treat as separators
+func validUIPath(urlPath string) bool {
+	if strings.ContainsAny(urlPath, "\\\x00") {
+		return false
+	}
+	for _, segment := range strings.Split(urlPath, "/") {
+		if segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// hidden is true for a path segment that is never served
+func (rules uiFileRules) hidden(name string) bool {
+	if rules.hideDotfiles && strings.HasPrefix(name, ".") {
+		return true
+	}
+	for _, pattern := range rules.hiddenFiles {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// uiFileSystem serves the document root without the hidden files. Symlinks are followed only while their
+// target stays inside the document root, and directories without an index.html are only listed with
+// --ui-directory-listing. Files that are not served are reported as not existing.
+type uiFileSystem struct {
+	root  string
+	rules uiFileRules
+}
+
+func (fs uiFileSystem) Open(name string) (http.File, error) {
+	name = path.Clean("/" + name)
+	for _, segment := range strings.Split(name, "/") {
+		if fs.rules.hidden(segment) {
+			return nil, os.ErrNotExist
+		}
+	}
+	if !insideDir(fs.root, filepath.Join(fs.root, filepath.FromSlash(name))) {
+		return nil, os.ErrNotExist
+	}
+	file, err := http.Dir(fs.root).Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !info.IsDir() {
+		return file, nil
+	}
+	if !fs.rules.listDirs {
+		index, err := fs.Open(path.Join(name, "index.html"))
+		if err != nil {
+			file.Close()
+			return nil, os.ErrNotExist
+		}
+		index.Close()
+	}
+	return uiDir{File: file, rules: fs.rules}, nil
+}
+
+// insideDir is true if target resolves to a path inside dir once all symlinks are followed
+func insideDir(dir, target string) bool {
+	resolvedDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	resolved, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(resolvedDir, resolved)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// uiDir leaves the hidden files out of directory listings
+type uiDir struct {
+	http.File
+	rules uiFileRules
+}
+
+func (d uiDir) Readdir(count int) ([]os.FileInfo, error) {
+	infos, err := d.File.Readdir(count)
+	visible := infos[:0]
+	for _, info := range infos {
+		if !d.rules.hidden(info.Name()) {
+			visible = append(visible, info)
+		}
+	}
+	return visible, err
+}
+
+// notAPIPath keeps the ui files from shadowing the API, unknown API paths still get the not found handler
+func notAPIPath(r *http.Request, _ *mux.RouteMatch) bool {
+	return !strings.HasPrefix(r.URL.Path, "/api/")
+}
@@ -0,0 +1,177 @@
+package uiservice
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func setupServeUIService(prefix string) *UIService {
+	service := setupUIServiceWithVersion()
+	service.Config, _ = config.Parse([]string{
+		"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+		"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+		"--ui-dist-stage-symlink", "../testdata/uiserv-sandbox/new-dcos-ui-dist",
+		"--serve-ui",
+		"--ui-asset-prefix", prefix,
+	})
+	return service
+}
+
+func writeUIFile(t *testing.T, dir, name, content string) {
+	os.MkdirAll(dir, 0755)
+	tests.H(t).IsNil(ioutil.WriteFile(path.Join(dir, name), []byte(content), 0644))
+}
+
+func getUIFile(service *UIService, url string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", url, nil))
+	return rr
+}
+
+func TestServeUI(t *testing.T) {
+	t.Run("serves the files of the served version below the prefix", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupServeUIService("/ui/")
+		writeUIFile(t, path.Join(service.Config.VersionsRoot(), "2.24.4", "dist"), "main.js", "2.24.4")
+
+		rr := getUIFile(service, "/ui/main.js")
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringEql(rr.Body.String(), "2.24.4")
+	})
+
+	t.Run("switches the document root with the served version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupServeUIService("/")
+		writeUIFile(t, path.Join(service.Config.VersionsRoot(), "2.24.4", "dist"), "main.js", "2.24.4")
+		newVersionPath := path.Join(service.Config.VersionsRoot(), "2.25.0", "dist")
+		writeUIFile(t, newVersionPath, "main.js", "2.25.0")
+		getUIFile(service, "/main.js")
+
+		tests.H(t).IsNil(updateServedVersion(service, newVersionPath))
+		rr := getUIFile(service, "/main.js")
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringEql(rr.Body.String(), "2.25.0")
+	})
+
+	t.Run("does not serve ui files below /api/", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupServeUIService("/")
+
+		rr := getUIFile(service, "/api/v1/unknown/")
+
+		tests.H(t).IntEql(rr.Code, http.StatusNotFound)
+	})
+
+	t.Run("does not serve ui files without serve-ui", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupUIServiceWithVersion()
+		writeUIFile(t, path.Join(service.Config.VersionsRoot(), "2.24.4", "dist"), "main.js", "2.24.4")
+
+		rr := getUIFile(service, "/main.js")
+
+		tests.H(t).IntEql(rr.Code, http.StatusNotFound)
+	})
+}
+
+func TestServeUIFileRules(t *testing.T) {
+	setup := func(t *testing.T, extra ...string) (*UIService, string) {
+		service := setupUIServiceWithVersion()
+		service.Config, _ = config.Parse(append([]string{
+			"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+			"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+			"--ui-dist-stage-symlink", "../testdata/uiserv-sandbox/new-dcos-ui-dist",
+			"--serve-ui",
+		}, extra...))
+		versionDir := path.Join(service.Config.VersionsRoot(), "2.24.4")
+		dist := path.Join(versionDir, "dist")
+		writeUIFile(t, dist, "index.html", "index")
+		writeUIFile(t, dist, "manifest.json", "{}")
+		writeUIFile(t, path.Join(dist, ".git"), "config", "secret")
+		writeUIFile(t, path.Join(dist, "assets"), "main.js", "main")
+		writeUIFile(t, versionDir, "outside.txt", "outside")
+		return service, dist
+	}
+
+	t.Run("rejects paths climbing above the document root", func(t *testing.T) {
+		defer tearDown(t)
+		service, _ := setup(t)
+		handler := service.uiFileHandler()
+
+		for _, url := range []string{"/../outside.txt", "/assets/%2e%2e/%2e%2e/outside.txt", "/assets/..%2f..%2foutside.txt", "/assets%5c..%5c..%5coutside.txt"} {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", url, nil))
+			tests.H(t).IntEql(rr.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("does not follow symlinks out of the document root", func(t *testing.T) {
+		defer tearDown(t)
+		service, dist := setup(t)
+		tests.H(t).IsNil(os.Symlink("../outside.txt", path.Join(dist, "link.txt")))
+		tests.H(t).IsNil(os.Symlink("assets/main.js", path.Join(dist, "main.js")))
+
+		tests.H(t).IntEql(getUIFile(service, "/link.txt").Code, http.StatusNotFound)
+		tests.H(t).IntEql(getUIFile(service, "/main.js").Code, http.StatusOK)
+	})
+
+	t.Run("hides dotfiles and serves a web app manifest by default", func(t *testing.T) {
+		defer tearDown(t)
+		service, _ := setup(t)
+
+		tests.H(t).IntEql(getUIFile(service, "/.git/config").Code, http.StatusNotFound)
+		tests.H(t).IntEql(getUIFile(service, "/manifest.json").Code, http.StatusOK)
+		tests.H(t).IntEql(getUIFile(service, "/assets/main.js").Code, http.StatusOK)
+	})
+
+	t.Run("hides files matching the configured patterns", func(t *testing.T) {
+		defer tearDown(t)
+		service, _ := setup(t, "--ui-hidden-files=manifest.json")
+
+		tests.H(t).IntEql(getUIFile(service, "/manifest.json").Code, http.StatusNotFound)
+		tests.H(t).IntEql(getUIFile(service, "/assets/main.js").Code, http.StatusOK)
+	})
+
+	t.Run("serves dotfiles if configured", func(t *testing.T) {
+		defer tearDown(t)
+		service, _ := setup(t, "--ui-hide-dotfiles=false", "--ui-hidden-files=")
+
+		tests.H(t).IntEql(getUIFile(service, "/.git/config").Code, http.StatusOK)
+		tests.H(t).IntEql(getUIFile(service, "/manifest.json").Code, http.StatusOK)
+	})
+
+	t.Run("does not list directories without an index", func(t *testing.T) {
+		defer tearDown(t)
+		service, _ := setup(t)
+
+		tests.H(t).IntEql(getUIFile(service, "/assets/").Code, http.StatusNotFound)
+		rr := getUIFile(service, "/")
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringEql(rr.Body.String(), "index")
+	})
+
+	t.Run("lists directories without the hidden files if configured", func(t *testing.T) {
+		defer tearDown(t)
+		service, dist := setup(t, "--ui-directory-listing", "--ui-hidden-files=*.reason")
+		writeUIFile(t, path.Join(dist, "assets"), ".hidden", "")
+		writeUIFile(t, path.Join(dist, "assets"), "main.js.reason", "")
+
+		rr := getUIFile(service, "/assets/")
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringContains(rr.Body.String(), "main.js")
+		tests.H(t).BoolEql(strings.Contains(rr.Body.String(), ".hidden"), false)
+		tests.H(t).BoolEql(strings.Contains(rr.Body.String(), "main.js.reason"), false)
+	})
+}
@@ -1,5 +1,12 @@
 package uiservice
 
+import (
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/sirupsen/logrus"
+)
+
 type UIVersion string
 
 var (
@@ -13,3 +20,62 @@ type VersionStore interface {
 	UpdateCurrentVersion(UIVersion) error
 	WatchForVersionChange(VersionChangeListener) error
 }
+
+// NewVersionStore creates the version store selected by the config, zookeeper shares the version between
+// the masters, the file store keeps it on this master only. With a migration source the version is moved
+// from that backend to the selected one.
+func NewVersionStore(cfg *config.Config) (VersionStore, error) {
+	target, err := newVersionStoreBackend(cfg, cfg.VersionStore())
+	if err != nil || cfg.VersionStoreMigrateFrom() == "" {
+		return target, err
+	}
+	source, err := newVersionStoreBackend(cfg, cfg.VersionStoreMigrateFrom())
+	if err != nil {
+		if closer, ok := target.(VersionStoreCloser); ok {
+			closer.Close()
+		}
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"source": cfg.VersionStoreMigrateFrom(),
+		"target": cfg.VersionStore(),
+		"period": cfg.VersionStoreMigrationPeriod(),
+	}).Info("Migrating the version store")
+	return NewMigrationVersionStore(source, target, cfg.VersionStoreMigrationPeriod()), nil
+}
+
+func newVersionStoreBackend(cfg *config.Config, backend string) (VersionStore, error) {
+	if backend == "file" {
+		return NewFileVersionStore(cfg.VersionStoreFile())
+	}
+	return NewZKVersionStore(cfg), nil
+}
+
+// VersionConflictRule decides version writes racing between masters: the latest write is stored and
+// overwrites the version of the other master, inside and outside of a cluster operation
+const VersionConflictRule = "latest-write-wins"
+
+// VersionConflict is a version write that raced with a version stored concurrently by another master
+type VersionConflict struct {
+	Overwritten UIVersion `json:"overwritten"`
+	Version     UIVersion `json:"version"`
+	// Rule is the rule that decided the conflict, VersionConflictRule
+	Rule string    `json:"rule"`
+	Time time.Time `json:"time"`
+}
+
+// VersionConflicts reports version writes that raced with another master
+type VersionConflicts interface {
+	LastVersionConflict() *VersionConflict
+}
+
+// VersionHistory keeps the version stored before the current one, so it can be rolled back to
+type VersionHistory interface {
+	// PreviousVersion returns the version stored before the current one, false if none was recorded
+	PreviousVersion() (UIVersion, bool, error)
+}
+
+// VersionStoreCloser is implemented by version stores holding a connection that is closed on shutdown
+type VersionStoreCloser interface {
+	Close()
+}
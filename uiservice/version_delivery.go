@@ -0,0 +1,62 @@
+package uiservice
+
+import (
+	"sync"
+)
+
+// versionDelivery calls a VersionChangeListener with one version at a time, in the order the versions were
+// stored. A version stored while the listener is still running replaces the versions waiting for it, so the
+// listener skips the intermediate versions and is always called last with the latest version. A version
+// equal to the last one delivered is dropped.
+type versionDelivery struct {
+	listener   VersionChangeListener
+	pending    UIVersion
+	hasPending bool
+	delivered  UIVersion
+	// hasDelivered is false until the listener was called once
+	hasDelivered bool
+	running      bool
+	sync.Mutex
+}
+
+func newVersionDelivery(listener VersionChangeListener) *versionDelivery {
+	return &versionDelivery{listener: listener}
+}
+
+// deliver queues version for the listener, it does not wait for the listener
+func (d *versionDelivery) deliver(version UIVersion) {
+	d.Lock()
+	defer d.Unlock()
+	d.pending = version
+	d.hasPending = true
+	if !d.running {
+		d.running = true
+		go d.run()
+	}
+}
+
+// run calls the listener until no version is pending
+func (d *versionDelivery) run() {
+	for {
+		version, ok := d.next()
+		if !ok {
+			return
+		}
+		d.listener(version)
+	}
+}
+
+// next takes the pending version, false once there is none left to deliver
+func (d *versionDelivery) next() (UIVersion, bool) {
+	d.Lock()
+	defer d.Unlock()
+	if d.hasPending && !(d.hasDelivered && d.pending == d.delivered) {
+		d.hasPending = false
+		d.delivered = d.pending
+		d.hasDelivered = true
+		return d.delivered, true
+	}
+	d.hasPending = false
+	d.running = false
+	return "", false
+}
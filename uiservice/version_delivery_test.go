@@ -0,0 +1,75 @@
+package uiservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func receiveVersion(t *testing.T, received chan UIVersion) UIVersion {
+	select {
+	case version := <-received:
+		return version
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener not called")
+	}
+	return ""
+}
+
+func expectNoVersion(t *testing.T, received chan UIVersion) {
+	select {
+	case version := <-received:
+		t.Fatalf("unexpected delivery of %s", version)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestVersionDelivery(t *testing.T) {
+	t.Run("skips the versions superseded while the listener runs", func(t *testing.T) {
+		received := make(chan UIVersion, 10)
+		release := make(chan struct{})
+		delivery := newVersionDelivery(func(version UIVersion) {
+			received <- version
+			<-release
+		})
+
+		delivery.deliver("2.24.4")
+		tests.H(t).StringEql(string(receiveVersion(t, received)), "2.24.4")
+		delivery.deliver("2.25.0")
+		delivery.deliver("2.26.0")
+		delivery.deliver("2.27.0")
+		close(release)
+
+		tests.H(t).StringEql(string(receiveVersion(t, received)), "2.27.0")
+		expectNoVersion(t, received)
+	})
+
+	t.Run("drops a version equal to the last one delivered", func(t *testing.T) {
+		received := make(chan UIVersion, 10)
+		delivery := newVersionDelivery(func(version UIVersion) {
+			received <- version
+		})
+
+		delivery.deliver("2.25.0")
+		tests.H(t).StringEql(string(receiveVersion(t, received)), "2.25.0")
+		delivery.deliver("2.25.0")
+
+		expectNoVersion(t, received)
+	})
+
+	t.Run("delivers a version again after another one", func(t *testing.T) {
+		received := make(chan UIVersion, 10)
+		delivery := newVersionDelivery(func(version UIVersion) {
+			received <- version
+		})
+
+		delivery.deliver("2.25.0")
+		receiveVersion(t, received)
+		delivery.deliver("2.26.0")
+		receiveVersion(t, received)
+		delivery.deliver("2.25.0")
+
+		tests.H(t).StringEql(string(receiveVersion(t, received)), "2.25.0")
+	})
+}
@@ -0,0 +1,139 @@
+package uiservice
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// uiVersionHeader selects the ui version of a request with --ui-version-selection
+	uiVersionHeader = "X-DCOS-UI-Version"
+	// uiVersionParam selects the ui version of a request and stores the selection in uiVersionCookie,
+	// so the assets loaded by the selected index.html are served from the same version
+	uiVersionParam  = "ui-version"
+	uiVersionCookie = "dcos-ui-version"
+)
+
+// ErrUIVersionNotInstalled is returned for a selected ui version that is not installed in versions-root
+var ErrUIVersionNotInstalled = errors.New("The selected ui version is not installed")
+
+// selectedUIVersion returns the ui version selected by the request, the header takes precedence over the
+// query parameter and the cookie. The query parameter is reported, an empty one clears the selection.
+func selectedUIVersion(r *http.Request) (version string, fromParam bool) {
+	if version := r.Header.Get(uiVersionHeader); version != "" {
+		return version, false
+	}
+	if values, ok := r.URL.Query()[uiVersionParam]; ok {
+		return values[0], true
+	}
+	if cookie, err := r.Cookie(uiVersionCookie); err == nil {
+		return cookie.Value, false
+	}
+	return "", false
+}
+
+// storeUIVersionSelection sets or, for an empty version, clears the selection cookie below prefix
+func storeUIVersionSelection(w http.ResponseWriter, prefix, version string) {
+	cookie := &http.Cookie{
+		Name:     uiVersionCookie,
+		Value:    version,
+		Path:     prefix,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if version == "" {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+}
+
+// installedDocRoot returns the dist directory of an installed version. Versions are installed once they
+// have a manifest, versions still being downloaded are not served.
+func installedDocRoot(versionsRoot, version string) (string, error) {
+	if !directVersionPattern.MatchString(version) {
+		return "", ErrUIVersionNotInstalled
+	}
+	versionPath := filepath.Join(versionsRoot, version)
+	if _, err := os.Stat(filepath.Join(versionPath, "manifest.json")); err != nil {
+		return "", ErrUIVersionNotInstalled
+	}
+	return filepath.Join(versionPath, "dist"), nil
+}
+
+// selectDocRoot returns the document root of the version selected by the request, the served version's if
+// none is selected. It returns false after writing an error response.
+func (h *uiFileHandler) selectDocRoot(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if !h.selectVersions {
+		return h.DocRoot(), true
+	}
+	// caches must not serve the files of one version for requests selecting another one
+	w.Header().Add("Vary", uiVersionHeader+", Cookie")
+	version, fromParam := selectedUIVersion(r)
+	if fromParam {
+		storeUIVersionSelection(w, h.prefix, version)
+	}
+	if version == "" {
+		return h.DocRoot(), true
+	}
+	docRoot, err := installedDocRoot(h.versionsRoot, version)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s: %s", err.Error(), version), http.StatusNotFound)
+		return "", false
+	}
+	w.Header().Set(uiVersionHeader, version)
+	return docRoot, true
+}
+
+// installVersionHandler installs a version on this master without serving it, so it can be selected by
+// requests with --ui-version-selection before the cluster switches to it. ?label= labels the installed version.
+func installVersionHandler(service *UIService) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version := mux.Vars(r)["version"]
+		logrus.WithField("version", version).Debug("Received install version request.")
+		if !directVersionPattern.MatchString(version) {
+			http.Error(w, ErrInvalidVersionName.Error(), http.StatusBadRequest)
+			return
+		}
+		label := r.URL.Query().Get("label")
+		if label != "" && !labelPattern.MatchString(label) {
+			http.Error(w, ErrInvalidLabel.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, lockErr := setServiceUpdating(service, version); lockErr != nil {
+			http.Error(w, "Cannot install version, an update is currently in progress.", http.StatusConflict)
+			return
+		}
+		defer resetServiceFromUpdate(service)
+
+		if writableErr := service.UpdateManager.CheckWritable(); writableErr != nil {
+			http.Error(w, writableErr.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		err := service.UpdateManager.InstallVersion(version)
+		if err == nil && label != "" {
+			err = service.UpdateManager.SetVersionLabel(version, label)
+		}
+		switch err {
+		case nil:
+			w.Header().Add("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(fmt.Sprintf("Installed version %s, select it with the %s header or the %s parameter", version, uiVersionHeader, uiVersionParam)))
+		case updatemanager.ErrRequestedVersionNotFound, updatemanager.ErrUIPackageAssetNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case updatemanager.ErrCosmosRequestFailure:
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		default:
+			logrus.WithError(err).WithField("version", version).Error("Failed to install version")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
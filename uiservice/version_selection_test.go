@@ -0,0 +1,170 @@
+package uiservice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/dcos/dcos-ui-update-service/updatemanager"
+)
+
+func setupVersionSelectionService(t *testing.T) *UIService {
+	service := setupUIServiceWithVersion()
+	service.Config, _ = config.Parse([]string{
+		"--default-ui-path", "../testdata/uiserv-sandbox/dcos-ui",
+		"--versions-root", "../testdata/uiserv-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/uiserv-sandbox/dcos-ui-dist",
+		"--ui-dist-stage-symlink", "../testdata/uiserv-sandbox/new-dcos-ui-dist",
+		"--serve-ui",
+		"--ui-version-selection",
+	})
+	writeUIFile(t, path.Join(service.Config.VersionsRoot(), "2.24.4", "dist"), "main.js", "2.24.4")
+	canary := path.Join(service.Config.VersionsRoot(), "2.25.0")
+	writeUIFile(t, path.Join(canary, "dist"), "main.js", "2.25.0")
+	writeUIFile(t, canary, "manifest.json", "{}")
+	return service
+}
+
+func getSelectedUIFile(service *UIService, url string, setup func(*http.Request)) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", url, nil)
+	setup(req)
+	newRouter(service).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestUIVersionSelection(t *testing.T) {
+	t.Run("serves the version selected by the header", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupVersionSelectionService(t)
+
+		rr := getSelectedUIFile(service, "/main.js", func(r *http.Request) { r.Header.Set(uiVersionHeader, "2.25.0") })
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringEql(rr.Body.String(), "2.25.0")
+		tests.H(t).StringEql(rr.Header().Get(uiVersionHeader), "2.25.0")
+		tests.H(t).StringContains(rr.Header().Get("Vary"), uiVersionHeader)
+	})
+
+	t.Run("serves the served version without a selection", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupVersionSelectionService(t)
+
+		rr := getUIFile(service, "/main.js")
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).StringEql(rr.Body.String(), "2.24.4")
+	})
+
+	t.Run("keeps the selection of the query parameter in a cookie", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupVersionSelectionService(t)
+
+		rr := getUIFile(service, "/main.js?ui-version=2.25.0")
+
+		tests.H(t).StringEql(rr.Body.String(), "2.25.0")
+		cookies := rr.Result().Cookies()
+		tests.H(t).IntEql(len(cookies), 1)
+		tests.H(t).StringEql(cookies[0].Value, "2.25.0")
+
+		rr = getSelectedUIFile(service, "/main.js", func(r *http.Request) { r.AddCookie(cookies[0]) })
+		tests.H(t).StringEql(rr.Body.String(), "2.25.0")
+	})
+
+	t.Run("clears the selection for an empty query parameter", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupVersionSelectionService(t)
+
+		rr := getSelectedUIFile(service, "/main.js?ui-version=", func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: uiVersionCookie, Value: "2.25.0"})
+		})
+
+		tests.H(t).StringEql(rr.Body.String(), "2.24.4")
+		tests.H(t).IntEql(rr.Result().Cookies()[0].MaxAge, -1)
+	})
+
+	t.Run("does not serve versions that are not installed", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupVersionSelectionService(t)
+		writeUIFile(t, path.Join(service.Config.VersionsRoot(), "2.26.0", "dist"), "main.js", "2.26.0")
+
+		for _, version := range []string{"2.26.0", "9.9.9", "..", ".trash"} {
+			rr := getSelectedUIFile(service, "/main.js", func(r *http.Request) { r.Header.Set(uiVersionHeader, version) })
+			tests.H(t).IntEql(rr.Code, http.StatusNotFound)
+			tests.H(t).StringContains(rr.Body.String(), ErrUIVersionNotInstalled.Error())
+		}
+	})
+
+	t.Run("ignores the selection without ui-version-selection", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupServeUIService("/")
+		writeUIFile(t, path.Join(service.Config.VersionsRoot(), "2.24.4", "dist"), "main.js", "2.24.4")
+
+		rr := getSelectedUIFile(service, "/main.js", func(r *http.Request) { r.Header.Set(uiVersionHeader, "2.25.0") })
+
+		tests.H(t).StringEql(rr.Body.String(), "2.24.4")
+	})
+}
+
+func TestInstallVersionHandler(t *testing.T) {
+	post := func(service *UIService, version string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/versions/"+version+"/install/", nil))
+		return rr
+	}
+
+	t.Run("installs the version without serving it", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupVersionSelectionService(t)
+		um := UpdateManagerDouble()
+		service.UpdateManager = um
+
+		rr := post(service, "2.25.1")
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).InterfaceEql(um.InstalledVersions, []string{"2.25.1"})
+	})
+
+	t.Run("labels the installed version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupVersionSelectionService(t)
+		um := UpdateManagerDouble()
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/versions/2.25.1/install/?label=rc", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		tests.H(t).InterfaceEql(um.Labels, map[string]string{"rc": "2.25.1"})
+	})
+
+	t.Run("returns 404 for a version unknown to the package source", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupVersionSelectionService(t)
+		um := UpdateManagerDouble()
+		um.InstallError = updatemanager.ErrRequestedVersionNotFound
+		service.UpdateManager = um
+
+		tests.H(t).IntEql(post(service, "9.9.9").Code, http.StatusNotFound)
+	})
+
+	t.Run("rejects invalid version names", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupVersionSelectionService(t)
+		um := UpdateManagerDouble()
+		service.UpdateManager = um
+
+		tests.H(t).IntEql(post(service, ".trash").Code, http.StatusBadRequest)
+		tests.H(t).IntEql(len(um.InstalledVersions), 0)
+	})
+
+	t.Run("is not available without ui-version-selection", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		tests.H(t).IntEql(post(service, "2.25.1").Code, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,90 @@
+package uiservice
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dcos/dcos-ui-update-service/semver"
+	"github.com/pkg/errors"
+)
+
+const defaultVersionsPageLimit = 50
+
+type versionsQuery struct {
+	prefix         string
+	limit          int
+	page           int
+	compatibleOnly bool
+}
+
+type versionsResponse struct {
+	PackageName string   `json:"packageName"`
+	Installed   string   `json:"installed"`
+	Versions    []string `json:"versions"`
+	Total       int      `json:"total"`
+	Page        int      `json:"page"`
+	Limit       int      `json:"limit"`
+}
+
+func parseVersionsQuery(values url.Values) (versionsQuery, error) {
+	query := versionsQuery{
+		prefix: values.Get("prefix"),
+		limit:  defaultVersionsPageLimit,
+		page:   1,
+	}
+	var err error
+	if limit := values.Get("limit"); limit != "" {
+		if query.limit, err = strconv.Atoi(limit); err != nil || query.limit < 1 {
+			return query, errors.New("limit must be a positive integer")
+		}
+	}
+	if page := values.Get("page"); page != "" {
+		if query.page, err = strconv.Atoi(page); err != nil || query.page < 1 {
+			return query, errors.New("page must be a positive integer")
+		}
+	}
+	if compatibleOnly := values.Get("compatibleOnly"); compatibleOnly != "" {
+		if query.compatibleOnly, err = strconv.ParseBool(compatibleOnly); err != nil {
+			return query, errors.New("compatibleOnly must be a boolean")
+		}
+	}
+	return query, nil
+}
+
+// apply filters the versions, sorts them from newest to oldest and returns the requested page. With
+// compatibleOnly only the versions compatible accepts are kept, see GET /api/v1/compatibility/.
+func (q versionsQuery) apply(versions []string, compatible func(version string) bool) versionsResponse {
+	filtered := make([]string, 0, len(versions))
+	for _, version := range versions {
+		if !strings.HasPrefix(version, q.prefix) {
+			continue
+		}
+		if q.compatibleOnly && !compatible(version) {
+			continue
+		}
+		filtered = append(filtered, version)
+	}
+	semver.SortDescending(filtered)
+
+	response := versionsResponse{
+		Versions: []string{},
+		Total:    len(filtered),
+		Page:     q.page,
+		Limit:    q.limit,
+	}
+	// the page count is computed without multiplying page and limit, both can be close to the maximum int
+	pages := len(filtered) / q.limit
+	if len(filtered)%q.limit != 0 {
+		pages++
+	}
+	if q.page <= pages {
+		start := (q.page - 1) * q.limit
+		end := len(filtered)
+		if end-start > q.limit {
+			end = start + q.limit
+		}
+		response.Versions = filtered[start:end]
+	}
+	return response
+}
@@ -0,0 +1,157 @@
+package uiservice
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+var availableVersions = []string{"2.25.0", "1.0.20-3.0.10", "2.24.4", "2.25.2", "2.25.1", "2.1.0-3.0.16"}
+
+func TestVersionsQuery(t *testing.T) {
+	t.Run("sorts versions newest first", func(t *testing.T) {
+		query, _ := parseVersionsQuery(url.Values{})
+		response := query.apply(availableVersions, nil)
+		tests.H(t).InterfaceEql(response.Versions, []string{"2.25.2", "2.25.1", "2.25.0", "2.24.4", "2.1.0-3.0.16", "1.0.20-3.0.10"})
+		tests.H(t).IntEql(response.Total, 6)
+	})
+
+	t.Run("filters by prefix", func(t *testing.T) {
+		query, _ := parseVersionsQuery(url.Values{"prefix": {"2.25"}})
+		tests.H(t).InterfaceEql(query.apply(availableVersions, nil).Versions, []string{"2.25.2", "2.25.1", "2.25.0"})
+	})
+
+	t.Run("keeps the compatible versions if compatibleOnly", func(t *testing.T) {
+		query, _ := parseVersionsQuery(url.Values{"compatibleOnly": {"true"}})
+		compatible := func(version string) bool { return version != "2.25.1" && version != "2.1.0-3.0.16" }
+		tests.H(t).InterfaceEql(query.apply(availableVersions, compatible).Versions, []string{"2.25.2", "2.25.0", "2.24.4", "1.0.20-3.0.10"})
+	})
+
+	t.Run("paginates", func(t *testing.T) {
+		query, _ := parseVersionsQuery(url.Values{"limit": {"4"}, "page": {"2"}})
+		response := query.apply(availableVersions, nil)
+		tests.H(t).InterfaceEql(response.Versions, []string{"2.1.0-3.0.16", "1.0.20-3.0.10"})
+		tests.H(t).IntEql(response.Total, 6)
+	})
+
+	t.Run("returns an empty page past the end", func(t *testing.T) {
+		query, _ := parseVersionsQuery(url.Values{"limit": {"4"}, "page": {"3"}})
+		tests.H(t).InterfaceEql(query.apply(availableVersions, nil).Versions, []string{})
+	})
+
+	t.Run("returns an empty page if page and limit overflow the offset", func(t *testing.T) {
+		query, err := parseVersionsQuery(url.Values{"limit": {strconv.Itoa(math.MaxInt64)}, "page": {"3"}})
+		tests.H(t).IsNil(err)
+		response := query.apply(availableVersions, nil)
+		tests.H(t).InterfaceEql(response.Versions, []string{})
+		tests.H(t).IntEql(response.Total, 6)
+
+		query, _ = parseVersionsQuery(url.Values{"limit": {strconv.Itoa(math.MaxInt64)}})
+		tests.H(t).IntEql(len(query.apply(availableVersions, nil).Versions), 6)
+	})
+
+	t.Run("rejects invalid parameters", func(t *testing.T) {
+		for _, values := range []url.Values{{"limit": {"0"}}, {"page": {"x"}}, {"compatibleOnly": {"maybe"}}} {
+			_, err := parseVersionsQuery(values)
+			tests.H(t).NotNil(err)
+		}
+	})
+}
+
+func TestAvailableVersionsHandler(t *testing.T) {
+	t.Run("returns filtered versions", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.AvailableResult = availableVersions
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/versions/?prefix=2.24", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var response versionsResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		tests.H(t).InterfaceEql(response.Versions, []string{"2.24.4"})
+	})
+
+	t.Run("returns the package name and the installed version", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.AvailableResult = availableVersions
+		um.VersionResult = "2.25.1"
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/versions/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var response versionsResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		tests.H(t).StringEql(response.PackageName, "dcos-ui")
+		tests.H(t).StringEql(response.Installed, "2.25.1")
+	})
+
+	t.Run("returns the package name alias the versions were listed from", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.AvailableResult = availableVersions
+		um.PackageNameResult = "dcos-ui-oss"
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/versions/", nil))
+
+		var response versionsResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		tests.H(t).StringEql(response.PackageName, "dcos-ui-oss")
+	})
+
+	t.Run("applies the compatibility check of the cluster if compatibleOnly", func(t *testing.T) {
+		defer tearDown(t)
+		um := UpdateManagerDouble()
+		um.AvailableResult = []string{"1.0.5-2.2.5", "2.24.4", "2.25.0", "3.0.0"}
+		um.MinDcosResult = map[string]string{"2.25.0": "2.0", "3.0.0": "2.2"}
+		service := setupCompatibilityUIService(um, "../fixtures/dcos-version.json")
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/versions/?compatibleOnly=true", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusOK)
+		var response versionsResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		tests.H(t).InterfaceEql(response.Versions, []string{"2.25.0"})
+	})
+
+	t.Run("returns 400 on invalid query", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/versions/?limit=-1", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusBadRequest)
+	})
+
+	t.Run("returns 502 if versions cannot be listed", func(t *testing.T) {
+		defer tearDown(t)
+		service := setupTestUIService()
+		um := UpdateManagerDouble()
+		um.AvailableError = errors.New("cosmos down")
+		service.UpdateManager = um
+
+		rr := httptest.NewRecorder()
+		newRouter(service).ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/versions/", nil))
+
+		tests.H(t).IntEql(rr.Code, http.StatusBadGateway)
+	})
+}
@@ -2,11 +2,15 @@ package uiservice
 
 import (
 	"fmt"
+	"net"
 	"path"
 	"sync"
 	"time"
 
+	"github.com/dcos/dcos-ui-update-service/clock"
 	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/health"
+	"github.com/dcos/dcos-ui-update-service/metrics"
 	"github.com/dcos/dcos-ui-update-service/zookeeper"
 	"github.com/jpillora/backoff"
 	"github.com/pkg/errors"
@@ -19,9 +23,70 @@ type zkVersionStore struct {
 	client            zookeeper.ZKClient
 	zkClientState     zookeeper.ClientState
 	zkBasePath        string
+	znodeOwner        string
 	versionPath       string
 	zkPollingInterval time.Duration
-	versionWatcher    zookeeper.ValueNodeWatcher
+	// writeMaxRetries and writeRetryInterval bound the retries of a version write racing with another master
+	writeMaxRetries    int
+	writeRetryInterval time.Duration
+	// operationTimeout is the age after which another master's cluster operation can be taken over, 0 if never
+	operationTimeout time.Duration
+	operation        zkOperation
+	eventsToKeep     int
+	versionWatcher   zookeeper.ValueNodeWatcher
+	triggerWatcher   zookeeper.ParentNodeWatcher
+	trigger          zkVersionTrigger
+	clock            clock.Clock
+	nodeIP           zkNodeIP
+	conflict         zkVersionConflict
+	lifecycle        zkLifecycle
+	access           zkAccess
+	reinit           zkReinit
+	advertiseURL     string
+	leader           zkLeader
+	// readOnly is set for replicas, they follow the version node but never write to ZK
+	readOnly bool
+	// stateLock guards zkClientState, the state changes are handled in goroutines of the client listener
+	stateLock sync.Mutex
+}
+
+// zkVersionTrigger tracks the latest trigger node seen and the last one published by this master
+type zkVersionTrigger struct {
+	seen      string
+	published string
+	sync.Mutex
+}
+
+// zkLifecycle tracks if the store was closed, a connection established afterwards is closed right away
+type zkLifecycle struct {
+	closed bool
+	sync.Mutex
+}
+
+// zkOperation is the id of the cluster operation acquired by this master
+type zkOperation struct {
+	acquired string
+	// observed is the version node read when the operation was acquired, updated by the writes of the operation
+	observed *zkVersionNode
+	sync.Mutex
+}
+
+// zkVersionNode is a version read from the version node with the stat version it was read at
+type zkVersionNode struct {
+	version UIVersion
+	stat    int32
+}
+
+type zkVersionConflict struct {
+	last *VersionConflict
+	sync.Mutex
+}
+
+type zkNodeIP struct {
+	ip net.IP
+	// state is the last NodeVersionState reported by this master, it is restored with the registration
+	state []byte
+	sync.Mutex
 }
 
 type zkUIVersion struct {
@@ -31,7 +96,7 @@ type zkUIVersion struct {
 }
 
 type versionChangeListeners struct {
-	versionListeners []VersionChangeListener
+	versionListeners []*versionDelivery
 	sync.Mutex
 }
 
@@ -39,6 +104,12 @@ var (
 	ErrZookeeperNotConnected = errors.New("Zookeeper is not currently connected")
 
 	log = logrus.WithFields(logrus.Fields{"package": "ZKVersionStore"})
+
+	versionConflicts = metrics.DefaultRegistry.Counter(
+		"ui_update_version_conflicts_total",
+		"Number of version writes that overwrote a version another master stored concurrently, by the rule applied.",
+		"rule",
+	)
 )
 
 // NewZKVersionStore creates a new zookeeper version store from the config.
@@ -49,10 +120,22 @@ func NewZKVersionStore(cfg *config.Config) VersionStore {
 			currentVersion: PreBundledUIVersion,
 			initialized:    false,
 		},
-		zkBasePath:        cfg.ZKBasePath(),
-		versionPath:       makeVersionPath(cfg.ZKBasePath()),
-		zkPollingInterval: cfg.ZKPollingInterval(),
-		versionWatcher:    nil,
+		zkBasePath:         cfg.ZKBasePath(),
+		znodeOwner:         cfg.ZKZnodeOwner(),
+		versionPath:        makeVersionPath(cfg.ZKBasePath()),
+		zkPollingInterval:  cfg.ZKPollingInterval(),
+		writeMaxRetries:    cfg.ZKWriteMaxRetries(),
+		writeRetryInterval: cfg.ZKWriteRetryInterval(),
+		operationTimeout:   cfg.UpdateOperationTimeout(),
+		eventsToKeep:       cfg.ClusterEventsToKeep(),
+		advertiseURL:       cfg.AdvertiseURL(),
+		readOnly:           cfg.Replica(),
+		versionWatcher:     nil,
+		clock:              clock.New(),
+		reinit: zkReinit{
+			jitter: cfg.ZKReinitJitter(),
+			offset: randomReinitOffset(cfg.ZKReinitJitter()),
+		},
 	}
 	go store.connectAndInitZKAsync(cfg)
 	return store
@@ -65,29 +148,119 @@ func (zks *zkVersionStore) CurrentVersion() (UIVersion, error) {
 
 // UpdateCurrentVersion sets the UIVersion stored to the newVersion provided
 func (zks *zkVersionStore) UpdateCurrentVersion(newVersion UIVersion) error {
+	if zks.readOnly {
+		return ErrReadOnlyReplica
+	}
+	err := zks.setVersion(newVersion)
+	health.DefaultTracker.Record(health.VersionStore, err)
+	return err
+}
+
+func (zks *zkVersionStore) setVersion(newVersion UIVersion) error {
 	if zks.client == nil || zks.client.ClientState() != zookeeper.Connected {
 		return ErrZookeeperNotConnected
 	}
+	raced := false
+	if observed := zks.operationVersionNode(); observed != nil {
+		// the cluster operation writes against the node it read when it started, so a version stored by
+		// another master in the meantime is reported even if this master already observed it
+		stat, err := zks.client.SetVersioned(zks.versionPath, []byte(newVersion), observed.stat)
+		if err == nil {
+			zks.setOperationVersionNode(&zkVersionNode{version: newVersion, stat: stat})
+			zks.versionStored(observed.version, newVersion)
+			return nil
+		}
+		if err != zookeeper.ErrBadVersion {
+			return errors.Wrap(err, "Failed to create version in ZK, not able to set the version node")
+		}
+		raced = true
+	}
 
-	_, err := zks.client.Set(zks.versionPath, []byte(newVersion))
-	if err != nil {
-		return errors.Wrap(err, "Failed to create version in ZK, not able to set the version node")
+	// the write only succeeds if the node is unchanged since it was read, so a concurrent write
+	// of another master is detected and the latest write wins
+	var stored UIVersion
+	var stat int32
+	for attempt := 1; ; attempt++ {
+		data, readStat, err := zks.client.Get(zks.versionPath)
+		if err != nil {
+			return errors.Wrap(err, "Failed to read the version node before setting it")
+		}
+		if stored = UIVersion(data); stored != newVersion && (raced || stored != zks.localVersion()) {
+			zks.recordConflict(stored, newVersion)
+		}
+		stat, err = zks.client.SetVersioned(zks.versionPath, []byte(newVersion), readStat)
+		if err == nil {
+			break
+		}
+		if err != zookeeper.ErrBadVersion || attempt > zks.writeMaxRetries {
+			return errors.Wrap(err, "Failed to create version in ZK, not able to set the version node")
+		}
+		raced = true
+		log.WithFields(logrus.Fields{"version": newVersion, "zk-node": zks.versionPath}).Warn("Version node changed while setting it, retrying")
+		if zks.writeRetryInterval > 0 {
+			<-zks.clock.After(zks.writeRetryInterval)
+		}
 	}
-	zks.updateLocalCurrentVersion(newVersion)
+	zks.setOperationVersionNode(&zkVersionNode{version: newVersion, stat: stat})
+	zks.versionStored(stored, newVersion)
 	return nil
 }
 
+// versionStored records the overwritten version as the previous one and publishes the new version
+func (zks *zkVersionStore) versionStored(stored, newVersion UIVersion) {
+	if stored != newVersion {
+		if err := zks.setPreviousVersion(stored); err != nil {
+			log.WithError(err).WithField("previousVersion", stored).Warn("Failed to record the previous version")
+		}
+	}
+	zks.updateLocalCurrentVersion(newVersion)
+	zks.publishVersionTrigger(newVersion)
+}
+
+// recordConflict logs and counts a write of version racing with a version stored by another master,
+// the write overwrites the other version by VersionConflictRule
+func (zks *zkVersionStore) recordConflict(stored, version UIVersion) {
+	logger := log.WithFields(logrus.Fields{
+		"stored":  stored,
+		"version": version,
+		"zk-node": zks.versionPath,
+	})
+	logger.WithField("rule", VersionConflictRule).Warn("Conflicting version write, another master stored a different version concurrently. The latest write wins")
+	versionConflicts.Inc(VersionConflictRule)
+
+	zks.conflict.Lock()
+	defer zks.conflict.Unlock()
+	zks.conflict.last = &VersionConflict{
+		Overwritten: stored,
+		Version:     version,
+		Rule:        VersionConflictRule,
+		Time:        zks.clock.Now().UTC(),
+	}
+}
+
+// LastVersionConflict returns the last conflicting version write of this master, nil if there was none
+func (zks *zkVersionStore) LastVersionConflict() *VersionConflict {
+	zks.conflict.Lock()
+	defer zks.conflict.Unlock()
+	return zks.conflict.last
+}
+
 // WatchForVersionChange registers the VersionChangeListener provided to be called when changes
 // to the stored version are received. Provided listener will be called with the current version
 // upon successful registration. VersionChangeListener is called asyncronously and must handle all
-// errors internally.
+// errors internally. Calls of the same listener do not overlap, it is called with the versions in the
+// order they were stored and the last call is with the latest version.
 func (zks *zkVersionStore) WatchForVersionChange(listener VersionChangeListener) error {
+	// the version cannot change while the listener is registered
+	zks.currentVersion.Lock()
+	defer zks.currentVersion.Unlock()
 	zks.listeners.Lock()
 	defer zks.listeners.Unlock()
 
-	zks.listeners.versionListeners = append(zks.listeners.versionListeners, listener)
+	delivery := newVersionDelivery(listener)
+	zks.listeners.versionListeners = append(zks.listeners.versionListeners, delivery)
 	if zks.currentVersion.initialized {
-		go listener(zks.currentVersion.currentVersion)
+		delivery.deliver(zks.currentVersion.currentVersion)
 	}
 
 	return nil
@@ -104,13 +277,14 @@ func (zks *zkVersionStore) connectAndInitZKAsync(cfg *config.Config) {
 	for {
 		connectionAttempt++
 		zkClient, err := zookeeper.Connect(cfg)
+		health.DefaultTracker.Record(health.Zookeeper, err)
 		if err != nil {
 			backoffDuration := b.Duration()
 			log.WithError(err).WithFields(logrus.Fields{
 				"connectionAttempt": connectionAttempt,
 				"backOffDuration":   backoffDuration,
 			}).Warning("Failed to connect to ZK")
-			<-time.After(backoffDuration)
+			<-zks.clock.After(backoffDuration)
 		} else {
 			zks.initZKVersionStore(zkClient)
 			if connectionAttempt > 1 {
@@ -126,6 +300,15 @@ func (zks *zkVersionStore) connectAndInitZKAsync(cfg *config.Config) {
 }
 
 func (zks *zkVersionStore) initZKVersionStore(client zookeeper.ZKClient) {
+	zks.lifecycle.Lock()
+	defer zks.lifecycle.Unlock()
+	if zks.lifecycle.closed {
+		client.Close()
+		return
+	}
+	if zks.readOnly {
+		client = zookeeper.ReadOnly(client)
+	}
 	zks.client = client
 	client.RegisterListener("zk-version-store-version", func(state zookeeper.ClientState) {
 		go zks.handleZKStateChange(state)
@@ -137,15 +320,22 @@ func makeVersionPath(basePath string) string {
 }
 
 func (zks *zkVersionStore) handleZKStateChange(state zookeeper.ClientState) {
-	if zks.zkClientState == state {
-		return
-	}
+	zks.stateLock.Lock()
 	oldState := zks.zkClientState
 	zks.zkClientState = state
+	zks.stateLock.Unlock()
+	if oldState == state {
+		return
+	}
 	log.WithFields(logrus.Fields{"state": state}).Info("ZK connection state changed")
+	if state == zookeeper.Connected {
+		health.DefaultTracker.Record(health.Zookeeper, nil)
+	} else {
+		health.DefaultTracker.Record(health.Zookeeper, ErrZookeeperNotConnected)
+	}
 
 	if oldState == zookeeper.Disconnected {
-		zks.initCurrentVersion()
+		zks.initializeConnection()
 	}
 }
 
@@ -163,12 +353,13 @@ func (zks *zkVersionStore) updateLocalCurrentVersion(version UIVersion) {
 		zks.currentVersion.initialized = true
 	}
 
-	go zks.broadcastVersionChange()
+	zks.broadcastVersionChange(version)
 	log.WithFields(logrus.Fields{"version": version}).Debug("Current UI version cached from ZK")
 }
 
 func (zks *zkVersionStore) getVersionFromZK() (UIVersion, error) {
 	data, _, err := zks.client.Get(zks.versionPath)
+	health.DefaultTracker.Record(health.VersionStore, err)
 	if err != nil {
 		return UIVersion(""), errors.Wrap(err, "unable to get version from zk")
 	}
@@ -183,7 +374,10 @@ func (zks *zkVersionStore) initCurrentVersion() {
 	if err != nil {
 		panic(fmt.Sprintf("Error making exists check in zookeeper for ui version node @ '%v'. Error: %v", zks.versionPath, err.Error()))
 	}
-	if !found {
+	if !found && zks.readOnly {
+		// the masters have not stored a version yet
+		version = PreBundledUIVersion
+	} else if !found {
 		err = zks.client.Create(zks.versionPath, []byte(PreBundledUIVersion), zookeeper.PermAll)
 		if err != nil {
 			panic(fmt.Sprintf("Error creating zookeeper ui version node @ '%v'. Error: %v", zks.versionPath, err.Error()))
@@ -200,22 +394,18 @@ func (zks *zkVersionStore) initCurrentVersion() {
 	zks.updateLocalCurrentVersion(version)
 
 	zks.createVersionWatcher()
+	zks.createTriggerWatcher()
 }
 
-func (zks *zkVersionStore) broadcastVersionChange() {
-	if len(zks.listeners.versionListeners) == 0 {
-		// don't bother contining if there are no listeners
-		return
-	}
-
+// broadcastVersionChange queues version for each listener. It is called while the current version is
+// locked, so the versions are queued in the order they were stored.
+func (zks *zkVersionStore) broadcastVersionChange(version UIVersion) {
 	// Don't add new listeners while we are broadcasting
 	zks.listeners.Lock()
-	// Wait for broadcast to complete before allowing to update the local version again
 	defer zks.listeners.Unlock()
 
-	currentVersion := zks.localVersion()
-	for _, listener := range zks.listeners.versionListeners {
-		go listener(currentVersion)
+	for _, delivery := range zks.listeners.versionListeners {
+		delivery.deliver(version)
 	}
 }
 
@@ -230,10 +420,12 @@ func (zks *zkVersionStore) createVersionWatcher() {
 		log.WithError(err).Warn("Failed to create ZK node watcher")
 		return
 	}
+	watcher.SetRestartDelay(zks.reinit.versionDelay())
 	zks.versionWatcher = watcher
 }
 
 func (zks *zkVersionStore) versionWatcherCallback(data []byte) {
+	health.DefaultTracker.Record(health.VersionStore, nil)
 	version := UIVersion(data)
 	currentVersion := zks.localVersion()
 	if version != currentVersion {
@@ -246,3 +438,24 @@ func (zks *zkVersionStore) localVersion() UIVersion {
 	defer zks.currentVersion.Unlock()
 	return zks.currentVersion.currentVersion
 }
+
+// Close stops watching the version node and closes the ZK connection, which releases the
+// ephemeral nodes of this master
+func (zks *zkVersionStore) Close() {
+	zks.lifecycle.Lock()
+	defer zks.lifecycle.Unlock()
+	if zks.lifecycle.closed {
+		return
+	}
+	zks.lifecycle.closed = true
+	if zks.versionWatcher != nil {
+		zks.versionWatcher.Close()
+	}
+	if zks.triggerWatcher != nil {
+		zks.triggerWatcher.Close()
+	}
+	if zks.client != nil {
+		zks.client.Close()
+	}
+	log.Info("Closed ZK connection")
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/dcos/dcos-ui-update-service/clock"
 	"github.com/dcos/dcos-ui-update-service/tests"
 	"github.com/dcos/dcos-ui-update-service/zookeeper"
 	"github.com/pkg/errors"
@@ -23,12 +24,24 @@ func makeZKStore(version string) (*zkVersionStore, *zookeeper.FakeZKClient) {
 		zkBasePath:        "/dcos/ui-service-test",
 		versionPath:       "/dcos/ui-service-test/version",
 		zkPollingInterval: time.Duration(60 * time.Second),
+		writeMaxRetries:   2,
+		clock:             clock.New(),
 	}, fakeClient
 }
 
 func TestZKVersionStore(t *testing.T) {
 	t.Parallel()
 
+	t.Run("Close() drops a connection established afterwards", func(t *testing.T) {
+		store, _ := makeZKStore("1.0.0")
+		store.client = nil
+
+		store.Close()
+		store.initZKVersionStore(zookeeper.NewFakeZKClient())
+
+		tests.H(t).BoolEql(store.client == nil, true)
+	})
+
 	t.Run("CurrentVersion() returns cached current version", func(t *testing.T) {
 		expectedVersion := "1.0.0"
 
@@ -61,7 +74,7 @@ func TestZKVersionStore(t *testing.T) {
 		store, client := makeZKStore("1.0.0")
 
 		var setCalled bool
-		client.SetCall = func(path string, data []byte) {
+		client.SetVersionedCall = func(path string, data []byte, version int32) {
 			setCalled = true
 		}
 
@@ -74,7 +87,7 @@ func TestZKVersionStore(t *testing.T) {
 		store, client := makeZKStore("1.0.0")
 
 		var setData []byte
-		client.SetCall = func(path string, data []byte) {
+		client.SetVersionedCall = func(path string, data []byte, version int32) {
 			setData = data
 		}
 		expectedVersion := "1.1.0"
@@ -110,6 +123,104 @@ func TestZKVersionStore(t *testing.T) {
 		}
 	})
 
+	t.Run("UpdateCurrentVersion() retries if another master changed the node", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.GetResults = [][]byte{[]byte("1.0.0"), []byte("1.2.0")}
+		client.SetVersionedErrors = []error{zookeeper.ErrBadVersion}
+		var setVersions []int32
+		client.SetVersionedCall = func(path string, data []byte, version int32) {
+			setVersions = append(setVersions, version)
+		}
+
+		err := store.UpdateCurrentVersion(UIVersion("1.1.0"))
+
+		tests.H(t).IsNil(err)
+		tests.H(t).InterfaceEql(setVersions, []int32{0, 1})
+		conflict := store.LastVersionConflict()
+		tests.H(t).StringEql(string(conflict.Overwritten), "1.2.0")
+		tests.H(t).StringEql(string(conflict.Version), "1.1.0")
+		cv, _ := store.CurrentVersion()
+		tests.H(t).StringEql(string(cv), "1.1.0")
+	})
+
+	t.Run("UpdateCurrentVersion() gives up after losing the race repeatedly", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.GetResult = []byte("1.0.0")
+		client.SetVersionedErrors = []error{zookeeper.ErrBadVersion, zookeeper.ErrBadVersion, zookeeper.ErrBadVersion}
+
+		err := store.UpdateCurrentVersion(UIVersion("1.1.0"))
+
+		tests.H(t).NotNil(err)
+		cv, _ := store.CurrentVersion()
+		tests.H(t).StringEql(string(cv), "1.0.0")
+	})
+
+	t.Run("UpdateCurrentVersion() retries as often as configured", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		store.writeMaxRetries = 3
+		client.GetResult = []byte("1.0.0")
+		client.SetVersionedErrors = []error{zookeeper.ErrBadVersion, zookeeper.ErrBadVersion, zookeeper.ErrBadVersion}
+
+		tests.H(t).IsNil(store.UpdateCurrentVersion(UIVersion("1.1.0")))
+	})
+
+	t.Run("UpdateCurrentVersion() writes against the version node read when the operation started", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.GetResults = [][]byte{[]byte("1.0.0"), []byte("1.2.0")}
+		var setVersions []int32
+		client.SetVersionedCall = func(path string, data []byte, version int32) {
+			setVersions = append(setVersions, version)
+		}
+		_, err := store.AcquireOperation(ClusterOperation{ID: "op-1"})
+		tests.H(t).IsNil(err)
+
+		tests.H(t).IsNil(store.UpdateCurrentVersion(UIVersion("1.1.0")))
+		tests.H(t).IsNil(store.UpdateCurrentVersion(UIVersion("1.0.0")))
+
+		tests.H(t).InterfaceEql(setVersions, []int32{0, 1})
+		tests.H(t).BoolEql(store.LastVersionConflict() == nil, true)
+	})
+
+	t.Run("UpdateCurrentVersion() overwrites a version stored since the operation started", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.GetResults = [][]byte{[]byte("1.0.0"), []byte("1.2.0")}
+		client.SetVersionedErrors = []error{zookeeper.ErrBadVersion}
+		var setVersions []int32
+		client.SetVersionedCall = func(path string, data []byte, version int32) {
+			setVersions = append(setVersions, version)
+		}
+		_, err := store.AcquireOperation(ClusterOperation{ID: "op-1"})
+		tests.H(t).IsNil(err)
+
+		tests.H(t).IsNil(store.UpdateCurrentVersion(UIVersion("1.1.0")))
+
+		tests.H(t).InterfaceEql(setVersions, []int32{0, 1})
+		conflict := store.LastVersionConflict()
+		tests.H(t).StringEql(string(conflict.Overwritten), "1.2.0")
+		tests.H(t).StringEql(conflict.Rule, VersionConflictRule)
+		cv, _ := store.CurrentVersion()
+		tests.H(t).StringEql(string(cv), "1.1.0")
+	})
+
+	t.Run("ReleaseOperation() ends the operation while zk is disconnected", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.GetResult = []byte("1.0.0")
+		_, err := store.AcquireOperation(ClusterOperation{ID: "op-1"})
+		tests.H(t).IsNil(err)
+		client.ClientStateResult = zookeeper.Disconnected
+
+		tests.H(t).ErrEql(store.ReleaseOperation(), ErrZookeeperNotConnected)
+		tests.H(t).BoolEql(store.operationVersionNode() == nil, true)
+	})
+
+	t.Run("UpdateCurrentVersion() does not report a conflict for an observed version", func(t *testing.T) {
+		store, client := makeZKStore("1.0.0")
+		client.GetResult = []byte("1.0.0")
+
+		tests.H(t).IsNil(store.UpdateCurrentVersion(UIVersion("1.1.0")))
+		tests.H(t).BoolEql(store.LastVersionConflict() == nil, true)
+	})
+
 	t.Run("UpdateCurrentVersion() fails if zk is disconnected", func(t *testing.T) {
 		store, client := makeZKStore("")
 		client.ClientStateResult = zookeeper.Disconnected
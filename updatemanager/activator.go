@@ -0,0 +1,98 @@
+package updatemanager
+
+import (
+	"context"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/downloader"
+	"github.com/dcos/dcos-ui-update-service/hooks"
+	"github.com/sirupsen/logrus"
+)
+
+// Activator switches the served ui to a downloaded version. UpdateToVersion drives the phases and
+// guarantees the cleanup: the downloaded version is removed if Prepare or Commit fail and Rollback
+// is called with the previously served path if Commit fails, so callers only implement the steps.
+type Activator interface {
+	// Prepare checks that the version at newVersionPath can be activated without changing what is served
+	Prepare(newVersionPath string) error
+	// Commit serves the version at newVersionPath and records it, it may fail after partially applying
+	Commit(newVersionPath string) error
+	// Rollback serves previousVersionPath again after Commit failed, it must be safe to call after
+	// a partially applied Commit
+	Rollback(previousVersionPath string) error
+}
+
+// Phases of an update reported to a ProgressReporter
+const (
+	PhaseDownloading = "downloading"
+	PhaseUnpacking   = "unpacking"
+	PhaseSwapping    = "swapping"
+)
+
+// ProgressReporter is implemented by an Activator that follows the phases of the update
+type ProgressReporter interface {
+	ReportProgress(phase string)
+}
+
+func reportProgress(activator Activator, phase string) {
+	if reporter, ok := activator.(ProgressReporter); ok {
+		reporter.ReportProgress(phase)
+	}
+}
+
+// Cancellable is implemented by an Activator whose update can be cancelled. The download and unpack stop once
+// the context is done, the partially created version directory is removed and the update fails with
+// ErrUpdateCancelled. Once Prepare was called the update can no longer be cancelled by the context.
+type Cancellable interface {
+	Context() context.Context
+}
+
+// updateContext returns the context of a Cancellable activator, context.Background otherwise
+func updateContext(activator Activator) context.Context {
+	if cancellable, ok := activator.(Cancellable); ok {
+		return cancellable.Context()
+	}
+	return context.Background()
+}
+
+// HookReporter is implemented by an Activator that records the results of the hooks run for the update,
+// e.g. to report a failed post-activate hook in the result of an otherwise successful update
+type HookReporter interface {
+	ReportHookResults(point hooks.Point, results []hooks.Result)
+}
+
+func reportHookResults(activator Activator, point hooks.Point, results []hooks.Result) {
+	if reporter, ok := activator.(HookReporter); ok {
+		reporter.ReportHookResults(point, results)
+	}
+}
+
+// DownloadProgressReporter is implemented by an Activator that follows the bytes received while downloading
+type DownloadProgressReporter interface {
+	ReportDownloadProgress(progress downloader.Progress)
+}
+
+// downloadLogInterval is the minimum time between two download progress log records
+var downloadLogInterval = 10 * time.Second
+
+// downloadProgress returns the progress callback of a download of version, it reports the progress to the
+// activator and logs it at most once per downloadLogInterval and once the download completed
+func downloadProgress(version string, activator Activator) func(downloader.Progress) {
+	var logged time.Time
+	return func(progress downloader.Progress) {
+		if reporter, ok := activator.(DownloadProgressReporter); ok {
+			reporter.ReportDownloadProgress(progress)
+		}
+		complete := progress.Total >= 0 && progress.Downloaded >= progress.Total
+		if !complete && time.Since(logged) < downloadLogInterval {
+			return
+		}
+		logged = time.Now()
+		logrus.WithFields(logrus.Fields{
+			"version":    version,
+			"downloaded": progress.Downloaded,
+			"total":      progress.Total,
+			"percent":    progress.Percent(),
+		}).Info("Download progress")
+	}
+}
@@ -0,0 +1,87 @@
+package updatemanager
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// ArtifactCache looks up bundles by file name in local artifact mirrors, so clusters with an
+// on-prem mirror don't download every version from the external URL of the package source
+type ArtifactCache struct {
+	// Dir is a local directory holding bundle files, not checked if empty
+	Dir string
+	// BaseURL is the URL of an artifact mirror like the bootstrap node, not checked if nil
+	BaseURL *url.URL
+	Fs      afero.Fs
+	Fetcher BundleFetcher
+}
+
+// Load unpacks the bundle of bundleURL from the first mirror having it to targetDirectory,
+// it returns false if no mirror had the bundle and targetDirectory is left empty
+func (c *ArtifactCache) Load(ctx context.Context, bundleURL *url.URL, targetDirectory string) bool {
+	if c == nil {
+		return false
+	}
+	name := path.Base(bundleURL.Path)
+	if name == "." || name == "/" {
+		return false
+	}
+	logger := logrus.WithField("bundle", name)
+
+	if c.Dir != "" {
+		filePath := path.Join(c.Dir, name)
+		if exists, _ := afero.Exists(c.Fs, filePath); exists {
+			fileURL := &url.URL{Scheme: "file", Path: filePath}
+			if err := c.Fetcher.DownloadAndUnpack(ctx, fileURL, targetDirectory); err == nil {
+				logger.WithField("path", filePath).Info("Loaded bundle from the local artifact cache")
+				return true
+			}
+			logger.WithField("path", filePath).Warn("Failed to unpack bundle from the local artifact cache")
+			c.clean(targetDirectory)
+		}
+	}
+
+	if c.BaseURL != nil {
+		mirrorURL := *c.BaseURL
+		mirrorURL.Path = path.Join(mirrorURL.Path, name)
+		status, _, err := c.Fetcher.Head(ctx, &mirrorURL)
+		if err == nil && status == http.StatusOK {
+			if err := c.Fetcher.DownloadAndUnpack(ctx, &mirrorURL, targetDirectory); err == nil {
+				logger.WithField("url", mirrorURL.String()).Info("Loaded bundle from the artifact mirror")
+				return true
+			}
+			logger.WithField("url", mirrorURL.String()).Warn("Failed to download bundle from the artifact mirror")
+			c.clean(targetDirectory)
+		}
+	}
+	return false
+}
+
+// clean removes files left in targetDirectory by a failed attempt
+func (c *ArtifactCache) clean(targetDirectory string) {
+	if err := c.Fs.RemoveAll(targetDirectory); err != nil {
+		logrus.WithError(err).Warn("Failed to clean up after loading from artifact cache failed")
+	}
+	c.Fs.MkdirAll(targetDirectory, 0755)
+}
+
+// newArtifactCache creates the ArtifactCache configured by dir and baseURL, nil if none is configured
+func newArtifactCache(dir, baseURL string, fs afero.Fs, fetcher BundleFetcher) (*ArtifactCache, error) {
+	if dir == "" && baseURL == "" {
+		return nil, nil
+	}
+	cache := &ArtifactCache{Dir: dir, Fs: fs, Fetcher: fetcher}
+	if baseURL != "" {
+		parsed, err := url.Parse(baseURL)
+		if err != nil {
+			return nil, err
+		}
+		cache.BaseURL = parsed
+	}
+	return cache, nil
+}
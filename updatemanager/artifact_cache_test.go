@@ -0,0 +1,67 @@
+package updatemanager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/spf13/afero"
+)
+
+func TestArtifactCache(t *testing.T) {
+	bundleURL, _ := url.Parse("https://downloads.example.com/dcos-ui/release.tar.gz")
+	mirrorURL, _ := url.Parse("http://bootstrap.example.com/artifacts")
+
+	t.Run("loads the bundle from the local directory", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		afero.WriteFile(fs, "/artifacts/release.tar.gz", []byte("bundle"), 0644)
+		fetcher := &FakeBundleFetcher{}
+		cache := &ArtifactCache{Dir: "/artifacts", BaseURL: mirrorURL, Fs: fs, Fetcher: fetcher}
+
+		loaded := cache.Load(context.Background(), bundleURL, "/ui-versions/2.25.2")
+
+		tests.H(t).BoolEql(loaded, true)
+		tests.H(t).InterfaceEql(fetcher.Downloaded, []string{"file:///artifacts/release.tar.gz"})
+		tests.H(t).IntEql(len(fetcher.Headed), 0)
+	})
+
+	t.Run("loads the bundle from the mirror", func(t *testing.T) {
+		fetcher := &FakeBundleFetcher{}
+		cache := &ArtifactCache{Dir: "/missing", BaseURL: mirrorURL, Fs: afero.NewMemMapFs(), Fetcher: fetcher}
+
+		loaded := cache.Load(context.Background(), bundleURL, "/ui-versions/2.25.2")
+
+		tests.H(t).BoolEql(loaded, true)
+		tests.H(t).InterfaceEql(fetcher.Headed, []string{"http://bootstrap.example.com/artifacts/release.tar.gz"})
+		tests.H(t).InterfaceEql(fetcher.Downloaded, []string{"http://bootstrap.example.com/artifacts/release.tar.gz"})
+	})
+
+	t.Run("returns false if the mirror does not have the bundle", func(t *testing.T) {
+		fetcher := &FakeBundleFetcher{HeadStatus: http.StatusNotFound}
+		cache := &ArtifactCache{BaseURL: mirrorURL, Fs: afero.NewMemMapFs(), Fetcher: fetcher}
+
+		tests.H(t).BoolEql(cache.Load(context.Background(), bundleURL, "/ui-versions/2.25.2"), false)
+		tests.H(t).IntEql(len(fetcher.Downloaded), 0)
+	})
+
+	t.Run("cleans up after a corrupted local bundle", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		afero.WriteFile(fs, "/artifacts/release.tar.gz", []byte("not a tarball"), 0644)
+		afero.WriteFile(fs, "/ui-versions/2.25.2/partial", []byte("partial"), 0644)
+		cache := &ArtifactCache{Dir: "/artifacts", Fs: fs, Fetcher: &FakeBundleFetcher{DownloadError: errors.New("Error unzipping package")}}
+
+		loaded := cache.Load(context.Background(), bundleURL, "/ui-versions/2.25.2")
+
+		tests.H(t).BoolEql(loaded, false)
+		empty, _ := afero.IsEmpty(fs, "/ui-versions/2.25.2")
+		tests.H(t).BoolEql(empty, true)
+	})
+
+	t.Run("returns false without a cache", func(t *testing.T) {
+		var cache *ArtifactCache
+		tests.H(t).BoolEql(cache.Load(context.Background(), bundleURL, "/ui-versions/2.25.2"), false)
+	})
+}
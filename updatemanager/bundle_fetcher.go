@@ -0,0 +1,69 @@
+package updatemanager
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/dcos/dcos-ui-update-service/auth"
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/downloader"
+	"github.com/dcos/dcos-ui-update-service/proxy"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// BundleFetcher downloads ui bundles, the downloader.Client is the HTTP implementation
+type BundleFetcher interface {
+	// DownloadAndUnpack downloads the tar.gz bundle from bundleURL and extracts it to targetDirectory,
+	// file URLs are used for bundles in the local artifact cache
+	DownloadAndUnpack(ctx context.Context, bundleURL *url.URL, targetDirectory string) error
+	// Head requests only the headers of bundleURL, returns the response status and content length
+	Head(ctx context.Context, bundleURL *url.URL) (int, int64, error)
+	// Fetch downloads the small file at fileURL, e.g. the detached signature of a bundle
+	Fetch(ctx context.Context, fileURL *url.URL) ([]byte, error)
+}
+
+// NewBundleFetcher creates the HTTP BundleFetcher with the timeout and proxy of the config, bundles
+// served by the Universe host are downloaded with the auth token of credentials if they are not nil
+func NewBundleFetcher(cfg *config.Config, fs afero.Fs, credentials *auth.CredentialProvider) (BundleFetcher, error) {
+	loader := downloader.New(fs)
+	loader.Timeout = cfg.DownloadTimeout()
+	loader.MaxFiles = cfg.ExtractMaxFiles()
+	loader.MaxDepth = cfg.ExtractMaxDepth()
+	loader.MaxSize = cfg.DownloadMaxSize()
+	loader.Retry = retryPolicy(cfg)
+	settings, err := proxySettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DownloadProxy() != "" {
+		downloadProxy, err := url.Parse(cfg.DownloadProxy())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse configured download proxy")
+		}
+		settings.HTTP, settings.HTTPS = downloadProxy, downloadProxy
+	}
+	settings.NoProxy = append(settings.NoProxy, cfg.DownloadNoProxy()...)
+	// the proxy environment variables are only used if no proxy is configured
+	fromEnvironment := !settings.Configured()
+	loader.UseProxy(settings, fromEnvironment)
+	logrus.WithFields(settings.Fields()).WithField("fromEnvironment", fromEnvironment).Info("Bundle download proxy")
+	if credentials != nil {
+		universeURL, err := url.Parse(cfg.UniverseURL())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse configured Universe URL")
+		}
+		loader.UseCredentials(credentials, universeURL.Host)
+	}
+	return loader, nil
+}
+
+// proxySettings are the http-proxy, https-proxy and no-proxy settings of the config
+func proxySettings(cfg *config.Config) (proxy.Settings, error) {
+	settings, err := proxy.Parse(cfg.HTTPProxy(), cfg.HTTPSProxy(), cfg.NoProxy())
+	if err != nil {
+		return proxy.Settings{}, errors.Wrap(err, "failed to parse configured proxy")
+	}
+	return settings, nil
+}
@@ -1,15 +1,20 @@
 package updatemanager
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"os"
 	"path"
+	"strings"
 	"sync"
 
 	"github.com/dcos/dcos-ui-update-service/config"
 	"github.com/dcos/dcos-ui-update-service/cosmos"
 	"github.com/dcos/dcos-ui-update-service/downloader"
+	"github.com/dcos/dcos-ui-update-service/health"
+	"github.com/dcos/dcos-ui-update-service/hooks"
+	"github.com/dcos/dcos-ui-update-service/signature"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
@@ -30,96 +35,191 @@ var (
 	ErrCosmosRequestFailure = errors.New("Retrieving data from Cosmos failed")
 	// ErrRequestedVersionNotFound occurs if the version requests is not available in Cosmos
 	ErrRequestedVersionNotFound = errors.New("The requested version is not available")
-	// ErrUIPackageAssetNotFound occurs if we cannot find the dcos-ui-bundle asset URI in the version package details
-	ErrUIPackageAssetNotFound = errors.New("Could not find dcos-ui-bundle asset in package details")
-	// ErrUIPackageAssetBadURI occurs if the dcos-ui-bundle asset URI cannot be parsed
-	ErrUIPackageAssetBadURI = errors.New("Failed to parse dcos-ui-bundle asset URI")
+	// ErrUIPackageAssetNotFound occurs if we cannot find the ui bundle asset URI in the version package details
+	ErrUIPackageAssetNotFound = errors.New("Could not find ui bundle asset in package details")
+	// ErrUIPackageAssetBadURI occurs if the ui bundle asset URI cannot be parsed
+	ErrUIPackageAssetBadURI = errors.New("Failed to parse ui bundle asset URI")
 	// ErrRemovingVersion occurs if removing the version fails
 	ErrRemovingVersion = errors.New("Failed to remove the version")
 	// ErrReadingVersions occurs if client cannot read versions-root for deleting all
 	ErrReadingVersions = errors.New("Failed to read versions root directory")
+	// ErrReadOnlyFilesystem occurs if versions-root or the directory of the ui dist symlink is not writable
+	ErrReadOnlyFilesystem = errors.New("E_READONLY_FS: versions-root or ui dist symlink directory is not writable")
+	// ErrInvalidBundleLayout occurs if an unpacked bundle has no dist/index.html setting DCOS_UI_VERSION
+	ErrInvalidBundleLayout = errors.New("E_INVALID_BUNDLE: the bundle has no dist/index.html setting DCOS_UI_VERSION")
+	// ErrUpdateCancelled occurs if the update was cancelled before the new version was activated
+	ErrUpdateCancelled = errors.New("The update was cancelled")
 )
 
 // Client handles access to common setup question
 type Client struct {
-	Cosmos      *cosmos.Client
-	Loader      *downloader.Client
+	Source      PackageSource
+	Fetcher     BundleFetcher
 	UniverseURL *url.URL
 	Config      *config.Config
 	Fs          afero.Fs
+	Hooks       *hooks.Registry
+	Cache       *ArtifactCache
+	// Mirror receives the verified bundles of the cluster updates led by this master, nil if not configured
+	Mirror MirrorPublisher
+	// Verifier checks the bundle signatures before they are unpacked, nil if no signature keys are configured
+	Verifier *signature.Verifier
+	// requirements caches the minimum DC/OS release by version, it never changes for a published version
+	requirements     map[string]string
+	requirementsLock sync.Mutex
+	labelsLock       sync.Mutex
+	packages         packageNames
+	direct           directBundles
+	activation       lastActivation
+	contentChecks    contentChecks
+	// probeLock serializes the writability probes, they do not wait for a running update
+	probeLock sync.Mutex
 	sync.Mutex
 }
 
 type UpdateManager interface {
-	UpdateToVersion(string, func(string) error) error
+	UpdateToVersion(string, Activator) error
+	UpdateFromBundle(string, *url.URL, Activator) error
+	InstallVersion(string) error
 	RemoveVersion(string) error
 	RemoveAllVersionsExcept(string) error
+	PruneVersions(string) error
 	CurrentVersion() (string, error)
 	PathToCurrentVersion() (string, error)
+	AvailableVersions() ([]string, error)
+	CheckWritable() error
+	VerifyVersion(string) error
+	ProbeAsset(string) (*AssetProbe, error)
+	TrashedVersions() ([]TrashedVersion, error)
+	RestoreVersion(string) error
+	PurgeTrash() error
+	QuarantinedVersions() ([]QuarantinedVersion, error)
+	MinDcosReleaseVersion(string) (string, error)
+	VersionProvenance(string) (*Provenance, error)
+	SetApprovalTicket(string, string) (*Provenance, error)
+	RerunPostActivateHooks(context.Context) (*HookRerun, error)
+	LocalVersions() ([]LocalVersion, error)
+	SetVersionLabel(string, string) error
+	RemoveVersionLabel(string, string) error
+	LabeledVersion(string) (string, error)
+	PackageName(string) string
 }
 
-// NewClient creates a new instance of Client
-func NewClient(cfg *config.Config) (*Client, error) {
+// NewClient creates a new instance of Client loading versions from the given PackageSource
+// with the given BundleFetcher
+func NewClient(cfg *config.Config, source PackageSource, fetcher BundleFetcher) (*Client, error) {
 	universeURL, err := url.Parse(cfg.UniverseURL())
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse configured Universe URL")
 	}
 	fs := afero.NewOsFs()
+	cache, err := newArtifactCache(cfg.ArtifactCacheDir(), cfg.ArtifactCacheURL(), fs, fetcher)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse configured artifact cache URL")
+	}
+	publisher, err := NewMirrorPublisher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var verifier *signature.Verifier
+	if keys := cfg.SignatureKeys(); len(keys) > 0 {
+		if verifier, err = signature.LoadKeys(keys); err != nil {
+			return nil, err
+		}
+		logrus.WithField("keys", strings.Join(keys, ",")).Info("Verifying bundle signatures")
+	}
 
 	return &Client{
-		Cosmos:      cosmos.NewClient(universeURL),
-		Loader:      downloader.New(fs),
+		Source:      source,
+		Fetcher:     fetcher,
 		UniverseURL: universeURL,
 		Config:      cfg,
 		Fs:          fs,
+		Hooks:       hooks.New(cfg),
+		Cache:       cache,
+		Mirror:      publisher,
+		Verifier:    verifier,
 	}, nil
 }
 
 // LoadVersion downloads the given DC/OS UI version to the target directory.
-func (um *Client) loadVersion(version string, targetDirectory string) error {
-	pkgName := um.Config.PackageName()
-	listVersionResp, listErr := um.Cosmos.ListPackageVersions(pkgName)
-	if listErr != nil {
-		logrus.WithError(listErr).Error("Cosmos ListPackageVersions request failed")
-		return ErrCosmosRequestFailure
+func (um *Client) loadVersion(ctx context.Context, version string, targetDirectory string) error {
+	uiBundleURL, err := um.resolveBundleURL(version)
+	if err != nil {
+		return err
 	}
-	logrus.WithFields(logrus.Fields{"versions": listVersionResp}).Info("Loading Version: Retrieved package versions from cosmos")
 
-	if !listVersionResp.IncludesTargetVersion(version) {
-		return ErrRequestedVersionNotFound
+	ctx, err = um.withSignatureVerification(ctx, version, uiBundleURL)
+	if err != nil {
+		return err
 	}
 
-	assets, getAssetsErr := um.Cosmos.GetPackageAssets(pkgName, version)
-	if getAssetsErr != nil {
-		logrus.WithError(listErr).Error("Cosmos GetPackageAssets request failed")
-		return ErrCosmosRequestFailure
+	provenance := um.newProvenance(version, uiBundleURL)
+	ctx = withProvenance(ctx, provenance)
+	if !um.Cache.Load(ctx, uiBundleURL, targetDirectory) {
+		if umErr := um.Fetcher.DownloadAndUnpack(ctx, uiBundleURL, targetDirectory); umErr != nil {
+			logrus.WithError(umErr).Errorf("Download and unpack failed for %s", uiBundleURL)
+			return umErr
+		}
+		logrus.Info("Loading Version: Completed download and unpack")
 	}
-	logrus.Info("Loading Version: Retrieved package assets from cosmos")
 
-	uiBundleName := cosmos.PackageAssetNameString(pkgName + "-bundle")
-	uiBundleURI, found := assets[uiBundleName]
-	if !found {
-		return ErrUIPackageAssetNotFound
+	// the version is usable without its provenance, a failure is only logged
+	if err := um.writeProvenance(targetDirectory, provenance); err != nil {
+		logrus.WithError(err).WithField("version", version).Warn("Could not record the provenance of the version")
 	}
-	logrus.WithFields(logrus.Fields{
-		"asset": uiBundleURI,
-		"name":  uiBundleName,
-	}).Info("Loading Version: Found asset by name")
+	return nil
+}
 
-	uiBundleURL, err := url.Parse(string(uiBundleURI))
-	if err != nil {
-		logrus.WithError(err).Error("Failed to parse dcos-ui-bundle asset URI")
-		return ErrUIPackageAssetBadURI
+// resolveBundleURL checks that the version is available from the package source and returns its bundle URL,
+// versions installed by UpdateFromBundle are not looked up in the package source
+func (um *Client) resolveBundleURL(version string) (*url.URL, error) {
+	if bundleURL, ok := um.directBundle(version); ok {
+		return bundleURL, nil
+	}
+	pkgName, listErr := um.findPackageVersion(version)
+	if listErr != nil {
+		logrus.WithError(listErr).Error("Package source ListVersions request failed")
+		return nil, ErrCosmosRequestFailure
 	}
-	logrus.WithFields(logrus.Fields{"url": uiBundleURL}).Info("Loading Version: Bundle URI parsed to a URL")
+	if pkgName == "" {
+		return nil, ErrRequestedVersionNotFound
+	}
+	logrus.WithFields(logrus.Fields{"version": version, "package": pkgName}).Info("Loading Version: Found version in package source")
+
+	uiBundleURL, resolveErr := um.Source.ResolveBundle(pkgName, version)
+	switch resolveErr {
+	case nil:
+	case cosmos.ErrBundleAssetNotFound, ErrUIPackageAssetNotFound:
+		return nil, ErrUIPackageAssetNotFound
+	case cosmos.ErrBundleAssetBadURI:
+		logrus.WithError(resolveErr).WithField("asset", um.Config.PackageAssetName()).Error("Failed to parse ui bundle asset URI")
+		return nil, ErrUIPackageAssetBadURI
+	default:
+		logrus.WithError(resolveErr).Error("Package source ResolveBundle request failed")
+		return nil, ErrCosmosRequestFailure
+	}
+	logrus.WithFields(logrus.Fields{"url": uiBundleURL}).Info("Loading Version: Resolved bundle URL")
+	return uiBundleURL, nil
+}
 
-	if umErr := um.Loader.DownloadAndUnpack(uiBundleURL, targetDirectory); umErr != nil {
-		logrus.WithError(umErr).Errorf("Download and unpack failed for %s", uiBundleURI)
-		return umErr
+// AvailableVersions lists the versions of the configured package available from the package source
+func (um *Client) AvailableVersions() ([]string, error) {
+	versions, _, err := um.listPackageVersions()
+	if err != nil {
+		logrus.WithError(err).Error("Package source ListVersions request failed")
+		return nil, ErrCosmosRequestFailure
 	}
-	logrus.Info("Loading Version: Completed download and unpack")
+	return versions, nil
+}
 
-	return nil
+func includesVersion(versions []string, version string) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
 }
 
 // CurrentVersion retrieves the current version being served
@@ -139,7 +239,16 @@ func (um *Client) CurrentVersion() (string, error) {
 
 	versionPath, distDir := path.Split(servedVersionPath)
 	if distDir != "dist" {
-		return "", fmt.Errorf("Expected served version directory to be `dist` but got %s", distDir)
+		// manually installed layouts serve the ui from other directories, their bundle tells the version
+		buildVersion, err := BuildVersionFromIndex(servedVersionPath)
+		if err != nil {
+			return "", fmt.Errorf("Expected served version directory to be `dist` but got %s", distDir)
+		}
+		logrus.WithFields(logrus.Fields{
+			"path":    servedVersionPath,
+			"version": buildVersion,
+		}).Warn("Served directory is not the dist directory of a version, using the build version of its index.html")
+		return buildVersion, nil
 	}
 
 	currentVersion := path.Base(versionPath)
@@ -159,8 +268,8 @@ func (um *Client) PathToCurrentVersion() (string, error) {
 	return servedVersionPath, nil
 }
 
-// UpdateToVersion updates the ui to the given version
-func (um *Client) UpdateToVersion(version string, updateCompleteCallback func(string) error) error {
+// UpdateToVersion downloads the given version and activates it with the activator
+func (um *Client) UpdateToVersion(version string, activator Activator) error {
 	// Find out which version we currently have
 	currentVersion, cvErr := um.CurrentVersion()
 
@@ -170,9 +279,25 @@ func (um *Client) UpdateToVersion(version string, updateCompleteCallback func(st
 	}
 
 	if len(currentVersion) > 0 && currentVersion == version {
-		// noop if we are currently on the requested version
-		logrus.Info("Currently on requested version")
-		return nil
+		if um.VerifyVersion(version) != ErrVersionCorrupted {
+			// noop if we are currently on the requested version
+			logrus.Info("Currently on requested version")
+			return nil
+		}
+		// the immutable version changed on disk, serve the default ui while downloading it again
+		logrus.WithField("version", version).Warn("Served version is corrupted, downloading it again")
+		if err := um.activate(activator, um.Config.DefaultDocRoot(), um.Config.DefaultDocRoot()); err != nil {
+			logrus.WithError(err).Error("Could not switch to default ui to replace corrupted version")
+			return err
+		}
+		if err := um.RemoveVersion(version); err != nil {
+			return err
+		}
+		currentVersion = ""
+	}
+	previousVersionPath, err := um.PathToCurrentVersion()
+	if err != nil {
+		return ErrCouldNotGetCurrentVersion
 	}
 	um.Lock()
 	defer um.Unlock()
@@ -187,36 +312,190 @@ func (um *Client) UpdateToVersion(version string, updateCompleteCallback func(st
 		return ErrVersionsPathDoesNotExist
 	}
 
+	if err := um.checkWritable(); err != nil {
+		return err
+	}
+
 	targetDir := path.Join(um.Config.VersionsRoot(), version)
+	hookEvent := hooks.Event{Version: version, PreviousVersion: currentVersion, Path: path.Join(targetDir, "dist")}
+	// a previously served version kept for rollbacks is activated again without downloading it
+	reused := um.reusableVersion(version)
+	if reused {
+		logrus.WithField("version", version).Info("Activating kept version without downloading it")
+	} else {
+		if err := um.downloadVersion(version, targetDir, hookEvent, activator); err != nil {
+			return err
+		}
+	}
+	removeTarget := func() {
+		if !reused {
+			um.Fs.RemoveAll(targetDir)
+		}
+	}
+	if updateContext(activator).Err() != nil {
+		removeTarget()
+		logrus.WithField("version", version).Info("Update cancelled before the activation")
+		return ErrUpdateCancelled
+	}
+
+	if err = um.runHooks(activator, hooks.PreActivate, hookEvent); err != nil {
+		removeTarget()
+		return err
+	}
+	reportProgress(activator, PhaseSwapping)
+	if err = um.activate(activator, path.Join(targetDir, "dist"), previousVersionPath); err != nil {
+		if err != errRollbackFailed {
+			// nothing serves the new version, it can be removed
+			removeTarget()
+		}
+		logrus.WithError(err).Error("Activating the new version failed. Update aborted")
+		um.runHooks(activator, hooks.PostRollback, hookEvent)
+		return err
+	}
+	um.runHooks(activator, hooks.PostActivate, hookEvent)
+
+	if reused {
+		// the manifest is rewritten with the new activation time
+		if err = um.chmodTree(targetDir, writableDirMode, writableFileMode); err != nil {
+			logrus.WithError(err).WithField("version", version).Warn("Could not make kept version writable")
+		}
+	}
+	if err = um.markImmutable(version); err != nil {
+		logrus.WithError(err).WithField("version", version).Warn("Could not mark version immutable")
+	}
+
+	// Remove the old versions not kept for rollbacks
+	return um.PruneVersions(version)
+}
+
+// downloadVersion downloads the version into targetDir, which is removed again if the download fails
+func (um *Client) downloadVersion(version, targetDir string, hookEvent hooks.Event, activator Activator) error {
+	if exists, _ := afero.DirExists(um.Fs, targetDir); exists {
+		// a kept version that changed since it was activated is downloaded again
+		logrus.WithField("version", version).Warn("Replacing version directory that cannot be reused")
+		um.chmodTree(targetDir, writableDirMode, writableFileMode)
+		if err := um.Fs.RemoveAll(targetDir); err != nil {
+			logrus.WithError(err).Error("Failed to remove version directory before the download")
+			return ErrCouldNotCreateNewVersionDirectory
+		}
+	}
 	// Create directory for next version
-	err := um.Fs.MkdirAll(targetDir, 0755)
-	if err != nil {
+	if err := um.Fs.MkdirAll(targetDir, 0755); err != nil {
 		logrus.WithError(err).Error("Failed to create new version directory for update")
 		return ErrCouldNotCreateNewVersionDirectory
 	}
 	logrus.WithFields(logrus.Fields{"directory": targetDir}).Info("Created directory for next version")
 
+	if err := um.runHooks(activator, hooks.PreDownload, hookEvent); err != nil {
+		um.Fs.RemoveAll(targetDir)
+		return err
+	}
+
 	// Update to next version
-	err = um.loadVersion(version, targetDir)
-	if err != nil {
+	reportProgress(activator, PhaseDownloading)
+	ctx := downloader.WithUnpackNotifier(updateContext(activator), func() {
+		reportProgress(activator, PhaseUnpacking)
+	})
+	ctx = downloader.WithProgressCallback(ctx, downloadProgress(version, activator))
+	bundle := um.newBundleCopy(activator)
+	if bundle != nil {
+		defer bundle.remove(um.Fs)
+		ctx = downloader.WithArchiveCopy(ctx, bundle.reset)
+	}
+	if err := um.loadVersion(ctx, version, targetDir); err != nil {
 		// Install failed delete the targetDir
 		um.Fs.RemoveAll(targetDir)
+		if ctx.Err() != nil {
+			logrus.WithField("version", version).Info("Update cancelled while downloading, deleted target directory")
+			return ErrUpdateCancelled
+		}
 		logrus.Error("Update to new version failed, deleted target directory")
 		return err
 	}
-	err = updateCompleteCallback(path.Join(targetDir, "dist"))
-	if err != nil {
-		// Swap to new version failed, abort update
+	if err := validateBundleLayout(version, targetDir); err != nil {
 		um.Fs.RemoveAll(targetDir)
-		logrus.WithError(err).Error("Update complete callback failed. Update aborted")
 		return err
 	}
+	if bundle != nil {
+		// the bundle is published before the activation, so the other masters find it in the mirror
+		um.publishBundle(version, bundle)
+	}
+	return nil
+}
 
-	if len(currentVersion) > 0 {
-		// Remove the old version
-		return um.RemoveVersion(currentVersion)
+// validateBundleLayout checks that the version unpacked to targetDir has a dist/index.html with a
+// DCOS_UI_VERSION, so a malformed package is never swapped in for the served ui
+func validateBundleLayout(version, targetDir string) error {
+	buildVersion, err := BuildVersionFromIndex(path.Join(targetDir, "dist"))
+	if err != nil {
+		logrus.WithError(err).WithField("version", version).Error("Unpacked bundle is not a ui distribution, deleted target directory")
+		return ErrInvalidBundleLayout
 	}
+	logrus.WithFields(logrus.Fields{"version": version, "buildVersion": buildVersion}).Debug("Validated unpacked bundle")
+	return nil
+}
+
+// errRollbackFailed is returned by activate if the previous version could not be served again
+var errRollbackFailed = errors.New("Failed to roll back to the previously served version")
+
+// activate runs the activator phases, rolling back to previousVersionPath if the commit fails
+func (um *Client) activate(activator Activator, newVersionPath, previousVersionPath string) error {
+	if err := activator.Prepare(newVersionPath); err != nil {
+		logrus.WithError(err).WithField("path", newVersionPath).Error("Preparing the activation failed")
+		return err
+	}
+	commitErr := activator.Commit(newVersionPath)
+	if commitErr == nil {
+		return nil
+	}
+	logrus.WithError(commitErr).WithField("path", newVersionPath).Error("Committing the activation failed, rolling back")
+	if err := activator.Rollback(previousVersionPath); err != nil {
+		logrus.WithError(err).WithField("path", previousVersionPath).Error("Rolling back the activation failed")
+		return errRollbackFailed
+	}
+	return commitErr
+}
 
+func (um *Client) runHooks(activator Activator, point hooks.Point, event hooks.Event) error {
+	event.Point = point
+	if point == hooks.PostActivate {
+		um.activation.set(event)
+	}
+	results, err := um.Hooks.Run(event)
+	reportHookResults(activator, point, results)
+	if err != nil {
+		logrus.WithError(err).WithField("point", point).Error("Version activation hook failed")
+	}
+	return err
+}
+
+// CheckWritable probes that files can be created in versions-root and in the directory of the
+// ui dist symlink, returns ErrReadOnlyFilesystem otherwise
+func (um *Client) CheckWritable() error {
+	return um.checkWritable()
+}
+
+func (um *Client) checkWritable() error {
+	um.probeLock.Lock()
+	defer um.probeLock.Unlock()
+	err := um.probeWritable()
+	health.DefaultTracker.Record(health.Filesystem, err)
+	return err
+}
+
+func (um *Client) probeWritable() error {
+	for _, dir := range []string{um.Config.VersionsRoot(), path.Dir(um.Config.UIDistSymlink())} {
+		probe, err := afero.TempFile(um.Fs, dir, ".write-probe-")
+		if err != nil {
+			logrus.WithError(err).WithField("directory", dir).Error("Writability probe failed")
+			return ErrReadOnlyFilesystem
+		}
+		probe.Close()
+		if err := um.Fs.Remove(probe.Name()); err != nil {
+			logrus.WithError(err).WithField("directory", dir).Error("Writability probe could not be removed")
+			return ErrReadOnlyFilesystem
+		}
+	}
 	return nil
 }
 
@@ -227,28 +506,36 @@ func (um *Client) RemoveAllVersionsExcept(omitVersion string) error {
 	dirContent, readErr := afero.ReadDir(um.Fs, root)
 	if readErr != nil {
 		// return all types of errors
-		logrus.WithError(readErr).Fatal("Unable to read versions-root.")
+		logrus.WithError(readErr).Error("Unable to read versions-root.")
 		return ErrReadingVersions
 	}
 
 	for _, info := range dirContent {
 		// The starting directory is included in Walk and should be skipped
-		if info.Name() == omitVersion {
+		if info.Name() == omitVersion || info.Name() == trashDirName || info.Name() == quarantineDirName {
 			continue
 		}
 
 		if info.IsDir() {
 			um.RemoveVersion(info.Name())
 		}
-
-		return nil
 	}
 
 	logrus.Info("Removed all versions")
 	return nil
 }
 
+// reservedName is true for the trash, the quarantine and the other dot-prefixed entries of versions-root,
+// they are never versions
+func reservedName(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
 func (um *Client) RemoveVersion(version string) error {
+	if version == "" || reservedName(version) {
+		logrus.WithField("version", version).Error("RemoveVersion failed, the name is reserved.")
+		return ErrRequestedVersionNotFound
+	}
 	versionPath := path.Join(um.Config.VersionsRoot(), version)
 	if exists, err := afero.DirExists(um.Fs, versionPath); err != nil || !exists {
 		if err != nil {
@@ -259,11 +546,30 @@ func (um *Client) RemoveVersion(version string) error {
 		return ErrRequestedVersionNotFound
 	}
 
+	// immutable versions are read-only and have to be made writable before removal
+	if err := um.chmodTree(versionPath, writableDirMode, writableFileMode); err != nil {
+		logrus.WithError(err).Warn("Could not make version writable before removal")
+	}
+
+	if um.trashEnabled() {
+		if err := um.trashVersion(version, versionPath); err != nil {
+			logrus.WithError(err).Error("Could not move version to the trash.")
+			return ErrRemovingVersion
+		}
+		logrus.Infof("Moved version v%s to the trash", version)
+		um.dropLabels(version)
+		if err := um.PurgeTrash(); err != nil {
+			logrus.WithError(err).Warn("Could not purge the trash")
+		}
+		return nil
+	}
+
 	err := um.Fs.RemoveAll(versionPath)
 	if err != nil {
 		logrus.WithError(err).Error("Could not remove version.")
 		return ErrRemovingVersion
 	}
 	logrus.Infof("Removed version v%s", version)
+	um.dropLabels(version)
 	return nil
 }
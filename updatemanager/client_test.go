@@ -1,19 +1,24 @@
 package updatemanager
 
 import (
+	"context"
 	"errors"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dcos/dcos-ui-update-service/config"
 	"github.com/dcos/dcos-ui-update-service/cosmos"
 	"github.com/dcos/dcos-ui-update-service/downloader"
+	"github.com/dcos/dcos-ui-update-service/hooks"
 	"github.com/dcos/dcos-ui-update-service/tests"
 	"github.com/spf13/afero"
 )
@@ -71,6 +76,13 @@ func setupServingVersion(t *testing.T) {
 
 func tearDown(t *testing.T) {
 	t.Log("Teardown testdata sandbox")
+	// immutable versions are read-only, make them writable again so they can be removed
+	filepath.Walk("../testdata/um-sandbox", func(p string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			os.Chmod(p, 0755)
+		}
+		return nil
+	})
 	os.RemoveAll("../testdata/um-sandbox")
 }
 
@@ -94,10 +106,10 @@ func TestClientCurrentVersion(t *testing.T) {
 		fs := afero.NewOsFs()
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 
 		ver, err := loader.CurrentVersion()
@@ -124,10 +136,10 @@ func TestClientCurrentVersion(t *testing.T) {
 		fs := afero.NewOsFs()
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 
 		_, err := loader.CurrentVersion()
@@ -153,10 +165,10 @@ func TestClientCurrentVersion(t *testing.T) {
 		fs := afero.NewOsFs()
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 
 		ver, err := loader.CurrentVersion()
@@ -186,10 +198,10 @@ func TestClientCurrentVersion(t *testing.T) {
 		fs := afero.NewOsFs()
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 
 		_, err := loader.CurrentVersion()
@@ -197,6 +209,36 @@ func TestClientCurrentVersion(t *testing.T) {
 		tests.H(t).StringContains(err.Error(), "Expected served version directory to be `dist` but got")
 	})
 
+	t.Run("returns the build version of index.html if non-default isn't serving dist folder", func(t *testing.T) {
+		os.MkdirAll("../testdata/um-sandbox/ui-versions/manual", 0755)
+		os.MkdirAll("../testdata/um-sandbox/dcos-ui", 0755)
+		ioutil.WriteFile(
+			"../testdata/um-sandbox/ui-versions/manual/index.html",
+			[]byte(`<script>window.DCOS_UI_VERSION = "2.24.4";</script>`),
+			0644,
+		)
+		os.Symlink("../testdata/um-sandbox/ui-versions/manual", "../testdata/um-sandbox/dcos-ui-dist")
+		defer tearDown(t)
+
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		fs := afero.NewOsFs()
+
+		loader := Client{
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
+		}
+
+		version, err := loader.CurrentVersion()
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(version, "2.24.4")
+	})
+
 	t.Run("returns version even if its not semver", func(t *testing.T) {
 		// Setup bad version
 		os.MkdirAll("../testdata/um-sandbox/ui-versions/not_semver/dist", 0755)
@@ -218,10 +260,10 @@ func TestClientCurrentVersion(t *testing.T) {
 		fs := afero.NewOsFs()
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 
 		version, err := loader.CurrentVersion()
@@ -249,10 +291,10 @@ func TestClientPathToCurrentVersion(t *testing.T) {
 		fs := afero.NewOsFs()
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 
 		result, err := loader.PathToCurrentVersion()
@@ -279,10 +321,10 @@ func TestClientPathToCurrentVersion(t *testing.T) {
 		fs := afero.NewOsFs()
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 
 		_, err := loader.PathToCurrentVersion()
@@ -299,16 +341,17 @@ func TestClientRemoveAllVersionsExcept(t *testing.T) {
 		fs := afero.NewMemMapFs()
 		cfg, _ := config.Parse([]string{
 			"--versions-root", "/ui-versions",
+			"--trash-retention", "0",
 		})
 
 		cosmosURL, _ := url.Parse(server.URL)
 		cosmos := cosmos.NewClient(cosmosURL)
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 
 		deletedVersionPath := "/ui-versions/nightly/dist"
@@ -339,16 +382,17 @@ func TestClientRemoveVersion(t *testing.T) {
 		fs := afero.NewMemMapFs()
 		cfg, _ := config.Parse([]string{
 			"--versions-root", "/ui-versions",
+			"--trash-retention", "0",
 		})
 
 		cosmosURL, _ := url.Parse(server.URL)
 		cosmos := cosmos.NewClient(cosmosURL)
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 		currentVersionPath := "/ui-versions/2.25.3/dist"
 		fs.MkdirAll(currentVersionPath, 0755)
@@ -374,10 +418,10 @@ func TestClientRemoveVersion(t *testing.T) {
 		cosmos := cosmos.NewClient(cosmosURL)
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 		currentVersionPath := "/ui-versions/2.25.3/dist"
 		fs.MkdirAll(currentVersionPath, 0755)
@@ -390,7 +434,7 @@ func TestClientRemoveVersion(t *testing.T) {
 func TestClientUpdateToVersion(t *testing.T) {
 
 	t.Run("creates update in new dir when no current version exists", func(t *testing.T) {
-		urlChan := make(chan string, 3) // because three requests will be made
+		urlChan := make(chan string, 4) // because four requests will be made, describe is also asked for the provenance
 		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 			baseURL := <-urlChan
 			path := req.URL.Path
@@ -399,10 +443,11 @@ func TestClientUpdateToVersion(t *testing.T) {
 			} else if path == "/package/describe" {
 				io.WriteString(rw, strings.Replace(defaultDescribeResponse, "https://frontend-elasticl-11uu7xp48vh9c-805473783.eu-central-1.elb.amazonaws.com", baseURL, -1))
 			} else {
-				http.ServeFile(rw, req, "../fixtures/release.tar.gz")
+				http.ServeFile(rw, req, "../fixtures/ui-release.tar.gz")
 			}
 		}))
-		// because three requests will be made
+		// because four requests will be made
+		urlChan <- server.URL
 		urlChan <- server.URL
 		urlChan <- server.URL
 		urlChan <- server.URL
@@ -423,13 +468,13 @@ func TestClientUpdateToVersion(t *testing.T) {
 		cosmos := cosmos.NewClient(cosmosURL)
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 
-		err := loader.UpdateToVersion("2.25.2", successfulUpdateCompleteCallback)
+		err := loader.UpdateToVersion("2.25.2", &fakeActivator{})
 
 		if err != nil {
 			t.Fatalf("Expected no error, got %#v", err)
@@ -487,13 +532,13 @@ func TestClientUpdateToVersion(t *testing.T) {
 		cosmos := cosmos.NewClient(cosmosURL)
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 
-		err := loader.UpdateToVersion("2.25.2", successfulUpdateCompleteCallback)
+		err := loader.UpdateToVersion("2.25.2", &fakeActivator{})
 
 		tests.H(t).ErrEql(err, downloader.ErrDowloadPackageFailed)
 	})
@@ -526,21 +571,127 @@ func TestClientUpdateToVersion(t *testing.T) {
 		cosmos := cosmos.NewClient(cosmosURL)
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 
-		loader.UpdateToVersion("2.25.2", successfulUpdateCompleteCallback)
+		loader.UpdateToVersion("2.25.2", &fakeActivator{})
 
 		newVersionExists, _ := afero.DirExists(fs, "/ui-versions/2.25.2")
 
 		tests.H(t).BoolEqlWithMessage(newVersionExists, false, "Expected new directoy to not exist")
 	})
 
+	t.Run("fetches the resolved bundle and removes the new version dir if fetching fails", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		fs := afero.NewOsFs()
+		loader, fetcher := newFakeFetcherClient(cfg, fs)
+		fetcher.DownloadError = downloader.ErrDowloadPackageFailed
+
+		err := loader.UpdateToVersion("2.25.2", &fakeActivator{})
+
+		tests.H(t).ErrEql(err, downloader.ErrDowloadPackageFailed)
+		tests.H(t).InterfaceEql(fetcher.Downloaded, []string{"https://downloads.example.com/dcos-ui/release.tar.gz"})
+		newVersionExists, _ := afero.DirExists(fs, path.Join(cfg.VersionsRoot(), "2.25.2"))
+		tests.H(t).BoolEqlWithMessage(newVersionExists, false, "Expected new directoy to be removed on failure")
+	})
+
+	t.Run("reports the update phases to the activator", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		loader, _ := newFakeFetcherClient(cfg, afero.NewOsFs())
+		var calls []string
+		activator := &progressActivator{fakeActivator{calls: &calls}}
+
+		err := loader.UpdateToVersion("2.25.2", activator)
+
+		tests.H(t).ErrEql(err, nil)
+		tests.H(t).InterfaceEql(calls, []string{PhaseDownloading, PhaseUnpacking, PhaseSwapping, "prepare", "commit"})
+	})
+
+	t.Run("reports the download progress to the activator", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		loader, fetcher := newFakeFetcherClient(cfg, afero.NewOsFs())
+		fetcher.Progress = []downloader.Progress{{Downloaded: 0, Total: 200}, {Downloaded: 200, Total: 200}}
+		activator := &downloadProgressActivator{}
+
+		err := loader.UpdateToVersion("2.25.2", activator)
+
+		tests.H(t).ErrEql(err, nil)
+		tests.H(t).InterfaceEql(activator.progress, fetcher.Progress)
+	})
+
+	t.Run("stops a cancelled update before the activation and removes the new version dir", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		fs := afero.NewOsFs()
+		loader, fetcher := newFakeFetcherClient(cfg, fs)
+		var calls []string
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		activator := &cancellableActivator{fakeActivator: fakeActivator{calls: &calls}, ctx: ctx}
+
+		err := loader.UpdateToVersion("2.25.2", activator)
+
+		tests.H(t).ErrEql(err, ErrUpdateCancelled)
+		tests.H(t).IntEql(len(fetcher.Downloaded), 1)
+		tests.H(t).IntEql(len(calls), 0)
+		newVersionExists, _ := afero.DirExists(fs, path.Join(cfg.VersionsRoot(), "2.25.2"))
+		tests.H(t).BoolEqlWithMessage(newVersionExists, false, "Expected new directoy to be removed on cancellation")
+	})
+
+	t.Run("reports a download aborted by the cancellation as cancelled", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		fs := afero.NewOsFs()
+		loader, fetcher := newFakeFetcherClient(cfg, fs)
+		fetcher.DownloadError = context.Canceled
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := loader.UpdateToVersion("2.25.2", &cancellableActivator{ctx: ctx})
+
+		tests.H(t).ErrEql(err, ErrUpdateCancelled)
+		newVersionExists, _ := afero.DirExists(fs, path.Join(cfg.VersionsRoot(), "2.25.2"))
+		tests.H(t).BoolEqlWithMessage(newVersionExists, false, "Expected new directoy to be removed on cancellation")
+	})
+
 	t.Run("creates update in new directory and returns no error", func(t *testing.T) {
-		urlChan := make(chan string, 3) // because three requests will be made
+		urlChan := make(chan string, 4) // because four requests will be made, describe is also asked for the provenance
 		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 			baseURL := <-urlChan
 			path := req.URL.Path
@@ -549,10 +700,11 @@ func TestClientUpdateToVersion(t *testing.T) {
 			} else if path == "/package/describe" {
 				io.WriteString(rw, strings.Replace(defaultDescribeResponse, "https://frontend-elasticl-11uu7xp48vh9c-805473783.eu-central-1.elb.amazonaws.com", baseURL, -1))
 			} else {
-				http.ServeFile(rw, req, "../fixtures/release.tar.gz")
+				http.ServeFile(rw, req, "../fixtures/ui-release.tar.gz")
 			}
 		}))
-		// because three requests will be made
+		// because four requests will be made
+		urlChan <- server.URL
 		urlChan <- server.URL
 		urlChan <- server.URL
 		urlChan <- server.URL
@@ -573,13 +725,13 @@ func TestClientUpdateToVersion(t *testing.T) {
 		cosmos := cosmos.NewClient(cosmosURL)
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 
-		err := loader.UpdateToVersion("2.25.2", successfulUpdateCompleteCallback)
+		err := loader.UpdateToVersion("2.25.2", &fakeActivator{})
 
 		tests.H(t).ErrEql(err, nil)
 
@@ -618,34 +770,112 @@ func TestClientUpdateToVersion(t *testing.T) {
 		cosmos := cosmos.NewClient(cosmosURL)
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos,
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
 		}
 
-		err := loader.UpdateToVersion("2.25.1", successfulUpdateCompleteCallback)
+		err := loader.UpdateToVersion("2.25.1", &fakeActivator{})
 
 		tests.H(t).ErrEql(err, nil)
 	})
 
-	t.Run("returns error if complete callback returns an error", func(t *testing.T) {
-		urlChan := make(chan string, 3) // because three requests will be made
+	t.Run("rolls back and removes the new version if the commit fails", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		fs := afero.NewOsFs()
+
+		loader, _ := newFakeFetcherClient(cfg, fs)
+		commitErr := errors.New("error completing update")
+		var calls []string
+		activator := &fakeActivator{CommitError: commitErr, calls: &calls}
+		err := loader.UpdateToVersion("2.25.2", activator)
+
+		tests.H(t).ErrEql(err, commitErr)
+		tests.H(t).InterfaceEql(calls, []string{"prepare", "commit", "rollback"})
+		tests.H(t).StringEql(activator.RollbackPath, path.Join(cfg.VersionsRoot(), "2.25.1", "dist"))
+
+		newVersionExists, _ := afero.DirExists(fs, path.Join(cfg.VersionsRoot(), "/2.25.2"))
+		oldVersionExists, _ := afero.DirExists(fs, path.Join(cfg.VersionsRoot(), "/2.25.1"))
+
+		tests.H(t).BoolEqlWithMessage(newVersionExists, false, "Expected new directoy to be removed on failure")
+		tests.H(t).BoolEqlWithMessage(oldVersionExists, true, "Expected old directoy to not be removed")
+	})
+
+	t.Run("rejects bundles without a ui distribution before activating them", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			files map[string]string
+		}{
+			{"without dist/index.html", map[string]string{"index.js": "module.exports = {}"}},
+			{"without DCOS_UI_VERSION", map[string]string{"dist/index.html": "<html></html>"}},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				defer tearDown(t)
+				setupServingSpecificVersion(t, "2.25.1")
+
+				cfg, _ := config.Parse([]string{
+					"--versions-root", "../testdata/um-sandbox/ui-versions",
+					"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+					"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+				})
+				fs := afero.NewOsFs()
+
+				loader, fetcher := newFakeFetcherClient(cfg, fs)
+				fetcher.Files = tc.files
+				var calls []string
+				err := loader.UpdateToVersion("2.25.2", &fakeActivator{calls: &calls})
+
+				tests.H(t).ErrEql(err, ErrInvalidBundleLayout)
+				tests.H(t).IntEql(len(calls), 0)
+				newVersionExists, _ := afero.DirExists(fs, path.Join(cfg.VersionsRoot(), "2.25.2"))
+				tests.H(t).BoolEqlWithMessage(newVersionExists, false, "Expected the invalid version to be removed")
+			})
+		}
+	})
+
+	t.Run("keeps the new version if the rollback fails", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		fs := afero.NewOsFs()
+
+		loader, _ := newFakeFetcherClient(cfg, fs)
+		commitErr := errors.New("error completing update")
+		var calls []string
+		activator := &fakeActivator{CommitError: commitErr, RollbackError: errors.New("symlink failed"), calls: &calls}
+		err := loader.UpdateToVersion("2.25.2", activator)
+
+		tests.H(t).ErrEql(err, errRollbackFailed)
+		tests.H(t).InterfaceEql(calls, []string{"prepare", "commit", "rollback"})
+
+		newVersionExists, _ := afero.DirExists(fs, path.Join(cfg.VersionsRoot(), "/2.25.2"))
+		oldVersionExists, _ := afero.DirExists(fs, path.Join(cfg.VersionsRoot(), "/2.25.1"))
+
+		tests.H(t).BoolEqlWithMessage(newVersionExists, true, "Expected new directoy to be kept as it may still be served")
+		tests.H(t).BoolEqlWithMessage(oldVersionExists, true, "Expected old directoy to not be removed")
+	})
+}
+
+func TestClientUpdateToVersionHooks(t *testing.T) {
+	t.Run("aborts update and removes new version dir if a pre-download hook fails", func(t *testing.T) {
+		cosmosCalled := false
 		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
-			baseURL := <-urlChan
-			path := req.URL.Path
-			if path == "/package/list-versions" {
-				io.WriteString(rw, defaultListResponse)
-			} else if path == "/package/describe" {
-				io.WriteString(rw, strings.Replace(defaultDescribeResponse, "https://frontend-elasticl-11uu7xp48vh9c-805473783.eu-central-1.elb.amazonaws.com", baseURL, -1))
-			} else {
-				http.ServeFile(rw, req, "fixtures/release.tar.gz")
-			}
+			cosmosCalled = true
 		}))
-		// because three requests will be made
-		urlChan <- server.URL
-		urlChan <- server.URL
-		urlChan <- server.URL
 		// Close the server when test finishes
 		defer server.Close()
 
@@ -660,30 +890,235 @@ func TestClientUpdateToVersion(t *testing.T) {
 		fs := afero.NewOsFs()
 
 		cosmosURL, _ := url.Parse(server.URL)
-		cosmos := cosmos.NewClient(cosmosURL)
+		registry := hooks.NewRegistry(time.Second)
+		registry.Register(hooks.PreDownload, hooks.HookFunc{
+			HookName: "deny",
+			Func: func(ctx context.Context, e hooks.Event) error {
+				return errors.New("downloads are frozen")
+			},
+		})
 
 		loader := Client{
-			Cosmos: cosmos,
-			Loader: downloader.New(fs),
-			Config: cfg,
-			Fs:     fs,
+			Source:  cosmos.NewClient(cosmosURL),
+			Fetcher: downloader.New(fs),
+			Config:  cfg,
+			Fs:      fs,
+			Hooks:   registry,
 		}
-		err := loader.UpdateToVersion("2.25.2", unsuccessfulUpdateCompleteCallback)
-
-		tests.H(t).ErrEql(err, downloader.ErrDowloadPackageFailed)
+		err := loader.UpdateToVersion("2.25.2", &fakeActivator{})
 
+		tests.H(t).NotNil(err)
+		tests.H(t).StringContains(err.Error(), "downloads are frozen")
+		tests.H(t).BoolEql(cosmosCalled, false)
 		newVersionExists, _ := afero.DirExists(fs, path.Join(cfg.VersionsRoot(), "/2.25.2"))
-		oldVersionExists, _ := afero.DirExists(fs, path.Join(cfg.VersionsRoot(), "/2.25.1"))
-
 		tests.H(t).BoolEqlWithMessage(newVersionExists, false, "Expected new directoy to be removed on failure")
-		tests.H(t).BoolEqlWithMessage(oldVersionExists, true, "Expected old directoy to not be removed")
 	})
+
+	t.Run("runs pre-activate and post-activate hooks around the complete callback", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		fs := afero.NewOsFs()
+
+		var calls []string
+		recordHook := func(name string) hooks.HookFunc {
+			return hooks.HookFunc{
+				HookName: name,
+				Func: func(ctx context.Context, e hooks.Event) error {
+					calls = append(calls, name+":"+e.PreviousVersion+"->"+e.Version)
+					return nil
+				},
+			}
+		}
+		registry := hooks.NewRegistry(time.Second)
+		registry.Register(hooks.PreActivate, recordHook("pre-activate"))
+		registry.Register(hooks.PostActivate, recordHook("post-activate"))
+
+		loader, _ := newFakeFetcherClient(cfg, fs)
+		loader.Hooks = registry
+		err := loader.UpdateToVersion("2.25.2", &fakeActivator{calls: &calls})
+
+		tests.H(t).ErrEql(err, nil)
+		tests.H(t).InterfaceEql(calls, []string{"pre-activate:2.25.1->2.25.2", "prepare", "commit", "post-activate:2.25.1->2.25.2"})
+	})
+
+	t.Run("reports a failed post-activate hook without failing the update", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		registry := hooks.NewRegistry(time.Second)
+		registry.Register(hooks.PostActivate, hooks.HookFunc{
+			HookName: "purge",
+			Func: func(ctx context.Context, e hooks.Event) error {
+				return errors.New("proxy unreachable")
+			},
+		})
+		loader, _ := newFakeFetcherClient(cfg, afero.NewOsFs())
+		loader.Hooks = registry
+		activator := &hookActivator{}
+
+		tests.H(t).IsNil(loader.UpdateToVersion("2.25.2", activator))
+
+		results := activator.results[hooks.PostActivate]
+		tests.H(t).IntEql(len(results), 1)
+		tests.H(t).StringEql(results[0].Name, "purge")
+		tests.H(t).StringContains(results[0].Error, "proxy unreachable")
+	})
+}
+
+// newFakeFetcherClient creates a Client offering 2.25.1 and 2.25.2 from fakes, the fetched bundle
+// is written to fs without network access
+func newFakeFetcherClient(cfg *config.Config, fs afero.Fs) (*Client, *FakeBundleFetcher) {
+	bundleURL, _ := url.Parse("https://downloads.example.com/dcos-ui/release.tar.gz")
+	fetcher := &FakeBundleFetcher{
+		Fs:    fs,
+		Files: map[string]string{"dist/index.html": `<html><script>window.DCOS_UI_VERSION = "2.25.2";</script></html>`},
+	}
+	return &Client{
+		Source:  &FakePackageSource{Versions: []string{"2.25.1", "2.25.2"}, BundleURL: bundleURL},
+		Fetcher: fetcher,
+		Config:  cfg,
+		Fs:      fs,
+	}, fetcher
+}
+
+// progressActivator records the reported phases in addition to the activation phases
+type progressActivator struct {
+	fakeActivator
+}
+
+func (a *progressActivator) ReportProgress(phase string) {
+	a.record(phase)
+}
+
+// hookActivator records the reported hook results by hook point
+type hookActivator struct {
+	fakeActivator
+	results map[hooks.Point][]hooks.Result
+}
+
+func (a *hookActivator) ReportHookResults(point hooks.Point, results []hooks.Result) {
+	if a.results == nil {
+		a.results = map[hooks.Point][]hooks.Result{}
+	}
+	a.results[point] = results
+}
+
+// downloadProgressActivator records the reported download progress
+type downloadProgressActivator struct {
+	fakeActivator
+	progress []downloader.Progress
+}
+
+func (a *downloadProgressActivator) ReportDownloadProgress(progress downloader.Progress) {
+	a.progress = append(a.progress, progress)
+}
+
+// cancellableActivator stops the update once ctx is done
+type cancellableActivator struct {
+	fakeActivator
+	ctx context.Context
+}
+
+func (a *cancellableActivator) Context() context.Context {
+	return a.ctx
+}
+
+// fakeActivator records the phases UpdateToVersion runs
+type fakeActivator struct {
+	PrepareError  error
+	CommitError   error
+	RollbackError error
+	RollbackPath  string
+	calls         *[]string
+}
+
+func (a *fakeActivator) record(phase string) {
+	if a.calls != nil {
+		*a.calls = append(*a.calls, phase)
+	}
+}
+
+func (a *fakeActivator) Prepare(newVersionPath string) error {
+	a.record("prepare")
+	return a.PrepareError
 }
 
-func successfulUpdateCompleteCallback(s string) error {
-	return nil
+func (a *fakeActivator) Commit(newVersionPath string) error {
+	a.record("commit")
+	return a.CommitError
 }
 
-func unsuccessfulUpdateCompleteCallback(s string) error {
-	return errors.New("error completing update")
+func (a *fakeActivator) Rollback(previousVersionPath string) error {
+	a.record("rollback")
+	a.RollbackPath = previousVersionPath
+	return a.RollbackError
+}
+
+func TestClientCheckWritable(t *testing.T) {
+	t.Run("returns nil if directories are writable", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		fs.MkdirAll("/ui-versions", 0755)
+		fs.MkdirAll("/opt/mesosphere/active", 0755)
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "/ui-versions",
+			"--ui-dist-symlink", "/opt/mesosphere/active/dcos-ui-dist",
+		})
+		loader := Client{Config: cfg, Fs: fs}
+
+		tests.H(t).ErrEql(loader.CheckWritable(), nil)
+
+		files, _ := afero.ReadDir(fs, "/ui-versions")
+		tests.H(t).IntEql(len(files), 0)
+	})
+
+	t.Run("returns ErrReadOnlyFilesystem if versions-root is read-only", func(t *testing.T) {
+		base := afero.NewMemMapFs()
+		base.MkdirAll("/ui-versions", 0755)
+		base.MkdirAll("/opt/mesosphere/active", 0755)
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "/ui-versions",
+			"--ui-dist-symlink", "/opt/mesosphere/active/dcos-ui-dist",
+		})
+		loader := Client{Config: cfg, Fs: afero.NewReadOnlyFs(base)}
+
+		tests.H(t).ErrEql(loader.CheckWritable(), ErrReadOnlyFilesystem)
+	})
+
+	t.Run("does not wait for a running download", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		loader, fetcher := newFakeFetcherClient(cfg, afero.NewOsFs())
+		fetcher.Downloading = make(chan struct{})
+		fetcher.Release = make(chan struct{})
+		updated := make(chan error, 1)
+		go func() { updated <- loader.UpdateToVersion("2.25.2", &fakeActivator{}) }()
+		<-fetcher.Downloading
+
+		checked := make(chan error, 1)
+		go func() { checked <- loader.CheckWritable() }()
+		select {
+		case err := <-checked:
+			tests.H(t).ErrEql(err, nil)
+		case <-time.After(5 * time.Second):
+			t.Fatal("CheckWritable waited for the running download")
+		}
+		close(fetcher.Release)
+		tests.H(t).ErrEql(<-updated, nil)
+	})
 }
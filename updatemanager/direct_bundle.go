@@ -0,0 +1,36 @@
+package updatemanager
+
+import (
+	"net/url"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// directBundles are the bundle URLs of versions installed from a direct URL or a local file instead of
+// the package source
+type directBundles struct {
+	urls map[string]*url.URL
+	sync.Mutex
+}
+
+// UpdateFromBundle downloads the version from bundleURL instead of the package source and activates it
+// with the activator. The bundle URL is kept, so the version can be activated again, e.g. by a rollback.
+func (um *Client) UpdateFromBundle(version string, bundleURL *url.URL, activator Activator) error {
+	um.direct.Lock()
+	if um.direct.urls == nil {
+		um.direct.urls = make(map[string]*url.URL)
+	}
+	um.direct.urls[version] = bundleURL
+	um.direct.Unlock()
+	logrus.WithFields(logrus.Fields{"version": version, "url": bundleURL}).Info("Updating from a direct bundle")
+	return um.UpdateToVersion(version, activator)
+}
+
+// directBundle returns the bundle URL the version was installed from by UpdateFromBundle
+func (um *Client) directBundle(version string) (*url.URL, bool) {
+	um.direct.Lock()
+	defer um.direct.Unlock()
+	bundleURL, ok := um.direct.urls[version]
+	return bundleURL, ok
+}
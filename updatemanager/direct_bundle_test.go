@@ -0,0 +1,37 @@
+package updatemanager
+
+import (
+	"net/url"
+	"path"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/spf13/afero"
+)
+
+func TestClientUpdateFromBundle(t *testing.T) {
+	t.Run("downloads a version unknown to the package source from the bundle url", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		fs := afero.NewOsFs()
+		loader, fetcher := newFakeFetcherClient(cfg, fs)
+		bundleURL, _ := url.Parse("https://mirror.example.com/acme-ui.tar.gz")
+
+		err := loader.UpdateFromBundle("direct-0123456789ab", bundleURL, &fakeActivator{})
+
+		tests.H(t).IsNil(err)
+		tests.H(t).InterfaceEql(fetcher.Downloaded, []string{"https://mirror.example.com/acme-ui.tar.gz"})
+		exists, _ := afero.DirExists(fs, path.Join(cfg.VersionsRoot(), "direct-0123456789ab"))
+		tests.H(t).BoolEql(exists, true)
+		resolved, err := loader.resolveBundleURL("direct-0123456789ab")
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(resolved.String(), "https://mirror.example.com/acme-ui.tar.gz")
+	})
+}
@@ -0,0 +1,103 @@
+package updatemanager
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/dcos/dcos-ui-update-service/downloader"
+	"github.com/spf13/afero"
+)
+
+// FakeBundleFetcher is a BundleFetcher test double writing Files instead of unpacking a bundle
+type FakeBundleFetcher struct {
+	// Fs receives the Files on download, nothing is written if nil
+	Fs afero.Fs
+	// Files maps paths relative to the target directory to their content
+	Files map[string]string
+	// Progress is reported to the progress callback of the context before the Files are written
+	Progress []downloader.Progress
+	// Digest is recorded to the digest recorder of the context after the Files are written, if set
+	Digest string
+	// Archive is written to the archive copy of the context
+	Archive       string
+	DownloadError error
+	// HeadStatus is returned by Head, http.StatusOK if zero
+	HeadStatus int
+	HeadSize   int64
+	HeadError  error
+	Downloaded []string
+	Headed     []string
+	// Fetched maps URLs to the content returned by Fetch, unknown URLs are not found
+	Fetched    map[string]string
+	FetchError error
+	FetchCalls []string
+	// Downloading is closed once a download started, the download then waits for Release if set
+	Downloading chan struct{}
+	Release     chan struct{}
+}
+
+// DownloadAndUnpack records the URL and writes the Files to targetDirectory or returns DownloadError
+func (f *FakeBundleFetcher) DownloadAndUnpack(ctx context.Context, bundleURL *url.URL, targetDirectory string) error {
+	f.Downloaded = append(f.Downloaded, bundleURL.String())
+	if f.Downloading != nil {
+		close(f.Downloading)
+	}
+	if f.Release != nil {
+		<-f.Release
+	}
+	if f.DownloadError != nil {
+		return f.DownloadError
+	}
+	if err := downloader.VerifyPackage(ctx, strings.NewReader(f.Archive)); err != nil {
+		return err
+	}
+	downloader.NotifyUnpacking(ctx)
+	for _, progress := range f.Progress {
+		downloader.ReportProgress(ctx, progress)
+	}
+	if f.Fs != nil {
+		for name, content := range f.Files {
+			filePath := path.Join(targetDirectory, name)
+			if err := f.Fs.MkdirAll(path.Dir(filePath), 0755); err != nil {
+				return err
+			}
+			if err := afero.WriteFile(f.Fs, filePath, []byte(content), 0644); err != nil {
+				return err
+			}
+		}
+	}
+	io.WriteString(downloader.ArchiveCopy(ctx), f.Archive)
+	if f.Digest != "" {
+		downloader.RecordDigest(ctx, f.Digest)
+	}
+	return nil
+}
+
+// Head records the URL and returns HeadStatus and HeadSize or HeadError
+func (f *FakeBundleFetcher) Head(ctx context.Context, bundleURL *url.URL) (int, int64, error) {
+	f.Headed = append(f.Headed, bundleURL.String())
+	if f.HeadError != nil {
+		return 0, -1, f.HeadError
+	}
+	if f.HeadStatus == 0 {
+		return http.StatusOK, f.HeadSize, nil
+	}
+	return f.HeadStatus, f.HeadSize, nil
+}
+
+// Fetch records the URL and returns its entry of Fetched or FetchError
+func (f *FakeBundleFetcher) Fetch(ctx context.Context, fileURL *url.URL) ([]byte, error) {
+	f.FetchCalls = append(f.FetchCalls, fileURL.String())
+	if f.FetchError != nil {
+		return nil, f.FetchError
+	}
+	content, found := f.Fetched[fileURL.String()]
+	if !found {
+		return nil, downloader.ErrDowloadPackageFailed
+	}
+	return []byte(content), nil
+}
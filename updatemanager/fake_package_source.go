@@ -0,0 +1,71 @@
+package updatemanager
+
+import (
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// FakePackageSource is a PackageSource test double
+type FakePackageSource struct {
+	Versions      []string
+	ListError     error
+	BundleURL     *url.URL
+	ResolveError  error
+	ResolveCalled []string
+	MinDcos       map[string]string
+	DescribeCalls int
+	// Scm and Releases are returned by PackageOrigin
+	Scm      string
+	Releases map[string]int
+	// Packages are the versions by package name, if set they replace Versions and unknown packages fail
+	Packages map[string][]string
+	// ResolvedPackages are the package names passed to ResolveBundle
+	ResolvedPackages []string
+	// Signature is returned by ResolveSignature, the signature is not found if nil
+	Signature *url.URL
+}
+
+// ListVersions returns the Versions or ListError
+func (s *FakePackageSource) ListVersions(packageName string) ([]string, error) {
+	if s.ListError != nil {
+		return nil, s.ListError
+	}
+	if s.Packages != nil {
+		versions, ok := s.Packages[packageName]
+		if !ok {
+			return nil, errors.Errorf("package %s not found", packageName)
+		}
+		return versions, nil
+	}
+	return s.Versions, nil
+}
+
+// ResolveBundle records the call and returns BundleURL or ResolveError
+func (s *FakePackageSource) ResolveBundle(packageName string, version string) (*url.URL, error) {
+	s.ResolveCalled = append(s.ResolveCalled, version)
+	s.ResolvedPackages = append(s.ResolvedPackages, packageName)
+	if s.ResolveError != nil {
+		return nil, s.ResolveError
+	}
+	return s.BundleURL, nil
+}
+
+// MinDcosReleaseVersion records the call and returns the entry of MinDcos for version
+func (s *FakePackageSource) MinDcosReleaseVersion(packageName string, version string) (string, error) {
+	s.DescribeCalls++
+	return s.MinDcos[version], nil
+}
+
+// PackageOrigin returns Scm and the entry of Releases for version
+func (s *FakePackageSource) PackageOrigin(packageName string, version string) (string, int, error) {
+	return s.Scm, s.Releases[version], nil
+}
+
+// ResolveSignature returns Signature
+func (s *FakePackageSource) ResolveSignature(packageName string, version string) (*url.URL, error) {
+	if s.Signature == nil {
+		return nil, errors.Errorf("no signature of %s %s", packageName, version)
+	}
+	return s.Signature, nil
+}
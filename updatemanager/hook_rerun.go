@@ -0,0 +1,71 @@
+package updatemanager
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dcos/dcos-ui-update-service/hooks"
+	"github.com/sirupsen/logrus"
+)
+
+// HookRerun is the outcome of running the post-activate hooks of the served version again
+type HookRerun struct {
+	Version         string         `json:"version"`
+	PreviousVersion string         `json:"previousVersion"`
+	Path            string         `json:"path"`
+	Results         []hooks.Result `json:"results"`
+}
+
+// lastActivation keeps the post-activate event of the last activation, so a rerun passes the hooks the
+// same previous version
+type lastActivation struct {
+	event *hooks.Event
+	sync.Mutex
+}
+
+func (a *lastActivation) set(event hooks.Event) {
+	a.Lock()
+	defer a.Unlock()
+	a.event = &event
+}
+
+// previousVersion returns the version served before version was activated, "" if it is unknown
+func (a *lastActivation) previousVersion(version string) string {
+	a.Lock()
+	defer a.Unlock()
+	if a.event == nil || a.event.Version != version {
+		return ""
+	}
+	return a.event.PreviousVersion
+}
+
+// RerunPostActivateHooks runs the post-activate hooks for the served version again, e.g. after a cache purge
+// failed following an otherwise successful update. Every hook is run, the error is the first failure.
+// The hooks are cancelled when ctx is done.
+func (um *Client) RerunPostActivateHooks(ctx context.Context) (*HookRerun, error) {
+	version, err := um.CurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+	servedPath, err := um.PathToCurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+	event := hooks.Event{
+		Point:           hooks.PostActivate,
+		Version:         version,
+		PreviousVersion: um.activation.previousVersion(version),
+		Path:            servedPath,
+	}
+	logrus.WithField("version", version).Info("Running the post-activate hooks again")
+	results, err := um.Hooks.RunContext(ctx, event)
+	if err != nil {
+		logrus.WithError(err).WithField("version", version).Error("Post-activate hook failed again")
+	}
+	return &HookRerun{
+		Version:         version,
+		PreviousVersion: event.PreviousVersion,
+		Path:            servedPath,
+		Results:         results,
+	}, err
+}
@@ -0,0 +1,67 @@
+package updatemanager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/hooks"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/spf13/afero"
+)
+
+func TestClientRerunPostActivateHooks(t *testing.T) {
+	setup := func(t *testing.T, purgeErr error) (*Client, *[]string) {
+		setupServingSpecificVersion(t, "2.25.1")
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		var calls []string
+		registry := hooks.NewRegistry(time.Second)
+		registry.Register(hooks.PostActivate, hooks.HookFunc{
+			HookName: "purge-cache",
+			Func: func(ctx context.Context, e hooks.Event) error {
+				calls = append(calls, e.PreviousVersion+"->"+e.Version)
+				return purgeErr
+			},
+		})
+		loader, _ := newFakeFetcherClient(cfg, afero.NewOsFs())
+		loader.Hooks = registry
+		return loader, &calls
+	}
+
+	t.Run("runs the post-activate hooks for the served version again", func(t *testing.T) {
+		defer tearDown(t)
+		loader, calls := setup(t, nil)
+		tests.H(t).IsNil(loader.UpdateToVersion("2.25.2", &fakeActivator{}))
+		// the fake activator does not switch the symlink
+		os.Remove("../testdata/um-sandbox/dcos-ui-dist")
+		setupServingSpecificVersion(t, "2.25.2")
+
+		rerun, err := loader.RerunPostActivateHooks(context.Background())
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(rerun.Version, "2.25.2")
+		tests.H(t).StringEql(rerun.PreviousVersion, "2.25.1")
+		tests.H(t).IntEql(len(rerun.Results), 1)
+		tests.H(t).StringEql(rerun.Results[0].Name, "purge-cache")
+		tests.H(t).InterfaceEql(*calls, []string{"2.25.1->2.25.2", "2.25.1->2.25.2"})
+	})
+
+	t.Run("reports the failed hooks", func(t *testing.T) {
+		defer tearDown(t)
+		loader, _ := setup(t, errors.New("purge failed"))
+
+		rerun, err := loader.RerunPostActivateHooks(context.Background())
+
+		tests.H(t).NotNil(err)
+		tests.H(t).StringEql(rerun.Version, "2.25.1")
+		tests.H(t).StringEql(rerun.PreviousVersion, "")
+		tests.H(t).StringContains(rerun.Results[0].Error, "purge failed")
+	})
+}
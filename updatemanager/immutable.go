@@ -0,0 +1,248 @@
+package updatemanager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+const (
+	manifestFileName = "manifest.json"
+	readOnlyFileMode = 0444
+	readOnlyDirMode  = 0555
+	writableFileMode = 0644
+	writableDirMode  = 0755
+)
+
+var (
+	// ErrVersionCorrupted occurs if files of an immutable version changed after it was activated
+	ErrVersionCorrupted = errors.New("Version files changed after activation")
+	// ErrVersionNotImmutable occurs if a version has no manifest marking it immutable
+	ErrVersionNotImmutable = errors.New("Version is not marked immutable")
+)
+
+type manifestFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	// SHA256 is the hex digest of the content, empty in manifests of older releases
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+type versionManifest struct {
+	Version     string                  `json:"version"`
+	Immutable   bool                    `json:"immutable"`
+	ActivatedAt time.Time               `json:"activatedAt"`
+	Files       map[string]manifestFile `json:"files"`
+}
+
+// markImmutable records the size and mtime of all files of the version in its manifest
+// and makes the version directory tree read-only
+func (um *Client) markImmutable(version string) error {
+	versionPath := path.Join(um.Config.VersionsRoot(), version)
+	manifest := versionManifest{
+		Version:     version,
+		Immutable:   true,
+		ActivatedAt: time.Now().UTC(),
+	}
+	files, err := um.snapshotFiles(path.Join(versionPath, "dist"))
+	if err != nil {
+		return errors.Wrap(err, "could not read version files")
+	}
+	for name, file := range files {
+		digest, err := um.fileDigest(path.Join(versionPath, "dist", name))
+		if err != nil {
+			return errors.Wrap(err, "could not hash version files")
+		}
+		file.SHA256 = digest
+		files[name] = file
+	}
+	manifest.Files = files
+	um.contentChecks.reset(version)
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := afero.WriteFile(um.Fs, path.Join(versionPath, manifestFileName), data, readOnlyFileMode); err != nil {
+		return errors.Wrap(err, "could not write version manifest")
+	}
+	return um.chmodTree(versionPath, readOnlyDirMode, readOnlyFileMode)
+}
+
+// contentChecks keeps the position of the rotating sample hashed by VerifyVersion and the versions whose
+// content changed, so a version stays corrupted even if the next sample misses the changed file
+type contentChecks struct {
+	offset    int
+	corrupted map[string]bool
+	sync.Mutex
+}
+
+// sample returns the next count names, continuing after the sample of the previous call
+func (c *contentChecks) sample(names []string, count int) []string {
+	c.Lock()
+	defer c.Unlock()
+	if count >= len(names) {
+		return names
+	}
+	start := c.offset % len(names)
+	c.offset = start + count
+	sample := make([]string, 0, count)
+	for i := start; i < start+count; i++ {
+		sample = append(sample, names[i%len(names)])
+	}
+	return sample
+}
+
+func (c *contentChecks) markCorrupted(version string) {
+	c.Lock()
+	defer c.Unlock()
+	if c.corrupted == nil {
+		c.corrupted = map[string]bool{}
+	}
+	c.corrupted[version] = true
+}
+
+func (c *contentChecks) isCorrupted(version string) bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.corrupted[version]
+}
+
+// reset forgets that version was corrupted once its files were written again
+func (c *contentChecks) reset(version string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.corrupted, version)
+}
+
+// VerifyVersion compares the files of an immutable version with its manifest and hashes a sample of
+// verify-sample-size files, returns ErrVersionCorrupted if any file was added, removed or modified
+func (um *Client) VerifyVersion(version string) error {
+	versionPath := path.Join(um.Config.VersionsRoot(), version)
+	data, err := afero.ReadFile(um.Fs, path.Join(versionPath, manifestFileName))
+	if err != nil {
+		return ErrVersionNotImmutable
+	}
+	var manifest versionManifest
+	if err := json.Unmarshal(data, &manifest); err != nil || !manifest.Immutable {
+		return ErrVersionNotImmutable
+	}
+
+	files, err := um.snapshotFiles(path.Join(versionPath, "dist"))
+	if err != nil {
+		logrus.WithError(err).WithField("version", version).Warn("Could not read version files")
+		return ErrVersionCorrupted
+	}
+	if len(files) != len(manifest.Files) {
+		return ErrVersionCorrupted
+	}
+	for name, recorded := range manifest.Files {
+		current, ok := files[name]
+		if !ok || current.Size != recorded.Size || !current.ModTime.Equal(recorded.ModTime) {
+			logrus.WithFields(logrus.Fields{"version": version, "file": name}).Warn("Immutable version file changed")
+			return ErrVersionCorrupted
+		}
+	}
+	return um.verifyContentSample(version, versionPath, manifest)
+}
+
+// verifyContentSample hashes the next sample of the files with a recorded digest, so files edited while
+// keeping their size and mtime are detected once the rotating sample reaches them
+func (um *Client) verifyContentSample(version, versionPath string, manifest versionManifest) error {
+	if um.contentChecks.isCorrupted(version) {
+		return ErrVersionCorrupted
+	}
+	count := um.Config.VerifySampleSize()
+	if count <= 0 {
+		return nil
+	}
+	var names []string
+	for name, file := range manifest.Files {
+		if file.SHA256 != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	for _, name := range um.contentChecks.sample(names, count) {
+		digest, err := um.fileDigest(path.Join(versionPath, "dist", name))
+		if err != nil || digest != manifest.Files[name].SHA256 {
+			logrus.WithFields(logrus.Fields{"version": version, "file": name}).Warn("Immutable version file content changed")
+			um.contentChecks.markCorrupted(version)
+			return ErrVersionCorrupted
+		}
+	}
+	return nil
+}
+
+// fileDigest returns the hex SHA-256 digest of the content of the file
+func (um *Client) fileDigest(name string) (string, error) {
+	f, err := um.Fs.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (um *Client) snapshotFiles(root string) (map[string]manifestFile, error) {
+	files := map[string]manifestFile{}
+	err := afero.Walk(um.Fs, root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = manifestFile{Size: info.Size(), ModTime: info.ModTime().UTC()}
+		return nil
+	})
+	return files, err
+}
+
+// chmodTree applies the modes to the tree below root, directories are made writable before
+// descending so the tree can be made writable again after it was read-only
+func (um *Client) chmodTree(root string, dirMode, fileMode os.FileMode) error {
+	var dirs []string
+	err := afero.Walk(um.Fs, root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// keep traversing, the final directory modes are applied afterwards
+			dirs = append(dirs, p)
+			return um.Fs.Chmod(p, writableDirMode)
+		}
+		return um.Fs.Chmod(p, fileMode)
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := um.Fs.Chmod(dirs[i], dirMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
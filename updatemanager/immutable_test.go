@@ -0,0 +1,120 @@
+package updatemanager
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/spf13/afero"
+)
+
+func setupImmutableClient(t *testing.T, version string, args ...string) (*Client, string) {
+	setupServingSpecificVersion(t, version)
+	cfg, _ := config.Parse(append([]string{
+		"--versions-root", "../testdata/um-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+		"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+	}, args...))
+	fs := afero.NewOsFs()
+	distPath := path.Join(cfg.VersionsRoot(), version, "dist")
+	fs.MkdirAll(path.Join(distPath, "assets"), 0755)
+	afero.WriteFile(fs, path.Join(distPath, "index.html"), []byte("<html></html>"), 0644)
+	afero.WriteFile(fs, path.Join(distPath, "assets", "app.js"), []byte("app()"), 0644)
+	return &Client{Config: cfg, Fs: fs}, distPath
+}
+
+func TestClientMarkImmutable(t *testing.T) {
+	t.Run("makes the version read-only and writes a manifest", func(t *testing.T) {
+		defer tearDown(t)
+		loader, distPath := setupImmutableClient(t, "2.25.2")
+
+		tests.H(t).ErrEql(loader.markImmutable("2.25.2"), nil)
+
+		info, err := loader.Fs.Stat(path.Join(distPath, "index.html"))
+		tests.H(t).ErrEql(err, nil)
+		tests.H(t).IntEql(int(info.Mode().Perm()), readOnlyFileMode)
+
+		info, err = loader.Fs.Stat(path.Join(distPath, "assets"))
+		tests.H(t).ErrEql(err, nil)
+		tests.H(t).IntEql(int(info.Mode().Perm()), readOnlyDirMode)
+
+		exists, _ := afero.Exists(loader.Fs, path.Join(loader.Config.VersionsRoot(), "2.25.2", manifestFileName))
+		tests.H(t).BoolEqlWithMessage(exists, true, "Expected manifest to exist")
+		tests.H(t).ErrEql(loader.VerifyVersion("2.25.2"), nil)
+	})
+}
+
+func TestClientVerifyVersion(t *testing.T) {
+	t.Run("returns ErrVersionNotImmutable without manifest", func(t *testing.T) {
+		defer tearDown(t)
+		loader, _ := setupImmutableClient(t, "2.25.2")
+
+		tests.H(t).ErrEql(loader.VerifyVersion("2.25.2"), ErrVersionNotImmutable)
+	})
+
+	t.Run("returns ErrVersionCorrupted if a file was modified", func(t *testing.T) {
+		defer tearDown(t)
+		loader, distPath := setupImmutableClient(t, "2.25.2")
+		loader.markImmutable("2.25.2")
+
+		later := time.Now().Add(time.Hour)
+		os.Chtimes(path.Join(distPath, "assets", "app.js"), later, later)
+
+		tests.H(t).ErrEql(loader.VerifyVersion("2.25.2"), ErrVersionCorrupted)
+	})
+
+	t.Run("returns ErrVersionCorrupted if a file was added", func(t *testing.T) {
+		defer tearDown(t)
+		loader, distPath := setupImmutableClient(t, "2.25.2")
+		loader.markImmutable("2.25.2")
+
+		os.Chmod(distPath, 0755)
+		afero.WriteFile(loader.Fs, path.Join(distPath, "extra.js"), []byte("evil()"), 0644)
+
+		tests.H(t).ErrEql(loader.VerifyVersion("2.25.2"), ErrVersionCorrupted)
+	})
+
+	t.Run("returns ErrVersionCorrupted if the content changed keeping size and mtime", func(t *testing.T) {
+		defer tearDown(t)
+		loader, distPath := setupImmutableClient(t, "2.25.2")
+		loader.markImmutable("2.25.2")
+		file := path.Join(distPath, "assets", "app.js")
+		info, _ := os.Stat(file)
+
+		os.Chmod(file, 0644)
+		afero.WriteFile(loader.Fs, file, []byte("bad()"), 0644)
+		os.Chtimes(file, info.ModTime(), info.ModTime())
+
+		tests.H(t).ErrEql(loader.VerifyVersion("2.25.2"), ErrVersionCorrupted)
+	})
+
+	t.Run("hashes a rotating sample of the files", func(t *testing.T) {
+		defer tearDown(t)
+		loader, distPath := setupImmutableClient(t, "2.25.2", "--verify-sample-size", "1")
+		loader.markImmutable("2.25.2")
+		file := path.Join(distPath, "index.html")
+		info, _ := os.Stat(file)
+		os.Chmod(file, 0644)
+		afero.WriteFile(loader.Fs, file, []byte("<HTML></HTML>"), 0644)
+		os.Chtimes(file, info.ModTime(), info.ModTime())
+
+		// assets/app.js is sampled first
+		tests.H(t).ErrEql(loader.VerifyVersion("2.25.2"), nil)
+		tests.H(t).ErrEql(loader.VerifyVersion("2.25.2"), ErrVersionCorrupted)
+		tests.H(t).ErrEql(loader.VerifyVersion("2.25.2"), ErrVersionCorrupted)
+	})
+
+	t.Run("removes an immutable version", func(t *testing.T) {
+		defer tearDown(t)
+		loader, _ := setupImmutableClient(t, "2.25.2")
+		loader.markImmutable("2.25.2")
+
+		tests.H(t).ErrEql(loader.RemoveVersion("2.25.2"), nil)
+
+		exists, _ := afero.DirExists(loader.Fs, path.Join(loader.Config.VersionsRoot(), "2.25.2"))
+		tests.H(t).BoolEqlWithMessage(exists, false, "Expected version directory to be removed")
+	})
+}
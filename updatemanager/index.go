@@ -0,0 +1,39 @@
+package updatemanager
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrIndexFileNotFound occurs if the provided uiDistPath doesn't contain an index.html file
+	ErrIndexFileNotFound = errors.New("index.html file was not found in the ui dist path")
+	// ErrIndexFileCouldNotBeRead occurs if there is an error reading the index.html file
+	ErrIndexFileCouldNotBeRead = errors.New("index.html file could not be read")
+	// ErrVersionNotFoundInIndex occurs if we can't find the DCOS_UI_VERSION in the ui dist's index.html
+	ErrVersionNotFoundInIndex = errors.New("DCOS_UI_VERSION not found in ui dist's index.html")
+)
+
+var uiVersionPattern = regexp.MustCompile(`window.DCOS_UI_VERSION\s*=\s*["'](?P<version>[\w\d.\-+]+)["'];`)
+
+// BuildVersionFromIndex reads the DCOS_UI_VERSION set by the index.html of the ui in uiDistPath
+func BuildVersionFromIndex(uiDistPath string) (string, error) {
+	indexFilePath := path.Join(uiDistPath, "index.html")
+	if _, err := os.Stat(indexFilePath); os.IsNotExist(err) {
+		return "", ErrIndexFileNotFound
+	}
+
+	indexFileBytes, err := ioutil.ReadFile(indexFilePath)
+	if err != nil {
+		return "", ErrIndexFileCouldNotBeRead
+	}
+	matches := uiVersionPattern.FindSubmatch(indexFileBytes)
+	if len(matches) < 2 {
+		return "", ErrVersionNotFoundInIndex
+	}
+	return string(matches[1]), nil
+}
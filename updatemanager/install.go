@@ -0,0 +1,49 @@
+package updatemanager
+
+import (
+	"path"
+
+	"github.com/dcos/dcos-ui-update-service/hooks"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// InstallVersion downloads version into versions-root and marks it immutable without serving it, so it can
+// be served to selected requests before the cluster switches to it. An installed version is kept like a
+// previously served version, it is pruned once more than versions-to-keep newer versions were installed.
+func (um *Client) InstallVersion(version string) error {
+	currentVersion, err := um.CurrentVersion()
+	if err != nil {
+		logrus.WithError(err).Error("Could not get current version for install")
+		return ErrCouldNotGetCurrentVersion
+	}
+	if version == currentVersion || um.reusableVersion(version) {
+		logrus.WithField("version", version).Info("Version is already installed")
+		return nil
+	}
+	um.Lock()
+	defer um.Unlock()
+
+	if exists, err := afero.DirExists(um.Fs, um.Config.VersionsRoot()); err != nil || !exists {
+		logrus.WithError(err).Error("DirExists check for VersionsRoot failed")
+		return ErrVersionsPathDoesNotExist
+	}
+	if err := um.checkWritable(); err != nil {
+		return err
+	}
+
+	targetDir := path.Join(um.Config.VersionsRoot(), version)
+	hookEvent := hooks.Event{Version: version, PreviousVersion: currentVersion, Path: path.Join(targetDir, "dist")}
+	if err := um.downloadVersion(version, targetDir, hookEvent, nil); err != nil {
+		return err
+	}
+	if err := um.markImmutable(version); err != nil {
+		// a version without manifest is not kept, it would be pruned with the next update
+		logrus.WithError(err).WithField("version", version).Error("Could not mark installed version immutable")
+		um.chmodTree(targetDir, writableDirMode, writableFileMode)
+		um.Fs.RemoveAll(targetDir)
+		return err
+	}
+	logrus.WithField("version", version).Info("Installed version without serving it")
+	return nil
+}
@@ -0,0 +1,66 @@
+package updatemanager
+
+import (
+	"path"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/spf13/afero"
+)
+
+func TestClientInstallVersion(t *testing.T) {
+	t.Run("installs a version without serving it", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		fs := afero.NewOsFs()
+		loader, fetcher := newFakeFetcherClient(cfg, fs)
+
+		err := loader.InstallVersion("2.25.2")
+
+		tests.H(t).IsNil(err)
+		index, _ := afero.Exists(fs, path.Join(cfg.VersionsRoot(), "2.25.2", "dist", "index.html"))
+		tests.H(t).BoolEql(index, true)
+		tests.H(t).IsNil(loader.VerifyVersion("2.25.2"))
+		current, _ := loader.CurrentVersion()
+		tests.H(t).StringEql(current, "2.25.1")
+
+		tests.H(t).IsNil(loader.InstallVersion("2.25.2"))
+		tests.H(t).IntEql(len(fetcher.Downloaded), 1)
+	})
+
+	t.Run("does not download the served version", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		loader, fetcher := newFakeFetcherClient(cfg, afero.NewOsFs())
+
+		tests.H(t).IsNil(loader.InstallVersion("2.25.1"))
+		tests.H(t).IntEql(len(fetcher.Downloaded), 0)
+	})
+
+	t.Run("returns ErrRequestedVersionNotFound for a version unknown to the package source", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		cfg, _ := config.Parse([]string{
+			"--versions-root", "../testdata/um-sandbox/ui-versions",
+			"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+			"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		})
+		fs := afero.NewOsFs()
+		loader, _ := newFakeFetcherClient(cfg, fs)
+
+		tests.H(t).ErrEql(loader.InstallVersion("9.9.9"), ErrRequestedVersionNotFound)
+		exists, _ := afero.DirExists(fs, path.Join(cfg.VersionsRoot(), "9.9.9"))
+		tests.H(t).BoolEql(exists, false)
+	})
+}
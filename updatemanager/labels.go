@@ -0,0 +1,215 @@
+package updatemanager
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// labelsFileName is the file in versions-root mapping the labels of this master to versions
+const labelsFileName = ".labels.json"
+
+var (
+	// ErrLabelNotFound occurs if no local version has the label
+	ErrLabelNotFound = errors.New("No local version has the label")
+	// ErrVersionNotInstalled occurs if a label is set on a version that is not installed in versions-root
+	ErrVersionNotInstalled = errors.New("The version is not installed on this master")
+	// ErrWritingLabels occurs if the labels cannot be updated
+	ErrWritingLabels = errors.New("Failed to write the version labels")
+)
+
+// LocalVersion is a version installed in versions-root of this master
+type LocalVersion struct {
+	Version string `json:"version"`
+	// Served is true for the version the ui dist symlink points to
+	Served bool `json:"served"`
+	// Labels are the labels of this master pointing to the version
+	Labels []string `json:"labels"`
+}
+
+// readLabels returns the labels by name, the labels lock must be held
+func (um *Client) readLabels() (map[string]string, error) {
+	labels := map[string]string{}
+	data, err := afero.ReadFile(um.Fs, path.Join(um.Config.VersionsRoot(), labelsFileName))
+	if os.IsNotExist(err) {
+		return labels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &labels); err != nil {
+		logrus.WithError(err).Warn("Could not parse the version labels, starting without labels")
+		return map[string]string{}, nil
+	}
+	return labels, nil
+}
+
+// writeLabels replaces the labels file atomically, the labels lock must be held
+func (um *Client) writeLabels(labels map[string]string) error {
+	data, err := json.Marshal(labels)
+	if err != nil {
+		return err
+	}
+	tmpPath := path.Join(um.Config.VersionsRoot(), labelsFileName+".tmp")
+	if err := afero.WriteFile(um.Fs, tmpPath, data, writableFileMode); err != nil {
+		return err
+	}
+	if err := um.Fs.Rename(tmpPath, path.Join(um.Config.VersionsRoot(), labelsFileName)); err != nil {
+		um.Fs.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// installed is true if the version has a manifest, versions still being downloaded have none
+func (um *Client) installed(version string) bool {
+	exists, _ := afero.Exists(um.Fs, path.Join(um.Config.VersionsRoot(), version, manifestFileName))
+	return exists
+}
+
+// LocalVersions lists the versions installed in versions-root with their labels, ordered by version
+func (um *Client) LocalVersions() ([]LocalVersion, error) {
+	served, err := um.CurrentVersion()
+	if err != nil {
+		return nil, ErrCouldNotGetCurrentVersion
+	}
+	entries, err := afero.ReadDir(um.Fs, um.Config.VersionsRoot())
+	if err != nil {
+		logrus.WithError(err).Error("Unable to read versions-root.")
+		return nil, ErrReadingVersions
+	}
+	um.labelsLock.Lock()
+	labels, err := um.readLabels()
+	um.labelsLock.Unlock()
+	if err != nil {
+		logrus.WithError(err).Warn("Could not read the version labels")
+		labels = map[string]string{}
+	}
+	byVersion := map[string][]string{}
+	for label, version := range labels {
+		byVersion[version] = append(byVersion[version], label)
+	}
+
+	versions := []LocalVersion{}
+	for _, info := range entries {
+		name := info.Name()
+		if !info.IsDir() || name == trashDirName || name == quarantineDirName || !um.installed(name) {
+			continue
+		}
+		versionLabels := byVersion[name]
+		if versionLabels == nil {
+			versionLabels = []string{}
+		}
+		sort.Strings(versionLabels)
+		versions = append(versions, LocalVersion{Version: name, Served: name == served, Labels: versionLabels})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+	return versions, nil
+}
+
+// SetVersionLabel points label to the installed version, moving it from the version it pointed to before
+func (um *Client) SetVersionLabel(version, label string) error {
+	if !um.installed(version) {
+		return ErrVersionNotInstalled
+	}
+	if err := um.checkWritable(); err != nil {
+		return err
+	}
+	um.labelsLock.Lock()
+	defer um.labelsLock.Unlock()
+	labels, err := um.readLabels()
+	if err != nil {
+		logrus.WithError(err).Error("Could not read the version labels")
+		return ErrWritingLabels
+	}
+	labels[label] = version
+	if err := um.writeLabels(labels); err != nil {
+		logrus.WithError(err).Error("Could not write the version labels")
+		return ErrWritingLabels
+	}
+	logrus.WithFields(logrus.Fields{"version": version, "label": label}).Info("Labeled local version")
+	return nil
+}
+
+// RemoveVersionLabel removes label from version, it returns ErrLabelNotFound if the label points elsewhere
+func (um *Client) RemoveVersionLabel(version, label string) error {
+	um.labelsLock.Lock()
+	defer um.labelsLock.Unlock()
+	labels, err := um.readLabels()
+	if err != nil {
+		logrus.WithError(err).Error("Could not read the version labels")
+		return ErrWritingLabels
+	}
+	if labels[label] != version {
+		return ErrLabelNotFound
+	}
+	delete(labels, label)
+	if err := um.writeLabels(labels); err != nil {
+		logrus.WithError(err).Error("Could not write the version labels")
+		return ErrWritingLabels
+	}
+	logrus.WithFields(logrus.Fields{"version": version, "label": label}).Info("Removed label of local version")
+	return nil
+}
+
+// LabeledVersion returns the installed version label points to
+func (um *Client) LabeledVersion(label string) (string, error) {
+	um.labelsLock.Lock()
+	labels, err := um.readLabels()
+	um.labelsLock.Unlock()
+	if err != nil {
+		logrus.WithError(err).Error("Could not read the version labels")
+		return "", ErrLabelNotFound
+	}
+	version, ok := labels[label]
+	if !ok || !um.installed(version) {
+		return "", ErrLabelNotFound
+	}
+	return version, nil
+}
+
+// labeled is true if any label points to the version
+func (um *Client) labeled(version string) bool {
+	um.labelsLock.Lock()
+	defer um.labelsLock.Unlock()
+	labels, err := um.readLabels()
+	if err != nil {
+		return false
+	}
+	for _, labeledVersion := range labels {
+		if labeledVersion == version {
+			return true
+		}
+	}
+	return false
+}
+
+// dropLabels removes the labels pointing to a removed version
+func (um *Client) dropLabels(version string) {
+	um.labelsLock.Lock()
+	defer um.labelsLock.Unlock()
+	labels, err := um.readLabels()
+	if err != nil {
+		return
+	}
+	dropped := false
+	for label, labeledVersion := range labels {
+		if labeledVersion == version {
+			delete(labels, label)
+			dropped = true
+		}
+	}
+	if !dropped {
+		return
+	}
+	if err := um.writeLabels(labels); err != nil {
+		logrus.WithError(err).WithField("version", version).Warn("Could not remove the labels of the removed version")
+	}
+}
@@ -0,0 +1,98 @@
+package updatemanager
+
+import (
+	"path"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/spf13/afero"
+)
+
+func TestClientVersionLabels(t *testing.T) {
+	t.Run("lists installed versions with their labels", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		um, _ := newRetentionClient(t)
+		writeActivatedVersion(t, um, "2.25.1")
+		writeActivatedVersion(t, um, "2.25.2")
+
+		tests.H(t).IsNil(um.SetVersionLabel("2.25.2", "rc"))
+		tests.H(t).IsNil(um.SetVersionLabel("2.25.1", "previous-good"))
+
+		versions, err := um.LocalVersions()
+		tests.H(t).IsNil(err)
+		tests.H(t).InterfaceEql(versions, []LocalVersion{
+			{Version: "2.25.1", Served: true, Labels: []string{"previous-good"}},
+			{Version: "2.25.2", Served: false, Labels: []string{"rc"}},
+		})
+	})
+
+	t.Run("moves a label to another version", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		um, _ := newRetentionClient(t)
+		writeActivatedVersion(t, um, "2.25.1")
+		writeActivatedVersion(t, um, "2.25.2")
+
+		tests.H(t).IsNil(um.SetVersionLabel("2.25.1", "rc"))
+		tests.H(t).IsNil(um.SetVersionLabel("2.25.2", "rc"))
+
+		version, err := um.LabeledVersion("rc")
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(version, "2.25.2")
+		tests.H(t).ErrEql(um.RemoveVersionLabel("2.25.1", "rc"), ErrLabelNotFound)
+	})
+
+	t.Run("rejects labels of versions which are not installed", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		um, _ := newRetentionClient(t)
+		writeVersionFile(t, path.Join(um.Config.VersionsRoot(), "2.25.2"), 10)
+
+		tests.H(t).ErrEql(um.SetVersionLabel("2.25.2", "rc"), ErrVersionNotInstalled)
+		tests.H(t).ErrEql(um.SetVersionLabel("9.9.9", "rc"), ErrVersionNotInstalled)
+	})
+
+	t.Run("removes a label", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		um, _ := newRetentionClient(t)
+		writeActivatedVersion(t, um, "2.25.2")
+		tests.H(t).IsNil(um.SetVersionLabel("2.25.2", "rc"))
+
+		tests.H(t).IsNil(um.RemoveVersionLabel("2.25.2", "rc"))
+
+		_, err := um.LabeledVersion("rc")
+		tests.H(t).ErrEql(err, ErrLabelNotFound)
+	})
+
+	t.Run("drops the labels of a removed version", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		um, _ := newRetentionClient(t)
+		writeActivatedVersion(t, um, "2.25.2")
+		tests.H(t).IsNil(um.SetVersionLabel("2.25.2", "rc"))
+
+		tests.H(t).IsNil(um.RemoveVersion("2.25.2"))
+
+		_, err := um.LabeledVersion("rc")
+		tests.H(t).ErrEql(err, ErrLabelNotFound)
+	})
+
+	t.Run("keeps labeled versions when pruning", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingDefault(t)
+		um, _ := newRetentionClient(t, "--versions-to-keep", "1")
+		for _, version := range []string{"2.25.1", "2.25.2", "2.25.3"} {
+			writeActivatedVersion(t, um, version)
+		}
+		tests.H(t).IsNil(um.SetVersionLabel("2.25.1", "previous-good"))
+
+		tests.H(t).IsNil(um.PruneVersions(""))
+
+		for version, kept := range map[string]bool{"2.25.1": true, "2.25.2": false, "2.25.3": true} {
+			exists, _ := afero.DirExists(um.Fs, path.Join(um.Config.VersionsRoot(), version))
+			tests.H(t).BoolEqlWithMessage(exists, kept, "Unexpected retention of "+version)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package updatemanager
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/mirror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// MirrorPublisher uploads verified bundles to an internal artifact mirror, mirror.WebDAV and mirror.S3
+// are the implementations
+type MirrorPublisher interface {
+	Publish(ctx context.Context, name string, bundle io.Reader, size int64, digest string) error
+}
+
+// MirrorPublishing is implemented by an Activator of the master leading a cluster update, only that master
+// publishes the bundle it downloaded to the mirror
+type MirrorPublishing interface {
+	PublishToMirror() bool
+}
+
+// NewMirrorPublisher creates the MirrorPublisher configured by mirror-publish-url, nil if none is configured
+func NewMirrorPublisher(cfg *config.Config) (MirrorPublisher, error) {
+	if cfg.MirrorPublishURL() == "" {
+		return nil, nil
+	}
+	publishURL, err := url.Parse(cfg.MirrorPublishURL())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse configured mirror publish URL")
+	}
+	var user, secret string
+	if file := cfg.MirrorPublishCredentialsFile(); file != "" {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read mirror publish credentials file '%s'", file)
+		}
+		parts := strings.SplitN(strings.TrimSpace(string(content)), ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Errorf("mirror publish credentials file '%s' must contain 'user:secret'", file)
+		}
+		user, secret = parts[0], parts[1]
+	}
+	client := &http.Client{Timeout: cfg.DownloadTimeout()}
+
+	if publishURL.Scheme != "s3" {
+		return &mirror.WebDAV{BaseURL: publishURL, Username: user, Password: secret, HTTPClient: client}, nil
+	}
+	endpoint := cfg.MirrorS3Endpoint()
+	if endpoint == "" {
+		endpoint = "https://s3." + cfg.MirrorS3Region() + ".amazonaws.com"
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse configured S3 endpoint")
+	}
+	return &mirror.S3{
+		Endpoint:        endpointURL,
+		Region:          cfg.MirrorS3Region(),
+		Bucket:          publishURL.Host,
+		Prefix:          publishURL.Path,
+		AccessKeyID:     user,
+		SecretAccessKey: secret,
+		HTTPClient:      client,
+	}, nil
+}
+
+// bundleCopy keeps the downloaded bundle in a temporary file until it is published
+type bundleCopy struct {
+	file afero.File
+	// err is the first error writing the copy, the copy is not published if set
+	err error
+}
+
+// newBundleCopy creates the copy of the bundle of a version downloaded for activator, nil if the bundle
+// is not published by this master
+func (um *Client) newBundleCopy(activator Activator) *bundleCopy {
+	publishing, ok := activator.(MirrorPublishing)
+	if um.Mirror == nil || !ok || !publishing.PublishToMirror() {
+		return nil
+	}
+	file, err := afero.TempFile(um.Fs, "", "dcos-ui-bundle-")
+	if err != nil {
+		logrus.WithError(err).Warn("Could not create a copy of the bundle, it is not published to the mirror")
+		return nil
+	}
+	return &bundleCopy{file: file}
+}
+
+// reset starts the copy of a new download attempt
+func (c *bundleCopy) reset() io.Writer {
+	if err := c.file.Truncate(0); err != nil {
+		c.err = err
+	} else if _, err := c.file.Seek(0, io.SeekStart); err != nil {
+		c.err = err
+	} else {
+		c.err = nil
+	}
+	return c
+}
+
+// Write copies p to the file, errors are kept for publishing and never fail the download
+func (c *bundleCopy) Write(p []byte) (int, error) {
+	if c.err == nil {
+		_, c.err = c.file.Write(p)
+	}
+	return len(p), nil
+}
+
+// remove deletes the temporary file
+func (c *bundleCopy) remove(fs afero.Fs) {
+	c.file.Close()
+	fs.Remove(c.file.Name())
+}
+
+// publishBundle uploads the copy of the bundle of version to the mirror once its checksum was verified by
+// a complete download, a failed upload is only logged
+func (um *Client) publishBundle(version string, bundle *bundleCopy) {
+	logger := logrus.WithField("version", version)
+	provenance, err := um.VersionProvenance(version)
+	if err != nil || provenance.AssetChecksum == "" {
+		logger.Warn("The bundle has no verified checksum, it is not published to the mirror")
+		return
+	}
+	if bundle.err != nil {
+		logger.WithError(bundle.err).Warn("Could not copy the bundle, it is not published to the mirror")
+		return
+	}
+	bundleURL, err := url.Parse(provenance.BundleURL)
+	if err != nil {
+		logger.WithError(err).Warn("Could not parse the bundle URL, the bundle is not published to the mirror")
+		return
+	}
+	name := path.Base(bundleURL.Path)
+	if name == "." || name == "/" {
+		logger.Warn("The bundle URL has no file name, the bundle is not published to the mirror")
+		return
+	}
+	size, err := bundle.file.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = bundle.file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logger.WithError(err).Warn("Could not read the copy of the bundle, it is not published to the mirror")
+		return
+	}
+
+	logger = logger.WithFields(logrus.Fields{"bundle": name, "checksum": provenance.AssetChecksum})
+	if err := um.Mirror.Publish(context.Background(), name, bundle.file, size, provenance.AssetChecksum); err != nil {
+		logger.WithError(err).Error("Publishing the bundle to the mirror failed")
+		return
+	}
+	logger.Info("Published the verified bundle to the mirror")
+}
@@ -0,0 +1,128 @@
+package updatemanager
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// packageNames remembers which of package-name and its aliases the versions were found in, so the describe
+// requests of a version use the package it was listed in
+type packageNames struct {
+	byVersion map[string]string
+	// listed is the package the available versions were last listed from
+	listed string
+	sync.Mutex
+}
+
+func (p *packageNames) record(name string, versions []string) {
+	p.Lock()
+	defer p.Unlock()
+	if p.byVersion == nil {
+		p.byVersion = map[string]string{}
+	}
+	for _, version := range versions {
+		p.byVersion[version] = name
+	}
+}
+
+func (p *packageNames) setListed(name string) {
+	p.Lock()
+	defer p.Unlock()
+	p.listed = name
+}
+
+func (p *packageNames) get(version string) (string, bool) {
+	p.Lock()
+	defer p.Unlock()
+	if version == "" {
+		return p.listed, p.listed != ""
+	}
+	name, ok := p.byVersion[version]
+	return name, ok
+}
+
+// listPackageVersions lists the versions of the first package of package-name and its aliases the package
+// source offers versions of, it returns the name of that package. Errors of a package are only returned if
+// no package offers versions.
+func (um *Client) listPackageVersions() ([]string, string, error) {
+	var firstErr error
+	for _, name := range um.Config.PackageNames() {
+		versions, err := um.Source.ListVersions(name)
+		if err != nil {
+			logrus.WithError(err).WithField("package", name).Debug("Package source ListVersions request failed, trying the next package name")
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if len(versions) == 0 {
+			continue
+		}
+		um.resolvedPackage(name)
+		um.packages.record(name, versions)
+		um.packages.setListed(name)
+		return versions, name, nil
+	}
+	if firstErr != nil {
+		return nil, "", firstErr
+	}
+	return []string{}, um.Config.PackageName(), nil
+}
+
+// findPackageVersion returns the first package of package-name and its aliases offering version, "" if none
+// does. Errors of a package are only returned if no package could be listed.
+func (um *Client) findPackageVersion(version string) (string, error) {
+	var firstErr error
+	listed := false
+	for _, name := range um.Config.PackageNames() {
+		versions, err := um.Source.ListVersions(name)
+		if err != nil {
+			logrus.WithError(err).WithField("package", name).Debug("Package source ListVersions request failed, trying the next package name")
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		listed = true
+		if includesVersion(versions, version) {
+			um.resolvedPackage(name)
+			um.packages.record(name, []string{version})
+			return name, nil
+		}
+	}
+	if listed {
+		return "", nil
+	}
+	return "", firstErr
+}
+
+// resolvedPackage logs that versions are resolved from an alias instead of package-name
+func (um *Client) resolvedPackage(name string) {
+	if name != um.Config.PackageName() {
+		logrus.WithFields(logrus.Fields{"package": name, "packageName": um.Config.PackageName()}).Info("Resolved versions from package name alias")
+	}
+}
+
+// packageOf returns the package version was found in, package-name if it was not listed yet
+func (um *Client) packageOf(version string) string {
+	if name, ok := um.packages.get(version); ok {
+		return name
+	}
+	return um.Config.PackageName()
+}
+
+// PackageName returns the package version was resolved from, or for an empty version the package the
+// available versions were last listed from. Installed versions fall back to their provenance, anything
+// not resolved yet to package-name.
+func (um *Client) PackageName(version string) string {
+	if name, ok := um.packages.get(version); ok {
+		return name
+	}
+	if version != "" {
+		if provenance, err := um.VersionProvenance(version); err == nil && provenance.PackageName != "" {
+			return provenance.PackageName
+		}
+	}
+	return um.Config.PackageName()
+}
@@ -0,0 +1,79 @@
+package updatemanager
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/spf13/afero"
+)
+
+func newAliasClient(t *testing.T, packages map[string][]string) (*Client, *FakePackageSource) {
+	cfg, _ := config.Parse([]string{
+		"--versions-root", "../testdata/um-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+		"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		"--package-name-aliases", "dcos-ui-oss,dcos-ui-legacy",
+	})
+	um, _ := newFakeFetcherClient(cfg, afero.NewOsFs())
+	bundleURL, _ := url.Parse("https://downloads.example.com/dcos-ui/release.tar.gz")
+	source := &FakePackageSource{Packages: packages, BundleURL: bundleURL}
+	um.Source = source
+	return um, source
+}
+
+func TestClientPackageNameAliases(t *testing.T) {
+	t.Run("lists the versions of the first known package name", func(t *testing.T) {
+		um, _ := newAliasClient(t, map[string][]string{
+			"dcos-ui-oss":    {"2.25.1", "2.25.2"},
+			"dcos-ui-legacy": {"2.24.4"},
+		})
+
+		versions, err := um.AvailableVersions()
+
+		tests.H(t).IsNil(err)
+		tests.H(t).InterfaceEql(versions, []string{"2.25.1", "2.25.2"})
+		tests.H(t).StringEql(um.PackageName(""), "dcos-ui-oss")
+	})
+
+	t.Run("returns ErrCosmosRequestFailure if no package name is known", func(t *testing.T) {
+		um, _ := newAliasClient(t, map[string][]string{})
+
+		_, err := um.AvailableVersions()
+
+		tests.H(t).ErrEql(err, ErrCosmosRequestFailure)
+	})
+
+	t.Run("updates from the first package offering the version", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		um, source := newAliasClient(t, map[string][]string{
+			"dcos-ui":        {"2.25.1"},
+			"dcos-ui-oss":    {"2.25.1", "2.25.2"},
+			"dcos-ui-legacy": {"2.25.2"},
+		})
+
+		tests.H(t).IsNil(um.UpdateToVersion("2.25.2", &fakeActivator{}))
+
+		tests.H(t).InterfaceEql(source.ResolvedPackages, []string{"dcos-ui-oss"})
+		tests.H(t).StringEql(um.PackageName("2.25.2"), "dcos-ui-oss")
+		provenance, err := um.VersionProvenance("2.25.2")
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(provenance.PackageName, "dcos-ui-oss")
+	})
+
+	t.Run("returns ErrRequestedVersionNotFound if no package offers the version", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		um, _ := newAliasClient(t, map[string][]string{"dcos-ui-oss": {"2.25.1"}})
+
+		tests.H(t).ErrEql(um.UpdateToVersion("2.25.2", &fakeActivator{}), ErrRequestedVersionNotFound)
+	})
+
+	t.Run("falls back to package-name for unresolved versions", func(t *testing.T) {
+		um, _ := newAliasClient(t, map[string][]string{})
+
+		tests.H(t).StringEql(um.PackageName("9.9.9"), "dcos-ui")
+	})
+}
@@ -0,0 +1,111 @@
+package updatemanager
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/dcos/dcos-ui-update-service/auth"
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/cosmos"
+	"github.com/dcos/dcos-ui-update-service/retry"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// PackageSource provides the available ui versions and where to download them from
+type PackageSource interface {
+	ListVersions(packageName string) ([]string, error)
+	ResolveBundle(packageName string, version string) (*url.URL, error)
+}
+
+// PackageRequirements is implemented by package sources knowing the minimum DC/OS release of a version
+type PackageRequirements interface {
+	MinDcosReleaseVersion(packageName string, version string) (string, error)
+}
+
+// PackageOrigins is implemented by package sources knowing the source repository and release of a version
+type PackageOrigins interface {
+	PackageOrigin(packageName string, version string) (string, int, error)
+}
+
+// DirectURLSource is a PackageSource serving a fixed set of versions from direct bundle URLs
+type DirectURLSource struct {
+	Bundles map[string]*url.URL
+}
+
+// NewPackageSource creates the PackageSource selected by the config, the direct URL source is
+// used if bundle URLs are configured, Cosmos otherwise. Cosmos requests are authenticated with
+// credentials if they are not nil.
+func NewPackageSource(cfg *config.Config, credentials *auth.CredentialProvider) (PackageSource, error) {
+	if bundleURLs := cfg.BundleURLs(); len(bundleURLs) > 0 {
+		return NewDirectURLSource(bundleURLs)
+	}
+
+	universeURL, err := url.Parse(cfg.UniverseURL())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse configured Universe URL")
+	}
+	cosmosClient := cosmos.NewClient(universeURL)
+	cosmosClient.RequestTimeout = cfg.CosmosTimeout()
+	cosmosClient.Retry = retryPolicy(cfg)
+	// without package-asset-name the bundle asset is named after the package or alias the version is found in
+	if assetName := cfg.PackageAssetName(); assetName != cfg.PackageName()+"-bundle" {
+		cosmosClient.BundleAssetName = assetName
+	}
+	settings, err := proxySettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Configured() {
+		cosmosClient.UseProxy(settings)
+		logrus.WithFields(settings.Fields()).Info("Cosmos proxy")
+	}
+	if credentials != nil {
+		cosmosClient.UseCredentials(credentials)
+	}
+	return cosmosClient, nil
+}
+
+// NewDirectURLSource creates a DirectURLSource from `version=url` entries
+func NewDirectURLSource(entries []string) (*DirectURLSource, error) {
+	source := &DirectURLSource{Bundles: make(map[string]*url.URL)}
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Errorf("invalid bundle url entry '%s', expected format 'version=url'", entry)
+		}
+		bundleURL, err := url.Parse(parts[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid bundle url for version %s", parts[0])
+		}
+		source.Bundles[parts[0]] = bundleURL
+	}
+	return source, nil
+}
+
+// ListVersions returns the configured versions, packageName is ignored
+func (s *DirectURLSource) ListVersions(packageName string) ([]string, error) {
+	versions := make([]string, 0, len(s.Bundles))
+	for version := range s.Bundles {
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// ResolveBundle returns the configured URL for version
+func (s *DirectURLSource) ResolveBundle(packageName string, version string) (*url.URL, error) {
+	bundleURL, found := s.Bundles[version]
+	if !found {
+		return nil, ErrUIPackageAssetNotFound
+	}
+	return bundleURL, nil
+}
+
+// retryPolicy is the retry policy of Cosmos requests and bundle downloads configured by cfg
+func retryPolicy(cfg *config.Config) retry.Policy {
+	return retry.Policy{
+		Attempts: cfg.RetryAttempts(),
+		Min:      cfg.RetryMinBackoff(),
+		Max:      cfg.RetryMaxBackoff(),
+	}
+}
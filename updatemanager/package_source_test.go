@@ -0,0 +1,109 @@
+package updatemanager
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/cosmos"
+	"github.com/dcos/dcos-ui-update-service/downloader"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/spf13/afero"
+)
+
+func TestDirectURLSource(t *testing.T) {
+	t.Run("lists configured versions", func(t *testing.T) {
+		source, err := NewDirectURLSource([]string{"2.25.0=https://example.com/a.tar.gz", "2.25.1=https://example.com/b.tar.gz"})
+		tests.H(t).IsNil(err)
+
+		versions, err := source.ListVersions("dcos-ui")
+		sort.Strings(versions)
+		tests.H(t).IsNil(err)
+		tests.H(t).InterfaceEql(versions, []string{"2.25.0", "2.25.1"})
+	})
+
+	t.Run("resolves bundle url by version", func(t *testing.T) {
+		source, _ := NewDirectURLSource([]string{"2.25.0=https://example.com/a.tar.gz"})
+
+		bundleURL, err := source.ResolveBundle("dcos-ui", "2.25.0")
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(bundleURL.String(), "https://example.com/a.tar.gz")
+
+		_, err = source.ResolveBundle("dcos-ui", "2.25.1")
+		tests.H(t).ErrEql(err, ErrUIPackageAssetNotFound)
+	})
+
+	t.Run("rejects malformed entries", func(t *testing.T) {
+		_, err := NewDirectURLSource([]string{"https://example.com/a.tar.gz"})
+		tests.H(t).NotNil(err)
+	})
+}
+
+func TestNewPackageSource(t *testing.T) {
+	t.Run("uses cosmos by default", func(t *testing.T) {
+		cfg, _ := config.Parse(nil)
+		source, err := NewPackageSource(cfg, nil)
+		tests.H(t).IsNil(err)
+		tests.H(t).TypeEql(source, &cosmos.Client{})
+	})
+
+	t.Run("names the bundle asset after the resolved package unless an asset name is configured", func(t *testing.T) {
+		cfg, _ := config.Parse([]string{"--package-name-aliases", "dcos-ui-oss"})
+		source, _ := NewPackageSource(cfg, nil)
+		tests.H(t).StringEql(source.(*cosmos.Client).BundleAssetName, "")
+
+		cfg, _ = config.Parse([]string{"--package-asset-name", "ui-archive"})
+		source, _ = NewPackageSource(cfg, nil)
+		tests.H(t).StringEql(source.(*cosmos.Client).BundleAssetName, "ui-archive")
+	})
+
+	t.Run("uses direct urls if configured", func(t *testing.T) {
+		cfg, _ := config.Parse([]string{"--bundle-urls", "2.25.0=https://example.com/a.tar.gz"})
+		source, err := NewPackageSource(cfg, nil)
+		tests.H(t).IsNil(err)
+		tests.H(t).TypeEql(source, &DirectURLSource{})
+	})
+}
+
+func TestClientLoadVersionWithFakeSource(t *testing.T) {
+	cfg, _ := config.Parse([]string{"--versions-root", "/ui-versions"})
+	fs := afero.NewMemMapFs()
+
+	t.Run("returns ErrRequestedVersionNotFound if the source doesn't list the version", func(t *testing.T) {
+		source := &FakePackageSource{Versions: []string{"2.25.0"}}
+		client := Client{Source: source, Fetcher: downloader.New(fs), Config: cfg, Fs: fs}
+
+		err := client.loadVersion(context.Background(), "2.25.1", "/ui-versions/2.25.1")
+		tests.H(t).ErrEql(err, ErrRequestedVersionNotFound)
+		tests.H(t).IntEql(len(source.ResolveCalled), 0)
+	})
+
+	t.Run("returns ErrCosmosRequestFailure if listing fails", func(t *testing.T) {
+		source := &FakePackageSource{ListError: errors.New("502")}
+		client := Client{Source: source, Fetcher: downloader.New(fs), Config: cfg, Fs: fs}
+
+		err := client.loadVersion(context.Background(), "2.25.1", "/ui-versions/2.25.1")
+		tests.H(t).ErrEql(err, ErrCosmosRequestFailure)
+	})
+
+	t.Run("maps missing bundle asset to ErrUIPackageAssetNotFound", func(t *testing.T) {
+		source := &FakePackageSource{Versions: []string{"2.25.1"}, ResolveError: cosmos.ErrBundleAssetNotFound}
+		client := Client{Source: source, Fetcher: downloader.New(fs), Config: cfg, Fs: fs}
+
+		err := client.loadVersion(context.Background(), "2.25.1", "/ui-versions/2.25.1")
+		tests.H(t).ErrEql(err, ErrUIPackageAssetNotFound)
+	})
+
+	t.Run("downloads from the resolved bundle url", func(t *testing.T) {
+		bundleURL, _ := url.Parse("http://unknown")
+		source := &FakePackageSource{Versions: []string{"2.25.1"}, BundleURL: bundleURL}
+		client := Client{Source: source, Fetcher: downloader.New(fs), Config: cfg, Fs: fs}
+
+		err := client.loadVersion(context.Background(), "2.25.1", "/ui-versions/2.25.1")
+		tests.H(t).ErrEql(err, downloader.ErrDowloadPackageFailed)
+		tests.H(t).InterfaceEql(source.ResolveCalled, []string{"2.25.1"})
+	})
+}
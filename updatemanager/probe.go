@@ -0,0 +1,42 @@
+package updatemanager
+
+import (
+	"context"
+	"time"
+)
+
+// AssetProbe describes the bundle of a version as seen by a HEAD request to its artifact host
+type AssetProbe struct {
+	Version         string `json:"version"`
+	URL             string `json:"url"`
+	StatusCode      int    `json:"statusCode,omitempty"`
+	Size            int64  `json:"size"`
+	ResolveDuration string `json:"resolveDuration"`
+	HeadDuration    string `json:"headDuration,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// ProbeAsset resolves the bundle URL of the version and sends a HEAD request to it without downloading
+// the bundle. Resolve failures are returned as errors, a failing HEAD request is reported in the probe.
+func (um *Client) ProbeAsset(version string) (*AssetProbe, error) {
+	probe := &AssetProbe{Version: version, Size: -1}
+
+	start := time.Now()
+	bundleURL, err := um.resolveBundleURL(version)
+	probe.ResolveDuration = time.Since(start).String()
+	if err != nil {
+		return nil, err
+	}
+	probe.URL = bundleURL.String()
+
+	start = time.Now()
+	status, size, err := um.Fetcher.Head(context.Background(), bundleURL)
+	probe.HeadDuration = time.Since(start).String()
+	if err != nil {
+		probe.Error = err.Error()
+		return probe, nil
+	}
+	probe.StatusCode = status
+	probe.Size = size
+	return probe, nil
+}
@@ -0,0 +1,61 @@
+package updatemanager
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestClientProbeAsset(t *testing.T) {
+	t.Run("reports status and size of the bundle without downloading it", func(t *testing.T) {
+		bundleURL, _ := url.Parse("https://downloads.example.com/dcos-ui.tar.gz")
+		cfg, _ := config.Parse(nil)
+		fetcher := &FakeBundleFetcher{HeadSize: 1024}
+		loader := Client{
+			Source:  &FakePackageSource{Versions: []string{"2.25.0"}, BundleURL: bundleURL},
+			Fetcher: fetcher,
+			Config:  cfg,
+		}
+
+		probe, err := loader.ProbeAsset("2.25.0")
+
+		tests.H(t).ErrEql(err, nil)
+		tests.H(t).StringEql(probe.URL, bundleURL.String())
+		tests.H(t).IntEql(probe.StatusCode, http.StatusOK)
+		tests.H(t).Int64Eql(probe.Size, 1024)
+		tests.H(t).InterfaceEql(fetcher.Headed, []string{bundleURL.String()})
+		tests.H(t).IntEql(len(fetcher.Downloaded), 0)
+	})
+
+	t.Run("reports an unreachable artifact host in the probe", func(t *testing.T) {
+		bundleURL, _ := url.Parse("https://downloads.example.com/dcos-ui.tar.gz")
+		cfg, _ := config.Parse(nil)
+		loader := Client{
+			Source:  &FakePackageSource{Versions: []string{"2.25.0"}, BundleURL: bundleURL},
+			Fetcher: &FakeBundleFetcher{HeadError: errors.New("connection refused")},
+			Config:  cfg,
+		}
+
+		probe, err := loader.ProbeAsset("2.25.0")
+
+		tests.H(t).ErrEql(err, nil)
+		tests.H(t).StringEql(probe.Error, "connection refused")
+		tests.H(t).Int64Eql(probe.Size, -1)
+	})
+
+	t.Run("returns ErrRequestedVersionNotFound for unknown versions", func(t *testing.T) {
+		cfg, _ := config.Parse(nil)
+		loader := Client{
+			Source: &FakePackageSource{Versions: []string{"2.25.0"}},
+			Config: cfg,
+		}
+
+		_, err := loader.ProbeAsset("3.0.0")
+
+		tests.H(t).ErrEql(err, ErrRequestedVersionNotFound)
+	})
+}
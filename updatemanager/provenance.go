@@ -0,0 +1,143 @@
+package updatemanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/downloader"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// provenanceFileName is the file in the version directory recording where the version came from
+const provenanceFileName = "provenance.json"
+
+var (
+	// ErrProvenanceNotFound occurs if no provenance was recorded for a version
+	ErrProvenanceNotFound = errors.New("No provenance recorded for the version")
+	// ErrWritingProvenance occurs if the provenance of a version cannot be updated
+	ErrWritingProvenance = errors.New("Failed to write the provenance of the version")
+)
+
+// Provenance records where an installed version came from and who verified it
+type Provenance struct {
+	Version string `json:"version"`
+	// BundleURL is the URL the bundle was downloaded from, without credentials
+	BundleURL string `json:"bundleUrl"`
+	// PackageName, SourceRepo and PackageRelease are provided by the package source, they are empty for
+	// direct bundles. PackageName is package-name or the alias the version was found in.
+	PackageName    string `json:"packageName,omitempty"`
+	SourceRepo     string `json:"sourceRepo,omitempty"`
+	PackageRelease int    `json:"packageRelease,omitempty"`
+	// AssetChecksum is the checksum of the downloaded bundle, formatted as "sha256:<hex>"
+	AssetChecksum string `json:"assetChecksum,omitempty"`
+	// VerifiedBy is the hostname of the master that downloaded the bundle and computed its checksum
+	VerifiedBy  string    `json:"verifiedBy"`
+	InstalledAt time.Time `json:"installedAt"`
+	// ApprovalTicket is supplied through the API after the version was installed
+	ApprovalTicket string `json:"approvalTicket,omitempty"`
+}
+
+// withProvenance returns a context recording the checksum of the downloaded bundle into provenance
+func withProvenance(ctx context.Context, provenance *Provenance) context.Context {
+	return downloader.WithDigestRecorder(ctx, func(digest string) {
+		provenance.AssetChecksum = digest
+	})
+}
+
+// newProvenance describes the version downloaded from bundleURL, the package source is asked for the
+// origin of versions not installed from a direct bundle
+func (um *Client) newProvenance(version string, bundleURL *url.URL) *Provenance {
+	redacted := *bundleURL
+	redacted.User = nil
+	provenance := &Provenance{
+		Version:     version,
+		BundleURL:   redacted.String(),
+		InstalledAt: time.Now().UTC(),
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		provenance.VerifiedBy = hostname
+	}
+	if _, direct := um.directBundle(version); direct {
+		return provenance
+	}
+	provenance.PackageName = um.packageOf(version)
+	if source, ok := um.Source.(PackageOrigins); ok {
+		scm, release, err := source.PackageOrigin(provenance.PackageName, version)
+		if err != nil {
+			logrus.WithError(err).WithField("version", version).Warn("Could not get the package origin of the version")
+		}
+		provenance.SourceRepo = scm
+		provenance.PackageRelease = release
+	}
+	return provenance
+}
+
+// writeProvenance writes the provenance into the version directory, replacing the file atomically
+func (um *Client) writeProvenance(versionPath string, provenance *Provenance) error {
+	data, err := json.Marshal(provenance)
+	if err != nil {
+		return err
+	}
+	tmpPath := path.Join(versionPath, "."+provenanceFileName+".tmp")
+	if err := afero.WriteFile(um.Fs, tmpPath, data, readOnlyFileMode); err != nil {
+		return err
+	}
+	if err := um.Fs.Rename(tmpPath, path.Join(versionPath, provenanceFileName)); err != nil {
+		um.Fs.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// VersionProvenance returns the provenance recorded when the version was installed
+func (um *Client) VersionProvenance(version string) (*Provenance, error) {
+	data, err := afero.ReadFile(um.Fs, path.Join(um.Config.VersionsRoot(), version, provenanceFileName))
+	if err != nil {
+		return nil, ErrProvenanceNotFound
+	}
+	var provenance Provenance
+	if err := json.Unmarshal(data, &provenance); err != nil {
+		logrus.WithError(err).WithField("version", version).Warn("Could not parse the provenance of the version")
+		return nil, ErrProvenanceNotFound
+	}
+	return &provenance, nil
+}
+
+// SetApprovalTicket records the approval ticket in the provenance of the version, an empty ticket removes it
+func (um *Client) SetApprovalTicket(version, ticket string) (*Provenance, error) {
+	um.Lock()
+	defer um.Unlock()
+
+	provenance, err := um.VersionProvenance(version)
+	if err != nil {
+		return nil, err
+	}
+	if err := um.checkWritable(); err != nil {
+		return nil, err
+	}
+	provenance.ApprovalTicket = ticket
+
+	// the directory of an immutable version is read-only, the files of the version are not touched
+	versionPath := path.Join(um.Config.VersionsRoot(), version)
+	info, err := um.Fs.Stat(versionPath)
+	if err != nil {
+		return nil, ErrProvenanceNotFound
+	}
+	if err := um.Fs.Chmod(versionPath, writableDirMode); err != nil {
+		logrus.WithError(err).WithField("version", version).Error("Could not make the version directory writable")
+		return nil, ErrWritingProvenance
+	}
+	defer um.Fs.Chmod(versionPath, info.Mode().Perm())
+	if err := um.writeProvenance(versionPath, provenance); err != nil {
+		logrus.WithError(err).WithField("version", version).Error("Could not write the provenance of the version")
+		return nil, ErrWritingProvenance
+	}
+	logrus.WithFields(logrus.Fields{"version": version, "ticket": ticket}).Info("Recorded approval ticket of the version")
+	return provenance, nil
+}
@@ -0,0 +1,143 @@
+package updatemanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// quarantineDirName is the directory below versions-root partially-written versions are moved to,
+// entries are named <unix nano timestamp>-<version> and the reason is kept in <entry>.reason
+const quarantineDirName = ".quarantine"
+
+// Reasons a version is quarantined
+const (
+	QuarantineMissingIndex    = "missing dist/index.html"
+	QuarantineMissingManifest = "missing manifest"
+	QuarantineInvalidManifest = "invalid manifest"
+)
+
+// QuarantinedVersion is a partially-written version kept for inspection, it is never served
+type QuarantinedVersion struct {
+	Version       string    `json:"version"`
+	QuarantinedAt time.Time `json:"quarantinedAt"`
+	Reason        string    `json:"reason"`
+}
+
+func (um *Client) quarantinePath() string {
+	return path.Join(um.Config.VersionsRoot(), quarantineDirName)
+}
+
+// incompleteReason returns why the version directory is not a completely installed version,
+// empty if it is complete
+func (um *Client) incompleteReason(version string) string {
+	versionPath := path.Join(um.Config.VersionsRoot(), version)
+	if exists, _ := afero.Exists(um.Fs, path.Join(versionPath, "dist", "index.html")); !exists {
+		return QuarantineMissingIndex
+	}
+	data, err := afero.ReadFile(um.Fs, path.Join(versionPath, manifestFileName))
+	if err != nil {
+		return QuarantineMissingManifest
+	}
+	var manifest versionManifest
+	if err := json.Unmarshal(data, &manifest); err != nil || manifest.Version != version {
+		return QuarantineInvalidManifest
+	}
+	return ""
+}
+
+// QuarantinePartialVersions moves the versions lacking dist/index.html or a valid manifest,
+// left behind by a crash during an install, out of versions-root. The served version is only reported.
+func (um *Client) QuarantinePartialVersions(servedVersion string) ([]QuarantinedVersion, error) {
+	entries, err := afero.ReadDir(um.Fs, um.Config.VersionsRoot())
+	if err != nil {
+		logrus.WithError(err).Error("Unable to read versions-root.")
+		return nil, ErrReadingVersions
+	}
+
+	quarantined := []QuarantinedVersion{}
+	for _, info := range entries {
+		version := info.Name()
+		if !info.IsDir() || version == trashDirName || version == quarantineDirName {
+			continue
+		}
+		reason := um.incompleteReason(version)
+		if reason == "" {
+			continue
+		}
+		logger := logrus.WithFields(logrus.Fields{"version": version, "reason": reason})
+		if version == servedVersion {
+			logger.Warn("Served version is incomplete, it is not quarantined while it is served")
+			continue
+		}
+		entry, err := um.quarantineVersion(version, reason)
+		if err != nil {
+			logger.WithError(err).Error("Could not quarantine incomplete version")
+			continue
+		}
+		logger.Warn("Quarantined incomplete version")
+		quarantined = append(quarantined, entry)
+	}
+	return quarantined, nil
+}
+
+func (um *Client) quarantineVersion(version, reason string) (QuarantinedVersion, error) {
+	entry := QuarantinedVersion{Version: version, QuarantinedAt: time.Now().UTC(), Reason: reason}
+	if err := um.Fs.MkdirAll(um.quarantinePath(), writableDirMode); err != nil {
+		return entry, errors.Wrap(err, "could not create quarantine directory")
+	}
+	name := fmt.Sprintf("%d-%s", entry.QuarantinedAt.UnixNano(), version)
+	if err := um.Fs.Rename(path.Join(um.Config.VersionsRoot(), version), path.Join(um.quarantinePath(), name)); err != nil {
+		return entry, err
+	}
+	if err := afero.WriteFile(um.Fs, path.Join(um.quarantinePath(), name+".reason"), []byte(reason), writableFileMode); err != nil {
+		logrus.WithError(err).WithField("version", version).Warn("Could not record the quarantine reason")
+	}
+	return entry, nil
+}
+
+// QuarantinedVersions lists the quarantined versions, the most recently quarantined version first
+func (um *Client) QuarantinedVersions() ([]QuarantinedVersion, error) {
+	entries, err := afero.ReadDir(um.Fs, um.quarantinePath())
+	if os.IsNotExist(err) {
+		return []QuarantinedVersion{}, nil
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Unable to read the quarantine directory.")
+		return nil, ErrReadingVersions
+	}
+
+	quarantined := []QuarantinedVersion{}
+	for _, info := range entries {
+		if !info.IsDir() {
+			continue
+		}
+		parts := strings.SplitN(info.Name(), "-", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		nanos, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		reason, _ := afero.ReadFile(um.Fs, path.Join(um.quarantinePath(), info.Name()+".reason"))
+		quarantined = append(quarantined, QuarantinedVersion{
+			Version:       parts[1],
+			QuarantinedAt: time.Unix(0, nanos).UTC(),
+			Reason:        string(reason),
+		})
+	}
+	sort.Slice(quarantined, func(i, j int) bool {
+		return quarantined[i].QuarantinedAt.After(quarantined[j].QuarantinedAt)
+	})
+	return quarantined, nil
+}
@@ -0,0 +1,88 @@
+package updatemanager
+
+import (
+	"path"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/spf13/afero"
+)
+
+func writeManifest(t *testing.T, versionPath, content string) {
+	if err := afero.WriteFile(afero.NewOsFs(), path.Join(versionPath, manifestFileName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientQuarantinePartialVersions(t *testing.T) {
+	t.Run("quarantines versions lacking index.html or a valid manifest", func(t *testing.T) {
+		defer tearDown(t)
+		um := newTrashClient()
+		root := "../testdata/um-sandbox/ui-versions"
+		writeVersionFile(t, path.Join(root, "2.25.2"), 10)
+		writeManifest(t, path.Join(root, "2.25.2"), `{"version":"2.25.2","immutable":true}`)
+		writeVersionFile(t, path.Join(root, "2.25.3"), 10)
+		writeVersionFile(t, path.Join(root, "2.25.4"), 10)
+		writeManifest(t, path.Join(root, "2.25.4"), `{"version":`)
+		afero.NewOsFs().MkdirAll(path.Join(root, "2.25.5", "dist"), 0755)
+
+		quarantined, err := um.QuarantinePartialVersions("")
+
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(len(quarantined), 3)
+		exists, _ := afero.DirExists(um.Fs, path.Join(root, "2.25.2"))
+		tests.H(t).BoolEql(exists, true)
+		for _, version := range []string{"2.25.3", "2.25.4", "2.25.5"} {
+			exists, _ := afero.DirExists(um.Fs, path.Join(root, version))
+			tests.H(t).BoolEqlWithMessage(exists, false, version+" should be quarantined")
+		}
+
+		listed, err := um.QuarantinedVersions()
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(len(listed), 3)
+		reasons := map[string]string{}
+		for _, entry := range listed {
+			reasons[entry.Version] = entry.Reason
+		}
+		tests.H(t).StringEql(reasons["2.25.3"], QuarantineMissingManifest)
+		tests.H(t).StringEql(reasons["2.25.4"], QuarantineInvalidManifest)
+		tests.H(t).StringEql(reasons["2.25.5"], QuarantineMissingIndex)
+	})
+
+	t.Run("does not quarantine the served version", func(t *testing.T) {
+		defer tearDown(t)
+		um := newTrashClient()
+		writeVersionFile(t, "../testdata/um-sandbox/ui-versions/2.25.3", 10)
+
+		quarantined, err := um.QuarantinePartialVersions("2.25.3")
+
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(len(quarantined), 0)
+		exists, _ := afero.DirExists(um.Fs, "../testdata/um-sandbox/ui-versions/2.25.3")
+		tests.H(t).BoolEql(exists, true)
+	})
+
+	t.Run("does not remove the quarantine as a version", func(t *testing.T) {
+		defer tearDown(t)
+		um := newTrashClient()
+		writeVersionFile(t, "../testdata/um-sandbox/ui-versions/2.25.3", 10)
+		um.QuarantinePartialVersions("")
+
+		tests.H(t).ErrEql(um.RemoveVersion(quarantineDirName), ErrRequestedVersionNotFound)
+
+		listed, _ := um.QuarantinedVersions()
+		tests.H(t).IntEql(len(listed), 1)
+	})
+
+	t.Run("keeps quarantined versions when removing all other versions", func(t *testing.T) {
+		defer tearDown(t)
+		um := newTrashClient()
+		writeVersionFile(t, "../testdata/um-sandbox/ui-versions/2.25.3", 10)
+		um.QuarantinePartialVersions("")
+
+		tests.H(t).IsNil(um.RemoveAllVersionsExcept(""))
+
+		listed, _ := um.QuarantinedVersions()
+		tests.H(t).IntEql(len(listed), 1)
+	})
+}
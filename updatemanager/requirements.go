@@ -0,0 +1,35 @@
+package updatemanager
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// MinDcosReleaseVersion returns the minimum DC/OS release required by version, empty if it has none or
+// the package source does not provide requirements. Results are cached, so the package source is asked
+// once per version.
+func (um *Client) MinDcosReleaseVersion(version string) (string, error) {
+	source, ok := um.Source.(PackageRequirements)
+	if !ok {
+		return "", nil
+	}
+
+	um.requirementsLock.Lock()
+	minVersion, cached := um.requirements[version]
+	um.requirementsLock.Unlock()
+	if cached {
+		return minVersion, nil
+	}
+
+	minVersion, err := source.MinDcosReleaseVersion(um.packageOf(version), version)
+	if err != nil {
+		logrus.WithError(err).WithField("version", version).Error("Package source describe request failed")
+		return "", ErrCosmosRequestFailure
+	}
+	um.requirementsLock.Lock()
+	if um.requirements == nil {
+		um.requirements = make(map[string]string)
+	}
+	um.requirements[version] = minVersion
+	um.requirementsLock.Unlock()
+	return minVersion, nil
+}
@@ -0,0 +1,35 @@
+package updatemanager
+
+import (
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestClientMinDcosReleaseVersion(t *testing.T) {
+	t.Run("asks the package source once per version", func(t *testing.T) {
+		cfg, _ := config.Parse(nil)
+		source := &FakePackageSource{MinDcos: map[string]string{"2.25.0": "1.13"}}
+		loader := Client{Source: source, Config: cfg}
+
+		first, err := loader.MinDcosReleaseVersion("2.25.0")
+		tests.H(t).ErrEql(err, nil)
+		second, err := loader.MinDcosReleaseVersion("2.25.0")
+		tests.H(t).ErrEql(err, nil)
+
+		tests.H(t).StringEql(first, "1.13")
+		tests.H(t).StringEql(second, "1.13")
+		tests.H(t).IntEql(source.DescribeCalls, 1)
+	})
+
+	t.Run("returns no requirement for sources without requirements", func(t *testing.T) {
+		cfg, _ := config.Parse(nil)
+		loader := Client{Source: &DirectURLSource{}, Config: cfg}
+
+		minVersion, err := loader.MinDcosReleaseVersion("2.25.0")
+
+		tests.H(t).ErrEql(err, nil)
+		tests.H(t).StringEql(minVersion, "")
+	})
+}
@@ -0,0 +1,75 @@
+package updatemanager
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// keptVersion is a version directory in versions-root with the time it was last activated,
+// the zero time if it has no manifest
+type keptVersion struct {
+	version     string
+	activatedAt time.Time
+}
+
+// keptVersions lists the version directories except the served version, the most recently activated first
+func (um *Client) keptVersions(servedVersion string) ([]keptVersion, error) {
+	entries, err := afero.ReadDir(um.Fs, um.Config.VersionsRoot())
+	if err != nil {
+		logrus.WithError(err).Error("Unable to read versions-root.")
+		return nil, ErrReadingVersions
+	}
+	kept := []keptVersion{}
+	for _, info := range entries {
+		name := info.Name()
+		if !info.IsDir() || name == servedVersion || name == trashDirName || name == quarantineDirName {
+			continue
+		}
+		entry := keptVersion{version: name}
+		if data, err := afero.ReadFile(um.Fs, path.Join(um.Config.VersionsRoot(), name, manifestFileName)); err == nil {
+			var manifest versionManifest
+			if json.Unmarshal(data, &manifest) == nil {
+				entry.activatedAt = manifest.ActivatedAt
+			}
+		}
+		kept = append(kept, entry)
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].activatedAt.After(kept[j].activatedAt)
+	})
+	return kept, nil
+}
+
+// PruneVersions removes all versions except the served version, the labeled versions and the most recently
+// activated versions-to-keep versions, which can be rolled back to without downloading them again
+func (um *Client) PruneVersions(servedVersion string) error {
+	kept, err := um.keptVersions(servedVersion)
+	if err != nil {
+		return err
+	}
+	keep := um.Config.VersionsToKeep()
+	for i, entry := range kept {
+		if i < keep && !entry.activatedAt.IsZero() {
+			logrus.WithField("version", entry.version).Debug("Keeping previous version for rollbacks")
+			continue
+		}
+		if um.labeled(entry.version) {
+			logrus.WithField("version", entry.version).Debug("Keeping labeled version")
+			continue
+		}
+		if err := um.RemoveVersion(entry.version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reusableVersion is true if the version is kept in versions-root and unchanged since it was activated
+func (um *Client) reusableVersion(version string) bool {
+	return um.VerifyVersion(version) == nil
+}
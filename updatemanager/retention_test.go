@@ -0,0 +1,87 @@
+package updatemanager
+
+import (
+	"path"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/spf13/afero"
+)
+
+func newRetentionClient(t *testing.T, args ...string) (*Client, *FakeBundleFetcher) {
+	cfg, _ := config.Parse(append([]string{
+		"--versions-root", "../testdata/um-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+		"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+		"--trash-retention", "0",
+	}, args...))
+	return newFakeFetcherClient(cfg, afero.NewOsFs())
+}
+
+// writeActivatedVersion creates a version which was served before and is marked immutable
+func writeActivatedVersion(t *testing.T, um *Client, version string) {
+	writeVersionFile(t, path.Join(um.Config.VersionsRoot(), version), 10)
+	if err := um.markImmutable(version); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientVersionRetention(t *testing.T) {
+	t.Run("keeps the previously served version after an update", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		um, _ := newRetentionClient(t)
+		writeActivatedVersion(t, um, "2.25.1")
+
+		tests.H(t).IsNil(um.UpdateToVersion("2.25.2", &fakeActivator{}))
+
+		exists, _ := afero.DirExists(um.Fs, path.Join(um.Config.VersionsRoot(), "2.25.1"))
+		tests.H(t).BoolEqlWithMessage(exists, true, "Expected the previous version to be kept")
+	})
+
+	t.Run("removes the previously served version if no versions are kept", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		um, _ := newRetentionClient(t, "--versions-to-keep", "0")
+		writeActivatedVersion(t, um, "2.25.1")
+
+		tests.H(t).IsNil(um.UpdateToVersion("2.25.2", &fakeActivator{}))
+
+		exists, _ := afero.DirExists(um.Fs, path.Join(um.Config.VersionsRoot(), "2.25.1"))
+		tests.H(t).BoolEqlWithMessage(exists, false, "Expected the previous version to be removed")
+	})
+
+	t.Run("activates a kept version without downloading it", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		um, fetcher := newRetentionClient(t)
+		writeActivatedVersion(t, um, "2.25.2")
+		var calls []string
+
+		tests.H(t).IsNil(um.UpdateToVersion("2.25.2", &fakeActivator{calls: &calls}))
+
+		tests.H(t).IntEql(len(fetcher.Downloaded), 0)
+		tests.H(t).InterfaceEql(calls, []string{"prepare", "commit"})
+		tests.H(t).IsNil(um.VerifyVersion("2.25.2"))
+	})
+
+	t.Run("prunes all but the most recently activated versions", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingDefault(t)
+		um, _ := newRetentionClient(t, "--versions-to-keep", "2")
+		for _, version := range []string{"2.25.1", "2.25.2", "2.25.3"} {
+			writeActivatedVersion(t, um, version)
+		}
+		writeVersionFile(t, path.Join(um.Config.VersionsRoot(), "2.25.4"), 10)
+
+		tests.H(t).IsNil(um.PruneVersions(""))
+
+		entries, _ := afero.ReadDir(um.Fs, um.Config.VersionsRoot())
+		var remaining []string
+		for _, entry := range entries {
+			remaining = append(remaining, entry.Name())
+		}
+		tests.H(t).InterfaceEql(remaining, []string{"2.25.2", "2.25.3"})
+	})
+}
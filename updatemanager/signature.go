@@ -0,0 +1,69 @@
+package updatemanager
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/dcos/dcos-ui-update-service/downloader"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrBundleSignatureNotFound occurs if signature keys are configured but the signature of the bundle cannot be fetched
+	ErrBundleSignatureNotFound = errors.New("E_SIGNATURE_INVALID: the bundle signature could not be found")
+	// ErrBundleSignatureInvalid occurs if the bundle signature does not match the bundle with any trusted key
+	ErrBundleSignatureInvalid = errors.New("E_SIGNATURE_INVALID: the bundle signature does not match any trusted key")
+)
+
+// SignatureSource is implemented by package sources publishing the detached signatures of the bundles
+type SignatureSource interface {
+	ResolveSignature(packageName string, version string) (*url.URL, error)
+}
+
+// withSignatureVerification returns a context verifying the bundle of version against its detached
+// signature before it is unpacked, ctx is returned unchanged without signature keys. Fails with
+// ErrBundleSignatureNotFound if the signature cannot be fetched.
+func (um *Client) withSignatureVerification(ctx context.Context, version string, bundleURL *url.URL) (context.Context, error) {
+	if um.Verifier == nil {
+		return ctx, nil
+	}
+	log := logrus.WithFields(logrus.Fields{"version": version, "bundle": bundleURL.String()})
+	signatureURL, err := um.signatureURL(version, bundleURL)
+	if err != nil {
+		log.WithError(err).Error("Could not resolve the bundle signature")
+		return ctx, ErrBundleSignatureNotFound
+	}
+	signature, err := um.Fetcher.Fetch(ctx, signatureURL)
+	if err != nil {
+		log.WithError(err).WithField("signature", signatureURL.String()).Error("Could not fetch the bundle signature")
+		return ctx, ErrBundleSignatureNotFound
+	}
+	return downloader.WithVerifier(ctx, func(r io.Reader) error {
+		if err := um.Verifier.Verify(r, signature); err != nil {
+			log.WithError(err).WithField("signature", signatureURL.String()).Error("Bundle signature verification failed")
+			return ErrBundleSignatureInvalid
+		}
+		log.WithField("signature", signatureURL.String()).Info("Verified the bundle signature")
+		return nil
+	}), nil
+}
+
+// signatureURL is the configured signature URL pattern expanded for version and bundleURL, without a pattern
+// the signature published by the package source
+func (um *Client) signatureURL(version string, bundleURL *url.URL) (*url.URL, error) {
+	if pattern := um.Config.SignatureURL(); pattern != "" {
+		replacer := strings.NewReplacer("{version}", url.PathEscape(version), "{bundle}", bundleURL.String())
+		return url.Parse(replacer.Replace(pattern))
+	}
+	if _, direct := um.directBundle(version); direct {
+		return nil, errors.New("direct bundles have no package source signature, signature-url is not configured")
+	}
+	source, ok := um.Source.(SignatureSource)
+	if !ok {
+		return nil, errors.New("the package source publishes no signatures, signature-url is not configured")
+	}
+	return source.ResolveSignature(um.PackageName(version), version)
+}
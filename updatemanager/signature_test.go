@@ -0,0 +1,128 @@
+package updatemanager
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/signature"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/spf13/afero"
+)
+
+const signedArchive = "signed dcos-ui bundle"
+
+// newSigningKey writes the public key of a new ECDSA key to dir and returns its file and the base64 signature
+// of signedArchive
+func newSigningKey(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "cosign.pub")
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(signedArchive))
+	sig, err := key.Sign(rand.Reader, digest[:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyFile, base64.StdEncoding.EncodeToString(sig)
+}
+
+func newSignatureClient(t *testing.T, args ...string) (*Client, *FakeBundleFetcher, afero.Fs) {
+	cfg, err := config.Parse(append([]string{
+		"--versions-root", "../testdata/um-sandbox/ui-versions",
+		"--ui-dist-symlink", "../testdata/um-sandbox/dcos-ui-dist",
+		"--default-ui-path", "../testdata/um-sandbox/dcos-ui",
+	}, args...))
+	tests.H(t).IsNil(err)
+	fs := afero.NewOsFs()
+	loader, fetcher := newFakeFetcherClient(cfg, fs)
+	fetcher.Archive = signedArchive
+	loader.Verifier, err = signature.LoadKeys(cfg.SignatureKeys())
+	tests.H(t).IsNil(err)
+	return loader, fetcher, fs
+}
+
+func TestClientSignatureVerification(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signature-keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile, bundleSignature := newSigningKey(t, dir)
+
+	t.Run("unpacks a bundle matching the signature of the configured URL pattern", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		loader, fetcher, fs := newSignatureClient(t,
+			"--signature-keys", keyFile,
+			"--signature-url", "https://downloads.example.com/dcos-ui/{version}.sig",
+		)
+		fetcher.Fetched = map[string]string{"https://downloads.example.com/dcos-ui/2.25.2.sig": bundleSignature}
+
+		err := loader.UpdateToVersion("2.25.2", &fakeActivator{})
+
+		tests.H(t).IsNil(err)
+		tests.H(t).InterfaceEql(fetcher.FetchCalls, []string{"https://downloads.example.com/dcos-ui/2.25.2.sig"})
+		exists, _ := afero.DirExists(fs, path.Join(loader.Config.VersionsRoot(), "2.25.2"))
+		tests.H(t).BoolEql(exists, true)
+	})
+
+	t.Run("fetches the signature published by the package source without URL pattern", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		loader, fetcher, _ := newSignatureClient(t, "--signature-keys", keyFile)
+		loader.Source.(*FakePackageSource).Signature, _ = url.Parse("https://downloads.example.com/dcos-ui/release.tar.gz.sig")
+		fetcher.Fetched = map[string]string{"https://downloads.example.com/dcos-ui/release.tar.gz.sig": bundleSignature}
+
+		err := loader.UpdateToVersion("2.25.2", &fakeActivator{})
+
+		tests.H(t).IsNil(err)
+	})
+
+	t.Run("returns ErrBundleSignatureInvalid and removes the version if the signature does not match", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		loader, fetcher, fs := newSignatureClient(t,
+			"--signature-keys", keyFile,
+			"--signature-url", "{bundle}.sig",
+		)
+		fetcher.Archive = "tampered dcos-ui bundle"
+		fetcher.Fetched = map[string]string{"https://downloads.example.com/dcos-ui/release.tar.gz.sig": bundleSignature}
+
+		err := loader.UpdateToVersion("2.25.2", &fakeActivator{})
+
+		tests.H(t).ErrEql(err, ErrBundleSignatureInvalid)
+		exists, _ := afero.DirExists(fs, path.Join(loader.Config.VersionsRoot(), "2.25.2"))
+		tests.H(t).BoolEql(exists, false)
+	})
+
+	t.Run("returns ErrBundleSignatureNotFound without downloading if the signature is missing", func(t *testing.T) {
+		defer tearDown(t)
+		setupServingSpecificVersion(t, "2.25.1")
+		loader, fetcher, _ := newSignatureClient(t, "--signature-keys", keyFile)
+
+		err := loader.UpdateToVersion("2.25.2", &fakeActivator{})
+
+		tests.H(t).ErrEql(err, ErrBundleSignatureNotFound)
+		tests.H(t).IntEql(len(fetcher.Downloaded), 0)
+	})
+}
@@ -0,0 +1,168 @@
+package updatemanager
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// trashDirName is the directory below versions-root removed versions are moved to,
+// entries are named <unix nano timestamp>-<version>
+const trashDirName = ".trash"
+
+var (
+	// ErrVersionNotTrashed occurs if a version to restore is not in the trash
+	ErrVersionNotTrashed = errors.New("The version is not in the trash")
+	// ErrVersionAlreadyExists occurs if a version is restored while it is also stored in versions-root
+	ErrVersionAlreadyExists = errors.New("The version already exists in versions-root")
+	// ErrRestoringVersion occurs if moving a version out of the trash fails
+	ErrRestoringVersion = errors.New("Failed to restore the version from the trash")
+)
+
+// TrashedVersion is a removed version kept in the trash
+type TrashedVersion struct {
+	Version   string    `json:"version"`
+	TrashedAt time.Time `json:"trashedAt"`
+	Size      int64     `json:"size"`
+	name      string
+}
+
+func (um *Client) trashPath() string {
+	return path.Join(um.Config.VersionsRoot(), trashDirName)
+}
+
+// trashEnabled is false if removed versions are deleted immediately
+func (um *Client) trashEnabled() bool {
+	return um.Config.TrashRetention() > 0
+}
+
+// trashVersion moves the version directory into the trash
+func (um *Client) trashVersion(version, versionPath string) error {
+	if err := um.Fs.MkdirAll(um.trashPath(), writableDirMode); err != nil {
+		return errors.Wrap(err, "could not create trash directory")
+	}
+	name := fmt.Sprintf("%d-%s", time.Now().UTC().UnixNano(), version)
+	return um.Fs.Rename(versionPath, path.Join(um.trashPath(), name))
+}
+
+// TrashedVersions lists the versions in the trash, the most recently removed version first
+func (um *Client) TrashedVersions() ([]TrashedVersion, error) {
+	entries, err := afero.ReadDir(um.Fs, um.trashPath())
+	if os.IsNotExist(err) {
+		return []TrashedVersion{}, nil
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Unable to read the trash directory.")
+		return nil, ErrReadingVersions
+	}
+
+	trashed := []TrashedVersion{}
+	for _, info := range entries {
+		if !info.IsDir() {
+			continue
+		}
+		parts := strings.SplitN(info.Name(), "-", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		nanos, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		trashed = append(trashed, TrashedVersion{
+			Version:   parts[1],
+			TrashedAt: time.Unix(0, nanos).UTC(),
+			Size:      um.treeSize(path.Join(um.trashPath(), info.Name())),
+			name:      info.Name(),
+		})
+	}
+	sort.Slice(trashed, func(i, j int) bool {
+		return trashed[i].TrashedAt.After(trashed[j].TrashedAt)
+	})
+	return trashed, nil
+}
+
+// RestoreVersion moves the most recently removed copy of the version back to versions-root
+func (um *Client) RestoreVersion(version string) error {
+	trashed, err := um.TrashedVersions()
+	if err != nil {
+		return err
+	}
+	for _, entry := range trashed {
+		if entry.Version != version {
+			continue
+		}
+		versionPath := path.Join(um.Config.VersionsRoot(), version)
+		if exists, _ := afero.Exists(um.Fs, versionPath); exists {
+			return ErrVersionAlreadyExists
+		}
+		if err := um.Fs.Rename(path.Join(um.trashPath(), entry.name), versionPath); err != nil {
+			logrus.WithError(err).WithField("version", version).Error("Could not move version out of the trash.")
+			return ErrRestoringVersion
+		}
+		// versions activated before keep their manifest and are made read-only again
+		if exists, _ := afero.Exists(um.Fs, path.Join(versionPath, manifestFileName)); exists {
+			if err := um.chmodTree(versionPath, readOnlyDirMode, readOnlyFileMode); err != nil {
+				logrus.WithError(err).WithField("version", version).Warn("Could not make restored version read-only")
+			}
+		}
+		logrus.WithFields(logrus.Fields{"version": version, "trashedAt": entry.TrashedAt}).Info("Restored version from the trash")
+		return nil
+	}
+	return ErrVersionNotTrashed
+}
+
+// PurgeTrash deletes the versions kept longer than the trash retention, then the oldest versions
+// until the trash is not larger than the maximum trash size
+func (um *Client) PurgeTrash() error {
+	trashed, err := um.TrashedVersions()
+	if err != nil {
+		return err
+	}
+	retention := um.Config.TrashRetention()
+	maxSize := um.Config.TrashMaxSize()
+	var size int64
+	for _, entry := range trashed {
+		size += entry.Size
+	}
+
+	// oldest first
+	for i := len(trashed) - 1; i >= 0; i-- {
+		entry := trashed[i]
+		expired := time.Since(entry.TrashedAt) > retention
+		oversized := maxSize > 0 && size > maxSize
+		if !expired && !oversized {
+			continue
+		}
+		if err := um.Fs.RemoveAll(path.Join(um.trashPath(), entry.name)); err != nil {
+			logrus.WithError(err).WithField("version", entry.Version).Error("Could not purge version from the trash.")
+			return ErrRemovingVersion
+		}
+		size -= entry.Size
+		logrus.WithFields(logrus.Fields{
+			"version":   entry.Version,
+			"trashedAt": entry.TrashedAt,
+			"expired":   expired,
+		}).Info("Purged version from the trash")
+	}
+	return nil
+}
+
+func (um *Client) treeSize(root string) int64 {
+	var size int64
+	afero.Walk(um.Fs, root, func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
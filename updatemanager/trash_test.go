@@ -0,0 +1,136 @@
+package updatemanager
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/spf13/afero"
+)
+
+func newTrashClient(args ...string) *Client {
+	cfg, _ := config.Parse(append([]string{
+		"--versions-root", "../testdata/um-sandbox/ui-versions",
+	}, args...))
+	return &Client{
+		Config: cfg,
+		Fs:     afero.NewOsFs(),
+	}
+}
+
+func writeVersionFile(t *testing.T, versionPath string, size int) {
+	os.MkdirAll(path.Join(versionPath, "dist"), 0755)
+	if err := afero.WriteFile(afero.NewOsFs(), path.Join(versionPath, "dist", "index.html"), make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func trashEntry(um *Client, version string, trashedAt time.Time) string {
+	return path.Join(um.trashPath(), fmt.Sprintf("%d-%s", trashedAt.UnixNano(), version))
+}
+
+func TestClientTrash(t *testing.T) {
+	t.Run("moves removed versions to the trash", func(t *testing.T) {
+		defer tearDown(t)
+		um := newTrashClient()
+		versionPath := "../testdata/um-sandbox/ui-versions/2.25.3"
+		writeVersionFile(t, versionPath, 10)
+
+		tests.H(t).IsNil(um.RemoveVersion("2.25.3"))
+
+		exists, _ := afero.DirExists(um.Fs, versionPath)
+		tests.H(t).BoolEql(exists, false)
+		trashed, err := um.TrashedVersions()
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(len(trashed), 1)
+		tests.H(t).StringEql(trashed[0].Version, "2.25.3")
+		tests.H(t).IntEql(int(trashed[0].Size), 10)
+	})
+
+	t.Run("does not remove the trash as a version", func(t *testing.T) {
+		defer tearDown(t)
+		um := newTrashClient()
+		writeVersionFile(t, "../testdata/um-sandbox/ui-versions/2.25.3", 10)
+		tests.H(t).IsNil(um.RemoveVersion("2.25.3"))
+
+		tests.H(t).ErrEql(um.RemoveVersion(trashDirName), ErrRequestedVersionNotFound)
+
+		trashed, _ := um.TrashedVersions()
+		tests.H(t).IntEql(len(trashed), 1)
+	})
+
+	t.Run("does not remove the trash with all other versions", func(t *testing.T) {
+		defer tearDown(t)
+		um := newTrashClient()
+		writeVersionFile(t, "../testdata/um-sandbox/ui-versions/2.25.3", 10)
+		writeVersionFile(t, "../testdata/um-sandbox/ui-versions/2.25.4", 10)
+
+		tests.H(t).IsNil(um.RemoveAllVersionsExcept(""))
+		tests.H(t).IsNil(um.RemoveAllVersionsExcept(""))
+
+		trashed, _ := um.TrashedVersions()
+		tests.H(t).IntEql(len(trashed), 2)
+	})
+
+	t.Run("restores the most recently removed version", func(t *testing.T) {
+		defer tearDown(t)
+		um := newTrashClient()
+		writeVersionFile(t, trashEntry(um, "2.25.3", time.Now().Add(-time.Hour)), 10)
+		writeVersionFile(t, trashEntry(um, "2.25.3", time.Now()), 20)
+
+		tests.H(t).IsNil(um.RestoreVersion("2.25.3"))
+
+		info, err := os.Stat("../testdata/um-sandbox/ui-versions/2.25.3/dist/index.html")
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(int(info.Size()), 20)
+		trashed, _ := um.TrashedVersions()
+		tests.H(t).IntEql(len(trashed), 1)
+	})
+
+	t.Run("returns ErrVersionNotTrashed for an unknown version", func(t *testing.T) {
+		defer tearDown(t)
+		um := newTrashClient()
+
+		tests.H(t).ErrEql(um.RestoreVersion("2.25.3"), ErrVersionNotTrashed)
+	})
+
+	t.Run("returns ErrVersionAlreadyExists if the version was downloaded again", func(t *testing.T) {
+		defer tearDown(t)
+		um := newTrashClient()
+		writeVersionFile(t, "../testdata/um-sandbox/ui-versions/2.25.3", 10)
+		writeVersionFile(t, trashEntry(um, "2.25.3", time.Now()), 10)
+
+		tests.H(t).ErrEql(um.RestoreVersion("2.25.3"), ErrVersionAlreadyExists)
+	})
+
+	t.Run("purges expired versions", func(t *testing.T) {
+		defer tearDown(t)
+		um := newTrashClient("--trash-retention", "1h")
+		writeVersionFile(t, trashEntry(um, "2.25.2", time.Now().Add(-2*time.Hour)), 10)
+		writeVersionFile(t, trashEntry(um, "2.25.3", time.Now()), 10)
+
+		tests.H(t).IsNil(um.PurgeTrash())
+
+		trashed, _ := um.TrashedVersions()
+		tests.H(t).IntEql(len(trashed), 1)
+		tests.H(t).StringEql(trashed[0].Version, "2.25.3")
+	})
+
+	t.Run("purges the oldest versions once the trash is too large", func(t *testing.T) {
+		defer tearDown(t)
+		um := newTrashClient("--trash-max-size", "25")
+		writeVersionFile(t, trashEntry(um, "2.25.1", time.Now().Add(-3*time.Minute)), 10)
+		writeVersionFile(t, trashEntry(um, "2.25.2", time.Now().Add(-2*time.Minute)), 10)
+		writeVersionFile(t, trashEntry(um, "2.25.3", time.Now().Add(-time.Minute)), 10)
+
+		tests.H(t).IsNil(um.PurgeTrash())
+
+		trashed, _ := um.TrashedVersions()
+		tests.H(t).IntEql(len(trashed), 2)
+		tests.H(t).StringEql(trashed[1].Version, "2.25.2")
+	})
+}
@@ -0,0 +1,115 @@
+package zookeeper
+
+import (
+	"bytes"
+	"path"
+	"strings"
+
+	"github.com/dcos/dcos-ui-update-service/config"
+	"github.com/pkg/errors"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+var (
+	// BootstrapNodes are the nodes created below the base path when bootstrapping the ZK subtree
+	BootstrapNodes = []string{"version", "version-trigger", "cluster-status", "node-status"}
+
+	// ErrBootstrapAccessCheckFailed occurs if the bootstrapped subtree cannot be written to and read back
+	ErrBootstrapAccessCheckFailed = errors.New("could not verify read/write access to the ZK base path")
+
+	// bootstrapCheckNode is the prefix of the sequential check node, each master checks its own node
+	bootstrapCheckNode  = ".bootstrap-check-"
+	bootstrapCheckValue = []byte("ok")
+)
+
+// Bootstrap connects to ZK and creates the base path and the BootstrapNodes with the
+// configured ACLs in a single transaction, then verifies read and write access.
+// Data found at the configured legacy base path is migrated afterwards.
+func Bootstrap(cfg *config.Config) error {
+	zkCfg := zkConfigFrom(cfg)
+	zkCfg.SkipBasePathInit = true
+	client, err := connect(zkCfg)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err := bootstrapTree(client.conn, client.basePath, client.acl); err != nil {
+		return err
+	}
+	if legacyBasePath := cfg.ZKLegacyBasePath(); legacyBasePath != "" {
+		return migrateBasePath(client.conn, legacyBasePath, client.basePath, client.acl)
+	}
+	return nil
+}
+
+func bootstrapTree(conn ZKConnection, basePath string, acl []zk.ACL) error {
+	err := createMissingNodes(conn, bootstrapPaths(basePath), acl)
+	if err == zk.ErrNodeExists {
+		// another master created some of the nodes concurrently, only create what is still missing
+		log.Info("ZK nodes were created concurrently while bootstrapping, retrying")
+		err = createMissingNodes(conn, bootstrapPaths(basePath), acl)
+	}
+	if err != nil {
+		return errors.Wrap(err, "could not create ZK subtree")
+	}
+	return verifyAccess(conn, path.Join(basePath, bootstrapCheckNode), acl)
+}
+
+func bootstrapPaths(basePath string) []string {
+	var paths []string
+	fullPath := ""
+	for _, node := range strings.Split(basePath, "/") {
+		if strings.TrimSpace(node) == "" {
+			continue
+		}
+		fullPath += "/" + node
+		paths = append(paths, fullPath)
+	}
+	for _, node := range BootstrapNodes {
+		paths = append(paths, path.Join(fullPath, node))
+	}
+	return paths
+}
+
+func createMissingNodes(conn ZKConnection, paths []string, acl []zk.ACL) error {
+	var ops []interface{}
+	for _, p := range paths {
+		exists, _, err := conn.Exists(p)
+		if err != nil {
+			return errors.Wrapf(err, "could not check if path '%s' exists", p)
+		}
+		if !exists {
+			ops = append(ops, &zk.CreateRequest{Path: p, Data: nil, Acl: acl, Flags: zkNoFlags})
+		}
+	}
+	if len(ops) == 0 {
+		log.Info("ZK subtree already exists")
+		return nil
+	}
+	if _, err := conn.Multi(ops...); err != nil {
+		return err
+	}
+	log.WithField("created", len(ops)).Info("Created ZK subtree")
+	return nil
+}
+
+func verifyAccess(conn ZKConnection, checkPrefix string, acl []zk.ACL) error {
+	checkPath, err := conn.Create(checkPrefix, nil, zk.FlagEphemeral|zk.FlagSequence, acl)
+	if err != nil {
+		return errors.Wrap(ErrBootstrapAccessCheckFailed, err.Error())
+	}
+	defer conn.Delete(checkPath, zkNoVersion)
+
+	if _, err := conn.Set(checkPath, bootstrapCheckValue, zkNoVersion); err != nil {
+		return errors.Wrap(ErrBootstrapAccessCheckFailed, err.Error())
+	}
+	data, _, err := conn.Get(checkPath)
+	if err != nil {
+		return errors.Wrap(ErrBootstrapAccessCheckFailed, err.Error())
+	}
+	if !bytes.Equal(data, bootstrapCheckValue) {
+		return ErrBootstrapAccessCheckFailed
+	}
+	log.Info("Verified read/write access to ZK subtree")
+	return nil
+}
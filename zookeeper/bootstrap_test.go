@@ -0,0 +1,85 @@
+package zookeeper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+func TestBootstrapTree(t *testing.T) {
+	acl := []zk.ACL{{Perms: zk.PermAll, Scheme: "world", ID: "anyone"}}
+
+	t.Run("creates base path and child nodes", func(t *testing.T) {
+		conn := newMemConnection()
+
+		err := bootstrapTree(conn, "/dcos/ui-update/", acl)
+		tests.H(t).IsNil(err)
+
+		for _, p := range []string{"/dcos", "/dcos/ui-update", "/dcos/ui-update/version", "/dcos/ui-update/version-trigger", "/dcos/ui-update/cluster-status", "/dcos/ui-update/node-status"} {
+			exists, _, _ := conn.Exists(p)
+			tests.H(t).BoolEqlWithMessage(exists, true, p+" should exist")
+		}
+		tests.H(t).InterfaceEql(conn.nodes["/dcos/ui-update/version"].acl, acl)
+	})
+
+	t.Run("removes access check node", func(t *testing.T) {
+		conn := newMemConnection()
+
+		err := bootstrapTree(conn, "/dcos/ui-update/", acl)
+		tests.H(t).IsNil(err)
+
+		children, _, _ := conn.Children("/dcos/ui-update")
+		for _, child := range children {
+			tests.H(t).BoolEqlWithMessage(strings.HasPrefix(child, bootstrapCheckNode), false, child+" should be removed")
+		}
+	})
+
+	t.Run("leaves the access check node of another master alone", func(t *testing.T) {
+		conn := newMemConnection()
+		tests.H(t).IsNil(bootstrapTree(conn, "/dcos/ui-update/", acl))
+		otherCheck, err := conn.Create("/dcos/ui-update/"+bootstrapCheckNode, []byte("other"), zk.FlagEphemeral|zk.FlagSequence, acl)
+		tests.H(t).IsNil(err)
+
+		err = bootstrapTree(conn, "/dcos/ui-update/", acl)
+		tests.H(t).IsNil(err)
+
+		data, _, err := conn.Get(otherCheck)
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(string(data), "other")
+	})
+
+	t.Run("only creates missing nodes", func(t *testing.T) {
+		conn := newMemConnection()
+		conn.Create("/dcos", nil, 0, acl)
+		conn.Create("/dcos/ui-update", nil, 0, acl)
+		conn.Create("/dcos/ui-update/version", []byte("2.24.4"), 0, acl)
+
+		err := bootstrapTree(conn, "/dcos/ui-update/", acl)
+		tests.H(t).IsNil(err)
+
+		data, _, _ := conn.Get("/dcos/ui-update/version")
+		tests.H(t).StringEql(string(data), "2.24.4")
+		exists, _, _ := conn.Exists("/dcos/ui-update/node-status")
+		tests.H(t).BoolEql(exists, true)
+	})
+
+	t.Run("retries when another master created nodes concurrently", func(t *testing.T) {
+		conn := newMemConnection()
+		conn.multiErr = zk.ErrNodeExists
+
+		err := bootstrapTree(conn, "/dcos/ui-update/", acl)
+		tests.H(t).IsNil(err)
+	})
+
+	t.Run("fails if the subtree cannot be written", func(t *testing.T) {
+		conn := newMemConnection()
+		conn.setError = errors.New("zk: not authenticated")
+
+		err := bootstrapTree(conn, "/dcos/ui-update/", acl)
+		tests.H(t).NotNil(err)
+		tests.H(t).StringContains(err.Error(), ErrBootstrapAccessCheckFailed.Error())
+	})
+}
@@ -1,6 +1,7 @@
 package zookeeper
 
 import (
+	"path"
 	"strings"
 	"sync"
 	"time"
@@ -36,7 +37,12 @@ type ZKClient interface {
 	Get(path string) ([]byte, int32, error)
 	getW(path string) ([]byte, int32, <-chan zk.Event, error)
 	Create(path string, data []byte, perms []int32) error
+	CreateEphemeral(path string, data []byte, perms []int32) error
+	CreateEphemeralSequential(path string, data []byte, perms []int32) (string, error)
+	CreateSequential(path string, data []byte, perms []int32) (string, error)
 	Set(path string, data []byte) (int32, error)
+	SetVersioned(path string, data []byte, version int32) (int32, error)
+	Delete(path string) error
 	Children(path string) ([]string, int32, error)
 	childrenW(path string) ([]string, int32, <-chan zk.Event, error)
 }
@@ -53,20 +59,45 @@ type ZKConnection interface {
 	Get(path string) ([]byte, *zk.Stat, error)
 	GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error)
 	Set(path string, data []byte, version int32) (*zk.Stat, error)
+	Multi(ops ...interface{}) ([]zk.MultiResponse, error)
 }
 
 var (
 	// PermAll grants all permissions
 	PermAll = []int32{zk.PermAll}
+
+	// ErrNodeExists is returned when creating a node that already exists
+	ErrNodeExists = zk.ErrNodeExists
+
+	// ErrNoNode is returned when reading a node that does not exist
+	ErrNoNode = zk.ErrNoNode
+
+	// ErrNoAuth is returned if the configured credentials lack the permission for a request
+	ErrNoAuth = zk.ErrNoAuth
+
+	// ErrBadVersion is returned by SetVersioned if the node was changed since the given version was read
+	ErrBadVersion = zk.ErrBadVersion
 )
 
 type zkConfig struct {
-	BasePath       string
-	ZnodeOwner     string
-	AuthInfo       string
-	Address        string
+	BasePath   string
+	ZnodeOwner string
+	AuthInfo   string
+	// DigestUser and DigestPasswordFile authenticate with the digest scheme instead of AuthInfo
+	DigestUser         string
+	DigestPasswordFile string
+	// TLSCAFile, TLSCertFile and TLSKeyFile enable TLS to the ZK servers if set
+	TLSCAFile   string
+	TLSCertFile string
+	TLSKeyFile  string
+	// Addresses are the host:port addresses of the ensemble members
+	Addresses      []string
 	SessionTimeout time.Duration
 	ConnectTimeout time.Duration
+	// SkipBasePathInit disables creating the base path when connecting
+	SkipBasePathInit bool
+	// LegacyBasePath is migrated to BasePath when connecting if set
+	LegacyBasePath string
 }
 
 // schemaOwner composes a schema and owner
@@ -88,14 +119,13 @@ var (
 
 // Connect creates and initializes a zookeeper client
 func Connect(cfg *config.Config) (*Client, error) {
-	return connect(zkConfig{
-		BasePath:       cfg.ZKBasePath(),
-		ZnodeOwner:     cfg.ZKZnodeOwner(),
-		AuthInfo:       cfg.ZKAuthInfo(),
-		Address:        cfg.ZKAddress(),
-		SessionTimeout: cfg.ZKSessionTimeout(),
-		ConnectTimeout: cfg.ZKConnectionTimeout(),
-	})
+	zkCfg := zkConfigFrom(cfg)
+	zkCfg.LegacyBasePath = cfg.ZKLegacyBasePath()
+	if cfg.Replica() {
+		// a replica only reads the tree the masters initialized
+		zkCfg.SkipBasePathInit = true
+	}
+	return connect(zkCfg)
 }
 
 func (c *Client) Close() {
@@ -107,55 +137,140 @@ func (c *Client) ClientState() ClientState {
 }
 
 func (c *Client) Exists(path string) (bool, int32, error) {
+	start := time.Now()
 	found, stat, err := c.conn.Exists(path)
+	observeRequest("exists", path, start, err)
 	return found, stat.Version, err
 }
 
 func (c *Client) existsW(path string) (bool, int32, <-chan zk.Event, error) {
+	start := time.Now()
 	found, stat, channel, err := c.conn.ExistsW(path)
+	observeRequest("existsw", path, start, err)
 	return found, stat.Version, channel, err
 }
 
 func (c *Client) Get(path string) ([]byte, int32, error) {
+	start := time.Now()
 	data, stat, err := c.conn.Get(path)
-	return data, stat.Version, err
+	observeRequest("get", path, start, err)
+	if err != nil {
+		return data, stat.Version, err
+	}
+	value, err := DecodeValue(data)
+	return value, stat.Version, err
 }
 
 func (c *Client) getW(path string) ([]byte, int32, <-chan zk.Event, error) {
+	start := time.Now()
 	data, stat, channel, err := c.conn.GetW(path)
-	return data, stat.Version, channel, err
+	observeRequest("getw", path, start, err)
+	if err != nil {
+		return data, stat.Version, channel, err
+	}
+	value, err := DecodeValue(data)
+	return value, stat.Version, channel, err
 }
 
 func (c *Client) Create(path string, data []byte, perms []int32) error {
-	return c.create(path, data, perms)
+	start := time.Now()
+	err := c.create(path, data, perms)
+	observeRequest("create", path, start, err)
+	return err
+}
+
+// CreateEphemeral creates a node that is removed when the ZK session ends
+func (c *Client) CreateEphemeral(path string, data []byte, perms []int32) error {
+	data, err := EncodeValue(data)
+	if err != nil {
+		return errors.Wrapf(err, "unable to encode the value of %s", path)
+	}
+	start := time.Now()
+	_, err = c.conn.Create(path, data, zk.FlagEphemeral, c.aclsFor(perms))
+	observeRequest("create", path, start, err)
+	return err
+}
+
+// CreateEphemeralSequential creates a node named path followed by a sequence number that is removed
+// when the ZK session ends, it returns the path of the created node
+func (c *Client) CreateEphemeralSequential(path string, data []byte, perms []int32) (string, error) {
+	data, err := EncodeValue(data)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to encode the value of %s", path)
+	}
+	start := time.Now()
+	created, err := c.conn.Create(path, data, zk.FlagEphemeral|zk.FlagSequence, c.aclsFor(perms))
+	observeRequest("create", path, start, err)
+	return created, err
+}
+
+// CreateSequential creates a persistent node named path followed by a sequence number, it returns the path
+// of the created node
+func (c *Client) CreateSequential(path string, data []byte, perms []int32) (string, error) {
+	data, err := EncodeValue(data)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to encode the value of %s", path)
+	}
+	start := time.Now()
+	created, err := c.conn.Create(path, data, zk.FlagSequence, c.aclsFor(perms))
+	observeRequest("create", path, start, err)
+	return created, err
 }
 
 func (c *Client) Set(path string, data []byte) (int32, error) {
+	data, err := EncodeValue(data)
+	if err != nil {
+		return zkNoVersion, errors.Wrapf(err, "unable to encode the value of %s", path)
+	}
+	start := time.Now()
 	_, stat, err := c.conn.Get(path)
 	if err != nil {
+		observeRequest("set", path, start, err)
 		return zkNoVersion, err
 	}
 	stat, err = c.conn.Set(path, data, stat.Version)
+	observeRequest("set", path, start, err)
 	return stat.Version, err
 }
 
+// SetVersioned sets the data of the node only if its stat version still is version
+func (c *Client) SetVersioned(path string, data []byte, version int32) (int32, error) {
+	data, err := EncodeValue(data)
+	if err != nil {
+		return zkNoVersion, errors.Wrapf(err, "unable to encode the value of %s", path)
+	}
+	start := time.Now()
+	stat, err := c.conn.Set(path, data, version)
+	observeRequest("set", path, start, err)
+	if err != nil {
+		return zkNoVersion, err
+	}
+	return stat.Version, nil
+}
+
 func (c *Client) Children(path string) ([]string, int32, error) {
+	start := time.Now()
 	children, stat, err := c.conn.Children(path)
+	observeRequest("children", path, start, err)
 	return children, stat.Cversion, err
 }
 
 func (c *Client) childrenW(path string) ([]string, int32, <-chan zk.Event, error) {
+	start := time.Now()
 	children, stat, channel, err := c.conn.ChildrenW(path)
+	observeRequest("childrenw", path, start, err)
 	return children, stat.Cversion, channel, err
 }
 
 // Delete removes a node at the path provided
 func (c *Client) Delete(path string) error {
+	start := time.Now()
 	_, stat, err := c.conn.Get(path)
-	if err != nil {
-		return err
+	if err == nil {
+		err = c.conn.Delete(path, stat.Version)
 	}
-	return c.conn.Delete(path, stat.Version)
+	observeRequest("delete", path, start, err)
+	return err
 }
 
 // RegisterListener adds the specified listener and also sends the current state to the listener
@@ -183,18 +298,55 @@ func (c *Client) UnregisterListener(id string) {
 
 // private functions
 
+// zkConfigFrom returns the connection settings of cfg
+func zkConfigFrom(cfg *config.Config) zkConfig {
+	return zkConfig{
+		BasePath:           cfg.ZKBasePath(),
+		ZnodeOwner:         cfg.ZKZnodeOwner(),
+		AuthInfo:           cfg.ZKAuthInfo(),
+		DigestUser:         cfg.ZKDigestUser(),
+		DigestPasswordFile: cfg.ZKDigestPasswordFile(),
+		TLSCAFile:          cfg.ZKTLSCAFile(),
+		TLSCertFile:        cfg.ZKTLSCertFile(),
+		TLSKeyFile:         cfg.ZKTLSKeyFile(),
+		Addresses:          cfg.ZKAddresses(),
+		SessionTimeout:     cfg.ZKSessionTimeout(),
+		ConnectTimeout:     cfg.ZKConnectionTimeout(),
+	}
+}
+
+// connectionAuth returns the credentials added to the session and the default owner of the created nodes
+func connectionAuth(config zkConfig) (*schemaOwner, *schemaOwner, error) {
+	if config.DigestUser == "" {
+		authInfo, err := parseSchemaOwner(config.AuthInfo, nil)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "could not parse ZK auth '%s'", config.AuthInfo)
+		}
+		return authInfo, defaultZnodeOwner, nil
+	}
+	authInfo, err := digestAuth(config.DigestUser, config.DigestPasswordFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return authInfo, digestOwner(authInfo), nil
+}
+
 func connect(config zkConfig) (*Client, error) {
 	basePath, err := parseBasePath(config.BasePath)
 	if err != nil {
 		return nil, errors.Wrapf(err, "could not parse base path '%s'", basePath)
 	}
-	znodeOwner, err := parseSchemaOwner(config.ZnodeOwner, defaultZnodeOwner)
+	authInfo, defaultOwner, err := connectionAuth(config)
+	if err != nil {
+		return nil, err
+	}
+	znodeOwner, err := parseSchemaOwner(config.ZnodeOwner, defaultOwner)
 	if err != nil {
 		return nil, errors.Wrapf(err, "could not parse ZK owner '%s'", config.ZnodeOwner)
 	}
-	authInfo, err := parseSchemaOwner(config.AuthInfo, nil)
+	tlsConfig, err := zkTLSConfig(config)
 	if err != nil {
-		return nil, errors.Wrapf(err, "could not parse ZK auth '%s'", config.AuthInfo)
+		return nil, err
 	}
 	sessionEstablished := make(chan struct{})
 	client := &Client{
@@ -211,17 +363,19 @@ func connect(config zkConfig) (*Client, error) {
 		},
 		listeners: make(map[string]StateListener),
 	}
-	client.conn, _, err = zk.Connect([]string{config.Address},
+	client.conn, _, err = zk.Connect(config.Addresses,
 		config.SessionTimeout,
+		zk.WithHostProvider(&ensembleHostProvider{}),
+		zk.WithDialer(zkDialer(tlsConfig)),
 		zk.WithEventCallback(client.eventCallback(sessionEstablished)),
 		zk.WithLogger(zookeeperClientLogger()))
 	if err != nil {
-		return nil, errors.Wrapf(err, "could not connect to ZK at '%s'", config.Address)
+		return nil, errors.Wrapf(err, "could not connect to ZK at '%s'", strings.Join(config.Addresses, ","))
 	}
 	err = func() error {
 		if authInfo != nil {
 			if addAuthErr := client.conn.AddAuth(authInfo.schema, []byte(authInfo.owner)); addAuthErr != nil {
-				return errors.Wrapf(addAuthErr, "could not authenticate to ZK using '%s'", config.AuthInfo)
+				return errors.Wrapf(addAuthErr, "could not authenticate to ZK with the %s scheme", authInfo.schema)
 			}
 		}
 		// wait for the session to be established
@@ -232,9 +386,15 @@ func connect(config zkConfig) (*Client, error) {
 			return errCouldNotEstablishConnection
 		}
 
+		if config.SkipBasePathInit {
+			return nil
+		}
 		if initErr := client.initialize(); initErr != nil {
 			return errors.Wrap(initErr, "could not initialize ZK client")
 		}
+		if config.LegacyBasePath != "" {
+			return migrateBasePath(client.conn, config.LegacyBasePath, client.basePath, client.acl)
+		}
 		return nil
 	}()
 
@@ -299,22 +459,43 @@ func (c *Client) eventCallback(sessionEstablished chan struct{}) zk.EventCallbac
 				go listener(c.clientState)
 			}
 		}
+		eventLog := log.WithField("zk-node", e.Path)
 		if e.Err != nil {
-			log.WithError(e.Err).Tracef("ZK event: %s %s %s %s", e.Type, e.State, e.Path, e.Server)
-		} else {
-			log.Tracef("ZK event: %s %s %s %s", e.Type, e.State, e.Path, e.Server)
+			eventLog = eventLog.WithError(e.Err)
 		}
+		eventLog.Tracef("ZK event: %s %s %s %s", e.Type, e.State, e.Path, e.Server)
 	}
 }
 
+// parentNodes are created below the base path when connecting, the nodes of the masters are created below them
+var parentNodes = []string{"cluster-status", "node-status"}
+
 func (c *Client) initialize() error {
 	if err := c.createParents(c.basePath, nil, []int32{zk.PermAll}); err != nil {
 		return errors.Wrapf(err, "could not create parent for base path '%s'", c.basePath)
 	}
+	for _, node := range parentNodes {
+		nodePath := path.Join(c.basePath, node)
+		// another master may create the node concurrently
+		if err := c.create(nodePath, nil, []int32{zk.PermAll}); err != nil && err != zk.ErrNodeExists {
+			return errors.Wrapf(err, "could not create path '%s'", nodePath)
+		}
+	}
 	return nil
 }
 
 func (c *Client) create(path string, value []byte, perms []int32) error {
+	value, err := EncodeValue(value)
+	if err != nil {
+		return errors.Wrapf(err, "unable to encode the value of %s", path)
+	}
+	if _, err := c.conn.Create(path, value, zkNoFlags, c.aclsFor(perms)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) aclsFor(perms []int32) []zk.ACL {
 	acls := []zk.ACL{}
 	for _, perm := range perms {
 		acl := zk.ACL{
@@ -324,10 +505,7 @@ func (c *Client) create(path string, value []byte, perms []int32) error {
 		}
 		acls = append(acls, acl)
 	}
-	if _, err := c.conn.Create(path, value, zkNoFlags, acls); err != nil {
-		return err
-	}
-	return nil
+	return acls
 }
 
 func (c *Client) createParents(path string, value []byte, perms []int32) error {
@@ -0,0 +1,74 @@
+package zookeeper
+
+import (
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestClientInitialize(t *testing.T) {
+	t.Run("creates the base path and the parent nodes of the masters", func(t *testing.T) {
+		conn := newMemConnection()
+		client := &Client{conn: conn, basePath: "/dcos/ui-update/"}
+
+		tests.H(t).IsNil(client.initialize())
+
+		for _, p := range []string{"/dcos/ui-update", "/dcos/ui-update/cluster-status", "/dcos/ui-update/node-status"} {
+			exists, _, _ := conn.Exists(p)
+			tests.H(t).BoolEqlWithMessage(exists, true, p)
+		}
+		_, err := conn.Create("/dcos/ui-update/cluster-status/operation", nil, zkNoFlags, nil)
+		tests.H(t).IsNil(err)
+	})
+
+	t.Run("registers masters below node-status of a tree that was never bootstrapped", func(t *testing.T) {
+		conn := newMemConnection()
+		client := &Client{conn: conn, basePath: "/dcos/ui-update/", clientState: Connected}
+		tests.H(t).IsNil(client.initialize())
+
+		err := client.CreateEphemeral("/dcos/ui-update/node-status/10.0.0.1", []byte("10.0.0.1"), PermAll)
+
+		tests.H(t).IsNil(err)
+		children, _, err := client.Children("/dcos/ui-update/node-status")
+		tests.H(t).IsNil(err)
+		tests.H(t).InterfaceEql(children, []string{"10.0.0.1"})
+	})
+
+	t.Run("stores the sync freeze below cluster-status of a tree that was never bootstrapped", func(t *testing.T) {
+		conn := newMemConnection()
+		client := &Client{conn: conn, basePath: "/dcos/ui-update/", clientState: Connected}
+		tests.H(t).IsNil(client.initialize())
+
+		err := client.Create("/dcos/ui-update/cluster-status/freeze", []byte(`{"version":"2.25.0"}`), PermAll)
+
+		tests.H(t).IsNil(err)
+		data, _, err := client.Get("/dcos/ui-update/cluster-status/freeze")
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(string(data), `{"version":"2.25.0"}`)
+	})
+
+	t.Run("stores the version pin below cluster-status of a tree that was never bootstrapped", func(t *testing.T) {
+		conn := newMemConnection()
+		client := &Client{conn: conn, basePath: "/dcos/ui-update/", clientState: Connected}
+		tests.H(t).IsNil(client.initialize())
+
+		err := client.Create("/dcos/ui-update/cluster-status/pin", []byte(`{"version":"2.24.4"}`), PermAll)
+
+		tests.H(t).IsNil(err)
+		exists, _, _ := client.Exists("/dcos/ui-update/cluster-status/pin")
+		tests.H(t).BoolEql(exists, true)
+	})
+
+	t.Run("keeps existing parent nodes", func(t *testing.T) {
+		conn := newMemConnection()
+		client := &Client{conn: conn, basePath: "/dcos/ui-update/"}
+		tests.H(t).IsNil(client.initialize())
+		conn.Create("/dcos/ui-update/node-status/10.0.0.1", []byte("2.25.0"), zkNoFlags, nil)
+
+		tests.H(t).IsNil(client.initialize())
+
+		data, _, err := conn.Get("/dcos/ui-update/node-status/10.0.0.1")
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(string(data), "2.25.0")
+	})
+}
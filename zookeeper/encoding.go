@@ -0,0 +1,101 @@
+package zookeeper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// MaxValueSize is the largest value stored in a znode after encoding. It leaves room for the request
+// framing below the 1 MiB default jute.maxbuffer of the ZK servers.
+const MaxValueSize = 1000 * 1024
+
+// compressThreshold is the size above which values are compressed. Smaller values are stored as they are,
+// so the version and status nodes stay readable by masters running older versions of the service.
+const compressThreshold = 8 * 1024
+
+// maxDecodedSize bounds the size of a decompressed value
+const maxDecodedSize = 16 * MaxValueSize
+
+// encodedValueHeader starts encoded values, it cannot start a plain value which has no control characters.
+// It is followed by the encoding format.
+const encodedValueHeader = "\x01zv"
+
+// formatGzip is the format of values compressed with gzip
+const formatGzip = "1g"
+
+var (
+	// ErrValueTooLarge is returned for a value that is larger than MaxValueSize once encoded
+	ErrValueTooLarge = errors.New("The value exceeds the maximum znode value size")
+	// ErrInvalidValueCharset is returned for a value that is not UTF-8 text without control characters
+	ErrInvalidValueCharset = errors.New("The value is not UTF-8 text without control characters")
+	// ErrUnknownValueEncoding is returned for a value written in an encoding format this version cannot read
+	ErrUnknownValueEncoding = errors.New("The value has an unknown encoding format")
+)
+
+// EncodeValue checks the charset of value and encodes it for a znode. Values larger than compressThreshold
+// are compressed, smaller values are returned unchanged.
+func EncodeValue(value []byte) ([]byte, error) {
+	if !validValueCharset(value) {
+		return nil, ErrInvalidValueCharset
+	}
+	if len(value) <= compressThreshold {
+		return value, nil
+	}
+	var buf bytes.Buffer
+	buf.WriteString(encodedValueHeader + formatGzip)
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(value); err != nil {
+		return nil, errors.Wrap(err, "unable to compress the value")
+	}
+	if err := writer.Close(); err != nil {
+		return nil, errors.Wrap(err, "unable to compress the value")
+	}
+	if buf.Len() > MaxValueSize {
+		return nil, ErrValueTooLarge
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeValue returns the value encoded by EncodeValue. Values without the encoding header are plain values
+// written as they are.
+func DecodeValue(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(encodedValueHeader)) {
+		return data, nil
+	}
+	encoded := data[len(encodedValueHeader):]
+	if !bytes.HasPrefix(encoded, []byte(formatGzip)) {
+		return nil, ErrUnknownValueEncoding
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(encoded[len(formatGzip):]))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decompress the value")
+	}
+	defer reader.Close()
+	value, err := ioutil.ReadAll(io.LimitReader(reader, maxDecodedSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decompress the value")
+	}
+	if len(value) > maxDecodedSize {
+		return nil, ErrValueTooLarge
+	}
+	return value, nil
+}
+
+// validValueCharset is true for UTF-8 text without control characters other than tabs and line breaks
+func validValueCharset(value []byte) bool {
+	if !utf8.Valid(value) {
+		return false
+	}
+	for _, r := range string(value) {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
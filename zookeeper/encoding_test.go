@@ -0,0 +1,88 @@
+package zookeeper
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestEncodeValue(t *testing.T) {
+	t.Run("stores small values as they are", func(t *testing.T) {
+		encoded, err := EncodeValue([]byte("2.25.0"))
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(string(encoded), "2.25.0")
+	})
+
+	t.Run("compresses large values", func(t *testing.T) {
+		value := []byte(strings.Repeat(`{"node":"10.0.0.1","version":"2.25.0"}`, 1000))
+
+		encoded, err := EncodeValue(value)
+
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(len(encoded) < len(value), true)
+		tests.H(t).StringEql(string(encoded[:len(encodedValueHeader)]), encodedValueHeader)
+		decoded, err := DecodeValue(encoded)
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(bytes.Equal(decoded, value), true)
+	})
+
+	t.Run("rejects control characters", func(t *testing.T) {
+		_, err := EncodeValue([]byte("2.25.0\x00"))
+
+		tests.H(t).ErrEql(err, ErrInvalidValueCharset)
+	})
+
+	t.Run("rejects invalid UTF-8", func(t *testing.T) {
+		_, err := EncodeValue([]byte{0xff, 0xfe})
+
+		tests.H(t).ErrEql(err, ErrInvalidValueCharset)
+	})
+
+	t.Run("rejects values too large for a znode", func(t *testing.T) {
+		// base64 like text does not compress below the limit
+		var buf bytes.Buffer
+		seed := uint32(1)
+		for buf.Len() < 2*MaxValueSize {
+			seed = seed*1664525 + 1013904223
+			buf.WriteByte("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"[seed>>26])
+		}
+
+		_, err := EncodeValue(buf.Bytes())
+
+		tests.H(t).ErrEql(err, ErrValueTooLarge)
+	})
+}
+
+func TestDecodeValue(t *testing.T) {
+	t.Run("returns plain values as they are", func(t *testing.T) {
+		decoded, err := DecodeValue([]byte("2.25.0"))
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(string(decoded), "2.25.0")
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		_, err := DecodeValue([]byte(encodedValueHeader + "9x"))
+
+		tests.H(t).ErrEql(err, ErrUnknownValueEncoding)
+	})
+}
+
+func TestClientEncodesValues(t *testing.T) {
+	client := &Client{conn: newMemConnection()}
+	value := []byte(strings.Repeat("10.0.0.1=2.25.0\n", 1000))
+
+	tests.H(t).IsNil(client.Create("/registry", value, PermAll))
+
+	stored, _, _ := client.conn.Get("/registry")
+	tests.H(t).BoolEql(len(stored) < len(value), true)
+	read, _, err := client.Get("/registry")
+	tests.H(t).IsNil(err)
+	tests.H(t).BoolEql(bytes.Equal(read, value), true)
+
+	_, err = client.Set("/registry", []byte("bad\x07value"))
+	tests.H(t).StringContains(err.Error(), ErrInvalidValueCharset.Error())
+}
@@ -1,6 +1,7 @@
 package zookeeper
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/samuel/go-zookeeper/zk"
@@ -12,6 +13,7 @@ type FakeZKClient struct {
 	CreateError   error
 	SetError      error
 	ChildrenError error
+	DeleteError   error
 
 	ClientStateResult ClientState
 	IDListeners       map[string]StateListener
@@ -19,11 +21,18 @@ type FakeZKClient struct {
 	GetResult         []byte
 	GetResults        [][]byte
 	GetResultsIndex   int
-	ChildrenResults   []string
-	EventChannel      chan zk.Event
-
-	CreateCall func(string, []byte, []int32)
-	SetCall    func(string, []byte)
+	// SetVersionedErrors are returned by consecutive SetVersioned calls, nil once exhausted
+	SetVersionedErrors []error
+	ChildrenResults    []string
+	EventChannel       chan zk.Event
+
+	CreateCall          func(string, []byte, []int32)
+	CreateEphemeralCall func(string, []byte, []int32)
+	// SequentialCreated are the paths created by CreateEphemeralSequential and CreateSequential
+	SequentialCreated []string
+	SetCall           func(string, []byte)
+	SetVersionedCall  func(string, []byte, int32)
+	DeleteCall        func(string)
 	sync.Mutex
 }
 
@@ -109,6 +118,42 @@ func (zkc *FakeZKClient) Create(path string, data []byte, perms []int32) error {
 	return nil
 }
 
+func (zkc *FakeZKClient) CreateEphemeral(path string, data []byte, perms []int32) error {
+	zkc.Lock()
+	defer zkc.Unlock()
+	if zkc.CreateEphemeralCall != nil {
+		zkc.CreateEphemeralCall(path, data, perms)
+	}
+	if zkc.CreateError != nil {
+		return zkc.CreateError
+	}
+	return nil
+}
+
+func (zkc *FakeZKClient) CreateEphemeralSequential(path string, data []byte, perms []int32) (string, error) {
+	zkc.Lock()
+	defer zkc.Unlock()
+	if zkc.CreateError != nil {
+		return "", zkc.CreateError
+	}
+	created := fmt.Sprintf("%s%010d", path, len(zkc.SequentialCreated))
+	zkc.SequentialCreated = append(zkc.SequentialCreated, created)
+	return created, nil
+}
+
+func (zkc *FakeZKClient) CreateSequential(path string, data []byte, perms []int32) (string, error) {
+	return zkc.CreateEphemeralSequential(path, data, perms)
+}
+
+func (zkc *FakeZKClient) Delete(path string) error {
+	zkc.Lock()
+	defer zkc.Unlock()
+	if zkc.DeleteCall != nil {
+		zkc.DeleteCall(path)
+	}
+	return zkc.DeleteError
+}
+
 func (zkc *FakeZKClient) Set(path string, data []byte) (int32, error) {
 	zkc.Lock()
 	defer zkc.Unlock()
@@ -121,6 +166,25 @@ func (zkc *FakeZKClient) Set(path string, data []byte) (int32, error) {
 	return 0, nil
 }
 
+func (zkc *FakeZKClient) SetVersioned(path string, data []byte, version int32) (int32, error) {
+	zkc.Lock()
+	defer zkc.Unlock()
+	if zkc.SetVersionedCall != nil {
+		zkc.SetVersionedCall(path, data, version)
+	}
+	if len(zkc.SetVersionedErrors) > 0 {
+		err := zkc.SetVersionedErrors[0]
+		zkc.SetVersionedErrors = zkc.SetVersionedErrors[1:]
+		if err != nil {
+			return -1, err
+		}
+	}
+	if zkc.SetError != nil {
+		return -1, zkc.SetError
+	}
+	return version + 1, nil
+}
+
 func (zkc *FakeZKClient) Children(path string) ([]string, int32, error) {
 	zkc.Lock()
 	defer zkc.Unlock()
@@ -0,0 +1,185 @@
+package zookeeper
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+type memNode struct {
+	data      []byte
+	version   int32
+	acl       []zk.ACL
+	ephemeral bool
+}
+
+func (n *memNode) stat() *zk.Stat {
+	stat := &zk.Stat{Version: n.version}
+	if n.ephemeral {
+		stat.EphemeralOwner = 1
+	}
+	return stat
+}
+
+// memConnection is an in-memory ZKConnection used to test code talking to the raw connection
+type memConnection struct {
+	nodes    map[string]*memNode
+	setError error
+	multiErr error
+	sequence int
+	sync.Mutex
+}
+
+func newMemConnection() *memConnection {
+	return &memConnection{nodes: map[string]*memNode{"/": {}}}
+}
+
+func (c *memConnection) AddAuth(scheme string, auth []byte) error { return nil }
+func (c *memConnection) Close()                                   {}
+
+func (c *memConnection) Children(p string) ([]string, *zk.Stat, error) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.nodes[p]; !ok {
+		return nil, &zk.Stat{}, zk.ErrNoNode
+	}
+	var children []string
+	for name := range c.nodes {
+		if name != p && path.Dir(name) == p {
+			children = append(children, strings.TrimPrefix(name, strings.TrimSuffix(p, "/")+"/"))
+		}
+	}
+	return children, &zk.Stat{}, nil
+}
+
+func (c *memConnection) ChildrenW(p string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	children, stat, err := c.Children(p)
+	return children, stat, make(chan zk.Event), err
+}
+
+func (c *memConnection) Create(p string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+	return c.create(p, data, flags, acl)
+}
+
+func (c *memConnection) create(p string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	if flags&zk.FlagSequence != 0 {
+		p = fmt.Sprintf("%s%010d", p, c.sequence)
+		c.sequence++
+	}
+	if _, ok := c.nodes[p]; ok {
+		return "", zk.ErrNodeExists
+	}
+	if _, ok := c.nodes[path.Dir(p)]; !ok {
+		return "", zk.ErrNoNode
+	}
+	c.nodes[p] = &memNode{data: data, acl: acl, ephemeral: flags&zk.FlagEphemeral != 0}
+	return p, nil
+}
+
+func (c *memConnection) Delete(p string, version int32) error {
+	c.Lock()
+	defer c.Unlock()
+	node, ok := c.nodes[p]
+	if !ok {
+		return zk.ErrNoNode
+	}
+	if version != zkNoVersion && version != node.version {
+		return zk.ErrBadVersion
+	}
+	delete(c.nodes, p)
+	return nil
+}
+
+func (c *memConnection) Exists(p string) (bool, *zk.Stat, error) {
+	c.Lock()
+	defer c.Unlock()
+	node, ok := c.nodes[p]
+	if !ok {
+		return false, &zk.Stat{}, nil
+	}
+	return true, node.stat(), nil
+}
+
+func (c *memConnection) ExistsW(p string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	found, stat, err := c.Exists(p)
+	return found, stat, make(chan zk.Event), err
+}
+
+func (c *memConnection) Get(p string) ([]byte, *zk.Stat, error) {
+	c.Lock()
+	defer c.Unlock()
+	node, ok := c.nodes[p]
+	if !ok {
+		return nil, &zk.Stat{}, zk.ErrNoNode
+	}
+	return node.data, node.stat(), nil
+}
+
+func (c *memConnection) GetW(p string) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	data, stat, err := c.Get(p)
+	return data, stat, make(chan zk.Event), err
+}
+
+func (c *memConnection) Set(p string, data []byte, version int32) (*zk.Stat, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.setError != nil {
+		return &zk.Stat{}, c.setError
+	}
+	node, ok := c.nodes[p]
+	if !ok {
+		return &zk.Stat{}, zk.ErrNoNode
+	}
+	if version != zkNoVersion && version != node.version {
+		return &zk.Stat{}, zk.ErrBadVersion
+	}
+	node.data = data
+	node.version++
+	return &zk.Stat{Version: node.version}, nil
+}
+
+func (c *memConnection) Multi(ops ...interface{}) ([]zk.MultiResponse, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.multiErr != nil {
+		err := c.multiErr
+		c.multiErr = nil
+		return nil, err
+	}
+	snapshot := make(map[string]*memNode, len(c.nodes))
+	for p, node := range c.nodes {
+		copied := *node
+		snapshot[p] = &copied
+	}
+	responses := []zk.MultiResponse{}
+	for _, op := range ops {
+		var err error
+		switch req := op.(type) {
+		case *zk.CreateRequest:
+			_, err = c.create(req.Path, req.Data, req.Flags, req.Acl)
+			responses = append(responses, zk.MultiResponse{String: req.Path})
+		case *zk.SetDataRequest:
+			node, ok := c.nodes[req.Path]
+			switch {
+			case !ok:
+				err = zk.ErrNoNode
+			case req.Version != zkNoVersion && req.Version != node.version:
+				err = zk.ErrBadVersion
+			default:
+				node.data = req.Data
+				node.version++
+			}
+			responses = append(responses, zk.MultiResponse{})
+		}
+		if err != nil {
+			c.nodes = snapshot
+			return nil, err
+		}
+	}
+	return responses, nil
+}
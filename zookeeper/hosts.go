@@ -0,0 +1,73 @@
+package zookeeper
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+)
+
+var errNoZKServers = errors.New("no ZK server addresses configured")
+
+// ensembleHostProvider hands out the addresses of the ZK ensemble members in turn. Unlike the default
+// provider of the ZK library it does not resolve host names once on start, the dialer resolves them on every
+// connection attempt, so replaced or temporarily unresolvable members do not require a restart.
+type ensembleHostProvider struct {
+	servers []string
+	curr    int
+	last    int
+	sync.Mutex
+}
+
+// Init is called with the configured addresses before the first connection attempt
+func (hp *ensembleHostProvider) Init(servers []string) error {
+	hp.Lock()
+	defer hp.Unlock()
+	if len(servers) == 0 {
+		return errNoZKServers
+	}
+	for _, server := range servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			return err
+		}
+	}
+	hp.servers = append([]string{}, servers...)
+	// spread the masters across the ensemble members
+	rand.Shuffle(len(hp.servers), func(i, j int) {
+		hp.servers[i], hp.servers[j] = hp.servers[j], hp.servers[i]
+	})
+	hp.curr = -1
+	hp.last = -1
+	return nil
+}
+
+// Len returns the number of ensemble members
+func (hp *ensembleHostProvider) Len() int {
+	hp.Lock()
+	defer hp.Unlock()
+	return len(hp.servers)
+}
+
+// Next returns the member to connect to next, retryStart is true once all members were tried without
+// a connection
+func (hp *ensembleHostProvider) Next() (string, bool) {
+	hp.Lock()
+	defer hp.Unlock()
+	hp.curr = (hp.curr + 1) % len(hp.servers)
+	retryStart := hp.curr == hp.last
+	if hp.last == -1 {
+		hp.last = 0
+	}
+	if retryStart {
+		log.WithField("zk-servers", hp.servers).Warn("Could not connect to any ZK server, retrying")
+	}
+	return hp.servers[hp.curr], retryStart
+}
+
+// Connected is called once a connection to the current member was established
+func (hp *ensembleHostProvider) Connected() {
+	hp.Lock()
+	defer hp.Unlock()
+	hp.last = hp.curr
+	log.WithField("zk-node", hp.servers[hp.curr]).Debug("Connected to ZK server")
+}
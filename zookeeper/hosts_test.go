@@ -0,0 +1,50 @@
+package zookeeper
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestEnsembleHostProvider(t *testing.T) {
+	servers := []string{"zk-1.zk:2181", "zk-2.zk:2181", "zk-3.zk:2181"}
+
+	t.Run("keeps the host names unresolved", func(t *testing.T) {
+		hp := &ensembleHostProvider{}
+		tests.H(t).IsNil(hp.Init(servers))
+
+		var handed []string
+		for i := 0; i < hp.Len(); i++ {
+			server, _ := hp.Next()
+			handed = append(handed, server)
+		}
+		sort.Strings(handed)
+
+		tests.H(t).InterfaceEql(handed, servers)
+	})
+
+	t.Run("fails over to the next member and restarts after trying all", func(t *testing.T) {
+		hp := &ensembleHostProvider{}
+		tests.H(t).IsNil(hp.Init(servers))
+		first, _ := hp.Next()
+		hp.Connected()
+
+		second, retryStart := hp.Next()
+		tests.H(t).BoolEql(second != first, true)
+		tests.H(t).BoolEql(retryStart, false)
+		hp.Next()
+		again, retryStart := hp.Next()
+
+		tests.H(t).StringEql(again, first)
+		tests.H(t).BoolEql(retryStart, true)
+	})
+
+	t.Run("returns an error for an address without port", func(t *testing.T) {
+		tests.H(t).NotNil((&ensembleHostProvider{}).Init([]string{"zk-1.zk"}))
+	})
+
+	t.Run("returns an error without addresses", func(t *testing.T) {
+		tests.H(t).ErrEql((&ensembleHostProvider{}).Init(nil), errNoZKServers)
+	})
+}
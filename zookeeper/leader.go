@@ -0,0 +1,156 @@
+package zookeeper
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// memberPrefix is the name of the member nodes below the election path, followed by their sequence number
+const memberPrefix = "member-"
+
+// ErrNoLeader occurs if no member has joined the election
+var ErrNoLeader = errors.New("No member has joined the leader election")
+
+// Leader tells if this member leads the masters and how to reach the member that does
+type Leader interface {
+	// IsLeader is true while the member node of this master is the oldest member of the election
+	IsLeader() (bool, error)
+	// LeaderData returns the data the leading member joined with, ErrNoLeader if there is no member
+	LeaderData() ([]byte, error)
+	// MemberData returns the data every member joined with
+	MemberData() ([][]byte, error)
+}
+
+// Election is a Leader using ephemeral sequential member nodes below path, the member with the lowest
+// sequence number leads. The member node is removed with the ZK session of the leader, so the next member
+// leads once the leader's session expired.
+type Election struct {
+	client ZKClient
+	path   string
+	data   []byte
+	// member is the node of this master, empty before Join
+	member string
+	sync.Mutex
+}
+
+// NewElection creates an election below path, members join with data
+func NewElection(client ZKClient, path string, data []byte) *Election {
+	return &Election{client: client, path: path, data: data}
+}
+
+// Join creates the member node of this master unless it still exists, e.g. after a reconnect within the
+// session timeout. It is called again after a new session was established.
+func (e *Election) Join() error {
+	e.Lock()
+	defer e.Unlock()
+	if e.member != "" {
+		exists, _, err := e.client.Exists(e.member)
+		if err != nil {
+			return errors.Wrap(err, "Failed to check the election member node")
+		}
+		if exists {
+			return nil
+		}
+	}
+	if err := e.client.Create(e.path, nil, PermAll); err != nil && err != ErrNodeExists {
+		return errors.Wrap(err, "Failed to create the election node")
+	}
+	member, err := e.client.CreateEphemeralSequential(path.Join(e.path, memberPrefix), e.data, PermAll)
+	if err != nil {
+		return errors.Wrap(err, "Failed to join the leader election")
+	}
+	e.member = member
+	return nil
+}
+
+// Resign removes the member node of this master, the next member leads
+func (e *Election) Resign() error {
+	e.Lock()
+	defer e.Unlock()
+	if e.member == "" {
+		return nil
+	}
+	member := e.member
+	e.member = ""
+	if err := e.client.Delete(member); err != nil {
+		return errors.Wrap(err, "Failed to leave the leader election")
+	}
+	return nil
+}
+
+// IsLeader compares the member node of this master with the oldest member
+func (e *Election) IsLeader() (bool, error) {
+	leader, err := e.leader()
+	if err != nil {
+		return false, err
+	}
+	e.Lock()
+	defer e.Unlock()
+	return e.member != "" && path.Base(e.member) == leader, nil
+}
+
+// LeaderData reads the node of the oldest member
+func (e *Election) LeaderData() ([]byte, error) {
+	leader, err := e.leader()
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := e.client.Get(path.Join(e.path, leader))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read the leader")
+	}
+	return data, nil
+}
+
+// MemberData reads the nodes of all members, members leaving meanwhile are skipped
+func (e *Election) MemberData() ([][]byte, error) {
+	members, err := e.members()
+	if err != nil {
+		return nil, err
+	}
+	var data [][]byte
+	for _, member := range members {
+		memberData, _, err := e.client.Get(path.Join(e.path, member))
+		if err == ErrNoNode {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read the election member")
+		}
+		data = append(data, memberData)
+	}
+	return data, nil
+}
+
+// leader returns the name of the member node with the lowest sequence number, ZK pads the sequence numbers
+// to equal length so they sort as strings
+func (e *Election) leader() (string, error) {
+	members, err := e.members()
+	if err != nil {
+		return "", err
+	}
+	if len(members) == 0 {
+		return "", ErrNoLeader
+	}
+	return members[0], nil
+}
+
+// members returns the names of the member nodes, oldest first
+func (e *Election) members() ([]string, error) {
+	children, _, err := e.client.Children(e.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list the election members")
+	}
+	members := children[:0:0]
+	for _, child := range children {
+		if strings.HasPrefix(child, memberPrefix) {
+			members = append(members, child)
+		}
+	}
+	sort.Strings(members)
+	return members, nil
+}
@@ -0,0 +1,100 @@
+package zookeeper
+
+import (
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestElection(t *testing.T) {
+	t.Parallel()
+
+	t.Run("leads while its member node is the oldest", func(t *testing.T) {
+		client := NewFakeZKClient()
+		election := NewElection(client, "/dcos/ui-update/leader", []byte("http://10.0.0.1"))
+		tests.H(t).IsNil(election.Join())
+		client.ChildrenResults = []string{"member-0000000001", "member-0000000000"}
+
+		leader, err := election.IsLeader()
+
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(leader, true)
+	})
+
+	t.Run("follows an older member", func(t *testing.T) {
+		client := NewFakeZKClient()
+		client.SequentialCreated = []string{"/dcos/ui-update/leader/member-0000000000"}
+		client.GetResult = []byte("http://10.0.0.2")
+		election := NewElection(client, "/dcos/ui-update/leader", []byte("http://10.0.0.1"))
+		tests.H(t).IsNil(election.Join())
+		client.ChildrenResults = []string{"member-0000000001", "member-0000000000"}
+
+		leader, err := election.IsLeader()
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(leader, false)
+
+		data, err := election.LeaderData()
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(string(data), "http://10.0.0.2")
+	})
+
+	t.Run("reads the data of all members oldest first", func(t *testing.T) {
+		client := NewFakeZKClient()
+		client.GetResults = [][]byte{[]byte("http://10.0.0.2"), []byte("http://10.0.0.1")}
+		election := NewElection(client, "/dcos/ui-update/leader", []byte("http://10.0.0.1"))
+		client.ChildrenResults = []string{"member-0000000001", "other", "member-0000000000"}
+
+		data, err := election.MemberData()
+
+		tests.H(t).IsNil(err)
+		tests.H(t).IntEql(len(data), 2)
+		tests.H(t).StringEql(string(data[0]), "http://10.0.0.2")
+		tests.H(t).StringEql(string(data[1]), "http://10.0.0.1")
+	})
+
+	t.Run("returns ErrNoLeader without members", func(t *testing.T) {
+		client := NewFakeZKClient()
+		election := NewElection(client, "/dcos/ui-update/leader", nil)
+
+		_, err := election.LeaderData()
+
+		tests.H(t).ErrEql(err, ErrNoLeader)
+	})
+
+	t.Run("keeps its member node while it exists", func(t *testing.T) {
+		client := NewFakeZKClient()
+		election := NewElection(client, "/dcos/ui-update/leader", nil)
+		tests.H(t).IsNil(election.Join())
+		client.ExistsResult = true
+
+		tests.H(t).IsNil(election.Join())
+
+		tests.H(t).IntEql(len(client.SequentialCreated), 1)
+	})
+
+	t.Run("joins again once its member node was removed with the session", func(t *testing.T) {
+		client := NewFakeZKClient()
+		election := NewElection(client, "/dcos/ui-update/leader", nil)
+		tests.H(t).IsNil(election.Join())
+		client.ExistsResult = false
+
+		tests.H(t).IsNil(election.Join())
+
+		tests.H(t).IntEql(len(client.SequentialCreated), 2)
+	})
+
+	t.Run("does not lead after resigning", func(t *testing.T) {
+		client := NewFakeZKClient()
+		var deleted string
+		client.DeleteCall = func(path string) { deleted = path }
+		election := NewElection(client, "/dcos/ui-update/leader", nil)
+		tests.H(t).IsNil(election.Join())
+		client.ChildrenResults = []string{"member-0000000000"}
+
+		tests.H(t).IsNil(election.Resign())
+
+		tests.H(t).StringEql(deleted, "/dcos/ui-update/leader/member-0000000000")
+		leader, _ := election.IsLeader()
+		tests.H(t).BoolEql(leader, false)
+	})
+}
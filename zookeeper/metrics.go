@@ -0,0 +1,46 @@
+package zookeeper
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/metrics"
+)
+
+// metricsPathDepth limits the path label to the first segments of a node path to bound cardinality
+const metricsPathDepth = 3
+
+var (
+	requestsTotal = metrics.DefaultRegistry.Counter(
+		"zookeeper_requests_total",
+		"Number of ZooKeeper requests made.",
+		"operation", "path",
+	)
+	requestErrorsTotal = metrics.DefaultRegistry.Counter(
+		"zookeeper_request_errors_total",
+		"Number of ZooKeeper requests that returned an error.",
+		"operation", "path",
+	)
+	requestDuration = metrics.DefaultRegistry.Summary(
+		"zookeeper_request_duration_seconds",
+		"Latency of ZooKeeper requests in seconds.",
+		"operation", "path",
+	)
+)
+
+func observeRequest(operation, path string, start time.Time, err error) {
+	prefix := metricsPathPrefix(path)
+	requestsTotal.Inc(operation, prefix)
+	requestDuration.Observe(time.Since(start).Seconds(), operation, prefix)
+	if err != nil {
+		requestErrorsTotal.Inc(operation, prefix)
+	}
+}
+
+func metricsPathPrefix(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > metricsPathDepth {
+		segments = segments[:metricsPathDepth]
+	}
+	return "/" + strings.Join(segments, "/")
+}
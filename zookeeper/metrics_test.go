@@ -0,0 +1,30 @@
+package zookeeper
+
+import (
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestClientMetrics(t *testing.T) {
+	t.Run("counts requests and errors by operation and path prefix", func(t *testing.T) {
+		client := &Client{conn: newMemConnection()}
+		path := "/metrics-test/ui-update/version/child"
+		prefix := "/metrics-test/ui-update/version"
+
+		client.Create("/metrics-test", nil, PermAll)
+		client.Get(path)
+
+		tests.H(t).Int64Eql(int64(requestsTotal.Value("create", "/metrics-test")), 1)
+		tests.H(t).Int64Eql(int64(requestErrorsTotal.Value("create", "/metrics-test")), 0)
+		tests.H(t).Int64Eql(int64(requestsTotal.Value("get", prefix)), 1)
+		tests.H(t).Int64Eql(int64(requestErrorsTotal.Value("get", prefix)), 1)
+		tests.H(t).Int64Eql(int64(requestDuration.Count("get", prefix)), 1)
+	})
+
+	t.Run("metricsPathPrefix keeps the first segments", func(t *testing.T) {
+		tests.H(t).StringEql(metricsPathPrefix("/dcos/ui-update/version"), "/dcos/ui-update/version")
+		tests.H(t).StringEql(metricsPathPrefix("/dcos/ui-update/node-status/master-1"), "/dcos/ui-update/node-status")
+		tests.H(t).StringEql(metricsPathPrefix("/dcos"), "/dcos")
+	})
+}
@@ -0,0 +1,160 @@
+package zookeeper
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/sirupsen/logrus"
+)
+
+// MigrationTombstoneNode is created below the legacy base path once its data was migrated,
+// it holds the base path the data was copied to.
+const MigrationTombstoneNode = ".migrated-to"
+
+// registrationNodes hold the ephemeral registrations of the masters, their children are not migrated. The
+// masters register again below the new base path, a copied registration would never expire.
+var registrationNodes = map[string]bool{"node-status": true}
+
+// migrateBasePath copies the BootstrapNodes and their direct children from the legacy base path
+// to the new base path and creates a tombstone pointing to the new base path, all in one transaction.
+// Data already present at the new base path is never overwritten.
+func migrateBasePath(conn ZKConnection, legacyBasePath, basePath string, acl []zk.ACL) error {
+	err := migrateBasePathOnce(conn, legacyBasePath, basePath, acl)
+	if err == zk.ErrNodeExists || err == zk.ErrBadVersion {
+		// another master migrated concurrently, the retry sees the tombstone or the copied data
+		log.Info("ZK base path was migrated concurrently, retrying")
+		err = migrateBasePathOnce(conn, legacyBasePath, basePath, acl)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "could not migrate ZK base path '%s' to '%s'", legacyBasePath, basePath)
+	}
+	return nil
+}
+
+func migrateBasePathOnce(conn ZKConnection, legacyBasePath, basePath string, acl []zk.ACL) error {
+	legacyBasePath = path.Clean(legacyBasePath)
+	basePath = path.Clean(basePath)
+	migrationLog := log.WithFields(logrus.Fields{
+		"legacyBasePath": legacyBasePath,
+		"basePath":       basePath,
+	})
+
+	if exists, _, err := conn.Exists(legacyBasePath); err != nil || !exists {
+		if err == nil {
+			migrationLog.Debug("No data found at legacy ZK base path, nothing to migrate")
+		}
+		return err
+	}
+	tombstone := path.Join(legacyBasePath, MigrationTombstoneNode)
+	if exists, _, err := conn.Exists(tombstone); err != nil || exists {
+		if err == nil {
+			migrationLog.Debug("Legacy ZK base path was already migrated")
+		}
+		return err
+	}
+
+	var ops []interface{}
+	for _, p := range bootstrapPaths(basePath) {
+		if !strings.HasPrefix(p, basePath+"/") {
+			// parents of the base path have no legacy counterpart
+			op, err := createIfMissing(conn, p, nil, acl)
+			if err != nil {
+				return err
+			}
+			ops = appendOp(ops, op)
+			continue
+		}
+		nodeOps, err := migrateNode(conn, path.Join(legacyBasePath, strings.TrimPrefix(p, basePath+"/")), p, acl)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, nodeOps...)
+	}
+	ops = append(ops, &zk.CreateRequest{Path: tombstone, Data: []byte(basePath), Acl: acl, Flags: zkNoFlags})
+
+	if _, err := conn.Multi(ops...); err != nil {
+		return err
+	}
+	migrationLog.WithField("operations", len(ops)).Info("Migrated ZK data from legacy base path")
+	return nil
+}
+
+// migrateNode returns the operations copying the legacy node and its children to the new path
+func migrateNode(conn ZKConnection, legacyPath, newPath string, acl []zk.ACL) ([]interface{}, error) {
+	data, _, err := conn.Get(legacyPath)
+	if err == zk.ErrNoNode {
+		op, createErr := createIfMissing(conn, newPath, nil, acl)
+		return appendOp(nil, op), createErr
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read legacy node '%s'", legacyPath)
+	}
+
+	var ops []interface{}
+	exists, stat, err := conn.Exists(newPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not check if path '%s' exists", newPath)
+	}
+	switch {
+	case !exists:
+		ops = append(ops, &zk.CreateRequest{Path: newPath, Data: data, Acl: acl, Flags: zkNoFlags})
+	case len(data) > 0:
+		current, _, err := conn.Get(newPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read node '%s'", newPath)
+		}
+		if len(current) == 0 {
+			ops = append(ops, &zk.SetDataRequest{Path: newPath, Data: data, Version: stat.Version})
+		}
+	}
+
+	if registrationNodes[path.Base(newPath)] {
+		return ops, nil
+	}
+	children, _, err := conn.Children(legacyPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not list children of legacy node '%s'", legacyPath)
+	}
+	sort.Strings(children)
+	for _, child := range children {
+		childData, childStat, err := conn.Get(path.Join(legacyPath, child))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read legacy node '%s'", path.Join(legacyPath, child))
+		}
+		if childStat.EphemeralOwner != 0 {
+			// e.g. the cluster operation lock, it belongs to the session of a master
+			continue
+		}
+		if exists {
+			op, err := createIfMissing(conn, path.Join(newPath, child), childData, acl)
+			if err != nil {
+				return nil, err
+			}
+			ops = appendOp(ops, op)
+			continue
+		}
+		ops = append(ops, &zk.CreateRequest{Path: path.Join(newPath, child), Data: childData, Acl: acl, Flags: zkNoFlags})
+	}
+	return ops, nil
+}
+
+func createIfMissing(conn ZKConnection, p string, data []byte, acl []zk.ACL) (interface{}, error) {
+	exists, _, err := conn.Exists(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not check if path '%s' exists", p)
+	}
+	if exists {
+		return nil, nil
+	}
+	return &zk.CreateRequest{Path: p, Data: data, Acl: acl, Flags: zkNoFlags}, nil
+}
+
+func appendOp(ops []interface{}, op interface{}) []interface{} {
+	if op == nil {
+		return ops
+	}
+	return append(ops, op)
+}
@@ -0,0 +1,118 @@
+package zookeeper
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+func TestMigrateBasePath(t *testing.T) {
+	acl := []zk.ACL{{Perms: zk.PermAll, Scheme: "world", ID: "anyone"}}
+
+	newLegacyConnection := func() *memConnection {
+		conn := newMemConnection()
+		conn.Create("/dcos", nil, 0, acl)
+		conn.Create("/dcos/ui-update", nil, 0, acl)
+		conn.Create("/dcos/ui-update/version", []byte("2.25.1"), 0, acl)
+		conn.Create("/dcos/ui-update/node-status", nil, 0, acl)
+		conn.Create("/dcos/ui-update/node-status/master-1", []byte("ready"), zk.FlagEphemeral, acl)
+		conn.Create("/dcos/ui-update/cluster-status", nil, 0, acl)
+		conn.Create("/dcos/ui-update/cluster-status/pin", []byte(`{"version":"2.25.1"}`), 0, acl)
+		conn.Create("/dcos/ui-update/cluster-status/operation", []byte("update"), zk.FlagEphemeral, acl)
+		return conn
+	}
+
+	t.Run("copies nodes and creates a tombstone", func(t *testing.T) {
+		conn := newLegacyConnection()
+
+		err := migrateBasePath(conn, "/dcos/ui-update", "/dcos/ui-service/", acl)
+		tests.H(t).IsNil(err)
+
+		version, _, _ := conn.Get("/dcos/ui-service/version")
+		tests.H(t).StringEql(string(version), "2.25.1")
+		pin, _, _ := conn.Get("/dcos/ui-service/cluster-status/pin")
+		tests.H(t).StringEql(string(pin), `{"version":"2.25.1"}`)
+		exists, _, _ := conn.Exists("/dcos/ui-service/node-status")
+		tests.H(t).BoolEql(exists, true)
+		tombstone, _, _ := conn.Get("/dcos/ui-update/" + MigrationTombstoneNode)
+		tests.H(t).StringEql(string(tombstone), "/dcos/ui-service")
+	})
+
+	t.Run("does not copy the ephemeral registrations and locks of the masters", func(t *testing.T) {
+		conn := newLegacyConnection()
+		conn.Create("/dcos/ui-update/node-status/master-2", []byte("ready"), 0, acl)
+
+		err := migrateBasePath(conn, "/dcos/ui-update", "/dcos/ui-service", acl)
+		tests.H(t).IsNil(err)
+
+		for _, p := range []string{
+			"/dcos/ui-service/node-status/master-1",
+			"/dcos/ui-service/node-status/master-2",
+			"/dcos/ui-service/cluster-status/operation",
+		} {
+			exists, _, _ := conn.Exists(p)
+			tests.H(t).BoolEqlWithMessage(exists, false, p)
+		}
+	})
+
+	t.Run("fills empty nodes created by bootstrap", func(t *testing.T) {
+		conn := newLegacyConnection()
+		tests.H(t).IsNil(bootstrapTree(conn, "/dcos/ui-service/", acl))
+
+		err := migrateBasePath(conn, "/dcos/ui-update", "/dcos/ui-service", acl)
+		tests.H(t).IsNil(err)
+
+		version, _, _ := conn.Get("/dcos/ui-service/version")
+		tests.H(t).StringEql(string(version), "2.25.1")
+	})
+
+	t.Run("does not overwrite data at the new base path", func(t *testing.T) {
+		conn := newLegacyConnection()
+		conn.Create("/dcos/ui-service", nil, 0, acl)
+		conn.Create("/dcos/ui-service/version", []byte("2.26.0"), 0, acl)
+
+		err := migrateBasePath(conn, "/dcos/ui-update", "/dcos/ui-service", acl)
+		tests.H(t).IsNil(err)
+
+		version, _, _ := conn.Get("/dcos/ui-service/version")
+		tests.H(t).StringEql(string(version), "2.26.0")
+	})
+
+	t.Run("does nothing once migrated", func(t *testing.T) {
+		conn := newLegacyConnection()
+		tests.H(t).IsNil(migrateBasePath(conn, "/dcos/ui-update", "/dcos/ui-service", acl))
+		conn.Set("/dcos/ui-update/version", []byte("2.24.0"), zkNoVersion)
+		conn.Set("/dcos/ui-service/version", nil, zkNoVersion)
+
+		err := migrateBasePath(conn, "/dcos/ui-update", "/dcos/ui-service", acl)
+		tests.H(t).IsNil(err)
+
+		version, _, _ := conn.Get("/dcos/ui-service/version")
+		tests.H(t).StringEql(string(version), "")
+	})
+
+	t.Run("does nothing without legacy data", func(t *testing.T) {
+		conn := newMemConnection()
+
+		err := migrateBasePath(conn, "/dcos/ui-update", "/dcos/ui-service", acl)
+		tests.H(t).IsNil(err)
+
+		exists, _, _ := conn.Exists("/dcos/ui-service")
+		tests.H(t).BoolEql(exists, false)
+	})
+
+	t.Run("leaves no partial copy if the transaction fails", func(t *testing.T) {
+		conn := newLegacyConnection()
+		conn.multiErr = errors.New("connection lost")
+
+		err := migrateBasePath(conn, "/dcos/ui-update", "/dcos/ui-service", acl)
+		tests.H(t).NotNil(err)
+
+		exists, _, _ := conn.Exists("/dcos/ui-service")
+		tests.H(t).BoolEql(exists, false)
+		exists, _, _ = conn.Exists("/dcos/ui-update/" + MigrationTombstoneNode)
+		tests.H(t).BoolEql(exists, false)
+	})
+}
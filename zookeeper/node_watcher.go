@@ -1,6 +1,9 @@
 package zookeeper
 
 import (
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
 	"github.com/pkg/errors"
 )
 
@@ -18,11 +21,33 @@ var (
 type ParentNodeWatcher interface {
 	Children() []string
 	Path() string
+	// SetRestartDelay delays restarting the watch after the connection was re-established,
+	// the first watch starts right away
+	SetRestartDelay(delay time.Duration)
 	Close()
 }
 
 type ValueNodeWatcher interface {
 	Value() []byte
 	Path() string
+	// SetRestartDelay delays restarting the watch after the connection was re-established,
+	// the first watch starts right away
+	SetRestartDelay(delay time.Duration)
 	Close()
 }
+
+// waitRestartDelay waits delay before a watch is restarted. It returns false if the connection was lost
+// again or the watcher was closed meanwhile.
+func waitRestartDelay(clk clock.Clock, delay time.Duration, disconnected, closed <-chan struct{}) bool {
+	if delay <= 0 {
+		return true
+	}
+	select {
+	case <-clk.After(delay):
+		return true
+	case <-disconnected:
+		return false
+	case <-closed:
+		return false
+	}
+}
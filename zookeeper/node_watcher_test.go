@@ -0,0 +1,53 @@
+package zookeeper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/clock"
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestWaitRestartDelay(t *testing.T) {
+	t.Run("returns right away without delay", func(t *testing.T) {
+		tests.H(t).BoolEql(waitRestartDelay(clock.NewFake(time.Now()), 0, nil, nil), true)
+	})
+
+	t.Run("returns true once the delay passed", func(t *testing.T) {
+		clk := clock.NewFake(time.Now())
+		done := make(chan bool)
+		go func() { done <- waitRestartDelay(clk, 5*time.Second, nil, nil) }()
+
+		clk.BlockUntil(1)
+		clk.Advance(5 * time.Second)
+
+		tests.H(t).BoolEql(<-done, true)
+	})
+
+	t.Run("returns false if the connection is lost again", func(t *testing.T) {
+		disconnected := make(chan struct{})
+		close(disconnected)
+
+		tests.H(t).BoolEql(waitRestartDelay(clock.NewFake(time.Now()), 5*time.Second, disconnected, nil), false)
+	})
+}
+
+func TestNodeWatcherStartDelay(t *testing.T) {
+	client := NewFakeZKClient()
+	client.ClientStateResult = Connected
+	client.ExistsResult = true
+
+	valueWatcher, _ := newValueNodeWatcher(client, "/foo", time.Minute, clock.NewFake(time.Now()), func(val []byte) {})
+	defer valueWatcher.Close()
+	parentWatcher, _ := newParentNodeWatcher(client, "/bar", time.Minute, clock.NewFake(time.Now()), func(children []string) {})
+	defer parentWatcher.Close()
+	valueWatcher.SetRestartDelay(2 * time.Second)
+	parentWatcher.SetRestartDelay(3 * time.Second)
+
+	// the first watches were started when the watchers were registered
+	value := valueWatcher.(*valueNodeWatcher)
+	value.watchMutex.Lock()
+	tests.H(t).Int64Eql(int64(value.startDelay()), int64(2*time.Second))
+	value.watchMutex.Unlock()
+	tests.H(t).Int64Eql(int64(parentWatcher.(*parentNodeWatcher).startDelay()), int64(3*time.Second))
+}
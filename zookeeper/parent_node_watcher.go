@@ -1,8 +1,10 @@
 package zookeeper
 
 import (
+	"sync/atomic"
 	"time"
 
+	"github.com/dcos/dcos-ui-update-service/clock"
 	"github.com/jpillora/backoff"
 	"github.com/samuel/go-zookeeper/zk"
 	"github.com/sirupsen/logrus"
@@ -15,6 +17,7 @@ type parentNodeWatcher struct {
 	client       ZKClient
 	nodePath     string
 	pollTimeout  time.Duration
+	clock        clock.Clock
 	lastVersion  int32
 	children     []string
 	eventChannel <-chan zk.Event
@@ -22,11 +25,18 @@ type parentNodeWatcher struct {
 	closed       chan struct{}
 	listener     ParentNodeWatchListener
 	watchActive  bool
+	// started is set once the first watch was started, restartDelay applies to the following ones
+	started      bool
+	restartDelay int64
 	log          *logrus.Entry
 }
 
 // CreateParentNodeWatcher returns a ZK ParentNodeWatcher that will call the provided listener when the children of the node are modified.
 func CreateParentNodeWatcher(client ZKClient, path string, polltimeout time.Duration, listener ParentNodeWatchListener) (ParentNodeWatcher, error) {
+	return newParentNodeWatcher(client, path, polltimeout, clock.New(), listener)
+}
+
+func newParentNodeWatcher(client ZKClient, path string, polltimeout time.Duration, clk clock.Clock, listener ParentNodeWatchListener) (ParentNodeWatcher, error) {
 	if listener == nil {
 		return nil, ErrListenerNotProvided
 	}
@@ -53,6 +63,7 @@ func CreateParentNodeWatcher(client ZKClient, path string, polltimeout time.Dura
 		client:       client,
 		nodePath:     path,
 		pollTimeout:  polltimeout,
+		clock:        clk,
 		lastVersion:  ver,
 		children:     children,
 		eventChannel: nil,
@@ -84,6 +95,19 @@ func (nw *parentNodeWatcher) Close() {
 	nw.client.UnregisterListener(nw.nodePath)
 }
 
+func (nw *parentNodeWatcher) SetRestartDelay(delay time.Duration) {
+	atomic.StoreInt64(&nw.restartDelay, int64(delay))
+}
+
+// startDelay returns the delay before the next watch is started
+func (nw *parentNodeWatcher) startDelay() time.Duration {
+	if !nw.started {
+		nw.started = true
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&nw.restartDelay))
+}
+
 func (nw *parentNodeWatcher) handleZkStateChange(state ClientState) {
 	if state == Disconnected {
 		nw.log.Debug("ZK Disconnected, stopping node watcher")
@@ -95,12 +119,19 @@ func (nw *parentNodeWatcher) handleZkStateChange(state ClientState) {
 			nw.disconnected = make(chan struct{})
 		}
 		if !nw.watchActive {
-			nw.log.Debug("ZK Connected, starting node watcher")
-			go nw.startWatch()
+			delay := nw.startDelay()
+			nw.log.WithField("delay", delay).Debug("ZK Connected, starting node watcher")
+			go nw.startWatchAfter(delay, nw.disconnected)
 		}
 	}
 }
 
+func (nw *parentNodeWatcher) startWatchAfter(delay time.Duration, disconnected <-chan struct{}) {
+	if waitRestartDelay(nw.clock, delay, disconnected, nw.closed) {
+		nw.startWatch()
+	}
+}
+
 func (nw *parentNodeWatcher) startWatch() {
 	nw.log.Trace("Creating ZK eventChannel")
 	children, ver, eventChannel, err := nw.client.childrenW(nw.nodePath)
@@ -139,7 +170,7 @@ func (nw *parentNodeWatcher) waitOrPoll() {
 			nw.handleClosed()
 			return
 		// Timeout and poll
-		case <-time.After(nw.pollTimeout):
+		case <-nw.clock.After(nw.pollTimeout):
 			nw.handlePollTimeout()
 		}
 	}
@@ -173,7 +204,7 @@ func (nw *parentNodeWatcher) restartWatchAfterError() {
 		case <-nw.closed:
 			nw.handleClosed()
 			return
-		case <-time.After(b.Duration()):
+		case <-nw.clock.After(b.Duration()):
 			nw.log.Trace("Retrying to create watch after error")
 		}
 	}
@@ -8,14 +8,12 @@ import (
 	"github.com/pkg/errors"
 	"github.com/samuel/go-zookeeper/zk"
 
+	"github.com/dcos/dcos-ui-update-service/clock"
 	"github.com/dcos/dcos-ui-update-service/tests"
 )
 
 func TestParentNodeWatcher(t *testing.T) {
-	const (
-		pollTimeout      = time.Duration(30 * time.Second)
-		shortPollTimeout = time.Duration(500 * time.Millisecond)
-	)
+	const pollTimeout = time.Duration(30 * time.Second)
 
 	t.Parallel()
 
@@ -139,7 +137,8 @@ func TestParentNodeWatcher(t *testing.T) {
 		var listenerCalls [][]string
 		wg.Add(1)
 
-		watcher, _ := CreateParentNodeWatcher(client, "/foo", shortPollTimeout, func(val []string) {
+		clk := clock.NewFake(time.Now())
+		watcher, _ := newParentNodeWatcher(client, "/foo", pollTimeout, clk, func(val []string) {
 			listenerMutex.Lock()
 			defer listenerMutex.Unlock()
 			listenerCalls = append(listenerCalls, val)
@@ -151,6 +150,9 @@ func TestParentNodeWatcher(t *testing.T) {
 		client.ChildrenResults = []string{"bar"}
 		client.Unlock()
 
+		clk.BlockUntil(1)
+		clk.Advance(pollTimeout)
+
 		wg.Wait()
 
 		listenerMutex.Lock()
@@ -0,0 +1,44 @@
+package zookeeper
+
+import "github.com/pkg/errors"
+
+// ErrReadOnly is returned by the writes of a read-only client
+var ErrReadOnly = errors.New("The ZK client is read-only")
+
+// readOnlyClient passes reads and watches to the wrapped client and rejects all writes
+type readOnlyClient struct {
+	ZKClient
+}
+
+// ReadOnly wraps client so nodes can be read and watched but never created, set or deleted
+func ReadOnly(client ZKClient) ZKClient {
+	return readOnlyClient{client}
+}
+
+func (readOnlyClient) Create(path string, data []byte, perms []int32) error {
+	return ErrReadOnly
+}
+
+func (readOnlyClient) CreateEphemeral(path string, data []byte, perms []int32) error {
+	return ErrReadOnly
+}
+
+func (readOnlyClient) CreateEphemeralSequential(path string, data []byte, perms []int32) (string, error) {
+	return "", ErrReadOnly
+}
+
+func (readOnlyClient) CreateSequential(path string, data []byte, perms []int32) (string, error) {
+	return "", ErrReadOnly
+}
+
+func (readOnlyClient) Set(path string, data []byte) (int32, error) {
+	return 0, ErrReadOnly
+}
+
+func (readOnlyClient) SetVersioned(path string, data []byte, version int32) (int32, error) {
+	return 0, ErrReadOnly
+}
+
+func (readOnlyClient) Delete(path string) error {
+	return ErrReadOnly
+}
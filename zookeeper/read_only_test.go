@@ -0,0 +1,45 @@
+package zookeeper
+
+import (
+	"testing"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reads from the wrapped client", func(t *testing.T) {
+		client := NewFakeZKClient()
+		client.GetResult = []byte("2.25.0")
+
+		data, _, err := ReadOnly(client).Get("/dcos/ui-update/version")
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(string(data), "2.25.0")
+	})
+
+	t.Run("rejects all writes without passing them to the wrapped client", func(t *testing.T) {
+		client := NewFakeZKClient()
+		written := false
+		client.CreateCall = func(string, []byte, []int32) { written = true }
+		client.CreateEphemeralCall = func(string, []byte, []int32) { written = true }
+		client.SetCall = func(string, []byte) { written = true }
+		client.SetVersionedCall = func(string, []byte, int32) { written = true }
+		client.DeleteCall = func(string) { written = true }
+		readOnly := ReadOnly(client)
+
+		tests.H(t).ErrEql(readOnly.Create("/dcos/ui-update/version", nil, PermAll), ErrReadOnly)
+		tests.H(t).ErrEql(readOnly.CreateEphemeral("/dcos/ui-update/node-status/10.0.0.1", nil, PermAll), ErrReadOnly)
+		_, err := readOnly.CreateEphemeralSequential("/dcos/ui-update/leader/member-", nil, PermAll)
+		tests.H(t).ErrEql(err, ErrReadOnly)
+		_, err = readOnly.CreateSequential("/dcos/ui-update/events/event-", nil, PermAll)
+		tests.H(t).ErrEql(err, ErrReadOnly)
+		_, err = readOnly.Set("/dcos/ui-update/version", []byte("2.25.0"))
+		tests.H(t).ErrEql(err, ErrReadOnly)
+		_, err = readOnly.SetVersioned("/dcos/ui-update/version", []byte("2.25.0"), 1)
+		tests.H(t).ErrEql(err, ErrReadOnly)
+		tests.H(t).ErrEql(readOnly.Delete("/dcos/ui-update/version"), ErrReadOnly)
+		tests.H(t).BoolEql(written, false)
+	})
+}
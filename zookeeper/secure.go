@@ -0,0 +1,77 @@
+package zookeeper
+
+import (
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// digestScheme is the ZK auth scheme authenticating a user with a password
+const digestScheme = "digest"
+
+var errEmptyDigestPassword = errors.New("zk digest password file is empty")
+
+// zkTLSConfig loads the TLS configuration of the ZK connection, nil if TLS is not configured
+func zkTLSConfig(config zkConfig) (*tls.Config, error) {
+	if config.TLSCAFile == "" && config.TLSCertFile == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.TLSCAFile != "" {
+		pem, err := ioutil.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read ZK CA file '%s'", config.TLSCAFile)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in ZK CA file '%s'", config.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not load ZK client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// zkDialer dials the ZK servers with TLS if tlsConfig is set, the server name is taken from the address
+func zkDialer(tlsConfig *tls.Config) zk.Dialer {
+	if tlsConfig == nil {
+		return net.DialTimeout
+	}
+	return func(network, address string, timeout time.Duration) (net.Conn, error) {
+		return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, network, address, tlsConfig)
+	}
+}
+
+// digestAuth returns the digest credentials of user with the password read from passwordFile
+func digestAuth(user, passwordFile string) (*schemaOwner, error) {
+	content, err := ioutil.ReadFile(passwordFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read ZK digest password file '%s'", passwordFile)
+	}
+	password := strings.TrimSpace(string(content))
+	if password == "" {
+		return nil, errEmptyDigestPassword
+	}
+	return &schemaOwner{schema: digestScheme, owner: user + ":" + password}, nil
+}
+
+// digestOwner returns the digest ACL owner of the credentials, 'user:base64(sha1(user:password))'
+func digestOwner(auth *schemaOwner) *schemaOwner {
+	user := strings.SplitN(auth.owner, ":", 2)[0]
+	hash := sha1.Sum([]byte(auth.owner))
+	return &schemaOwner{schema: digestScheme, owner: user + ":" + base64.StdEncoding.EncodeToString(hash[:])}
+}
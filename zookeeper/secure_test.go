@@ -0,0 +1,92 @@
+package zookeeper
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-ui-update-service/tests"
+)
+
+func writeSecret(t *testing.T, dir, name, content string) string {
+	file := path.Join(dir, name)
+	tests.H(t).IsNil(ioutil.WriteFile(file, []byte(content), 0600))
+	return file
+}
+
+func TestDigestAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zk-secure")
+	tests.H(t).IsNil(err)
+	defer os.RemoveAll(dir)
+
+	t.Run("reads the password without trailing newline", func(t *testing.T) {
+		auth, err := digestAuth("super", writeSecret(t, dir, "password", "secret\n"))
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(auth.schema, "digest")
+		tests.H(t).StringEql(auth.owner, "super:secret")
+	})
+
+	t.Run("returns an error for an empty password file", func(t *testing.T) {
+		_, err := digestAuth("super", writeSecret(t, dir, "empty", "\n"))
+
+		tests.H(t).ErrEql(err, errEmptyDigestPassword)
+	})
+
+	t.Run("owns created nodes with the digest of the credentials", func(t *testing.T) {
+		authInfo, owner, err := connectionAuth(zkConfig{
+			DigestUser:         "super",
+			DigestPasswordFile: writeSecret(t, dir, "password", "secret"),
+		})
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(authInfo.owner, "super:secret")
+		tests.H(t).StringEql(owner.schema, "digest")
+		tests.H(t).StringEql(owner.owner, "super:lK75jTNcA+U9vtVEw5vB51mj/w4=")
+	})
+
+	t.Run("uses the auth info without digest user", func(t *testing.T) {
+		authInfo, owner, err := connectionAuth(zkConfig{AuthInfo: "digest:super:secret"})
+
+		tests.H(t).IsNil(err)
+		tests.H(t).StringEql(authInfo.owner, "super:secret")
+		tests.H(t).StringEql(owner.schema+":"+owner.owner, "world:anyone")
+	})
+}
+
+func TestZKTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zk-secure")
+	tests.H(t).IsNil(err)
+	defer os.RemoveAll(dir)
+
+	t.Run("is nil without TLS files", func(t *testing.T) {
+		tlsConfig, err := zkTLSConfig(zkConfig{})
+
+		tests.H(t).IsNil(err)
+		tests.H(t).BoolEql(tlsConfig == nil, true)
+	})
+
+	t.Run("returns an error for a CA file without certificates", func(t *testing.T) {
+		_, err := zkTLSConfig(zkConfig{TLSCAFile: writeSecret(t, dir, "ca.crt", "garbage")})
+
+		tests.H(t).NotNil(err)
+	})
+
+	t.Run("dials servers signed by the CA", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		defer server.Close()
+		caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		tlsConfig, err := zkTLSConfig(zkConfig{TLSCAFile: writeSecret(t, dir, "ca.crt", string(caPEM))})
+		tests.H(t).IsNil(err)
+
+		conn, err := zkDialer(tlsConfig)("tcp", server.Listener.Addr().String(), time.Second)
+
+		tests.H(t).IsNil(err)
+		conn.Close()
+	})
+}
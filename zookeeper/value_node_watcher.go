@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/dcos/dcos-ui-update-service/clock"
 	"github.com/jpillora/backoff"
 	"github.com/samuel/go-zookeeper/zk"
 	"github.com/sirupsen/logrus"
@@ -16,6 +17,7 @@ type valueNodeWatcher struct {
 	client       ZKClient
 	nodePath     string
 	pollTimeout  time.Duration
+	clock        clock.Clock
 	lastVersion  int32
 	value        []byte
 	eventChannel <-chan zk.Event
@@ -23,12 +25,19 @@ type valueNodeWatcher struct {
 	closed       chan struct{}
 	listener     ValueNodeWatchListener
 	watchActive  bool
+	// started is set once the first watch was started, restartDelay applies to the following ones
+	started      bool
+	restartDelay time.Duration
 	log          *logrus.Entry
 	watchMutex   sync.Mutex
 }
 
 // CreateValueNodeWatcher returns a ZK ValueNodeWatcher that will call the provided listener when the node value changes, is created or deleted.
 func CreateValueNodeWatcher(client ZKClient, path string, polltimeout time.Duration, listener ValueNodeWatchListener) (ValueNodeWatcher, error) {
+	return newValueNodeWatcher(client, path, polltimeout, clock.New(), listener)
+}
+
+func newValueNodeWatcher(client ZKClient, path string, polltimeout time.Duration, clk clock.Clock, listener ValueNodeWatchListener) (ValueNodeWatcher, error) {
 	if listener == nil {
 		return nil, ErrListenerNotProvided
 	}
@@ -54,6 +63,7 @@ func CreateValueNodeWatcher(client ZKClient, path string, polltimeout time.Durat
 		client:       client,
 		nodePath:     path,
 		pollTimeout:  polltimeout,
+		clock:        clk,
 		lastVersion:  ver,
 		value:        value,
 		eventChannel: nil,
@@ -82,6 +92,21 @@ func (nw *valueNodeWatcher) Path() string {
 	return nw.nodePath
 }
 
+func (nw *valueNodeWatcher) SetRestartDelay(delay time.Duration) {
+	nw.watchMutex.Lock()
+	defer nw.watchMutex.Unlock()
+	nw.restartDelay = delay
+}
+
+// startDelay returns the delay before the next watch is started, it is called while watchMutex is held
+func (nw *valueNodeWatcher) startDelay() time.Duration {
+	if !nw.started {
+		nw.started = true
+		return 0
+	}
+	return nw.restartDelay
+}
+
 func (nw *valueNodeWatcher) Close() {
 	close(nw.closed)
 	nw.client.UnregisterListener(nw.nodePath)
@@ -100,12 +125,19 @@ func (nw *valueNodeWatcher) handleZkStateChange(state ClientState) {
 			nw.disconnected = make(chan struct{})
 		}
 		if !nw.watchActive {
-			nw.log.Debug("ZK Connected, starting node watcher")
-			go nw.startWatch()
+			delay := nw.startDelay()
+			nw.log.WithField("delay", delay).Debug("ZK Connected, starting node watcher")
+			go nw.startWatchAfter(delay, nw.disconnected)
 		}
 	}
 }
 
+func (nw *valueNodeWatcher) startWatchAfter(delay time.Duration, disconnected <-chan struct{}) {
+	if waitRestartDelay(nw.clock, delay, disconnected, nw.closed) {
+		nw.startWatch()
+	}
+}
+
 func (nw *valueNodeWatcher) startWatch() {
 	nw.watchMutex.Lock()
 
@@ -142,7 +174,7 @@ func (nw *valueNodeWatcher) waitOrPoll() {
 			nw.handleClosed()
 			return
 		// Timeout and poll
-		case <-time.After(nw.pollTimeout):
+		case <-nw.clock.After(nw.pollTimeout):
 			nw.handlePollTimeout()
 		}
 	}
@@ -196,7 +228,7 @@ func (nw *valueNodeWatcher) restartWatchAfterError() {
 		case <-nw.closed:
 			nw.handleClosed()
 			return
-		case <-time.After(b.Duration()):
+		case <-nw.clock.After(b.Duration()):
 			nw.log.Trace("Retrying to create watch after error")
 		}
 	}
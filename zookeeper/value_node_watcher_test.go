@@ -8,14 +8,12 @@ import (
 	"github.com/pkg/errors"
 	"github.com/samuel/go-zookeeper/zk"
 
+	"github.com/dcos/dcos-ui-update-service/clock"
 	"github.com/dcos/dcos-ui-update-service/tests"
 )
 
 func TestValueNodeWatcher(t *testing.T) {
-	const (
-		pollTimeout      = time.Duration(30 * time.Second)
-		shortPollTimeout = time.Duration(500 * time.Millisecond)
-	)
+	const pollTimeout = time.Duration(30 * time.Second)
 
 	t.Parallel()
 
@@ -135,7 +133,8 @@ func TestValueNodeWatcher(t *testing.T) {
 		var listenerMutex sync.Mutex
 		wg.Add(1)
 		var listenerCalls [][]byte
-		watcher, _ := CreateValueNodeWatcher(client, "/foo", shortPollTimeout, func(val []byte) {
+		clk := clock.NewFake(time.Now())
+		watcher, _ := newValueNodeWatcher(client, "/foo", pollTimeout, clk, func(val []byte) {
 			listenerMutex.Lock()
 			defer listenerMutex.Unlock()
 			listenerCalls = append(listenerCalls, val)
@@ -148,6 +147,9 @@ func TestValueNodeWatcher(t *testing.T) {
 		client.GetResult = []byte("bar")
 		client.Unlock()
 
+		clk.BlockUntil(1)
+		clk.Advance(pollTimeout)
+
 		wg.Wait()
 
 		listenerMutex.Lock()
@@ -215,13 +217,17 @@ func TestValueNodeWatcher(t *testing.T) {
 			wg.Done()
 		}
 
-		watcher, err := CreateValueNodeWatcher(client, "/foo", shortPollTimeout, callback)
+		clk := clock.NewFake(time.Now())
+		watcher, err := newValueNodeWatcher(client, "/foo", pollTimeout, clk, callback)
 
 		helper.IsNil(err)
 		defer watcher.Close()
 
 		helper.StringEql(string(watcher.Value()), "bar")
 
+		clk.BlockUntil(1)
+		clk.Advance(pollTimeout)
+
 		wg.Wait()
 
 		listenerMutex.Lock()
@@ -284,7 +290,8 @@ func TestValueNodeWatcher(t *testing.T) {
 		var listenerMutex sync.Mutex
 		wg.Add(1)
 		var listenerCalls [][]byte
-		watcher, _ := CreateValueNodeWatcher(client, "/foo", shortPollTimeout, func(val []byte) {
+		clk := clock.NewFake(time.Now())
+		watcher, _ := newValueNodeWatcher(client, "/foo", pollTimeout, clk, func(val []byte) {
 			listenerMutex.Lock()
 			defer listenerMutex.Unlock()
 			listenerCalls = append(listenerCalls, val)
@@ -297,6 +304,9 @@ func TestValueNodeWatcher(t *testing.T) {
 		client.GetResult = []byte{}
 		client.Unlock()
 
+		clk.BlockUntil(1)
+		clk.Advance(pollTimeout)
+
 		wg.Wait()
 
 		listenerMutex.Lock()